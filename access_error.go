@@ -0,0 +1,32 @@
+package twig
+
+import "fmt"
+
+// AccessError reports a panic recovered while reading an attribute or item
+// or calling a func with reflection, such as calling a method on a nil
+// pointer. Rendering stops
+// with the error instead of crashing the program.
+type AccessError struct {
+	Kind  string      // "attribute", "item" or "call"
+	Name  string      // Attribute name or item key
+	Type  string      // Type of the object accessed
+	Panic interface{} // Value recovered from the panic
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("cannot access %s %q of %s: %v", e.Kind, e.Name, e.Type, e.Panic)
+}
+
+// recoverAttribute converts a panic in getAttribute into an AccessError
+func recoverAttribute(err *error, attr string, obj interface{}) {
+	if r := recover(); r != nil {
+		*err = &AccessError{Kind: "attribute", Name: attr, Type: fmt.Sprintf("%T", obj), Panic: r}
+	}
+}
+
+// recoverItem converts a panic in getItem into an AccessError
+func recoverItem(err *error, index, container interface{}) {
+	if r := recover(); r != nil {
+		*err = &AccessError{Kind: "item", Name: toString(index), Type: fmt.Sprintf("%T", container), Panic: r}
+	}
+}
@@ -0,0 +1,65 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type panickyUser struct{ Name string }
+
+func (u *panickyUser) Boom() string { panic("boom") }
+
+type panickyList struct{}
+
+func (panickyList) Len() int             { return 1 }
+func (panickyList) At(i int) interface{} { panic("bad index") }
+
+func TestAccessPanicsBecomeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		context map[string]interface{}
+		message string
+		line    string
+	}{
+		{
+			name:    "method panics",
+			source:  "line one\n{{ user.Boom }}",
+			context: map[string]interface{}{"user": &panickyUser{}},
+			message: `cannot access attribute "Boom" of *twig.panickyUser: boom`,
+			line:    "at line 2",
+		},
+		{
+			name:    "item panics",
+			source:  "{% if list[0] %}x{% endif %}",
+			context: map[string]interface{}{"list": panickyList{}},
+			message: `cannot access item "0" of twig.panickyList: bad index`,
+			line:    "at line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			_, err := engine.Render("test", tt.context)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+
+			var accessErr *AccessError
+			if !errors.As(err, &accessErr) {
+				t.Fatalf("Expected an AccessError, got %T: %v", err, err)
+			}
+			for _, want := range []string{tt.message, tt.line} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in %q", want, err.Error())
+				}
+			}
+		})
+	}
+}
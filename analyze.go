@@ -0,0 +1,214 @@
+package twig
+
+import (
+	"sort"
+)
+
+// TemplateReport summarizes the structure of a template, to find templates
+// that are expensive to render or depend on undeclared variables
+type TemplateReport struct {
+	Name               string      `json:"name"`
+	Nodes              int         `json:"nodes"`                       // Statements and expressions
+	MaxLoopDepth       int         `json:"max_loop_depth"`              // Deepest nesting of for loops
+	Includes           int         `json:"includes"`                    // include tags and include() calls
+	Parent             string      `json:"parent,omitempty"`            // Template named by a static extends
+	Blocks             []string    `json:"blocks"`                      // Blocks defined by the template
+	Filters            []string    `json:"filters"`                     // Filters used
+	Functions          []string    `json:"functions"`                   // Functions called
+	Macros             []MacroInfo `json:"macros,omitempty"`            // Macros defined, with their doc comments
+	UnknownIdentifiers []string    `json:"unknown_identifiers"`         // Variables neither set in the template nor global
+	UnknownFilters     []string    `json:"unknown_filters,omitempty"`   // Filters not registered in the engine
+	UnknownFunctions   []string    `json:"unknown_functions,omitempty"` // Functions not registered in the engine
+	UnknownBlocks      []string    `json:"unknown_blocks,omitempty"`    // Blocks overriding none of the parent's blocks
+}
+
+// builtinVariables are provided by the engine during rendering
+var builtinVariables = map[string]bool{
+	"_self":    true,
+	"_context": true,
+	"loop":     true,
+	"now":      true,
+}
+
+// builtinFunctions are handled by the render context rather than registered
+var builtinFunctions = map[string]bool{
+	"range":       true,
+	"length":      true,
+	"count":       true,
+	"max":         true,
+	"min":         true,
+	"feature":     true,
+	"csp_nonce":   true,
+	"block":       true,
+	"attribute":   true,
+	"include_raw": true,
+}
+
+// builtinFilters are handled by the render context rather than registered
+var builtinFilters = map[string]bool{
+	"map_macro": true,
+}
+
+// Analyze parses the named template and reports on its structure
+func (e *Engine) Analyze(name string) (*TemplateReport, error) {
+	template, err := e.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	return e.analyzeTemplate(template), nil
+}
+
+// analyzeTemplate builds the report for a parsed template
+func (e *Engine) analyzeTemplate(template *Template) *TemplateReport {
+	a := &analyzer{
+		filters:    make(map[string]bool),
+		functions:  make(map[string]bool),
+		variables:  make(map[string]bool),
+		defined:    make(map[string]bool),
+		macroNames: make(map[string]bool),
+	}
+	Walk(template.nodes, a)
+
+	report := &TemplateReport{
+		Name:         template.name,
+		Nodes:        a.nodes,
+		MaxLoopDepth: a.maxDepth,
+		Includes:     a.includes,
+		Parent:       a.parent,
+		Blocks:       a.blocks,
+		Filters:      sortedSet(a.filters, nil),
+		Functions:    sortedSet(a.functions, nil),
+		Macros:       a.macros,
+	}
+	if report.Blocks == nil {
+		report.Blocks = []string{}
+	}
+
+	env := e.environment
+	report.UnknownIdentifiers = sortedSet(a.variables, func(name string) bool {
+		_, global := env.globals[name]
+		_, service := env.services[name]
+		return !a.defined[name] && !global && !service && !builtinVariables[name] && !a.macroNames[name]
+	})
+	report.UnknownFilters = sortedSet(a.filters, func(name string) bool {
+		_, ok := env.filters[name]
+		return !ok && !builtinFilters[name]
+	})
+	report.UnknownFunctions = sortedSet(a.functions, func(name string) bool {
+		_, ok := env.functions[name]
+		return !ok && !builtinFunctions[name] && !a.macroNames[name]
+	})
+	report.UnknownBlocks = e.unknownBlocks(template, a.parent)
+
+	return report
+}
+
+// analyzer collects template statistics while walking the tree
+type analyzer struct {
+	nodes    int
+	depth    int
+	maxDepth int
+	includes int
+	parent   string
+	blocks   []string
+	macros   []MacroInfo
+
+	filters    map[string]bool
+	functions  map[string]bool
+	variables  map[string]bool // Referenced variables
+	defined    map[string]bool // Variables assigned in the template
+	macroNames map[string]bool
+}
+
+// Enter records a node and the names it uses or defines
+func (a *analyzer) Enter(node Node) bool {
+	a.nodes++
+
+	switch n := node.(type) {
+	case *ForNode:
+		a.depth++
+		if a.depth > a.maxDepth {
+			a.maxDepth = a.depth
+		}
+		a.defined[n.keyVar] = true
+		a.defined[n.valueVar] = true
+		for _, target := range n.targets {
+			a.defined[target] = true
+		}
+	case *IncludeNode:
+		a.includes++
+	case *EmbedNode:
+		a.includes++
+	case *ExtendsNode:
+		if literal, ok := n.parent.(*LiteralNode); ok {
+			if name, ok := literal.value.(string); ok {
+				a.parent = name
+			}
+		}
+	case *BlockNode:
+		a.blocks = append(a.blocks, n.name)
+	case *SetNode:
+		a.defined[n.name] = true
+	case *MacroNode:
+		a.macroNames[n.name] = true
+		a.macros = append(a.macros, MacroInfo{Name: n.name, Params: n.params, Doc: n.doc})
+		for _, param := range n.params {
+			a.defined[param] = true
+		}
+	case *ImportNode:
+		a.defined[n.module] = true
+	case *FromImportNode:
+		for _, macro := range n.macros {
+			a.macroNames[macro] = true
+			if alias, ok := n.aliases[macro]; ok {
+				a.macroNames[alias] = true
+			}
+		}
+	case *ApplyNode:
+		a.filters[n.filter] = true
+	case *FilterNode:
+		a.filters[n.filter] = true
+	case *FunctionNode:
+		// module.macro() calls are resolved through the module variable
+		if n.moduleExpr == nil {
+			a.functions[n.name] = true
+			if n.name == "include" {
+				a.includes++
+			}
+		}
+	case *VariableNode:
+		a.variables[n.name] = true
+	case *GetAttrNode:
+		// The attribute is a literal name, not a variable reference
+		Walk(n.node, a)
+		return false
+	case *TestNode:
+		// defined tests are how templates guard optional variables
+		if n.test == "defined" || n.test == "not defined" {
+			if v, ok := n.node.(*VariableNode); ok {
+				a.defined[v.name] = true
+			}
+		}
+	}
+
+	return true
+}
+
+// Leave tracks loop nesting
+func (a *analyzer) Leave(node Node) {
+	if _, ok := node.(*ForNode); ok {
+		a.depth--
+	}
+}
+
+// sortedSet returns the names in a set that pass keep, sorted
+func sortedSet(set map[string]bool, keep func(string) bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		if name != "" && (keep == nil || keep(name)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
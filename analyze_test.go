@@ -0,0 +1,167 @@
+package twig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		check  func(t *testing.T, report *TemplateReport)
+	}{
+		{
+			name:   "loop depth",
+			source: `{% for row in rows %}{% for cell in row %}{{ cell }}{% endfor %}{% endfor %}{% for x in other %}{% endfor %}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				if report.MaxLoopDepth != 2 {
+					t.Errorf("Expected loop depth 2, got %d", report.MaxLoopDepth)
+				}
+			},
+		},
+		{
+			name:   "includes",
+			source: `{% include "a.twig" %}{{ include("b.twig") }}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				if report.Includes != 2 {
+					t.Errorf("Expected 2 includes, got %d", report.Includes)
+				}
+			},
+		},
+		{
+			name:   "filters and functions",
+			source: `{{ name|upper|trim }}{{ range(1, 3)|join(",") }}{{ name|shout }}{{ frobnicate() }}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				assertNames(t, "filters", report.Filters, []string{"join", "shout", "trim", "upper"})
+				assertNames(t, "functions", report.Functions, []string{"frobnicate", "range"})
+				assertNames(t, "unknown filters", report.UnknownFilters, []string{"shout"})
+				assertNames(t, "unknown functions", report.UnknownFunctions, []string{"frobnicate"})
+			},
+		},
+		{
+			name:   "unknown identifiers",
+			source: `{% set title = "x" %}{{ title }}{% for item in items %}{{ item.name }}{{ loop.index }}{% endfor %}{{ site_name }}{{ user.name }}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				assertNames(t, "unknown identifiers", report.UnknownIdentifiers, []string{"items", "user"})
+			},
+		},
+		{
+			name:   "macros and imports",
+			source: `{% macro field(label) %}{{ label }}{% endmacro %}{% import "forms.twig" as forms %}{{ forms.input("q") }}{{ _self.field("x") }}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				assertNames(t, "unknown identifiers", report.UnknownIdentifiers, []string{})
+				assertNames(t, "unknown functions", report.UnknownFunctions, []string{})
+			},
+		},
+		{
+			name: "macro docs",
+			source: "{# @doc Renders a text input #}\n{% macro input(name, value) %}{% endmacro %}" +
+				"{# @doc dropped by the print #}{{ x }}{% macro plain() %}{% endmacro %}" +
+				"{#- @doc\n  Renders a button\n-#}{% macro button(label) %}{% endmacro %}",
+			check: func(t *testing.T, report *TemplateReport) {
+				expected := []MacroInfo{
+					{Name: "input", Params: []string{"name", "value"}, Doc: "Renders a text input"},
+					{Name: "plain", Params: nil, Doc: ""},
+					{Name: "button", Params: []string{"label"}, Doc: "Renders a button"},
+				}
+				if len(report.Macros) != len(expected) {
+					t.Fatalf("Expected %d macros, got %+v", len(expected), report.Macros)
+				}
+				for i, macro := range report.Macros {
+					if macro.Name != expected[i].Name || macro.Doc != expected[i].Doc ||
+						len(macro.Params) != len(expected[i].Params) {
+						t.Errorf("Expected macro %+v, got %+v", expected[i], macro)
+					}
+				}
+			},
+		},
+		{
+			name:   "inheritance",
+			source: `{% extends "base.twig" %}{% block title %}Home{% endblock %}{% block body %}{% endblock %}`,
+			check: func(t *testing.T, report *TemplateReport) {
+				if report.Parent != "base.twig" {
+					t.Errorf("Expected parent base.twig, got %q", report.Parent)
+				}
+				assertNames(t, "blocks", report.Blocks, []string{"title", "body"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.AddGlobal("site_name", "Example")
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			report, err := engine.Analyze("test")
+			if err != nil {
+				t.Fatalf("Error analyzing template: %v", err)
+			}
+			if report.Name != "test" || report.Nodes == 0 {
+				t.Errorf("Unexpected report header: %+v", report)
+			}
+			tt.check(t, report)
+		})
+	}
+}
+
+func TestAnalyzeMissingTemplate(t *testing.T) {
+	engine := New()
+	if _, err := engine.Analyze("missing.twig"); err == nil {
+		t.Error("Expected an error for a missing template")
+	}
+}
+
+func TestAnalyzeUnknownBlocks(t *testing.T) {
+	engine := New()
+	templates := map[string]string{
+		"base.twig":    `<title>{% block title %}{% endblock %}</title>{% block body %}{% block content %}{% endblock %}{% endblock %}`,
+		"layout.twig":  `{% extends 'base.twig' %}{% block body %}<main>{% block main %}{% endblock %}</main>{% endblock %}`,
+		"page.twig":    `{% extends 'layout.twig' %}{% block title %}T{% endblock %}{% block main %}M{% endblock %}{% block contnet %}C{% endblock %}`,
+		"dynamic.twig": `{% extends layout %}{% block anything %}{% endblock %}`,
+		"embed.twig":   `{% extends 'base.twig' %}{% block title %}{% embed 'base.twig' %}{% block extra %}{% endblock %}{% endembed %}{% endblock %}`,
+		"missing.twig": `{% extends 'nowhere.twig' %}{% block typo %}{% endblock %}`,
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{"page.twig", []string{"contnet"}},
+		{"layout.twig", nil},
+		{"base.twig", nil},
+		{"dynamic.twig", nil},
+		{"embed.twig", nil},
+		{"missing.twig", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := engine.Analyze(tt.name)
+			if err != nil {
+				t.Fatalf("Error analyzing template: %v", err)
+			}
+			if !reflect.DeepEqual(report.UnknownBlocks, tt.expected) {
+				t.Errorf("Expected unknown blocks %v, got %v", tt.expected, report.UnknownBlocks)
+			}
+		})
+	}
+}
+
+func assertNames(t *testing.T, what string, got, expected []string) {
+	t.Helper()
+	if len(got) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %s %v, got %v", what, expected, got)
+	}
+}
@@ -0,0 +1,227 @@
+package twig
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AppRequest gives templates read access to the current request as
+// app.request. NewAppRequest adapts a *http.Request.
+type AppRequest interface {
+	Method() string
+	URI() string  // Path and query string
+	Path() string // Path without the query string
+	Host() string
+	Scheme() string
+	Query(name string) string
+	Header(name string) string
+	Cookie(name string) string
+}
+
+// httpRequest adapts *http.Request to AppRequest
+type httpRequest struct {
+	r *http.Request
+}
+
+// NewAppRequest wraps an HTTP request for use as app.request
+func NewAppRequest(r *http.Request) AppRequest {
+	return httpRequest{r: r}
+}
+
+func (h httpRequest) Method() string { return h.r.Method }
+func (h httpRequest) URI() string    { return h.r.URL.RequestURI() }
+func (h httpRequest) Path() string   { return h.r.URL.Path }
+func (h httpRequest) Host() string   { return h.r.Host }
+
+func (h httpRequest) Scheme() string {
+	if h.r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func (h httpRequest) Query(name string) string  { return h.r.URL.Query().Get(name) }
+func (h httpRequest) Header(name string) string { return h.r.Header.Get(name) }
+
+func (h httpRequest) Cookie(name string) string {
+	cookie, err := h.r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// AppConfig holds the application settings exposed through app
+type AppConfig struct {
+	Environment   string   // Application environment, e.g. "prod" or "dev"
+	Locales       []string // Supported locales, negotiated from Accept-Language
+	DefaultLocale string   // Locale used when negotiation finds no match
+}
+
+// AppGlobals is the standard app variable: app.environment, app.debug,
+// app.locale, app.user and app.request.uri and friends, as Symfony
+// templates expect
+type AppGlobals struct {
+	Environment string
+	Debug       bool
+	Locale      string
+	User        interface{}
+	Request     AppRequest
+}
+
+// Vars returns the globals as template values. Request accessors taking a
+// name are exposed as functions: app.request.query('page').
+func (a *AppGlobals) Vars() map[string]interface{} {
+	vars := map[string]interface{}{
+		"environment": a.Environment,
+		"debug":       a.Debug,
+		"locale":      a.Locale,
+		"user":        a.User,
+		"request":     nil,
+	}
+
+	if r := a.Request; r != nil {
+		lookup := func(get func(string) string) func(...interface{}) (interface{}, error) {
+			return func(args ...interface{}) (interface{}, error) {
+				if len(args) == 0 {
+					return "", nil
+				}
+				return get(toString(args[0])), nil
+			}
+		}
+
+		vars["request"] = map[string]interface{}{
+			"method": r.Method(),
+			"uri":    r.URI(),
+			"path":   r.Path(),
+			"host":   r.Host(),
+			"scheme": r.Scheme(),
+			"query":  lookup(r.Query),
+			"header": lookup(r.Header),
+			"cookie": lookup(r.Cookie),
+		}
+	}
+
+	return vars
+}
+
+// NewAppGlobals builds the app variable for a request using the engine's
+// app configuration and debug setting
+func (e *Engine) NewAppGlobals(r *http.Request) *AppGlobals {
+	config := e.environment.appConfig
+
+	app := &AppGlobals{
+		Environment: config.Environment,
+		Debug:       e.environment.debug,
+		Locale:      config.DefaultLocale,
+	}
+	if r != nil {
+		app.Request = NewAppRequest(r)
+		if locale := negotiateLocale(r.Header.Get("Accept-Language"), config.Locales); locale != "" {
+			app.Locale = locale
+		}
+	}
+	return app
+}
+
+// RenderHTTP renders a template as the response to r, injecting app unless
+// the context already defines it. The output is buffered so a failed render
+// does not send a partial page.
+func (e *Engine) RenderHTTP(w http.ResponseWriter, r *http.Request, name string, context map[string]interface{}) error {
+	vars := make(map[string]interface{}, len(context)+1)
+	for k, v := range context {
+		vars[k] = v
+	}
+
+	app := e.NewAppGlobals(r)
+	if _, ok := vars["app"]; !ok {
+		vars["app"] = app.Vars()
+	}
+
+	// The nonce is chosen up front so the header matches csp_nonce()
+	policy := e.environment.cspPolicy
+	if policy != "" {
+		nonce, ok := vars[CSPNonceVar].(string)
+		if !ok || nonce == "" {
+			var err error
+			if nonce, err = e.environment.newNonce(); err != nil {
+				return err
+			}
+			vars[CSPNonceVar] = nonce
+		}
+		policy = e.environment.cspHeader(nonce)
+	}
+
+	var buf bytes.Buffer
+	if panel := DebugPanelFrom(r); panel != nil {
+		if err := e.renderHTTPProfiled(&buf, name, vars, panel); err != nil {
+			return err
+		}
+	} else if err := e.RenderTo(&buf, name, vars); err != nil {
+		return err
+	}
+
+	header := w.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "text/html; charset=utf-8")
+	}
+	if app.Locale != "" && header.Get("Content-Language") == "" {
+		header.Set("Content-Language", app.Locale)
+	}
+	if len(e.environment.appConfig.Locales) > 0 {
+		header.Add("Vary", "Accept-Language")
+	}
+	if policy != "" && header.Get("Content-Security-Policy") == "" {
+		header.Set("Content-Security-Policy", policy)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// negotiateLocale picks the supported locale best matching an
+// Accept-Language header, preferring exact matches over language matches
+func negotiateLocale(acceptLanguage string, supported []string) string {
+	if acceptLanguage == "" || len(supported) == 0 {
+		return ""
+	}
+
+	type preference struct {
+		tag string
+		q   float64
+	}
+
+	var prefs []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				q = f
+			}
+		}
+		if tag != "" && q > 0 {
+			prefs = append(prefs, preference{tag: strings.ReplaceAll(tag, "_", "-"), q: q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	for _, pref := range prefs {
+		for _, locale := range supported {
+			if strings.EqualFold(strings.ReplaceAll(locale, "_", "-"), pref.tag) {
+				return locale
+			}
+		}
+		language, _, _ := strings.Cut(pref.tag, "-")
+		for _, locale := range supported {
+			supportedLanguage, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+			if strings.EqualFold(supportedLanguage, language) {
+				return locale
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package twig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRenderHTTPAppGlobals(t *testing.T) {
+	engine := New()
+	engine.SetAppConfig(AppConfig{
+		Environment:   "prod",
+		Locales:       []string{"en", "de_DE", "fr"},
+		DefaultLocale: "en",
+	})
+
+	source := `{{ app.environment }} {{ app.debug ? "debug" : "nodebug" }} {{ app.locale }} ` +
+		`{{ app.request.method }} {{ app.request.uri }} {{ app.request.path }} {{ app.request.query('page') }} ` +
+		`{{ app.request.header('X-Trace') }} {{ app.request.cookie('theme') }} {{ app.user.name }}`
+	if err := engine.RegisterString("page", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	r := httptest.NewRequest("GET", "http://example.com/posts?page=2", nil)
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	r.Header.Set("X-Trace", "abc")
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	w := httptest.NewRecorder()
+
+	if err := engine.RenderHTTP(w, r, "page", map[string]interface{}{}); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	expected := "prod nodebug de_DE GET /posts?page=2 /posts 2 abc dark "
+	if w.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Expected an HTML content type, got %q", ct)
+	}
+	if cl := w.Header().Get("Content-Language"); cl != "de_DE" {
+		t.Errorf("Expected Content-Language de_DE, got %q", cl)
+	}
+}
+
+func TestRenderHTTPKeepsContextApp(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", `{{ app }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	if err := engine.RenderHTTP(w, r, "page", map[string]interface{}{"app": "custom"}); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if w.Body.String() != "custom" {
+		t.Errorf("Expected the context app to win, got %q", w.Body.String())
+	}
+}
+
+func TestRenderHTTPErrorWritesNothing(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", `partial{{ missing() }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	if err := engine.RenderHTTP(w, httptest.NewRequest("GET", "/", nil), "page", nil); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("Expected no output after an error, got %q", w.Body.String())
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	supported := []string{"en_US", "de", "pt-BR"}
+
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"de", "de"},
+		{"de-AT", "de"},
+		{"EN-us", "en_US"},
+		{"fr, pt-br;q=0.5, de;q=0.7", "de"},
+		{"fr;q=1, ja", ""},
+		{"de;q=0, en", "en_US"},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateLocale(tt.header, supported); got != tt.expected {
+			t.Errorf("negotiateLocale(%q) = %q, want %q", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestAppGlobalsWithoutRequest(t *testing.T) {
+	engine := New()
+	engine.SetDebug(true)
+	defer engine.SetDebug(false)
+	engine.SetAppConfig(AppConfig{Environment: "dev", DefaultLocale: "en"})
+
+	app := engine.NewAppGlobals(nil)
+	app.User = map[string]interface{}{"name": "Ada"}
+
+	if err := engine.RegisterString("page", `{{ app.environment }} {{ app.debug ? "debug" : "" }} {{ app.locale }} {{ app.user.name }}{{ app.request.uri }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", map[string]interface{}{"app": app.Vars()})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "dev debug en Ada" {
+		t.Errorf("Expected %q, got %q", "dev debug en Ada", result)
+	}
+}
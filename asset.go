@@ -0,0 +1,138 @@
+package twig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// AssetResolver maps an asset path used in a template to its public URL
+type AssetResolver interface {
+	ResolveAsset(path string) (string, error)
+}
+
+// AssetResolverFunc adapts a function to the AssetResolver interface
+type AssetResolverFunc func(path string) (string, error)
+
+// ResolveAsset calls f(path)
+func (f AssetResolverFunc) ResolveAsset(path string) (string, error) {
+	return f(path)
+}
+
+// joinAssetURL prefixes an asset path with a base URL or path
+func joinAssetURL(base, assetPath string) string {
+	if base == "" {
+		return assetPath
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(assetPath, "/")
+}
+
+// ManifestResolver resolves assets through a build manifest, such as the
+// manifest.json written by webpack or Vite. Paths missing from the manifest
+// are returned unchanged under the base URL.
+type ManifestResolver struct {
+	BaseURL  string            // Prefix for resolved paths, e.g. "/build"
+	Manifest map[string]string // Source path to built file
+}
+
+// LoadManifestResolver reads a JSON manifest file. Both flat manifests
+// ({"app.js": "app.3f2a.js"}) and Vite manifests ({"app.js": {"file": ...}})
+// are supported.
+func LoadManifestResolver(manifestPath, baseURL string) (*ManifestResolver, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading asset manifest: %w", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing asset manifest %s: %w", manifestPath, err)
+	}
+
+	manifest := make(map[string]string, len(raw))
+	for name, entry := range raw {
+		switch v := entry.(type) {
+		case string:
+			manifest[name] = v
+		case map[string]interface{}:
+			if file, ok := v["file"].(string); ok {
+				manifest[name] = file
+			}
+		}
+	}
+
+	return &ManifestResolver{BaseURL: baseURL, Manifest: manifest}, nil
+}
+
+// ResolveAsset returns the built file for path under the base URL
+func (r *ManifestResolver) ResolveAsset(assetPath string) (string, error) {
+	key := strings.TrimLeft(assetPath, "/")
+	if file, ok := r.Manifest[key]; ok {
+		return joinAssetURL(r.BaseURL, file), nil
+	}
+	if file, ok := r.Manifest[assetPath]; ok {
+		return joinAssetURL(r.BaseURL, file), nil
+	}
+	return joinAssetURL(r.BaseURL, assetPath), nil
+}
+
+// HashResolver appends a content hash of the asset file as a cache-busting
+// query string, e.g. /static/app.css?v=3f2a9c1b. Hashes are computed once per
+// path; missing files are returned without a version.
+type HashResolver struct {
+	Root    string // Directory containing the asset files
+	BaseURL string // Prefix for resolved paths, e.g. "/static"
+
+	hashes sync.Map // Asset path to hash
+}
+
+// NewHashResolver creates a resolver hashing files below root
+func NewHashResolver(root, baseURL string) *HashResolver {
+	return &HashResolver{Root: root, BaseURL: baseURL}
+}
+
+// ResolveAsset returns the asset URL with its content hash appended
+func (r *HashResolver) ResolveAsset(assetPath string) (string, error) {
+	url := joinAssetURL(r.BaseURL, assetPath)
+
+	if hash, ok := r.hashes.Load(assetPath); ok {
+		return url + "?v=" + hash.(string), nil
+	}
+
+	// Clean the path so templates cannot read files outside the root
+	cleaned := path.Clean("/" + assetPath)
+	data, err := os.ReadFile(filepath.Join(r.Root, filepath.FromSlash(cleaned)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return url, nil
+		}
+		return "", fmt.Errorf("hashing asset %s: %w", assetPath, err)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:4])
+	r.hashes.Store(assetPath, hash)
+
+	return url + "?v=" + hash, nil
+}
+
+// functionAsset resolves an asset path with the engine's asset resolver,
+// returning the path unchanged when none is configured
+func (e *CoreExtension) functionAsset(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("asset function requires a path argument")
+	}
+
+	assetPath := e.toString(args[0])
+	if e.env == nil || e.env.assetResolver == nil {
+		return assetPath, nil
+	}
+
+	return e.env.assetResolver.ResolveAsset(assetPath)
+}
@@ -0,0 +1,91 @@
+package twig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAssetFunction(t *testing.T) {
+	dir := t.TempDir()
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	os.WriteFile(manifestPath, []byte(`{
+		"app.js": "app.3f2a9c.js",
+		"src/main.ts": {"file": "assets/main.4889e940.js", "isEntry": true}
+	}`), 0o644)
+
+	os.MkdirAll(filepath.Join(dir, "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "css", "site.css"), []byte("body{}"), 0o644)
+
+	manifest, err := LoadManifestResolver(manifestPath, "/build/")
+	if err != nil {
+		t.Fatalf("Error loading manifest: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		resolver AssetResolver
+		source   string
+		expected string
+	}{
+		{"No resolver", nil, "{{ asset('css/site.css') }}", "css/site.css"},
+		{"Flat manifest", manifest, "{{ asset('app.js') }}", "/build/app.3f2a9c.js"},
+		{"Vite manifest", manifest, "{{ asset('/src/main.ts') }}", "/build/assets/main.4889e940.js"},
+		{"Missing from manifest", manifest, "{{ asset('other.js') }}", "/build/other.js"},
+		{"Content hash", NewHashResolver(dir, "/static"), "{{ asset('css/site.css') }}", "/static/css/site.css?v=7c98040a"},
+		{"Missing file", NewHashResolver(dir, "/static"), "{{ asset('missing.css') }}", "/static/missing.css"},
+		{"Function resolver", AssetResolverFunc(func(p string) (string, error) {
+			return "https://cdn.example.com/" + strings.TrimLeft(p, "/"), nil
+		}), "{{ asset('/img/logo.png') }}", "https://cdn.example.com/img/logo.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if tt.resolver != nil {
+				engine.SetAssetResolver(tt.resolver)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Hash resolver stays inside root", func(t *testing.T) {
+		outside := filepath.Join(filepath.Dir(dir), "secret.txt")
+		os.WriteFile(outside, []byte("secret"), 0o644)
+		defer os.Remove(outside)
+
+		resolver := NewHashResolver(dir, "")
+		url, err := resolver.ResolveAsset("../secret.txt")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if strings.Contains(url, "?v=") {
+			t.Errorf("Expected no hash for a path outside the root, got %q", url)
+		}
+	})
+
+	t.Run("Resolver error", func(t *testing.T) {
+		engine := New()
+		engine.SetAssetResolver(AssetResolverFunc(func(string) (string, error) {
+			return "", errors.New("unknown asset")
+		}))
+		engine.RegisterString("test", "{{ asset('x') }}")
+
+		if _, err := engine.Render("test", nil); err == nil || !strings.Contains(err.Error(), "unknown asset") {
+			t.Errorf("Expected resolver error, got %v", err)
+		}
+	})
+}
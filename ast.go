@@ -0,0 +1,177 @@
+package twig
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/semihalev/twig/ast"
+)
+
+// ASTJSON returns the parse tree of the template as indented JSON, for
+// debugging the parser. Each node has its type, line and key fields; the
+// format is not stable across versions.
+func (t *Template) ASTJSON() ([]byte, error) {
+	return json.MarshalIndent(astNode(t.nodes), "", "  ")
+}
+
+// AST returns the parse tree of the template in the stable form of the ast
+// package, for tools that inspect templates
+func (t *Template) AST() *ast.Node {
+	return publicNode(t.nodes)
+}
+
+// SetRetainComments makes the engine keep comments in the templates it
+// parses after the call, as CommentNodes with their content, for tools such
+// as formatters and documentation extractors that walk the tree. Comments
+// are dropped by default; either way they render nothing.
+func (e *Engine) SetRetainComments(retain bool) {
+	e.retainComments = retain
+}
+
+// astNode describes a node and its children as JSON-friendly values
+func astNode(node Node) map[string]interface{} {
+	if node == nil {
+		return nil
+	}
+
+	out := map[string]interface{}{
+		"type": astTypeName(node),
+		"line": node.Line(),
+	}
+
+	for key, value := range astAttrs(node) {
+		out[key] = value
+	}
+
+	switch n := node.(type) {
+	// Show which children are conditions and which are bodies
+	case *IfNode:
+		branches := make([]map[string]interface{}, len(n.conditions))
+		for i, condition := range n.conditions {
+			branch := map[string]interface{}{"condition": astNode(condition)}
+			if i < len(n.bodies) {
+				branch["body"] = astNodes(n.bodies[i])
+			}
+			branches[i] = branch
+		}
+		out["branches"] = branches
+		if n.elseBranch != nil {
+			out["else"] = astNodes(n.elseBranch)
+		}
+		return out
+	case *ForNode:
+		out["sequence"] = astNode(n.sequence)
+		out["body"] = astNodes(n.body)
+		if n.elseBranch != nil {
+			out["else"] = astNodes(n.elseBranch)
+		}
+		return out
+	}
+
+	if children := childNodes(node); len(children) > 0 {
+		out["children"] = astNodes(children)
+	}
+	return out
+}
+
+// astAttrs returns the key fields of a node, shared by ASTJSON and AST
+func astAttrs(node Node) map[string]interface{} {
+	attrs := make(map[string]interface{})
+	switch n := node.(type) {
+	case *TextNode:
+		attrs["content"] = n.content
+	case *CommentNode:
+		attrs["content"] = n.content
+	case *VerbatimNode:
+		attrs["content"] = n.content
+	case *LiteralNode:
+		attrs["value"] = n.value
+	case *VariableNode:
+		attrs["name"] = n.name
+	case *UnaryNode:
+		attrs["operator"] = n.operator
+	case *BinaryNode:
+		attrs["operator"] = n.operator
+	case *FunctionNode:
+		attrs["name"] = n.name
+	case *FilterNode:
+		attrs["filter"] = n.filter
+	case *TestNode:
+		attrs["test"] = n.test
+	case *BlockNode:
+		attrs["name"] = n.name
+	case *MacroNode:
+		attrs["name"] = n.name
+		attrs["params"] = n.params
+		if n.doc != "" {
+			attrs["doc"] = n.doc
+		}
+	case *SetNode:
+		attrs["name"] = n.name
+	case *ApplyNode:
+		attrs["filter"] = n.filter
+	case *ImportNode:
+		attrs["module"] = n.module
+	case *FromImportNode:
+		attrs["macros"] = n.macros
+		if len(n.aliases) > 0 {
+			attrs["aliases"] = n.aliases
+		}
+	case *IncludeNode:
+		attrs["ignore_missing"] = n.ignoreMissing
+		attrs["only"] = n.only
+		attrs["sandboxed"] = n.sandboxed
+	case *EmbedNode:
+		attrs["ignore_missing"] = n.ignoreMissing
+		attrs["only"] = n.only
+		attrs["sandboxed"] = n.sandboxed
+	case *WithNode:
+		attrs["only"] = n.only
+	case *ForNode:
+		attrs["key"] = n.keyVar
+		attrs["value"] = n.valueVar
+		if n.targets != nil {
+			attrs["targets"] = n.targets
+		}
+	}
+	return attrs
+}
+
+// publicNode converts a node and its children to the ast package's form
+func publicNode(node Node) *ast.Node {
+	if node == nil {
+		return nil
+	}
+
+	out := &ast.Node{
+		Type:  astTypeName(node),
+		Line:  node.Line(),
+		Attrs: astAttrs(node),
+	}
+	for _, child := range childNodes(node) {
+		out.Children = append(out.Children, publicNode(child))
+	}
+	return out
+}
+
+// astNodes describes a list of nodes
+func astNodes(nodes []Node) []map[string]interface{} {
+	out := make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		if node != nil {
+			out = append(out, astNode(node))
+		}
+	}
+	return out
+}
+
+// astTypeName returns the node's type without the package and Node suffix,
+// e.g. "For" for *ForNode
+func astTypeName(node Node) string {
+	t := reflect.TypeOf(node)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimSuffix(t.Name(), "Node")
+}
@@ -0,0 +1,53 @@
+// Package ast describes parsed templates as plain data for tools such as
+// linters, formatters and documentation extractors. Unlike the engine's
+// node types, which change with the parser, these types are part of the
+// stable API. Get the tree of a template with twig's Template.AST.
+package ast
+
+// Node is one node of a template's tree
+type Node struct {
+	// Type is the kind of node without the Node suffix, e.g. "For", "If",
+	// "Variable" or "Text"
+	Type string
+
+	// Line is the line of the template the node starts on
+	Line int
+
+	// Attrs holds the node's key fields, such as the name of a variable or
+	// block, the operator of an expression or the content of text
+	Attrs map[string]interface{}
+
+	// Children are the nodes below this one, in source order
+	Children []*Node
+}
+
+// Attr returns the attribute named key, or nil when the node has none
+func (n *Node) Attr(key string) interface{} {
+	if n == nil {
+		return nil
+	}
+	return n.Attrs[key]
+}
+
+// Walk calls fn for node and each node below it, depth first in source
+// order. Returning false from fn skips the children of that node.
+func Walk(node *Node, fn func(*Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range node.Children {
+		Walk(child, fn)
+	}
+}
+
+// Find returns the nodes of the given type in the tree, in source order
+func Find(node *Node, nodeType string) []*Node {
+	var found []*Node
+	Walk(node, func(n *Node) bool {
+		if n.Type == nodeType {
+			found = append(found, n)
+		}
+		return true
+	})
+	return found
+}
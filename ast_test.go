@@ -0,0 +1,142 @@
+package twig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/semihalev/twig/ast"
+)
+
+func TestTemplateASTJSON(t *testing.T) {
+	engine := New()
+	source := "{% if user %}\n{% for item in items|sort %}{{ item.name|upper }}{% endfor %}\n{% else %}none{% endif %}"
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, err := engine.Load("test")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+
+	data, err := template.ASTJSON()
+	if err != nil {
+		t.Fatalf("Error encoding AST: %v", err)
+	}
+
+	var tree map[string]interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatalf("Invalid JSON: %v\n%s", err, data)
+	}
+
+	if tree["type"] != "Root" {
+		t.Fatalf("Expected a Root node, got %v", tree["type"])
+	}
+
+	ifNode := tree["children"].([]interface{})[0].(map[string]interface{})
+	if ifNode["type"] != "If" {
+		t.Fatalf("Expected an If node, got %v", ifNode["type"])
+	}
+	branch := ifNode["branches"].([]interface{})[0].(map[string]interface{})
+	if condition := branch["condition"].(map[string]interface{}); condition["name"] != "user" {
+		t.Errorf("Expected the condition to reference user, got %v", condition)
+	}
+
+	var forNode map[string]interface{}
+	for _, child := range branch["body"].([]interface{}) {
+		if node := child.(map[string]interface{}); node["type"] == "For" {
+			forNode = node
+		}
+	}
+	if forNode == nil {
+		t.Fatalf("Expected a For node in the if body:\n%s", data)
+	}
+	if forNode["value"] != "item" || forNode["line"] != float64(2) {
+		t.Errorf("Unexpected For node: %v", forNode)
+	}
+	if sequence := forNode["sequence"].(map[string]interface{}); sequence["type"] != "Filter" || sequence["filter"] != "sort" {
+		t.Errorf("Expected the sequence to be the sort filter, got %v", sequence)
+	}
+
+	if elseBody := ifNode["else"].([]interface{}); len(elseBody) != 1 {
+		t.Errorf("Expected one else node, got %v", elseBody)
+	}
+}
+
+func TestRetainComments(t *testing.T) {
+	source := `{# Card component #}<div>{{ title }}{#- trailing -#}</div>`
+
+	engine := New()
+	engine.SetRetainComments(true)
+	if err := engine.RegisterString("card", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, err := engine.Load("card")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+
+	var comments []string
+	for _, node := range template.nodes.(*RootNode).Children() {
+		if comment, ok := node.(*CommentNode); ok {
+			comments = append(comments, comment.Content())
+		}
+	}
+	if len(comments) != 2 || comments[0] != " Card component " || comments[1] != " trailing " {
+		t.Errorf("Expected both comments to be kept, got %q", comments)
+	}
+
+	result, err := engine.Render("card", map[string]interface{}{"title": "Hi"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "<div>Hi</div>" {
+		t.Errorf("Expected comments to render nothing, got %q", result)
+	}
+
+	// The same source parsed without the mode has no comments
+	other := New()
+	if err := other.RegisterString("card", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, err = other.Load("card")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+	for _, node := range template.nodes.(*RootNode).Children() {
+		if _, ok := node.(*CommentNode); ok {
+			t.Errorf("Expected comments to be dropped by default")
+		}
+	}
+}
+
+func TestTemplateAST(t *testing.T) {
+	engine := New()
+	source := "{% block body %}\n{% for item in items %}{{ item.name|upper }}{% endfor %}\n{% endblock %}"
+	template, err := engine.ParseTemplate(source)
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	tree := template.AST()
+	if tree.Type != "Root" {
+		t.Fatalf("Expected a Root node, got %q", tree.Type)
+	}
+
+	blocks := ast.Find(tree, "Block")
+	if len(blocks) != 1 || blocks[0].Attr("name") != "body" {
+		t.Fatalf("Expected the body block, got %v", blocks)
+	}
+
+	loops := ast.Find(tree, "For")
+	if len(loops) != 1 {
+		t.Fatalf("Expected one loop, got %d", len(loops))
+	}
+	if loops[0].Attr("value") != "item" || loops[0].Line != 2 {
+		t.Errorf("Expected the loop over item on line 2, got %+v", loops[0])
+	}
+
+	filters := ast.Find(tree, "Filter")
+	if len(filters) != 1 || filters[0].Attr("filter") != "upper" {
+		t.Errorf("Expected the upper filter, got %v", filters)
+	}
+}
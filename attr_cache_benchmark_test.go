@@ -152,3 +152,19 @@ func TestAttributeCacheLRUEviction(t *testing.T) {
 		}
 	}
 }
+
+// Benchmark attribute access on common map types
+func BenchmarkAttributeCache_Maps(b *testing.B) {
+	ctx := NewRenderContext(nil, nil, nil)
+	defer ctx.Release()
+
+	labels := map[string]string{"title": "Home", "lang": "en"}
+	counts := map[string]int{"posts": 12, "pages": 3}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = ctx.getAttribute(labels, "title")
+		_, _ = ctx.getAttribute(counts, "posts")
+		_, _ = ctx.getItem(labels, "lang")
+	}
+}
@@ -0,0 +1,136 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// maxBatchSize caps the size of a batch, since the last row is padded up to
+// it when a fill value is given
+const maxBatchSize = 1 << 16
+
+// filterBatch implements items|batch(size, fill) for grid layouts, also
+// written {% for row in items in groups of size %}. It splits a sequence
+// into rows of size items, each a []interface{} whatever the type of the
+// sequence, and pads the last row with fill when fill is given. Mappings
+// are split into mappings that keep the keys in order, sorted for Go maps;
+// they are never padded.
+func (e *CoreExtension) filterBatch(value interface{}, args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("batch filter requires a size")
+	}
+	size, err := toInt(args[0])
+	if err != nil || size < 1 {
+		return nil, fmt.Errorf("batch size must be a positive integer, got %v", args[0])
+	}
+	if size > maxBatchSize {
+		return nil, fmt.Errorf("batch size %d exceeds the limit of %d", size, maxBatchSize)
+	}
+	if value == nil {
+		return []interface{}{}, nil
+	}
+
+	if m, ok := batchMapping(value); ok {
+		rows := make([]interface{}, 0, (len(m.keys)+size-1)/size)
+		for start := 0; start < len(m.keys); start += size {
+			end := min(start+size, len(m.keys))
+			row := &sortedMapping{keys: m.keys[start:end:end], values: make(map[string]interface{}, end-start)}
+			for _, key := range row.keys {
+				row.values[key] = m.values[key]
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	items, err := batchItems(value)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]interface{}, 0, (len(items)+size-1)/size)
+	for start := 0; start < len(items); start += size {
+		end := min(start+size, len(items))
+		row := make([]interface{}, end-start)
+		copy(row, items[start:end])
+		rows = append(rows, row)
+	}
+
+	// Pad the last row
+	if len(args) > 1 && args[1] != nil && len(rows) > 0 {
+		last := rows[len(rows)-1].([]interface{})
+		if len(last) < size {
+			padded := make([]interface{}, size)
+			copy(padded, last)
+			for i := len(last); i < size; i++ {
+				padded[i] = args[1]
+			}
+			rows[len(rows)-1] = padded
+		}
+	}
+	return rows, nil
+}
+
+// batchMapping returns the keys and values of a mapping, in the order of a
+// Keyer or sorted for a Go map
+func batchMapping(value interface{}) (*sortedMapping, bool) {
+	if keyer, ok := value.(Keyer); ok {
+		m := &sortedMapping{keys: keyer.Keys(), values: make(map[string]interface{})}
+		for _, key := range m.keys {
+			m.values[key], _ = keyer.GetAttr(key)
+		}
+		return m, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map {
+		return nil, false
+	}
+	m := &sortedMapping{values: make(map[string]interface{}, rv.Len())}
+	iter := rv.MapRange()
+	for iter.Next() {
+		key := toString(iter.Key().Interface())
+		m.keys = append(m.keys, key)
+		m.values[key] = iter.Value().Interface()
+	}
+	sort.Strings(m.keys)
+	return m, true
+}
+
+// batchItems returns the items of a sequence
+func batchItems(value interface{}) ([]interface{}, error) {
+	if indexer, ok := value.(Indexer); ok {
+		items := make([]interface{}, indexer.Len())
+		for i := range items {
+			items[i] = indexer.At(i)
+		}
+		return items, nil
+	}
+
+	if each, ok := iterateFunc(value); ok {
+		var items []interface{}
+		each(func(_, value interface{}) bool {
+			items = append(items, value)
+			return true
+		})
+		return items, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = rv.Index(i).Interface()
+		}
+		return items, nil
+	case reflect.String:
+		var items []interface{}
+		for _, r := range rv.String() {
+			items = append(items, string(r))
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("cannot batch %T", value)
+}
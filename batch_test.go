@@ -0,0 +1,71 @@
+package twig
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	engine := New()
+
+	grid := "{% for row in items in groups of 3 fill '-' %}[{% for cell in row %}{{ cell }}{% endfor %}]{% endfor %}"
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{"groups of", grid, map[string]interface{}{"items": []int{1, 2, 3, 4}}, "[123][4--]"},
+		{"typed slice", "{% for row in items|batch(2) %}{{ row|join(',') }};{% endfor %}", map[string]interface{}{"items": []string{"a", "b", "c"}}, "a,b;c;"},
+		{"iterator", "{% for row in items in groups of 2 %}{{ row|length }}{% endfor %}", map[string]interface{}{"items": slices.Values([]int{1, 2, 3})}, "21"},
+		{"filtered sequence", "{% for row in items|sort in groups of size %}{{ row|join('') }} {% endfor %}", map[string]interface{}{"items": []int{3, 1, 2}, "size": 2}, "12 3 "},
+		{"in inside the sequence", "{% for row in (1 in [1]) ? [1, 2] : [] in groups of 1 %}{{ row|join('') }}{% endfor %}", nil, "12"},
+		{"mapping", "{% for row in m|batch(2) %}{% for k, v in row %}{{ k }}={{ v }} {% endfor %}|{% endfor %}", map[string]interface{}{"m": map[string]int{"c": 3, "a": 1, "b": 2}}, "a=1 b=2 |c=3 |"},
+		{"empty", "{% for row in items in groups of 3 %}x{% else %}none{% endfor %}", map[string]interface{}{"items": []int{}}, "none"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := engine.ParseTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := template.Render(tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	template, err := engine.ParseTemplate("{{ [1]|batch(0) }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := template.Render(nil); err == nil {
+		t.Error("Expected an error for a batch size of 0")
+	}
+
+	// A size beyond the items must not allocate rows of that size
+	template, err = engine.ParseTemplate("{{ [1, 2]|batch(60000)|first|length }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := template.Render(nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "2" {
+		t.Errorf("Expected %q, got %q", "2", result)
+	}
+
+	template, err = engine.ParseTemplate("{{ [1, 2]|batch(1000000000000)|length }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := template.Render(nil); err == nil {
+		t.Error("Expected an error for a batch size over the limit")
+	}
+}
@@ -0,0 +1,228 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FragmentCache stores rendered fragments of templates, such as blocks
+// annotated with cache(...). Implementations must be safe for concurrent
+// use; a shared store such as Redis lets several servers reuse fragments.
+type FragmentCache interface {
+	Get(key string) (string, bool)
+	Set(key string, fragment string, ttl time.Duration)
+}
+
+// SetFragmentCache sets the store for cached blocks. Without one, the cache
+// annotation of blocks is ignored and they render every time.
+func (e *Engine) SetFragmentCache(cache FragmentCache) {
+	e.environment.fragmentCache = cache
+}
+
+// MemoryFragmentCache is a FragmentCache keeping fragments in memory until
+// they expire
+type MemoryFragmentCache struct {
+	mu        sync.Mutex
+	fragments map[string]memoryFragment
+	sweepAt   int // Number of fragments at which Set drops the expired ones
+}
+
+// minFragmentSweep is the fewest fragments a memory cache sweeps at
+const minFragmentSweep = 64
+
+// memoryFragment is a fragment and the time it expires
+type memoryFragment struct {
+	content string
+	expires time.Time
+}
+
+// NewMemoryFragmentCache creates an empty in-memory fragment cache
+func NewMemoryFragmentCache() *MemoryFragmentCache {
+	return &MemoryFragmentCache{fragments: make(map[string]memoryFragment)}
+}
+
+// Get returns the fragment stored under key if it has not expired
+func (c *MemoryFragmentCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fragment, ok := c.fragments[key]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(fragment.expires) {
+		delete(c.fragments, key)
+		return "", false
+	}
+	return fragment.content, true
+}
+
+// Set stores a fragment for ttl. Expired fragments are dropped whenever the
+// cache has doubled in size since the last sweep, so fragments that are
+// never read again do not pile up.
+func (c *MemoryFragmentCache) Set(key string, fragment string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.fragments) >= c.sweepAt {
+		for k, f := range c.fragments {
+			if now.After(f.expires) {
+				delete(c.fragments, k)
+			}
+		}
+		c.sweepAt = max(2*len(c.fragments), minFragmentSweep)
+	}
+	c.fragments[key] = memoryFragment{content: fragment, expires: now.Add(ttl)}
+}
+
+// blockCache is the cache(ttl, vary=[...]) annotation of a block
+type blockCache struct {
+	ttl  Node // Seconds to keep the output
+	vary Node // List of variable paths, such as 'user.role', the output depends on
+}
+
+// fragmentKey returns the cache key of a block: the scope it renders in,
+// the template providing its content, so theme and child overrides get their
+// own entries, the block name and the values of the vary paths
+func (c *blockCache) fragmentKey(ctx *RenderContext, name string) (string, error) {
+	owner := ctx.blockOwners[name]
+	if owner == nil {
+		owner = ctx.lastLoadedTemplate
+	}
+
+	var key strings.Builder
+	key.WriteString("block:")
+	key.WriteString(ctx.env.fragmentPrefix)
+	if owner != nil {
+		if owner.path != "" {
+			key.WriteString(owner.path)
+		} else {
+			key.WriteString(owner.name)
+		}
+	}
+	key.WriteString(":")
+	key.WriteString(name)
+
+	if c.vary == nil {
+		return key.String(), nil
+	}
+
+	paths, err := ctx.EvaluateExpression(c.vary)
+	if err != nil {
+		return "", err
+	}
+	list, ok := paths.([]interface{})
+	if !ok {
+		return "", fmt.Errorf("vary of block '%s' must be a list of variable names", name)
+	}
+	for _, path := range list {
+		value, err := ctx.variablePath(ctx.ToString(path))
+		if err != nil {
+			return "", err
+		}
+		key.WriteString(":")
+		key.WriteString(strconv.Quote(ctx.ToString(value)))
+	}
+	return key.String(), nil
+}
+
+// variablePath looks up a dotted path such as user.role
+func (ctx *RenderContext) variablePath(path string) (interface{}, error) {
+	parts := strings.Split(path, ".")
+	value, err := ctx.GetVariable(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts[1:] {
+		if value == nil {
+			return nil, nil
+		}
+		if value, err = ctx.getAttribute(value, part); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// renderCached renders a block through the fragment cache
+func (c *blockCache) renderCached(w io.Writer, ctx *RenderContext, name string, render func(io.Writer) error) error {
+	cache := ctx.env.fragmentCache
+	if cache == nil {
+		return render(w)
+	}
+
+	key, err := c.fragmentKey(ctx, name)
+	if err != nil {
+		return err
+	}
+	if fragment, ok := cache.Get(key); ok {
+		_, err := io.WriteString(w, fragment)
+		return err
+	}
+
+	ttlValue, err := ctx.EvaluateExpression(c.ttl)
+	if err != nil {
+		return err
+	}
+	ttl, err := toInt(ttlValue)
+	if err != nil {
+		return fmt.Errorf("cache ttl of block '%s': %w", name, err)
+	}
+
+	buf := NewStringBuffer()
+	defer buf.Release()
+	if err := render(buf); err != nil {
+		return err
+	}
+
+	fragment := buf.String()
+	cache.Set(key, fragment, time.Duration(ttl)*time.Second)
+	_, err = io.WriteString(w, fragment)
+	return err
+}
+
+// parseBlockCache parses the cache(ttl, vary=[...]) annotation after a
+// block name. The cache name has been consumed.
+func (p *Parser) parseBlockCache(blockName string, line int) (*blockCache, error) {
+	if !p.isPunctuation("(") {
+		return nil, fmt.Errorf("expected '(' after cache in block %s at line %d", blockName, line)
+	}
+	p.tokenIndex++
+
+	ttl, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cache ttl of block %s at line %d: %w", blockName, line, err)
+	}
+	cache := &blockCache{ttl: ttl}
+
+	if p.isPunctuation(",") {
+		p.tokenIndex++
+		if p.tokenIndex+1 >= len(p.tokens) || p.tokens[p.tokenIndex].Type != TOKEN_NAME ||
+			p.tokens[p.tokenIndex].Value != "vary" || p.tokens[p.tokenIndex+1].Value != "=" {
+			return nil, fmt.Errorf("expected vary=[...] in cache of block %s at line %d", blockName, line)
+		}
+		p.tokenIndex += 2
+
+		if cache.vary, err = p.parseExpression(); err != nil {
+			return nil, fmt.Errorf("error parsing cache vary of block %s at line %d: %w", blockName, line, err)
+		}
+	}
+
+	if !p.isPunctuation(")") {
+		return nil, fmt.Errorf("expected ')' after cache in block %s at line %d", blockName, line)
+	}
+	p.tokenIndex++
+	return cache, nil
+}
+
+// isPunctuation reports whether the current token is the punctuation value
+func (p *Parser) isPunctuation(value string) bool {
+	return p.tokenIndex < len(p.tokens) &&
+		p.tokens[p.tokenIndex].Type == TOKEN_PUNCTUATION &&
+		p.tokens[p.tokenIndex].Value == value
+}
@@ -0,0 +1,148 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingFragmentCache records the keys stored in a memory cache
+type countingFragmentCache struct {
+	*MemoryFragmentCache
+	keys []string
+	ttls []time.Duration
+}
+
+func (c *countingFragmentCache) Set(key string, fragment string, ttl time.Duration) {
+	c.keys = append(c.keys, key)
+	c.ttls = append(c.ttls, ttl)
+	c.MemoryFragmentCache.Set(key, fragment, ttl)
+}
+
+func TestBlockCache(t *testing.T) {
+	engine := New()
+	cache := &countingFragmentCache{MemoryFragmentCache: NewMemoryFragmentCache()}
+	engine.SetFragmentCache(cache)
+
+	calls := 0
+	engine.AddFunction("expensive", func(args ...interface{}) (interface{}, error) {
+		calls++
+		return "menu", nil
+	})
+
+	source := `[{% block sidebar cache(300, vary=['user.role']) %}{{ expensive() }} for {{ user.role }}{% endblock %}]`
+	if err := engine.RegisterString("page", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	render := func(role string) string {
+		result, err := engine.Render("page", map[string]interface{}{"user": map[string]interface{}{"role": role}})
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		return result
+	}
+
+	for _, tt := range []struct {
+		role     string
+		expected string
+		calls    int
+	}{
+		{"admin", "[menu for admin]", 1},
+		{"admin", "[menu for admin]", 1},
+		{"editor", "[menu for editor]", 2},
+		{"editor", "[menu for editor]", 2},
+	} {
+		if result := render(tt.role); result != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, result)
+		}
+		if calls != tt.calls {
+			t.Errorf("Expected %d renders of the block, got %d", tt.calls, calls)
+		}
+	}
+
+	if len(cache.keys) != 2 || cache.keys[0] == cache.keys[1] || !strings.Contains(cache.keys[0], "sidebar") {
+		t.Errorf("Expected one key per role, got %q", cache.keys)
+	}
+	if cache.ttls[0] != 300*time.Second {
+		t.Errorf("Expected a ttl of 300s, got %v", cache.ttls[0])
+	}
+}
+
+func TestBlockCacheOverride(t *testing.T) {
+	engine := New()
+	engine.SetFragmentCache(NewMemoryFragmentCache())
+
+	sources := map[string]string{
+		"base":  `{% block nav cache(60) %}default{% endblock %}`,
+		"child": `{% extends "base" %}{% block nav %}child{% endblock %}`,
+	}
+	for name, source := range sources {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	// Each template providing the block's content has its own entry
+	for _, tt := range []struct{ name, expected string }{
+		{"base", "default"},
+		{"child", "child"},
+		{"base", "default"},
+	} {
+		result, err := engine.Render(tt.name, nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != tt.expected {
+			t.Errorf("Rendering %s: expected %q, got %q", tt.name, tt.expected, result)
+		}
+	}
+}
+
+func TestBlockCacheWithoutBackend(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", `{% block clock cache(60) %}{{ n }}{% endblock %}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	for _, n := range []string{"1", "2"} {
+		result, err := engine.Render("page", map[string]interface{}{"n": n})
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != n {
+			t.Errorf("Expected %q without a fragment cache, got %q", n, result)
+		}
+	}
+}
+
+func TestBlockCacheScopes(t *testing.T) {
+	engine := New()
+	engine.SetFragmentCache(NewMemoryFragmentCache())
+	if err := engine.RegisterString("page", `{% block header cache(60) %}{{ tenant }}{% endblock %}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	// Tenant scopes share the engine's cache but not its blocks
+	for _, tenant := range []string{"acme", "globex"} {
+		scope := engine.NewScope()
+		scope.AddGlobal("tenant", tenant)
+		result, err := scope.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != tenant {
+			t.Errorf("Expected %q, got %q", tenant, result)
+		}
+	}
+}
+
+func TestMemoryFragmentCacheSweep(t *testing.T) {
+	cache := NewMemoryFragmentCache()
+	for i := 0; i < 3*minFragmentSweep; i++ {
+		cache.Set(strings.Repeat("k", i+1), "fragment", -time.Second)
+	}
+	if len(cache.fragments) > minFragmentSweep {
+		t.Errorf("Expected expired fragments to be dropped, got %d", len(cache.fragments))
+	}
+}
@@ -0,0 +1,78 @@
+package twig
+
+import (
+	"errors"
+	"sort"
+)
+
+// unknownBlocks returns the blocks a template defines at its top level that
+// no template it extends defines, sorted. Such blocks are usually typos,
+// like {% block contnet %}, and silently render nothing. Templates whose
+// ancestors cannot be loaded or extend a name chosen at render time are
+// not checked.
+func (e *Engine) unknownBlocks(template *Template, parent string) []string {
+	if parent == "" {
+		return nil
+	}
+	defined := topLevelBlocks(template)
+	if len(defined) == 0 {
+		return nil
+	}
+
+	inherited := make(map[string]bool)
+	seen := map[string]bool{template.name: true}
+	from := template.name
+	for parent != "" {
+		ancestor, err := e.Load(resolveDependency(from, parent))
+		if err != nil && errors.Is(err, ErrTemplateNotFound) {
+			ancestor, err = e.Load(parent)
+		}
+		if err != nil || seen[ancestor.name] {
+			return nil
+		}
+		seen[ancestor.name] = true
+
+		collector := &blockCollector{names: inherited}
+		Walk(ancestor.nodes, collector)
+		if collector.dynamicParent {
+			return nil
+		}
+		from, parent = ancestor.name, collector.parent
+	}
+
+	var unknown []string
+	for name := range defined {
+		if !inherited[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// blockCollector gathers the blocks of an ancestor template and the
+// template it extends in turn
+type blockCollector struct {
+	names         map[string]bool
+	parent        string
+	dynamicParent bool
+}
+
+func (c *blockCollector) Enter(node Node) bool {
+	switch n := node.(type) {
+	case *BlockNode:
+		c.names[n.name] = true
+	case *ExtendsNode:
+		name, ok := literalString(n.parent)
+		if !ok {
+			c.dynamicParent = true
+		}
+		c.parent = name
+	case *EmbedNode:
+		// Blocks of an embed override the embedded template's, not ours
+		return false
+	}
+	return true
+}
+
+func (c *blockCollector) Leave(node Node) {}
@@ -0,0 +1,61 @@
+package twig
+
+import "testing"
+
+func TestBlockShortForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		expected string
+	}{
+		{
+			name: "prints the expression",
+			sources: map[string]string{
+				"test": `<title>{% block title page.title|upper %}</title>`,
+			},
+			expected: "<title>HOME</title>",
+		},
+		{
+			name: "escaped like a print",
+			sources: map[string]string{
+				"test": `{% block title page.html|e %}`,
+			},
+			expected: "&lt;b&gt;",
+		},
+		{
+			name: "overridden by a child",
+			sources: map[string]string{
+				"base": `<title>{% block title 'Site' %}</title>`,
+				"test": `{% extends "base" %}{% block title page.title ~ ' | Site' %}`,
+			},
+			expected: "<title>home | Site</title>",
+		},
+		{
+			name: "overrides a full block",
+			sources: map[string]string{
+				"base": `{% block content %}default{% endblock %}`,
+				"test": `{% extends "base" %}{% block content 'short' %}`,
+			},
+			expected: "short",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			for name, source := range tt.sources {
+				if err := engine.RegisterString(name, source); err != nil {
+					t.Fatalf("Error parsing template %s: %v", name, err)
+				}
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"page": map[string]interface{}{"title": "home", "html": "<b>"}})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
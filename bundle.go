@@ -0,0 +1,262 @@
+package twig
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// bundleMagic starts every template bundle
+const bundleMagic = "TWGB"
+
+// bundleVersion is the bundle format written by ExportBundle
+const bundleVersion = 1
+
+// ExportBundle writes the named templates, and every template they extend,
+// include or import by a literal name, to w as a single archive of compiled
+// templates. Without names it exports every cached template.
+func (e *Engine) ExportBundle(w io.Writer, names ...string) error {
+	if len(names) == 0 {
+		names = e.GetCachedTemplateNames()
+		sort.Strings(names)
+	}
+
+	type entry struct {
+		template     *Template
+		dependencies []string
+	}
+	var entries []entry
+	seen := make(map[string]bool)
+
+	// Templates included with ignore missing are bundled when they exist
+	type pending struct {
+		name     string
+		fallback string // Name as written, tried when a relative name is missing
+		optional bool
+	}
+	var queue []pending
+	for _, name := range names {
+		queue = append(queue, pending{name: name})
+	}
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next.name] {
+			continue
+		}
+
+		template, err := e.Load(next.name)
+		if err != nil && errors.Is(err, ErrTemplateNotFound) && next.fallback != next.name && next.fallback != "" {
+			queue = append(queue, pending{name: next.fallback, optional: next.optional})
+			continue
+		}
+		if err != nil {
+			if next.optional && errors.Is(err, ErrTemplateNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed to export template %s: %w", next.name, err)
+		}
+		seen[next.name] = true
+
+		deps := &dependencyCollector{}
+		Walk(template.nodes, deps)
+		for _, dep := range deps.required {
+			queue = append(queue, pending{name: resolveDependency(next.name, dep), fallback: dep})
+		}
+		for _, dep := range deps.optional {
+			queue = append(queue, pending{name: resolveDependency(next.name, dep), fallback: dep, optional: true})
+		}
+		entries = append(entries, entry{template: template, dependencies: deps.required})
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(bundleMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(bundleVersion); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(len(entries))); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		compiled, err := CompileTemplate(entry.template)
+		if err != nil {
+			return fmt.Errorf("failed to compile template %s: %w", entry.template.name, err)
+		}
+		data, err := SerializeCompiledTemplate(compiled)
+		if err != nil {
+			return fmt.Errorf("failed to serialize template %s: %w", entry.template.name, err)
+		}
+
+		if err := writeString(bw, entry.template.name); err != nil {
+			return err
+		}
+		if err := binary.Write(bw, binary.LittleEndian, uint32(len(entry.dependencies))); err != nil {
+			return err
+		}
+		for _, dep := range entry.dependencies {
+			if err := writeString(bw, dep); err != nil {
+				return err
+			}
+		}
+		if err := writeString(bw, string(data)); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ImportBundle loads the templates in a bundle written by ExportBundle. The
+// bundle is read and parsed completely before any template is registered,
+// so a damaged bundle leaves the engine unchanged. Imported templates are
+// served from the template cache.
+func (e *Engine) ImportBundle(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(bundleMagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("failed to read bundle header: %w", err)
+	}
+	if string(header[:len(bundleMagic)]) != bundleMagic {
+		return errors.New("not a template bundle")
+	}
+	if header[len(bundleMagic)] != bundleVersion {
+		return fmt.Errorf("unsupported bundle version: %d", header[len(bundleMagic)])
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+		return fmt.Errorf("failed to read bundle: %w", err)
+	}
+
+	templates := make(map[string]*Template, count)
+	dependencies := make(map[string][]string, count)
+	for i := uint32(0); i < count; i++ {
+		name, err := readString(br)
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+
+		var depCount uint32
+		if err := binary.Read(br, binary.LittleEndian, &depCount); err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		for j := uint32(0); j < depCount; j++ {
+			dep, err := readString(br)
+			if err != nil {
+				return fmt.Errorf("failed to read bundle: %w", err)
+			}
+			dependencies[name] = append(dependencies[name], dep)
+		}
+
+		data, err := readString(br)
+		if err != nil {
+			return fmt.Errorf("failed to read bundle: %w", err)
+		}
+		compiled, err := DeserializeCompiledTemplate([]byte(data))
+		if err != nil {
+			return fmt.Errorf("failed to read template %s from bundle: %w", name, err)
+		}
+		template, err := LoadFromCompiled(compiled, e.environment, e)
+		if err != nil {
+			return fmt.Errorf("failed to load template %s from bundle: %w", name, err)
+		}
+		template.name = name
+		templates[name] = template
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Bundles carry their static dependencies; a missing one means the
+	// bundle was cut short or built against different templates
+	available := func(name string) bool {
+		_, bundled := templates[name]
+		_, cached := e.templates[name]
+		return bundled || cached
+	}
+	for name, deps := range dependencies {
+		for _, dep := range deps {
+			if !available(resolveDependency(name, dep)) && !available(dep) {
+				return fmt.Errorf("template %s depends on %s, which is not in the bundle", name, dep)
+			}
+		}
+	}
+
+	for name, template := range templates {
+		e.templates[name] = template
+	}
+	return nil
+}
+
+// resolveDependency resolves a template name relative to the template that
+// uses it, as include does for names starting with ./ or ../
+func resolveDependency(from, name string) string {
+	if strings.HasPrefix(name, "./") || strings.HasPrefix(name, "../") {
+		return filepath.Join(filepath.Dir(from), name)
+	}
+	return name
+}
+
+// dependencyCollector gathers the templates a template loads by a literal
+// name. Names chosen at render time cannot be bundled.
+type dependencyCollector struct {
+	required []string
+	optional []string // Included with ignore missing
+}
+
+// Enter records literal template names
+func (d *dependencyCollector) Enter(node Node) bool {
+	switch n := node.(type) {
+	case *ExtendsNode:
+		d.add(n.parent, false)
+	case *IncludeNode:
+		d.add(n.template, n.ignoreMissing)
+	case *EmbedNode:
+		d.add(n.template, n.ignoreMissing)
+	case *ImportNode:
+		d.add(n.template, false)
+	case *FromImportNode:
+		d.add(n.template, false)
+	case *FunctionNode:
+		if n.name == "include" && n.moduleExpr == nil && len(n.args) > 0 {
+			d.add(n.args[0], false)
+		}
+	}
+	return true
+}
+
+// Leave is a no-op
+func (d *dependencyCollector) Leave(node Node) {}
+
+// add records the name held by a string literal
+func (d *dependencyCollector) add(node Node, optional bool) {
+	literal, ok := node.(*LiteralNode)
+	if !ok {
+		return
+	}
+	name, ok := literal.value.(string)
+	if !ok || name == "" || name == "_self" {
+		return
+	}
+
+	list := &d.required
+	if optional {
+		list = &d.optional
+	}
+	for _, existing := range *list {
+		if existing == name {
+			return
+		}
+	}
+	*list = append(*list, name)
+}
@@ -0,0 +1,112 @@
+package twig
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	source := New()
+	source.RegisterLoader(NewArrayLoader(map[string]string{
+		"base.twig":            `<main>{% block content %}{% endblock %}</main>`,
+		"pages/page.twig":      `{% extends "base.twig" %}{% block content %}{% include "./nav.twig" %}{% include "missing.twig" ignore missing %}{{ title }}{% endblock %}`,
+		"pages/nav.twig":       `<nav>{% from "macros.twig" import link %}{{ link("home") }}</nav>`,
+		"macros.twig":          `{% macro link(name) %}<a>{{ name }}</a>{% endmacro %}`,
+		"pages/unrelated.twig": `unrelated`,
+	}))
+
+	var buf bytes.Buffer
+	if err := source.ExportBundle(&buf, "pages/page.twig"); err != nil {
+		t.Fatalf("Error exporting bundle: %v", err)
+	}
+
+	target := New()
+	if err := target.ImportBundle(&buf); err != nil {
+		t.Fatalf("Error importing bundle: %v", err)
+	}
+
+	names := target.GetCachedTemplateNames()
+	sort.Strings(names)
+	if strings.Join(names, ",") != "base.twig,macros.twig,pages/nav.twig,pages/page.twig" {
+		t.Errorf("Expected the page and its dependencies, got %s", names)
+	}
+
+	result, err := target.Render("pages/page.twig", map[string]interface{}{"title": "Hi"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	expected := "<main><nav><a>home</a></nav>Hi</main>"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestImportBundleErrors(t *testing.T) {
+	source := New()
+	if err := source.RegisterString("a", "{{ a }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := source.ExportBundle(&buf); err != nil {
+		t.Fatalf("Error exporting bundle: %v", err)
+	}
+	data := buf.Bytes()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not a bundle", data: []byte("hello world")},
+		{name: "unsupported version", data: append([]byte("TWGB\x09"), data[5:]...)},
+		{name: "truncated", data: data[:len(data)-3]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := New()
+			if err := target.ImportBundle(bytes.NewReader(tt.data)); err == nil {
+				t.Fatal("Expected an error")
+			}
+			if len(target.GetCachedTemplateNames()) != 0 {
+				t.Error("Expected a failed import to leave the engine unchanged")
+			}
+		})
+	}
+}
+
+func TestImportBundleMissingDependency(t *testing.T) {
+	source := New()
+	if err := source.RegisterString("page.twig", `{% extends "base.twig" %}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, _ := source.Load("page.twig")
+	compiled, _ := CompileTemplate(template)
+	data, _ := SerializeCompiledTemplate(compiled)
+
+	// A bundle holding the page but not the parent it extends
+	var buf bytes.Buffer
+	buf.WriteString(bundleMagic)
+	buf.WriteByte(bundleVersion)
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	writeString(&buf, "page.twig")
+	binary.Write(&buf, binary.LittleEndian, uint32(1))
+	writeString(&buf, "base.twig")
+	writeString(&buf, string(data))
+
+	target := New()
+	err := target.ImportBundle(bytes.NewReader(buf.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "base.twig") {
+		t.Fatalf("Expected a missing dependency error, got %v", err)
+	}
+
+	// A cached parent satisfies the dependency
+	if err := target.RegisterString("base.twig", "base"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := target.ImportBundle(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Errorf("Error importing bundle: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package twig
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBytesAndReaderValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{"Print bytes", "<{{ data }}>", map[string]interface{}{"data": []byte("<b>raw</b>")}, "<<b>raw</b>>"},
+		{"Print reader", "<{{ data }}>", map[string]interface{}{"data": strings.NewReader("from reader")}, "<from reader>"},
+		{"Bytes through raw", "{{ data|raw }}", map[string]interface{}{"data": []byte("fragment")}, "fragment"},
+		{"Bytes through upper", "{{ data|upper }}", map[string]interface{}{"data": []byte("loud")}, "LOUD"},
+		{"Bytes escaped", "{{ data|e }}", map[string]interface{}{"data": []byte("<i>")}, "&lt;i&gt;"},
+		{"Reader through upper", "{{ data|upper }}", map[string]interface{}{"data": strings.NewReader("loud")}, "LOUD"},
+		{"Stream through upper", "{{ data|upper }}", map[string]interface{}{"data": io.MultiReader(strings.NewReader("loud"))}, "LOUD"},
+		{"Reader concatenation", "{{ 'a' ~ data ~ 'c' }}", map[string]interface{}{"data": strings.NewReader("b")}, "abc"},
+		{"Reader kept by filters", "{% set s = data|upper %}{{ data }}", map[string]interface{}{"data": strings.NewReader("whole")}, "whole"},
+		{"Bytes length", "{{ data|length }}", map[string]interface{}{"data": []byte("12345")}, "5"},
+		{"Bytes truthiness", "{% if data %}yes{% endif %}{% if empty %}no{% endif %}", map[string]interface{}{"data": []byte("x"), "empty": []byte{}}, "yes"},
+		{"Bytes concatenation", "{{ 'a' ~ data ~ 'c' }}", map[string]interface{}{"data": []byte("b")}, "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Large reader streamed to writer", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("test", "[{{ file }}]")
+
+		content := strings.Repeat("0123456789", 10000)
+		var buf bytes.Buffer
+		err := engine.RenderTo(&buf, "test", map[string]interface{}{"file": strings.NewReader(content)})
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if buf.String() != "["+content+"]" {
+			t.Errorf("Expected streamed content of length %d, got length %d", len(content)+2, buf.Len())
+		}
+	})
+}
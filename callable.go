@@ -0,0 +1,143 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// errorType is the reflect type of the error interface
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// autoInvoke calls a func reached by attribute access when it takes no
+// arguments, so {{ user.permissions }} prints what the func returns rather
+// than the func itself. Other values are returned as they are.
+func (ctx *RenderContext) autoInvoke(value interface{}, name string) (interface{}, error) {
+	fn := reflect.ValueOf(value)
+	if fn.Kind() != reflect.Func || fn.IsNil() || fn.Type().NumIn() != 0 {
+		return value, nil
+	}
+	if ctx.sandboxed && ctx.env.securityPolicy != nil && !ctx.env.securityPolicy.IsFunctionAllowed(name) {
+		return nil, NewFunctionViolation(name)
+	}
+	return callFunc(fn, name, nil)
+}
+
+// callableValue returns the func value named by a call on obj, as in
+// {{ user.permissions('admin') }}: a func stored in a map or struct field,
+// or a method of obj
+func callableValue(obj interface{}, name string) (reflect.Value, bool) {
+	if obj == nil {
+		return reflect.Value{}, false
+	}
+
+	v := reflect.ValueOf(obj)
+	if method := v.MethodByName(name); method.IsValid() {
+		return method, true
+	}
+
+	elem := v
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			return reflect.Value{}, false
+		}
+		elem = elem.Elem()
+	}
+
+	var value reflect.Value
+	switch elem.Kind() {
+	case reflect.Map:
+		if elem.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		value = elem.MapIndex(reflect.ValueOf(name).Convert(elem.Type().Key()))
+	case reflect.Struct:
+		if field, ok := elem.Type().FieldByName(name); ok && field.IsExported() {
+			value = elem.FieldByIndex(field.Index)
+		}
+	}
+
+	for value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() || value.Kind() != reflect.Func || value.IsNil() {
+		return reflect.Value{}, false
+	}
+	return value, true
+}
+
+// callFunc calls a Go func with template arguments, converting them to the
+// parameter types. The func may return nothing, a value, an error, or a
+// value and an error.
+func callFunc(fn reflect.Value, name string, args []interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &AccessError{Kind: "call", Name: name, Type: fn.Type().String(), Panic: r}
+		}
+	}()
+
+	fnType := fn.Type()
+	fixed := fnType.NumIn()
+	if fnType.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("%s expects at least %d arguments, got %d", name, fixed, len(args))
+		}
+	} else if len(args) != fixed {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name, fixed, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if i < fixed {
+			paramType = fnType.In(i)
+		} else {
+			paramType = fnType.In(fixed).Elem()
+		}
+		if in[i], err = convertArg(arg, paramType); err != nil {
+			return nil, fmt.Errorf("%s argument %d: %w", name, i+1, err)
+		}
+	}
+
+	out := fn.Call(in)
+	if n := len(out); n > 0 && fnType.Out(n-1) == errorType {
+		if e := out[n-1].Interface(); e != nil {
+			return nil, e.(error)
+		}
+		out = out[:n-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out[0].Interface(), nil
+}
+
+// convertArg converts a template value to a parameter type
+func convertArg(arg interface{}, paramType reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(paramType), nil
+	}
+
+	v := reflect.ValueOf(arg)
+	if v.Type().AssignableTo(paramType) {
+		return v, nil
+	}
+
+	switch paramType.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(toString(arg)).Convert(paramType), nil
+	case reflect.Bool:
+		return reflect.ValueOf(toBool(arg)).Convert(paramType), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if f, err := toFloat64(arg); err == nil {
+			return reflect.ValueOf(f).Convert(paramType), nil
+		}
+	default:
+		if v.Type().ConvertibleTo(paramType) {
+			return v.Convert(paramType), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, paramType)
+}
@@ -0,0 +1,125 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testAccount struct {
+	Name  string
+	Roles func() []string
+}
+
+func (a *testAccount) Can(permission string, level int) bool {
+	return permission == "edit" && level <= 2
+}
+
+func TestCallableContextValues(t *testing.T) {
+	engine := New()
+
+	account := &testAccount{Name: "ann", Roles: func() []string { return []string{"admin", "editor"} }}
+	context := map[string]interface{}{
+		"account": account,
+		"user": map[string]interface{}{
+			"permissions": func() []string { return []string{"read", "write"} },
+			"greet":       func(name string, times int) string { return strings.Repeat("hi "+name+" ", times) },
+			"balance":     func() (float64, error) { return 12.5, nil },
+		},
+		"shout": func(s string) string { return strings.ToUpper(s) },
+		"sum": func(values ...int) int {
+			total := 0
+			for _, v := range values {
+				total += v
+			}
+			return total
+		},
+		"broken": func() (string, error) { return "", errors.New("backend down") },
+		"nilref": func() string { var a *testAccount; return a.Name },
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"auto invoked on attribute access", "{{ user.permissions|join(',') }}", "read,write"},
+		{"explicit call", "{{ user.permissions()|join(',') }}", "read,write"},
+		{"arguments adapted", "{{ user.greet('bob', '2') }}", "hi bob hi bob "},
+		{"value and nil error", "{{ user.balance }}", "12.5"},
+		{"struct func field", "{{ account.Roles|join(',') }}", "admin,editor"},
+		{"method with arguments", "{{ account.Can('edit', 1) ? 'yes' : 'no' }}", "yes"},
+		{"context func", "{{ shout('hey') }}", "HEY"},
+		{"variadic", "{{ sum(1, 2, 3) }}", "6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name   string
+		source string
+		errMsg string
+	}{
+		{"returned error", "{{ broken() }}", "backend down"},
+		{"wrong argument count", "{{ shout() }}", "shout expects 1 arguments, got 0"},
+		{"unconvertible argument", "{{ user.greet('bob', [1]) }}", "greet argument 2: cannot use"},
+		{"panic", "{{ nilref() }}", `cannot access call "nilref"`},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			_, err := engine.Render("page", context)
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected an error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestCallableSandbox(t *testing.T) {
+	engine := New()
+	policy := NewDefaultSecurityPolicy()
+	engine.EnableSandbox(policy)
+
+	templates := map[string]string{
+		"main":    "{% include 'partial' sandboxed %}",
+		"partial": "{{ user.secret }}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	context := map[string]interface{}{
+		"user": map[string]interface{}{"secret": func() string { return "s3cret" }},
+	}
+	if _, err := engine.Render("main", context); err == nil || !strings.Contains(err.Error(), "Function 'secret' is not allowed") {
+		t.Errorf("Expected a sandbox violation, got %v", err)
+	}
+
+	policy.AllowedFunctions["secret"] = true
+	result, err := engine.Render("main", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "s3cret" {
+		t.Errorf("Expected the allowed func to be called, got %q", result)
+	}
+}
@@ -0,0 +1,93 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+type cacheUser struct{ Name string }
+
+func TestAttributeCachePerEngine(t *testing.T) {
+	first := New()
+	second := New()
+	if err := first.RegisterString("test", `{{ user.Name }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := first.Render("test", map[string]interface{}{"user": cacheUser{Name: "Ann"}})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Ann" {
+		t.Errorf("Expected Ann, got %q", result)
+	}
+
+	if first.attributes.currSize != 1 {
+		t.Errorf("Expected one cached lookup, got %d", first.attributes.currSize)
+	}
+	if second.attributes.currSize != 0 {
+		t.Errorf("Expected other engines to have their own cache, got %d entries", second.attributes.currSize)
+	}
+
+	// Long attribute names are resolved but not cached
+	ctx := NewRenderContext(first.environment, nil, first)
+	defer ctx.Release()
+	if _, err := ctx.getAttribute(cacheUser{}, strings.Repeat("x", maxAttributeCacheKeyLen+1)); err != nil {
+		t.Fatalf("Error getting attribute: %v", err)
+	}
+	if first.attributes.currSize != 1 {
+		t.Errorf("Expected long attribute names to skip the cache, got %d entries", first.attributes.currSize)
+	}
+}
+
+func TestClearCaches(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"macros.twig": `{% macro hi() %}hi{% endmacro %}`,
+		"page.twig":   `{% import "macros.twig" as m %}{{ m.hi() }} {{ user.Name }}`,
+	}))
+
+	if _, err := engine.Render("page.twig", map[string]interface{}{"user": cacheUser{Name: "Ann"}}); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if len(engine.templates) == 0 || engine.attributes.currSize == 0 {
+		t.Fatal("Expected the render to fill the caches")
+	}
+
+	engine.ClearCaches()
+
+	if len(engine.templates) != 0 {
+		t.Errorf("Expected no cached templates, got %d", len(engine.templates))
+	}
+	if engine.attributes.currSize != 0 {
+		t.Errorf("Expected no cached attributes, got %d", engine.attributes.currSize)
+	}
+	if len(engine.macros.entries) != 0 {
+		t.Errorf("Expected no cached macros, got %d", len(engine.macros.entries))
+	}
+
+	result, err := engine.Render("page.twig", map[string]interface{}{"user": cacheUser{Name: "Bob"}})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "hi Bob" {
+		t.Errorf("Expected rendering to work after clearing, got %q", result)
+	}
+}
+
+func TestClearCachesKeepsRegisteredTemplates(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("t", `{{ user.Name }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	engine.ClearCaches()
+
+	result, err := engine.Render("t", map[string]interface{}{"user": cacheUser{Name: "Ann"}})
+	if err != nil {
+		t.Fatalf("Error rendering template after clearing: %v", err)
+	}
+	if result != "Ann" {
+		t.Errorf("Expected Ann, got %q", result)
+	}
+}
@@ -0,0 +1,60 @@
+package twig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetClock(t *testing.T) {
+	fixed := time.Date(2024, 2, 29, 13, 45, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{"Date filter on now string", "{{ 'now'|date('Y-m-d H:i') }}", nil, "2024-02-29 13:45"},
+		{"Date filter on nil", "{{ missing|date('Y-m-d') }}", nil, "2024-02-29"},
+		{"Date function", "{{ date()|date('Y') }}", nil, "2024"},
+		{"Date function with now", "{{ date('now')|date('m/d') }}", nil, "02/29"},
+		{"Now variable", "{{ now|date('Y-m-d') }}", nil, "2024-02-29"},
+		{"Now variable is a date", "{% if now is date %}yes{% endif %}", nil, "yes"},
+		{"Comparison with now", "{% if published < now %}published{% endif %}", map[string]interface{}{"published": fixed.Add(-time.Hour)}, "published"},
+		{"Context overrides now", "{{ now }}", map[string]interface{}{"now": "custom"}, "custom"},
+		{"Set overrides now", "{% set now = 'later' %}{{ now }}", nil, "later"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetClock(func() time.Time { return fixed })
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Nil clock restores time.Now", func(t *testing.T) {
+		engine := New()
+		engine.SetClock(func() time.Time { return fixed })
+		engine.SetClock(nil)
+		engine.RegisterString("test", "{{ now|date('Y') }}")
+
+		result, err := engine.Render("test", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if expected := time.Now().Format("2006"); result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+// Command twig provides tools for working with Twig templates.
+//
+// Usage:
+//
+//	twig lint [-json] [-strict-blocks] path...
+//	twig extract [-format json|po|xliff] [-domain name] [-locale en] path...
+//	twig ast file.twig
+//
+// lint parses every .twig file below the given paths and reports syntax
+// errors. With -json it prints an analysis report for each template. Blocks
+// that override no block of the parent templates are reported as warnings,
+// or as errors with -strict-blocks.
+//
+// extract prints a catalog of the messages translated with the trans filter.
+//
+// ast prints the parse tree of a template as JSON.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/semihalev/twig"
+)
+
+// commands maps subcommand names to their implementations
+var commands = map[string]func(args []string, stdout, stderr io.Writer) int{
+	"lint":    runLint,
+	"extract": runExtract,
+	"ast":     runAST,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run dispatches to a subcommand and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "twig: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+	return command(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "usage: twig <command> [arguments]\n\ncommands: %s\n", strings.Join(names, ", "))
+}
+
+// lintError is a template that failed to parse
+type lintError struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// runLint implements the lint command
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print an analysis report for each template as JSON")
+	strictBlocks := flags.Bool("strict-blocks", false, "report blocks overriding no parent block as errors")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: twig lint [-json] [-strict-blocks] path...")
+		return 2
+	}
+
+	engine := twig.New()
+	names, err := loadTemplates(engine, flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+
+	reports := []*twig.TemplateReport{}
+	errors := []lintError{}
+	warnings := []lintError{}
+	for _, name := range names {
+		report, err := engine.Analyze(name)
+		if err != nil {
+			errors = append(errors, lintError{Name: name, Error: err.Error()})
+			continue
+		}
+		reports = append(reports, report)
+
+		for _, block := range report.UnknownBlocks {
+			issue := lintError{Name: name, Error: fmt.Sprintf("block %q overrides no block of the parent templates", block)}
+			if *strictBlocks {
+				errors = append(errors, issue)
+			} else {
+				warnings = append(warnings, issue)
+			}
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(map[string]interface{}{
+			"templates": reports,
+			"errors":    errors,
+			"warnings":  warnings,
+		}); err != nil {
+			fmt.Fprintf(stderr, "twig: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, w := range warnings {
+			fmt.Fprintf(stderr, "%s: warning: %s\n", w.Name, w.Error)
+		}
+		for _, e := range errors {
+			fmt.Fprintf(stderr, "%s: %s\n", e.Name, e.Error)
+		}
+		fmt.Fprintf(stdout, "%d templates, %d errors\n", len(names), len(errors))
+	}
+
+	if len(errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runExtract implements the extract command
+func runExtract(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "json", "catalog format: json, po or xliff")
+	domain := flags.String("domain", "", "only extract messages of this domain")
+	locale := flags.String("locale", "en", "source language of the messages, for xliff")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "usage: twig extract [-format json|po|xliff] [-domain name] [-locale en] path...")
+		return 2
+	}
+
+	engine := twig.New()
+	names, err := loadTemplates(engine, flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+
+	catalog, err := engine.ExtractMessages(names...)
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+	if *domain != "" {
+		catalog = catalog.Domain(*domain)
+	}
+
+	switch *format {
+	case "json":
+		err = catalog.WriteJSON(stdout)
+	case "po":
+		err = catalog.WritePO(stdout)
+	case "xliff":
+		err = catalog.WriteXLIFF(stdout, *locale)
+	default:
+		fmt.Fprintf(stderr, "twig: unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runAST implements the ast command
+func runAST(args []string, stdout, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: twig ast file.twig")
+		return 2
+	}
+
+	source, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+
+	engine := twig.New()
+	name := filepath.Base(args[0])
+	if err := engine.RegisterString(name, string(source)); err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+	template, err := engine.Load(name)
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+
+	tree, err := template.ASTJSON()
+	if err != nil {
+		fmt.Fprintf(stderr, "twig: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s\n", tree)
+	return 0
+}
+
+// loadTemplates registers every .twig file below paths with the engine,
+// named by its path relative to the argument it was found under. Templates
+// are parsed on first load, so syntax errors are reported per template.
+func loadTemplates(engine *twig.Engine, paths []string) ([]string, error) {
+	var names []string
+	sources := make(map[string]string)
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+
+		base := root
+		if !info.IsDir() {
+			base = filepath.Dir(root)
+		}
+
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".twig") {
+				return nil
+			}
+
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			source, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			name := filepath.ToSlash(rel)
+			if _, seen := sources[name]; !seen {
+				names = append(names, name)
+			}
+			sources[name] = string(source)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	engine.RegisterLoader(twig.NewArrayLoader(sources))
+	sort.Strings(names)
+	return names, nil
+}
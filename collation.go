@@ -0,0 +1,417 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Collator orders strings the way a language sorts them. CompareString
+// returns a negative number, zero or a positive number when a sorts before,
+// with or after b. The collators of golang.org/x/text/collate satisfy it,
+// for example collate.New(language.Turkish).
+type Collator interface {
+	CompareString(a, b string) int
+}
+
+// SetCollator sets the collator used by the sort, natural_sort and
+// sort_by_keys filters when they are passed locale, as in
+// items|sort(locale='tr'). Locales without a collator use a built-in one
+// that ignores case and accents on a first pass and knows the extra
+// letters of Turkish, Spanish, Polish and the Nordic languages; register
+// the x/text collators for full CLDR ordering. A nil collator removes it.
+func (e *Engine) SetCollator(locale string, collator Collator) {
+	locale = normalizeLocale(locale)
+	if collator == nil {
+		delete(e.environment.collators, locale)
+		return
+	}
+	if e.environment.collators == nil {
+		e.environment.collators = make(map[string]Collator)
+	}
+	e.environment.collators[locale] = collator
+}
+
+// normalizeLocale lowercases a locale and uses - between its parts
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// collator returns the collator for a locale: a registered one for the
+// locale or its language, or else the built-in one
+func (env *Environment) collator(locale string) Collator {
+	locale = normalizeLocale(locale)
+	language, _, _ := strings.Cut(locale, "-")
+	if env != nil {
+		if c, ok := env.collators[locale]; ok {
+			return c
+		}
+		if c, ok := env.collators[language]; ok {
+			return c
+		}
+	}
+	return newBasicCollator(language)
+}
+
+// basicCollator compares strings in three passes, as collation does: by
+// letter ignoring accents and case, then by accents, then by case
+type basicCollator struct {
+	tailoring map[rune]int // Letters sorting as separate letters, by weight
+	turkish   bool         // Use the Turkish dotted and dotless i
+}
+
+// collationWeight spaces out the letters so tailored letters fit after them
+const collationWeight = 8
+
+// letterTailorings lists the letters that languages sort after another
+// letter instead of as accented forms of it
+var letterTailorings = map[string][]struct {
+	after   rune
+	letters string
+}{
+	"tr": {{'c', "ç"}, {'g', "ğ"}, {'h', "ı"}, {'o', "ö"}, {'s', "ş"}, {'u', "ü"}},
+	"az": {{'c', "ç"}, {'g', "ğ"}, {'h', "ı"}, {'o', "ö"}, {'s', "ş"}, {'u', "ü"}},
+	"es": {{'n', "ñ"}},
+	"pl": {{'a', "ą"}, {'c', "ć"}, {'e', "ę"}, {'l', "ł"}, {'n', "ń"}, {'o', "ó"}, {'s', "ś"}, {'z', "źż"}},
+	"sv": {{'z', "åäö"}},
+	"fi": {{'z', "åäö"}},
+	"da": {{'z', "æøå"}},
+	"nb": {{'z', "æøå"}},
+	"nn": {{'z', "æøå"}},
+	"no": {{'z', "æøå"}},
+}
+
+// newBasicCollator returns the built-in collator for a language
+func newBasicCollator(language string) *basicCollator {
+	c := &basicCollator{turkish: language == "tr" || language == "az"}
+	if tailorings, ok := letterTailorings[language]; ok {
+		c.tailoring = make(map[rune]int)
+		for _, t := range tailorings {
+			for i, r := range []rune(t.letters) {
+				c.tailoring[r] = int(t.after)*collationWeight + i + 1
+			}
+		}
+	}
+	return c
+}
+
+// accentedLetters maps accented Latin letters to their base letter. The
+// position of a letter in its list orders it among the accented forms.
+var accentedLetters = map[rune]string{
+	'a': "àáâãäåāăą",
+	'c': "çćĉċč",
+	'd': "ďđ",
+	'e': "èéêëēĕėęě",
+	'g': "ĝğġģ",
+	'h': "ĥħ",
+	'i': "ìíîïĩīĭįı",
+	'j': "ĵ",
+	'k': "ķ",
+	'l': "ĺļľŀł",
+	'n': "ñńņňŉ",
+	'o': "òóôõöøōŏő",
+	'r': "ŕŗř",
+	's': "śŝşš",
+	't': "ţťŧ",
+	'u': "ùúûüũūŭůűų",
+	'w': "ŵ",
+	'y': "ýÿŷ",
+	'z': "źżž",
+}
+
+// expandedLetters sort as two letters
+var expandedLetters = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'þ': "th",
+}
+
+// baseLetters maps each accented letter to its base and accent rank
+var baseLetters = func() map[rune][2]int {
+	m := make(map[rune][2]int)
+	for base, accented := range accentedLetters {
+		for i, r := range []rune(accented) {
+			m[r] = [2]int{int(base), i + 1}
+		}
+	}
+	return m
+}()
+
+// collationKey holds the weights of a string for each pass
+type collationKey struct {
+	primary, secondary, tertiary []int
+}
+
+// key computes the collation weights of s
+func (c *basicCollator) key(s string) collationKey {
+	var k collationKey
+	for _, r := range s {
+		lower := unicode.ToLower(r)
+		if c.turkish {
+			lower = unicode.TurkishCase.ToLower(r)
+		}
+		upper := 0
+		if lower != r {
+			upper = 1
+		}
+
+		if weight, ok := c.tailoring[lower]; ok {
+			k.add(weight, 0, upper)
+			continue
+		}
+		if expansion, ok := expandedLetters[lower]; ok {
+			for _, e := range expansion {
+				k.add(int(e)*collationWeight, 1, upper)
+			}
+			continue
+		}
+		if base, ok := baseLetters[lower]; ok {
+			k.add(base[0]*collationWeight, base[1], upper)
+			continue
+		}
+		k.add(int(lower)*collationWeight, 0, upper)
+	}
+	return k
+}
+
+// add appends the weights of one letter
+func (k *collationKey) add(primary, secondary, tertiary int) {
+	k.primary = append(k.primary, primary)
+	k.secondary = append(k.secondary, secondary)
+	k.tertiary = append(k.tertiary, tertiary)
+}
+
+// CompareString orders a and b by letter, then accents, then case, and
+// finally byte by byte so distinct strings never compare equal
+func (c *basicCollator) CompareString(a, b string) int {
+	ka, kb := c.key(a), c.key(b)
+	if n := compareWeights(ka.primary, kb.primary); n != 0 {
+		return n
+	}
+	if n := compareWeights(ka.secondary, kb.secondary); n != 0 {
+		return n
+	}
+	if n := compareWeights(ka.tertiary, kb.tertiary); n != 0 {
+		return n
+	}
+	return strings.Compare(a, b)
+}
+
+// compareWeights orders two weight lists lexicographically
+func compareWeights(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// naturalCompare orders strings with numbers by value, so file2 sorts
+// before file10. Text between the numbers is compared with compareText.
+func naturalCompare(a, b string, compareText func(a, b string) int) int {
+	for a != "" && b != "" {
+		ca, restA := naturalChunk(a)
+		cb, restB := naturalChunk(b)
+
+		var n int
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			n = compareNumerals(ca, cb)
+		} else {
+			n = compareText(ca, cb)
+		}
+		if n != 0 {
+			return n
+		}
+		a, b = restA, restB
+	}
+	return len(a) - len(b)
+}
+
+// naturalChunk splits off the leading run of digits or of other characters
+func naturalChunk(s string) (string, string) {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumerals orders two runs of digits by their value
+func compareNumerals(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		return len(ta) - len(tb)
+	}
+	if n := strings.Compare(ta, tb); n != 0 {
+		return n
+	}
+	// Fewer leading zeros first, so 1 sorts before 01
+	return len(a) - len(b)
+}
+
+// collationLocale returns the locale argument of a sorting filter
+func collationLocale(args FilterArgs, position int) (string, bool, error) {
+	value, ok := args.Get(position, "locale")
+	if !ok || value == nil {
+		return "", false, nil
+	}
+	locale, isString := value.(string)
+	if !isString {
+		return "", false, fmt.Errorf("locale must be a string, got %T", value)
+	}
+	return locale, true, nil
+}
+
+// textComparer returns how a sorting filter compares text: collated for the
+// locale passed, or else byte by byte
+func (e *CoreExtension) textComparer(args FilterArgs, position int) (func(a, b string) int, bool, error) {
+	locale, ok, err := collationLocale(args, position)
+	if err != nil || !ok {
+		return strings.Compare, false, err
+	}
+
+	env := e.env
+	if args.Context != nil && args.Context.env != nil {
+		env = args.Context.env
+	}
+	return env.collator(locale).CompareString, true, nil
+}
+
+// sortValues copies a slice or array into a sorted []interface{}
+func (e *CoreExtension) sortValues(value interface{}, less func(a, b interface{}) bool) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot sort %T", value)
+	}
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return less(result[i], result[j])
+	})
+	return result, nil
+}
+
+// filterSortCollated sorts like sort, collating strings when a locale is
+// passed, as in items|sort(locale='tr')
+func (e *CoreExtension) filterSortCollated(value interface{}, args FilterArgs) (interface{}, error) {
+	compareText, collated, err := e.textComparer(args, -1)
+	if err != nil {
+		return nil, err
+	}
+	if !collated {
+		return e.filterSort(value, args.Positional...)
+	}
+
+	return e.sortValues(value, func(a, b interface{}) bool {
+		if c, ok := e.env.compare(a, b); ok {
+			return c < 0
+		}
+		if isNumberKind(a) && isNumberKind(b) {
+			fa, _ := toFloat64(a)
+			fb, _ := toFloat64(b)
+			return fa < fb
+		}
+		return compareText(e.toString(a), e.toString(b)) < 0
+	})
+}
+
+// isNumberKind reports whether v is an integer or a float
+func isNumberKind(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// filterNaturalSort sorts strings with numbers by value, so file2 comes
+// before file10: items|natural_sort or items|natural_sort(locale='de')
+func (e *CoreExtension) filterNaturalSort(value interface{}, args FilterArgs) (interface{}, error) {
+	compareText, _, err := e.textComparer(args, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.sortValues(value, func(a, b interface{}) bool {
+		return naturalCompare(e.toString(a), e.toString(b), compareText) < 0
+	})
+}
+
+// sortedMapping is a mapping whose keys a for loop visits in order
+type sortedMapping struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// GetAttr returns the value of a key
+func (m *sortedMapping) GetAttr(name string) (interface{}, bool) {
+	value, ok := m.values[name]
+	return value, ok
+}
+
+// Keys returns the keys in sorted order
+func (m *sortedMapping) Keys() []string {
+	return m.keys
+}
+
+// filterSortByKeys orders a mapping by its keys, which Go maps do not keep,
+// so {% for key, value in map|sort_by_keys %} visits them in order. Keys
+// are collated when a locale is passed and compared naturally with
+// natural=true.
+func (e *CoreExtension) filterSortByKeys(value interface{}, args FilterArgs) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	compareText, _, err := e.textComparer(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	natural := false
+	if n, ok := args.Get(1, "natural"); ok {
+		natural = toBool(n)
+	}
+
+	m := &sortedMapping{values: make(map[string]interface{})}
+	switch v := value.(type) {
+	case Keyer:
+		for _, key := range v.Keys() {
+			m.values[key], _ = v.GetAttr(key)
+		}
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Map {
+			return nil, fmt.Errorf("sort_by_keys expects a mapping, got %T", value)
+		}
+		iter := rv.MapRange()
+		for iter.Next() {
+			m.values[e.toString(iter.Key().Interface())] = iter.Value().Interface()
+		}
+	}
+
+	for key := range m.values {
+		m.keys = append(m.keys, key)
+	}
+	sort.Slice(m.keys, func(i, j int) bool {
+		if natural {
+			return naturalCompare(m.keys[i], m.keys[j], compareText) < 0
+		}
+		return compareText(m.keys[i], m.keys[j]) < 0
+	})
+	return m, nil
+}
@@ -0,0 +1,100 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollation(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		// Byte order puts accented and capitalized words out of place
+		{"{{ words|sort|join(',') }}", map[string]interface{}{"words": []string{"şeker", "çay", "su", "Cam"}}, "Cam,su,çay,şeker"},
+		{"{{ words|sort(locale='tr')|join(',') }}", map[string]interface{}{"words": []string{"şeker", "çay", "su", "Cam", "ılık", "iz"}}, "Cam,çay,ılık,iz,su,şeker"},
+		{"{{ words|sort(locale='en_US')|join(',') }}", map[string]interface{}{"words": []string{"émigré", "zebra", "Eagle", "apple"}}, "apple,Eagle,émigré,zebra"},
+		{"{{ words|sort(locale='sv')|join(',') }}", map[string]interface{}{"words": []string{"öl", "zon", "arm"}}, "arm,zon,öl"},
+		{"{{ numbers|sort(locale='de')|join(',') }}", map[string]interface{}{"numbers": []int{10, 9, 100}}, "9,10,100"},
+
+		{"{{ files|natural_sort|join(',') }}", map[string]interface{}{"files": []string{"file10", "file2", "file1", "file02"}}, "file1,file2,file02,file10"},
+		{"{{ files|natural_sort(locale='tr')|join(',') }}", map[string]interface{}{"files": []string{"şube 10", "şube 2", "sube 3"}}, "sube 3,şube 2,şube 10"},
+
+		{"{% for k, v in m|sort_by_keys %}{{ k }}={{ v }};{% endfor %}", map[string]interface{}{"m": map[string]int{"b": 2, "a": 1, "c": 3}}, "a=1;b=2;c=3;"},
+		{"{% for k in m|sort_by_keys(natural=true) %}{{ k }};{% endfor %}", map[string]interface{}{"m": map[string]int{"v10": 1, "v9": 2}}, "2;1;"},
+		{"{{ m|sort_by_keys(locale='tr', natural=true)|length }}", map[string]interface{}{"m": map[string]int{"a": 1, "b": 2}}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			template, err := engine.ParseTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := template.Render(tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+// reverseCollator orders strings backwards, standing in for x/text
+type reverseCollator struct{}
+
+func (reverseCollator) CompareString(a, b string) int {
+	return strings.Compare(b, a)
+}
+
+func TestSetCollator(t *testing.T) {
+	engine := New()
+	engine.SetCollator("xx", reverseCollator{})
+
+	template, err := engine.ParseTemplate("{{ ['a', 'c', 'b']|sort(locale='xx-YY')|join('') }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := template.Render(nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "cba" {
+		t.Errorf("Expected the registered collator to be used, got %q", result)
+	}
+
+	engine.SetCollator("xx", nil)
+	if result, _ = template.Render(nil); result != "abc" {
+		t.Errorf("Expected the built-in collator after removal, got %q", result)
+	}
+}
+
+func TestNamedFilterArguments(t *testing.T) {
+	engine := New()
+
+	errors := []struct {
+		source string
+		want   string
+	}{
+		{"{{ x|sort(locale='tr', locale='de') }}", "passed twice"},
+		{"{{ x|slice(start=1, 2) }}", "positional argument after named"},
+	}
+	for _, tt := range errors {
+		if _, err := engine.ParseTemplate(tt.source); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.source, tt.want, err)
+		}
+	}
+
+	template, err := engine.ParseTemplate("{{ 'abc'|upper(x=1) }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := template.Render(nil); err == nil || !strings.Contains(err.Error(), "does not accept named arguments") {
+		t.Errorf("Expected filters without names to reject named arguments, got %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/semihalev/twig/runtime"
+)
+
+// Lengther is implemented by collections that know their size.
+//
+// Deprecated: Use runtime.Lengther.
+type Lengther = runtime.Lengther
+
+// Indexer is implemented by sequences.
+//
+// Deprecated: Use runtime.Indexer.
+type Indexer = runtime.Indexer
+
+// AttrGetter is implemented by objects that resolve their own attributes.
+//
+// Deprecated: Use runtime.AttrGetter.
+type AttrGetter = runtime.AttrGetter
+
+// Keyer is implemented by mappings that list their keys.
+//
+// Deprecated: Use runtime.Keyer.
+type Keyer = runtime.Keyer
+
+// collectionLen returns the length of a custom collection
+func collectionLen(v interface{}) (int, bool) {
+	switch c := v.(type) {
+	case Lengther:
+		return c.Len(), true
+	case Keyer:
+		return len(c.Keys()), true
+	}
+	return 0, false
+}
+
+// collectionItem implements items[index] for custom collections. String
+// indexes go to GetAttr, numeric ones to At.
+func (ctx *RenderContext) collectionItem(container, index interface{}) (interface{}, bool, error) {
+	indexer, isIndexer := container.(Indexer)
+	if getter, ok := container.(AttrGetter); ok {
+		if name, isString := index.(string); isString || !isIndexer {
+			if !isString {
+				name = ctx.ToString(index)
+			}
+			value, _ := getter.GetAttr(name)
+			return value, true, nil
+		}
+	}
+	if !isIndexer {
+		return nil, false, nil
+	}
+
+	idx, _ := ctx.toNumber(index)
+	i, ok := itemIndex(int(idx), indexer.Len())
+	if !ok {
+		return nil, true, fmt.Errorf("array index out of bounds: %d", int(idx))
+	}
+	return indexer.At(i), true, nil
+}
+
+// renderCollection runs a for loop over an Indexer or Keyer. It reports
+// false when seq is neither, leaving it to the reflection based loop.
+func (n *ForNode) renderCollection(w io.Writer, ctx *RenderContext, seq interface{}) (bool, error) {
+	var length int
+	var next func(i int) (key, value interface{})
+
+	switch c := seq.(type) {
+	case Indexer:
+		length = c.Len()
+		next = func(i int) (interface{}, interface{}) {
+			return i, c.At(i)
+		}
+	case Keyer:
+		keys := c.Keys()
+		length = len(keys)
+		next = func(i int) (interface{}, interface{}) {
+			value, _ := c.GetAttr(keys[i])
+			return keys[i], value
+		}
+	default:
+		return false, nil
+	}
+
+	if length == 0 {
+		for _, node := range n.elseBranch {
+			if err := ctx.render(w, node); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+
+	loopCtx := ctx.newLoopScope()
+	loop := map[string]interface{}{"length": length}
+	for i := 0; i < length; i++ {
+		loop["index"] = i + 1
+		loop["index0"] = i
+		loop["revindex"] = length - i
+		loop["revindex0"] = length - i - 1
+		loop["first"] = i == 0
+		loop["last"] = i == length-1
+
+		key, value := next(i)
+		n.setValue(loopCtx, value)
+		if n.keyVar != "" {
+			loopCtx.setLocal(n.keyVar, key)
+		}
+		loopCtx.setLocal("loop", loop)
+
+		for _, node := range n.body {
+			if err := loopCtx.render(w, node); err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
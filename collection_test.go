@@ -0,0 +1,75 @@
+package twig
+
+import "testing"
+
+// testList is a sequence backed by an unexported slice
+type testList struct{ items []string }
+
+func (l *testList) Len() int             { return len(l.items) }
+func (l *testList) At(i int) interface{} { return l.items[i] }
+
+// testRecord is an ordered mapping with computed attributes
+type testRecord struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (r *testRecord) Keys() []string { return r.keys }
+
+func (r *testRecord) GetAttr(name string) (interface{}, bool) {
+	value, ok := r.values[name]
+	return value, ok
+}
+
+func TestCustomCollections(t *testing.T) {
+	context := map[string]interface{}{
+		"list":  &testList{items: []string{"a", "b", "c"}},
+		"empty": &testList{},
+		"record": &testRecord{
+			keys:   []string{"z", "a"},
+			values: map[string]interface{}{"z": 1, "a": 2},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"for over indexer", `{% for i, v in list %}{{ i }}{{ v }}{% if not loop.last %},{% endif %}{% endfor %}`, "0a,1b,2c"},
+		{"for over empty indexer", `{% for v in empty %}{{ v }}{% else %}none{% endfor %}`, "none"},
+		{"for over keyer keeps order", `{% for k, v in record %}{{ k }}={{ v }};{% endfor %}`, "z=1;a=2;"},
+		{"loop length", `{% for v in list %}{{ loop.length }}{% endfor %}`, "333"},
+		{"length filter", `{{ list|length }} {{ record|length }}`, "3 2"},
+		{"length function", `{{ length(list) }} {{ count(record) }}`, "3 2"},
+		{"index", `{{ list[1] }}`, "b"},
+		{"attribute", `{{ record.a }}`, "2"},
+		{"string key", `{{ record['z'] }}`, "1"},
+		{"missing attribute", `{{ record.missing is null ? 'nil' : 'set' }}`, "nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	engine := New()
+	if err := engine.RegisterString("test", `{{ list[5] }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("test", context); err == nil {
+		t.Error("Expected an out of bounds error")
+	}
+}
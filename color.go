@@ -0,0 +1,292 @@
+package twig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// color is an RGB color with alpha, channels in [0, 1]
+type color struct {
+	r, g, b, a float64
+}
+
+// parseColor reads #rgb, #rgba, #rrggbb, #rrggbbaa, rgb() and rgba() colors
+func parseColor(value interface{}) (color, error) {
+	s := strings.ToLower(strings.TrimSpace(toString(value)))
+
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 || len(hex) == 4 {
+			// Expand the short form: #abc is #aabbcc
+			var long strings.Builder
+			for _, c := range hex {
+				long.WriteRune(c)
+				long.WriteRune(c)
+			}
+			hex = long.String()
+		}
+		if len(hex) != 6 && len(hex) != 8 {
+			return color{}, fmt.Errorf("invalid hex color %q", s)
+		}
+
+		n, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return color{}, fmt.Errorf("invalid hex color %q", s)
+		}
+		if len(hex) == 6 {
+			n = n<<8 | 0xff
+		}
+		return color{
+			r: float64(n>>24&0xff) / 255,
+			g: float64(n>>16&0xff) / 255,
+			b: float64(n>>8&0xff) / 255,
+			a: float64(n&0xff) / 255,
+		}, nil
+	}
+
+	for _, prefix := range []string{"rgba(", "rgb("} {
+		if !strings.HasPrefix(s, prefix) || !strings.HasSuffix(s, ")") {
+			continue
+		}
+
+		parts := strings.Split(s[len(prefix):len(s)-1], ",")
+		if len(parts) != 3 && len(parts) != 4 {
+			return color{}, fmt.Errorf("invalid color %q", s)
+		}
+
+		var channels [4]float64
+		channels[3] = 1
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			percent := strings.HasSuffix(part, "%")
+			f, err := strconv.ParseFloat(strings.TrimSuffix(part, "%"), 64)
+			if err != nil {
+				return color{}, fmt.Errorf("invalid color %q", s)
+			}
+			switch {
+			case percent:
+				f /= 100
+			case i < 3:
+				f /= 255
+			}
+			channels[i] = clamp01(f)
+		}
+		return color{r: channels[0], g: channels[1], b: channels[2], a: channels[3]}, nil
+	}
+
+	return color{}, fmt.Errorf("invalid color %q", s)
+}
+
+// String formats opaque colors as #rrggbb and translucent ones as rgba()
+func (c color) String() string {
+	r, g, b := channel255(c.r), channel255(c.g), channel255(c.b)
+	if c.a >= 1 {
+		return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+	}
+	alpha := strconv.FormatFloat(math.Round(c.a*1000)/1000, 'f', -1, 64)
+	return fmt.Sprintf("rgba(%d, %d, %d, %s)", r, g, b, alpha)
+}
+
+// channel255 converts a channel to an integer in [0, 255]
+func channel255(v float64) int {
+	return int(math.Round(clamp01(v) * 255))
+}
+
+// clamp01 limits v to [0, 1]
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+// hsl converts the color to hue (degrees), saturation and lightness
+func (c color) hsl() (h, s, l float64) {
+	max := math.Max(c.r, math.Max(c.g, c.b))
+	min := math.Min(c.r, math.Min(c.g, c.b))
+	l = (max + min) / 2
+
+	if max == min {
+		return 0, 0, l
+	}
+
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+
+	switch max {
+	case c.r:
+		h = (c.g - c.b) / d
+		if c.g < c.b {
+			h += 6
+		}
+	case c.g:
+		h = (c.b-c.r)/d + 2
+	default:
+		h = (c.r-c.g)/d + 4
+	}
+	return h * 60, s, l
+}
+
+// fromHSL builds a color from hue, saturation, lightness and alpha
+func fromHSL(h, s, l, a float64) color {
+	if s == 0 {
+		return color{r: l, g: l, b: l, a: a}
+	}
+
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	h /= 360
+
+	return color{
+		r: hueToRGB(p, q, h+1.0/3),
+		g: hueToRGB(p, q, h),
+		b: hueToRGB(p, q, h-1.0/3),
+		a: a,
+	}
+}
+
+// hueToRGB computes one channel of an HSL color
+func hueToRGB(p, q, t float64) float64 {
+	if t < 0 {
+		t++
+	}
+	if t > 1 {
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 1.0/2:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	}
+	return p
+}
+
+// luminance returns the WCAG relative luminance of the color
+func (c color) luminance() float64 {
+	linear := func(v float64) float64 {
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.r) + 0.7152*linear(c.g) + 0.0722*linear(c.b)
+}
+
+// contrastRatio returns the WCAG contrast ratio between two colors
+func contrastRatio(a, b color) float64 {
+	la, lb := a.luminance(), b.luminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// parseAmount reads a filter amount given as a number or a percentage
+// string, returning the amount as a fraction when percent is true
+func parseAmount(filter string, args []interface{}, percent bool) (float64, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("%s filter requires an amount", filter)
+	}
+
+	s := strings.TrimSpace(toString(args[0]))
+	isPercent := strings.HasSuffix(s, "%")
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s filter: invalid amount %q", filter, s)
+	}
+	if percent || isPercent {
+		f /= 100
+	}
+	return f, nil
+}
+
+// isEmptyColor reports whether a color filter was given no color, which
+// filters to an empty string like a nil value in the other core filters
+func isEmptyColor(value interface{}) bool {
+	s, ok := value.(string)
+	return value == nil || (ok && strings.TrimSpace(s) == "")
+}
+
+// adjustLightness creates the lighten and darken filters, which change
+// HSL lightness by a number of percentage points like Sass does
+func adjustLightness(filter string, sign float64) FilterFunc {
+	return func(value interface{}, args ...interface{}) (interface{}, error) {
+		if isEmptyColor(value) {
+			return "", nil
+		}
+		c, err := parseColor(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s filter: %w", filter, err)
+		}
+		amount, err := parseAmount(filter, args, true)
+		if err != nil {
+			return nil, err
+		}
+
+		h, s, l := c.hsl()
+		return fromHSL(h, s, clamp01(l+sign*amount), c.a).String(), nil
+	}
+}
+
+// filterAlpha implements color|alpha(opacity), setting the color's opacity
+// to a value between 0 and 1 or a percentage such as "50%"
+func (e *CoreExtension) filterAlpha(value interface{}, args ...interface{}) (interface{}, error) {
+	if isEmptyColor(value) {
+		return "", nil
+	}
+	c, err := parseColor(value)
+	if err != nil {
+		return nil, fmt.Errorf("alpha filter: %w", err)
+	}
+	opacity, err := parseAmount("alpha", args, false)
+	if err != nil {
+		return nil, err
+	}
+
+	c.a = clamp01(opacity)
+	return c.String(), nil
+}
+
+// filterContrastColor implements color|contrast_color(dark, light), returning
+// whichever of the two candidates is more readable on the color
+func (e *CoreExtension) filterContrastColor(value interface{}, args ...interface{}) (interface{}, error) {
+	if isEmptyColor(value) {
+		return "", nil
+	}
+	background, err := parseColor(value)
+	if err != nil {
+		return nil, fmt.Errorf("contrast_color filter: %w", err)
+	}
+
+	candidates := []interface{}{"#000000", "#ffffff"}
+	for i := 0; i < len(args) && i < 2; i++ {
+		if args[i] != nil {
+			candidates[i] = args[i]
+		}
+	}
+
+	dark, err := parseColor(candidates[0])
+	if err != nil {
+		return nil, fmt.Errorf("contrast_color filter: %w", err)
+	}
+	light, err := parseColor(candidates[1])
+	if err != nil {
+		return nil, fmt.Errorf("contrast_color filter: %w", err)
+	}
+
+	if contrastRatio(background, dark) >= contrastRatio(background, light) {
+		return e.toString(candidates[0]), nil
+	}
+	return e.toString(candidates[1]), nil
+}
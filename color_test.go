@@ -0,0 +1,70 @@
+package twig
+
+import "testing"
+
+func TestColorFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"lighten", `{{ "#336699"|lighten(20) }}`, "#6699cc"},
+		{"lighten percentage string", `{{ "#336699"|lighten("20%") }}`, "#6699cc"},
+		{"darken", `{{ "#6699cc"|darken(20) }}`, "#336699"},
+		{"darken clamps to black", `{{ "#336699"|darken(100) }}`, "#000000"},
+		{"short hex", `{{ "#fff"|darken(50) }}`, "#808080"},
+		{"rgb input", `{{ "rgb(51, 102, 153)"|lighten(0) }}`, "#336699"},
+		{"alpha", `{{ "#336699"|alpha(0.5) }}`, "rgba(51, 102, 153, 0.5)"},
+		{"alpha percentage", `{{ "#336699"|alpha("25%") }}`, "rgba(51, 102, 153, 0.25)"},
+		{"alpha one is opaque", `{{ "rgba(51, 102, 153, 0.2)"|alpha(1) }}`, "#336699"},
+		{"hex with alpha keeps alpha", `{{ "#33669980"|lighten(20) }}`, "rgba(102, 153, 204, 0.502)"},
+		{"contrast on dark", `{{ "#1a1a2e"|contrast_color }}`, "#ffffff"},
+		{"contrast on light", `{{ "#ffeb3b"|contrast_color }}`, "#000000"},
+		{"contrast custom candidates", `{{ "#ffeb3b"|contrast_color("#222", "#eee") }}`, "#222"},
+		{"nil is empty", `[{{ nothing|lighten(10) }}{{ nothing|darken(10) }}{{ nothing|alpha(0.5) }}{{ nothing|contrast_color }}]`, "[]"},
+		{"empty string is empty", `[{{ ""|lighten(10) }}{{ ""|alpha(0.5) }}{{ ""|contrast_color }}]`, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestColorFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"invalid hex", `{{ "#12345"|lighten(10) }}`},
+		{"named color", `{{ "red"|darken(10) }}`},
+		{"missing amount", `{{ "#fff"|lighten }}`},
+		{"invalid amount", `{{ "#fff"|alpha("half") }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			if _, err := engine.Render("test", nil); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
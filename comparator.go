@@ -0,0 +1,138 @@
+package twig
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/semihalev/twig/runtime"
+)
+
+// Comparator orders values of custom types such as version strings, money
+// or time.Time.
+//
+// Deprecated: Use runtime.Comparator.
+type Comparator = runtime.Comparator
+
+// ComparatorFunc adapts a function to the Comparator interface.
+//
+// Deprecated: Use runtime.ComparatorFunc.
+type ComparatorFunc = runtime.ComparatorFunc
+
+// compare asks the registered comparators to order a and b, falling back to
+// chronological order when both are times
+func (env *Environment) compare(a, b interface{}) (int, bool) {
+	if env != nil {
+		for _, comparator := range env.comparators {
+			if result, ok := comparator.Compare(a, b); ok {
+				return result, true
+			}
+		}
+	}
+	return compareTimes(a, b)
+}
+
+// hasComparators reports whether any comparator is registered
+func (env *Environment) hasComparators() bool {
+	return env != nil && len(env.comparators) > 0
+}
+
+// compareOperation evaluates a comparison operator with the registered
+// comparators or between two times, reporting false when neither applies
+func (ctx *RenderContext) compareOperation(operator string, left, right interface{}) (bool, bool) {
+	switch operator {
+	case "==", "!=", "<", ">", "<=", ">=":
+	default:
+		return false, false
+	}
+
+	c, ok := ctx.env.compare(left, right)
+	if !ok {
+		return false, false
+	}
+
+	switch operator {
+	case "==":
+		return c == 0, true
+	case "!=":
+		return c != 0, true
+	case "<":
+		return c < 0, true
+	case ">":
+		return c > 0, true
+	case "<=":
+		return c <= 0, true
+	default:
+		return c >= 0, true
+	}
+}
+
+// sortWithComparators sorts a slice using the registered comparators, falling
+// back to string order for pairs they do not handle
+func (env *Environment) sortWithComparators(value interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	result := make([]interface{}, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if c, ok := env.compare(result[i], result[j]); ok {
+			return c < 0
+		}
+		return toString(result[i]) < toString(result[j])
+	})
+
+	return result, true
+}
+
+// extremeWithComparators returns the smallest (sign < 0) or largest (sign > 0)
+// argument, reporting false unless every pair is ordered by a comparator or
+// consists of two times
+func (env *Environment) extremeWithComparators(args []interface{}, sign int) (interface{}, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+
+	best := args[0]
+	for _, arg := range args[1:] {
+		c, ok := env.compare(arg, best)
+		if !ok {
+			return nil, false
+		}
+		if c*sign > 0 {
+			best = arg
+		}
+	}
+	return best, true
+}
+
+// asTime returns the time held by a time.Time or non-nil *time.Time value
+func asTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareTimes orders two time values, reporting false unless both are times
+func compareTimes(a, b interface{}) (int, bool) {
+	at, ok := asTime(a)
+	if !ok {
+		return 0, false
+	}
+	bt, ok := asTime(b)
+	if !ok {
+		return 0, false
+	}
+	return at.Compare(bt), true
+}
@@ -0,0 +1,90 @@
+package twig
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// compareVersions orders dotted version strings numerically
+func compareVersions(a, b interface{}) (int, bool) {
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if !aok || !bok || !strings.Contains(as, ".") || !strings.Contains(bs, ".") {
+		return 0, false
+	}
+
+	ap := strings.Split(as, ".")
+	bp := strings.Split(bs, ".")
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		an, err1 := strconv.Atoi(ap[i])
+		bn, err2 := strconv.Atoi(bp[i])
+		if err1 != nil || err2 != nil {
+			return 0, false
+		}
+		if an != bn {
+			return an - bn, true
+		}
+	}
+	return len(ap) - len(bp), true
+}
+
+// compareDates orders time.Time values by calendar day
+func compareDates(a, b interface{}) (int, bool) {
+	at, aok := a.(time.Time)
+	bt, bok := b.(time.Time)
+	if !aok || !bok {
+		return 0, false
+	}
+	day := 24 * time.Hour
+	return at.UTC().Truncate(day).Compare(bt.UTC().Truncate(day)), true
+}
+
+func TestRegisterComparator(t *testing.T) {
+	engine := New()
+	engine.RegisterComparator(ComparatorFunc(compareVersions))
+	engine.RegisterComparator(ComparatorFunc(compareDates))
+
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	context := map[string]interface{}{
+		"releases": []string{"1.10.0", "1.2.0", "1.9.3", "2.0"},
+		"early":    early,
+		"late":     late,
+		"sameDay":  early.Add(5 * time.Hour),
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Sort", "{{ releases|sort|join(' ') }}", "1.2.0 1.9.3 1.10.0 2.0"},
+		{"Max", "{{ max('1.9.0', '1.10.0') }}", "1.10.0"},
+		{"Min", "{{ min('1.9.0', '1.10.0', '1.2.5') }}", "1.2.5"},
+		{"Less than", "{% if '1.9.0' < '1.10.0' %}yes{% else %}no{% endif %}", "yes"},
+		{"Greater or equal", "{% if '2.0' >= '1.10' %}yes{% else %}no{% endif %}", "yes"},
+		{"Equality", "{% if early == sameDay %}yes{% else %}no{% endif %}", "yes"},
+		{"Times", "{% if early < late %}yes{% else %}no{% endif %}", "yes"},
+		{"Unhandled values use default", "{{ [3, 1, 2]|sort|join(',') }}{% if 1 < 2 %}!{% endif %}", "1,2,3!"},
+		{"Unhandled max uses default", "{{ max(1, 5, 3) }}", "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
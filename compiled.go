@@ -115,6 +115,7 @@ func LoadFromCompiled(compiled *CompiledTemplate, env *Environment, engine *Engi
 	}
 
 	var nodes Node
+	parseStart := time.Now()
 
 	// Try to use the cached AST if available
 	if len(compiled.AST) > 0 {
@@ -133,6 +134,9 @@ func LoadFromCompiled(compiled *CompiledTemplate, env *Environment, engine *Engi
 	// If AST deserialization failed or AST is not available, parse the source
 	if nodes == nil {
 		parser := &Parser{}
+		if engine != nil {
+			parser = engine.newParser()
+		}
 		var err error
 		nodes, err = parser.Parse(compiled.Source)
 		if err != nil {
@@ -142,12 +146,14 @@ func LoadFromCompiled(compiled *CompiledTemplate, env *Environment, engine *Engi
 
 	// Create the template from the nodes (either from AST or freshly parsed)
 	tmpl := &Template{
-		name:         compiled.Name,
-		source:       compiled.Source,
-		nodes:        nodes,
-		env:          env,
-		engine:       engine,
-		lastModified: compiled.LastModified,
+		name:          compiled.Name,
+		source:        compiled.Source,
+		nodes:         nodes,
+		env:           env,
+		engine:        engine,
+		lastModified:  compiled.LastModified,
+		parsedAt:      parseStart,
+		parseDuration: time.Since(parseStart),
 	}
 
 	return tmpl, nil
@@ -0,0 +1,75 @@
+package twig
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// CompiledCodec encodes compiled templates into artifacts and back. An
+// adapter around a msgpack or flatbuffers library satisfies it.
+type CompiledCodec interface {
+	Encode(compiled *CompiledTemplate) ([]byte, error)
+	Decode(data []byte) (*CompiledTemplate, error)
+}
+
+// BinaryCodec is the default length-prefixed binary format written by
+// SerializeCompiledTemplate. It also reads artifacts in the older gob
+// format.
+var BinaryCodec CompiledCodec = binaryCodec{}
+
+// GobCodec encodes compiled templates with encoding/gob
+var GobCodec CompiledCodec = gobCodec{}
+
+// binaryCodec implements CompiledCodec with SerializeCompiledTemplate
+type binaryCodec struct{}
+
+// Encode writes the binary format
+func (binaryCodec) Encode(compiled *CompiledTemplate) ([]byte, error) {
+	return SerializeCompiledTemplate(compiled)
+}
+
+// Decode reads the binary format, or the gob format it replaced
+func (binaryCodec) Decode(data []byte) (*CompiledTemplate, error) {
+	return DeserializeCompiledTemplate(data)
+}
+
+// gobCodec implements CompiledCodec with encoding/gob
+type gobCodec struct{}
+
+// Encode gob-encodes the compiled template
+func (gobCodec) Encode(compiled *CompiledTemplate) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(compiled); err != nil {
+		return nil, fmt.Errorf("failed to serialize compiled template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode gob-decodes a compiled template
+func (gobCodec) Decode(data []byte) (*CompiledTemplate, error) {
+	return deserializeGobFormat(data)
+}
+
+// SetCodec sets how compiled templates are encoded into artifacts.
+// Artifacts are read with the same codec they are written with; nil uses
+// BinaryCodec.
+func (l *CompiledLoader) SetCodec(codec CompiledCodec) {
+	l.codec = codec
+}
+
+// encode serializes a compiled template with the loader's codec
+func (l *CompiledLoader) encode(compiled *CompiledTemplate) ([]byte, error) {
+	if l.codec == nil {
+		return BinaryCodec.Encode(compiled)
+	}
+	return l.codec.Encode(compiled)
+}
+
+// decode deserializes an artifact with the loader's codec
+func (l *CompiledLoader) decode(data []byte) (*CompiledTemplate, error) {
+	if l.codec == nil {
+		return BinaryCodec.Decode(data)
+	}
+	return l.codec.Decode(data)
+}
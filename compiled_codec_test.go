@@ -0,0 +1,86 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestCompiledLoaderCodecs(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec CompiledCodec
+	}{
+		{name: "default"},
+		{name: "binary", codec: BinaryCodec},
+		{name: "gob", codec: GobCodec},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("page", "Hello {{ name }}"); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			loader := NewCompiledLoader(t.TempDir())
+			loader.SetCodec(tt.codec)
+			if err := loader.SaveCompiled(engine, "page"); err != nil {
+				t.Fatalf("Error saving template: %v", err)
+			}
+
+			fresh := New()
+			fresh.RegisterLoader(loader)
+			result, err := fresh.Render("page", map[string]interface{}{"name": "World"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != "Hello World" {
+				t.Errorf("Expected %q, got %q", "Hello World", result)
+			}
+		})
+	}
+}
+
+func TestBinaryCodecReadsGob(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", "{{ a }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	tmpl, _ := engine.Load("page")
+	compiled, err := CompileTemplate(tmpl)
+	if err != nil {
+		t.Fatalf("Error compiling template: %v", err)
+	}
+
+	data, err := GobCodec.Encode(compiled)
+	if err != nil {
+		t.Fatalf("Error encoding: %v", err)
+	}
+	decoded, err := BinaryCodec.Decode(data)
+	if err != nil {
+		t.Fatalf("Error decoding: %v", err)
+	}
+	if decoded.Name != "page" || decoded.Source != "{{ a }}" {
+		t.Errorf("Expected the gob artifact to decode, got %+v", decoded)
+	}
+}
+
+func BenchmarkCompiledCodecs(b *testing.B) {
+	engine := New()
+	engine.RegisterString("page", "{% for item in items %}{{ item.name|upper }}{% endfor %}")
+	tmpl, _ := engine.Load("page")
+	compiled, _ := CompileTemplate(tmpl)
+
+	for _, codec := range []struct {
+		name  string
+		codec CompiledCodec
+	}{{"binary", BinaryCodec}, {"gob", GobCodec}} {
+		data, _ := codec.codec.Encode(compiled)
+		b.Run(codec.name+"/decode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := codec.codec.Decode(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -4,15 +4,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 )
 
 // CompiledLoader loads templates from compiled files
 type CompiledLoader struct {
-	directory     string
-	fileExtension string
+	directory        string
+	fileExtension    string
+	contentAddressed bool                // Store artifacts by source hash, see SetContentAddressed
+	compression      ArtifactCompression // Compresses artifacts, nil to store them as is
+	codec            CompiledCodec       // Encodes artifacts, nil for BinaryCodec
+	workers          int                 // Parallel LoadAll workers, 0 for GOMAXPROCS
+	lazy             bool                // LoadAll only registers names, see SetLazyLoad
+	progress         LoadProgressFunc    // Called as LoadAll handles each template
+	names            []string            // Templates found by the last LoadAll
 }
 
+// LoadProgressFunc reports LoadAll progress: the template just handled,
+// how many have been handled so far and how many there are in total. It is
+// never called concurrently.
+type LoadProgressFunc func(name string, done, total int)
+
 // NewCompiledLoader creates a new compiled loader
 func NewCompiledLoader(directory string) *CompiledLoader {
 	return &CompiledLoader{
@@ -23,7 +38,10 @@ func NewCompiledLoader(directory string) *CompiledLoader {
 
 // Load implements the Loader interface
 func (l *CompiledLoader) Load(name string) (string, error) {
-	filePath := filepath.Join(l.directory, name+l.fileExtension)
+	filePath, err := l.artifactPath(name)
+	if err != nil {
+		return "", err
+	}
 
 	// Check if the file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
@@ -36,8 +54,15 @@ func (l *CompiledLoader) Load(name string) (string, error) {
 		return "", fmt.Errorf("failed to read compiled template file: %w", err)
 	}
 
+	if l.compression != nil {
+		data, err = l.compression.Decompress(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to decompress compiled template file: %w", err)
+		}
+	}
+
 	// Deserialize the compiled template
-	compiled, err := DeserializeCompiledTemplate(data)
+	compiled, err := l.decode(data)
 	if err != nil {
 		return "", fmt.Errorf("failed to deserialize compiled template: %w", err)
 	}
@@ -48,8 +73,11 @@ func (l *CompiledLoader) Load(name string) (string, error) {
 
 // Exists checks if a compiled template exists
 func (l *CompiledLoader) Exists(name string) bool {
-	filePath := filepath.Join(l.directory, name+l.fileExtension)
-	_, err := os.Stat(filePath)
+	filePath, err := l.artifactPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filePath)
 	return err == nil
 }
 
@@ -76,18 +104,29 @@ func (l *CompiledLoader) SaveCompiled(engine *Engine, name string) error {
 	}
 
 	// Serialize the compiled template
-	data, err := SerializeCompiledTemplate(compiled)
+	data, err := l.encode(compiled)
 	if err != nil {
 		return err
 	}
 
+	if l.compression != nil {
+		data, err = l.compression.Compress(data)
+		if err != nil {
+			return fmt.Errorf("failed to compress compiled template: %w", err)
+		}
+	}
+
+	if l.contentAddressed {
+		return l.saveObject(name, template.source, data)
+	}
+
 	// Ensure the directory exists
-	if err := os.MkdirAll(l.directory, 0755); err != nil {
+	filePath := filepath.Join(l.directory, name+l.fileExtension)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// Save the compiled template
-	filePath := filepath.Join(l.directory, name+l.fileExtension)
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write compiled template file: %w", err)
 	}
@@ -110,64 +149,120 @@ func (l *CompiledLoader) CompileAll(engine *Engine) error {
 	return nil
 }
 
-// LoadAll loads all compiled templates from the directory
+// SetLoadWorkers sets how many templates LoadAll deserializes in parallel.
+// Zero or less uses GOMAXPROCS.
+func (l *CompiledLoader) SetLoadWorkers(workers int) {
+	l.workers = workers
+}
+
+// SetLazyLoad makes LoadAll only register the names of the compiled
+// templates, leaving each to be deserialized on its first Load. This cuts
+// cold-start time when most templates are rarely rendered.
+func (l *CompiledLoader) SetLazyLoad(lazy bool) {
+	l.lazy = lazy
+}
+
+// SetLoadProgress sets a callback for LoadAll progress
+func (l *CompiledLoader) SetLoadProgress(progress LoadProgressFunc) {
+	l.progress = progress
+}
+
+// Names returns the templates found by the last LoadAll, sorted
+func (l *CompiledLoader) Names() []string {
+	return append([]string(nil), l.names...)
+}
+
+// LoadAll loads all compiled templates from the directory, deserializing
+// them in parallel, or only registering their names with SetLazyLoad
 func (l *CompiledLoader) LoadAll(engine *Engine) error {
 	// Register loader with the engine
 	engine.RegisterLoader(l)
 
 	LogInfo("Loading all compiled templates from directory: %s", l.directory)
 
-	// List all files in the directory
-	files, err := os.ReadDir(l.directory)
+	names, err := l.listNames()
 	if err != nil {
 		LogError(err, fmt.Sprintf("Failed to read directory: %s", l.directory))
 		return fmt.Errorf("failed to read compiled templates directory '%s': %w", l.directory, err)
 	}
+	l.names = names
 
-	// Collect errors during loading
-	var loadErrors []error
-	var loadedCount int
-
-	// Load each compiled template
-	for _, file := range files {
-		// Skip directories
-		if file.IsDir() {
-			LogVerbose("Skipping directory: %s", file.Name())
-			continue
+	if l.lazy {
+		for i, name := range names {
+			LogVerbose("Registered compiled template: %s", name)
+			if l.progress != nil {
+				l.progress(name, i+1, len(names))
+			}
 		}
+		LogInfo("Registered %d compiled templates for lazy loading", len(names))
+		return nil
+	}
+
+	workers := l.workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
 
-		// Check if it's a compiled template file
-		ext := filepath.Ext(file.Name())
-		if ext == l.fileExtension {
-			// Get the template name (filename without extension)
-			name := file.Name()[:len(file.Name())-len(ext)]
-			LogInfo("Loading compiled template: %s", name)
+	// Errors are kept by index so they are reported in name order
+	loadErrors := make([]error, len(names))
+	var mu sync.Mutex
+	var done int
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				name := names[i]
+				LogInfo("Loading compiled template: %s", name)
 
-			// Load the template
-			if err := l.LoadCompiled(engine, name); err != nil {
 				// Collect the error but continue loading other templates
-				errWithContext := fmt.Errorf("failed to load compiled template '%s' from '%s': %w",
-					name, filepath.Join(l.directory, file.Name()), err)
-				LogError(errWithContext)
-				loadErrors = append(loadErrors, errWithContext)
-			} else {
-				loadedCount++
-				LogInfo("Successfully loaded compiled template: %s", name)
+				if err := l.LoadCompiled(engine, name); err != nil {
+					errWithContext := fmt.Errorf("failed to load compiled template '%s' from '%s': %w",
+						name, filepath.Join(l.directory, name+l.listExtension()), err)
+					LogError(errWithContext)
+					loadErrors[i] = errWithContext
+				} else {
+					LogInfo("Successfully loaded compiled template: %s", name)
+				}
+
+				mu.Lock()
+				done++
+				if l.progress != nil {
+					l.progress(name, done, len(names))
+				}
+				mu.Unlock()
 			}
-		} else {
-			LogVerbose("Skipping non-template file: %s", file.Name())
+		}()
+	}
+	for i := range names {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var failed []error
+	for _, err := range loadErrors {
+		if err != nil {
+			failed = append(failed, err)
 		}
 	}
+	loadedCount := len(names) - len(failed)
 
 	// If there were any errors, return a combined error with detailed information
-	if len(loadErrors) > 0 {
-		LogWarning("Completed loading with %d success(es) and %d error(s)", loadedCount, len(loadErrors))
+	if len(failed) > 0 {
+		LogWarning("Completed loading with %d success(es) and %d error(s)", loadedCount, len(failed))
 
 		errDetails := strings.Builder{}
 		errDetails.WriteString(fmt.Sprintf("%d out of %d template(s) failed to load:\n",
-			len(loadErrors), loadedCount+len(loadErrors)))
+			len(failed), len(names)))
 
-		for i, err := range loadErrors {
+		for i, err := range failed {
 			errDetails.WriteString(fmt.Sprintf("  %d) %s\n", i+1, err.Error()))
 		}
 
@@ -178,9 +273,47 @@ func (l *CompiledLoader) LoadAll(engine *Engine) error {
 	return nil
 }
 
+// listExtension is the extension of the files LoadAll looks for: the
+// artifacts themselves, or references to them when content-addressed
+func (l *CompiledLoader) listExtension() string {
+	if l.contentAddressed {
+		return refExtension
+	}
+	return l.fileExtension
+}
+
+// listNames returns the names of the compiled templates in the directory
+func (l *CompiledLoader) listNames() ([]string, error) {
+	files, err := os.ReadDir(l.directory)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := l.listExtension()
+	var names []string
+	for _, file := range files {
+		// Skip directories
+		if file.IsDir() {
+			LogVerbose("Skipping directory: %s", file.Name())
+			continue
+		}
+		if !strings.HasSuffix(file.Name(), ext) {
+			LogVerbose("Skipping non-template file: %s", file.Name())
+			continue
+		}
+		names = append(names, strings.TrimSuffix(file.Name(), ext))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // Implement TimestampAwareLoader interface
 func (l *CompiledLoader) GetModifiedTime(name string) (int64, error) {
+	// A content-addressed template changes when its reference is rewritten
 	filePath := filepath.Join(l.directory, name+l.fileExtension)
+	if l.contentAddressed {
+		filePath = l.refPath(name)
+	}
 
 	// Check if the file exists
 	info, err := os.Stat(filePath)
@@ -0,0 +1,70 @@
+package twig
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompiledLoaderLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	var names []string
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("page%02d", i)
+		names = append(names, name)
+		if err := engine.RegisterString(name, fmt.Sprintf("%d {{ name }}", i)); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+	}
+	if err := NewCompiledLoader(dir).CompileAll(engine); err != nil {
+		t.Fatalf("Error compiling templates: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		lazy bool
+	}{
+		{name: "parallel"},
+		{name: "lazy", lazy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewCompiledLoader(dir)
+			loader.SetLoadWorkers(4)
+			loader.SetLazyLoad(tt.lazy)
+			var last, calls int
+			loader.SetLoadProgress(func(name string, done, total int) {
+				calls++
+				if done != last+1 || total != len(names) {
+					t.Errorf("Unexpected progress %d/%d after %d", done, total, last)
+				}
+				last = done
+			})
+
+			fresh := New()
+			if err := loader.LoadAll(fresh); err != nil {
+				t.Fatalf("Error loading templates: %v", err)
+			}
+			if calls != len(names) {
+				t.Errorf("Expected %d progress calls, got %d", len(names), calls)
+			}
+			if got := loader.Names(); fmt.Sprint(got) != fmt.Sprint(names) {
+				t.Errorf("Expected names %v, got %v", names, got)
+			}
+
+			// Lazily registered templates are deserialized on first use
+			cached := len(fresh.GetCachedTemplateNames())
+			if tt.lazy && cached != 0 || !tt.lazy && cached != len(names) {
+				t.Errorf("Unexpected %d cached templates", cached)
+			}
+			result, err := fresh.Render("page07", map[string]interface{}{"name": "x"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != "7 x" {
+				t.Errorf("Expected %q, got %q", "7 x", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package twig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// refExtension names the files mapping a template name to the hash of its
+// compiled artifact in a content-addressed store
+const refExtension = ".twig.ref"
+
+// ArtifactCompression compresses compiled template artifacts on disk. An
+// adapter around a zstd encoder and decoder satisfies it.
+type ArtifactCompression interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompression compresses artifacts with gzip
+var GzipCompression ArtifactCompression = gzipCompression{}
+
+// gzipCompression implements ArtifactCompression with compress/gzip
+type gzipCompression struct{}
+
+// Compress gzips data
+func (gzipCompression) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress gunzips data
+func (gzipCompression) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// SetContentAddressed stores artifacts under the hash of their source
+// instead of the template name. Each name gets a small reference file
+// pointing at its artifact, so identical templates are compiled once,
+// unchanged templates are never rewritten whatever their mtime, and the
+// objects directory can be shared as a build cache between machines.
+func (l *CompiledLoader) SetContentAddressed(enabled bool) {
+	l.contentAddressed = enabled
+}
+
+// SetCompression sets how artifacts are compressed on disk. Artifacts are
+// read with the same compression they are written with; nil stores them
+// uncompressed.
+func (l *CompiledLoader) SetCompression(compression ArtifactCompression) {
+	l.compression = compression
+}
+
+// ContentHash returns the key a content-addressed store files source under
+func ContentHash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// artifactPath returns the file holding the compiled template for name
+func (l *CompiledLoader) artifactPath(name string) (string, error) {
+	if !l.contentAddressed {
+		return filepath.Join(l.directory, name+l.fileExtension), nil
+	}
+
+	ref, err := os.ReadFile(l.refPath(name))
+	if err != nil {
+		return "", fmt.Errorf("compiled template reference not found: %w", err)
+	}
+	hash := strings.TrimSpace(string(ref))
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid compiled template reference for %s", name)
+	}
+	return l.objectPath(hash), nil
+}
+
+// refPath returns the reference file for name
+func (l *CompiledLoader) refPath(name string) string {
+	return filepath.Join(l.directory, name+refExtension)
+}
+
+// objectPath returns the artifact file for a content hash, fanned out by
+// the first byte of the hash to keep directories small. Artifacts in another
+// codec or compression than the default get their own file, so loaders
+// configured differently can share a store.
+func (l *CompiledLoader) objectPath(hash string) string {
+	return filepath.Join(l.directory, "objects", hash[:2], hash+l.artifactFormat()+l.fileExtension)
+}
+
+// artifactFormat names the codec and compression of the loader's artifacts
+// by their types, empty for BinaryCodec without compression
+func (l *CompiledLoader) artifactFormat() string {
+	var format string
+	if _, binary := l.codec.(binaryCodec); l.codec != nil && !binary {
+		format += "." + strings.TrimPrefix(fmt.Sprintf("%T", l.codec), "*")
+	}
+	if l.compression != nil {
+		format += "." + strings.TrimPrefix(fmt.Sprintf("%T", l.compression), "*")
+	}
+	return format
+}
+
+// saveObject writes an artifact unless one for the same source exists, then
+// points the reference for name at it
+func (l *CompiledLoader) saveObject(name, source string, data []byte) error {
+	hash := ContentHash(source)
+
+	objectPath := l.objectPath(hash)
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err := writeFileAtomic(objectPath, data); err != nil {
+			return fmt.Errorf("failed to write compiled template file: %w", err)
+		}
+	}
+
+	refPath := l.refPath(name)
+	if current, err := os.ReadFile(refPath); err == nil && string(current) == hash {
+		return nil
+	}
+	if err := writeFileAtomic(refPath, []byte(hash)); err != nil {
+		return fmt.Errorf("failed to write compiled template reference: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data through a temporary file and a rename, so
+// concurrent builds sharing a store never read a partial file
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
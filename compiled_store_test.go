@@ -0,0 +1,140 @@
+package twig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentAddressedCompiledLoader(t *testing.T) {
+	tests := []struct {
+		name        string
+		compression ArtifactCompression
+	}{
+		{name: "uncompressed"},
+		{name: "gzip", compression: GzipCompression},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := "Hello {{ name }}"
+
+			engine := New()
+			for _, name := range []string{"page", "copy", "other"} {
+				body := source
+				if name == "other" {
+					body = "Bye {{ name }}"
+				}
+				if err := engine.RegisterString(name, body); err != nil {
+					t.Fatalf("Error parsing template: %v", err)
+				}
+			}
+
+			loader := NewCompiledLoader(dir)
+			loader.SetContentAddressed(true)
+			loader.SetCompression(tt.compression)
+			for _, name := range []string{"page", "copy", "other"} {
+				if err := loader.SaveCompiled(engine, name); err != nil {
+					t.Fatalf("Error saving %s: %v", name, err)
+				}
+			}
+
+			// Identical sources share one artifact
+			objects, _ := filepath.Glob(filepath.Join(dir, "objects", "*", "*.twig.compiled"))
+			if len(objects) != 2 {
+				t.Errorf("Expected 2 artifacts, got %d", len(objects))
+			}
+			hash := ContentHash(source)
+			if _, err := os.Stat(loader.objectPath(hash)); err != nil || !strings.HasPrefix(filepath.Base(loader.objectPath(hash)), hash) {
+				t.Errorf("Expected the artifact to be stored under its hash: %v", err)
+			}
+			if !loader.Exists("copy") || loader.Exists("missing") {
+				t.Error("Expected Exists to follow references")
+			}
+
+			fresh := New()
+			fresh.RegisterLoader(loader)
+			result, err := fresh.Render("copy", map[string]interface{}{"name": "World"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != "Hello World" {
+				t.Errorf("Expected %q, got %q", "Hello World", result)
+			}
+		})
+	}
+}
+
+func TestContentAddressedSaveSkipsUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	if err := engine.RegisterString("page", "{{ a }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	loader := NewCompiledLoader(dir)
+	loader.SetContentAddressed(true)
+	if err := loader.SaveCompiled(engine, "page"); err != nil {
+		t.Fatalf("Error saving template: %v", err)
+	}
+
+	// An artifact that already exists is not rewritten
+	hash := ContentHash("{{ a }}")
+	object := filepath.Join(dir, "objects", hash[:2], hash+".twig.compiled")
+	if err := os.WriteFile(object, []byte("sentinel"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.SaveCompiled(engine, "page"); err != nil {
+		t.Fatalf("Error saving template: %v", err)
+	}
+	if data, _ := os.ReadFile(object); string(data) != "sentinel" {
+		t.Error("Expected the existing artifact to be reused")
+	}
+}
+
+func TestContentAddressedSharedStore(t *testing.T) {
+	dir := t.TempDir()
+	engine := New()
+	if err := engine.RegisterString("page", "Hello {{ name }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	// Loaders with different compression write and read their own artifacts
+	plain := NewCompiledLoader(dir)
+	plain.SetContentAddressed(true)
+	gzipped := NewCompiledLoader(dir)
+	gzipped.SetContentAddressed(true)
+	gzipped.SetCompression(GzipCompression)
+
+	for _, loader := range []*CompiledLoader{gzipped, plain} {
+		if err := loader.SaveCompiled(engine, "page"); err != nil {
+			t.Fatalf("Error saving template: %v", err)
+		}
+	}
+	for _, loader := range []*CompiledLoader{plain, gzipped} {
+		source, err := loader.Load("page")
+		if err != nil {
+			t.Fatalf("Error loading template: %v", err)
+		}
+		if source != "Hello {{ name }}" {
+			t.Errorf("Expected the template source, got %q", source)
+		}
+	}
+}
+
+func TestGzipCompression(t *testing.T) {
+	data := []byte("compiled template data")
+	compressed, err := GzipCompression.Compress(data)
+	if err != nil {
+		t.Fatalf("Error compressing: %v", err)
+	}
+	decompressed, err := GzipCompression.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Error decompressing: %v", err)
+	}
+	if string(decompressed) != string(data) {
+		t.Errorf("Expected %q, got %q", data, decompressed)
+	}
+}
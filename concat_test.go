@@ -0,0 +1,58 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestConcatChains(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{"Two parts", "{{ 'a' ~ 'b' }}", nil, "ab"},
+		{"Long chain", "{{ 'a' ~ 'b' ~ 'c' ~ 'd' }}", nil, "abcd"},
+		{"Mixed types", "{{ 'n=' ~ n ~ ', f=' ~ f ~ ', b=' ~ b }}", map[string]interface{}{"n": 3, "f": 1.5, "b": true}, "n=3, f=1.5, b=true"},
+		{"Parenthesized", "{{ ('a' ~ 'b') ~ ('c' ~ 'd') }}", nil, "abcd"},
+		{"With higher precedence", "{{ 'x' ~ 2 * 3 ~ 'y' }}", nil, "x6y"},
+		{"In comparison", "{% if 'a' ~ 'b' == 'ab' %}yes{% endif %}", nil, "yes"},
+		{"With filter", "{{ ('a' ~ 'b' ~ 'c')|upper }}", nil, "ABC"},
+		{"In set", "{% set s = name ~ '!' ~ '!' %}{{ s }}", map[string]interface{}{"name": "hi"}, "hi!!"},
+		{"Undefined part", "{{ 'a' ~ missing ~ 'b' }}", nil, "ab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Chain parsed into single node", func(t *testing.T) {
+		parser := &Parser{}
+		node, err := parser.Parse("{{ a ~ b ~ c ~ d }}")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		print := node.(*RootNode).children[0].(*PrintNode)
+		concat, ok := print.expression.(*ConcatNode)
+		if !ok {
+			t.Fatalf("Expected ConcatNode, got %T", print.expression)
+		}
+		if len(concat.parts) != 4 {
+			t.Errorf("Expected 4 parts, got %d", len(concat.parts))
+		}
+	})
+}
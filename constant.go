@@ -0,0 +1,39 @@
+package twig
+
+import "fmt"
+
+// RegisterConstants makes values available to the constant function and
+// the constant test, so constant('STATUS_ACTIVE') and
+// x is constant('ROLE_ADMIN') work as in Twig. Names may use any form,
+// including PHP style class constants such as 'App\\User::ROLE_ADMIN'.
+func (e *Engine) RegisterConstants(constants map[string]interface{}) {
+	if e.environment.constants == nil {
+		e.environment.constants = make(map[string]interface{}, len(constants))
+	}
+	for name, value := range constants {
+		e.environment.constants[name] = value
+	}
+}
+
+// RegisterEnum registers the values of a Go enum as constants named by the
+// prefix followed by each value's String() in upper case, with spaces and
+// dashes turned into underscores. For an iota enum Status whose String
+// returns "active", RegisterEnum("STATUS_", StatusActive) registers
+// STATUS_ACTIVE.
+func (e *Engine) RegisterEnum(prefix string, values ...fmt.Stringer) {
+	constants := make(map[string]interface{}, len(values))
+	for _, value := range values {
+		constants[prefix+enumConstantName(value)] = value
+	}
+	e.RegisterConstants(constants)
+}
+
+// constant looks up a registered constant
+func (env *Environment) constant(name string) (interface{}, error) {
+	if env != nil {
+		if value, ok := env.constants[name]; ok {
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("constant %q is not defined", name)
+}
@@ -0,0 +1,89 @@
+package twig
+
+import "testing"
+
+// testStatus is an iota enum with a String method
+type testStatus int
+
+const (
+	testStatusDraft testStatus = iota
+	testStatusActive
+	testStatusOnHold
+)
+
+func (s testStatus) String() string {
+	return [...]string{"draft", "active", "on hold"}[s]
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "constant function",
+			source:   `{{ constant('MAX_ITEMS') }}`,
+			expected: "50",
+		},
+		{
+			name:     "class constant name",
+			source:   `{{ constant('App\\User::ROLE_ADMIN') }}`,
+			expected: "admin",
+		},
+		{
+			name:     "constant test",
+			source:   `{% if role is constant('App\\User::ROLE_ADMIN') %}admin{% else %}user{% endif %}`,
+			context:  map[string]interface{}{"role": "admin"},
+			expected: "admin",
+		},
+		{
+			name:     "constant test mismatch",
+			source:   `{% if role is constant('App\\User::ROLE_ADMIN') %}admin{% else %}user{% endif %}`,
+			context:  map[string]interface{}{"role": "editor"},
+			expected: "user",
+		},
+		{
+			name:     "enum values",
+			source:   `{% if status is constant('STATUS_ACTIVE') %}active{% endif %}|{{ constant('STATUS_ON_HOLD') }}`,
+			context:  map[string]interface{}{"status": testStatusActive},
+			expected: "active|on hold",
+		},
+		{
+			name:    "undefined constant",
+			source:  `{{ constant('MISSING') }}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.RegisterConstants(map[string]interface{}{
+				"MAX_ITEMS":             50,
+				"App\\User::ROLE_ADMIN": "admin",
+			})
+			engine.RegisterEnum("STATUS_", testStatusDraft, testStatusActive, testStatusOnHold)
+
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
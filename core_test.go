@@ -435,3 +435,21 @@ func TestCorePool(t *testing.T) {
 	// Release the second context
 	ctx2.Release()
 }
+
+// TestCoreRenderCachedTemplateTwice tests that a cached template can be rendered repeatedly
+func TestCoreRenderCachedTemplateTwice(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("repeat", "Hello {{ name }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := engine.Render("repeat", map[string]interface{}{"name": "World"})
+		if err != nil {
+			t.Fatalf("Error rendering template (pass %d): %v", i+1, err)
+		}
+		if result != "Hello World" {
+			t.Errorf("Pass %d: expected %q, got %q", i+1, "Hello World", result)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package twig
+
+import "io"
+
+// CountingWriter counts the bytes written through it to another writer, so
+// handlers can log payload sizes or set Content-Length without wrapping
+// writers themselves
+type CountingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// NewCountingWriter returns a CountingWriter writing to w
+func NewCountingWriter(w io.Writer) *CountingWriter {
+	return &CountingWriter{w: w}
+}
+
+// Write writes p to the underlying writer, counting the bytes it accepted
+func (c *CountingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// WriteString writes s to the underlying writer without converting it to
+// bytes when the writer supports that
+func (c *CountingWriter) WriteString(s string) (int, error) {
+	n, err := WriteString(c.w, s)
+	c.n += int64(n)
+	return n, err
+}
+
+// Count returns the number of bytes written so far
+func (c *CountingWriter) Count() int64 {
+	return c.n
+}
+
+// RenderToCount renders a template like RenderTo and returns the number of
+// bytes written to w. Writer middleware run before the count, so with a
+// middleware that compresses the output the count is the compressed size,
+// the value a buffering handler needs for Content-Length.
+func (e *Engine) RenderToCount(w io.Writer, name string, context map[string]interface{}, opts ...RenderOptions) (int64, error) {
+	counter := NewCountingWriter(w)
+	err := e.RenderTo(counter, name, context, opts...)
+	return counter.Count(), err
+}
+
+// RenderToCount renders the template like RenderTo and returns the number
+// of bytes written to w
+func (t *Template) RenderToCount(w io.Writer, context map[string]interface{}, opts ...RenderOptions) (int64, error) {
+	counter := NewCountingWriter(w)
+	err := t.RenderTo(counter, context, opts...)
+	return counter.Count(), err
+}
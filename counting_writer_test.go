@@ -0,0 +1,51 @@
+package twig
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenderToCount(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", "{% for i in range(1, 3) %}héllo {% endfor %}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := engine.RenderToCount(&buf, "page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if n != int64(buf.Len()) || n != int64(len("héllo héllo héllo ")) {
+		t.Errorf("Expected %d bytes, got %d", buf.Len(), n)
+	}
+
+	template, _ := engine.Load("page")
+	buf.Reset()
+	if n, err := template.RenderToCount(&buf, nil); err != nil || n != int64(buf.Len()) {
+		t.Errorf("Expected %d bytes, got %d (%v)", buf.Len(), n, err)
+	}
+
+	// With a compressing middleware the count is the compressed size
+	engine.UseWriter("gzip", func(next io.Writer, info RenderInfo) io.WriteCloser {
+		return gzip.NewWriter(next)
+	})
+	buf.Reset()
+	n, err = engine.RenderToCount(&buf, "page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected the compressed size %d, got %d", buf.Len(), n)
+	}
+	reader, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("Error reading gzip output: %v", err)
+	}
+	if out, _ := io.ReadAll(reader); !strings.HasPrefix(string(out), "héllo") {
+		t.Errorf("Expected the rendered page, got %q", out)
+	}
+}
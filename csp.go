@@ -0,0 +1,135 @@
+package twig
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSPNonceVar is the context variable holding the nonce of the current
+// render. RenderHTTP sets it; csp_nonce() creates it on first use otherwise.
+const CSPNonceVar = "_csp_nonce"
+
+// NonceProvider returns a fresh Content-Security-Policy nonce
+type NonceProvider func() (string, error)
+
+// IntegrityProvider returns the Subresource Integrity value of an asset,
+// such as "sha384-...", for the sri_hash function. Build pipelines that
+// already know their hashes can serve them from a manifest.
+type IntegrityProvider interface {
+	Integrity(path string) (string, error)
+}
+
+// IntegrityProviderFunc adapts a function to the IntegrityProvider interface
+type IntegrityProviderFunc func(path string) (string, error)
+
+// Integrity calls f(path)
+func (f IntegrityProviderFunc) Integrity(path string) (string, error) {
+	return f(path)
+}
+
+// SetNonceProvider sets how csp_nonce() and RenderHTTP create nonces. By
+// default a nonce is 16 bytes from the random source, base64 encoded.
+func (e *Engine) SetNonceProvider(provider NonceProvider) {
+	e.environment.nonceProvider = provider
+}
+
+// SetContentSecurityPolicy sets the Content-Security-Policy header sent by
+// RenderHTTP. Every {nonce} in the policy is replaced by the nonce that
+// csp_nonce() returns during the render, for example
+// "script-src 'nonce-{nonce}'".
+func (e *Engine) SetContentSecurityPolicy(policy string) {
+	e.environment.cspPolicy = policy
+}
+
+// SetIntegrityProvider sets the provider for sri_hash. Without one, files are
+// opened with the file resolver and hashed with SHA-384.
+func (e *Engine) SetIntegrityProvider(provider IntegrityProvider) {
+	e.environment.integrityProvider = provider
+	e.environment.integrityHashes.Clear()
+}
+
+// newNonce creates a nonce with the environment's provider
+func (env *Environment) newNonce() (string, error) {
+	if env != nil && env.nonceProvider != nil {
+		return env.nonceProvider()
+	}
+
+	b := make([]byte, 16)
+	if err := env.readRandom(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// cspHeader returns the policy with the nonce filled in
+func (env *Environment) cspHeader(nonce string) string {
+	return strings.ReplaceAll(env.cspPolicy, "{nonce}", nonce)
+}
+
+// callCSPNonceFunction implements csp_nonce(), returning the same nonce for
+// the whole render including included templates
+func (ctx *RenderContext) callCSPNonceFunction(args []interface{}) (interface{}, error) {
+	if nonce, ok := ctx.GetVariableOrNil(CSPNonceVar).(string); ok && nonce != "" {
+		return nonce, nil
+	}
+
+	nonce, err := ctx.env.newNonce()
+	if err != nil {
+		return nil, fmt.Errorf("csp_nonce: %w", err)
+	}
+	for c := ctx; c != nil; c = c.parent {
+		c.context[CSPNonceVar] = nonce
+	}
+	return nonce, nil
+}
+
+// functionSRIHash implements sri_hash(path), returning the integrity
+// attribute value for an asset. Results are cached per path.
+func (e *CoreExtension) functionSRIHash(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("sri_hash function requires a path argument")
+	}
+	if e.env == nil {
+		return nil, fmt.Errorf("sri_hash: no environment")
+	}
+
+	assetPath := e.toString(args[0])
+	if integrity, ok := e.env.integrityHashes.Load(assetPath); ok {
+		return integrity, nil
+	}
+
+	var integrity string
+	var err error
+	switch {
+	case e.env.integrityProvider != nil:
+		integrity, err = e.env.integrityProvider.Integrity(assetPath)
+	case e.env.fileResolver != nil:
+		integrity, err = fileIntegrity(e.env.fileResolver, assetPath)
+	default:
+		err = fmt.Errorf("no integrity provider or file resolver configured")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sri_hash: %w", err)
+	}
+
+	e.env.integrityHashes.Store(assetPath, integrity)
+	return integrity, nil
+}
+
+// fileIntegrity hashes a file with SHA-384
+func fileIntegrity(resolver FileResolver, path string) (string, error) {
+	file, err := resolver.OpenFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha512.New384()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return "sha384-" + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
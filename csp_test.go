@@ -0,0 +1,155 @@
+package twig
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCSPNonce(t *testing.T) {
+	engine := New()
+	engine.SetRandomSource(bytes.NewReader(bytes.Repeat([]byte{1}, 64)))
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"page.twig":   `<script nonce="{{ csp_nonce() }}"></script>{% include "widget.twig" %}`,
+		"widget.twig": `<script nonce="{{ csp_nonce() }}"></script>`,
+	}))
+
+	result, err := engine.Render("page.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	nonce := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 16))
+	expected := strings.Repeat(`<script nonce="`+nonce+`"></script>`, 2)
+	if result != expected {
+		t.Errorf("Expected one nonce per render, got %q", result)
+	}
+
+	// A nonce passed in the context is used as is
+	result, err = engine.Render("widget.twig", map[string]interface{}{CSPNonceVar: "given"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != `<script nonce="given"></script>` {
+		t.Errorf("Expected the context nonce, got %q", result)
+	}
+}
+
+func TestCSPHeader(t *testing.T) {
+	engine := New()
+	engine.SetNonceProvider(func() (string, error) { return "n0nce", nil })
+	engine.SetContentSecurityPolicy("script-src 'nonce-{nonce}'")
+	if err := engine.RegisterString("page", `<script nonce="{{ csp_nonce() }}"></script>`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	if err := engine.RenderHTTP(rec, req, "page", nil); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	if header := rec.Header().Get("Content-Security-Policy"); header != "script-src 'nonce-n0nce'" {
+		t.Errorf("Unexpected header %q", header)
+	}
+	if body := rec.Body.String(); body != `<script nonce="n0nce"></script>` {
+		t.Errorf("Unexpected body %q", body)
+	}
+}
+
+func TestSRIHash(t *testing.T) {
+	content := "console.log('hi')"
+	sum := sha512.Sum384([]byte(content))
+	fileHash := "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	opens := 0
+	files := FileResolverFunc(func(path string) (io.ReadCloser, error) {
+		opens++
+		if path != "app.js" {
+			return nil, errors.New("not found")
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	})
+
+	tests := []struct {
+		name     string
+		files    FileResolver
+		provider IntegrityProvider
+		source   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "hashes files",
+			files:    files,
+			source:   `<script src="app.js" integrity="{{ sri_hash('app.js') }}"></script>`,
+			expected: `<script src="app.js" integrity="` + fileHash + `"></script>`,
+		},
+		{
+			name: "provider",
+			provider: IntegrityProviderFunc(func(path string) (string, error) {
+				return "sha256-from-manifest", nil
+			}),
+			source:   `{{ sri_hash('app.js') }}`,
+			expected: "sha256-from-manifest",
+		},
+		{
+			name:    "missing file",
+			files:   files,
+			source:  `{{ sri_hash('missing.js') }}`,
+			wantErr: true,
+		},
+		{
+			name:    "not configured",
+			source:  `{{ sri_hash('app.js') }}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if tt.files != nil {
+				engine.SetFileResolver(tt.files)
+			}
+			if tt.provider != nil {
+				engine.SetIntegrityProvider(tt.provider)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Hashes are cached per path
+	engine := New()
+	engine.SetFileResolver(files)
+	if err := engine.RegisterString("test", `{{ sri_hash('app.js') }}{{ sri_hash('app.js') }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	opens = 0
+	if _, err := engine.Render("test", nil); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if opens != 1 {
+		t.Errorf("Expected the file to be hashed once, got %d opens", opens)
+	}
+}
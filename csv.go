@@ -0,0 +1,269 @@
+package twig
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// csvOptions control how the csv and tsv filters serialize rows
+type csvOptions struct {
+	columns   []string // Column order; taken from the first row when empty
+	delimiter byte
+	header    bool // Write a header row
+	quoteAll  bool // Quote every field instead of only those that need it
+}
+
+// csvFlushSize is how much encoded output csvStream buffers before writing
+// it out
+const csvFlushSize = 4096
+
+// csvStream encodes rows as it writes them, so printing a large export
+// writes it to the output a few rows at a time instead of building it in
+// memory. Filters and operators get the whole export through String. Each
+// use encodes the rows again, so the value can be printed more than once.
+type csvStream struct {
+	rows    reflect.Value
+	options csvOptions
+}
+
+// WriteTo encodes the rows to w
+func (s *csvStream) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var written int64
+	flush := func() error {
+		n, err := w.Write(buf.Bytes())
+		written += int64(n)
+		buf.Reset()
+		return err
+	}
+
+	if s.options.header && len(s.options.columns) > 0 {
+		s.writeRecord(&buf, s.options.columns)
+	}
+
+	record := make([]string, len(s.options.columns))
+	for i := 0; i < s.rows.Len(); i++ {
+		row := s.rows.Index(i)
+		for j, column := range s.options.columns {
+			record[j] = csvField(row, column)
+		}
+		s.writeRecord(&buf, record)
+
+		if buf.Len() >= csvFlushSize {
+			if err := flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+
+	if buf.Len() == 0 {
+		return written, nil
+	}
+	return written, flush()
+}
+
+// String encodes all rows
+func (s *csvStream) String() string {
+	var sb strings.Builder
+	s.WriteTo(&sb)
+	return sb.String()
+}
+
+// writeRecord encodes one line of fields into buf
+func (s *csvStream) writeRecord(buf *bytes.Buffer, record []string) {
+	for i, field := range record {
+		if i > 0 {
+			buf.WriteByte(s.options.delimiter)
+		}
+
+		if !s.options.quoteAll && !s.needsQuotes(field) {
+			buf.WriteString(field)
+			continue
+		}
+
+		buf.WriteByte('"')
+		buf.WriteString(strings.ReplaceAll(field, `"`, `""`))
+		buf.WriteByte('"')
+	}
+	buf.WriteString("\n")
+}
+
+// needsQuotes reports whether a field must be quoted to be read back
+func (s *csvStream) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field[0] == ' ' || field[0] == '\t' {
+		return true
+	}
+	return strings.IndexByte(field, s.options.delimiter) >= 0 || strings.ContainsAny(field, "\"\r\n")
+}
+
+// csvField returns the value of column in a map or struct row as text
+func csvField(row reflect.Value, column string) string {
+	for row.Kind() == reflect.Interface || row.Kind() == reflect.Ptr {
+		if row.IsNil() {
+			return ""
+		}
+		row = row.Elem()
+	}
+
+	var value reflect.Value
+	switch row.Kind() {
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return ""
+		}
+		value = row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+	case reflect.Struct:
+		if field, ok := csvStructField(row.Type(), column); ok {
+			value = row.FieldByIndex(field.Index)
+		}
+	}
+
+	if !value.IsValid() {
+		return ""
+	}
+	if (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && value.IsNil() {
+		return ""
+	}
+	return toString(value.Interface())
+}
+
+// csvStructField finds the struct field for a column, matching a csv tag
+// before the field name
+func csvStructField(t reflect.Type, column string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && csvColumnName(field) == column {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// csvColumnName returns the column name of a struct field, or "" to skip it
+func csvColumnName(field reflect.StructField) string {
+	tag := field.Tag.Get("csv")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// csvColumns derives the column list from the first row: sorted keys for
+// maps and exported fields in declaration order for structs
+func csvColumns(rows reflect.Value) []string {
+	if rows.Len() == 0 {
+		return nil
+	}
+
+	row := rows.Index(0)
+	for row.Kind() == reflect.Interface || row.Kind() == reflect.Ptr {
+		if row.IsNil() {
+			return nil
+		}
+		row = row.Elem()
+	}
+
+	var columns []string
+	switch row.Kind() {
+	case reflect.Map:
+		for _, key := range row.MapKeys() {
+			columns = append(columns, toString(key.Interface()))
+		}
+		sort.Strings(columns)
+	case reflect.Struct:
+		for i := 0; i < row.NumField(); i++ {
+			field := row.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if name := csvColumnName(field); name != "" {
+				columns = append(columns, name)
+			}
+		}
+	}
+	return columns
+}
+
+// parseCSVOptions reads the options hash passed to the csv and tsv filters
+func parseCSVOptions(args []interface{}, options csvOptions) (csvOptions, error) {
+	if len(args) == 0 || args[0] == nil {
+		return options, nil
+	}
+
+	hash, ok := args[0].(map[string]interface{})
+	if !ok {
+		return options, fmt.Errorf("csv filter options must be a hash, got %T", args[0])
+	}
+
+	for key, value := range hash {
+		switch key {
+		case "columns":
+			v := reflect.ValueOf(value)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return options, fmt.Errorf("csv filter columns must be a list, got %T", value)
+			}
+			options.columns = make([]string, v.Len())
+			for i := range options.columns {
+				options.columns[i] = toString(v.Index(i).Interface())
+			}
+		case "delimiter":
+			delimiter := toString(value)
+			if len(delimiter) != 1 {
+				return options, fmt.Errorf("csv filter delimiter must be a single character, got %q", delimiter)
+			}
+			options.delimiter = delimiter[0]
+		case "header":
+			options.header = toBool(value)
+		case "quote_all":
+			options.quoteAll = toBool(value)
+		default:
+			return options, fmt.Errorf("unknown csv filter option %q", key)
+		}
+	}
+
+	return options, nil
+}
+
+// newCSVStream prepares a stream for rows, which must be a list of maps or
+// structs
+func newCSVStream(value interface{}, args []interface{}, options csvOptions) (interface{}, error) {
+	options, err := parseCSVOptions(args, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		return "", nil
+	}
+
+	rows := reflect.ValueOf(value)
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return nil, fmt.Errorf("csv filter expects a list of rows, got %T", value)
+	}
+
+	if len(options.columns) == 0 {
+		options.columns = csvColumns(rows)
+	}
+
+	return &csvStream{rows: rows, options: options}, nil
+}
+
+// filterCSV serializes a list of maps or structs as comma separated values
+func (e *CoreExtension) filterCSV(value interface{}, args ...interface{}) (interface{}, error) {
+	return newCSVStream(value, args, csvOptions{delimiter: ',', header: true})
+}
+
+// filterTSV serializes a list of maps or structs as tab separated values
+func (e *CoreExtension) filterTSV(value interface{}, args ...interface{}) (interface{}, error) {
+	return newCSVStream(value, args, csvOptions{delimiter: '\t', header: true})
+}
@@ -0,0 +1,194 @@
+package twig
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type csvTestUser struct {
+	Name   string
+	Email  string `csv:"email"`
+	Age    int
+	secret string
+	Notes  string `csv:"-"`
+}
+
+func TestCSVFilters(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"name": "Alice", "city": "Paris"},
+		{"name": "Bob, Jr.", "city": `The "Big" Apple`},
+	}
+	users := []csvTestUser{
+		{Name: "Alice", Email: "alice@example.com", Age: 30, secret: "x", Notes: "skip"},
+		{Name: "Bob", Email: "bob@example.com", Age: 25},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "maps with sorted header",
+			source:   `{{ rows|csv }}`,
+			context:  map[string]interface{}{"rows": rows},
+			expected: "city,name\nParis,Alice\n\"The \"\"Big\"\" Apple\",\"Bob, Jr.\"\n",
+		},
+		{
+			name:     "structs use field order and csv tags",
+			source:   `{{ users|csv }}`,
+			context:  map[string]interface{}{"users": users},
+			expected: "Name,email,Age\nAlice,alice@example.com,30\nBob,bob@example.com,25\n",
+		},
+		{
+			name:     "explicit columns without header",
+			source:   `{{ rows|csv({"columns": ["name"], "header": false}) }}`,
+			context:  map[string]interface{}{"rows": rows},
+			expected: "Alice\n\"Bob, Jr.\"\n",
+		},
+		{
+			name:     "custom delimiter",
+			source:   `{{ rows|csv({"delimiter": ";", "columns": ["name", "city"]}) }}`,
+			context:  map[string]interface{}{"rows": rows[:1]},
+			expected: "name;city\nAlice;Paris\n",
+		},
+		{
+			name:     "quote all",
+			source:   `{{ rows|csv({"quote_all": true}) }}`,
+			context:  map[string]interface{}{"rows": rows[:1]},
+			expected: "\"city\",\"name\"\n\"Paris\",\"Alice\"\n",
+		},
+		{
+			name:     "tsv",
+			source:   `{{ rows|tsv }}`,
+			context:  map[string]interface{}{"rows": rows},
+			expected: "city\tname\nParis\tAlice\n\"The \"\"Big\"\" Apple\"\tBob, Jr.\n",
+		},
+		{
+			name:     "empty list",
+			source:   `[{{ rows|csv }}]`,
+			context:  map[string]interface{}{"rows": []map[string]interface{}{}},
+			expected: "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCSVFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"not a list", `{{ "text"|csv }}`},
+		{"unknown option", `{{ [{"a": 1}]|csv({"separator": ";"}) }}`},
+		{"long delimiter", `{{ [{"a": 1}]|csv({"delimiter": "::"}) }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			if _, err := engine.Render("test", nil); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+// writeRecorder records the size of each write
+type writeRecorder struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestCSVStreamsRows(t *testing.T) {
+	rows := make([]map[string]interface{}, 10000)
+	for i := range rows {
+		rows[i] = map[string]interface{}{"id": i}
+	}
+
+	ext := &CoreExtension{}
+	result, err := ext.filterCSV(rows)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stream, ok := result.(io.WriterTo)
+	if !ok {
+		t.Fatalf("Expected a stream, got %T", result)
+	}
+
+	// Rows are written out in small chunks, not built up in memory
+	var w writeRecorder
+	if _, err := stream.WriteTo(&w); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(w.writes) < 2 {
+		t.Errorf("Expected the rows to be written in chunks, got %d writes", len(w.writes))
+	}
+	for _, n := range w.writes {
+		if n > 2*csvFlushSize {
+			t.Errorf("Expected chunks of about %d bytes, got %d", csvFlushSize, n)
+		}
+	}
+	if lines := bytes.Count(w.Bytes(), []byte("\n")); lines != 10001 {
+		t.Errorf("Expected 10001 lines, got %d", lines)
+	}
+}
+
+func TestCSVFilterChaining(t *testing.T) {
+	context := map[string]interface{}{"rows": []map[string]interface{}{{"name": "<b>"}}}
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"escaped", `{{ rows|csv|e }}`, "name\n&lt;b&gt;\n"},
+		{"trimmed", `[{{ rows|csv|trim }}]`, "[name\n<b>]"},
+		{"printed twice", `{% set x = rows|csv %}{{ x }}|{{ x }}`, "name\n<b>\n|name\n<b>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Package datauri provides an optional Twig extension with qr_code and
+// inline_image functions, which return data URIs that can be embedded in
+// e-mails and tickets without hosting the images.
+//
+// The package does not generate QR codes itself. Plug in any QR library with
+// a BinaryHelper:
+//
+//	engine.AddExtension(&datauri.Extension{
+//		Root: "assets/images",
+//		QRCode: datauri.BinaryHelperFunc(func(args ...interface{}) ([]byte, string, error) {
+//			png, err := qrcode.Encode(fmt.Sprint(args[0]), qrcode.Medium, 256)
+//			return png, "image/png", err
+//		}),
+//	})
+package datauri
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/semihalev/twig"
+)
+
+// DefaultMaxSize is the largest file inline_image embeds by default
+const DefaultMaxSize = 1 << 20
+
+// BinaryHelper produces binary content, such as an image, from function
+// arguments, returning the data and its media type
+type BinaryHelper interface {
+	Generate(args ...interface{}) ([]byte, string, error)
+}
+
+// BinaryHelperFunc adapts a function to the BinaryHelper interface
+type BinaryHelperFunc func(args ...interface{}) ([]byte, string, error)
+
+// Generate calls f(args...)
+func (f BinaryHelperFunc) Generate(args ...interface{}) ([]byte, string, error) {
+	return f(args...)
+}
+
+// Extension adds the qr_code and inline_image functions
+type Extension struct {
+	Root    string                  // Directory inline_image reads files from
+	MaxSize int64                   // Largest file inline_image embeds, DefaultMaxSize when 0
+	QRCode  BinaryHelper            // Generates images for qr_code
+	Helpers map[string]BinaryHelper // Additional functions returning data URIs
+}
+
+// Encode returns data as a base64 data URI
+func Encode(data []byte, mediaType string) string {
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// GetName returns the name of the extension
+func (e *Extension) GetName() string {
+	return "datauri"
+}
+
+// GetFilters returns no filters
+func (e *Extension) GetFilters() map[string]twig.FilterFunc {
+	return map[string]twig.FilterFunc{}
+}
+
+// GetFunctions returns qr_code, inline_image and the additional helpers
+func (e *Extension) GetFunctions() map[string]twig.FunctionFunc {
+	functions := map[string]twig.FunctionFunc{
+		"qr_code":      e.functionQRCode,
+		"inline_image": e.functionInlineImage,
+	}
+	for name, helper := range e.Helpers {
+		functions[name] = helperFunction(name, helper)
+	}
+	return functions
+}
+
+// GetTests returns no tests
+func (e *Extension) GetTests() map[string]twig.TestFunc {
+	return map[string]twig.TestFunc{}
+}
+
+// GetOperators returns no operators
+func (e *Extension) GetOperators() map[string]twig.OperatorFunc {
+	return map[string]twig.OperatorFunc{}
+}
+
+// GetTokenParsers returns no token parsers
+func (e *Extension) GetTokenParsers() []twig.TokenParser {
+	return nil
+}
+
+// Initialize does nothing
+func (e *Extension) Initialize(engine *twig.Engine) {
+}
+
+// helperFunction wraps a helper as a function returning a data URI
+func helperFunction(name string, helper BinaryHelper) twig.FunctionFunc {
+	return func(args ...interface{}) (interface{}, error) {
+		data, mediaType, err := helper.Generate(args...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return Encode(data, mediaType), nil
+	}
+}
+
+// functionQRCode implements qr_code(data, ...) with the configured helper
+func (e *Extension) functionQRCode(args ...interface{}) (interface{}, error) {
+	if e.QRCode == nil {
+		return nil, fmt.Errorf("qr_code: no QR code generator configured")
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("qr_code requires a data argument")
+	}
+	return helperFunction("qr_code", e.QRCode)(args...)
+}
+
+// functionInlineImage implements inline_image(path), reading the file below
+// the extension's root
+func (e *Extension) functionInlineImage(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("inline_image requires a path argument")
+	}
+	if e.Root == "" {
+		return nil, fmt.Errorf("inline_image: no image root configured")
+	}
+
+	// Clean the path so templates cannot read files outside the root
+	name := path.Clean("/" + fmt.Sprint(args[0]))
+	file := filepath.Join(e.Root, filepath.FromSlash(name))
+
+	maxSize := e.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("inline_image: %w", err)
+	}
+	if info.Size() > maxSize {
+		return nil, fmt.Errorf("inline_image: %s is %d bytes, larger than the %d byte limit", name, info.Size(), maxSize)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("inline_image: %w", err)
+	}
+
+	mediaType := mime.TypeByExtension(strings.ToLower(filepath.Ext(file)))
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	return Encode(data, mediaType), nil
+}
@@ -0,0 +1,118 @@
+package datauri
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/semihalev/twig"
+)
+
+func render(t *testing.T, ext *Extension, source string) (string, error) {
+	t.Helper()
+
+	engine := twig.New()
+	engine.AddExtension(ext)
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+	return engine.Render("test", nil)
+}
+
+func TestQRCode(t *testing.T) {
+	ext := &Extension{
+		QRCode: BinaryHelperFunc(func(args ...interface{}) ([]byte, string, error) {
+			return []byte("qr:" + fmt.Sprint(args...)), "image/png", nil
+		}),
+	}
+
+	result, err := render(t, ext, `<img src="{{ qr_code('TICKET-42') }}">`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `<img src="data:image/png;base64,cXI6VElDS0VULTQy">`; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestQRCodeErrors(t *testing.T) {
+	if _, err := render(t, &Extension{}, `{{ qr_code('x') }}`); err == nil {
+		t.Error("Expected an error without a QR code generator")
+	}
+
+	ext := &Extension{
+		QRCode: BinaryHelperFunc(func(args ...interface{}) ([]byte, string, error) {
+			return nil, "", errors.New("data too long")
+		}),
+	}
+	_, err := render(t, ext, `{{ qr_code('x') }}`)
+	if err == nil || !strings.Contains(err.Error(), "data too long") {
+		t.Errorf("Expected the helper error, got %v", err)
+	}
+}
+
+func TestInlineImage(t *testing.T) {
+	ext := &Extension{Root: "testdata"}
+
+	tests := []struct {
+		name   string
+		source string
+		prefix string
+	}{
+		{"svg by extension", `{{ inline_image('dot.svg') }}`, "data:image/svg+xml;base64,PHN2Zy"},
+		{"png", `{{ inline_image('/pixel.png') }}`, "data:image/png;base64,iVBORw0KGgo"},
+		{"path stays inside root", `{{ inline_image('../testdata/../dot.svg') }}`, "data:image/svg+xml;base64,"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := render(t, ext, tt.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.HasPrefix(result, tt.prefix) {
+				t.Errorf("Expected prefix %q, got %q", tt.prefix, result)
+			}
+		})
+	}
+}
+
+func TestInlineImageErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		ext    *Extension
+		source string
+	}{
+		{"missing file", &Extension{Root: "testdata"}, `{{ inline_image('missing.png') }}`},
+		{"no root", &Extension{}, `{{ inline_image('dot.svg') }}`},
+		{"too large", &Extension{Root: "testdata", MaxSize: 4}, `{{ inline_image('dot.svg') }}`},
+		{"escaping the root", &Extension{Root: "testdata"}, `{{ inline_image('../datauri.go') }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := render(t, tt.ext, tt.source); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	ext := &Extension{
+		Helpers: map[string]BinaryHelper{
+			"barcode": BinaryHelperFunc(func(args ...interface{}) ([]byte, string, error) {
+				return []byte(fmt.Sprint(args...)), "image/gif", nil
+			}),
+		},
+	}
+
+	result, err := render(t, ext, `{{ barcode('hi') }}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "data:image/gif;base64,aGk="; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
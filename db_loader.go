@@ -0,0 +1,163 @@
+package twig
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DBTemplateStore reads templates from a database table in which every row
+// has a version that increases whenever its source is edited. Missing
+// templates are reported with an error wrapping ErrTemplateNotFound.
+type DBTemplateStore interface {
+	// TemplateVersion returns the current version of a template
+	TemplateVersion(name string) (int64, error)
+
+	// TemplateSource returns the source of a template and its version
+	TemplateSource(name string) (string, int64, error)
+}
+
+// DBLoader loads templates from a DBTemplateStore. Sources are cached by
+// version, so a load only reads the source column when the version changed.
+// The version doubles as the modification time, so engines with auto-reload
+// pick up edits on their own; Invalidate pushes an edit through immediately.
+type DBLoader struct {
+	store DBTemplateStore
+
+	mu      sync.Mutex
+	sources map[string]dbCacheEntry
+	engines []*Engine
+}
+
+// dbCacheEntry is a template source at one version
+type dbCacheEntry struct {
+	source  string
+	version int64
+}
+
+// NewDBLoader creates a loader that reads templates from store
+func NewDBLoader(store DBTemplateStore) *DBLoader {
+	return &DBLoader{
+		store:   store,
+		sources: make(map[string]dbCacheEntry),
+	}
+}
+
+// Attach registers the loader with an engine, and has Invalidate remove
+// edited templates from that engine's cache
+func (l *DBLoader) Attach(engine *Engine) {
+	engine.RegisterLoader(l)
+
+	l.mu.Lock()
+	l.engines = append(l.engines, engine)
+	l.mu.Unlock()
+}
+
+// Invalidate drops a template from the loader and from the engines it is
+// attached to. Call it when the database reports a change, for example from
+// a LISTEN/NOTIFY handler or the CMS save hook.
+func (l *DBLoader) Invalidate(name string) {
+	l.mu.Lock()
+	delete(l.sources, name)
+	engines := append([]*Engine(nil), l.engines...)
+	l.mu.Unlock()
+
+	for _, engine := range engines {
+		engine.InvalidateTemplate(name)
+	}
+}
+
+// Load implements the Loader interface
+func (l *DBLoader) Load(name string) (string, error) {
+	version, err := l.store.TemplateVersion(name)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	cached, ok := l.sources[name]
+	l.mu.Unlock()
+	if ok && cached.version == version {
+		return cached.source, nil
+	}
+
+	source, version, err := l.store.TemplateSource(name)
+	if err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	l.sources[name] = dbCacheEntry{source: source, version: version}
+	l.mu.Unlock()
+	return source, nil
+}
+
+// Exists checks if the table has the template
+func (l *DBLoader) Exists(name string) bool {
+	_, err := l.store.TemplateVersion(name)
+	return err == nil
+}
+
+// GetModifiedTime returns the version of a template
+func (l *DBLoader) GetModifiedTime(name string) (int64, error) {
+	return l.store.TemplateVersion(name)
+}
+
+// SQLQueryer runs a query returning at most one row. *sql.DB, *sql.Conn and
+// *sql.Tx satisfy it.
+type SQLQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// SQLTemplateStore is a DBTemplateStore for a table with name, source and
+// version columns, read through database/sql with any driver
+type SQLTemplateStore struct {
+	db           SQLQueryer
+	versionQuery string
+	sourceQuery  string
+}
+
+// NewSQLTemplateStore creates a store reading the name, source and version
+// columns of table. placeholder is the driver's parameter marker, "?" for
+// MySQL and SQLite or "$1" for PostgreSQL.
+func NewSQLTemplateStore(db SQLQueryer, table, placeholder string) *SQLTemplateStore {
+	return NewSQLTemplateStoreColumns(db, table, "name", "source", "version", placeholder)
+}
+
+// NewSQLTemplateStoreColumns creates a store for a table with custom column
+// names. The table and column names are written into the queries as given.
+func NewSQLTemplateStoreColumns(db SQLQueryer, table, nameColumn, sourceColumn, versionColumn, placeholder string) *SQLTemplateStore {
+	return &SQLTemplateStore{
+		db:           db,
+		versionQuery: fmt.Sprintf("SELECT %s FROM %s WHERE %s = %s", versionColumn, table, nameColumn, placeholder),
+		sourceQuery:  fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s = %s", sourceColumn, versionColumn, table, nameColumn, placeholder),
+	}
+}
+
+// TemplateVersion returns the version column of a template
+func (s *SQLTemplateStore) TemplateVersion(name string) (int64, error) {
+	var version int64
+	if err := s.db.QueryRow(s.versionQuery, name).Scan(&version); err != nil {
+		return 0, s.wrapError(name, err)
+	}
+	return version, nil
+}
+
+// TemplateSource returns the source and version columns of a template
+func (s *SQLTemplateStore) TemplateSource(name string) (string, int64, error) {
+	var source string
+	var version int64
+	if err := s.db.QueryRow(s.sourceQuery, name).Scan(&source, &version); err != nil {
+		return "", 0, s.wrapError(name, err)
+	}
+	return source, version, nil
+}
+
+// wrapError maps a missing row to ErrTemplateNotFound
+func (s *SQLTemplateStore) wrapError(name string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return fmt.Errorf("error reading template %s: %w", name, err)
+}
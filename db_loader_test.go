@@ -0,0 +1,200 @@
+package twig
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryStore is a DBTemplateStore counting how often sources are read
+type memoryStore struct {
+	sources     map[string]string
+	versions    map[string]int64
+	sourceReads int
+}
+
+func (s *memoryStore) TemplateVersion(name string) (int64, error) {
+	version, ok := s.versions[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return version, nil
+}
+
+func (s *memoryStore) TemplateSource(name string) (string, int64, error) {
+	s.sourceReads++
+	version, err := s.TemplateVersion(name)
+	return s.sources[name], version, err
+}
+
+func (s *memoryStore) save(name, source string) {
+	s.sources[name] = source
+	s.versions[name]++
+}
+
+func TestDBLoader(t *testing.T) {
+	store := &memoryStore{sources: map[string]string{}, versions: map[string]int64{}}
+	store.save("page", "v1")
+	loader := NewDBLoader(store)
+
+	for i := 0; i < 3; i++ {
+		source, err := loader.Load("page")
+		if err != nil || source != "v1" {
+			t.Fatalf("Expected v1, got %q, %v", source, err)
+		}
+	}
+	if store.sourceReads != 1 {
+		t.Errorf("Expected the source to be read once per version, got %d reads", store.sourceReads)
+	}
+
+	store.save("page", "v2")
+	if source, _ := loader.Load("page"); source != "v2" {
+		t.Errorf("Expected v2 after a version change, got %q", source)
+	}
+	if version, _ := loader.GetModifiedTime("page"); version != 2 {
+		t.Errorf("Expected version 2 as modification time, got %d", version)
+	}
+	if loader.Exists("missing") {
+		t.Error("Expected a missing template not to exist")
+	}
+}
+
+func TestDBLoaderInvalidate(t *testing.T) {
+	store := &memoryStore{sources: map[string]string{}, versions: map[string]int64{}}
+	store.save("page.twig", `{% include "nav.twig" %}`)
+	store.save("nav.twig", `old`)
+
+	engine := New()
+	loader := NewDBLoader(store)
+	loader.Attach(engine)
+
+	render := func(expected string) {
+		t.Helper()
+		result, err := engine.Render("page.twig", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	}
+
+	render("old")
+
+	// Without auto-reload the engine keeps its cached copy until notified
+	store.save("nav.twig", `new`)
+	render("old")
+	loader.Invalidate("nav.twig")
+	render("new")
+}
+
+func TestSQLTemplateStore(t *testing.T) {
+	db, err := sql.Open("twig_fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fakeRows.Lock()
+	fakeRows.templates = map[string][2]interface{}{
+		"page.twig": {"Hello {{ name }}", int64(7)},
+	}
+	fakeRows.Unlock()
+
+	store := NewSQLTemplateStore(db, "templates", "?")
+	version, err := store.TemplateVersion("page.twig")
+	if err != nil || version != 7 {
+		t.Fatalf("Expected version 7, got %d, %v", version, err)
+	}
+
+	source, version, err := store.TemplateSource("page.twig")
+	if err != nil || source != "Hello {{ name }}" || version != 7 {
+		t.Fatalf("Unexpected source %q version %d: %v", source, version, err)
+	}
+
+	if _, err := store.TemplateVersion("missing.twig"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+
+	engine := New()
+	NewDBLoader(store).Attach(engine)
+	result, err := engine.Render("page.twig", map[string]interface{}{"name": "World"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Hello World" {
+		t.Errorf("Expected %q, got %q", "Hello World", result)
+	}
+}
+
+// fakeRows holds the rows served by the twig_fake driver, keyed by name
+var fakeRows struct {
+	sync.Mutex
+	templates map[string][2]interface{}
+}
+
+func init() {
+	sql.Register("twig_fake", fakeDriver{})
+}
+
+// fakeDriver answers the two queries of SQLTemplateStore
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct{ query string }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	fakeRows.Lock()
+	row, ok := fakeRows.templates[args[0].(string)]
+	fakeRows.Unlock()
+
+	rows := &fakeResult{}
+	if strings.HasPrefix(s.query, "SELECT version FROM templates WHERE name = ?") {
+		rows.columns = []string{"version"}
+		if ok {
+			rows.values = [][]driver.Value{{row[1]}}
+		}
+	} else if strings.HasPrefix(s.query, "SELECT source, version FROM templates WHERE name = ?") {
+		rows.columns = []string{"source", "version"}
+		if ok {
+			rows.values = [][]driver.Value{{row[0], row[1]}}
+		}
+	} else {
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	return rows, nil
+}
+
+type fakeResult struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeResult) Columns() []string { return r.columns }
+func (r *fakeResult) Close() error      { return nil }
+func (r *fakeResult) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -0,0 +1,195 @@
+package twig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DebugPanelRender describes one RenderHTTP call made while handling a
+// request wrapped by DebugPanel
+type DebugPanelRender struct {
+	Template      string
+	Duration      time.Duration // Time spent rendering
+	ParseDuration time.Duration // Time the template took to parse
+	ContextKeys   []string      // Sorted names of the context variables
+	Profile       *OutputProfile
+	Err           error
+}
+
+// DebugPanelData collects the renders of a single request
+type DebugPanelData struct {
+	Renders    []DebugPanelRender
+	Violations []string // Sandbox violations that failed a render
+}
+
+// debugPanelKey is the request context key holding the DebugPanelData
+type debugPanelKey struct{}
+
+// DebugPanelFrom returns the data collected for a request wrapped by
+// DebugPanel, or nil when the request is not
+func DebugPanelFrom(r *http.Request) *DebugPanelData {
+	data, _ := r.Context().Value(debugPanelKey{}).(*DebugPanelData)
+	return data
+}
+
+// DebugPanel wraps a handler to inject a collapsible panel into its HTML
+// responses listing the templates rendered with RenderHTTP, their timings
+// and output sizes, the context keys they received and any sandbox
+// violations. The panel is meant for development: outside debug mode the
+// handler is returned unchanged.
+func (e *Engine) DebugPanel(next http.Handler) http.Handler {
+	if !e.environment.debug {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := &DebugPanelData{}
+		r = r.WithContext(context.WithValue(r.Context(), debugPanelKey{}, data))
+
+		rec := &debugPanelRecorder{header: w.Header(), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if isHTMLResponse(rec.header, body) && len(data.Renders) > 0 {
+			body = injectDebugPanel(body, data.HTML())
+			rec.header.Del("Content-Length")
+		}
+
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+// record adds a render to the request's panel
+func (d *DebugPanelData) record(render DebugPanelRender) {
+	var violation *SecurityViolation
+	if errors.As(render.Err, &violation) {
+		d.Violations = append(d.Violations, violation.Message)
+	}
+	d.Renders = append(d.Renders, render)
+}
+
+// HTML formats the collected data as the panel markup
+func (d *DebugPanelData) HTML() string {
+	var total time.Duration
+	for _, render := range d.Renders {
+		total += render.Duration
+	}
+
+	var b strings.Builder
+	b.WriteString(`<details id="twig-debug-panel" style="position:fixed;bottom:0;right:0;z-index:99999;max-width:60%;max-height:60%;overflow:auto;background:#fff;color:#222;border:1px solid #999;font:12px monospace;padding:4px 8px">`)
+	fmt.Fprintf(&b, "<summary>Twig: %d template(s), %s", len(d.Renders), total)
+	if len(d.Violations) > 0 {
+		fmt.Fprintf(&b, `, <strong style="color:#b0413e">%d sandbox violation(s)</strong>`, len(d.Violations))
+	}
+	b.WriteString("</summary>")
+
+	for _, render := range d.Renders {
+		fmt.Fprintf(&b, "<h4>%s</h4><p>rendered in %s, parsed in %s",
+			html.EscapeString(render.Template), render.Duration, render.ParseDuration)
+		if render.Profile != nil {
+			fmt.Fprintf(&b, ", %d bytes", render.Profile.Total)
+		}
+		b.WriteString("</p>")
+		if render.Err != nil {
+			fmt.Fprintf(&b, `<p style="color:#b0413e">%s</p>`, html.EscapeString(render.Err.Error()))
+		}
+		if len(render.ContextKeys) > 0 {
+			fmt.Fprintf(&b, "<p>context: %s</p>", html.EscapeString(strings.Join(render.ContextKeys, ", ")))
+		}
+		if render.Profile != nil && len(render.Profile.Entries) > 0 {
+			fmt.Fprintf(&b, "<pre>%s</pre>", html.EscapeString(render.Profile.String()))
+		}
+	}
+
+	if len(d.Violations) > 0 {
+		b.WriteString("<h4>Sandbox violations</h4><ul>")
+		for _, violation := range d.Violations {
+			fmt.Fprintf(&b, "<li>%s</li>", html.EscapeString(violation))
+		}
+		b.WriteString("</ul>")
+	}
+
+	b.WriteString("</details>")
+	return b.String()
+}
+
+// renderHTTPProfiled renders a template for RenderHTTP while recording it
+// in the request's debug panel
+func (e *Engine) renderHTTPProfiled(w io.Writer, name string, context map[string]interface{}, data *DebugPanelData) error {
+	render := DebugPanelRender{
+		Template:    name,
+		ContextKeys: make([]string, 0, len(context)),
+		Profile:     &OutputProfile{},
+	}
+	for key := range context {
+		render.ContextKeys = append(render.ContextKeys, key)
+	}
+	sort.Strings(render.ContextKeys)
+
+	start := time.Now()
+	template, err := e.loadResolved(name, context)
+	if err != nil {
+		template, err = e.loadFallback(err)
+	}
+	if err == nil {
+		render.ParseDuration = template.ParseDuration()
+
+		chain := e.wrapWriter(w, name, context)
+		if chain == nil {
+			err = template.renderProfiled(w, context, render.Profile, nil)
+		} else {
+			err = template.renderProfiled(chain, context, render.Profile, nil)
+			if closeErr := chain.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	}
+	render.Duration = time.Since(start)
+	render.Err = err
+
+	data.record(render)
+	return err
+}
+
+// debugPanelRecorder buffers a response so the panel can be injected
+type debugPanelRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *debugPanelRecorder) Header() http.Header         { return r.header }
+func (r *debugPanelRecorder) WriteHeader(status int)      { r.status = status }
+func (r *debugPanelRecorder) Write(p []byte) (int, error) { return r.body.Write(p) }
+
+// isHTMLResponse reports whether a response is an HTML page
+func isHTMLResponse(header http.Header, body []byte) bool {
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return strings.HasPrefix(contentType, "text/html")
+}
+
+// injectDebugPanel inserts the panel before the closing body tag, or
+// appends it when the page has none
+func injectDebugPanel(body []byte, panel string) []byte {
+	index := bytes.LastIndex(bytes.ToLower(body), []byte("</body>"))
+	if index < 0 {
+		return append(body, panel...)
+	}
+
+	result := make([]byte, 0, len(body)+len(panel))
+	result = append(result, body[:index]...)
+	result = append(result, panel...)
+	return append(result, body[index:]...)
+}
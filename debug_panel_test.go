@@ -0,0 +1,79 @@
+package twig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugPanel(t *testing.T) {
+	engine := New()
+	engine.SetDebug(true)
+	defer engine.SetDebug(false)
+
+	templates := map[string]string{
+		"page":    `<html><body>{% include 'partial' %}</body></html>`,
+		"partial": `<p>{{ title }}</p>`,
+		"json":    `{"title": "{{ title }}"}`,
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	serve := func(name, contentType string) *httptest.ResponseRecorder {
+		handler := engine.DebugPanel(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			if err := engine.RenderHTTP(w, r, name, map[string]interface{}{"title": "Hi"}); err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+		}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	t.Run("html", func(t *testing.T) {
+		body := serve("page", "").Body.String()
+		if !strings.HasPrefix(body, "<html><body><p>Hi</p>") || !strings.HasSuffix(body, "</details></body></html>") {
+			t.Fatalf("Expected the panel before </body>, got %q", body)
+		}
+		for _, expected := range []string{`id="twig-debug-panel"`, "<h4>page</h4>", "context: app, title", "include partial"} {
+			if !strings.Contains(body, expected) {
+				t.Errorf("Expected the panel to contain %q, got %q", expected, body)
+			}
+		}
+	})
+
+	t.Run("non html", func(t *testing.T) {
+		body := serve("json", "application/json").Body.String()
+		if body != `{"title": "Hi"}` {
+			t.Errorf("Expected JSON responses untouched, got %q", body)
+		}
+	})
+
+	t.Run("debug off", func(t *testing.T) {
+		engine.SetDebug(false)
+		body := serve("page", "").Body.String()
+		if body != "<html><body><p>Hi</p></body></html>" {
+			t.Errorf("Expected no panel outside debug mode, got %q", body)
+		}
+	})
+}
+
+func TestDebugPanelViolations(t *testing.T) {
+	data := &DebugPanelData{}
+	data.record(DebugPanelRender{Template: "page", Err: NewFilterViolation("upper")})
+
+	if len(data.Violations) != 1 {
+		t.Fatalf("Expected one violation, got %v", data.Violations)
+	}
+	panel := data.HTML()
+	if !strings.Contains(panel, "1 sandbox violation(s)") || !strings.Contains(panel, "Filter &#39;upper&#39; is not allowed") {
+		t.Errorf("Expected the violation in the panel, got %q", panel)
+	}
+}
@@ -157,7 +157,7 @@ func TraceSection(name string) func() {
 // DebugRender enables detailed rendering information
 func DebugRender(w io.Writer, tmpl *Template, ctx *RenderContext) error {
 	if !debugger.enabled {
-		return tmpl.RenderTo(w, ctx.context)
+		return tmpl.RenderTo(w, ctx.context, ctx.renderOptions()...)
 	}
 
 	LogInfo("Rendering template %s with context containing %d variables",
@@ -177,7 +177,7 @@ func DebugRender(w io.Writer, tmpl *Template, ctx *RenderContext) error {
 	// Trace full template rendering
 	defer StartTrace(tmpl.name)()
 
-	return tmpl.RenderTo(w, ctx.context)
+	return tmpl.RenderTo(w, ctx.context, ctx.renderOptions()...)
 }
 
 // FormatErrorContext creates a formatted context for syntax errors
@@ -219,6 +219,20 @@ func FormatErrorContext(source string, position int, line int) string {
 	return context
 }
 
+// TemplateFrame identifies a position in one template of an include chain
+type TemplateFrame struct {
+	Template string
+	Line     int
+}
+
+// String formats the frame as "name line N"
+func (f TemplateFrame) String() string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s line %d", f.Template, f.Line)
+	}
+	return f.Template
+}
+
 // EnhancedError provides more detailed error information for debugging
 type EnhancedError struct {
 	Err       error
@@ -227,6 +241,14 @@ type EnhancedError struct {
 	Column    int
 	Source    string
 	SourceCtx string
+	Stack     []TemplateFrame // Templates that included/extended/imported Template, outermost first
+}
+
+// TemplateChain returns the full include chain ending with the failing template
+func (e *EnhancedError) TemplateChain() []TemplateFrame {
+	chain := make([]TemplateFrame, 0, len(e.Stack)+1)
+	chain = append(chain, e.Stack...)
+	return append(chain, TemplateFrame{Template: e.Template, Line: e.Line})
 }
 
 // Error implements the error interface
@@ -249,8 +271,15 @@ func (e *EnhancedError) Error() string {
 	}
 
 	context := ""
+	if len(e.Stack) > 0 {
+		frames := make([]string, 0, len(e.Stack)+1)
+		for _, frame := range e.TemplateChain() {
+			frames = append(frames, frame.String())
+		}
+		context = "\nTemplate stack: " + strings.Join(frames, " → ")
+	}
 	if e.SourceCtx != "" {
-		context = "\n" + e.SourceCtx
+		context += "\n" + e.SourceCtx
 	}
 
 	return fmt.Sprintf("Error %s%s: %s%s", location, position, e.Err.Error(), context)
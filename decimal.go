@@ -0,0 +1,250 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// decimalMethods maps arithmetic operators to the method names used by
+// arbitrary-precision decimal types such as shopspring/decimal.Decimal
+var decimalMethods = map[string]string{
+	"+": "Add",
+	"-": "Sub",
+	"*": "Mul",
+	"/": "Div",
+}
+
+// hasDecimalOperand reports whether operator is arithmetic and either
+// operand is a decimal type
+func hasDecimalOperand(operator string, left, right interface{}) bool {
+	switch operator {
+	case "+", "-", "*", "/":
+		return isDecimalValue(left) || isDecimalValue(right)
+	}
+	return false
+}
+
+// applyDecimalOperation applies an arithmetic operator to operands of which
+// at least one is a decimal type. Two values of the same type with a
+// matching method (e.g. Add(T) T) use that method, so the result keeps the
+// decimal type. A decimal mixed with a plain number is computed exactly
+// with big.Rat.
+func applyDecimalOperation(operator string, left, right interface{}) (interface{}, error) {
+	if result, ok, err := callDecimalMethod(decimalMethods[operator], left, right); ok {
+		return result, err
+	}
+	return ratOperation(operator, left, right)
+}
+
+// isDecimalValue reports whether v is a non-primitive value that has an Add
+// method and formats as a decimal number, the shape of common decimal types
+func isDecimalValue(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64:
+		return false
+	}
+
+	stringer, ok := v.(fmt.Stringer)
+	if !ok {
+		return false
+	}
+
+	if _, ok := reflect.TypeOf(v).MethodByName("Add"); !ok {
+		return false
+	}
+
+	_, ok = new(big.Rat).SetString(stringer.String())
+	return ok
+}
+
+// callDecimalMethod calls left.method(right) when both values share a type
+// and the method has the signature func(T) T
+func callDecimalMethod(method string, left, right interface{}) (result interface{}, ok bool, err error) {
+	lv := reflect.ValueOf(left)
+	rv := reflect.ValueOf(right)
+	if !lv.IsValid() || !rv.IsValid() || lv.Type() != rv.Type() {
+		return nil, false, nil
+	}
+
+	m := lv.MethodByName(method)
+	if !m.IsValid() {
+		return nil, false, nil
+	}
+
+	mt := m.Type()
+	if mt.NumIn() != 1 || mt.NumOut() != 1 || mt.In(0) != lv.Type() || mt.Out(0) != lv.Type() {
+		return nil, false, nil
+	}
+
+	// Decimal libraries panic on division by zero
+	defer func() {
+		if r := recover(); r != nil {
+			result, ok, err = nil, true, fmt.Errorf("decimal %s failed: %v", method, r)
+		}
+	}()
+
+	return m.Call([]reflect.Value{rv})[0].Interface(), true, nil
+}
+
+// toRat converts a number, numeric string or decimal value to an exact big.Rat.
+// Floats are converted from their shortest decimal form, so 19.99 becomes
+// exactly 1999/100 rather than the nearest binary fraction.
+func toRat(v interface{}) (*big.Rat, bool) {
+	var s string
+
+	switch val := v.(type) {
+	case nil:
+		return nil, false
+	case *big.Rat:
+		return new(big.Rat).Set(val), true
+	case int:
+		return new(big.Rat).SetInt64(int64(val)), true
+	case int64:
+		return new(big.Rat).SetInt64(val), true
+	case float64:
+		s = strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case string:
+		s = val
+	case fmt.Stringer:
+		s = val.String()
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return new(big.Rat).SetInt64(rv.Int()), true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return new(big.Rat).SetUint64(rv.Uint()), true
+		case reflect.Float32, reflect.Float64:
+			s = strconv.FormatFloat(rv.Float(), 'f', -1, 64)
+		default:
+			return nil, false
+		}
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	return r, ok
+}
+
+// ratOperation applies an arithmetic operator exactly and returns the result
+// as the float64 closest to the exact decimal value
+func ratOperation(operator string, left, right interface{}) (interface{}, error) {
+	l, ok := toRat(left)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %T as a decimal number", left)
+	}
+	r, ok := toRat(right)
+	if !ok {
+		return nil, fmt.Errorf("cannot use %T as a decimal number", right)
+	}
+
+	result := new(big.Rat)
+	switch operator {
+	case "+":
+		result.Add(l, r)
+	case "-":
+		result.Sub(l, r)
+	case "*":
+		result.Mul(l, r)
+	case "/":
+		if r.Sign() == 0 {
+			return nil, errors.New("division by zero")
+		}
+		result.Quo(l, r)
+	default:
+		return nil, fmt.Errorf("unsupported decimal operator '%s'", operator)
+	}
+
+	f, _ := result.Float64()
+	return f, nil
+}
+
+// roundHalfEven rounds r to precision decimal places, rounding ties to the
+// nearest even digit (banker's rounding)
+func roundHalfEven(r *big.Rat, precision int) *big.Rat {
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(precision))), nil))
+	scaled := new(big.Rat).Set(r)
+	if precision >= 0 {
+		scaled.Mul(scaled, scale)
+	} else {
+		scaled.Quo(scaled, scale)
+	}
+
+	// Split into integer quotient and remainder, truncating toward zero
+	quo, rem := new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+
+	// Compare twice the remainder with the denominator to find the tie
+	twiceRem := new(big.Int).Abs(rem)
+	twiceRem.Lsh(twiceRem, 1)
+	switch cmp := twiceRem.Cmp(scaled.Denom()); {
+	case cmp > 0, cmp == 0 && quo.Bit(0) == 1:
+		if scaled.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+
+	result := new(big.Rat).SetInt(quo)
+	if precision >= 0 {
+		return result.Quo(result, scale)
+	}
+	return result.Mul(result, scale)
+}
+
+// abs returns the absolute value of an int
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// moneyFilter builds a filter applying an operator with exact decimal math
+func moneyFilter(operator string) FilterFunc {
+	return func(value interface{}, args ...interface{}) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("money filter requires an operand")
+		}
+		if value == nil {
+			value = 0
+		}
+
+		if hasDecimalOperand(operator, value, args[0]) {
+			return applyDecimalOperation(operator, value, args[0])
+		}
+		return ratOperation(operator, value, args[0])
+	}
+}
+
+// filterRoundHalfEven rounds a number using banker's rounding
+func (e *CoreExtension) filterRoundHalfEven(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	r, ok := toRat(value)
+	if !ok {
+		return value, nil
+	}
+
+	precision := 0
+	if len(args) > 0 {
+		if p, err := toInt(args[0]); err == nil {
+			precision = p
+		}
+	}
+
+	f, _ := roundHalfEven(r, precision).Float64()
+
+	// If precision is 0, return an integer like round does
+	if precision <= 0 {
+		return int(f), nil
+	}
+	return f, nil
+}
@@ -0,0 +1,119 @@
+package twig
+
+import (
+	"math/big"
+	"testing"
+)
+
+// testDecimal mimics the method set of common decimal libraries
+type testDecimal struct {
+	r *big.Rat
+}
+
+func newTestDecimal(s string) testDecimal {
+	r, _ := new(big.Rat).SetString(s)
+	return testDecimal{r: r}
+}
+
+func (d testDecimal) Add(o testDecimal) testDecimal { return testDecimal{new(big.Rat).Add(d.r, o.r)} }
+func (d testDecimal) Sub(o testDecimal) testDecimal { return testDecimal{new(big.Rat).Sub(d.r, o.r)} }
+func (d testDecimal) Mul(o testDecimal) testDecimal { return testDecimal{new(big.Rat).Mul(d.r, o.r)} }
+func (d testDecimal) Div(o testDecimal) testDecimal { return testDecimal{new(big.Rat).Quo(d.r, o.r)} }
+func (d testDecimal) String() string                { return d.r.FloatString(2) }
+
+func TestMoneyFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Add", "{{ 0.1|money_add(0.2) }}", "0.3"},
+		{"Add prices", "{{ 10.01|money_add(9.98) }}", "19.99"},
+		{"Subtract", "{{ 19.99|money_sub(0.99) }}", "19"},
+		{"Multiply", "{{ 19.99|money_mul(3) }}", "59.97"},
+		{"Multiply by rate", "{{ 1.15|money_mul(100) }}", "115"},
+		{"Divide", "{{ 10|money_div(4) }}", "2.5"},
+		{"Chain", "{{ 4.35|money_mul(100)|money_add(0.01) }}", "435.01"},
+		{"Numeric strings", "{{ '19.99'|money_add('0.01') }}", "20"},
+		{"Nil value", "{{ missing|money_add(1.1) }}", "1.1"},
+		{"Round half even down", "{{ 2.5|round_half_even }}", "2"},
+		{"Round half even up", "{{ 3.5|round_half_even }}", "4"},
+		{"Round half even negative", "{{ -2.5|round_half_even }}", "-2"},
+		{"Round half even precision", "{{ 2.675|round_half_even(2) }}", "2.68"},
+		{"Round half even precision tie down", "{{ 2.665|round_half_even(2) }}", "2.66"},
+		{"Round half even not a tie", "{{ 2.6651|round_half_even(2) }}", "2.67"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Divide by zero", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("test", "{{ 10|money_div(0) }}")
+		if _, err := engine.Render("test", nil); err == nil {
+			t.Error("Expected division by zero error")
+		}
+	})
+}
+
+func TestDecimalOperators(t *testing.T) {
+	context := map[string]interface{}{
+		"price": newTestDecimal("19.99"),
+		"tax":   newTestDecimal("0.01"),
+		"zero":  newTestDecimal("0"),
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Add decimals", "{{ price + tax }}", "20.00"},
+		{"Subtract decimals", "{{ price - tax }}", "19.98"},
+		{"Multiply decimals", "{{ price * tax }}", "0.20"},
+		{"Divide decimals", "{{ price / tax }}", "1999.00"},
+		{"Decimal with number", "{{ price * 3 }}", "59.97"},
+		{"Number with decimal", "{{ 0.01 + price }}", "20"},
+		{"Money filter keeps decimal type", "{{ price|money_add(tax) }}", "20.00"},
+		{"Comparison unaffected", "{% if price == price %}yes{% endif %}", "yes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Decimal division by zero", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("test", "{{ price / zero }}")
+		if _, err := engine.Render("test", context); err == nil {
+			t.Error("Expected error when dividing a decimal by zero")
+		}
+	})
+}
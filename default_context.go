@@ -0,0 +1,37 @@
+package twig
+
+// SetDefaultContext sets values merged under the context of every render,
+// such as site_name. Unlike globals, they are ordinary context variables: a
+// value passed to Render under the same name replaces the default, and
+// include with only hides them. The map is copied; nil clears the defaults.
+func (e *Engine) SetDefaultContext(defaults map[string]interface{}) {
+	if len(defaults) == 0 {
+		e.defaultContext = nil
+		return
+	}
+
+	e.defaultContext = make(map[string]interface{}, len(defaults))
+	for name, value := range defaults {
+		e.defaultContext[name] = value
+	}
+}
+
+// newRootContext creates the context of a render started by the engine or a
+// template: the engine's default context with the given context over it
+func newRootContext(env *Environment, context map[string]interface{}, engine *Engine) *RenderContext {
+	var ctx *RenderContext
+	if engine == nil || len(engine.defaultContext) == 0 {
+		ctx = NewRenderContext(env, context, engine)
+	} else {
+		ctx = NewRenderContext(env, engine.defaultContext, engine)
+		for name, value := range context {
+			ctx.context[name] = value
+		}
+	}
+	ctx.visits = engine.newVisitCounter()
+	ctx.labels = engine.newLabelState()
+	if env != nil && env.featureProvider != nil {
+		ctx.features = make(map[string]bool)
+	}
+	return ctx
+}
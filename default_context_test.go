@@ -0,0 +1,82 @@
+package twig
+
+import "testing"
+
+func TestDefaultContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "default value",
+			source:   `{{ site_name }}`,
+			expected: "Example",
+		},
+		{
+			name:     "overridden per render",
+			source:   `{{ site_name }}`,
+			context:  map[string]interface{}{"site_name": "Other"},
+			expected: "Other",
+		},
+		{
+			name:     "merged with the render context",
+			source:   `{{ site_name }}/{{ page }}`,
+			context:  map[string]interface{}{"page": "home"},
+			expected: "Example/home",
+		},
+		{
+			name:     "takes precedence over globals",
+			source:   `{{ theme }}`,
+			expected: "dark",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.AddGlobal("theme", "light")
+			engine.SetDefaultContext(map[string]interface{}{"site_name": "Example", "theme": "dark"})
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDefaultContextCopied(t *testing.T) {
+	engine := New()
+	defaults := map[string]interface{}{"site_name": "Example"}
+	engine.SetDefaultContext(defaults)
+	defaults["site_name"] = "Changed"
+
+	if err := engine.RegisterString("test", `{{ site_name|default('none') }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Example" {
+		t.Errorf("Expected %q, got %q", "Example", result)
+	}
+
+	engine.SetDefaultContext(nil)
+	result, err = engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "none" {
+		t.Errorf("Expected %q after clearing, got %q", "none", result)
+	}
+}
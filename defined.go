@@ -0,0 +1,160 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// isDefined evaluates the defined test for variables, attributes, items
+// and attribute() calls, probing whether the name, key or index exists
+// instead of evaluating the expression, so items[3] is defined is false
+// rather than an out of bounds error. handled is false for other
+// expressions, which are tested by value.
+func (ctx *RenderContext) isDefined(node Node) (defined, handled bool) {
+	switch n := node.(type) {
+	case *VariableNode:
+		if ctx.context != nil {
+			if _, exists := ctx.context[n.name]; exists {
+				return true, true
+			}
+		}
+		value, err := ctx.GetVariable(n.name)
+		return err == nil && value != nil, true
+
+	case *GetAttrNode:
+		if _, found, ok := ctx.namespaceAttr(n); ok {
+			return found, true
+		}
+		obj, ok := ctx.probe(n.node)
+		if !ok {
+			return false, true
+		}
+		attr, err := ctx.EvaluateExpression(n.attribute)
+		if err != nil {
+			return false, true
+		}
+		name, ok := attr.(string)
+		return ok && ctx.hasAttribute(obj, name), true
+
+	case *GetItemNode:
+		container, ok := ctx.probe(n.node)
+		if !ok {
+			return false, true
+		}
+		key, err := ctx.EvaluateExpression(n.item)
+		if err != nil {
+			return false, true
+		}
+		return ctx.hasItem(container, key), true
+
+	case *FunctionNode:
+		if n.name != "attribute" || n.moduleExpr != nil || len(n.args) < 2 {
+			return false, false
+		}
+		obj, ok := ctx.probe(n.args[0])
+		if !ok {
+			return false, true
+		}
+		name, err := ctx.EvaluateExpression(n.args[1])
+		if err != nil {
+			return false, true
+		}
+		return ctx.hasAttribute(obj, ctx.ToString(name)), true
+	}
+
+	return false, false
+}
+
+// probe evaluates the object of an attribute or item access for a defined
+// test, reporting false when it is undefined itself
+func (ctx *RenderContext) probe(node Node) (interface{}, bool) {
+	if defined, handled := ctx.isDefined(node); handled && !defined {
+		return nil, false
+	}
+	value, err := ctx.EvaluateExpression(node)
+	return value, err == nil && value != nil
+}
+
+// hasAttribute reports whether obj has the named key, field or method
+func (ctx *RenderContext) hasAttribute(obj interface{}, name string) bool {
+	switch o := obj.(type) {
+	case nil:
+		return false
+	case map[string]interface{}:
+		_, exists := o[name]
+		return exists
+	case AttrGetter:
+		_, exists := o.GetAttr(name)
+		return exists
+	}
+
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return false
+		}
+		if _, ok := v.Type().MethodByName(name); ok {
+			return true
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		_, exists := mapAttribute(v, name)
+		return exists
+	case reflect.Struct:
+		entry := ctx.attributeCache().lookup(v.Type(), name)
+		return entry.fieldIndex >= 0 || entry.isMethod
+	}
+	return false
+}
+
+// hasItem reports whether container has the key or index
+func (ctx *RenderContext) hasItem(container, key interface{}) bool {
+	v := reflect.ValueOf(container)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.String:
+		index, ok := ctx.toNumber(key)
+		if !ok {
+			return false
+		}
+		if v.Kind() == reflect.String {
+			_, ok = itemIndex(int(index), len([]rune(v.String())))
+		} else {
+			_, ok = itemIndex(int(index), v.Len())
+		}
+		return ok
+	case reflect.Map:
+		if m, ok := container.(map[string]interface{}); ok {
+			_, exists := m[ctx.ToString(key)]
+			return exists
+		}
+	}
+
+	value, err := ctx.getItem(container, key)
+	return err == nil && value != nil
+}
+
+// callAttributeFunction implements attribute(object, name, arguments),
+// reading an attribute whose name is only known at render time. When
+// arguments are given the attribute is called with them.
+func (ctx *RenderContext) callAttributeFunction(args []interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("attribute function requires an object and an attribute name")
+	}
+
+	name := ctx.ToString(args[1])
+	if len(args) > 2 {
+		if fn, ok := callableValue(args[0], name); ok {
+			arguments, _ := args[2].([]interface{})
+			return callFunc(fn, name, arguments)
+		}
+	}
+
+	value, err := ctx.getAttribute(args[0], name)
+	if err != nil {
+		return nil, err
+	}
+	return ctx.autoInvoke(value, name)
+}
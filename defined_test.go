@@ -0,0 +1,66 @@
+package twig
+
+import (
+	"testing"
+)
+
+type testProfile struct {
+	Name  string
+	Email *string
+}
+
+func (p testProfile) Initials() string { return p.Name[:1] }
+
+func TestDefinedProbes(t *testing.T) {
+	engine := New()
+
+	context := map[string]interface{}{
+		"items":   []interface{}{"a", "b"},
+		"tags":    []string{"x"},
+		"word":    "go",
+		"config":  map[string]interface{}{"debug": nil, "nested": map[string]interface{}{"level": 2}},
+		"limits":  map[string]int{"max": 3},
+		"profile": &testProfile{Name: "Ann"},
+		"field":   "Name",
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"item in range", "{{ items[1] is defined ? 'y' : 'n' }}", "y"},
+		{"item out of range", "{{ items[3] is defined ? 'y' : 'n' }}", "n"},
+		{"negative index", "{{ items[-1] is defined ? 'y' : 'n' }}{{ items[-3] is defined ? 'y' : 'n' }}", "yn"},
+		{"not defined item", "{{ items[3] is not defined ? 'y' : 'n' }}", "y"},
+		{"typed slice", "{{ tags[0] is defined ? 'y' : 'n' }}{{ tags[1] is defined ? 'y' : 'n' }}", "yn"},
+		{"string index", "{{ word[1] is defined ? 'y' : 'n' }}{{ word[2] is defined ? 'y' : 'n' }}", "yn"},
+		{"map key holding null", "{{ config['debug'] is defined ? 'y' : 'n' }}", "y"},
+		{"missing map key", "{{ config['verbose'] is defined ? 'y' : 'n' }}", "n"},
+		{"typed map", "{{ limits['max'] is defined ? 'y' : 'n' }}{{ limits['min'] is defined ? 'y' : 'n' }}", "yn"},
+		{"nested item", "{{ config['nested']['level'] is defined ? 'y' : 'n' }}{{ config['missing']['level'] is defined ? 'y' : 'n' }}", "yn"},
+		{"item of undefined", "{{ nothing[0] is defined ? 'y' : 'n' }}", "n"},
+		{"struct field", "{{ profile.Name is defined ? 'y' : 'n' }}{{ profile.Email is defined ? 'y' : 'n' }}", "yy"},
+		{"struct method", "{{ profile.Initials is defined ? 'y' : 'n' }}", "y"},
+		{"missing struct field", "{{ profile.Phone is defined ? 'y' : 'n' }}", "n"},
+		{"attribute function", "{{ attribute(profile, field) is defined ? 'y' : 'n' }}{{ attribute(profile, 'Phone') is defined ? 'y' : 'n' }}", "yn"},
+		{"attribute of missing", "{{ attribute(nothing, 'x') is defined ? 'y' : 'n' }}", "n"},
+		{"attribute value", "{{ attribute(profile, field) }} {{ attribute(profile, 'Initials') }}", "Ann A"},
+		{"attribute of map", "{% set nested = attribute(config, 'nested') %}{{ nested.level }}", "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
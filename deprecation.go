@@ -0,0 +1,154 @@
+package twig
+
+import "fmt"
+
+// Deprecation reports a use of deprecated syntax in a template
+type Deprecation struct {
+	Template    string
+	Line        int
+	Kind        string // "operator" or "test"
+	Name        string // The deprecated name as written in the template
+	Replacement string // The name to use instead
+}
+
+// String formats the deprecation for the log
+func (d Deprecation) String() string {
+	return fmt.Sprintf("%s:%d: the %s %q is deprecated, use %q instead", d.Template, d.Line, d.Kind, d.Name, d.Replacement)
+}
+
+// DeprecationHandler receives the deprecations of a template
+type DeprecationHandler func(Deprecation)
+
+// syntaxAlias is the operator or test an alias stands for
+type syntaxAlias struct {
+	name       string
+	deprecated bool
+}
+
+// SetDeprecationHandler sets the function that receives deprecation
+// reports. The deprecations of a template are reported the first time it
+// renders; nil logs them as warnings.
+func (e *Engine) SetDeprecationHandler(handler DeprecationHandler) {
+	e.environment.deprecationHandler = handler
+}
+
+// AliasOperator makes operator alias evaluate as operator. The alias must
+// be a binary operator the parser accepts, such as ^ or a registered one.
+func (e *Engine) AliasOperator(alias, operator string) {
+	e.environment.setAlias(&e.environment.operatorAliases, alias, operator, false)
+}
+
+// DeprecateOperator makes operator evaluate as replacement and reports
+// each use of it to the deprecation handler, to migrate templates to the
+// replacement, for instance from ^ to **
+func (e *Engine) DeprecateOperator(operator, replacement string) {
+	e.environment.setAlias(&e.environment.operatorAliases, operator, replacement, true)
+}
+
+// AliasTest makes the test alias evaluate as test
+func (e *Engine) AliasTest(alias, test string) {
+	e.environment.setAlias(&e.environment.testAliases, alias, test, false)
+}
+
+// DeprecateTest makes test evaluate as replacement and reports each use of
+// it to the deprecation handler, for instance sameas in favour of same as
+func (e *Engine) DeprecateTest(test, replacement string) {
+	e.environment.setAlias(&e.environment.testAliases, test, replacement, true)
+}
+
+// DeprecateLegacySyntax deprecates the syntax kept for compatibility in
+// favour of the canonical Twig one: the ^ operator for **, and the sameas,
+// same_as and divisible_by tests for same as and divisible by
+func (e *Engine) DeprecateLegacySyntax() {
+	e.DeprecateOperator("^", "**")
+	e.DeprecateTest("sameas", "same as")
+	e.DeprecateTest("same_as", "same as")
+	e.DeprecateTest("divisible_by", "divisible by")
+}
+
+// setAlias records an alias in one of the environment's alias tables
+func (env *Environment) setAlias(table *map[string]syntaxAlias, alias, name string, deprecated bool) {
+	if *table == nil {
+		*table = make(map[string]syntaxAlias)
+	}
+	(*table)[alias] = syntaxAlias{name: name, deprecated: deprecated}
+	if deprecated {
+		env.hasDeprecations = true
+	}
+}
+
+// resolveOperator returns the operator an aliased operator stands for
+func (ctx *RenderContext) resolveOperator(operator string) string {
+	if ctx.env != nil && ctx.env.operatorAliases != nil {
+		if alias, ok := ctx.env.operatorAliases[operator]; ok {
+			return alias.name
+		}
+	}
+	return operator
+}
+
+// resolveTest returns the test an aliased test stands for
+func (ctx *RenderContext) resolveTest(test string) string {
+	if ctx.env != nil && ctx.env.testAliases != nil {
+		if alias, ok := ctx.env.testAliases[test]; ok {
+			return alias.name
+		}
+	}
+	return test
+}
+
+// Deprecations returns the uses of deprecated operators and tests in the
+// template, in source order
+func (t *Template) Deprecations() []Deprecation {
+	if t.env == nil || !t.env.hasDeprecations {
+		return nil
+	}
+	collector := &deprecationCollector{env: t.env, template: t.name}
+	Walk(t.nodes, collector)
+	return collector.deprecations
+}
+
+// reportDeprecations sends the template's deprecations to the handler once
+func (t *Template) reportDeprecations() {
+	t.deprecationOnce.Do(func() {
+		for _, deprecation := range t.Deprecations() {
+			if t.env.deprecationHandler != nil {
+				t.env.deprecationHandler(deprecation)
+			} else {
+				LogWarning("%s", deprecation)
+			}
+		}
+	})
+}
+
+// deprecationCollector finds the deprecated operators and tests of a tree
+type deprecationCollector struct {
+	env          *Environment
+	template     string
+	deprecations []Deprecation
+}
+
+func (c *deprecationCollector) Enter(node Node) bool {
+	switch n := node.(type) {
+	case *BinaryNode:
+		c.check(c.env.operatorAliases, "operator", n.operator, n.line)
+	case *TestNode:
+		c.check(c.env.testAliases, "test", n.test, n.line)
+	}
+	return true
+}
+
+func (c *deprecationCollector) Leave(node Node) {}
+
+// check records name if it is a deprecated alias in table
+func (c *deprecationCollector) check(table map[string]syntaxAlias, kind, name string, line int) {
+	if alias, ok := table[name]; ok && alias.deprecated {
+		c.deprecations = append(c.deprecations, Deprecation{
+			Template:    c.template,
+			Line:        line,
+			Kind:        kind,
+			Name:        name,
+			Replacement: alias.name,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPowerAndTwoWordTests(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"{{ 2 ** 10 }}", "1024"},
+		{"{{ 2 ^ 3 }}", "8"},
+		{"{{ 1 + 2 ** 3 }}", "9"},
+		{"{{ 2 ** 3 ** 2 }}", "512"},
+		{"{{ 2 ** 3 ** 2 + 1 }}", "513"},
+		{"{% if 1 is same as(1) %}yes{% endif %}", "yes"},
+		{"{% if 1 is not same as('1') %}yes{% endif %}", "yes"},
+		{"{% if 9 is divisible by(3) %}yes{% endif %}", "yes"},
+		{"{% if 1 is sameas(1) %}yes{% endif %}", "yes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDeprecateLegacySyntax(t *testing.T) {
+	engine := New()
+	engine.DeprecateLegacySyntax()
+
+	var reports []Deprecation
+	engine.SetDeprecationHandler(func(d Deprecation) {
+		reports = append(reports, d)
+	})
+
+	source := "{{ 2 ^ 3 }}\n{% if 4 is divisible_by(2) %}{% include 'part' %}{% endif %}"
+	if err := engine.RegisterString("page", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := engine.RegisterString("part", "{{ 1 is sameas(1) ? 'same' : '' }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		result, err := engine.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "8\nsame" {
+			t.Errorf("Expected %q, got %q", "8\nsame", result)
+		}
+	}
+
+	expected := []string{
+		`page:1: the operator "^" is deprecated, use "**" instead`,
+		`page:2: the test "divisible_by" is deprecated, use "divisible by" instead`,
+		`part:1: the test "sameas" is deprecated, use "same as" instead`,
+	}
+	if len(reports) != len(expected) {
+		t.Fatalf("Expected %d reports, got %v", len(expected), reports)
+	}
+	for i, report := range reports {
+		if report.String() != expected[i] {
+			t.Errorf("Expected %q, got %q", expected[i], report.String())
+		}
+	}
+}
+
+func TestAliasTest(t *testing.T) {
+	engine := New()
+	engine.AliasTest("nil", "null")
+	engine.AliasOperator("^", "-")
+
+	if err := engine.RegisterString("page", "{{ none is nil ? 'nil' : '' }} {{ 5 ^ 2 }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", map[string]interface{}{"none": nil})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "nil 3" {
+		t.Errorf("Expected %q, got %q", "nil 3", result)
+	}
+
+	template, _ := engine.Load("page")
+	if deprecations := template.Deprecations(); len(deprecations) != 0 {
+		t.Errorf("Expected plain aliases not to be deprecated, got %v", deprecations)
+	}
+	if !strings.Contains(Deprecation{Template: "a", Line: 1, Kind: "test", Name: "x", Replacement: "y"}.String(), "a:1") {
+		t.Error("Expected the deprecation to name its location")
+	}
+}
+
+func TestScopeAliases(t *testing.T) {
+	engine := New()
+	engine.DeprecateLegacySyntax()
+	scope := engine.NewScope()
+	scope.AliasOperator("^", "+")
+
+	for _, e := range []*Engine{engine, scope} {
+		if err := e.RegisterString("page", "{{ 2 ^ 3 }}"); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+	}
+
+	result, err := engine.Render("page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "8" {
+		t.Errorf("Expected the scope's alias not to reach the engine, got %q", result)
+	}
+
+	result, err = scope.Render("page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "5" {
+		t.Errorf("Expected %q, got %q", "5", result)
+	}
+}
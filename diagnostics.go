@@ -0,0 +1,90 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Diagnostic is an error RenderCollect recorded instead of failing the
+// render
+type Diagnostic struct {
+	Template string
+	Line     int
+	Err      error
+}
+
+// String formats the diagnostic as "name line N: message"
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %v", TemplateFrame{Template: d.Template, Line: d.Line}, d.Err)
+}
+
+// SetDiagnosticComments sets whether RenderCollect writes an HTML comment
+// with the error in place of a statement that failed. By default the
+// statement writes nothing.
+func (e *Engine) SetDiagnosticComments(enabled bool) {
+	e.markDiagnostics = enabled
+}
+
+// diagnostics collects the errors of a RenderCollect render
+type diagnostics struct {
+	list     []Diagnostic
+	comments bool
+}
+
+// RenderCollect renders a template like Render, but a statement that fails,
+// such as a missing include or a filter given a bad argument, is recorded
+// as a Diagnostic and renders nothing, so previews show as much of the page
+// as they can along with a list of problems. The error is only set when
+// the render could not run at all, for example when the template does not
+// exist, or hit a limit such as SetMaxNodeVisits.
+func (e *Engine) RenderCollect(name string, context map[string]interface{}, opts ...RenderOptions) (string, []Diagnostic, error) {
+	d := &diagnostics{comments: e.markDiagnostics}
+	output, err := e.Render(name, context, append(opts, RenderOptions{diagnostics: d})...)
+	return output, d.list, err
+}
+
+// RenderCollect renders the template like Render, recording the errors of
+// statements as diagnostics instead of failing, see Engine.RenderCollect
+func (t *Template) RenderCollect(context map[string]interface{}, opts ...RenderOptions) (string, []Diagnostic, error) {
+	d := &diagnostics{}
+	if t.engine != nil {
+		d.comments = t.engine.markDiagnostics
+	}
+	output, err := t.Render(context, append(opts, RenderOptions{diagnostics: d})...)
+	return output, d.list, err
+}
+
+// recordError records the error of a statement, returning it instead when
+// the render cannot go on after it
+func (d *diagnostics) recordError(w io.Writer, ctx *RenderContext, node Node, err error) error {
+	if _, ok := returnedValue(err); ok ||
+		errors.Is(err, ErrTooManyNodeVisits) || errors.Is(err, ErrOutputTooLarge) {
+		return err
+	}
+
+	diagnostic := Diagnostic{Template: ctx.templateName(), Line: node.Line(), Err: diagnosticCause(err)}
+	d.list = append(d.list, diagnostic)
+	if d.comments {
+		// Comments end at --, so the message must not contain it
+		message := strings.ReplaceAll(diagnostic.String(), "--", "- -")
+		if _, err := WriteString(w, "<!-- twig error: "+message+" -->"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diagnosticCause strips the EnhancedError wrapping from err. A Diagnostic
+// already carries the location, and the source excerpt of the wrapping must
+// not end up in the comments written into the page.
+func diagnosticCause(err error) error {
+	for {
+		enhanced, ok := err.(*EnhancedError)
+		if !ok || enhanced.Err == nil {
+			return err
+		}
+		err = enhanced.Err
+	}
+}
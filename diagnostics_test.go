@@ -0,0 +1,77 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderCollect(t *testing.T) {
+	engine := New()
+	source := "<h1>{{ title }}</h1>\n{% include 'missing' %}\n{{ 'x'|batch(0) }}<p>{% include 'part' %}</p>"
+	if err := engine.RegisterString("page", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := engine.RegisterString("part", "a{{ [1]|batch(-1) }}b"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	context := map[string]interface{}{"title": "Hi"}
+
+	// A plain render fails on the first problem
+	if _, err := engine.Render("page", context); err == nil {
+		t.Fatal("Expected the render to fail")
+	}
+
+	output, diagnostics, err := engine.RenderCollect("page", context)
+	if err != nil {
+		t.Fatalf("Expected the render to go on, got %v", err)
+	}
+	if output != "<h1>Hi</h1>\n\n<p>ab</p>" {
+		t.Errorf("Unexpected output %q", output)
+	}
+
+	expected := []struct {
+		template string
+		line     int
+	}{{"page", 2}, {"page", 3}, {"part", 1}}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for i, d := range diagnostics {
+		if d.Template != expected[i].template || d.Line != expected[i].line {
+			t.Errorf("Expected a diagnostic at %s line %d, got %s", expected[i].template, expected[i].line, d)
+		}
+	}
+	if !errors.Is(diagnostics[0].Err, ErrTemplateNotFound) {
+		t.Errorf("Expected the missing include to be reported, got %v", diagnostics[0].Err)
+	}
+	if d := diagnostics[2].String(); d != "part line 1: batch size must be a positive integer, got -1" {
+		t.Errorf("Expected the diagnostic to name its location once, got %q", d)
+	}
+
+	// Comments mark where the statements failed
+	engine.SetDiagnosticComments(true)
+	template, _ := engine.Load("page")
+	output, diagnostics, err = template.RenderCollect(context)
+	if err != nil || len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %v (%v)", diagnostics, err)
+	}
+	if strings.Count(output, "<!-- twig error: ") != 3 || !strings.Contains(output, "page line 2: ") {
+		t.Errorf("Expected comments for the failed statements, got %q", output)
+	}
+
+	// Comments hold the message only, without the template source
+	if !strings.Contains(output, "<!-- twig error: page line 3: batch size must be a positive integer, got 0 -->") ||
+		strings.Contains(output, "{%") {
+		t.Errorf("Expected the comments to leave out the source, got %q", output)
+	}
+
+	// Limits still stop the render
+	engine.SetMaxNodeVisits(3)
+	if _, _, err := engine.RenderCollect("page", context); !errors.Is(err, ErrTooManyNodeVisits) {
+		t.Errorf("Expected the visit limit to stop the render, got %v", err)
+	}
+	if _, _, err := engine.RenderCollect("nope", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected a missing template to fail, got %v", err)
+	}
+}
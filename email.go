@@ -0,0 +1,125 @@
+package twig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Email holds the parts of an email rendered by RenderEmail
+type Email struct {
+	Subject string // The subject block, trimmed and joined onto one line
+	HTML    string // The html_body block
+	Text    string // The text_body block
+}
+
+// RenderEmail renders the subject, html_body and text_body blocks of a
+// single template, so one file can hold every part of an email:
+//
+//	{% block subject %}Welcome {{ user.name }}{% endblock %}
+//	{% block html_body %}<p>Hello {{ user.name }}</p>{% endblock %}
+//	{% block text_body %}Hello {{ user.name }}{% endblock %}
+//
+// Blocks may be inherited from a template the email extends. Parts the
+// template does not define are left empty, but at least one must exist.
+func (e *Engine) RenderEmail(name string, context map[string]interface{}) (*Email, error) {
+	template, err := e.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	email := &Email{}
+	parts := []struct {
+		block  string
+		target *string
+	}{
+		{"subject", &email.Subject},
+		{"html_body", &email.HTML},
+		{"text_body", &email.Text},
+	}
+
+	found := false
+	for _, part := range parts {
+		content, ok, err := template.renderBlock(part.block, context)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			*part.target = content
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("template '%s' defines none of the subject, html_body and text_body blocks", name)
+	}
+
+	email.Subject = strings.Join(strings.Fields(email.Subject), " ")
+	return email, nil
+}
+
+// RenderBlock renders a single block of the template, including blocks it
+// inherits, without rendering the rest of the template
+func (t *Template) RenderBlock(name string, context map[string]interface{}) (string, error) {
+	content, ok, err := t.renderBlock(name, context)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("block '%s' not found in template '%s'", name, t.name)
+	}
+	return content, nil
+}
+
+// renderBlock renders the named block, reporting false when neither the
+// template nor any template it extends defines it
+func (t *Template) renderBlock(name string, context map[string]interface{}) (string, bool, error) {
+	ctx := newRootContext(t.env, context, t.engine)
+	defer ctx.Release()
+
+	// Register blocks the way extends does: the child's version wins and
+	// the next one up the chain is what parent() renders
+	template := t
+	seen := make(map[*Template]bool)
+	for template != nil && !seen[template] {
+		seen[template] = true
+		ctx.lastLoadedTemplate = template
+
+		var extends *ExtendsNode
+		if rootNode, ok := template.nodes.(*RootNode); ok {
+			for _, child := range rootNode.Children() {
+				switch n := child.(type) {
+				case *BlockNode:
+					if _, exists := ctx.blocks[n.name]; !exists {
+						ctx.blocks[n.name] = n.body
+						ctx.setBlockOwner(n.name, template)
+					} else if _, exists := ctx.parentBlocks[n.name]; !exists {
+						ctx.parentBlocks[n.name] = n.body
+					}
+				case *ExtendsNode:
+					extends = n
+				}
+			}
+		}
+		if extends == nil {
+			break
+		}
+
+		parent, _, err := extends.resolveParent(ctx)
+		if err != nil {
+			return "", false, ctx.wrapError(err, extends.line)
+		}
+		template = parent
+	}
+
+	if _, ok := ctx.blocks[name]; !ok {
+		return "", false, nil
+	}
+
+	ctx.lastLoadedTemplate = t
+	buf := NewStringBuffer()
+	defer buf.Release()
+	block := &BlockNode{name: name}
+	if err := block.Render(buf, ctx); err != nil {
+		return "", true, ctx.wrapError(err, 0)
+	}
+	return buf.String(), true, nil
+}
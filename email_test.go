@@ -0,0 +1,67 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderEmail(t *testing.T) {
+	engine := New()
+	engine.RegisterString("base_email.twig", "{% block html_body %}<html>{% block content %}{% endblock %}</html>{% endblock %}"+
+		"{% block text_body %}-- {% block signature %}The team{% endblock %}{% endblock %}")
+	engine.RegisterString("welcome.twig", `{% extends 'base_email.twig' %}
+{% block subject %}
+  Welcome, {{ user }}!
+{% endblock %}
+{% block content %}<p>Hi {{ user }}</p>{% endblock %}
+{% block text_body %}Hi {{ user }}
+{{ parent() }}{% endblock %}`)
+	engine.RegisterString("plain.twig", "{% block subject %}Reset{% endblock %}{% block text_body %}{{ link }}{% endblock %}")
+	engine.RegisterString("page.twig", "<p>{{ user }}</p>")
+
+	email, err := engine.RenderEmail("welcome.twig", map[string]interface{}{"user": "Ann"})
+	if err != nil {
+		t.Fatalf("Error rendering email: %v", err)
+	}
+	if email.Subject != "Welcome, Ann!" {
+		t.Errorf("Expected subject %q, got %q", "Welcome, Ann!", email.Subject)
+	}
+	if email.HTML != "<html><p>Hi Ann</p></html>" {
+		t.Errorf("Unexpected html body %q", email.HTML)
+	}
+	if email.Text != "Hi Ann\n-- The team" {
+		t.Errorf("Unexpected text body %q", email.Text)
+	}
+
+	email, err = engine.RenderEmail("plain.twig", map[string]interface{}{"link": "/reset"})
+	if err != nil {
+		t.Fatalf("Error rendering email: %v", err)
+	}
+	if email.Subject != "Reset" || email.HTML != "" || email.Text != "/reset" {
+		t.Errorf("Unexpected email %+v", email)
+	}
+
+	if _, err := engine.RenderEmail("page.twig", nil); err == nil || !strings.Contains(err.Error(), "defines none") {
+		t.Errorf("Expected an error for a template without email blocks, got %v", err)
+	}
+}
+
+func TestTemplateRenderBlock(t *testing.T) {
+	engine := New()
+	engine.RegisterString("page.twig", "before {% block title %}Hello {{ name }}{% endblock %} after")
+
+	template, err := engine.Load("page.twig")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+	result, err := template.RenderBlock("title", map[string]interface{}{"name": "World"})
+	if err != nil {
+		t.Fatalf("Error rendering block: %v", err)
+	}
+	if result != "Hello World" {
+		t.Errorf("Expected %q, got %q", "Hello World", result)
+	}
+	if _, err := template.RenderBlock("missing", nil); err == nil {
+		t.Error("Expected an error for a missing block")
+	}
+}
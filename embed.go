@@ -0,0 +1,155 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+)
+
+// EmbedNode represents {% embed "card.twig" with {...} only %} ...
+// {% endembed %}: the template is included like with include, and the
+// blocks inside the tag override its blocks, as if an anonymous child
+// template extended it
+type EmbedNode struct {
+	template      Node
+	variables     map[string]Node
+	ignoreMissing bool
+	only          bool
+	sandboxed     bool
+	blocks        []*BlockNode
+	line          int
+}
+
+func (n *EmbedNode) Type() NodeType {
+	return NodeEmbed
+}
+
+func (n *EmbedNode) Line() int {
+	return n.line
+}
+
+// Render renders the embedded template with the overriding blocks
+func (n *EmbedNode) Render(w io.Writer, ctx *RenderContext) error {
+	template, err := ctx.loadIncluded(n.template, n.ignoreMissing, n.line)
+	if err != nil || template == nil {
+		return err
+	}
+
+	embedCtx, err := ctx.scopedContext(includeScope{
+		variables: n.variables,
+		only:      n.only,
+		sandboxed: n.sandboxed,
+		line:      n.line,
+	})
+	if err != nil {
+		return err
+	}
+	defer embedCtx.Release()
+
+	embedCtx.lastLoadedTemplate = template
+	embedCtx.templateStack = ctx.childTemplateStack(n.line)
+
+	// Blocks of the embedding template don't reach the embedded one; only
+	// the blocks of this tag override it
+	for name := range embedCtx.blocks {
+		delete(embedCtx.blocks, name)
+	}
+	for name := range embedCtx.parentBlocks {
+		delete(embedCtx.parentBlocks, name)
+	}
+	embedCtx.blockOwners = nil
+
+	// The embedded template's own blocks are what parent() renders
+	if root, ok := template.nodes.(*RootNode); ok {
+		for _, child := range root.children {
+			if block, ok := child.(*BlockNode); ok {
+				embedCtx.parentBlocks[block.name] = block.body
+			}
+		}
+	}
+	for _, block := range n.blocks {
+		embedCtx.blocks[block.name] = block.body
+		embedCtx.setBlockOwner(block.name, ctx.lastLoadedTemplate)
+	}
+
+	// Rendered like a parent template, so its blocks don't replace ours
+	embedCtx.extending = true
+
+	out, done := ctx.trackOutput(w, "embed", template.name, n.line)
+	err = template.nodes.Render(out, embedCtx)
+	done()
+	return embedCtx.wrapError(err, 0)
+}
+
+// parseEmbed parses an embed tag. Only blocks are allowed inside; text
+// between them is ignored.
+func (p *Parser) parseEmbed(parser *Parser) (Node, error) {
+	embedLine := parser.tokens[parser.tokenIndex-2].Line
+
+	templateExpr, err := parser.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := parser.parseIncludeOptions("embed", embedLine)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parser.expectBlockEnd("embed", embedLine); err != nil {
+		return nil, err
+	}
+
+	body, err := parser.parseOuterTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*BlockNode
+	for _, node := range body {
+		switch node := node.(type) {
+		case *BlockNode:
+			blocks = append(blocks, node)
+		case *TextNode, *CommentNode:
+		default:
+			return nil, fmt.Errorf("only blocks are allowed inside embed at line %d", node.Line())
+		}
+	}
+
+	if err := parser.expectEndTag("endembed", embedLine); err != nil {
+		return nil, err
+	}
+
+	return &EmbedNode{
+		template:      templateExpr,
+		variables:     options.variables,
+		ignoreMissing: options.ignoreMissing,
+		only:          options.only,
+		sandboxed:     options.sandboxed,
+		blocks:        blocks,
+		line:          embedLine,
+	}, nil
+}
+
+// expectBlockEnd consumes the %} closing a tag
+func (p *Parser) expectBlockEnd(tag string, line int) error {
+	if p.tokenIndex >= len(p.tokens) ||
+		(p.tokens[p.tokenIndex].Type != TOKEN_BLOCK_END &&
+			p.tokens[p.tokenIndex].Type != TOKEN_BLOCK_END_TRIM) {
+		return fmt.Errorf("expected block end token after %s at line %d", tag, line)
+	}
+	p.tokenIndex++
+	return nil
+}
+
+// expectEndTag consumes a closing tag such as {% endembed %}
+func (p *Parser) expectEndTag(name string, line int) error {
+	if p.tokenIndex+1 >= len(p.tokens) ||
+		(p.tokens[p.tokenIndex].Type != TOKEN_BLOCK_START &&
+			p.tokens[p.tokenIndex].Type != TOKEN_BLOCK_START_TRIM) ||
+		p.tokens[p.tokenIndex+1].Type != TOKEN_NAME ||
+		p.tokens[p.tokenIndex+1].Value != name {
+		return fmt.Errorf("missing %s tag for tag at line %d", name, line)
+	}
+	p.tokenIndex += 2
+	return p.expectBlockEnd(name, line)
+}
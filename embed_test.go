@@ -0,0 +1,144 @@
+package twig
+
+import "testing"
+
+func TestEmbed(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		expected string
+	}{
+		{
+			name: "overrides blocks of the embedded template",
+			sources: map[string]string{
+				"card": `<div>{% block title %}Card{% endblock %}|{% block body %}empty{% endblock %}</div>`,
+				"test": `{% embed "card" %}{% block title %}{{ user }}{% endblock %}{% endembed %}`,
+			},
+			expected: "<div>ann|empty</div>",
+		},
+		{
+			name: "passes data with",
+			sources: map[string]string{
+				"card": `{% block title %}{{ label }}{% endblock %}:{{ user }}`,
+				"test": `{% embed "card" with {label: 'Hi'} %}{% endembed %}`,
+			},
+			expected: "Hi:ann",
+		},
+		{
+			name: "only hides the outer variables",
+			sources: map[string]string{
+				"card": `{% block title %}{{ label }}{% endblock %}:{{ user|default('none') }}`,
+				"test": `{% embed "card" with {label: 'Hi'} only %}{% endembed %}`,
+			},
+			expected: "Hi:none",
+		},
+		{
+			name: "nested embeds",
+			sources: map[string]string{
+				"card":  `[{% block body %}{% endblock %}]`,
+				"panel": `({% block inner %}{% endblock %})`,
+				"test":  `{% embed "card" %}{% block body %}{% embed "panel" %}{% block inner %}{{ user }}{% endblock %}{% endembed %}{% endblock %}{% endembed %}`,
+			},
+			expected: "[(ann)]",
+		},
+		{
+			name: "ignore missing",
+			sources: map[string]string{
+				"test": `a{% embed "nothing" ignore missing %}{% block body %}x{% endblock %}{% endembed %}b`,
+			},
+			expected: "ab",
+		},
+		{
+			name: "blocks of the embedding template are kept",
+			sources: map[string]string{
+				"base": `{% block content %}{% endblock %}`,
+				"card": `{% block content %}card{% endblock %}`,
+				"test": `{% extends "base" %}{% block content %}{% embed "card" %}{% endembed %}{% endblock %}`,
+			},
+			expected: "card",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			for name, source := range tt.sources {
+				if err := engine.RegisterString(name, source); err != nil {
+					t.Fatalf("Error parsing template %s: %v", name, err)
+				}
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"user": "ann"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEmbedRejectsContent(t *testing.T) {
+	engine := New()
+	err := engine.RegisterString("test", `{% embed "card" %}{{ user }}{% endembed %}`)
+	if err == nil {
+		t.Fatal("Expected an error for output inside embed")
+	}
+}
+
+func TestWith(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			name:     "adds values",
+			source:   `{% with {'greeting': 'hi'} %}{{ greeting }} {{ user }}{% endwith %}`,
+			expected: "hi ann",
+		},
+		{
+			name:     "only hides the outer variables",
+			source:   `{% with {'greeting': 'hi'} only %}{{ greeting }} {{ user|default('none') }}{% endwith %}`,
+			expected: "hi none",
+		},
+		{
+			name:     "unquoted keys",
+			source:   `{% with {z: 1, who: user} %}[{{ z }} {{ who }}]{% endwith %}`,
+			expected: "[1 ann]",
+		},
+		{
+			name:     "unquoted keys with only",
+			source:   `{% with {z: 1} only %}[{{ z }}]{% endwith %}`,
+			expected: "[1]",
+		},
+		{
+			name:     "sets do not leak",
+			source:   `{% with %}{% set user = 'bob' %}{{ user }}{% endwith %} {{ user }}`,
+			expected: "bob ann",
+		},
+		{
+			name:     "values from a variable",
+			source:   `{% set vars = {'a': 1} %}{% with vars %}{{ a }}{% endwith %}`,
+			expected: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"user": "ann"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
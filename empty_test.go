@@ -0,0 +1,74 @@
+package twig
+
+import (
+	"testing"
+	"time"
+)
+
+// testOrders has a length but is not indexable
+type testOrders struct{ count int }
+
+func (o testOrders) Len() int { return o.count }
+
+// testBalance reports its own zero value
+type testBalance struct{ cents int }
+
+func (m testBalance) IsZero() bool { return m.cents == 0 }
+
+type testOrder struct{ ID int }
+
+func TestEmptyAndIterableTests(t *testing.T) {
+	var nilOrder *testOrder
+	var nilList *testList
+	var nilMap map[string]int
+	var nilSlice []string
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		empty    bool
+		iterable bool
+	}{
+		{name: "typed nil pointer", value: nilOrder, empty: true, iterable: false},
+		{name: "pointer to struct", value: &testOrder{ID: 1}, empty: false, iterable: false},
+		{name: "nil map", value: nilMap, empty: true, iterable: true},
+		{name: "nil slice", value: nilSlice, empty: true, iterable: true},
+		{name: "empty Len", value: testOrders{}, empty: true, iterable: false},
+		{name: "non-empty Len", value: testOrders{count: 2}, empty: false, iterable: false},
+		{name: "zero IsZero", value: testBalance{}, empty: true, iterable: false},
+		{name: "non-zero IsZero", value: testBalance{cents: 5}, empty: false, iterable: false},
+		{name: "zero time", value: time.Time{}, empty: true, iterable: false},
+		{name: "empty collection", value: &testList{}, empty: true, iterable: true},
+		{name: "collection", value: &testList{items: []string{"a"}}, empty: false, iterable: true},
+		{name: "nil collection", value: nilList, empty: true, iterable: false},
+		{name: "pointer to empty string", value: new(string), empty: true, iterable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			source := `{{ value is empty ? 'empty' : 'full' }} {{ value is iterable ? 'iterable' : 'scalar' }}`
+			if err := engine.RegisterString("test", source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"value": tt.value})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			expected := "full"
+			if tt.empty {
+				expected = "empty"
+			}
+			if tt.iterable {
+				expected += " iterable"
+			} else {
+				expected += " scalar"
+			}
+			if result != expected {
+				t.Errorf("Expected %q, got %q", expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package twig
+
+import (
+	"unicode/utf8"
+
+	"github.com/semihalev/twig/loader"
+)
+
+// SourceDecoder converts template files in a legacy encoding to UTF-8.
+//
+// Deprecated: Use loader.SourceDecoder.
+type SourceDecoder = loader.SourceDecoder
+
+// Latin1 decodes ISO-8859-1 sources
+var Latin1 SourceDecoder = charmapDecoder{}
+
+// Windows1252 decodes Windows-1252 sources, the superset of Latin-1 most
+// legacy "latin-1" files are really written in
+var Windows1252 SourceDecoder = charmapDecoder{high: &windows1252High}
+
+// windows1252High maps bytes 0x80 to 0x9F, which differ from Latin-1.
+// Unassigned bytes keep their code point, as in the WHATWG encoding standard.
+var windows1252High = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// charmapDecoder decodes single-byte encodings that match Latin-1 apart
+// from an optional table for 0x80 to 0x9F
+type charmapDecoder struct {
+	high *[32]rune
+}
+
+// Bytes decodes b to UTF-8
+func (d charmapDecoder) Bytes(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b)+len(b)/4)
+	for _, c := range b {
+		switch {
+		case c < utf8.RuneSelf:
+			out = append(out, c)
+		case d.high != nil && c < 0xA0:
+			out = utf8.AppendRune(out, d.high[c-0x80])
+		default:
+			out = utf8.AppendRune(out, rune(c))
+		}
+	}
+	return out, nil
+}
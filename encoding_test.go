@@ -0,0 +1,67 @@
+package twig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSystemLoaderEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		decoder  SourceDecoder
+		content  []byte
+		expected string
+	}{
+		{"utf-8 by default", nil, []byte("Café {{ name }}"), "Café Ada"},
+		{"latin-1", Latin1, []byte("Caf\xe9 {{ name }} \xa9"), "Café Ada ©"},
+		{"windows-1252", Windows1252, []byte("\x93Caf\xe9\x94 \x80{{ name }}"), "“Café” €Ada"},
+		{"windows-1252 unassigned byte", Windows1252, []byte("a\x81b"), "a\u0081b"},
+		{"latin-1 C1 range", Latin1, []byte("\x80"), "\u0080"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "page.twig"), tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			loader := NewFileSystemLoader([]string{dir})
+			loader.SetEncoding(tt.decoder)
+
+			engine := New()
+			engine.RegisterLoader(loader)
+
+			result, err := engine.Render("page.twig", map[string]interface{}{"name": "Ada"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+// failingDecoder rejects every source
+type failingDecoder struct{}
+
+func (failingDecoder) Bytes(b []byte) ([]byte, error) {
+	return nil, errors.New("invalid byte")
+}
+
+func TestFileSystemLoaderDecodeError(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.twig"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewFileSystemLoader([]string{dir})
+	loader.SetEncoding(failingDecoder{})
+
+	if _, err := loader.Load("page.twig"); err == nil {
+		t.Error("Expected the decoding error")
+	}
+}
@@ -0,0 +1,81 @@
+package twig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// enumType holds the values of an enum registered with RegisterEnumType
+type enumType struct {
+	values []interface{}
+	byName map[string]interface{} // Values by String() and by constant name
+}
+
+// RegisterEnumType registers the Go enum type T under name. Templates list
+// its values with enum('Status'), so status in enum('Status') checks
+// membership, and look one up by name with enum('Status', 'active').
+// Without values, those returned by the Values method of T are used. Each
+// value is also registered as the constant Status::ACTIVE. Values render
+// through their String method.
+func RegisterEnumType[T fmt.Stringer](e *Engine, name string, values ...T) error {
+	if len(values) == 0 {
+		var zero T
+		lister, ok := interface{}(zero).(interface{ Values() []T })
+		if !ok {
+			return fmt.Errorf("enum %s: no values given and %T has no Values method", name, zero)
+		}
+		values = lister.Values()
+	}
+
+	enum := &enumType{
+		values: make([]interface{}, len(values)),
+		byName: make(map[string]interface{}, len(values)*2),
+	}
+	constants := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		enum.values[i] = value
+		enum.byName[value.String()] = value
+		constName := enumConstantName(value)
+		enum.byName[constName] = value
+		constants[name+"::"+constName] = value
+	}
+
+	if e.environment.enums == nil {
+		e.environment.enums = make(map[string]*enumType)
+	}
+	e.environment.enums[name] = enum
+	e.RegisterConstants(constants)
+	return nil
+}
+
+// enumConstantName returns the constant name of an enum value: its String()
+// in upper case, with spaces and dashes turned into underscores
+func enumConstantName(value fmt.Stringer) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_").Replace(value.String()))
+}
+
+// functionEnum implements enum(name) and enum(name, value)
+func (e *CoreExtension) functionEnum(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("enum function requires an enum name")
+	}
+
+	name := e.toString(args[0])
+	var enum *enumType
+	if e.env != nil {
+		enum = e.env.enums[name]
+	}
+	if enum == nil {
+		return nil, fmt.Errorf("enum %q is not registered", name)
+	}
+
+	if len(args) == 1 {
+		return append([]interface{}(nil), enum.values...), nil
+	}
+
+	valueName := e.toString(args[1])
+	if value, ok := enum.byName[valueName]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("enum %s has no value %q", name, valueName)
+}
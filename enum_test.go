@@ -0,0 +1,105 @@
+package twig
+
+import "testing"
+
+// Values lists the testStatus enum for RegisterEnumType
+func (testStatus) Values() []testStatus {
+	return []testStatus{testStatusDraft, testStatusActive, testStatusOnHold}
+}
+
+// testColor is a string enum without a Values method
+type testColor string
+
+func (c testColor) String() string { return string(c) }
+
+func TestEnumTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "renders through String",
+			source:   `{{ status }}`,
+			context:  map[string]interface{}{"status": testStatusOnHold},
+			expected: "on hold",
+		},
+		{
+			name:     "in operator",
+			source:   `{{ status in enum('Status') ? "valid" : "invalid" }}`,
+			context:  map[string]interface{}{"status": testStatusActive},
+			expected: "valid",
+		},
+		{
+			name:     "lists values",
+			source:   `{% for s in enum('Status') %}{{ s }},{% endfor %}`,
+			expected: "draft,active,on hold,",
+		},
+		{
+			name:     "value by name",
+			source:   `{{ status == enum('Status', 'active') ? "yes" : "no" }}|{{ enum('Status', 'ON_HOLD') }}`,
+			context:  map[string]interface{}{"status": testStatusActive},
+			expected: "yes|on hold",
+		},
+		{
+			name:     "constant",
+			source:   `{% if status is constant('Status::DRAFT') %}draft{% endif %}`,
+			context:  map[string]interface{}{"status": testStatusDraft},
+			expected: "draft",
+		},
+		{
+			name:     "explicit values",
+			source:   `{% for c in enum('Color') %}{{ c }}{% endfor %}|{{ color in enum('Color') ? "ok" : "bad" }}`,
+			context:  map[string]interface{}{"color": testColor("blue")},
+			expected: "redblue|ok",
+		},
+		{
+			name:    "unknown enum",
+			source:  `{{ enum('Missing') }}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown value",
+			source:  `{{ enum('Status', 'archived') }}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := RegisterEnumType[testStatus](engine, "Status"); err != nil {
+				t.Fatalf("Error registering enum: %v", err)
+			}
+			if err := RegisterEnumType(engine, "Color", testColor("red"), testColor("blue")); err != nil {
+				t.Fatalf("Error registering enum: %v", err)
+			}
+
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestRegisterEnumTypeWithoutValues(t *testing.T) {
+	if err := RegisterEnumType[testColor](New(), "Color"); err == nil {
+		t.Error("Expected an error for an enum without values")
+	}
+}
@@ -0,0 +1,90 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// structuralEquals compares two values when either is a map, list or struct,
+// which would otherwise be compared by their printed form, so that
+// {'id': 1} in selected finds a member with the same keys and values rather
+// than one that merely prints the same. Members of maps and lists are
+// compared with equals, so 1 and 1.0 are equal inside them as they are
+// outside; structs are equal when they have the same type and fields. It
+// reports false for ok when neither value is such a structure.
+func (ctx *RenderContext) structuralEquals(a, b interface{}) (equal, ok bool) {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	aStruct, bStruct := isStructural(av), isStructural(bv)
+	if !aStruct && !bStruct {
+		return false, false
+	}
+	if !aStruct || !bStruct {
+		return false, true
+	}
+
+	// Pointers to structs are equal when they point to equal structs
+	if av.Kind() == reflect.Ptr || bv.Kind() == reflect.Ptr {
+		if av.Kind() == reflect.Ptr && bv.Kind() == reflect.Ptr && av.Pointer() == bv.Pointer() {
+			return true, true
+		}
+		av, bv = reflect.Indirect(av), reflect.Indirect(bv)
+	}
+
+	switch av.Kind() {
+	case reflect.Slice, reflect.Array:
+		if bv.Kind() != reflect.Slice && bv.Kind() != reflect.Array || av.Len() != bv.Len() {
+			return false, true
+		}
+		for i := 0; i < av.Len(); i++ {
+			if !ctx.equals(av.Index(i).Interface(), bv.Index(i).Interface()) {
+				return false, true
+			}
+		}
+		return true, true
+
+	case reflect.Map:
+		if bv.Kind() != reflect.Map || av.Len() != bv.Len() {
+			return false, true
+		}
+		// Keys are matched by their string form, as in item lookups
+		others := make(map[string]reflect.Value, bv.Len())
+		iter := bv.MapRange()
+		for iter.Next() {
+			others[fmt.Sprint(iter.Key().Interface())] = iter.Value()
+		}
+		iter = av.MapRange()
+		for iter.Next() {
+			other, exists := others[fmt.Sprint(iter.Key().Interface())]
+			if !exists || !ctx.equals(iter.Value().Interface(), other.Interface()) {
+				return false, true
+			}
+		}
+		return true, true
+
+	case reflect.Struct:
+		return av.Type() == bv.Type() && reflect.DeepEqual(av.Interface(), bv.Interface()), true
+	}
+
+	return false, true
+}
+
+// isStructural reports whether v is a map, list, struct or pointer to a
+// struct. Byte slices and types with a String method are compared as text.
+func isStructural(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	if _, ok := v.Interface().(fmt.Stringer); ok {
+		return false
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Array, reflect.Struct:
+		return true
+	case reflect.Slice:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	case reflect.Ptr:
+		return !v.IsNil() && v.Elem().Kind() == reflect.Struct
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package twig
+
+import (
+	"testing"
+)
+
+type testTag struct {
+	ID   int
+	Name string
+}
+
+func TestInOperatorStructuralEquality(t *testing.T) {
+	engine := New()
+
+	selected := make([]interface{}, 0, 60)
+	for i := 0; i < 60; i++ {
+		selected = append(selected, map[string]interface{}{"id": i, "name": "n"})
+	}
+	context := map[string]interface{}{
+		"item":      map[string]interface{}{"id": 5, "name": "n"},
+		"other":     map[string]interface{}{"id": 5, "name": "m"},
+		"selected":  selected,
+		"small":     []map[string]interface{}{{"id": 1}, {"id": 2}},
+		"tags":      []testTag{{1, "go"}, {2, "twig"}},
+		"tag":       testTag{2, "twig"},
+		"tagPtr":    &testTag{1, "go"},
+		"tagPtrs":   []*testTag{{1, "go"}},
+		"lists":     [][]int{{1, 2}, {3}},
+		"printable": []interface{}{"map[id:5 name:n]"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"map in large list", "{{ item in selected ? 'y' : 'n' }}", "y"},
+		{"different map", "{{ other in selected ? 'y' : 'n' }}", "n"},
+		{"not in", "{{ other not in selected ? 'y' : 'n' }}", "y"},
+		{"literal hash", "{{ {'id': 2} in small ? 'y' : 'n' }}{{ {'id': 3} in small ? 'y' : 'n' }}", "yn"},
+		{"extra key", "{{ {'id': 1, 'x': 0} in small ? 'y' : 'n' }}", "n"},
+		{"struct", "{{ tag in tags ? 'y' : 'n' }}", "y"},
+		{"struct pointer", "{{ tagPtr in tags ? 'y' : 'n' }}{{ tag in tagPtrs ? 'y' : 'n' }}", "yn"},
+		{"list member", "{{ [3] in lists ? 'y' : 'n' }}{{ [1] in lists ? 'y' : 'n' }}", "yn"},
+		{"not equal to its printed form", "{{ item in printable ? 'y' : 'n' }}", "n"},
+		{"equality operator", "{{ item == {'id': 5, 'name': 'n'} ? 'y' : 'n' }}{{ item == other ? 'y' : 'n' }}", "yn"},
+		{"scalars unchanged", "{{ 2 in [1, 2] ? 'y' : 'n' }}{{ '2' in [1, 2] ? 'y' : 'n' }}", "yy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package twig
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+// errorExcerptRadius is the number of source lines shown around the failing line
+const errorExcerptRadius = 3
+
+// DefaultErrorTemplate is a ready-made error page that can be registered with
+// RegisterString and selected with SetErrorTemplate
+const DefaultErrorTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Twig Error: {{ message|e }}</title>
+<style>
+body { font-family: sans-serif; margin: 0; background: #f5f5f5; color: #222; }
+header { background: #b0413e; color: #fff; padding: 16px 24px; }
+header h1 { margin: 0; font-size: 20px; }
+section { margin: 16px 24px; background: #fff; border: 1px solid #ddd; }
+section h2 { margin: 0; padding: 8px 12px; font-size: 14px; background: #eee; }
+pre { margin: 0; padding: 8px 0; font-size: 13px; white-space: pre; overflow-x: auto; }
+.line { display: block; padding: 0 12px; }
+.line.current { background: #fce4e4; }
+.number { display: inline-block; width: 4em; color: #999; }
+ol { margin: 0; padding: 8px 32px; }
+</style>
+</head>
+<body>
+<header><h1>{{ message|e }}</h1></header>
+<section>
+<h2>{% if template %}{{ template|e }}{% else %}unknown template{% endif %}{% if line > 0 %} line {{ line }}{% endif %}</h2>
+{% if excerpt %}<pre>{% for row in excerpt %}<span class="line{% if row.current %} current{% endif %}"><span class="number">{{ row.number }}</span>{{ row.code|e }}</span>{% endfor %}</pre>{% endif %}
+</section>
+{% if stack %}<section>
+<h2>Template stack</h2>
+<ol>{% for frame in stack %}<li>{{ frame|e }}</li>{% endfor %}</ol>
+</section>{% endif %}
+</body>
+</html>
+`
+
+// SetErrorTemplate sets the template used to render errors while debug mode is on.
+// When a render fails, the error template is rendered with the error details and
+// its output is returned in place of the template output, along with the error.
+// An empty name disables error pages.
+func (e *Engine) SetErrorTemplate(name string) {
+	e.errorTemplate = name
+}
+
+// renderErrorPage renders the configured error template for a failed render
+func (e *Engine) renderErrorPage(w io.Writer, renderErr error) error {
+	template, err := e.Load(e.errorTemplate)
+	if err != nil {
+		return err
+	}
+
+	return template.RenderTo(w, errorPageContext(renderErr))
+}
+
+// errorPageContext builds the variables available to the error template
+func errorPageContext(err error) map[string]interface{} {
+	context := map[string]interface{}{
+		"message":  err.Error(),
+		"template": "",
+		"line":     0,
+		"column":   0,
+		"excerpt":  []interface{}{},
+		"stack":    []interface{}{},
+	}
+
+	var enhanced *EnhancedError
+	if !errors.As(err, &enhanced) {
+		return context
+	}
+
+	if enhanced.Err != nil {
+		context["message"] = enhanced.Err.Error()
+	}
+	context["template"] = enhanced.Template
+	context["line"] = enhanced.Line
+	context["column"] = enhanced.Column
+	context["excerpt"] = errorExcerpt(enhanced.Source, enhanced.Line)
+
+	if len(enhanced.Stack) > 0 {
+		stack := make([]interface{}, 0, len(enhanced.Stack)+1)
+		for _, frame := range enhanced.TemplateChain() {
+			stack = append(stack, frame.String())
+		}
+		context["stack"] = stack
+	}
+
+	return context
+}
+
+// errorExcerpt returns the source lines surrounding line, keeping their
+// whitespace intact so they can be shown in a <pre> block
+func errorExcerpt(source string, line int) []interface{} {
+	if source == "" || line <= 0 {
+		return []interface{}{}
+	}
+
+	lines := strings.Split(source, "\n")
+	if line > len(lines) {
+		return []interface{}{}
+	}
+
+	start := line - errorExcerptRadius
+	if start < 1 {
+		start = 1
+	}
+	end := line + errorExcerptRadius
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	excerpt := make([]interface{}, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		excerpt = append(excerpt, map[string]interface{}{
+			"number":  i,
+			"code":    strings.TrimRight(lines[i-1], "\r") + "\n",
+			"current": i == line,
+		})
+	}
+
+	return excerpt
+}
@@ -0,0 +1,76 @@
+package twig
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestErrorTemplate tests rendering errors into a configured error page in debug mode
+func TestErrorTemplate(t *testing.T) {
+	engine := New()
+	// Keep debug logging out of the test output
+	origWriter := debugger.writer
+	SetDebugWriter(&bytes.Buffer{})
+	defer SetDebugWriter(origWriter)
+
+	engine.SetDebug(true)
+	defer engine.SetDebug(false)
+
+	if err := engine.RegisterString("error.twig", "ERROR[{{ template }}]: {{ message|e }}"); err != nil {
+		t.Fatalf("Error parsing error template: %v", err)
+	}
+	if err := engine.RegisterString("broken", "Hello {{ name|no_such_filter }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	// Without an error template the error is returned as usual
+	result, err := engine.Render("broken", nil)
+	if err == nil || result != "" {
+		t.Fatalf("Expected plain error, got result=%q err=%v", result, err)
+	}
+
+	engine.SetErrorTemplate("error.twig")
+
+	result, err = engine.Render("broken", nil)
+	if err == nil {
+		t.Fatalf("Expected render error to still be returned")
+	}
+	if !strings.HasPrefix(result, "ERROR[broken]: ") || !strings.Contains(result, "no_such_filter") {
+		t.Errorf("Expected error page output, got %q", result)
+	}
+
+	// RenderTo writes only the error page, not the partial output
+	var buf bytes.Buffer
+	if err := engine.RenderTo(&buf, "broken", nil); err == nil {
+		t.Fatalf("Expected render error from RenderTo")
+	}
+	if !strings.HasPrefix(buf.String(), "ERROR[broken]: ") {
+		t.Errorf("Expected error page output from RenderTo, got %q", buf.String())
+	}
+}
+
+// TestDefaultErrorTemplate tests that the bundled error page parses and renders an excerpt
+func TestDefaultErrorTemplate(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("error.twig", DefaultErrorTemplate); err != nil {
+		t.Fatalf("Error parsing default error template: %v", err)
+	}
+
+	source := "line one\n\tline <two>\nline three"
+	renderErr := NewError(errors.New("boom"), "page.twig", 2, 1, source)
+
+	var buf bytes.Buffer
+	engine.SetErrorTemplate("error.twig")
+	if err := engine.renderErrorPage(&buf, renderErr); err != nil {
+		t.Fatalf("Error rendering error page: %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{"page.twig line 2", "boom", "\tline &lt;two&gt;", `class="line current"`} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected error page to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
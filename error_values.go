@@ -0,0 +1,70 @@
+package twig
+
+import "fmt"
+
+// ErrorFormatter formats error values printed by templates
+type ErrorFormatter func(err error) string
+
+// SetErrorFormatter sets how error values in the context print. By default
+// they print nothing, so internals such as SQL or file paths never reach a
+// page, and their message in debug mode. The formatter also receives the
+// panics of String methods, which would otherwise abort the render.
+func (e *Engine) SetErrorFormatter(formatter ErrorFormatter) {
+	e.environment.errorFormatter = formatter
+}
+
+// formatError returns the printed form of an error value. A panic in the
+// Error method or the formatter prints nothing.
+func (env *Environment) formatError(err error) (str string) {
+	if env == nil {
+		return ""
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			LogWarning("formatting error value %T panicked: %v", err, r)
+			str = ""
+		}
+	}()
+	if env.errorFormatter != nil {
+		return env.errorFormatter(err)
+	}
+	if env.debug {
+		return err.Error()
+	}
+	return ""
+}
+
+// safeString calls String, turning a panic into an error
+func safeString(s fmt.Stringer) (str string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("String method of %T panicked: %v", s, r)
+		}
+	}()
+	return s.String(), nil
+}
+
+// stringerString calls String for printing, formatting a panic like an
+// error value
+func (env *Environment) stringerString(s fmt.Stringer) string {
+	str, err := safeString(s)
+	if err != nil {
+		LogWarning("%v", err)
+		return env.formatError(err)
+	}
+	return str
+}
+
+// toString converts a value for a core filter, printing error values as
+// templates print them
+func (e *CoreExtension) toString(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return e.env.formatError(err)
+	}
+	return toString(v)
+}
+
+func (e *CoreExtension) testError(value interface{}, args ...interface{}) (bool, error) {
+	err, ok := value.(error)
+	return ok && err != nil, nil
+}
@@ -0,0 +1,60 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// panickyStringer fails while formatting itself
+type panickyStringer struct{}
+
+func (panickyStringer) String() string {
+	panic("secret connection string")
+}
+
+func TestErrorValues(t *testing.T) {
+	source := "[{{ err }}][{{ bad }}][{{ err is error ? 'error' : 'ok' }}][{{ 'x' is error ? 'error' : 'ok' }}][{{ bad|upper }}][{{ err|upper }}][{{ [err]|join }}]"
+	context := map[string]interface{}{
+		"err": errors.New("dial tcp 10.0.0.1:5432: refused"),
+		"bad": panickyStringer{},
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(*Engine)
+		expected string
+	}{
+		{"default", func(*Engine) {}, "[][][error][ok][][][]"},
+		{"debug", func(e *Engine) { e.SetDebug(true) }, "[dial tcp 10.0.0.1:5432: refused][String method of twig.panickyStringer panicked: secret connection string][error][ok][][DIAL TCP 10.0.0.1:5432: REFUSED][dial tcp 10.0.0.1:5432: refused]"},
+		{"formatter", func(e *Engine) {
+			e.SetErrorFormatter(func(err error) string { return "unavailable" })
+		}, "[unavailable][unavailable][error][ok][][UNAVAILABLE][unavailable]"},
+		{"panicking formatter", func(e *Engine) {
+			e.SetErrorFormatter(func(err error) string { panic("formatter") })
+		}, "[][][error][ok][][][]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			tt.setup(engine)
+			defer SetDebugLevel(DebugOff)
+
+			template, err := engine.ParseTemplate(source)
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := template.Render(context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if tt.name == "default" && strings.Contains(result, "10.0.0.1") {
+				t.Error("Expected the error message not to leak")
+			}
+		})
+	}
+}
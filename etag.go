@@ -0,0 +1,92 @@
+package twig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Hash returns a SHA-256 digest, in hex, of the template source and of
+// every template it extends, includes, embeds or imports by a literal name,
+// so it changes whenever a page built from the template can. Templates
+// chosen at render time are not covered. Dependencies included with ignore
+// missing are hashed when they exist.
+func (t *Template) Hash() (string, error) {
+	h := sha256.New()
+	seen := make(map[string]bool)
+
+	var visit func(template *Template) error
+	visit = func(template *Template) error {
+		if seen[template.name] {
+			return nil
+		}
+		seen[template.name] = true
+
+		fmt.Fprintf(h, "%s\x00%s\x00", template.name, ContentHash(template.source))
+		if t.engine == nil {
+			return nil
+		}
+
+		deps := &dependencyCollector{}
+		Walk(template.nodes, deps)
+		load := func(name string, optional bool) error {
+			dep, err := t.engine.Load(resolveDependency(template.name, name))
+			if err != nil && errors.Is(err, ErrTemplateNotFound) {
+				dep, err = t.engine.Load(name)
+			}
+			if err != nil {
+				if optional && errors.Is(err, ErrTemplateNotFound) {
+					return nil
+				}
+				return fmt.Errorf("failed to hash dependency %s of %s: %w", name, template.name, err)
+			}
+			return visit(dep)
+		}
+
+		for _, name := range deps.required {
+			if err := load(name, false); err != nil {
+				return err
+			}
+		}
+		for _, name := range deps.optional {
+			if err := load(name, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(t); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ETag returns a strong ETag for pages rendered from the template alone or
+// with a static context, derived from Hash
+func (t *Template) ETag() (string, error) {
+	hash, err := t.Hash()
+	if err != nil {
+		return "", err
+	}
+	return `"` + hash[:32] + `"`, nil
+}
+
+// NotModified sets the ETag header of a response and reports whether the
+// request already has that version, in which case it writes a 304 and the
+// handler should not render the page
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package twig
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTemplateHash(t *testing.T) {
+	engine := New()
+	templates := map[string]string{
+		"base.twig":    `<html>{% block content %}{% endblock %}</html>`,
+		"page.twig":    `{% extends 'base.twig' %}{% block content %}{% include 'partial.twig' %}{% include 'missing.twig' ignore missing %}{% endblock %}`,
+		"partial.twig": `<p>partial</p>`,
+		"other.twig":   `{% include 'page.twig' %}`,
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	hash := func(name string) string {
+		t.Helper()
+		template, err := engine.Load(name)
+		if err != nil {
+			t.Fatalf("Error loading template: %v", err)
+		}
+		h, err := template.Hash()
+		if err != nil {
+			t.Fatalf("Error hashing template: %v", err)
+		}
+		return h
+	}
+
+	page := hash("page.twig")
+	if len(page) != 64 || page != hash("page.twig") {
+		t.Fatalf("Expected a stable SHA-256 hex digest, got %q", page)
+	}
+	if page == hash("other.twig") {
+		t.Error("Expected different templates to hash differently")
+	}
+
+	other := hash("other.twig")
+	if err := engine.RegisterString("partial.twig", `<p>changed</p>`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if hash("page.twig") == page || hash("other.twig") == other {
+		t.Error("Expected a dependency change to change the hash")
+	}
+
+	if err := engine.RegisterString("broken.twig", `{% include 'nowhere.twig' %}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, _ := engine.Load("broken.twig")
+	if _, err := template.Hash(); err == nil || !strings.Contains(err.Error(), "nowhere.twig") {
+		t.Errorf("Expected an error for a missing dependency, got %v", err)
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("page", "static page"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, _ := engine.Load("page")
+	etag, err := template.ETag()
+	if err != nil {
+		t.Fatalf("Error computing ETag: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{"no header", "", false},
+		{"match", etag, true},
+		{"weak match in list", `"abc", W/` + etag, true},
+		{"other version", `"abc"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			if got := NotModified(w, r, etag); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("Expected the ETag header to be set")
+			}
+			if tt.expected && w.Code != http.StatusNotModified {
+				t.Errorf("Expected a 304, got %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/semihalev/twig"
+)
+
+// cmsStore stands in for a database table of templates edited through a CMS.
+// Each row has a revision number that serves as its etag.
+type cmsStore struct {
+	mu   sync.Mutex
+	rows map[string]cmsRow
+}
+
+type cmsRow struct {
+	source   string
+	revision int
+	updated  time.Time
+}
+
+// Fetch implements twig.Fetcher
+func (s *cmsStore) Fetch(name, etag string) (*twig.RemoteTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row, ok := s.rows[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", twig.ErrTemplateNotFound, name)
+	}
+
+	revision := fmt.Sprint(row.revision)
+	if etag == revision {
+		return &twig.RemoteTemplate{NotModified: true}, nil
+	}
+	return &twig.RemoteTemplate{Source: row.source, ETag: revision, ModTime: row.updated}, nil
+}
+
+// save stores a new revision of a template
+func (s *cmsStore) save(name, source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	row := s.rows[name]
+	s.rows[name] = cmsRow{source: source, revision: row.revision + 1, updated: time.Now()}
+}
+
+func main() {
+	store := &cmsStore{rows: make(map[string]cmsRow)}
+	store.save("layout.twig", `<html><body>{% block content %}{% endblock %}</body></html>`)
+	store.save("footer.twig", `<footer>{{ company }}</footer>`)
+	store.save("home.twig", `{% extends "layout.twig" %}
+{% block content %}<h1>{{ title }}</h1>{% include "footer.twig" %}{% endblock %}`)
+
+	// Cache templates for 30 seconds and unknown names for 5 seconds
+	loader := twig.NewRemoteLoader(store, 30*time.Second)
+	loader.SetNegativeTTL(5 * time.Second)
+
+	engine := twig.New()
+	engine.RegisterLoader(loader)
+
+	// With auto-reload the engine asks the loader whether a template
+	// changed, so edits are picked up once the loader's cache expires
+	engine.SetAutoReload(true)
+
+	context := map[string]interface{}{
+		"title":   "Welcome",
+		"company": "Example Inc.",
+	}
+
+	if err := engine.RenderTo(os.Stdout, "home.twig", context); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println()
+
+	// When the CMS saves a template it can invalidate it right away
+	store.save("footer.twig", `<footer>&copy; {{ company }}</footer>`)
+	loader.Invalidate("footer.twig")
+
+	if err := engine.RenderTo(os.Stdout, "home.twig", context); err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println()
+}
@@ -26,6 +26,7 @@ const (
 	ExprHash
 	ExprConditional
 	ExprModuleMethod
+	ExprConcat
 )
 
 // ExpressionNode represents a Twig expression
@@ -75,6 +76,7 @@ type FilterNode struct {
 	node   Node
 	filter string
 	args   []Node
+	named  map[string]Node // Arguments passed by name, as in sort(locale='tr')
 }
 
 // TestNode represents a test (is defined, is null, etc)
@@ -127,6 +129,12 @@ type ConditionalNode struct {
 	falseExpr Node
 }
 
+// ConcatNode represents a chain of ~ concatenations (a ~ b ~ c)
+type ConcatNode struct {
+	ExpressionNode
+	parts []Node
+}
+
 // Type implementation for ExpressionNode
 func (n *ExpressionNode) Type() NodeType {
 	return NodeExpression
@@ -375,6 +383,22 @@ func (n *ConditionalNode) Release() {
 	ReleaseConditionalNode(n)
 }
 
+// Render implementation for ConcatNode
+func (n *ConcatNode) Render(w io.Writer, ctx *RenderContext) error {
+	result, err := ctx.EvaluateExpression(n)
+	if err != nil {
+		return err
+	}
+
+	_, err = WriteString(w, result.(string))
+	return err
+}
+
+// Release returns a ConcatNode to the pool
+func (n *ConcatNode) Release() {
+	ReleaseConcatNode(n)
+}
+
 // Render implementation for ArrayNode
 func (n *ArrayNode) Render(w io.Writer, ctx *RenderContext) error {
 	result, err := ctx.EvaluateExpression(n)
@@ -441,6 +465,25 @@ func NewUnaryNode(operator string, node Node, line int) *UnaryNode {
 	return GetUnaryNode(operator, node, line)
 }
 
+// NewConcatNode creates a concatenation node for left ~ right, flattening
+// nested chains so the whole chain is evaluated into a single buffer
+func NewConcatNode(left, right Node, line int) *ConcatNode {
+	node, ok := left.(*ConcatNode)
+	if !ok {
+		node = GetConcatNode(line)
+		node.parts = append(node.parts, left)
+	}
+
+	if rightConcat, ok := right.(*ConcatNode); ok {
+		node.parts = append(node.parts, rightConcat.parts...)
+		ReleaseConcatNode(rightConcat)
+	} else {
+		node.parts = append(node.parts, right)
+	}
+
+	return node
+}
+
 // NewConditionalNode creates a new conditional (ternary) node
 func NewConditionalNode(condition, trueExpr, falseExpr Node, line int) *ConditionalNode {
 	return GetConditionalNode(condition, trueExpr, falseExpr, line)
@@ -502,14 +545,10 @@ func ParseLiteralOptimized(expr string) (interface{}, bool) {
 
 	// Check for number literals
 	if isDigit(expr[0]) || (expr[0] == '-' && len(expr) > 1 && isDigit(expr[1])) {
-		// Try parsing as integer first (most common case)
-		if i, err := strconv.Atoi(expr); err == nil {
-			return i, true
-		}
-
-		// Try parsing as float
-		if f, err := strconv.ParseFloat(expr, 64); err == nil {
-			return f, true
+		if scanNumber(expr, 0) == len(expr) {
+			if n, err := parseNumber(expr); err == nil {
+				return n, true
+			}
 		}
 	}
 
@@ -116,6 +116,7 @@ func ReleaseFilterNode(node *FilterNode) {
 	node.node = nil
 	node.filter = ""
 	node.args = nil
+	node.named = nil
 	FilterNodePool.Put(node)
 }
 
@@ -204,6 +205,34 @@ func ReleaseConditionalNode(node *ConditionalNode) {
 	ConditionalNodePool.Put(node)
 }
 
+// ConcatNodePool provides a pool for ConcatNode objects
+var ConcatNodePool = sync.Pool{
+	New: func() interface{} {
+		return &ConcatNode{}
+	},
+}
+
+// GetConcatNode gets a ConcatNode from the pool and initializes it
+func GetConcatNode(line int) *ConcatNode {
+	node := ConcatNodePool.Get().(*ConcatNode)
+	node.ExpressionNode.exprType = ExprConcat
+	node.ExpressionNode.line = line
+	node.parts = node.parts[:0]
+	return node
+}
+
+// ReleaseConcatNode returns a ConcatNode to the pool
+func ReleaseConcatNode(node *ConcatNode) {
+	if node == nil {
+		return
+	}
+	for i := range node.parts {
+		node.parts[i] = nil
+	}
+	node.parts = node.parts[:0]
+	ConcatNodePool.Put(node)
+}
+
 // ArrayNodePool provides a pool for ArrayNode objects
 var ArrayNodePool = sync.Pool{
 	New: func() interface{} {
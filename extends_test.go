@@ -0,0 +1,40 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendsPlacement(t *testing.T) {
+	engine := New()
+	engine.RegisterString("a.twig", "A[{% block content %}{% endblock %}]")
+	engine.RegisterString("b.twig", "B[{% block content %}{% endblock %}]")
+
+	nested := []string{
+		"{% if admin %}{% extends 'a.twig' %}{% else %}{% extends 'b.twig' %}{% endif %}",
+		"{% for x in [1] %}\n{% extends 'a.twig' %}{% endfor %}",
+		"{% block content %}{% extends 'a.twig' %}{% endblock %}",
+	}
+	for _, source := range nested {
+		err := engine.RegisterString("page.twig", source)
+		if err == nil || !strings.Contains(err.Error(), "must be at the top level") ||
+			!strings.Contains(err.Error(), "{% extends condition ? 'a.twig' : 'b.twig' %}") {
+			t.Errorf("Expected a placement error for %q, got %v", source, err)
+		}
+	}
+
+	// The suggested pattern chooses the layout at render time
+	source := "{% extends admin ? 'a.twig' : 'b.twig' %}{% block content %}page{% endblock %}"
+	if err := engine.RegisterString("page.twig", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	for admin, expected := range map[bool]string{true: "A[page]", false: "B[page]"} {
+		result, err := engine.Render("page.twig", map[string]interface{}{"admin": admin})
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"html"
+	"io"
 	"math"
 	"math/rand"
 	"net/url"
@@ -15,19 +16,35 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"github.com/semihalev/twig/runtime"
 )
 
-// FilterFunc is a function that can be used as a filter
-type FilterFunc func(value interface{}, args ...interface{}) (interface{}, error)
+// FilterFunc is a function that can be used as a filter.
+//
+// Deprecated: Use runtime.FilterFunc.
+type FilterFunc = runtime.FilterFunc
+
+// FunctionFunc is a function that can be used in templates.
+//
+// Deprecated: Use runtime.FunctionFunc.
+type FunctionFunc = runtime.FunctionFunc
 
-// FunctionFunc is a function that can be used in templates
-type FunctionFunc func(args ...interface{}) (interface{}, error)
+// TestFunc is a function that can be used for testing conditions.
+//
+// Deprecated: Use runtime.TestFunc.
+type TestFunc = runtime.TestFunc
 
-// TestFunc is a function that can be used for testing conditions
-type TestFunc func(value interface{}, args ...interface{}) (bool, error)
+// OperatorFunc is a function that implements a custom operator.
+//
+// Deprecated: Use runtime.OperatorFunc.
+type OperatorFunc = runtime.OperatorFunc
 
-// OperatorFunc is a function that implements a custom operator
-type OperatorFunc func(left, right interface{}) (interface{}, error)
+// StringerFunc converts a value to its template string form.
+//
+// Deprecated: Use runtime.StringerFunc.
+type StringerFunc = runtime.StringerFunc
 
 // Extension represents a Twig extension
 type Extension interface {
@@ -63,7 +80,9 @@ type TokenParser interface {
 }
 
 // CoreExtension provides the core Twig functionality
-type CoreExtension struct{}
+type CoreExtension struct {
+	env *Environment // Environment of the engine the extension was added to
+}
 
 // GetName returns the name of the core extension
 func (e *CoreExtension) GetName() string {
@@ -104,6 +123,44 @@ func (e *CoreExtension) GetFilters() map[string]FilterFunc {
 		"format":        e.filterFormat,
 		"json_encode":   e.filterJsonEncode,
 		"spaceless":     e.filterSpaceless,
+		"repeat":        e.filterRepeat,
+		"filesize":      e.filterFilesize,
+		"duration":      e.filterDuration,
+
+		"strip_control_chars":  e.filterStripControlChars,
+		"normalize_whitespace": e.filterNormalizeWhitespace,
+
+		// Exact decimal arithmetic for prices and other money values
+		"money_add":       moneyFilter("+"),
+		"money_sub":       moneyFilter("-"),
+		"money_mul":       moneyFilter("*"),
+		"money_div":       moneyFilter("/"),
+		"round_half_even": e.filterRoundHalfEven,
+
+		"sanitize_html": e.filterSanitizeHTML,
+		"truncate_html": e.filterTruncateHTML,
+		"mask":          e.filterMask,
+		"batch":         e.filterBatch,
+		"xml_encode":    e.filterXMLEncode,
+		"trans":         e.filterTrans,
+
+		"base64_encode": e.filterBase64Encode,
+		"base64_decode": e.filterBase64Decode,
+		"md5":           hashFilter("md5"),
+		"sha1":          hashFilter("sha1"),
+		"sha256":        hashFilter("sha256"),
+
+		"paginate": e.filterPaginate,
+
+		// Color manipulation for design tokens
+		"lighten":        adjustLightness("lighten", 1),
+		"darken":         adjustLightness("darken", -1),
+		"alpha":          e.filterAlpha,
+		"contrast_color": e.filterContrastColor,
+
+		// Streaming exports
+		"csv": e.filterCSV,
+		"tsv": e.filterTSV,
 	}
 }
 
@@ -120,9 +177,21 @@ func (e *CoreExtension) GetFunctions() map[string]FunctionFunc {
 		"cycle":       e.functionCycle,
 		"include":     e.functionInclude,
 		"json_encode": e.functionJsonEncode,
+		"json_ld":     e.functionJsonLD,
 		"length":      e.functionLength,
 		"merge":       e.functionMerge,
 		"parent":      e.functionParent,
+		"asset":       e.functionAsset,
+		"path":        e.functionPath,
+		"url":         e.functionURL,
+		"paginate":    e.functionPaginate,
+		"hash":        e.functionHash,
+
+		"uuid":          e.functionUUID,
+		"random_string": e.functionRandomString,
+		"image_size":    e.functionImageSize,
+		"sri_hash":      e.functionSRIHash,
+		"enum":          e.functionEnum,
 	}
 }
 
@@ -136,7 +205,9 @@ func (e *CoreExtension) GetTests() map[string]TestFunc {
 		"even":         e.testEven,
 		"odd":          e.testOdd,
 		"iterable":     e.testIterable,
+		"same as":      e.testSameAs,
 		"same_as":      e.testSameAs,
+		"divisible by": e.testDivisibleBy,
 		"divisible_by": e.testDivisibleBy,
 		"constant":     e.testConstant,
 		"equalto":      e.testEqualTo,
@@ -144,6 +215,8 @@ func (e *CoreExtension) GetTests() map[string]TestFunc {
 		"starts_with":  e.testStartsWith,
 		"ends_with":    e.testEndsWith,
 		"matches":      e.testMatches,
+		"date":         e.testDate,
+		"error":        e.testError,
 	}
 }
 
@@ -154,7 +227,6 @@ func (e *CoreExtension) GetOperators() map[string]OperatorFunc {
 		"not in":      e.operatorNotIn,
 		"is":          e.operatorIs,
 		"is not":      e.operatorIsNot,
-		"not":         e.operatorNot, // Add explicit support for 'not' operator
 		"matches":     e.operatorMatches,
 		"starts with": e.operatorStartsWith,
 		"ends with":   e.operatorEndsWith,
@@ -168,7 +240,13 @@ func (e *CoreExtension) GetTokenParsers() []TokenParser {
 
 // Initialize initializes the core extension
 func (e *CoreExtension) Initialize(engine *Engine) {
-	// Nothing to initialize for core extension
+	// Keep the environment for filters that consult engine settings
+	e.env = engine.environment
+
+	// Filters taking a locale by name, see collation.go
+	engine.AddFilterV2("sort", e.filterSortCollated)
+	engine.AddFilterV2("natural_sort", e.filterNaturalSort)
+	engine.AddFilterV2("sort_by_keys", e.filterSortByKeys)
 }
 
 // CustomExtension provides a simple way to create custom extensions
@@ -266,22 +344,23 @@ func (e *CoreExtension) filterDefault(value interface{}, args ...interface{}) (i
 }
 
 func (e *CoreExtension) filterEscape(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
-	return escapeHTML(s), nil
+	escape := escapeStrategy(args)
+	s := e.toString(value)
+	return escape(s), nil
 }
 
 func (e *CoreExtension) filterUpper(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 	return strings.ToUpper(s), nil
 }
 
 func (e *CoreExtension) filterLower(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 	return strings.ToLower(s), nil
 }
 
 func (e *CoreExtension) filterTrim(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 
 	// Basic trim with no args - just trim whitespace
 	if len(args) == 0 {
@@ -290,7 +369,7 @@ func (e *CoreExtension) filterTrim(value interface{}, args ...interface{}) (inte
 
 	// Trim specific characters
 	if len(args) > 0 {
-		chars := toString(args[0])
+		chars := e.toString(args[0])
 		return strings.Trim(s, chars), nil
 	}
 
@@ -334,7 +413,7 @@ func (e *CoreExtension) filterJoin(value interface{}, args ...interface{}) (inte
 		value = newSlice
 	}
 
-	return join(value, delimiter)
+	return e.join(value, delimiter)
 }
 
 func (e *CoreExtension) filterSplit(value interface{}, args ...interface{}) (interface{}, error) {
@@ -363,7 +442,12 @@ func (e *CoreExtension) filterSplit(value interface{}, args ...interface{}) (int
 		}
 	}
 
-	s := toString(value)
+	// A missing value splits into no parts
+	if value == nil {
+		return []string{}, nil
+	}
+
+	s := e.toString(value)
 
 	// Handle multiple character delimiters (split on any character in the delimiter)
 	if len(delimiter) > 1 {
@@ -395,7 +479,7 @@ func (e *CoreExtension) filterDate(value interface{}, args ...interface{}) (inte
 	// Special handling for nil/empty values
 	if value == nil {
 		// For nil, return current time
-		dt = time.Now()
+		dt = e.env.now()
 	} else {
 		switch v := value.(type) {
 		case time.Time:
@@ -403,14 +487,14 @@ func (e *CoreExtension) filterDate(value interface{}, args ...interface{}) (inte
 			// Check if it's a zero time value (0001-01-01 00:00:00)
 			if dt.Year() == 1 && dt.Month() == 1 && dt.Day() == 1 && dt.Hour() == 0 && dt.Minute() == 0 && dt.Second() == 0 {
 				// Use current time instead of zero time
-				dt = time.Now()
+				dt = e.env.now()
 			}
 		case string:
 			// Handle empty strings and "now"
 			if v == "" || v == "0" {
-				dt = time.Now()
+				dt = e.env.now()
 			} else if v == "now" {
-				dt = time.Now()
+				dt = e.env.now()
 			} else {
 				// Try to parse as integer timestamp first
 				if timestamp, err := strconv.ParseInt(v, 10, 64); err == nil {
@@ -444,34 +528,34 @@ func (e *CoreExtension) filterDate(value interface{}, args ...interface{}) (inte
 
 					if !parsed {
 						// If nothing worked, fallback to current time
-						dt = time.Now()
+						dt = e.env.now()
 					}
 				}
 			}
 		case int64:
 			// Handle 0 timestamp
 			if v == 0 {
-				dt = time.Now()
+				dt = e.env.now()
 			} else {
 				dt = time.Unix(v, 0)
 			}
 		case int:
 			// Handle 0 timestamp
 			if v == 0 {
-				dt = time.Now()
+				dt = e.env.now()
 			} else {
 				dt = time.Unix(int64(v), 0)
 			}
 		case float64:
 			// Handle 0 timestamp
 			if v == 0 {
-				dt = time.Now()
+				dt = e.env.now()
 			} else {
 				dt = time.Unix(int64(v), 0)
 			}
 		default:
 			// For unknown types, use current time
-			dt = time.Now()
+			dt = e.env.now()
 		}
 	}
 
@@ -484,11 +568,23 @@ func (e *CoreExtension) filterDate(value interface{}, args ...interface{}) (inte
 		}
 	}
 
+	// Show the date in the timezone given as second argument
+	if len(args) > 1 {
+		switch tz := args[1].(type) {
+		case *time.Location:
+			dt = dt.In(tz)
+		case string:
+			if loc, err := time.LoadLocation(tz); err == nil {
+				dt = dt.In(loc)
+			}
+		}
+	}
+
 	return dt.Format(format), nil
 }
 
 func (e *CoreExtension) filterUrlEncode(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 	return url.QueryEscape(s), nil
 }
 
@@ -568,7 +664,7 @@ func (e *CoreExtension) functionRange(args ...interface{}) (interface{}, error)
 
 func (e *CoreExtension) functionDate(args ...interface{}) (interface{}, error) {
 	// Default to current time
-	dt := time.Now()
+	dt := e.env.now()
 
 	// Check if a timestamp or date string was provided
 	if len(args) > 0 && args[0] != nil {
@@ -578,7 +674,7 @@ func (e *CoreExtension) functionDate(args ...interface{}) (interface{}, error) {
 		case string:
 			if v == "now" {
 				// "now" is current time
-				dt = time.Now()
+				dt = e.env.now()
 			} else {
 				// Try to parse string
 				var err error
@@ -677,6 +773,11 @@ func (e *CoreExtension) functionMax(args ...interface{}) (interface{}, error) {
 		return nil, errors.New("max function requires at least one argument")
 	}
 
+	// Registered comparators and times take precedence over the built-in ordering
+	if result, ok := e.env.extremeWithComparators(args, 1); ok {
+		return result, nil
+	}
+
 	// First, determine if we're comparing strings or numbers
 	allStrings := true
 	for _, arg := range args {
@@ -727,6 +828,11 @@ func (e *CoreExtension) functionMin(args ...interface{}) (interface{}, error) {
 		return nil, errors.New("min function requires at least one argument")
 	}
 
+	// Registered comparators and times take precedence over the built-in ordering
+	if result, ok := e.env.extremeWithComparators(args, -1); ok {
+		return result, nil
+	}
+
 	// First, determine if we're comparing strings or numbers
 	allStrings := true
 	for _, arg := range args {
@@ -789,8 +895,10 @@ func (e *CoreExtension) functionDump(args ...interface{}) (interface{}, error) {
 }
 
 func (e *CoreExtension) functionConstant(args ...interface{}) (interface{}, error) {
-	// Not applicable in Go, but included for compatibility
-	return nil, errors.New("constant function not supported in Go")
+	if len(args) == 0 {
+		return nil, errors.New("constant function requires a name argument")
+	}
+	return e.env.constant(e.toString(args[0]))
 }
 
 // Test implementations
@@ -813,6 +921,11 @@ func (e *CoreExtension) testNull(value interface{}, args ...interface{}) (bool,
 	return value == nil, nil
 }
 
+func (e *CoreExtension) testDate(value interface{}, args ...interface{}) (bool, error) {
+	_, ok := asTime(value)
+	return ok, nil
+}
+
 func (e *CoreExtension) testEven(value interface{}, args ...interface{}) (bool, error) {
 	i, err := toInt(value)
 	if err != nil {
@@ -863,8 +976,14 @@ func (e *CoreExtension) testDivisibleBy(value interface{}, args ...interface{})
 }
 
 func (e *CoreExtension) testConstant(value interface{}, args ...interface{}) (bool, error) {
-	// Not applicable in Go, but included for compatibility
-	return false, errors.New("constant test not supported in Go")
+	if len(args) == 0 {
+		return false, errors.New("constant test requires a name argument")
+	}
+	constant, err := e.env.constant(e.toString(args[0]))
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(value, constant), nil
 }
 
 func (e *CoreExtension) testEqualTo(value interface{}, args ...interface{}) (bool, error) {
@@ -876,8 +995,8 @@ func (e *CoreExtension) testEqualTo(value interface{}, args ...interface{}) (boo
 	compareWith := args[0]
 
 	// Convert to strings and compare
-	str1 := toString(value)
-	str2 := toString(compareWith)
+	str1 := e.toString(value)
+	str2 := e.toString(compareWith)
 
 	return str1 == str2, nil
 }
@@ -888,8 +1007,8 @@ func (e *CoreExtension) testStartsWith(value interface{}, args ...interface{}) (
 	}
 
 	// Convert to strings
-	str := toString(value)
-	prefix := toString(args[0])
+	str := e.toString(value)
+	prefix := e.toString(args[0])
 
 	return strings.HasPrefix(str, prefix), nil
 }
@@ -900,8 +1019,8 @@ func (e *CoreExtension) testEndsWith(value interface{}, args ...interface{}) (bo
 	}
 
 	// Convert to strings
-	str := toString(value)
-	suffix := toString(args[0])
+	str := e.toString(value)
+	suffix := e.toString(args[0])
 
 	return strings.HasSuffix(str, suffix), nil
 }
@@ -912,8 +1031,8 @@ func (e *CoreExtension) testMatches(value interface{}, args ...interface{}) (boo
 	}
 
 	// Convert to strings
-	str := toString(value)
-	pattern := toString(args[0])
+	str := e.toString(value)
+	pattern := e.toString(args[0])
 
 	// Remove any surrounding slashes (Twig style pattern) if present
 	if len(pattern) >= 2 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
@@ -936,7 +1055,7 @@ func (e *CoreExtension) operatorIn(left, right interface{}) (interface{}, error)
 		return false, errors.New("right operand must be iterable")
 	}
 
-	return contains(right, left)
+	return e.contains(right, left)
 }
 
 func (e *CoreExtension) operatorNotIn(left, right interface{}) (interface{}, error) {
@@ -944,7 +1063,7 @@ func (e *CoreExtension) operatorNotIn(left, right interface{}) (interface{}, err
 		return false, errors.New("right operand must be iterable")
 	}
 
-	result, err := contains(right, left)
+	result, err := e.contains(right, left)
 	if err != nil {
 		return false, err
 	}
@@ -1001,23 +1120,10 @@ func toBool(value interface{}) bool {
 	return true
 }
 
-// operatorNot implements the 'not' operator
-func (e *CoreExtension) operatorNot(left, right interface{}) (interface{}, error) {
-	// Special case for "not defined" test
-	if testName, ok := right.(string); ok && testName == "defined" {
-		// The actual check happens during evaluation in RenderContext
-		// This just returns true to indicate we're handling it
-		return true, nil
-	}
-
-	// For all other cases, just negate the boolean value of the right operand
-	return !toBool(right), nil
-}
-
 func (e *CoreExtension) operatorMatches(left, right interface{}) (interface{}, error) {
 	// Convert to strings
-	str := toString(left)
-	pattern := toString(right)
+	str := e.toString(left)
+	pattern := e.toString(right)
 
 	// Compile the regex
 	regex, err := regexp.Compile(pattern)
@@ -1030,16 +1136,16 @@ func (e *CoreExtension) operatorMatches(left, right interface{}) (interface{}, e
 
 func (e *CoreExtension) operatorStartsWith(left, right interface{}) (interface{}, error) {
 	// Convert to strings
-	str := toString(left)
-	prefix := toString(right)
+	str := e.toString(left)
+	prefix := e.toString(right)
 
 	return strings.HasPrefix(str, prefix), nil
 }
 
 func (e *CoreExtension) operatorEndsWith(left, right interface{}) (interface{}, error) {
 	// Convert to strings
-	str := toString(left)
-	suffix := toString(right)
+	str := e.toString(left)
+	suffix := e.toString(right)
 
 	return strings.HasSuffix(str, suffix), nil
 }
@@ -1068,9 +1174,28 @@ func isEmptyValue(v interface{}) bool {
 		return len(value) == 0
 	}
 
-	// Use reflection for other types
+	// Typed nils, such as a nil *Order, are empty
 	rv := reflect.ValueOf(v)
 	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if rv.IsNil() {
+			return true
+		}
+	}
+
+	// Custom types know whether they are empty
+	if n, ok := collectionLen(v); ok {
+		return n == 0
+	}
+	if zeroer, ok := v.(interface{ IsZero() bool }); ok {
+		return zeroer.IsZero()
+	}
+	if rv.Kind() == reflect.Ptr {
+		return isEmptyValue(rv.Elem().Interface())
+	}
+
+	// Use reflection for other types
+	switch rv.Kind() {
 	case reflect.Array, reflect.Slice, reflect.Map:
 		return rv.Len() == 0
 	case reflect.Bool:
@@ -1097,6 +1222,10 @@ func isIterable(v interface{}) bool {
 	switch v.(type) {
 	case string, []interface{}, map[string]interface{}:
 		return true
+	case Indexer, Keyer:
+		// Custom collections, unless a typed nil pointer
+		rv := reflect.ValueOf(v)
+		return rv.Kind() != reflect.Ptr || !rv.IsNil()
 	}
 
 	// Use reflection for other types
@@ -1122,6 +1251,9 @@ func length(v interface{}) (int, error) {
 	case map[string]interface{}:
 		return len(value), nil
 	}
+	if n, ok := collectionLen(v); ok {
+		return n, nil
+	}
 
 	// Use reflection for other types
 	rv := reflect.ValueOf(v)
@@ -1133,7 +1265,7 @@ func length(v interface{}) (int, error) {
 	return 0, fmt.Errorf("cannot get length of %T", v)
 }
 
-func join(v interface{}, delimiter string) (string, error) {
+func (e *CoreExtension) join(v interface{}, delimiter string) (string, error) {
 	var items []string
 
 	if v == nil {
@@ -1146,7 +1278,7 @@ func join(v interface{}, delimiter string) (string, error) {
 		return strings.Join(value, delimiter), nil
 	case []interface{}:
 		for _, item := range value {
-			items = append(items, toString(item))
+			items = append(items, e.toString(item))
 		}
 	default:
 		// Try reflection for other types
@@ -1154,22 +1286,22 @@ func join(v interface{}, delimiter string) (string, error) {
 		switch rv.Kind() {
 		case reflect.Array, reflect.Slice:
 			for i := 0; i < rv.Len(); i++ {
-				items = append(items, toString(rv.Index(i).Interface()))
+				items = append(items, e.toString(rv.Index(i).Interface()))
 			}
 		default:
-			return toString(v), nil
+			return e.toString(v), nil
 		}
 	}
 
 	return strings.Join(items, delimiter), nil
 }
 
-func contains(container, item interface{}) (bool, error) {
+func (e *CoreExtension) contains(container, item interface{}) (bool, error) {
 	if container == nil {
 		return false, nil
 	}
 
-	itemStr := toString(item)
+	itemStr := e.toString(item)
 
 	// Handle different container types
 	switch c := container.(type) {
@@ -1177,7 +1309,7 @@ func contains(container, item interface{}) (bool, error) {
 		return strings.Contains(c, itemStr), nil
 	case []interface{}:
 		for _, v := range c {
-			if toString(v) == itemStr {
+			if e.toString(v) == itemStr {
 				return true, nil
 			}
 		}
@@ -1195,13 +1327,13 @@ func contains(container, item interface{}) (bool, error) {
 			return strings.Contains(rv.String(), itemStr), nil
 		case reflect.Array, reflect.Slice:
 			for i := 0; i < rv.Len(); i++ {
-				if toString(rv.Index(i).Interface()) == itemStr {
+				if e.toString(rv.Index(i).Interface()) == itemStr {
 					return true, nil
 				}
 			}
 		case reflect.Map:
 			for _, key := range rv.MapKeys() {
-				if toString(key.Interface()) == itemStr {
+				if e.toString(key.Interface()) == itemStr {
 					return true, nil
 				}
 			}
@@ -1229,13 +1361,43 @@ func toString(v interface{}) string {
 		return strconv.FormatBool(val)
 	case []byte:
 		return string(val)
+	case error:
+		// Without an environment errors print nothing, see
+		// CoreExtension.toString and SetErrorFormatter
+		return ""
 	case fmt.Stringer:
-		return val.String()
+		str, _ := safeString(val)
+		return str
+	case io.Reader:
+		return readerString(val)
 	}
 
 	return fmt.Sprintf("%v", v)
 }
 
+// readerString reads the rest of a reader for filters and operators. A
+// reader that can seek is rewound afterwards, so the value can still be
+// printed; any other reader is consumed.
+func readerString(r io.Reader) string {
+	seeker, ok := r.(io.Seeker)
+	var offset int64
+	if ok {
+		var err error
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			ok = false
+		}
+	}
+
+	data, err := io.ReadAll(r)
+	if ok {
+		seeker.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
 func toInt(v interface{}) (int, error) {
 	if v == nil {
 		return 0, errors.New("cannot convert nil to int")
@@ -1335,7 +1497,7 @@ func convertDateFormat(format string) string {
 // Additional filter implementations
 
 func (e *CoreExtension) filterCapitalize(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 	if s == "" {
 		return "", nil
 	}
@@ -1352,7 +1514,7 @@ func (e *CoreExtension) filterCapitalize(value interface{}, args ...interface{})
 
 // filterTitle implements a title case filter (similar to capitalize but for all words)
 func (e *CoreExtension) filterTitle(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 	if s == "" {
 		return "", nil
 	}
@@ -1712,6 +1874,14 @@ func (e *CoreExtension) filterKeys(value interface{}, args ...interface{}) (inte
 }
 
 func (e *CoreExtension) filterMerge(value interface{}, args ...interface{}) (interface{}, error) {
+	// A missing value merges as empty, so the first argument becomes the base
+	if value == nil {
+		if len(args) == 0 {
+			return nil, nil
+		}
+		return e.filterMerge(args[0], args[1:]...)
+	}
+
 	// Handle merging arrays/slices
 	rv := reflect.ValueOf(value)
 	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
@@ -1773,21 +1943,52 @@ func (e *CoreExtension) filterMerge(value interface{}, args ...interface{}) (int
 }
 
 func (e *CoreExtension) filterReplace(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
+
+	// Twig style replacement map: replace({'search': 'replace', ...})
+	if len(args) == 1 {
+		if pairs, ok := args[0].(map[string]interface{}); ok {
+			return replacePairs(s, pairs), nil
+		}
+	}
 
 	if len(args) < 2 {
 		return s, errors.New("replace filter requires at least 2 arguments (search and replace values)")
 	}
 
 	// Get search and replace values
-	search := toString(args[0])
-	replace := toString(args[1])
+	search := e.toString(args[0])
+	replace := e.toString(args[1])
 
 	return strings.ReplaceAll(s, search, replace), nil
 }
 
+// replacePairs replaces every key of pairs with its value in a single pass,
+// trying longer keys first like PHP's strtr
+func replacePairs(s string, pairs map[string]interface{}) string {
+	keys := make([]string, 0, len(pairs))
+	for key := range pairs {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	oldnew := make([]string, 0, len(keys)*2)
+	for _, key := range keys {
+		oldnew = append(oldnew, key, toString(pairs[key]))
+	}
+
+	return strings.NewReplacer(oldnew...).Replace(s)
+}
+
 func (e *CoreExtension) filterStripTags(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 
 	// Very simple regexp-based HTML tag removal
 	re := regexp.MustCompile("<[^>]*>")
@@ -1799,6 +2000,13 @@ func (e *CoreExtension) filterSort(value interface{}, args ...interface{}) (inte
 		return nil, nil
 	}
 
+	// Registered comparators take precedence over the built-in ordering
+	if e.env.hasComparators() {
+		if result, ok := e.env.sortWithComparators(value); ok {
+			return result, nil
+		}
+	}
+
 	// Special handling for string slices - convert to []interface{} for consistent handling in for loops
 	switch v := value.(type) {
 	case []string:
@@ -1846,7 +2054,11 @@ func (e *CoreExtension) filterSort(value interface{}, args ...interface{}) (inte
 		result := make([]interface{}, len(v))
 		copy(result, v)
 		sort.Slice(result, func(i, j int) bool {
-			return toString(result[i]) < toString(result[j])
+			// Times are ordered chronologically
+			if c, ok := compareTimes(result[i], result[j]); ok {
+				return c < 0
+			}
+			return e.toString(result[i]) < e.toString(result[j])
 		})
 		return result, nil
 	}
@@ -1865,7 +2077,7 @@ func (e *CoreExtension) filterSort(value interface{}, args ...interface{}) (inte
 			b := result.Index(j).Interface()
 
 			// Always sort by string representation for consistency
-			return toString(a) < toString(b)
+			return e.toString(a) < e.toString(b)
 		})
 
 		return result.Interface(), nil
@@ -2006,7 +2218,7 @@ func (e *CoreExtension) filterRound(value interface{}, args ...interface{}) (int
 }
 
 func (e *CoreExtension) filterNl2Br(value interface{}, args ...interface{}) (interface{}, error) {
-	s := toString(value)
+	s := e.toString(value)
 
 	// Replace newlines with <br> (HTML5 style, no self-closing slash)
 	s = strings.ReplaceAll(s, "\r\n", "<br>")
@@ -2182,7 +2394,7 @@ func (e *CoreExtension) functionMerge(args ...interface{}) (interface{}, error)
 			// Use reflection for other map types
 			baseRv := reflect.ValueOf(base)
 			for _, key := range baseRv.MapKeys() {
-				keyStr := toString(key.Interface())
+				keyStr := e.toString(key.Interface())
 				result[keyStr] = baseRv.MapIndex(key).Interface()
 			}
 		}
@@ -2199,7 +2411,7 @@ func (e *CoreExtension) functionMerge(args ...interface{}) (interface{}, error)
 				argRv := reflect.ValueOf(arg)
 				if argRv.Kind() == reflect.Map {
 					for _, key := range argRv.MapKeys() {
-						keyStr := toString(key.Interface())
+						keyStr := e.toString(key.Interface())
 						result[keyStr] = argRv.MapIndex(key).Interface()
 					}
 				}
@@ -2246,7 +2458,11 @@ func (e *CoreExtension) functionParent(args ...interface{}) (interface{}, error)
 		var result bytes.Buffer
 
 		// Create a clean context without parent() function to prevent recursion
-		cleanCtx := NewRenderContext(ctx.env, ctx.context, ctx.engine)
+		cleanCtx := NewRenderContext(ctx.env, ctx.variables(), ctx.engine)
+		cleanCtx.options = ctx.options
+		cleanCtx.visits = ctx.visits
+		cleanCtx.labels = ctx.labels
+		cleanCtx.features = ctx.features
 		defer cleanCtx.Release()
 
 		// Copy all blocks and variables
@@ -2259,7 +2475,7 @@ func (e *CoreExtension) functionParent(args ...interface{}) (interface{}, error)
 
 		// Render each node with the clean context
 		for _, node := range parentContent {
-			if err := node.Render(&result, cleanCtx); err != nil {
+			if err := cleanCtx.render(&result, node); err != nil {
 				return nil, err
 			}
 		}
@@ -2283,14 +2499,18 @@ func escapeHTML(s string) string {
 
 // filterFormat implements the format filter similar to fmt.Sprintf
 func (e *CoreExtension) filterFormat(value interface{}, args ...interface{}) (interface{}, error) {
-	formatString := toString(value)
+	formatString := e.toString(value)
 
-	// If no args, just return the string
-	if len(args) == 0 {
+	// If no args or no format string, just return the string
+	if len(args) == 0 || formatString == "" {
 		return formatString, nil
 	}
 
-	// Apply formatting
+	// Validate the verbs against the arguments, see checkFormat
+	formatString, args, err := e.checkFormat(formatString, args)
+	if err != nil {
+		return nil, err
+	}
 	return fmt.Sprintf(formatString, args...), nil
 }
 
@@ -2346,3 +2566,55 @@ func (e *CoreExtension) filterSpaceless(value interface{}, args ...interface{})
 
 	return result, nil
 }
+
+// filterStripControlChars removes control characters except tabs and newlines
+func (e *CoreExtension) filterStripControlChars(value interface{}, args ...interface{}) (interface{}, error) {
+	str := e.toString(value)
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, str), nil
+}
+
+// isZeroWidth reports whether r is an invisible zero-width character
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200B', '\u200C', '\u200D', '\u2060', '\uFEFF':
+		return true
+	}
+	return false
+}
+
+// filterNormalizeWhitespace removes zero-width characters, collapses runs of
+// Unicode whitespace into a single space and trims the result
+func (e *CoreExtension) filterNormalizeWhitespace(value interface{}, args ...interface{}) (interface{}, error) {
+	str := e.toString(value)
+
+	var b strings.Builder
+	b.Grow(len(str))
+
+	pendingSpace := false
+	for _, r := range str {
+		switch {
+		case isZeroWidth(r):
+			continue
+		case unicode.IsSpace(r):
+			pendingSpace = b.Len() > 0
+			continue
+		}
+
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,224 @@
+package twig
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMessageDomain is the domain of messages that do not name one
+const DefaultMessageDomain = "messages"
+
+// MessageReference is a place in a template where a message is used
+type MessageReference struct {
+	Template string `json:"template"`
+	Line     int    `json:"line"`
+}
+
+// Message is a translatable string found in templates
+type Message struct {
+	ID         string             `json:"id"`
+	Domain     string             `json:"domain"`
+	References []MessageReference `json:"references"`
+}
+
+// Catalog is the set of messages extracted from templates, sorted by domain
+// and ID
+type Catalog struct {
+	Messages []*Message `json:"messages"`
+}
+
+// ExtractMessages collects the messages passed to the trans filter in the
+// named templates. Only literal strings can be extracted; the domain is
+// read from the second filter argument, as in 'Hello'|trans({}, 'admin').
+func (e *Engine) ExtractMessages(names ...string) (*Catalog, error) {
+	x := &messageExtractor{messages: make(map[[2]string]*Message)}
+
+	for _, name := range names {
+		template, err := e.Load(name)
+		if err != nil {
+			return nil, err
+		}
+		x.template = template.name
+		Walk(template.nodes, x)
+	}
+
+	catalog := &Catalog{Messages: make([]*Message, 0, len(x.messages))}
+	for _, message := range x.messages {
+		catalog.Messages = append(catalog.Messages, message)
+	}
+	sort.Slice(catalog.Messages, func(i, j int) bool {
+		a, b := catalog.Messages[i], catalog.Messages[j]
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		return a.ID < b.ID
+	})
+	return catalog, nil
+}
+
+// messageExtractor is the visitor used by ExtractMessages
+type messageExtractor struct {
+	template string
+	messages map[[2]string]*Message // Keyed by domain and ID
+}
+
+// Enter records trans filters applied to string literals
+func (x *messageExtractor) Enter(node Node) bool {
+	filter, ok := node.(*FilterNode)
+	if !ok || filter.filter != "trans" {
+		return true
+	}
+
+	id, ok := literalString(filter.node)
+	if !ok {
+		return true
+	}
+	domain := DefaultMessageDomain
+	if len(filter.args) > 1 {
+		if d, ok := literalString(filter.args[1]); ok && d != "" {
+			domain = d
+		}
+	}
+
+	key := [2]string{domain, id}
+	message, ok := x.messages[key]
+	if !ok {
+		message = &Message{ID: id, Domain: domain}
+		x.messages[key] = message
+	}
+	message.References = append(message.References, MessageReference{Template: x.template, Line: filter.Line()})
+	return true
+}
+
+// Leave implements NodeVisitor
+func (x *messageExtractor) Leave(node Node) {}
+
+// literalString returns the value of a string literal node
+func literalString(node Node) (string, bool) {
+	literal, ok := node.(*LiteralNode)
+	if !ok {
+		return "", false
+	}
+	s, ok := literal.value.(string)
+	return s, ok
+}
+
+// Domain returns the messages of one domain
+func (c *Catalog) Domain(domain string) *Catalog {
+	filtered := &Catalog{Messages: []*Message{}}
+	for _, message := range c.Messages {
+		if message.Domain == domain {
+			filtered.Messages = append(filtered.Messages, message)
+		}
+	}
+	return filtered
+}
+
+// WriteJSON writes the catalog as indented JSON
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// WritePO writes the catalog as a gettext PO template. Messages outside the
+// default domain get their domain as msgctxt.
+func (c *Catalog) WritePO(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("msgid \"\"\nmsgstr \"\"\n\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+
+	for _, message := range c.Messages {
+		b.WriteString("\n")
+		for _, ref := range message.References {
+			fmt.Fprintf(&b, "#: %s:%d\n", ref.Template, ref.Line)
+		}
+		if message.Domain != DefaultMessageDomain {
+			fmt.Fprintf(&b, "msgctxt %s\n", poQuote(message.Domain))
+		}
+		fmt.Fprintf(&b, "msgid %s\nmsgstr \"\"\n", poQuote(message.ID))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// poQuote quotes a string for a PO file, splitting multiline strings after
+// each newline as gettext tools do
+func poQuote(s string) string {
+	if !strings.Contains(s, "\n") || strings.Index(s, "\n") == len(s)-1 {
+		return strconv.Quote(s)
+	}
+
+	lines := strings.SplitAfter(s, "\n")
+	quoted := []string{`""`}
+	for _, line := range lines {
+		if line != "" {
+			quoted = append(quoted, strconv.Quote(line))
+		}
+	}
+	return strings.Join(quoted, "\n")
+}
+
+// xliff is the XLIFF 1.2 document written by WriteXLIFF
+type xliff struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string           `xml:"original,attr"`
+	SourceLanguage string           `xml:"source-language,attr"`
+	Datatype       string           `xml:"datatype,attr"`
+	Units          []xliffTransUnit `xml:"body>trans-unit"`
+}
+
+type xliffTransUnit struct {
+	ID     string `xml:"id,attr"`
+	Source string `xml:"source"`
+	Note   string `xml:"note,omitempty"`
+}
+
+// WriteXLIFF writes the catalog as an XLIFF 1.2 document with one file
+// element per domain
+func (c *Catalog) WriteXLIFF(w io.Writer, sourceLanguage string) error {
+	doc := xliff{Version: "1.2"}
+
+	for _, message := range c.Messages {
+		if len(doc.Files) == 0 || doc.Files[len(doc.Files)-1].Original != message.Domain {
+			doc.Files = append(doc.Files, xliffFile{
+				Original:       message.Domain,
+				SourceLanguage: sourceLanguage,
+				Datatype:       "plaintext",
+			})
+		}
+
+		refs := make([]string, len(message.References))
+		for i, ref := range message.References {
+			refs[i] = fmt.Sprintf("%s:%d", ref.Template, ref.Line)
+		}
+
+		file := &doc.Files[len(doc.Files)-1]
+		file.Units = append(file.Units, xliffTransUnit{
+			ID:     message.ID,
+			Source: message.ID,
+			Note:   strings.Join(refs, " "),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
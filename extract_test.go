@@ -0,0 +1,98 @@
+package twig
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExtractMessages(t *testing.T) {
+	engine := New()
+	templates := map[string]string{
+		"page.twig":   "{{ 'Hello'|trans }}\n{{ 'Save'|trans({}, 'admin') }}\n{{ title|trans }}",
+		"layout.twig": "{% block nav %}{{ 'Hello'|trans|upper }}{% endblock %}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+	}
+
+	catalog, err := engine.ExtractMessages("layout.twig", "page.twig")
+	if err != nil {
+		t.Fatalf("Error extracting messages: %v", err)
+	}
+
+	if len(catalog.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(catalog.Messages))
+	}
+
+	save := catalog.Messages[0]
+	if save.ID != "Save" || save.Domain != "admin" || len(save.References) != 1 || save.References[0].Line != 2 {
+		t.Errorf("Unexpected message: %+v", save)
+	}
+
+	hello := catalog.Messages[1]
+	if hello.ID != "Hello" || hello.Domain != DefaultMessageDomain {
+		t.Errorf("Unexpected message: %+v", hello)
+	}
+	expected := []MessageReference{{Template: "layout.twig", Line: 1}, {Template: "page.twig", Line: 1}}
+	if len(hello.References) != 2 || hello.References[0] != expected[0] || hello.References[1] != expected[1] {
+		t.Errorf("Expected references %v, got %v", expected, hello.References)
+	}
+
+	if admin := catalog.Domain("admin"); len(admin.Messages) != 1 || admin.Messages[0].ID != "Save" {
+		t.Errorf("Expected only the admin message, got %+v", admin.Messages)
+	}
+}
+
+func TestCatalogFormats(t *testing.T) {
+	catalog := &Catalog{Messages: []*Message{
+		{ID: "Delete", Domain: "admin", References: []MessageReference{{Template: "admin.twig", Line: 4}}},
+		{ID: "Hello \"you\"\nbye", Domain: DefaultMessageDomain, References: []MessageReference{{Template: "page.twig", Line: 1}}},
+	}}
+
+	tests := []struct {
+		name     string
+		write    func(*bytes.Buffer) error
+		expected []string
+	}{
+		{
+			name:     "json",
+			write:    func(b *bytes.Buffer) error { return catalog.WriteJSON(b) },
+			expected: []string{`"id": "Delete"`, `"domain": "admin"`, `"template": "admin.twig"`},
+		},
+		{
+			name:  "po",
+			write: func(b *bytes.Buffer) error { return catalog.WritePO(b) },
+			expected: []string{
+				"#: admin.twig:4\nmsgctxt \"admin\"\nmsgid \"Delete\"\nmsgstr \"\"",
+				"#: page.twig:1\nmsgid \"\"\n\"Hello \\\"you\\\"\\n\"\n\"bye\"\nmsgstr \"\"",
+			},
+		},
+		{
+			name:  "xliff",
+			write: func(b *bytes.Buffer) error { return catalog.WriteXLIFF(b, "en") },
+			expected: []string{
+				`<file original="admin" source-language="en" datatype="plaintext">`,
+				`<trans-unit id="Delete">`,
+				`<note>admin.twig:4</note>`,
+				`<source>Hello &#34;you&#34;&#xA;bye</source>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf); err != nil {
+				t.Fatalf("Error writing catalog: %v", err)
+			}
+			for _, want := range tt.expected {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Expected output to contain %q, got:\n%s", want, buf.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package twig
+
+import "errors"
+
+// SetFallbackTemplate sets the template rendered in place of a missing
+// template when debug mode is off. It applies to templates passed to Render
+// and RenderTo as well as include and extends targets, so a page survives a
+// fragment that is temporarily absent. The original error is passed to the
+// error hook. An empty name disables the fallback.
+func (e *Engine) SetFallbackTemplate(name string) {
+	e.fallback = name
+}
+
+// SetErrorHook sets a function called with errors the engine recovers from,
+// such as missing templates replaced by the fallback template
+func (e *Engine) SetErrorHook(hook func(err error)) {
+	e.errorHook = hook
+}
+
+// loadFallback returns the fallback template in place of a template that
+// failed to load with err. The error is returned unchanged when it is not a
+// missing template, no fallback is configured or debug mode is on.
+func (e *Engine) loadFallback(err error) (*Template, error) {
+	if e.fallback == "" || e.environment.debug || !errors.Is(err, ErrTemplateNotFound) {
+		return nil, err
+	}
+
+	template, fallbackErr := e.Load(e.fallback)
+	if fallbackErr != nil {
+		// Report the original problem rather than the broken fallback
+		return nil, err
+	}
+
+	e.reportError(err)
+	return template, nil
+}
+
+// reportError passes a recovered error to the error hook
+func (e *Engine) reportError(err error) {
+	if e.errorHook != nil {
+		e.errorHook(err)
+	}
+}
@@ -0,0 +1,118 @@
+package twig
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFallbackTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		render    string
+		templates map[string]string
+		expected  string
+		reported  bool
+	}{
+		{
+			name:   "missing include",
+			render: "page",
+			templates: map[string]string{
+				"page": `<main>{% include "sidebar.twig" %}</main>`,
+			},
+			expected: "<main>[missing]</main>",
+			reported: true,
+		},
+		{
+			name:   "missing parent",
+			render: "page",
+			templates: map[string]string{
+				"page": `{% extends "layout.twig" %}{% block body %}hi{% endblock %}`,
+			},
+			expected: "[missing]",
+			reported: true,
+		},
+		{
+			name:      "missing page",
+			render:    "nope.twig",
+			templates: map[string]string{},
+			expected:  "[missing]",
+			reported:  true,
+		},
+		{
+			name:   "ignore missing wins",
+			render: "page",
+			templates: map[string]string{
+				"page": `<main>{% include "sidebar.twig" ignore missing %}</main>`,
+			},
+			expected: "<main></main>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			var reported []error
+			engine.SetFallbackTemplate("404.twig")
+			engine.SetErrorHook(func(err error) { reported = append(reported, err) })
+
+			tt.templates["404.twig"] = "[missing]"
+			for name, source := range tt.templates {
+				if err := engine.RegisterString(name, source); err != nil {
+					t.Fatalf("Error parsing %s: %v", name, err)
+				}
+			}
+
+			result, err := engine.Render(tt.render, nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+
+			if tt.reported && (len(reported) != 1 || !errors.Is(reported[0], ErrTemplateNotFound)) {
+				t.Errorf("Expected the missing template to be reported, got %v", reported)
+			}
+			if !tt.reported && len(reported) != 0 {
+				t.Errorf("Expected no reported errors, got %v", reported)
+			}
+		})
+	}
+}
+
+func TestFallbackTemplateNotUsed(t *testing.T) {
+	t.Run("syntax errors", func(t *testing.T) {
+		engine := New()
+		engine.SetFallbackTemplate("404.twig")
+		engine.RegisterString("404.twig", "[missing]")
+		engine.RegisterLoader(NewArrayLoader(map[string]string{"broken.twig": "{% if %}"}))
+		engine.RegisterString("page", `{% include "broken.twig" %}`)
+
+		if _, err := engine.Render("page", nil); err == nil {
+			t.Error("Expected the syntax error")
+		}
+	})
+
+	t.Run("debug mode", func(t *testing.T) {
+		engine := New()
+		engine.SetFallbackTemplate("404.twig")
+		engine.RegisterString("404.twig", "[missing]")
+		engine.RegisterString("page", `{% include "sidebar.twig" %}`)
+		engine.SetDevelopmentMode(true)
+		defer engine.SetDebug(false)
+
+		if _, err := engine.Render("page", nil); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected the missing template error, got %v", err)
+		}
+	})
+
+	t.Run("missing fallback", func(t *testing.T) {
+		engine := New()
+		engine.SetFallbackTemplate("404.twig")
+		engine.RegisterString("page", `{% include "sidebar.twig" %}`)
+
+		if _, err := engine.Render("page", nil); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected the missing template error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package twig
+
+import "fmt"
+
+// FeatureProvider decides whether a feature flag is enabled. It receives the
+// variables of the render context, so flags can depend on values such as the
+// current user.
+type FeatureProvider interface {
+	IsEnabled(flag string, vars map[string]interface{}) (bool, error)
+}
+
+// FeatureProviderFunc adapts a function to the FeatureProvider interface
+type FeatureProviderFunc func(flag string, vars map[string]interface{}) (bool, error)
+
+// IsEnabled calls f(flag, vars)
+func (f FeatureProviderFunc) IsEnabled(flag string, vars map[string]interface{}) (bool, error) {
+	return f(flag, vars)
+}
+
+// FeatureFlags is a static FeatureProvider. Flags missing from the map are
+// disabled.
+type FeatureFlags map[string]bool
+
+// IsEnabled reports whether the flag is set
+func (f FeatureFlags) IsEnabled(flag string, vars map[string]interface{}) (bool, error) {
+	return f[flag], nil
+}
+
+// featureEnabled asks the environment's provider about a flag, remembering
+// the answer for the rest of the render so a flag checked in several places,
+// including included, imported and parent templates, cannot change halfway
+// through a page
+func (ctx *RenderContext) featureEnabled(flag interface{}) (bool, error) {
+	if ctx.env == nil || ctx.env.featureProvider == nil {
+		return false, nil
+	}
+
+	name := ctx.ToString(flag)
+	if ctx.features == nil {
+		// A context not created for a render keeps the answers itself
+		base := ctx.scopeBase()
+		if base.features == nil {
+			base.features = make(map[string]bool)
+		}
+		ctx.features = base.features
+	}
+	if enabled, ok := ctx.features[name]; ok {
+		return enabled, nil
+	}
+
+	enabled, err := ctx.env.featureProvider.IsEnabled(name, ctx.variables())
+	if err != nil {
+		return false, fmt.Errorf("feature %q: %w", name, err)
+	}
+	ctx.features[name] = enabled
+	return enabled, nil
+}
+
+// callFeatureFunction implements feature(name)
+func (ctx *RenderContext) callFeatureFunction(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("feature function requires a flag name")
+	}
+	return ctx.featureEnabled(args[0])
+}
@@ -0,0 +1,154 @@
+package twig
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	flags := FeatureFlags{"new_nav": true, "beta": false}
+
+	tests := []struct {
+		name     string
+		provider FeatureProvider
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "feature function",
+			provider: flags,
+			source:   `{% if feature('new_nav') %}new{% else %}old{% endif %}`,
+			expected: "new",
+		},
+		{
+			name:     "disabled flag",
+			provider: flags,
+			source:   `{% if feature('beta') %}beta{% else %}stable{% endif %}`,
+			expected: "stable",
+		},
+		{
+			name:     "unknown flag",
+			provider: flags,
+			source:   `{{ feature('missing') ? "on" : "off" }}`,
+			expected: "off",
+		},
+		{
+			name:     "enabled test",
+			provider: flags,
+			source:   `{% if 'new_nav' is enabled %}new{% endif %}|{% if 'beta' is not enabled %}no beta{% endif %}`,
+			expected: "new|no beta",
+		},
+		{
+			name:     "no provider",
+			provider: nil,
+			source:   `{{ feature('new_nav') ? "on" : "off" }}`,
+			expected: "off",
+		},
+		{
+			name: "provider sees context variables",
+			provider: FeatureProviderFunc(func(flag string, vars map[string]interface{}) (bool, error) {
+				user, _ := vars["user"].(map[string]interface{})
+				return flag == "admin_tools" && user["role"] == "admin", nil
+			}),
+			source:   `{% if feature('admin_tools') %}tools{% endif %}`,
+			context:  map[string]interface{}{"user": map[string]interface{}{"role": "admin"}},
+			expected: "tools",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if tt.provider != nil {
+				engine.SetFeatureProvider(tt.provider)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagsEvaluatedOncePerRender(t *testing.T) {
+	calls := 0
+	engine := New()
+	engine.SetFeatureProvider(FeatureProviderFunc(func(flag string, vars map[string]interface{}) (bool, error) {
+		calls++
+		return calls == 1, nil
+	}))
+
+	source := `{{ feature('flip') ? "a" : "b" }}{{ feature('flip') ? "a" : "b" }}{% if 'flip' is enabled %}a{% endif %}`
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "aaa" {
+		t.Errorf("Expected the flag to stay stable within a render, got %q", result)
+	}
+
+	// A new render asks the provider again
+	result, err = engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "bb" || calls != 2 {
+		t.Errorf("Expected a fresh evaluation, got %q after %d calls", result, calls)
+	}
+}
+
+func TestFeatureFlagsSharedAcrossTemplates(t *testing.T) {
+	calls := 0
+	engine := New()
+	engine.SetFeatureProvider(FeatureProviderFunc(func(flag string, vars map[string]interface{}) (bool, error) {
+		calls++
+		return calls == 1, nil
+	}))
+
+	templates := map[string]string{
+		"base":   `{{ feature('flip') ? "a" : "b" }}{% block body %}{% endblock %}`,
+		"page":   `{% extends "base" %}{% block body %}{% import "macros" as m %}{% include "part" %}{{ m.flag() }}{% endblock %}`,
+		"part":   `{{ feature('flip') ? "a" : "b" }}`,
+		"macros": `{% macro flag() %}{{ feature('flip') ? "a" : "b" }}{% endmacro %}`,
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	result, err := engine.Render("page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "aaa" || calls != 1 {
+		t.Errorf("Expected one evaluation for the whole render, got %q after %d calls", result, calls)
+	}
+}
+
+func TestFeatureProviderError(t *testing.T) {
+	engine := New()
+	engine.SetFeatureProvider(FeatureProviderFunc(func(flag string, vars map[string]interface{}) (bool, error) {
+		return false, errors.New("flag service unavailable")
+	}))
+
+	if err := engine.RegisterString("test", `{{ feature('x') }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("test", nil); err == nil {
+		t.Error("Expected the provider error")
+	}
+}
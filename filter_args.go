@@ -0,0 +1,87 @@
+package twig
+
+import "fmt"
+
+// FilterArgs holds the normalized arguments of a filter call: positional
+// arguments in order, named arguments by name, and the render context
+type FilterArgs struct {
+	Positional []interface{}
+	Named      map[string]interface{}
+	Context    *RenderContext // nil when the filter is called outside a render
+}
+
+// FilterFuncV2 is a filter that receives normalized arguments. Register it
+// with AddFilterV2.
+type FilterFuncV2 func(value interface{}, args FilterArgs) (interface{}, error)
+
+// Len returns the number of arguments passed, positional and named
+func (a FilterArgs) Len() int {
+	return len(a.Positional) + len(a.Named)
+}
+
+// Get returns the argument passed by name, or else at position i
+func (a FilterArgs) Get(i int, name string) (interface{}, bool) {
+	if value, ok := a.Named[name]; ok {
+		return value, true
+	}
+	if i >= 0 && i < len(a.Positional) {
+		return a.Positional[i], true
+	}
+	return nil, false
+}
+
+// GetDefault returns the argument like Get, or def when it was not passed
+func (a FilterArgs) GetDefault(i int, name string, def interface{}) interface{} {
+	if value, ok := a.Get(i, name); ok {
+		return value
+	}
+	return def
+}
+
+// AddFilterV2 registers a filter that receives normalized arguments. The
+// filter is also available as a FilterFunc to code that calls filters
+// directly, without a render context.
+func (e *Engine) AddFilterV2(name string, filter FilterFuncV2) {
+	if e.environment.filtersV2 == nil {
+		e.environment.filtersV2 = make(map[string]FilterFuncV2)
+	}
+	e.environment.filtersV2[name] = filter
+	e.environment.filters[name] = func(value interface{}, args ...interface{}) (interface{}, error) {
+		return filter(value, FilterArgs{Positional: args})
+	}
+}
+
+// AdaptFilter turns a FilterFunc into a FilterFuncV2. Positional arguments
+// are passed through; the filter rejects named arguments since it has no
+// names for its parameters.
+func AdaptFilter(filter FilterFunc) FilterFuncV2 {
+	return func(value interface{}, args FilterArgs) (interface{}, error) {
+		if len(args.Named) > 0 {
+			return nil, fmt.Errorf("filter does not accept named arguments")
+		}
+		return filter(value, args.Positional...)
+	}
+}
+
+// applyFilterArgs calls a registered filter with normalized arguments,
+// adapting FilterFunc filters. It reports false when no filter is registered
+// under name.
+func (ctx *RenderContext) applyFilterArgs(name string, value interface{}, args FilterArgs) (interface{}, bool, error) {
+	if ctx.env == nil {
+		return nil, false, nil
+	}
+
+	args.Context = ctx
+	if filter, ok := ctx.env.filtersV2[name]; ok {
+		result, err := filter(value, args)
+		return result, true, err
+	}
+	if filter, ok := ctx.env.filters[name]; ok {
+		if len(args.Named) > 0 {
+			return nil, true, fmt.Errorf("filter '%s' does not accept named arguments", name)
+		}
+		result, err := filter(value, args.Positional...)
+		return result, true, err
+	}
+	return nil, false, nil
+}
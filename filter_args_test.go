@@ -0,0 +1,95 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterV2(t *testing.T) {
+	engine := New()
+	engine.AddGlobal("sep", "-")
+	engine.AddFilterV2("pad", func(value interface{}, args FilterArgs) (interface{}, error) {
+		width, _ := args.GetDefault(0, "width", 5).(int)
+		fill := toString(args.GetDefault(1, "fill", "."))
+		if args.Context != nil {
+			if sep, ok := args.Context.GetVariableOrNil("sep").(string); ok {
+				fill = sep
+			}
+		}
+		s := toString(value)
+		if len(s) < width {
+			s += strings.Repeat(fill, width-len(s))
+		}
+		return s, nil
+	})
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"defaults", `{{ 'ab'|pad }}`, "ab---"},
+		{"positional", `{{ 'ab'|pad(3) }}`, "ab-"},
+		{"chained with v1 filters", `{{ 'ab'|upper|pad(4)|lower }}`, "ab--"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Registering a FilterFunc under the same name replaces the v2 filter
+	engine.AddFilter("pad", func(value interface{}, args ...interface{}) (interface{}, error) {
+		return "v1", nil
+	})
+	if err := engine.RegisterString("test", `{{ 'ab'|pad }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if result, err := engine.Render("test", nil); err != nil || result != "v1" {
+		t.Errorf("Expected the replacement filter, got %q (%v)", result, err)
+	}
+}
+
+func TestFilterArgs(t *testing.T) {
+	args := FilterArgs{
+		Positional: []interface{}{1, 2},
+		Named:      map[string]interface{}{"limit": 10},
+	}
+
+	if args.Len() != 3 {
+		t.Errorf("Expected 3 arguments, got %d", args.Len())
+	}
+	if v, ok := args.Get(1, "second"); !ok || v != 2 {
+		t.Errorf("Expected positional argument 2, got %v", v)
+	}
+	if v, ok := args.Get(5, "limit"); !ok || v != 10 {
+		t.Errorf("Expected named argument 10, got %v", v)
+	}
+	if _, ok := args.Get(5, "missing"); ok {
+		t.Error("Expected a missing argument")
+	}
+	if v := args.GetDefault(5, "missing", "def"); v != "def" {
+		t.Errorf("Expected the default, got %v", v)
+	}
+
+	upper := AdaptFilter(func(value interface{}, args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(value)) + toString(len(args)), nil
+	})
+	if v, err := upper("a", FilterArgs{Positional: []interface{}{1}}); err != nil || v != "A1" {
+		t.Errorf("Expected the adapted filter to run, got %v (%v)", v, err)
+	}
+	if _, err := upper("a", args); err == nil {
+		t.Error("Expected adapted filters to reject named arguments")
+	}
+}
@@ -0,0 +1,122 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FilterArgWarning reports a filter argument of a type the filter does not
+// expect and silently converts, such as a number passed to replace as the
+// search string
+type FilterArgWarning struct {
+	Template string
+	Line     int
+	Filter   string
+	Arg      int    // Position of the argument, from 0
+	Expected string // "string", "number" or "string or hash"
+	Got      string // Go type of the argument
+}
+
+// String formats the warning as key=value pairs
+func (w FilterArgWarning) String() string {
+	return fmt.Sprintf("filter argument coerced: template=%q line=%d filter=%s arg=%d expected=%q got=%s",
+		w.Template, w.Line, w.Filter, w.Arg, w.Expected, w.Got)
+}
+
+// FilterArgReporter receives the warnings of strict filter argument mode
+type FilterArgReporter func(warning FilterArgWarning)
+
+// SetStrictFilterArgs turns checking the argument types of the core filters
+// on or off. Rendering is unchanged; each argument that would be coerced is
+// passed to reporter, or logged with LogWarning when reporter is nil.
+func (e *Engine) SetStrictFilterArgs(enabled bool, reporter FilterArgReporter) {
+	e.environment.strictFilterArgs = enabled
+	e.environment.filterArgReporter = reporter
+}
+
+// filterArgKind is the type a filter expects for an argument
+type filterArgKind int
+
+const (
+	argAny filterArgKind = iota
+	argString
+	argNumber
+	argStringOrHash
+)
+
+func (k filterArgKind) String() string {
+	switch k {
+	case argString:
+		return "string"
+	case argNumber:
+		return "number"
+	case argStringOrHash:
+		return "string or hash"
+	}
+	return "any"
+}
+
+// accepts reports whether the filter can use v without converting it. A
+// null argument is the same as leaving it out.
+func (k filterArgKind) accepts(v interface{}) bool {
+	if v == nil || k == argAny {
+		return true
+	}
+
+	switch v.(type) {
+	case string, fmt.Stringer:
+		return k == argString || k == argStringOrHash
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return k == argNumber
+	case reflect.Map:
+		return k == argStringOrHash
+	}
+	return false
+}
+
+// coreFilterArgs lists the argument types of the core filters that convert
+// their arguments
+var coreFilterArgs = map[string][]filterArgKind{
+	"replace":       {argStringOrHash, argString},
+	"trim":          {argString},
+	"join":          {argString},
+	"split":         {argString, argNumber},
+	"slice":         {argNumber, argNumber},
+	"round":         {argNumber, argString},
+	"number_format": {argNumber, argString, argString},
+	"striptags":     {argString},
+	"date":          {argString, argString},
+}
+
+// checkFilterArgs reports the arguments of a core filter whose types the
+// filter does not expect
+func (ctx *RenderContext) checkFilterArgs(name string, args []interface{}, line int) {
+	kinds := coreFilterArgs[name]
+	for i, arg := range args {
+		if i >= len(kinds) || kinds[i].accepts(arg) {
+			continue
+		}
+
+		warning := FilterArgWarning{
+			Line:     line,
+			Filter:   name,
+			Arg:      i,
+			Expected: kinds[i].String(),
+			Got:      fmt.Sprintf("%T", arg),
+		}
+		if ctx.lastLoadedTemplate != nil {
+			warning.Template = ctx.lastLoadedTemplate.name
+		}
+
+		if ctx.env.filterArgReporter != nil {
+			ctx.env.filterArgReporter(warning)
+		} else {
+			LogWarning("%s", warning)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package twig
+
+import "testing"
+
+func TestStrictFilterArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+		warnings []FilterArgWarning
+	}{
+		{
+			name:     "number as replace search",
+			source:   "{{ 'a1b'|replace(1, 'x') }}",
+			expected: "axb",
+			warnings: []FilterArgWarning{
+				{Template: "test", Line: 1, Filter: "replace", Arg: 0, Expected: "string or hash", Got: "int"},
+			},
+		},
+		{
+			name:     "string as slice start",
+			source:   "{{ 'hello'|slice(start, 2) }}",
+			expected: "ll",
+			warnings: []FilterArgWarning{
+				{Template: "test", Line: 1, Filter: "slice", Arg: 0, Expected: "number", Got: "string"},
+			},
+		},
+		{
+			name:     "expected types",
+			source:   "{{ 'a-b'|replace({'-': '+'})|split('+', 2)|join(', ') }}{{ 1.5|round(0, 'floor') }}",
+			expected: "a, b1",
+		},
+		{
+			name:     "null arguments",
+			source:   "{{ 'a b c'|split(' ', null)|join('-') }}",
+			expected: "a-b-c",
+		},
+		{
+			name:     "line of the filter",
+			source:   "\n\n{{ 'x'|trim(0) }}",
+			expected: "\n\nx",
+			warnings: []FilterArgWarning{
+				{Template: "test", Line: 3, Filter: "trim", Arg: 0, Expected: "string", Got: "int"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			var warnings []FilterArgWarning
+			engine.SetStrictFilterArgs(true, func(warning FilterArgWarning) {
+				warnings = append(warnings, warning)
+			})
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"start": "2"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if len(warnings) != len(tt.warnings) {
+				t.Fatalf("Expected warnings %+v, got %+v", tt.warnings, warnings)
+			}
+			for i, warning := range warnings {
+				if warning != tt.warnings[i] {
+					t.Errorf("Expected warning %+v, got %+v", tt.warnings[i], warning)
+				}
+			}
+		})
+	}
+}
+
+func TestStrictFilterArgsDisabled(t *testing.T) {
+	engine := New()
+	reported := false
+	engine.SetStrictFilterArgs(false, func(FilterArgWarning) { reported = true })
+	if err := engine.RegisterString("test", "{{ 'a1b'|replace(1, 'x') }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("test", nil); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if reported {
+		t.Error("Expected no warnings with strict filter arguments off")
+	}
+}
@@ -1022,3 +1022,40 @@ func TestExtensionsFunctions(t *testing.T) {
 		})
 	}
 }
+
+// TestSanitizationFilters tests strip_control_chars and normalize_whitespace
+func TestSanitizationFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		input    interface{}
+		expected string
+	}{
+		{"Strip control chars", "{{ s|strip_control_chars }}", "a\x00b\x07c\x1bd\u0085e", "abcde"},
+		{"Strip control chars keeps tabs and newlines", "{{ s|strip_control_chars }}", "a\tb\nc\r\n", "a\tb\nc\r\n"},
+		{"Strip control chars keeps unicode text", "{{ s|strip_control_chars }}", "héllo 世界", "héllo 世界"},
+		{"Normalize whitespace collapses runs", "{{ s|normalize_whitespace }}", "a  \t\n b", "a b"},
+		{"Normalize whitespace trims", "[{{ s|normalize_whitespace }}]", "  \u00a0 a b \u3000 ", "[a b]"},
+		{"Normalize unicode spaces", "{{ s|normalize_whitespace }}", "a\u00a0b\u2003c\u202fd", "a b c d"},
+		{"Normalize removes zero-width chars", "{{ s|normalize_whitespace }}", "in\u200bvis\u200dible\ufeff", "invisible"},
+		{"Combined", "[{{ s|strip_control_chars|normalize_whitespace }}]", "\x00 user\x07 \u200b name \n", "[user name]"},
+		{"Nil input", "[{{ s|normalize_whitespace }}{{ s|strip_control_chars }}]", nil, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"s": tt.input})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
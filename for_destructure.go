@@ -0,0 +1,65 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// setValue binds the loop value to the value variable, or unpacks it into
+// the destructuring targets: lists by position, maps and structs by name.
+// Missing items bind nil.
+func (n *ForNode) setValue(ctx *RenderContext, value interface{}) {
+	if n.targets == nil {
+		ctx.setLocal(n.valueVar, value)
+		return
+	}
+
+	v := reflect.ValueOf(value)
+	for i, target := range n.targets {
+		var item interface{}
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			if i < v.Len() && v.Index(i).CanInterface() {
+				item = v.Index(i).Interface()
+			}
+		default:
+			// Lookups of missing names bind nil rather than failing
+			item, _ = ctx.getAttribute(value, target)
+		}
+		ctx.setLocal(target, item)
+	}
+}
+
+// parseForTarget parses a loop variable: a name, or a bracketed list of
+// names to destructure each value into, as in {% for [x, y] in points %}
+func (p *Parser) parseForTarget(line int) (string, []string, error) {
+	if p.isPunctuation("[") {
+		p.tokenIndex++
+
+		var targets []string
+		for {
+			if p.tokenIndex >= len(p.tokens) || p.tokens[p.tokenIndex].Type != TOKEN_NAME {
+				return "", nil, fmt.Errorf("expected variable name in for destructuring at line %d", line)
+			}
+			targets = append(targets, p.tokens[p.tokenIndex].Value)
+			p.tokenIndex++
+
+			if p.isPunctuation(",") {
+				p.tokenIndex++
+				continue
+			}
+			if p.isPunctuation("]") {
+				p.tokenIndex++
+				return "", targets, nil
+			}
+			return "", nil, fmt.Errorf("expected ',' or ']' in for destructuring at line %d", line)
+		}
+	}
+
+	if p.tokenIndex >= len(p.tokens) || p.tokens[p.tokenIndex].Type != TOKEN_NAME {
+		return "", nil, fmt.Errorf("expected variable name after for at line %d", line)
+	}
+	name := p.tokens[p.tokenIndex].Value
+	p.tokenIndex++
+	return name, nil, nil
+}
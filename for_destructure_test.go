@@ -0,0 +1,58 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+type testPoint struct {
+	X, Y int
+}
+
+func TestForDestructuring(t *testing.T) {
+	engine := New()
+
+	context := map[string]interface{}{
+		"coords":  [][]int{{1, 2}, {3, 4}},
+		"pairs":   []interface{}{[]interface{}{"a", 1}, []interface{}{"b"}},
+		"people":  []map[string]interface{}{{"name": "ann", "age": 30}, {"name": "bob"}},
+		"points":  []*testPoint{{X: 5, Y: 6}},
+		"grouped": map[string]interface{}{"first": []int{7, 8}},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"lists by position", "{% for [x, y] in coords %}({{ x }},{{ y }}){% endfor %}", "(1,2)(3,4)"},
+		{"missing items are null", "{% for [k, v] in pairs %}{{ k }}={{ v is null ? 'none' : v }};{% endfor %}", "a=1;b=none;"},
+		{"maps by name", "{% for [name, age] in people %}{{ name }}:{{ age }};{% endfor %}", "ann:30;bob:;"},
+		{"structs by name", "{% for [X, Y] in points %}{{ X }}/{{ Y }}{% endfor %}", "5/6"},
+		{"with key", "{% for key, [a, b] in grouped %}{{ key }} {{ a }} {{ b }}{% endfor %}", "first 7 8"},
+		{"loop variable", "{% for [x, y] in coords %}{{ loop.index }}{% endfor %}", "12"},
+		{"filtered sequence", "{% for [x, y] in coords|reverse %}{{ x }}{% endfor %}", "31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	for _, source := range []string{"{% for [] in coords %}{% endfor %}", "{% for [x y] in coords %}{% endfor %}"} {
+		err := engine.RegisterString("bad", source)
+		if err == nil || !strings.Contains(err.Error(), "for destructuring") {
+			t.Errorf("Expected a destructuring error for %q, got %v", source, err)
+		}
+	}
+}
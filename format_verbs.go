@@ -0,0 +1,187 @@
+package twig
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// SetEscapeUnknownFormatVerbs sets whether the format filter writes verbs
+// it does not know, such as %y, as they are instead of failing. Missing or
+// extra arguments are reported either way.
+func (e *Engine) SetEscapeUnknownFormatVerbs(enabled bool) {
+	e.environment.escapeFormatVerbs = enabled
+}
+
+// formatVerbs are the fmt verbs the format filter accepts. %p is left out,
+// as pointers mean nothing in a template.
+const formatVerbs = "vTtbcdoOqxXUeEfFgGs"
+
+// checkFormat validates a format string against its arguments before it is
+// passed to fmt.Sprintf, which would otherwise write %!d(string=abc) and
+// %!(EXTRA ...) noise into the output. It returns the format string with
+// unknown verbs escaped when that is enabled, and the arguments converted
+// where a number of the other kind is expected, so '%.2f'|format(5) and
+// '%d'|format(2.0) work.
+func (e *CoreExtension) checkFormat(format string, args []interface{}) (string, []interface{}, error) {
+	escape := e.env != nil && e.env.escapeFormatVerbs
+	converted := append([]interface{}(nil), args...)
+
+	var b strings.Builder
+	argNum := 0
+	reordered := false
+
+	// argIndex reads an explicit argument index such as [2] at format[i:]
+	argIndex := func(i int) (int, error) {
+		if i >= len(format) || format[i] != '[' {
+			return i, nil
+		}
+		end := strings.IndexByte(format[i:], ']')
+		if end < 0 {
+			return 0, fmt.Errorf("format filter has an unclosed argument index")
+		}
+		n, err := strconv.Atoi(format[i+1 : i+end])
+		if err != nil || n < 1 || n > len(args) {
+			return 0, fmt.Errorf("format filter has an invalid argument index %s", format[i:i+end+1])
+		}
+		argNum = n - 1
+		reordered = true
+		return i + end + 1, nil
+	}
+
+	// star consumes the argument for a * width or precision
+	star := func() error {
+		if argNum >= len(args) {
+			return fmt.Errorf("format filter is missing an argument for *")
+		}
+		if _, err := formatInteger(args[argNum]); err != nil {
+			return fmt.Errorf("format filter expects an integer for *, got %T", args[argNum])
+		}
+		converted[argNum], _ = formatInteger(args[argNum])
+		argNum++
+		return nil
+	}
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		start := i
+		i++
+		if i < len(format) && format[i] == '%' {
+			b.WriteString("%%")
+			continue
+		}
+
+		for i < len(format) && strings.IndexByte("+-# 0", format[i]) >= 0 {
+			i++
+		}
+		var err error
+		if i, err = argIndex(i); err != nil {
+			return "", nil, err
+		}
+		if i < len(format) && format[i] == '*' {
+			if err := star(); err != nil {
+				return "", nil, err
+			}
+			i++
+		}
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+		if i < len(format) && format[i] == '.' {
+			i++
+			if i, err = argIndex(i); err != nil {
+				return "", nil, err
+			}
+			if i < len(format) && format[i] == '*' {
+				if err := star(); err != nil {
+					return "", nil, err
+				}
+				i++
+			}
+			for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+				i++
+			}
+		}
+		if i, err = argIndex(i); err != nil {
+			return "", nil, err
+		}
+
+		if i >= len(format) {
+			if escape {
+				b.WriteString("%" + format[start:])
+				break
+			}
+			return "", nil, fmt.Errorf("format filter has an incomplete verb %s", format[start:])
+		}
+
+		verb := format[i]
+		if strings.IndexByte(formatVerbs, verb) < 0 {
+			if escape {
+				b.WriteString("%" + format[start:i+1])
+				continue
+			}
+			return "", nil, fmt.Errorf("format filter has an unknown verb %s", format[start:i+1])
+		}
+		if argNum >= len(args) {
+			return "", nil, fmt.Errorf("format filter is missing an argument for %s", format[start:i+1])
+		}
+
+		arg, err := formatArgument(verb, args[argNum])
+		if err != nil {
+			return "", nil, fmt.Errorf("format filter cannot format %T with %s", args[argNum], format[start:i+1])
+		}
+		converted[argNum] = arg
+		argNum++
+		b.WriteString(format[start : i+1])
+	}
+
+	if !reordered && argNum < len(args) {
+		return "", nil, fmt.Errorf("format filter got %d arguments but uses %d", len(args), argNum)
+	}
+	return b.String(), converted, nil
+}
+
+// formatArgument checks that verb can format arg and converts numbers to
+// the kind the verb expects
+func formatArgument(verb byte, arg interface{}) (interface{}, error) {
+	switch verb {
+	case 't':
+		if _, ok := arg.(bool); !ok {
+			return nil, fmt.Errorf("not a bool")
+		}
+	case 'd', 'c', 'U', 'o', 'O':
+		return formatInteger(arg)
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		switch reflect.ValueOf(arg).Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+			return arg, nil
+		}
+		return toFloat64(arg)
+	case 's':
+		if _, ok := arg.(string); !ok {
+			return toString(arg), nil
+		}
+	}
+	return arg, nil
+}
+
+// formatInteger converts arg for an integer verb, accepting floats without
+// a fractional part
+func formatInteger(arg interface{}) (interface{}, error) {
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return arg, nil
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return int64(f), nil
+		}
+	}
+	return nil, fmt.Errorf("not an integer")
+}
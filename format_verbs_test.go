@@ -0,0 +1,103 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatFilterVerbs(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"{{ 'Hello %s, you have %d new messages'|format('John', 5) }}", "Hello John, you have 5 new messages"},
+		{"{{ '%.2f'|format(5) }}", "5.00"},
+		{"{{ '%d items'|format(2.0) }}", "2 items"},
+		{"{{ '%s'|format(42) }}", "42"},
+		{"{{ '100%% of %s'|format('it') }}", "100% of it"},
+		{"{{ '%-*s|'|format(5, 'ab') }}", "ab   |"},
+		{"{{ '%[2]s %[1]s'|format('a', 'b') }}", "b a"},
+		{"{{ '%05.1f'|format(3.14159) }}", "003.1"},
+		{"{{ '%x'|format('hi') }}", "6869"},
+		{"{{ '%t'|format(true) }}", "true"},
+		{"{{ 'no verbs %s'|format }}", "no verbs %s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatFilterErrors(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source  string
+		message string
+	}{
+		{"{{ '%s and %s'|format('a') }}", "missing an argument for %s"},
+		{"{{ '%s'|format('a', 'b') }}", "got 2 arguments but uses 1"},
+		{"{{ '%y'|format('a') }}", "unknown verb %y"},
+		{"{{ '%p'|format('a') }}", "unknown verb %p"},
+		{"{{ '%d'|format('abc') }}", "cannot format string with %d"},
+		{"{{ '%d'|format(1.5) }}", "cannot format float64 with %d"},
+		{"{{ 'total %'|format(1) }}", "incomplete verb %"},
+		{"{{ '%[3]s'|format('a') }}", "invalid argument index [3]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := engine.RegisterString("page", "\n"+tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			_, err := engine.Render("page", nil)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %q", tt.message, err.Error())
+			}
+			var enhanced *EnhancedError
+			if !errors.As(err, &enhanced) || enhanced.Line != 2 {
+				t.Errorf("Expected the error to point at line 2, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFormatFilterEscapeUnknownVerbs(t *testing.T) {
+	engine := New()
+	engine.SetEscapeUnknownFormatVerbs(true)
+
+	if err := engine.RegisterString("page", "{{ '%y %s 50%'|format('ok') }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "%y ok 50%" {
+		t.Errorf("Expected %q, got %q", "%y ok 50%", result)
+	}
+
+	if err := engine.RegisterString("missing", "{{ '%s %s'|format('a') }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("missing", nil); err == nil {
+		t.Error("Expected missing arguments to fail with escaping enabled")
+	}
+}
@@ -0,0 +1,241 @@
+// Package forms provides Symfony-style form rendering functions for Twig
+// templates: form, form_row, form_widget, form_label and form_errors.
+//
+// Forms are described with FormView values and rendered through theme blocks,
+// small templates named after the part they render (form_row, input_widget,
+// choice_widget, ...). The default theme can be replaced block by block with
+// Renderer.SetBlock.
+package forms
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/semihalev/twig"
+)
+
+// FormView describes a form or a single field for rendering
+type FormView struct {
+	Name     string            // Field name
+	ID       string            // Element id, defaults to Name
+	FullName string            // Submitted name, defaults to Name
+	Type     string            // text, email, password, hidden, textarea, choice, checkbox, submit, ...
+	Label    string            // Label text
+	Help     string            // Help text shown below the widget
+	Value    interface{}       // Current value
+	Required bool              // Whether the field is required
+	Errors   []string          // Validation errors
+	Attr     map[string]string // Extra attributes for the widget element
+	Choices  []Choice          // Options of a choice field
+	Children []*FormView       // Fields of a compound form
+
+	Method string // Form method, defaults to post
+	Action string // Form action URL
+}
+
+// Choice is an option of a choice field
+type Choice struct {
+	Label string
+	Value string
+}
+
+// blockPrefix namespaces theme block templates in the engine
+const blockPrefix = "@form/"
+
+// Renderer renders form views with theme blocks registered in an engine
+type Renderer struct {
+	engine *twig.Engine
+
+	mu     sync.RWMutex
+	blocks map[string]bool
+}
+
+// Register adds the form functions and the default theme to an engine
+func Register(engine *twig.Engine) (*Renderer, error) {
+	r := &Renderer{
+		engine: engine,
+		blocks: make(map[string]bool),
+	}
+
+	for name, source := range defaultTheme {
+		if err := r.SetBlock(name, source); err != nil {
+			return nil, err
+		}
+	}
+
+	engine.AddFunction("form", r.function("form"))
+	engine.AddFunction("form_row", r.function("form_row"))
+	engine.AddFunction("form_label", r.function("form_label"))
+	engine.AddFunction("form_errors", r.function("form_errors"))
+	engine.AddFunction("form_widget", r.widgetFunction)
+
+	return r, nil
+}
+
+// SetBlock registers or replaces a theme block. Widget blocks are named after
+// the field type (textarea_widget); input_widget renders any type without its
+// own block.
+func (r *Renderer) SetBlock(name, source string) error {
+	if err := r.engine.RegisterString(blockPrefix+name, source); err != nil {
+		return fmt.Errorf("form theme block %s: %w", name, err)
+	}
+
+	r.mu.Lock()
+	r.blocks[name] = true
+	r.mu.Unlock()
+	return nil
+}
+
+// hasBlock reports whether a theme block is registered
+func (r *Renderer) hasBlock(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.blocks[name]
+}
+
+// function creates a template function rendering the named block
+func (r *Renderer) function(block string) twig.FunctionFunc {
+	return func(args ...interface{}) (interface{}, error) {
+		view, err := viewArg(block, args)
+		if err != nil {
+			return nil, err
+		}
+		return r.renderBlock(block, view)
+	}
+}
+
+// widgetFunction renders the widget block for the view's type
+func (r *Renderer) widgetFunction(args ...interface{}) (interface{}, error) {
+	view, err := viewArg("form_widget", args)
+	if err != nil {
+		return nil, err
+	}
+
+	block := "input_widget"
+	switch {
+	case len(view.Children) > 0:
+		block = "compound_widget"
+	case r.hasBlock(view.fieldType() + "_widget"):
+		block = view.fieldType() + "_widget"
+	}
+
+	return r.renderBlock(block, view)
+}
+
+// renderBlock renders a theme block with the view as form and its
+// normalized variables as vars
+func (r *Renderer) renderBlock(block string, view *FormView) (string, error) {
+	return r.engine.Render(blockPrefix+block, map[string]interface{}{
+		"form": view,
+		"vars": view.vars(),
+	})
+}
+
+// viewArg extracts the form view passed to a form function
+func viewArg(function string, args []interface{}) (*FormView, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s requires a form view argument", function)
+	}
+
+	switch v := args[0].(type) {
+	case *FormView:
+		if v != nil {
+			return v, nil
+		}
+	case FormView:
+		return &v, nil
+	}
+	return nil, fmt.Errorf("%s expects a form view, got %T", function, args[0])
+}
+
+// fieldType returns the view's type, defaulting to text
+func (v *FormView) fieldType() string {
+	if v.Type == "" {
+		return "text"
+	}
+	return v.Type
+}
+
+// vars returns the view's fields with defaults applied under snake_case
+// names for use in theme blocks
+func (v *FormView) vars() map[string]interface{} {
+	id := v.ID
+	if id == "" {
+		id = v.Name
+	}
+	fullName := v.FullName
+	if fullName == "" {
+		fullName = v.Name
+	}
+	method := v.Method
+	if method == "" {
+		method = "post"
+	}
+
+	value := ""
+	if v.Value != nil {
+		value = fmt.Sprint(v.Value)
+	}
+
+	// Attributes are sorted so output is stable
+	names := make([]string, 0, len(v.Attr))
+	for name := range v.Attr {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attr := make([]interface{}, len(names))
+	for i, name := range names {
+		attr[i] = map[string]interface{}{"name": name, "value": v.Attr[name]}
+	}
+
+	choices := make([]interface{}, len(v.Choices))
+	for i, choice := range v.Choices {
+		choices[i] = map[string]interface{}{
+			"label":    choice.Label,
+			"value":    choice.Value,
+			"selected": choice.Value == value,
+		}
+	}
+
+	errors := make([]interface{}, len(v.Errors))
+	for i, err := range v.Errors {
+		errors[i] = err
+	}
+
+	children := make([]interface{}, len(v.Children))
+	for i, child := range v.Children {
+		children[i] = child
+	}
+
+	return map[string]interface{}{
+		"name":      v.Name,
+		"id":        id,
+		"full_name": fullName,
+		"type":      v.fieldType(),
+		"label":     v.Label,
+		"help":      v.Help,
+		"value":     value,
+		"checked":   isChecked(v.Value),
+		"required":  v.Required,
+		"errors":    errors,
+		"attr":      attr,
+		"choices":   choices,
+		"children":  children,
+		"method":    method,
+		"action":    v.Action,
+	}
+}
+
+// isChecked reports whether a checkbox value is set
+func isChecked(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "0" && v != "false"
+	}
+	return true
+}
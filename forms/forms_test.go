@@ -0,0 +1,156 @@
+package forms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/semihalev/twig"
+)
+
+func newEngine(t *testing.T) (*twig.Engine, *Renderer) {
+	t.Helper()
+
+	engine := twig.New()
+	renderer, err := Register(engine)
+	if err != nil {
+		t.Fatalf("Error registering forms: %v", err)
+	}
+	return engine, renderer
+}
+
+func render(t *testing.T, engine *twig.Engine, source string, context map[string]interface{}) string {
+	t.Helper()
+
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+	result, err := engine.Render("test", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	return result
+}
+
+func TestFormFunctions(t *testing.T) {
+	email := &FormView{
+		Name:     "email",
+		Type:     "email",
+		Label:    "E-mail",
+		Value:    "a@b.c",
+		Required: true,
+		Attr:     map[string]string{"placeholder": "you@example.com", "autocomplete": "email"},
+	}
+	bio := &FormView{Name: "bio", Type: "textarea", Label: "Bio", Value: "<hi>", Help: "Tell us"}
+	country := &FormView{
+		Name:    "country",
+		Type:    "choice",
+		Label:   "Country",
+		Value:   "tr",
+		Choices: []Choice{{Label: "Germany", Value: "de"}, {Label: "Türkiye", Value: "tr"}},
+	}
+	terms := &FormView{Name: "terms", Type: "checkbox", Label: "Accept", Value: true}
+	name := &FormView{Name: "name", Label: "Name", Errors: []string{"Too short", "Use <letters>"}}
+	token := &FormView{Name: "_token", Type: "hidden", Value: "abc"}
+	submit := &FormView{Name: "save", Type: "submit", Label: "Save"}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Label", "{{ form_label(f) }}", `<label for="email" class="required">E-mail</label>`},
+		{"Input widget", "{{ form_widget(f) }}", `<input type="email" id="email" name="email" value="a@b.c" required autocomplete="email" placeholder="you@example.com">`},
+		{"Errors", "{{ form_errors(f) }}", ""},
+		{"Row", "{{ form_row(f) }}", `<div class="form-row"><label for="email" class="required">E-mail</label>` +
+			`<input type="email" id="email" name="email" value="a@b.c" required autocomplete="email" placeholder="you@example.com"></div>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := newEngine(t)
+			if result := render(t, engine, tt.source, map[string]interface{}{"f": email}); result != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, result)
+			}
+		})
+	}
+
+	widgets := []struct {
+		name     string
+		view     *FormView
+		expected string
+	}{
+		{"Textarea escapes value", bio, `<textarea id="bio" name="bio">&lt;hi&gt;</textarea>`},
+		{"Choice", country, `<select id="country" name="country"><option value="de">Germany</option><option value="tr" selected>Türkiye</option></select>`},
+		{"Checkbox", terms, `<input type="checkbox" id="terms" name="terms" value="1" checked>`},
+		{"Default type is text", name, `<input type="text" id="name" name="name">`},
+		{"Hidden", token, `<input type="hidden" id="_token" name="_token" value="abc">`},
+		{"Submit", submit, `<button type="submit" id="save" name="save">Save</button>`},
+	}
+
+	for _, tt := range widgets {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, _ := newEngine(t)
+			if result := render(t, engine, "{{ form_widget(f) }}", map[string]interface{}{"f": tt.view}); result != tt.expected {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Row with errors and help", func(t *testing.T) {
+		engine, _ := newEngine(t)
+		result := render(t, engine, "{{ form_row(f) }}", map[string]interface{}{"f": name})
+		expected := `<div class="form-row has-error"><label for="name">Name</label><input type="text" id="name" name="name">` +
+			`<ul class="form-errors"><li>Too short</li><li>Use &lt;letters&gt;</li></ul></div>`
+		if result != expected {
+			t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+		}
+
+		result = render(t, engine, "{{ form_row(f) }}", map[string]interface{}{"f": bio})
+		if !strings.Contains(result, `<small class="form-help">Tell us</small>`) {
+			t.Errorf("Expected help text in %s", result)
+		}
+	})
+
+	t.Run("Whole form", func(t *testing.T) {
+		engine, _ := newEngine(t)
+		form := &FormView{
+			Name:     "profile",
+			Action:   "/profile?x=1&y=2",
+			Children: []*FormView{token, terms, submit},
+		}
+		result := render(t, engine, "{{ form(f) }}", map[string]interface{}{"f": form})
+		expected := `<form method="post" action="/profile?x=1&amp;y=2">` +
+			`<input type="hidden" id="_token" name="_token" value="abc">` +
+			`<div class="form-row"><input type="checkbox" id="terms" name="terms" value="1" checked><label for="terms">Accept</label></div>` +
+			`<div class="form-row"><button type="submit" id="save" name="save">Save</button></div>` +
+			`</form>`
+		if result != expected {
+			t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+		}
+	})
+}
+
+func TestFormTheme(t *testing.T) {
+	engine, renderer := newEngine(t)
+
+	if err := renderer.SetBlock("form_row", `<p>{{ form_label(form) }} {{ form_widget(form) }}</p>`); err != nil {
+		t.Fatalf("Error setting block: %v", err)
+	}
+	if err := renderer.SetBlock("color_widget", `<input type="color" name="{{ vars.full_name|e }}">`); err != nil {
+		t.Fatalf("Error setting block: %v", err)
+	}
+
+	view := &FormView{Name: "c", Type: "color", Label: "Color"}
+	result := render(t, engine, "{{ form_row(f) }}", map[string]interface{}{"f": view})
+	expected := `<p><label for="c">Color</label> <input type="color" name="c"></p>`
+	if result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+
+	t.Run("Invalid argument", func(t *testing.T) {
+		engine.RegisterString("bad", "{{ form_row('x') }}")
+		if _, err := engine.Render("bad", nil); err == nil {
+			t.Error("Expected error for a non form view argument")
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package forms
+
+// defaultTheme holds the default theme blocks, keyed by block name
+var defaultTheme = map[string]string{
+	"form": `<form method="{{ vars.method|e }}"{% if vars.action %} action="{{ vars.action|e }}"{% endif %}>` +
+		`{{ form_errors(form) }}{{ form_widget(form) }}</form>`,
+
+	"form_row": `{% if vars.type == 'hidden' %}{{ form_widget(form) }}` +
+		`{% else %}<div class="form-row{% if vars.errors %} has-error{% endif %}">` +
+		`{% if vars.type != 'submit' and vars.type != 'checkbox' %}{{ form_label(form) }}{% endif %}` +
+		`{{ form_widget(form) }}` +
+		`{% if vars.type == 'checkbox' %}{{ form_label(form) }}{% endif %}` +
+		`{{ form_errors(form) }}` +
+		`{% if vars.help %}<small class="form-help">{{ vars.help|e }}</small>{% endif %}` +
+		`</div>{% endif %}`,
+
+	"form_label": `{% if vars.label %}<label for="{{ vars.id|e }}"{% if vars.required %} class="required"{% endif %}>` +
+		`{{ vars.label|e }}</label>{% endif %}`,
+
+	"form_errors": `{% if vars.errors %}<ul class="form-errors">` +
+		`{% for error in vars.errors %}<li>{{ error|e }}</li>{% endfor %}</ul>{% endif %}`,
+
+	"compound_widget": `{% for child in vars.children %}{{ form_row(child) }}{% endfor %}`,
+
+	"input_widget": `<input type="{{ vars.type|e }}" id="{{ vars.id|e }}" name="{{ vars.full_name|e }}"` +
+		`{% if vars.value != '' %} value="{{ vars.value|e }}"{% endif %}{% if vars.required %} required{% endif %}` +
+		`{% for a in vars.attr %} {{ a.name|e }}="{{ a.value|e }}"{% endfor %}>`,
+
+	"textarea_widget": `<textarea id="{{ vars.id|e }}" name="{{ vars.full_name|e }}"{% if vars.required %} required{% endif %}` +
+		`{% for a in vars.attr %} {{ a.name|e }}="{{ a.value|e }}"{% endfor %}>{{ vars.value|e }}</textarea>`,
+
+	"choice_widget": `<select id="{{ vars.id|e }}" name="{{ vars.full_name|e }}"{% if vars.required %} required{% endif %}` +
+		`{% for a in vars.attr %} {{ a.name|e }}="{{ a.value|e }}"{% endfor %}>` +
+		`{% for choice in vars.choices %}<option value="{{ choice.value|e }}"{% if choice.selected %} selected{% endif %}>` +
+		`{{ choice.label|e }}</option>{% endfor %}</select>`,
+
+	"checkbox_widget": `<input type="checkbox" id="{{ vars.id|e }}" name="{{ vars.full_name|e }}" value="1"` +
+		`{% if vars.checked %} checked{% endif %}{% if vars.required %} required{% endif %}` +
+		`{% for a in vars.attr %} {{ a.name|e }}="{{ a.value|e }}"{% endfor %}>`,
+
+	"submit_widget": `<button type="submit" id="{{ vars.id|e }}" name="{{ vars.full_name|e }}"` +
+		`{% for a in vars.attr %} {{ a.name|e }}="{{ a.value|e }}"{% endfor %}>{{ vars.label|default('Submit')|e }}</button>`,
+}
@@ -0,0 +1,107 @@
+package twig
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strings"
+)
+
+// hashAlgorithms are the algorithms accepted by the hash function. MD5 and
+// SHA-1 are included for cache keys and gravatar URLs, not for security.
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha224": sha256.New224,
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// hashHex returns the hex digest of value using the named algorithm
+func hashHex(algorithm string, value interface{}) (string, error) {
+	newHash, ok := hashAlgorithms[strings.ToLower(algorithm)]
+	if !ok {
+		names := make([]string, 0, len(hashAlgorithms))
+		for name := range hashAlgorithms {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("unknown hash algorithm %q, expected one of %s", algorithm, strings.Join(names, ", "))
+	}
+
+	h := newHash()
+	if b, ok := value.([]byte); ok {
+		h.Write(b)
+	} else {
+		h.Write([]byte(toString(value)))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// functionHash implements hash(algorithm, value)
+func (e *CoreExtension) functionHash(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("hash function requires an algorithm and a value")
+	}
+	return hashHex(e.toString(args[0]), args[1])
+}
+
+// hashFilter creates a filter returning the hex digest of its value
+func hashFilter(algorithm string) FilterFunc {
+	return func(value interface{}, args ...interface{}) (interface{}, error) {
+		return hashHex(algorithm, value)
+	}
+}
+
+// base64Encoding returns the encoding selected by the filter arguments:
+// standard by default, or "url" for the URL-safe alphabet
+func base64Encoding(args []interface{}) (*base64.Encoding, error) {
+	if len(args) == 0 || args[0] == nil {
+		return base64.StdEncoding, nil
+	}
+
+	switch variant := toString(args[0]); variant {
+	case "", "std":
+		return base64.StdEncoding, nil
+	case "url":
+		return base64.URLEncoding, nil
+	default:
+		return nil, fmt.Errorf("unknown base64 variant %q", variant)
+	}
+}
+
+// filterBase64Encode implements value|base64_encode(variant)
+func (e *CoreExtension) filterBase64Encode(value interface{}, args ...interface{}) (interface{}, error) {
+	encoding, err := base64Encoding(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := value.([]byte); ok {
+		return encoding.EncodeToString(b), nil
+	}
+	return encoding.EncodeToString([]byte(e.toString(value))), nil
+}
+
+// filterBase64Decode implements value|base64_decode(variant). Missing
+// padding is accepted.
+func (e *CoreExtension) filterBase64Decode(value interface{}, args ...interface{}) (interface{}, error) {
+	encoding, err := base64Encoding(args)
+	if err != nil {
+		return nil, err
+	}
+
+	s := strings.TrimRight(strings.TrimSpace(e.toString(value)), "=")
+	data, err := encoding.WithPadding(base64.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("base64_decode: %w", err)
+	}
+	return string(data), nil
+}
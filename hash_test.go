@@ -0,0 +1,98 @@
+package twig
+
+import "testing"
+
+func TestHashAndBase64(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "hash sha256",
+			source:   `{{ hash('sha256', 'abc') }}`,
+			expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name:     "hash md5 is case insensitive",
+			source:   `{{ hash('MD5', 'abc') }}`,
+			expected: "900150983cd24fb0d6963f7d28e17f72",
+		},
+		{
+			name:     "gravatar url",
+			source:   `https://www.gravatar.com/avatar/{{ email|trim|lower|md5 }}`,
+			context:  map[string]interface{}{"email": " MyEmailAddress@example.com "},
+			expected: "https://www.gravatar.com/avatar/0bc83cb571cd1c50ba6f3e8a78ef1346",
+		},
+		{
+			name:     "sha1 filter",
+			source:   `{{ 'abc'|sha1 }}`,
+			expected: "a9993e364706816aba3e25717850c26c9cd0d89d",
+		},
+		{
+			name:     "bytes are hashed directly",
+			source:   `{{ data|sha256 }}`,
+			context:  map[string]interface{}{"data": []byte("abc")},
+			expected: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name:     "base64 round trip",
+			source:   `{{ 'héllo?'|base64_encode }} {{ 'héllo?'|base64_encode|base64_decode }}`,
+			expected: "aMOpbGxvPw== héllo?",
+		},
+		{
+			name:     "url-safe base64",
+			source:   `{{ '??>'|base64_encode('url') }} {{ 'Pz8-'|base64_decode('url') }}`,
+			expected: "Pz8- ??>",
+		},
+		{
+			name:     "decode without padding",
+			source:   `{{ 'aGk'|base64_decode }}`,
+			expected: "hi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"unknown algorithm", `{{ hash('crc32', 'abc') }}`},
+		{"missing value", `{{ hash('sha256') }}`},
+		{"invalid base64", `{{ '!!!'|base64_decode }}`},
+		{"unknown variant", `{{ 'abc'|base64_encode('raw') }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			if _, err := engine.Render("test", nil); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
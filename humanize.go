@@ -0,0 +1,170 @@
+package twig
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnitFormatter formats a number with its unit for the filesize and
+// duration filters, to localize decimal separators and unit names. unit is
+// one of "B", "kB", "MB", "GB", "TB", "PB", their binary forms "KiB" to
+// "PiB", or "d", "h", "m", "s" and "ms" for durations.
+type UnitFormatter func(value float64, decimals int, unit string) string
+
+// SetUnitFormatter sets how the filesize and duration filters format each
+// number and unit. nil restores the default English format, such as
+// "1.4 MB" and "2h 3m".
+func (e *Engine) SetUnitFormatter(formatter UnitFormatter) {
+	e.environment.unitFormatter = formatter
+}
+
+// durationUnits are the units of the duration filter, largest first
+var durationUnits = []struct {
+	name    string
+	seconds float64
+}{
+	{"d", 86400},
+	{"h", 3600},
+	{"m", 60},
+	{"s", 1},
+}
+
+// defaultUnitFormatter writes sizes as "1.4 MB" and durations as "3m"
+func defaultUnitFormatter(value float64, decimals int, unit string) string {
+	number := strconv.FormatFloat(value, 'f', decimals, 64)
+	switch unit {
+	case "d", "h", "m", "s", "ms":
+		return number + unit
+	}
+	return number + " " + unit
+}
+
+// formatUnit formats a value with the environment's unit formatter
+func (e *CoreExtension) formatUnit(value float64, decimals int, unit string) string {
+	if e.env != nil && e.env.unitFormatter != nil {
+		return e.env.unitFormatter(value, decimals, unit)
+	}
+	return defaultUnitFormatter(value, decimals, unit)
+}
+
+// filterFilesize implements size|filesize(decimals = 1, binary = false),
+// humanizing a number of bytes as "1.4 MB", or "1.3 MiB" in binary units
+func (e *CoreExtension) filterFilesize(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return "", nil
+	}
+	size, err := toFloat64(value)
+	if err != nil {
+		return nil, fmt.Errorf("filesize filter expects a number of bytes, got %T", value)
+	}
+
+	decimals := 1
+	if len(args) > 0 {
+		if decimals, err = toInt(args[0]); err != nil || decimals < 0 {
+			return nil, fmt.Errorf("filesize filter expects a non-negative number of decimals")
+		}
+	}
+	base, units := 1000.0, []string{"B", "kB", "MB", "GB", "TB", "PB"}
+	if len(args) > 1 && toBool(args[1]) {
+		base, units = 1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	}
+
+	// Bytes are whole, so they never have decimals
+	sign := 1.0
+	if size < 0 {
+		sign, size = -1, -size
+	}
+	if size < base {
+		return e.formatUnit(sign*math.Floor(size), 0, units[0]), nil
+	}
+	unit := 0
+	for size >= base && unit < len(units)-1 {
+		size /= base
+		unit++
+	}
+
+	// Rounding may carry into the next unit, as 999.96 kB does
+	scale := math.Pow(10, float64(decimals))
+	if math.Round(size*scale)/scale >= base && unit < len(units)-1 {
+		size /= base
+		unit++
+	}
+	return e.formatUnit(sign*size, decimals, units[unit]), nil
+}
+
+// filterDuration implements value|duration(parts = 2), humanizing a number
+// of seconds or a time.Duration as "2h 3m". parts is the most units shown;
+// the rest is rounded into the last one. Durations under a second are shown
+// in milliseconds.
+func (e *CoreExtension) filterDuration(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return "", nil
+	}
+
+	var seconds float64
+	switch v := value.(type) {
+	case time.Duration:
+		seconds = v.Seconds()
+	case *time.Duration:
+		if v == nil {
+			return "", nil
+		}
+		seconds = v.Seconds()
+	default:
+		var err error
+		if seconds, err = toFloat64(value); err != nil {
+			if d, perr := time.ParseDuration(e.toString(value)); perr == nil {
+				seconds = d.Seconds()
+			} else {
+				return nil, fmt.Errorf("duration filter expects seconds or a time.Duration, got %T", value)
+			}
+		}
+	}
+
+	parts := 2
+	if len(args) > 0 {
+		var err error
+		if parts, err = toInt(args[0]); err != nil || parts < 1 {
+			return nil, fmt.Errorf("duration filter expects a positive number of parts")
+		}
+	}
+
+	sign := ""
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	if seconds < 1 {
+		if ms := math.Round(seconds * 1000); ms > 0 {
+			return sign + e.formatUnit(ms, 0, "ms"), nil
+		}
+		return e.formatUnit(0, 0, "s"), nil
+	}
+
+	// Round to the smallest unit that will be shown, counted from the
+	// largest unit the duration reaches
+	first := len(durationUnits) - 1
+	for i, unit := range durationUnits {
+		if seconds >= unit.seconds {
+			first = i
+			break
+		}
+	}
+	last := first + parts - 1
+	if last >= len(durationUnits) {
+		last = len(durationUnits) - 1
+	}
+	remaining := math.Round(seconds/durationUnits[last].seconds) * durationUnits[last].seconds
+
+	var out []string
+	for _, unit := range durationUnits[:last+1] {
+		count := math.Floor(remaining / unit.seconds)
+		remaining -= count * unit.seconds
+		if count > 0 {
+			out = append(out, e.formatUnit(count, 0, unit.name))
+		}
+	}
+	return sign + strings.Join(out, " "), nil
+}
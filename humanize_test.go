@@ -0,0 +1,104 @@
+package twig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilesizeFilter(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"{{ 0|filesize }}", "0 B"},
+		{"{{ 512|filesize }}", "512 B"},
+		{"{{ 1400000|filesize }}", "1.4 MB"},
+		{"{{ 1536|filesize }}", "1.5 kB"},
+		{"{{ 1536|filesize(0, true) }}", "2 KiB"},
+		{"{{ 1468006|filesize(2, true) }}", "1.40 MiB"},
+		{"{{ 999960|filesize }}", "1.0 MB"},
+		{"{{ 3000000000000|filesize }}", "3.0 TB"},
+		{"{{ -2048|filesize }}", "-2.0 kB"},
+		{"{{ '2500'|filesize }}", "2.5 kB"},
+		{"[{{ none|filesize }}]", "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", map[string]interface{}{"none": nil})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDurationFilter(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		value    interface{}
+		expected string
+	}{
+		{"{{ value|duration }}", 7380, "2h 3m"},
+		{"{{ value|duration }}", 7410, "2h 4m"},
+		{"{{ value|duration }}", 90061, "1d 1h"},
+		{"{{ value|duration(4) }}", 90061, "1d 1h 1m 1s"},
+		{"{{ value|duration(1) }}", 5400, "2h"},
+		{"{{ value|duration }}", 3600, "1h"},
+		{"{{ value|duration }}", 59.6, "1m"},
+		{"{{ value|duration }}", 45, "45s"},
+		{"{{ value|duration }}", 0.25, "250ms"},
+		{"{{ value|duration }}", 0, "0s"},
+		{"{{ value|duration }}", -90, "-1m 30s"},
+		{"{{ value|duration }}", 2*time.Hour + 3*time.Minute, "2h 3m"},
+		{"{{ value|duration }}", "1h30m", "1h 30m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", map[string]interface{}{"value": tt.value})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnitFormatter(t *testing.T) {
+	engine := New()
+	names := map[string]string{"MB": "Mo", "h": " h", "m": " min"}
+	engine.SetUnitFormatter(func(value float64, decimals int, unit string) string {
+		number := strings.Replace(strconv.FormatFloat(value, 'f', decimals, 64), ".", ",", 1)
+		return number + names[unit]
+	})
+
+	if err := engine.RegisterString("page", "{{ 1400000|filesize }} / {{ 7380|duration }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if expected := "1,4Mo / 2 h 3 min"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,173 @@
+package twig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF for image_size
+	_ "image/jpeg" // Register JPEG for image_size
+	_ "image/png"  // Register PNG for image_size
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileResolver opens files referenced by templates, such as images measured
+// by the image_size function
+type FileResolver interface {
+	OpenFile(path string) (io.ReadCloser, error)
+}
+
+// FileResolverFunc adapts a function to the FileResolver interface
+type FileResolverFunc func(path string) (io.ReadCloser, error)
+
+// OpenFile calls f(path)
+func (f FileResolverFunc) OpenFile(path string) (io.ReadCloser, error) {
+	return f(path)
+}
+
+// DirFileResolver opens files below a directory. Paths listed in the
+// optional manifest are mapped to their built file first, so the same asset
+// names used with the asset function work here too.
+type DirFileResolver struct {
+	Root     string            // Directory containing the files
+	Manifest map[string]string // Source path to built file, as in ManifestResolver
+}
+
+// OpenFile opens path below the root
+func (r *DirFileResolver) OpenFile(filePath string) (io.ReadCloser, error) {
+	if built, ok := r.Manifest[strings.TrimLeft(filePath, "/")]; ok {
+		filePath = built
+	}
+
+	// Clean the path so templates cannot read files outside the root
+	cleaned := path.Clean("/" + filePath)
+	return os.Open(filepath.Join(r.Root, filepath.FromSlash(cleaned)))
+}
+
+// imageInfo describes an image for the image_size function
+type imageInfo struct {
+	width  int
+	height int
+	mime   string
+}
+
+// toMap returns the info as template-friendly values
+func (i imageInfo) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"width":  i.width,
+		"height": i.height,
+		"mime":   i.mime,
+	}
+}
+
+// maxImageHeader bounds how much of a file is read to find its size
+const maxImageHeader = 1 << 20
+
+// readImageInfo detects the format and dimensions of an image. GIF, JPEG
+// and PNG are decoded from their headers; SVG sizes come from the width and
+// height attributes or the viewBox of the root element.
+func readImageInfo(r io.Reader) (imageInfo, error) {
+	header, err := io.ReadAll(io.LimitReader(r, maxImageHeader))
+	if err != nil {
+		return imageInfo{}, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(header))
+	if err == nil {
+		return imageInfo{width: config.Width, height: config.Height, mime: "image/" + format}, nil
+	}
+
+	if info, ok := svgInfo(header); ok {
+		return info, nil
+	}
+	return imageInfo{}, fmt.Errorf("unsupported image format")
+}
+
+// svgInfo reads the size of an SVG document from its root element
+func svgInfo(data []byte) (imageInfo, bool) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return imageInfo{}, false
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "svg" {
+			return imageInfo{}, false
+		}
+
+		var width, height float64
+		var viewBox string
+		for _, attr := range start.Attr {
+			switch attr.Name.Local {
+			case "width":
+				width = svgLength(attr.Value)
+			case "height":
+				height = svgLength(attr.Value)
+			case "viewBox":
+				viewBox = attr.Value
+			}
+		}
+
+		// Fall back to the viewBox for missing or relative sizes
+		if width == 0 || height == 0 {
+			fields := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ' ' || r == ',' })
+			if len(fields) == 4 {
+				width, _ = strconv.ParseFloat(fields[2], 64)
+				height, _ = strconv.ParseFloat(fields[3], 64)
+			}
+		}
+
+		return imageInfo{width: int(width + 0.5), height: int(height + 0.5), mime: "image/svg+xml"}, true
+	}
+}
+
+// svgLength parses an absolute SVG length such as "120" or "120px",
+// returning 0 for percentages and other relative units
+func svgLength(value string) float64 {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "px")
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// functionImageSize implements image_size(path), returning a hash with
+// width, height and mime. Results are cached per path.
+func (e *CoreExtension) functionImageSize(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("image_size function requires a path argument")
+	}
+	if e.env == nil || e.env.fileResolver == nil {
+		return nil, fmt.Errorf("image_size: no file resolver configured")
+	}
+
+	imagePath := e.toString(args[0])
+	if info, ok := e.env.imageSizes.Load(imagePath); ok {
+		return info.(imageInfo).toMap(), nil
+	}
+
+	file, err := e.env.fileResolver.OpenFile(imagePath)
+	if err != nil {
+		return nil, fmt.Errorf("image_size: %w", err)
+	}
+	defer file.Close()
+
+	info, err := readImageInfo(file)
+	if err != nil {
+		return nil, fmt.Errorf("image_size: %s: %w", imagePath, err)
+	}
+
+	e.env.imageSizes.Store(imagePath, info)
+	return info.toMap(), nil
+}
@@ -0,0 +1,152 @@
+package twig
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImageSizeFunction(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 480)))
+	os.MkdirAll(filepath.Join(dir, "img"), 0o755)
+	os.WriteFile(filepath.Join(dir, "img", "hero.png"), buf.Bytes(), 0o644)
+	os.WriteFile(filepath.Join(dir, "img", "hero.5d41.png"), buf.Bytes(), 0o644)
+	os.WriteFile(filepath.Join(dir, "logo.svg"), []byte(`<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="120px" height="40"></svg>`), 0o644)
+	os.WriteFile(filepath.Join(dir, "icon.svg"), []byte(`<svg viewBox="0 0 24 24" width="100%"></svg>`), 0o644)
+
+	tests := []struct {
+		name     string
+		resolver FileResolver
+		source   string
+		expected string
+	}{
+		{
+			name:     "png attributes",
+			resolver: &DirFileResolver{Root: dir},
+			source:   `{% set size = image_size('img/hero.png') %}<img width="{{ size.width }}" height="{{ size.height }}">`,
+			expected: `<img width="640" height="480">`,
+		},
+		{
+			name:     "mime",
+			resolver: &DirFileResolver{Root: dir},
+			source:   `{% set size = image_size('/img/hero.png') %}{{ size.mime }}`,
+			expected: "image/png",
+		},
+		{
+			name:     "manifest asset",
+			resolver: &DirFileResolver{Root: dir, Manifest: map[string]string{"hero.png": "img/hero.5d41.png"}},
+			source:   `{% set size = image_size('hero.png') %}{{ size.width }}`,
+			expected: "640",
+		},
+		{
+			name:     "svg attributes",
+			resolver: &DirFileResolver{Root: dir},
+			source:   `{% set size = image_size('logo.svg') %}{{ size.width }}x{{ size.height }} {{ size.mime }}`,
+			expected: "120x40 image/svg+xml",
+		},
+		{
+			name:     "svg viewBox",
+			resolver: &DirFileResolver{Root: dir},
+			source:   `{% set size = image_size('icon.svg') %}{{ size.width }}x{{ size.height }}`,
+			expected: "24x24",
+		},
+		{
+			name: "function resolver",
+			resolver: FileResolverFunc(func(p string) (io.ReadCloser, error) {
+				return os.Open(filepath.Join(dir, "img", strings.TrimPrefix(p, "cdn:")))
+			}),
+			source:   `{% set size = image_size('cdn:hero.png') %}{{ size.height }}`,
+			expected: "480",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetFileResolver(tt.resolver)
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestImageSizeCache(t *testing.T) {
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 10, 20)))
+	os.WriteFile(filepath.Join(dir, "a.png"), buf.Bytes(), 0o644)
+
+	opened := 0
+	resolver := &DirFileResolver{Root: dir}
+	engine := New()
+	engine.SetFileResolver(FileResolverFunc(func(p string) (io.ReadCloser, error) {
+		opened++
+		return resolver.OpenFile(p)
+	}))
+	source := `{% set a = image_size('a.png') %}{% set b = image_size('a.png') %}{{ a.width }} {{ b.height }}`
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "10 20" {
+		t.Errorf("Expected %q, got %q", "10 20", result)
+	}
+	if opened != 1 {
+		t.Errorf("Expected the file to be opened once, got %d", opened)
+	}
+}
+
+func TestImageSizeErrors(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0o644)
+
+	tests := []struct {
+		name     string
+		resolver FileResolver
+		source   string
+	}{
+		{"no resolver", nil, `{{ image_size('a.png') }}`},
+		{"missing file", &DirFileResolver{Root: dir}, `{{ image_size('a.png') }}`},
+		{"not an image", &DirFileResolver{Root: dir}, `{{ image_size('notes.txt') }}`},
+		{"outside root", &DirFileResolver{Root: filepath.Join(dir, "sub")}, `{{ image_size('../notes.txt') }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if tt.resolver != nil {
+				engine.SetFileResolver(tt.resolver)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			if _, err := engine.Render("test", nil); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
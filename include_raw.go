@@ -0,0 +1,124 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// rawSource is a file loaded by include_raw
+type rawSource struct {
+	content  string
+	loader   Loader
+	path     string // Name the loader found the file under
+	modified int64  // Modification time reported by the loader, 0 if unknown
+}
+
+// rawSourceCache stores include_raw files by name
+type rawSourceCache struct {
+	entries sync.Map
+}
+
+// clear removes all cached files
+func (c *rawSourceCache) clear() {
+	c.entries.Clear()
+}
+
+// callIncludeRaw implements include_raw(name, ignore_missing), which outputs
+// a file found by the engine's loaders verbatim, without parsing it, for
+// SVG icons and other snippets that are not templates. Loaders implementing
+// RawLoader read the file under its exact name. Relative names are
+// resolved against the template being rendered. In a sandbox the function
+// must be allowed by the policy like any other.
+func (ctx *RenderContext) callIncludeRaw(args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("include_raw function requires a file name")
+	}
+	if ctx.engine == nil {
+		return nil, fmt.Errorf("no template engine available to load file: %v", args[0])
+	}
+	name := ctx.ToString(args[0])
+	ignoreMissing := len(args) > 1 && ctx.toBool(args[1])
+
+	resolved := name
+	if ctx.lastLoadedTemplate != nil {
+		resolved = resolveDependency(ctx.lastLoadedTemplate.name, name)
+	}
+	content, err := ctx.engine.loadRaw(resolved)
+	if err != nil && errors.Is(err, ErrTemplateNotFound) && resolved != name {
+		content, err = ctx.engine.loadRaw(name)
+	}
+	if err != nil {
+		if ignoreMissing && errors.Is(err, ErrTemplateNotFound) {
+			return "", nil
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// loadRaw returns the source of a file from the engine's loaders without
+// parsing it. Files are cached like templates, and reloaded when they
+// change if auto-reload is on.
+func (e *Engine) loadRaw(name string) (string, error) {
+	if e.environment.cache {
+		if cached, ok := e.raw.entries.Load(name); ok {
+			entry := cached.(rawSource)
+			if !e.autoReload || !rawSourceChanged(entry) {
+				return entry.content, nil
+			}
+		}
+	}
+
+	for _, candidate := range e.themeCandidates(name) {
+		for _, loader := range e.loaders {
+			var content string
+			var err error
+			if rawLoader, ok := loader.(RawLoader); ok {
+				content, err = rawLoader.LoadRaw(candidate)
+			} else {
+				content, err = loader.Load(candidate)
+			}
+			if err != nil {
+				continue
+			}
+			if err := e.checkTemplateSize(name, len(content)); err != nil {
+				return "", err
+			}
+
+			entry := rawSource{content: content, loader: loader, path: candidate}
+			if tsLoader, ok := loader.(TimestampAwareLoader); ok {
+				entry.modified, _ = tsLoader.GetModifiedTime(candidate)
+			}
+			if e.environment.cache {
+				e.raw.entries.Store(name, entry)
+			}
+			return content, nil
+		}
+	}
+
+	if e.parent != nil {
+		return e.parent.loadRaw(name)
+	}
+
+	// Templates registered directly have no loader to read them again
+	e.mu.RLock()
+	template, ok := e.templates[name]
+	e.mu.RUnlock()
+	if ok && template.loader == nil {
+		return template.source, nil
+	}
+
+	return "", fmt.Errorf("%w: '%s'", ErrTemplateNotFound, name)
+}
+
+// rawSourceChanged reports whether the loader has a newer version of a
+// cached file
+func rawSourceChanged(entry rawSource) bool {
+	tsLoader, ok := entry.loader.(TimestampAwareLoader)
+	if !ok {
+		return false
+	}
+	modified, err := tsLoader.GetModifiedTime(entry.path)
+	return err != nil || modified > entry.modified
+}
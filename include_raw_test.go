@@ -0,0 +1,138 @@
+package twig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIncludeRaw(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"icons/star.svg":    "<svg>{{ not parsed }}{% if %}</svg>\n",
+		"icons/page.twig":   "{{ include_raw('./star.svg') }}",
+		"pages/embed.twig":  "[{{ include_raw('icons/star.svg')|trim }}]",
+		"pages/absent.twig": "[{{ include_raw('icons/missing.svg', true) }}]",
+		"pages/error.twig":  "{{ include_raw('icons/missing.svg') }}",
+	}))
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"pages/embed.twig", "[<svg>{{ not parsed }}{% if %}</svg>]"},
+		{"icons/page.twig", "<svg>{{ not parsed }}{% if %}</svg>\n"},
+		{"pages/absent.twig", "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.Render(tt.name, nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if _, err := engine.Render("pages/error.twig", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestIncludeRawSandbox(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{"star.svg": "<svg/>"}))
+	policy := NewDefaultSecurityPolicy()
+	engine.EnableSandbox(policy)
+
+	templates := map[string]string{
+		"main":    "{% include 'partial' sandboxed %}",
+		"partial": "{{ include_raw('star.svg') }}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	if _, err := engine.Render("main", nil); err == nil || !strings.Contains(err.Error(), "include_raw") {
+		t.Errorf("Expected a sandbox violation, got %v", err)
+	}
+
+	policy.AllowedFunctions["include_raw"] = true
+	result, err := engine.Render("main", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "<svg/>" {
+		t.Errorf("Expected %q, got %q", "<svg/>", result)
+	}
+}
+
+func TestIncludeRawReload(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logo.svg")
+	if err := os.WriteFile(path, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.RegisterLoader(NewFileSystemLoader([]string{dir}))
+	if err := engine.RegisterString("page", "{{ include_raw('logo.svg') }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	render := func() string {
+		result, err := engine.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		return result
+	}
+	if result := render(); result != "v1" {
+		t.Fatalf("Expected %q, got %q", "v1", result)
+	}
+
+	// Cached until the file changes and auto-reload is on
+	if err := os.WriteFile(path, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path, future, future)
+	if result := render(); result != "v1" {
+		t.Errorf("Expected the cached file, got %q", result)
+	}
+	engine.SetAutoReload(true)
+	if result := render(); result != "v2" {
+		t.Errorf("Expected the reloaded file, got %q", result)
+	}
+}
+
+func TestIncludeRawOutsideLoaderPaths(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "tpl")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(dir, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.RegisterLoader(NewFileSystemLoader([]string{root}))
+	for _, name := range []string{"../secret.txt", "a/../../secret.txt", secret} {
+		if err := engine.RegisterString("page", "{{ include_raw('"+name+"') }}"); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+		result, err := engine.Render("page", nil)
+		if !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected %s not to be found, got %q (%v)", name, result, err)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// includeScope describes the variables a template rendered from another one
+// sees. The include, embed and with tags share it.
+type includeScope struct {
+	variables map[string]Node // Values passed with with {...}
+	values    Node            // Expression giving a hash of values, for the with tag
+	only      bool            // Hide the variables of the current context
+	sandboxed bool            // Render under the security policy
+	line      int
+}
+
+// scopedContext creates the context for an included template or a with body.
+// Values passed in the scope are evaluated in the current context and set in
+// the new one, so they never leak back. The caller releases the context.
+func (ctx *RenderContext) scopedContext(scope includeScope) (*RenderContext, error) {
+	if scope.sandboxed && ctx.env.securityPolicy == nil {
+		return nil, fmt.Errorf("cannot use sandboxed include without a security policy")
+	}
+
+	var scoped *RenderContext
+	if !scope.only && !scope.sandboxed {
+		// Clone the current context, whose variables are read through the
+		// parent link
+		scoped = ctx.Clone()
+	} else {
+		var contextVars map[string]interface{}
+
+		if scope.only {
+			// Only mode - create empty context
+			contextVars = make(map[string]interface{}, len(scope.variables))
+		} else {
+			// For sandboxed mode but not 'only' mode, copy the parent context
+			vars := ctx.variables()
+			contextVars = make(map[string]interface{}, len(vars)+len(scope.variables))
+			for k, v := range vars {
+				contextVars[k] = v
+			}
+		}
+
+		scoped = NewRenderContext(ctx.env, contextVars, ctx.engine)
+		scoped.lastLoadedTemplate = ctx.lastLoadedTemplate
+		scoped.templateStack = ctx.templateStack
+		scoped.profile = ctx.profile
+		scoped.options = ctx.options
+		scoped.visits = ctx.visits
+		scoped.labels = ctx.labels
+		scoped.features = ctx.features
+		scoped.sandboxed = scope.sandboxed || ctx.sandboxed
+	}
+
+	// Pre-evaluate all variables before setting them
+	for name, valueNode := range scope.variables {
+		value, err := ctx.EvaluateExpression(valueNode)
+		if err != nil {
+			scoped.Release()
+			return nil, ctx.wrapError(err, scope.line)
+		}
+		scoped.SetVariable(name, value)
+	}
+
+	if scope.values != nil {
+		values, err := ctx.EvaluateExpression(scope.values)
+		if err == nil {
+			err = setHashVariables(scoped, values)
+		}
+		if err != nil {
+			scoped.Release()
+			return nil, ctx.wrapError(err, scope.line)
+		}
+	}
+
+	return scoped, nil
+}
+
+// setHashVariables sets every key of a hash as a variable
+func setHashVariables(ctx *RenderContext, values interface{}) error {
+	switch v := values.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for name, value := range v {
+			ctx.SetVariable(name, value)
+		}
+		return nil
+	}
+
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("variables must be a hash, got %T", values)
+	}
+	iter := rv.MapRange()
+	for iter.Next() {
+		ctx.SetVariable(iter.Key().String(), iter.Value().Interface())
+	}
+	return nil
+}
+
+// loadIncluded loads the template named by an include or embed tag. It
+// returns a nil template without an error when the template is missing and
+// ignoreMissing is set.
+func (ctx *RenderContext) loadIncluded(templateNode Node, ignoreMissing bool, line int) (*Template, error) {
+	// Get the template name
+	templateExpr, err := ctx.EvaluateExpression(templateNode)
+	if err != nil {
+		return nil, err
+	}
+
+	templateName := ctx.ToString(templateExpr)
+
+	// Load the template
+	if ctx.engine == nil {
+		return nil, fmt.Errorf("no template engine available to load included template: %s", templateName)
+	}
+
+	// Handle relative paths for templates
+	resolvedName := templateName
+	if strings.HasPrefix(templateName, "./") || strings.HasPrefix(templateName, "../") {
+		// Get the directory of the current template
+		currentTemplate := ctx.engine.currentTemplate
+		if currentTemplate != "" {
+			// Extract the directory part of the current template
+			currentDir := filepath.Dir(currentTemplate)
+			// Join the directory with the relative path
+			resolvedName = filepath.Join(currentDir, templateName)
+		}
+	}
+
+	// Load the template with resolved path
+	template, err := ctx.engine.loadResolved(resolvedName, ctx.variables())
+	if err != nil {
+		// Only try the fallback if the template was not found AND the paths are different
+		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
+			template, err = ctx.engine.loadResolved(templateName, ctx.variables())
+		}
+		if err != nil {
+			if ignoreMissing && errors.Is(err, ErrTemplateNotFound) {
+				return nil, nil
+			}
+			// Missing templates can be replaced by the fallback template;
+			// any other error (including syntax errors) is returned
+			if template, err = ctx.engine.loadFallback(err); err != nil {
+				return nil, ctx.wrapError(err, line)
+			}
+		}
+	}
+
+	return template, nil
+}
@@ -0,0 +1,161 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// inheritanceEntry holds the resolved parent of a template with a static extends
+type inheritanceEntry struct {
+	child        *Template         // Template containing the extends tag
+	parent       *Template         // Template it extends
+	parentBlocks map[string][]Node // Blocks defined at the top level of the parent
+}
+
+// inheritanceCache stores resolved parents per child template name so that
+// extends does not reload the parent and rescan its blocks on every render.
+// Every level of a multi-level chain has its own entry, so a change anywhere
+// in the chain only invalidates the level that points at the changed template.
+type inheritanceCache struct {
+	mu      sync.RWMutex
+	entries map[string]inheritanceEntry
+}
+
+// get returns the cached entry for child
+func (c *inheritanceCache) get(child *Template) (inheritanceEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[child.name]
+	if !ok || entry.child != child {
+		return inheritanceEntry{}, false
+	}
+	return entry, true
+}
+
+// set stores the resolved parent for a child template
+func (c *inheritanceCache) set(entry inheritanceEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]inheritanceEntry)
+	}
+	c.entries[entry.child.name] = entry
+}
+
+// clear removes all cached entries
+func (c *inheritanceCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// isCurrent reports whether template is still the version the engine would load
+func (e *Engine) isCurrent(template *Template) bool {
+	if !e.environment.cache {
+		return false
+	}
+
+	// With auto-reload, Load checks modification times and swaps in a new
+	// template when the source changed
+	if e.autoReload {
+		current, err := e.Load(template.name)
+		return err == nil && current == template
+	}
+
+	e.mu.RLock()
+	current := e.templates[template.name]
+	e.mu.RUnlock()
+	return current == template
+}
+
+// topLevelBlocks returns the blocks defined at the top level of a template
+func topLevelBlocks(template *Template) map[string][]Node {
+	blocks := make(map[string][]Node)
+	if rootNode, ok := template.nodes.(*RootNode); ok {
+		for _, child := range rootNode.Children() {
+			if block, ok := child.(*BlockNode); ok {
+				if _, exists := blocks[block.name]; !exists {
+					blocks[block.name] = block.body
+				}
+			}
+		}
+	}
+	return blocks
+}
+
+// resolveParent loads the parent template and its top-level blocks, reusing
+// the engine's inheritance cache when the parent name is a static literal
+func (n *ExtendsNode) resolveParent(ctx *RenderContext) (*Template, map[string][]Node, error) {
+	// Get the parent template name
+	templateExpr, err := ctx.EvaluateExpression(n.parent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	templateName := ctx.ToString(templateExpr)
+
+	// Load the parent template
+	if ctx.engine == nil {
+		return nil, nil, fmt.Errorf("no template engine available to load parent template: %s", templateName)
+	}
+
+	// Relative names depend on the template being rendered, so only
+	// static absolute names can be cached against the child template
+	relative := strings.HasPrefix(templateName, "./") || strings.HasPrefix(templateName, "../")
+	_, static := n.parent.(*LiteralNode)
+	child := ctx.lastLoadedTemplate
+	cacheable := static && !relative && child != nil && child.name != "" && ctx.engine.isCurrent(child)
+
+	if cacheable {
+		if entry, ok := ctx.engine.inheritance.get(child); ok && ctx.engine.isCurrent(entry.parent) {
+			return entry.parent, entry.parentBlocks, nil
+		}
+	}
+
+	// Handle relative paths for templates
+	resolvedName := templateName
+	if relative {
+		// Get the directory of the current template
+		currentTemplate := ctx.engine.currentTemplate
+		if currentTemplate != "" {
+			// Extract the directory part of the current template
+			currentDir := filepath.Dir(currentTemplate)
+			// Join the directory with the relative path
+			resolvedName = filepath.Join(currentDir, templateName)
+		}
+	}
+
+	// Load the parent template with resolved path
+	parentTemplate, err := ctx.engine.Load(resolvedName)
+	if err != nil {
+		// Only try the fallback if the template was not found AND the paths are different
+		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
+			parentTemplate, err = ctx.engine.Load(templateName)
+		}
+		if err != nil {
+			// A missing parent can be replaced by the fallback template, which
+			// is not cached so the real parent is used once it exists
+			if parentTemplate, err = ctx.engine.loadFallback(err); err != nil {
+				return nil, nil, err
+			}
+			cacheable = false
+		}
+	}
+
+	parentBlocks := topLevelBlocks(parentTemplate)
+
+	if cacheable {
+		ctx.engine.inheritance.set(inheritanceEntry{
+			child:        child,
+			parent:       parentTemplate,
+			parentBlocks: parentBlocks,
+		})
+	}
+
+	return parentTemplate, parentBlocks, nil
+}
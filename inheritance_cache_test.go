@@ -0,0 +1,90 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestInheritanceCache(t *testing.T) {
+	t.Run("Parent resolved once and reused", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("layout", "<main>{% block content %}default{% endblock %}</main>")
+		engine.RegisterString("page", "{% extends 'layout' %}{% block content %}page{% endblock %}")
+
+		for i := 0; i < 3; i++ {
+			result, err := engine.Render("page", nil)
+			if err != nil {
+				t.Fatalf("Render %d failed: %v", i, err)
+			}
+			if result != "<main>page</main>" {
+				t.Errorf("Render %d: expected %q, got %q", i, "<main>page</main>", result)
+			}
+		}
+
+		page, _ := engine.Load("page")
+		layout, _ := engine.Load("layout")
+		entry, ok := engine.inheritance.get(page)
+		if !ok {
+			t.Fatal("Expected resolved parent to be cached for 'page'")
+		}
+		if entry.parent != layout {
+			t.Error("Expected cached parent to be the loaded 'layout' template")
+		}
+	})
+
+	t.Run("Change anywhere in the chain invalidates", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("base", "[{% block content %}{% endblock %}]")
+		engine.RegisterString("layout", "{% extends 'base' %}{% block content %}<{% block inner %}{% endblock %}>{% endblock %}")
+		engine.RegisterString("page", "{% extends 'layout' %}{% block inner %}page{% endblock %}")
+
+		result, err := engine.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Render failed: %v", err)
+		}
+		if result != "[<page>]" {
+			t.Errorf("Expected %q, got %q", "[<page>]", result)
+		}
+
+		// Replace the root of the chain
+		engine.RegisterString("base", "({% block content %}{% endblock %})")
+		result, err = engine.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Render after base change failed: %v", err)
+		}
+		if result != "(<page>)" {
+			t.Errorf("Expected %q, got %q", "(<page>)", result)
+		}
+
+		// Replace the middle of the chain
+		engine.RegisterString("layout", "{% extends 'base' %}{% block content %}{% block inner %}{% endblock %}!{% endblock %}")
+		result, err = engine.Render("page", nil)
+		if err != nil {
+			t.Fatalf("Render after layout change failed: %v", err)
+		}
+		if result != "(page!)" {
+			t.Errorf("Expected %q, got %q", "(page!)", result)
+		}
+	})
+
+	t.Run("Dynamic parent names are not cached", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("a", "a:{% block content %}{% endblock %}")
+		engine.RegisterString("b", "b:{% block content %}{% endblock %}")
+		engine.RegisterString("page", "{% extends layout %}{% block content %}page{% endblock %}")
+
+		for _, layout := range []string{"a", "b"} {
+			result, err := engine.Render("page", map[string]interface{}{"layout": layout})
+			if err != nil {
+				t.Fatalf("Render failed: %v", err)
+			}
+			if expected := layout + ":page"; result != expected {
+				t.Errorf("Expected %q, got %q", expected, result)
+			}
+		}
+
+		page, _ := engine.Load("page")
+		if _, ok := engine.inheritance.get(page); ok {
+			t.Error("Expected no cached parent for a dynamic extends")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package twig
+
+import (
+	"strings"
+	"sync"
+)
+
+// maxInternedStrings bounds the number of identifiers an engine interns, so
+// templates generated from untrusted input cannot grow the table forever
+const maxInternedStrings = 1 << 16
+
+// stringInterner deduplicates identifier strings across the templates parsed
+// by an engine. The first occurrence of a string is copied, so interned names
+// do not keep the source of the template they were first seen in alive.
+type stringInterner struct {
+	mu      sync.RWMutex
+	strings map[string]string
+}
+
+// intern returns the canonical copy of s
+func (in *stringInterner) intern(s string) string {
+	if len(s) > maxCacheableLength {
+		return s
+	}
+
+	in.mu.RLock()
+	cached, ok := in.strings[s]
+	in.mu.RUnlock()
+	if ok {
+		return cached
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	// Check again, another parse may have added it
+	if cached, ok := in.strings[s]; ok {
+		return cached
+	}
+	if len(in.strings) >= maxInternedStrings {
+		return s
+	}
+	if in.strings == nil {
+		in.strings = make(map[string]string)
+	}
+
+	cached = strings.Clone(s)
+	in.strings[cached] = cached
+	return cached
+}
+
+// len returns the number of interned strings
+func (in *stringInterner) len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.strings)
+}
+
+// newParser returns a parser that interns identifiers in the engine's table
+func (e *Engine) newParser() *Parser {
+	return &Parser{interner: &e.interner, keepComments: e.retainComments, streamingSize: e.streamingParse}
+}
@@ -0,0 +1,80 @@
+package twig
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// variableNames collects the names of variables referenced by a template
+type variableNames []string
+
+func (v *variableNames) Enter(node Node) bool {
+	if n, ok := node.(*VariableNode); ok {
+		*v = append(*v, n.name)
+	}
+	return true
+}
+
+func (v *variableNames) Leave(node Node) {}
+
+func TestEngineInternsIdentifiers(t *testing.T) {
+	engine := New()
+	sources := map[string]string{
+		"a.twig": `{{ customer_name|upper }}`,
+		"b.twig": `{% if customer_name %}{{ customer_name }}{% endif %}`,
+	}
+
+	var names variableNames
+	for _, name := range []string{"a.twig", "b.twig"} {
+		if err := engine.RegisterString(name, sources[name]); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+		template, err := engine.Load(name)
+		if err != nil {
+			t.Fatalf("Error loading %s: %v", name, err)
+		}
+		Walk(template.nodes, &names)
+	}
+
+	if len(names) != 3 {
+		t.Fatalf("Expected 3 variable references, got %v", names)
+	}
+	for _, name := range names[1:] {
+		if unsafe.StringData(name) != unsafe.StringData(names[0]) {
+			t.Errorf("Expected %q to share the interned string", name)
+		}
+	}
+
+	// The interned copy must not point into either template source
+	data := uintptr(unsafe.Pointer(unsafe.StringData(names[0])))
+	for name, source := range sources {
+		start := uintptr(unsafe.Pointer(unsafe.StringData(source)))
+		if data >= start && data < start+uintptr(len(source)) {
+			t.Errorf("Expected the interned name not to reference the source of %s", name)
+		}
+	}
+
+	// Rendering is unaffected
+	result, err := engine.Render("b.twig", map[string]interface{}{"customer_name": "Ada"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Ada" {
+		t.Errorf("Expected %q, got %q", "Ada", result)
+	}
+}
+
+func TestStringInternerLimits(t *testing.T) {
+	var interner stringInterner
+
+	long := string(make([]byte, maxCacheableLength+1))
+	if interner.intern(long); interner.len() != 0 {
+		t.Error("Expected long strings not to be interned")
+	}
+
+	a := interner.intern(string([]byte("title")))
+	b := interner.intern(string([]byte("title")))
+	if unsafe.StringData(a) != unsafe.StringData(b) || interner.len() != 1 {
+		t.Error("Expected equal strings to be interned once")
+	}
+}
@@ -0,0 +1,56 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestNegativeItemIndex(t *testing.T) {
+	engine := New()
+
+	context := map[string]interface{}{
+		"items":  []interface{}{"a", "b", "c"},
+		"tags":   []string{"x", "y"},
+		"counts": []int{1, 2, 3},
+		"points": []float64{1.5, 2.5},
+		"list":   &testList{items: []string{"first", "last"}},
+		"name":   "héllo",
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"last item", "{{ items[-1] }}", "c"},
+		{"from the end", "{{ items[-3] }}{{ items[-2] }}", "ab"},
+		{"typed slices", "{{ tags[-1] }}{{ counts[-2] }}{{ points[-1] }}", "y22.5"},
+		{"literal array", "{{ [1, 2, 3][-1] }}", "3"},
+		{"custom collection", "{{ list[-1] }}", "last"},
+		{"string", "{{ name[0] }}{{ name[1] }}{{ name[-1] }}", "héo"},
+		{"expression index", "{{ items[items|length - 4] }}", "c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	for _, source := range []string{"{{ items[-4] }}", "{{ tags[-3] }}", "{{ name[-6] }}", "{{ list[-3] }}"} {
+		if err := engine.RegisterString("page", source); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+		if _, err := engine.Render("page", context); err == nil {
+			t.Errorf("Expected an out of bounds error for %q", source)
+		}
+	}
+}
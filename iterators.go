@@ -0,0 +1,116 @@
+package twig
+
+import (
+	"io"
+	"reflect"
+)
+
+// iterateFunc returns the iteration of a Go function iterator, an iter.Seq
+// or iter.Seq2 of any element types. Sequences of single values are keyed
+// by their position. It reports false for other values.
+func iterateFunc(seq interface{}) (func(yield func(key, value interface{}) bool), bool) {
+	rv := reflect.ValueOf(seq)
+	if rv.Kind() != reflect.Func {
+		return nil, false
+	}
+
+	switch {
+	case rv.Type().CanSeq2():
+		if rv.IsNil() {
+			return func(func(key, value interface{}) bool) {}, true
+		}
+		return func(yield func(key, value interface{}) bool) {
+			for k, v := range rv.Seq2() {
+				if !yield(reflectInterface(k), reflectInterface(v)) {
+					return
+				}
+			}
+		}, true
+	case rv.Type().CanSeq():
+		if rv.IsNil() {
+			return func(func(key, value interface{}) bool) {}, true
+		}
+		return func(yield func(key, value interface{}) bool) {
+			i := 0
+			for v := range rv.Seq() {
+				if !yield(i, reflectInterface(v)) {
+					return
+				}
+				i++
+			}
+		}, true
+	}
+	return nil, false
+}
+
+// reflectInterface returns the value held by v, nil when it is unexported
+func reflectInterface(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// renderIterator runs a for loop over a Go function iterator, pulling one
+// item at a time so streaming producers are never collected into a slice.
+// The iterator's length is unknown, so loop.length, loop.revindex and
+// loop.revindex0 are not set; loop.last is found by reading one item
+// ahead. It reports false when seq is not an iterator.
+func (n *ForNode) renderIterator(w io.Writer, ctx *RenderContext, seq interface{}) (bool, error) {
+	each, ok := iterateFunc(seq)
+	if !ok {
+		return false, nil
+	}
+
+	loopCtx := ctx.newLoopScope()
+	loop := make(map[string]interface{})
+	i := 0
+	renderItem := func(key, value interface{}, last bool) error {
+		loop["index"] = i + 1
+		loop["index0"] = i
+		loop["first"] = i == 0
+		loop["last"] = last
+		i++
+
+		n.setValue(loopCtx, value)
+		if n.keyVar != "" {
+			loopCtx.setLocal(n.keyVar, key)
+		}
+		loopCtx.setLocal("loop", loop)
+
+		for _, node := range n.body {
+			if err := loopCtx.render(w, node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Each item is rendered when the next one arrives, or when the
+	// iterator ends and it is known to be the last
+	var pendingKey, pendingValue interface{}
+	pending := false
+	var err error
+	each(func(key, value interface{}) bool {
+		if pending {
+			if err = renderItem(pendingKey, pendingValue, false); err != nil {
+				return false
+			}
+		}
+		pendingKey, pendingValue, pending = key, value, true
+		return true
+	})
+	if err != nil {
+		return true, err
+	}
+
+	if !pending {
+		for _, node := range n.elseBranch {
+			if err := ctx.render(w, node); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
+	return true, renderItem(pendingKey, pendingValue, true)
+}
@@ -0,0 +1,90 @@
+package twig
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestForLoopIterators(t *testing.T) {
+	engine := New()
+
+	// Count how many items the producer was asked for
+	produced := 0
+	numbers := func(yield func(int) bool) {
+		for i := 1; i <= 100; i++ {
+			produced++
+			if !yield(i * 10) {
+				return
+			}
+		}
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			"seq",
+			"{% for n in items %}{{ loop.index }}:{{ n }}{{ loop.last ? '' : ',' }}{% endfor %}",
+			map[string]interface{}{"items": slices.Values([]string{"a", "b", "c"})},
+			"1:a,2:b,3:c",
+		},
+		{
+			"seq keys are positions",
+			"{% for i, n in items %}{{ i }}={{ n }} {% endfor %}",
+			map[string]interface{}{"items": iter.Seq[string](slices.Values([]string{"x", "y"}))},
+			"0=x 1=y ",
+		},
+		{
+			"seq2",
+			"{% for k, v in items %}{{ k }}={{ v }};{% endfor %}",
+			map[string]interface{}{"items": maps.All(map[string]int{"one": 1})},
+			"one=1;",
+		},
+		{
+			"empty",
+			"{% for n in items %}{{ n }}{% else %}none{% endfor %}",
+			map[string]interface{}{"items": slices.Values([]int(nil))},
+			"none",
+		},
+		{
+			"first",
+			"{% for n in items %}{% if loop.first %}[{% endif %}{{ n }}{% endfor %}",
+			map[string]interface{}{"items": slices.Values([]int{1, 2})},
+			"[12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := engine.ParseTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := template.Render(tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// A failing body stops the producer
+	engine.SetMaxNodeVisits(20)
+	template, err := engine.ParseTemplate("{% for n in numbers %}{{ n }}{% endfor %}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := template.Render(map[string]interface{}{"numbers": numbers}); err == nil {
+		t.Fatal("Expected the visit limit to stop the loop")
+	}
+	if produced >= 100 {
+		t.Errorf("Expected the producer to stop early, produced %d items", produced)
+	}
+}
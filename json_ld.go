@@ -0,0 +1,44 @@
+package twig
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// schemaOrgContext is the @context given to JSON-LD maps that have none
+const schemaOrgContext = "https://schema.org"
+
+// functionJsonLD implements json_ld(data, pretty), returning a
+// <script type="application/ld+json"> block for the data. Maps without an
+// @context get schema.org. The JSON escapes <, > and & so the data cannot
+// close the script element, and the block is meant to be printed as is.
+func (e *CoreExtension) functionJsonLD(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("json_ld function requires a data argument")
+	}
+
+	data := args[0]
+	if m, ok := data.(map[string]interface{}); ok {
+		if _, ok := m["@context"]; !ok {
+			withContext := make(map[string]interface{}, len(m)+1)
+			for k, v := range m {
+				withContext[k] = v
+			}
+			withContext["@context"] = schemaOrgContext
+			data = withContext
+		}
+	}
+
+	var encoded []byte
+	var err error
+	if len(args) > 1 && toBool(args[1]) {
+		encoded, err = json.MarshalIndent(data, "", "  ")
+	} else {
+		encoded, err = json.Marshal(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("json_ld: %w", err)
+	}
+
+	return `<script type="application/ld+json">` + string(encoded) + `</script>`, nil
+}
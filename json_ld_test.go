@@ -0,0 +1,81 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJsonLDFunction(t *testing.T) {
+	engine := New()
+
+	type product struct {
+		Context string `json:"@context"`
+		Type    string `json:"@type"`
+		Name    string `json:"name"`
+	}
+
+	context := map[string]interface{}{
+		"org": map[string]interface{}{
+			"@type": "Organization",
+			"name":  "Acme </script><script>alert(1)</script>",
+		},
+		"event": map[string]interface{}{
+			"@context": "https://example.org",
+			"name":     "Launch",
+		},
+		"product": product{Context: "https://schema.org", Type: "Product", Name: "Anvil & Co"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			"escapes markup and adds context",
+			"{{ json_ld(org) }}",
+			`<script type="application/ld+json">{"@context":"https://schema.org","@type":"Organization","name":"Acme \u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script>`,
+		},
+		{
+			"keeps context",
+			"{{ json_ld(event) }}",
+			`<script type="application/ld+json">{"@context":"https://example.org","name":"Launch"}</script>`,
+		},
+		{
+			"struct",
+			"{{ json_ld(product) }}",
+			`<script type="application/ld+json">{"@context":"https://schema.org","@type":"Product","name":"Anvil \u0026 Co"}</script>`,
+		},
+		{
+			"pretty",
+			"{{ json_ld(event, true) }}",
+			"<script type=\"application/ld+json\">{\n  \"@context\": \"https://example.org\",\n  \"name\": \"Launch\"\n}</script>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if _, ok := context["org"].(map[string]interface{})["@context"]; ok {
+		t.Error("Expected json_ld not to modify the context map")
+	}
+
+	if err := engine.RegisterString("page", "{{ json_ld() }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("page", nil); err == nil || !strings.Contains(err.Error(), "requires a data argument") {
+		t.Errorf("Expected a missing argument error, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package loader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChainLoader(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "page.twig"), []byte("from disk"), 0644); err != nil {
+		t.Fatalf("Error writing template: %v", err)
+	}
+
+	chain := NewChainLoader([]Loader{
+		NewArrayLoader(map[string]string{"page": "from memory"}),
+	})
+	chain.AddLoader(NewFileSystemLoader([]string{dir}))
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"page", "from memory"},
+		{"page.twig", "from disk"},
+	}
+	for _, tt := range tests {
+		source, err := chain.Load(tt.name)
+		if err != nil {
+			t.Fatalf("Error loading %s: %v", tt.name, err)
+		}
+		if source != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, source)
+		}
+	}
+
+	if chain.Exists("missing") {
+		t.Error("Expected missing not to exist")
+	}
+	if _, err := chain.Load("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestFileSystemLoaderLoadRaw(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"logo.svg": "raw", "logo.svg.twig": "template"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Raw files and templates of the same name are remembered apart
+	loader := NewFileSystemLoader([]string{dir})
+	for _, load := range []func(string) (string, error){loader.LoadRaw, loader.Load, loader.LoadRaw} {
+		if _, err := load("logo.svg"); err != nil {
+			t.Fatalf("Error loading file: %v", err)
+		}
+	}
+	if source, err := loader.Load("logo.svg"); err != nil || source != "template" {
+		t.Errorf("Expected the template, got %q (%v)", source, err)
+	}
+	if source, err := loader.LoadRaw("logo.svg"); err != nil || source != "raw" {
+		t.Errorf("Expected the raw file, got %q (%v)", source, err)
+	}
+
+	if _, err := loader.LoadRaw("../" + filepath.Base(dir) + "/logo.svg"); err != nil {
+		t.Errorf("Expected a path back inside the loader to be found, got %v", err)
+	}
+	for _, name := range []string{"../logo.svg", filepath.Join(dir, "logo.svg")} {
+		if _, err := loader.LoadRaw(name); !errors.Is(err, ErrTemplateNotFound) {
+			t.Errorf("Expected %s not to be found, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package twig
+
+// LoopScopeMode controls how {% set %} inside a for loop affects variables
+// outside of it
+type LoopScopeMode int
+
+const (
+	// LoopScopeTwig follows Twig: setting a variable that exists outside the
+	// loop updates it, new variables are dropped when the loop ends (default)
+	LoopScopeTwig LoopScopeMode = iota
+	// LoopScopeIsolated keeps every variable set inside a loop local to it
+	LoopScopeIsolated
+	// LoopScopeShared renders loops in the enclosing context as older
+	// versions did, so loop variables and new variables remain after the loop
+	LoopScopeShared
+)
+
+// SetLoopScopeMode sets how variables set inside for loops are scoped
+func (e *Engine) SetLoopScopeMode(mode LoopScopeMode) {
+	e.environment.loopScopeMode = mode
+}
+
+// By default loops follow Twig's scoping rules. Each for loop renders its
+// body in a child scope linked to the enclosing context:
+//
+//   - the loop's value and key variables and loop itself live in the child
+//     scope, so they never overwrite an outer variable of the same name and
+//     nested loops keep their own values
+//   - {% set %} on a variable that exists outside the loop updates it, so
+//     accumulating a total across iterations works
+//   - {% set %} on a new variable keeps it for the rest of the loop, but it
+//     is gone once the loop ends
+//
+// The child scope shares blocks, macros and render state with its parent and
+// only owns a small variable map, so entering a loop copies nothing.
+
+// newLoopScope returns a child scope for the body of a loop, or the context
+// itself in LoopScopeShared mode
+func (ctx *RenderContext) newLoopScope() *RenderContext {
+	if ctx.env != nil && ctx.env.loopScopeMode == LoopScopeShared {
+		return ctx
+	}
+
+	return &RenderContext{
+		env:                ctx.env,
+		context:            make(map[string]interface{}, 4),
+		blocks:             ctx.blocks,
+		parentBlocks:       ctx.parentBlocks,
+		macros:             ctx.macros,
+		parent:             ctx,
+		engine:             ctx.engine,
+		extending:          ctx.extending,
+		currentBlock:       ctx.currentBlock,
+		inParentCall:       ctx.inParentCall,
+		sandboxed:          ctx.sandboxed,
+		lastLoadedTemplate: ctx.lastLoadedTemplate,
+		templateStack:      ctx.templateStack,
+		profile:            ctx.profile,
+		options:            ctx.options,
+		visits:             ctx.visits,
+		labels:             ctx.labels,
+		features:           ctx.features,
+		blockOwners:        ctx.blockOwners,
+		loopScope:          true,
+	}
+}
+
+// setLocal sets a variable in this scope only, as loops do for their own
+// variables
+func (ctx *RenderContext) setLocal(name string, value interface{}) {
+	ctx.context[name] = value
+}
+
+// setScoped implements SetVariable inside a loop: the innermost scope that
+// already holds the variable is updated, otherwise the variable is new and
+// stays local to the loop. In LoopScopeIsolated mode every variable is new.
+func (ctx *RenderContext) setScoped(name string, value interface{}) {
+	if ctx.env != nil && ctx.env.loopScopeMode == LoopScopeIsolated {
+		ctx.context[name] = value
+		return
+	}
+
+	c := ctx
+	for ; c.loopScope; c = c.parent {
+		if _, ok := c.context[name]; ok {
+			c.context[name] = value
+			return
+		}
+	}
+
+	if c.hasVariable(name) {
+		c.context[name] = value
+		return
+	}
+	ctx.context[name] = value
+}
+
+// hasVariable reports whether name is defined in this context, its parents
+// or the globals and services
+func (ctx *RenderContext) hasVariable(name string) bool {
+	for c := ctx; c != nil; c = c.parent {
+		if _, ok := c.context[name]; ok {
+			return true
+		}
+	}
+	if ctx.env != nil {
+		_, global := ctx.env.globals[name]
+		_, service := ctx.env.services[name]
+		return global || service
+	}
+	return false
+}
+
+// scopeBase returns the context that encloses all loop scopes
+func (ctx *RenderContext) scopeBase() *RenderContext {
+	c := ctx
+	for c.loopScope {
+		c = c.parent
+	}
+	return c
+}
+
+// variables returns every variable visible in this scope as one map. It is
+// the context map itself outside of loops, and a merged copy inside them.
+func (ctx *RenderContext) variables() map[string]interface{} {
+	if !ctx.loopScope {
+		return ctx.context
+	}
+
+	base := ctx.parent.variables()
+	vars := make(map[string]interface{}, len(base)+len(ctx.context))
+	for k, v := range base {
+		vars[k] = v
+	}
+	for k, v := range ctx.context {
+		vars[k] = v
+	}
+	return vars
+}
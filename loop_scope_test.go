@@ -0,0 +1,121 @@
+package twig
+
+import "testing"
+
+func TestLoopScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "loop variable does not leak",
+			source:   `{% for item in [1, 2] %}{% endfor %}[{{ item }}]`,
+			expected: "[]",
+		},
+		{
+			name:     "outer variable is restored",
+			source:   `{% for item in [1, 2] %}{{ item }}{% endfor %}{{ item }}`,
+			context:  map[string]interface{}{"item": "outer"},
+			expected: "12outer",
+		},
+		{
+			name:     "nested loops keep their values",
+			source:   `{% for x in ['a', 'b'] %}{% for x in [1, 2] %}{{ x }}{% endfor %}{{ x }}{% endfor %}`,
+			expected: "12a12b",
+		},
+		{
+			name:     "nested loop metadata",
+			source:   `{% for a in [1, 2] %}{% for b in [1, 2, 3] %}{% endfor %}{{ loop.index }}/{{ loop.length }} {% endfor %}`,
+			expected: "1/2 2/2 ",
+		},
+		{
+			name:     "set updates outer variable",
+			source:   `{% set total = 0 %}{% for n in [1, 2, 3] %}{% set total = total + n %}{% endfor %}{{ total }}`,
+			expected: "6",
+		},
+		{
+			name:     "set in nested loop updates outer variable",
+			source:   `{% set count = 0 %}{% for a in [1, 2] %}{% for b in [1, 2] %}{% set count = count + 1 %}{% endfor %}{% endfor %}{{ count }}`,
+			expected: "4",
+		},
+		{
+			name:     "new variable persists across iterations",
+			source:   `{% for n in [1, 2] %}{{ last }}{% set last = n %}{% endfor %}`,
+			expected: "1",
+		},
+		{
+			name:     "new variable does not leak",
+			source:   `{% for n in [1, 2] %}{% set inner = n %}{% endfor %}[{{ inner }}]`,
+			expected: "[]",
+		},
+		{
+			name:     "include sees loop variables",
+			source:   `{% for n in [1, 2] %}{% include "item" %}{% endfor %}`,
+			expected: "<1><2>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("item", `<{{ n }}>`); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLoopScopeMode(t *testing.T) {
+	const (
+		accumulate = `{% set total = 0 %}{% for n in [1, 2, 3] %}{% if n > 1 %}{% set total = total + n %}{% endif %}{% endfor %}{{ total }}`
+		leak       = `{% for n in [1, 2] %}{% set inner = n %}{% endfor %}[{{ n }}{{ inner }}]`
+		ifSet      = `{% if true %}{% set flag = 'on' %}{% endif %}{{ flag }}`
+	)
+
+	tests := []struct {
+		name     string
+		mode     LoopScopeMode
+		source   string
+		expected string
+	}{
+		{"twig accumulates", LoopScopeTwig, accumulate, "5"},
+		{"twig drops new variables", LoopScopeTwig, leak, "[]"},
+		{"twig if does not scope", LoopScopeTwig, ifSet, "on"},
+		{"isolated keeps outer value", LoopScopeIsolated, accumulate, "0"},
+		{"isolated drops new variables", LoopScopeIsolated, leak, "[]"},
+		{"isolated if does not scope", LoopScopeIsolated, ifSet, "on"},
+		{"shared accumulates", LoopScopeShared, accumulate, "5"},
+		{"shared leaks variables", LoopScopeShared, leak, "[22]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetLoopScopeMode(tt.mode)
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
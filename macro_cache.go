@@ -0,0 +1,118 @@
+package twig
+
+import (
+	"io"
+	"sync"
+)
+
+// macroCacheEntry holds the macros harvested from one version of a template
+type macroCacheEntry struct {
+	template     *Template // Template the macros were harvested from
+	lastModified int64     // Modification time of that template
+	macros       map[string]Node
+}
+
+// macroCache stores harvested macros per template name so that importing a
+// macro library does not re-render it on every render
+type macroCache struct {
+	mu      sync.RWMutex
+	entries map[string]macroCacheEntry
+}
+
+// get returns the cached macros for template if they are still current
+func (c *macroCache) get(template *Template) (map[string]Node, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[template.name]
+	if !ok || entry.template != template || entry.lastModified != template.lastModified {
+		return nil, false
+	}
+	return entry.macros, true
+}
+
+// set stores the macros harvested from template, replacing older versions
+func (c *macroCache) set(template *Template, macros map[string]Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]macroCacheEntry)
+	}
+	c.entries[template.name] = macroCacheEntry{
+		template:     template,
+		lastModified: template.lastModified,
+		macros:       macros,
+	}
+}
+
+// clear removes all cached macros
+func (c *macroCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
+
+// importMacros returns the macros defined by an imported template. The macros
+// are collected once per version of the template, see collectMacros; later
+// imports reuse them.
+func (ctx *RenderContext) importMacros(template *Template, line int) (map[string]Node, error) {
+	cacheable := ctx.engine != nil && ctx.env != nil && ctx.env.cache && template.name != ""
+	if cacheable {
+		if macros, ok := ctx.engine.macros.get(template); ok {
+			return macros, nil
+		}
+	}
+
+	// Create a new context for the imported template
+	importCtx := NewRenderContext(ctx.env, nil, ctx.engine)
+	// Set the template as the lastLoadedTemplate for relative path resolution
+	importCtx.lastLoadedTemplate = template
+	importCtx.templateStack = ctx.childTemplateStack(line)
+	importCtx.options = ctx.options
+	importCtx.visits = ctx.visits
+	importCtx.labels = ctx.labels
+	importCtx.features = ctx.features
+
+	// Ensure context is released even in error paths
+	defer importCtx.Release()
+
+	if err := importCtx.collectMacros(template.nodes); err != nil {
+		return nil, importCtx.wrapError(err, 0)
+	}
+
+	// Copy the macros out of the pooled context map
+	macros := make(map[string]Node, len(importCtx.macros))
+	for name, macro := range importCtx.macros {
+		macros[name] = macro
+	}
+
+	if cacheable {
+		ctx.engine.macros.set(template, macros)
+	}
+
+	return macros, nil
+}
+
+// collectMacros registers the macros of a template tree in the context
+// without rendering it: the macro tags at its top level and the macros it
+// imports with from. Text, prints and other tags are skipped, so importing
+// a template has no side effects and costs no output.
+func (ctx *RenderContext) collectMacros(nodes Node) error {
+	children := []Node{nodes}
+	if root, ok := nodes.(*RootNode); ok {
+		children = root.children
+	}
+
+	for _, node := range children {
+		switch n := node.(type) {
+		case *MacroNode:
+			ctx.macros[n.name] = n
+		case *FromImportNode:
+			if err := n.Render(io.Discard, ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestImportedMacrosHarvestedOnce tests that imported macro libraries are
+// cached per version and never rendered
+func TestImportedMacrosHarvestedOnce(t *testing.T) {
+	engine := New()
+
+	harvests := 0
+	engine.AddFunction("track_harvest", func(args ...interface{}) (interface{}, error) {
+		harvests++
+		return nil, nil
+	})
+
+	library := "{% do track_harvest() %}{% macro hello(name) %}Hello {{ name }}{% endmacro %}"
+	templates := map[string]string{
+		"macros.twig": library,
+		"import.twig": "{% import 'macros.twig' as m %}{{ m.hello('import') }}",
+		"from.twig":   "{% from 'macros.twig' import hello %}{{ hello('from') }}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, name := range []string{"import.twig", "from.twig"} {
+			result, err := engine.Render(name, nil)
+			if err != nil {
+				t.Fatalf("Error rendering %s: %v", name, err)
+			}
+			if !strings.HasPrefix(result, "Hello ") {
+				t.Errorf("Unexpected output from %s: %q", name, result)
+			}
+		}
+	}
+
+	if harvests != 0 {
+		t.Errorf("Expected macro library not to be rendered, got %d renders", harvests)
+	}
+	if _, ok := engine.macros.entries["macros.twig"]; !ok {
+		t.Error("Expected the macros of the library to be cached")
+	}
+
+	// Registering a new version of the library invalidates the cached macros
+	if err := engine.RegisterString("macros.twig", strings.Replace(library, "Hello", "Hi", 1)); err != nil {
+		t.Fatalf("Error parsing macros.twig: %v", err)
+	}
+
+	result, err := engine.Render("import.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering import.twig: %v", err)
+	}
+	if result != "Hi import" {
+		t.Errorf("Expected %q after library change, got %q", "Hi import", result)
+	}
+	if harvests != 0 {
+		t.Errorf("Expected macro library not to be rendered after change, got %d renders", harvests)
+	}
+}
+
+// TestImportCollectsMacrosWithoutRendering tests that importing a library
+// skips its output and tags but keeps the macros it imports with from
+func TestImportCollectsMacrosWithoutRendering(t *testing.T) {
+	engine := New()
+
+	calls := 0
+	engine.AddFunction("side_effect", func(args ...interface{}) (interface{}, error) {
+		calls++
+		return nil, nil
+	})
+
+	templates := map[string]string{
+		"base.twig":  "{% macro bold(text) %}<b>{{ text }}</b>{% endmacro %}",
+		"forms.twig": "Library text {{ side_effect() }}{% from 'base.twig' import bold %}{% set x = side_effect() %}{% macro label(text) %}<label>{{ text }}</label>{% endmacro %}",
+		"page.twig":  "{% import 'forms.twig' as forms %}{{ forms.label('Name') }}{{ forms.bold('!') }}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+	}
+
+	result, err := engine.Render("page.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering page.twig: %v", err)
+	}
+	if result != "<label>Name</label><b>!</b>" {
+		t.Errorf("Expected %q, got %q", "<label>Name</label><b>!</b>", result)
+	}
+	if calls != 0 {
+		t.Errorf("Expected the library not to be rendered, got %d calls", calls)
+	}
+}
@@ -0,0 +1,27 @@
+package twig
+
+import "strings"
+
+// MacroInfo describes a macro defined by a template, for documenting
+// macro libraries
+type MacroInfo struct {
+	Name   string   `json:"name"`
+	Params []string `json:"params"`
+	Doc    string   `json:"doc,omitempty"` // Text of the {# @doc ... #} comment before the macro
+}
+
+// Doc returns the text of the {# @doc ... #} comment right before the
+// macro, or "" if it has none
+func (n *MacroNode) Doc() string {
+	return n.doc
+}
+
+// macroDoc returns the text of an @doc comment, or "" for other comments
+func macroDoc(comment string) string {
+	text := strings.TrimSpace(comment)
+	rest, ok := strings.CutPrefix(text, "@doc")
+	if !ok || (rest != "" && !isWhitespace(rest[0])) {
+		return ""
+	}
+	return strings.TrimSpace(rest)
+}
@@ -52,6 +52,28 @@ func TestMacrosWithDefaults(t *testing.T) {
 	}
 }
 
+// TestMacroDefaultsReferenceParams tests defaults that use earlier parameters
+func TestMacroDefaultsReferenceParams(t *testing.T) {
+	engine := New()
+
+	source := `{% macro input(name, id = name, label = id|capitalize ~ ':') %}` +
+		`<label for="{{ id }}">{{ label }}</label><input id="{{ id }}" name="{{ name }}">{% endmacro %}` +
+		`{{ input('email') }}|{{ input('email', 'work') }}|{{ input('email', 'work', 'Mail') }}`
+
+	engine.RegisterString("test_macro_defaults_params", source)
+	result, err := engine.Render("test_macro_defaults_params", nil)
+	if err != nil {
+		t.Fatalf("Error parsing/rendering template: %v", err)
+	}
+
+	expected := `<label for="email">Email:</label><input id="email" name="email">|` +
+		`<label for="work">Work:</label><input id="work" name="email">|` +
+		`<label for="work">Mail</label><input id="work" name="email">`
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
 // TestMacrosWithEscaping tests macro functionality with escaped parameters
 func TestMacrosWithEscaping(t *testing.T) {
 	engine := New()
@@ -0,0 +1,73 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+// headers is a named map type with a method, like http.Header
+type headers map[string]string
+
+func (h headers) Count() int {
+	return len(h)
+}
+
+// labelKey is a named string key type
+type labelKey string
+
+func TestMapAttributeAccess(t *testing.T) {
+	context := map[string]interface{}{
+		"labels":  map[string]string{"title": "Home"},
+		"counts":  map[string]int{"posts": 12},
+		"big":     map[string]int64{"views": 1 << 40},
+		"prices":  map[string]float64{"total": 9.5},
+		"flags":   map[string]bool{"beta": true},
+		"lists":   map[string][]string{"tags": {"go", "twig"}},
+		"keyed":   map[labelKey]string{"name": "keyed"},
+		"headers": headers{"Accept": "text/html", "Host": "example.com"},
+		"names":   []string{"a", "b"},
+		"numbers": []int{10, 20},
+		"ids":     map[int]string{7: "seven"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"map[string]string attribute", `{{ labels.title }}`, "Home"},
+		{"map[string]string item", `{{ labels['title'] }}`, "Home"},
+		{"map[string]int attribute", `{{ counts.posts + 1 }}`, "13"},
+		{"map[string]int item", `{{ counts['posts'] }}`, "12"},
+		{"map[string]int64 attribute", `{{ big.views }}`, "1099511627776"},
+		{"map[string]float64 attribute", `{{ prices.total }}`, "9.5"},
+		{"map[string]bool attribute", `{% if flags.beta %}beta{% endif %}`, "beta"},
+		{"missing key", `{{ labels.missing is null ? "null" : "set" }}`, "null"},
+		{"other map values", `{{ lists.tags|join(",") }}`, "go,twig"},
+		{"named key type", `{{ keyed.name }}`, "keyed"},
+		{"named map key", `{{ headers.Host }}`, "example.com"},
+		{"named map method", `{{ headers.Count }}`, "2"},
+		{"[]string item", `{{ names[1] }}`, "b"},
+		{"[]int item", `{{ numbers[0] }}`, "10"},
+		{"int keyed map item", `{{ ids[7] }}`, "seven"},
+		{"loop over map[string]string", `{% for k, v in labels %}{{ k }}={{ v }}{% endfor %}`, "title=Home"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if strings.TrimSpace(result) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
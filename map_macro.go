@@ -0,0 +1,73 @@
+package twig
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// mapMacro implements the map_macro filter: items|map_macro(ui.card) calls
+// the macro once per item and concatenates the output, without the loop
+// scope a for loop of macro calls sets up for every item. Extra arguments
+// are passed to the macro after the item, and the macro may be given by
+// name when it is defined in the template itself.
+func (ctx *RenderContext) mapMacro(value interface{}, args []interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("map_macro filter requires a macro argument")
+	}
+
+	var macro *MacroNode
+	switch m := args[0].(type) {
+	case *MacroNode:
+		macro = m
+	case string:
+		if node, ok := ctx.GetMacro(m); ok {
+			macro, _ = node.(*MacroNode)
+		}
+		if macro == nil {
+			return nil, fmt.Errorf("map_macro filter: macro '%s' not found", m)
+		}
+	default:
+		return nil, fmt.Errorf("map_macro filter expects a macro, got %T", args[0])
+	}
+
+	var items []interface{}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return "", nil
+	case reflect.Slice, reflect.Array:
+		items = make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+	case reflect.Map:
+		// Sort keys so the output does not depend on map iteration order
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		items = make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = v.MapIndex(key).Interface()
+		}
+	default:
+		return nil, fmt.Errorf("map_macro filter expects a sequence or mapping, got %T", value)
+	}
+
+	var buf bytes.Buffer
+	callArgs := make([]interface{}, len(args))
+	copy(callArgs[1:], args[1:])
+	for _, item := range items {
+		callArgs[0] = item
+		returned, err := macro.call(&buf, ctx, callArgs)
+		if err != nil {
+			return nil, err
+		}
+		if returned != nil {
+			buf.WriteString(ctx.ToString(returned))
+		}
+	}
+	return buf.String(), nil
+}
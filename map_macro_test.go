@@ -0,0 +1,75 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestMapMacroFilter(t *testing.T) {
+	engine := New()
+	engine.RegisterString("ui.twig", `{% macro card(item, size) %}[{{ item.name }}{% if size %}:{{ size }}{% endif %}]{% endmacro %}`+
+		`{% macro label(item) %}{% return item|upper %}{% endmacro %}`)
+
+	context := map[string]interface{}{
+		"items": []map[string]interface{}{{"name": "a"}, {"name": "b"}},
+		"tags":  map[string]interface{}{"y": "two", "x": "one"},
+		"none":  nil,
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"imported macro", "{% import 'ui.twig' as ui %}{{ items|map_macro(ui.card) }}", "[a][b]"},
+		{"extra arguments", "{% import 'ui.twig' as ui %}{{ items|map_macro(ui.card, 'lg') }}", "[a:lg][b:lg]"},
+		{"returned values", "{% import 'ui.twig' as ui %}{{ ['a', 'b']|map_macro(ui.label) }}", "AB"},
+		{"mapping in key order", "{% import 'ui.twig' as ui %}{{ tags|map_macro(ui.label) }}", "ONETWO"},
+		{"local macro by name", "{% macro li(x) %}<li>{{ x }}</li>{% endmacro %}{{ [1, 2]|map_macro('li') }}", "<li>1</li><li>2</li>"},
+		{"null", "{% import 'ui.twig' as ui %}{{ none|map_macro(ui.card) }}", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if err := engine.RegisterString("page", "{{ [1]|map_macro('missing') }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("page", nil); err == nil {
+		t.Error("Expected an error for an unknown macro")
+	}
+}
+
+func BenchmarkMapMacro(b *testing.B) {
+	engine := New()
+	engine.RegisterString("ui.twig", "{% macro card(item) %}[{{ item }}]{% endmacro %}")
+	engine.RegisterString("filter", "{% import 'ui.twig' as ui %}{{ items|map_macro(ui.card) }}")
+	engine.RegisterString("loop", "{% import 'ui.twig' as ui %}{% for item in items %}{{ ui.card(item) }}{% endfor %}")
+
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = i
+	}
+	context := map[string]interface{}{"items": items}
+
+	for _, name := range []string{"filter", "loop"} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := engine.Render(name, context); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package twig
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maskVisible is how many trailing letters and digits mask leaves visible
+// when no pattern is given
+const maskVisible = 4
+
+// filterMask implements value|mask(pattern, char = '*') for phone numbers,
+// card numbers and other sensitive strings. Only the letters and digits of
+// the value are used. In the pattern, # shows the next one, * hides it
+// behind char and any other character is written as is, so
+// '+15551231234'|mask('+# *** *** ####') is "+1 *** *** 1234". Without a
+// pattern all but the last four letters and digits are hidden and the rest
+// of the value is kept.
+func (e *CoreExtension) filterMask(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return "", nil
+	}
+	str := e.toString(value)
+
+	maskChar := "*"
+	if len(args) > 1 && args[1] != nil {
+		maskChar = e.toString(args[1])
+	}
+
+	if len(args) == 0 || args[0] == nil {
+		var significant int
+		for _, r := range str {
+			if isMaskable(r) {
+				significant++
+			}
+		}
+
+		var b strings.Builder
+		for _, r := range str {
+			if isMaskable(r) {
+				significant--
+				if significant >= maskVisible {
+					b.WriteString(maskChar)
+					continue
+				}
+			}
+			b.WriteRune(r)
+		}
+		return b.String(), nil
+	}
+
+	pattern, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("mask filter expects a pattern string, got %T", args[0])
+	}
+
+	var chars []rune
+	for _, r := range str {
+		if isMaskable(r) {
+			chars = append(chars, r)
+		}
+	}
+
+	var b strings.Builder
+	for _, p := range pattern {
+		switch p {
+		case '#', '*':
+			// Stop where the value runs out rather than pad the pattern
+			if len(chars) == 0 {
+				return b.String(), nil
+			}
+			if p == '#' {
+				b.WriteRune(chars[0])
+			} else {
+				b.WriteString(maskChar)
+			}
+			chars = chars[1:]
+		default:
+			b.WriteRune(p)
+		}
+	}
+	return b.String(), nil
+}
+
+// isMaskable reports whether mask counts r as part of the value
+func isMaskable(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,44 @@
+package twig
+
+import "testing"
+
+func TestMaskFilter(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		value    interface{}
+		expected string
+	}{
+		{"{{ value|mask('+# *** *** ####') }}", "+15551231234", "+1 *** *** 1234"},
+		{"{{ value|mask('+# *** *** ####') }}", "+1 (555) 123-1234", "+1 *** *** 1234"},
+		{"{{ value|mask('**** **** **** ####', 'x') }}", "4111111111111111", "xxxx xxxx xxxx 1111"},
+		{"{{ value|mask('###-###') }}", "1234", "123-4"},
+		{"{{ value|mask }}", "4111-1111-1111-1111", "****-****-****-1111"},
+		{"{{ value|mask(none, '#') }}", 5551234567, "######4567"},
+		{"{{ value|mask }}", "123", "123"},
+		{"[{{ value|mask }}]", nil, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", map[string]interface{}{"value": tt.value})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if err := engine.RegisterString("bad", "{{ 'abc'|mask(3) }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("bad", nil); err == nil {
+		t.Error("Expected an error for a non-string pattern")
+	}
+}
@@ -0,0 +1,118 @@
+package twig
+
+import "io"
+
+// SkipMiddlewareVar is the context variable that opts a render out of writer
+// middleware: true skips every middleware, a []string skips those named
+const SkipMiddlewareVar = "_skip_middleware"
+
+// RenderInfo describes the render a writer middleware is applied to
+type RenderInfo struct {
+	Template string
+	Context  map[string]interface{}
+}
+
+// WriterMiddleware wraps the writer of a render to post-process its output,
+// for example to minify it, inject CSP nonces or rewrite links. The returned
+// writer receives the output and writes the result to next; it is closed
+// after the render, even a failed one, so it can flush buffered output.
+type WriterMiddleware func(next io.Writer, info RenderInfo) io.WriteCloser
+
+// namedMiddleware is a middleware registered with UseWriter
+type namedMiddleware struct {
+	name       string
+	middleware WriterMiddleware
+}
+
+// UseWriter adds a writer middleware applied to every Render and RenderTo.
+// Middleware run in the order they are added: the first receives the
+// template output and writes to the second, and the last writes to the
+// caller's writer. Adding a middleware under an existing name replaces it
+// in place.
+func (e *Engine) UseWriter(name string, middleware WriterMiddleware) {
+	for i := range e.middleware {
+		if e.middleware[i].name == name {
+			e.middleware[i].middleware = middleware
+			return
+		}
+	}
+	e.middleware = append(e.middleware, namedMiddleware{name: name, middleware: middleware})
+}
+
+// hasWriterMiddleware reports whether any middleware applies to a render
+func (e *Engine) hasWriterMiddleware(context map[string]interface{}) bool {
+	for _, m := range e.middleware {
+		if !skipsMiddleware(context, m.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrapWriter builds the middleware chain around w, or returns nil when no
+// middleware applies to the render
+func (e *Engine) wrapWriter(w io.Writer, name string, context map[string]interface{}) io.WriteCloser {
+	info := RenderInfo{Template: name, Context: context}
+
+	var writers []io.WriteCloser
+	next := w
+	for i := len(e.middleware) - 1; i >= 0; i-- {
+		m := e.middleware[i]
+		if skipsMiddleware(context, m.name) {
+			continue
+		}
+		wrapped := m.middleware(next, info)
+		writers = append(writers, wrapped)
+		next = wrapped
+	}
+	if len(writers) == 0 {
+		return nil
+	}
+
+	// Close from the first middleware to the last, so each flushes into a
+	// writer that is still open
+	closers := make([]io.Closer, len(writers))
+	for i, writer := range writers {
+		closers[len(writers)-1-i] = writer
+	}
+	return &middlewareChain{Writer: next, closers: closers}
+}
+
+// middlewareChain is the writer a render writes to when middleware apply
+type middlewareChain struct {
+	io.Writer
+	closers []io.Closer
+}
+
+// WriteString lets string output skip a conversion when the first
+// middleware supports it
+func (c *middlewareChain) WriteString(s string) (int, error) {
+	return WriteString(c.Writer, s)
+}
+
+// Close closes every middleware writer and returns the first error
+func (c *middlewareChain) Close() error {
+	var first error
+	for _, closer := range c.closers {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// skipsMiddleware reports whether the context opts out of the named
+// middleware
+func skipsMiddleware(context map[string]interface{}, name string) bool {
+	switch skip := context[SkipMiddlewareVar].(type) {
+	case bool:
+		return skip
+	case []string:
+		for _, skipped := range skip {
+			if skipped == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package twig
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// wrapMiddleware buffers the output and surrounds it with a tag on close
+func wrapMiddleware(tag string) WriterMiddleware {
+	return func(next io.Writer, info RenderInfo) io.WriteCloser {
+		return &wrapWriter{next: next, tag: tag}
+	}
+}
+
+type wrapWriter struct {
+	next io.Writer
+	tag  string
+	buf  bytes.Buffer
+}
+
+func (w *wrapWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+
+func (w *wrapWriter) Close() error {
+	_, err := io.WriteString(w.next, "<"+w.tag+">"+w.buf.String()+"</"+w.tag+">")
+	return err
+}
+
+// nonceMiddleware streams the output, adding the render's CSP nonce to
+// script tags
+func nonceMiddleware(next io.Writer, info RenderInfo) io.WriteCloser {
+	nonce, _ := info.Context["nonce"].(string)
+	return &replaceWriter{next: next, old: "<script>", new: `<script nonce="` + nonce + `">`}
+}
+
+type replaceWriter struct {
+	next     io.Writer
+	old, new string
+}
+
+func (w *replaceWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.next, strings.ReplaceAll(string(p), w.old, w.new)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *replaceWriter) Close() error { return nil }
+
+func TestWriterMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "applied in order",
+			context:  map[string]interface{}{"nonce": "abc"},
+			expected: `<outer><inner><script nonce="abc">x</script></inner></outer>`,
+		},
+		{
+			name:     "skip by name",
+			context:  map[string]interface{}{"nonce": "abc", SkipMiddlewareVar: []string{"inner", "nonce"}},
+			expected: `<outer><script>x</script></outer>`,
+		},
+		{
+			name:     "skip all",
+			context:  map[string]interface{}{SkipMiddlewareVar: true},
+			expected: `<script>x</script>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.UseWriter("nonce", nonceMiddleware)
+			engine.UseWriter("inner", wrapMiddleware("inner"))
+			engine.UseWriter("outer", wrapMiddleware("outer"))
+			if err := engine.RegisterString("test", `<script>x</script>`); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+
+			var buf bytes.Buffer
+			if err := engine.RenderTo(&buf, "test", tt.context); err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected RenderTo to match, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestUseWriterReplacesByName(t *testing.T) {
+	engine := New()
+	engine.UseWriter("a", wrapMiddleware("a"))
+	engine.UseWriter("b", wrapMiddleware("b"))
+	engine.UseWriter("a", wrapMiddleware("c"))
+	if err := engine.RegisterString("test", "x"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "<b><c>x</c></b>" {
+		t.Errorf("Expected the replacement to keep its position, got %q", result)
+	}
+}
@@ -0,0 +1,73 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultilineTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"include with hash", "{% include 'part.twig' with { a: 1,\n b: 2 } %}", "[12]"},
+		{"include with hash CRLF", "{% include 'part.twig' with { a: 1,\r\n b: 2 } %}", "[12]"},
+		{"print across lines CRLF", "{{ a\r\n  ~ b }}", "xy"},
+		{"for across lines CRLF", "{% for x in\r\n  items %}{{ x }}{% endfor %}", "12"},
+		{"multi-line comment", "{# one\r\ntwo #}{{ a }}", "x"},
+		{"text keeps CRLF", "a\r\n{{ b }}\r\n", "a\r\ny\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.RegisterString("part.twig", "[{{ a }}{{ b }}]")
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{
+				"a":     "x",
+				"b":     "y",
+				"items": []int{1, 2},
+			})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMultilineErrorLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   string
+	}{
+		{"parse error after comment", "a\n{#\nmulti\nline\n#}\n{{ 1 +\n }}", "line 7"},
+		{"parse error CRLF", "a\r\nb\r\n{% if\r\n  x ==\r\n %}{% endif %}", "line 5"},
+		{"unclosed tag CRLF", "a\r\nb\r\n{{ x", "line 3"},
+		{"render error in expression", "a\n{{\n  missing_function() }}", "line 3"},
+		{"render error CRLF", "a\r\n\r\n{{ missing_function() }}", "line 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			err := engine.RegisterString("test", tt.source)
+			if err == nil {
+				_, err = engine.Render("test", nil)
+			}
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.line) {
+				t.Errorf("Expected the error at %s, got %v", tt.line, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package twig
+
+import "strings"
+
+// NamespaceProvider resolves the values under a namespace variable. Get is
+// called with the dotted path after the namespace name, "site.title" for
+// {{ config.site.title }}, and reports false when nothing is there.
+type NamespaceProvider interface {
+	Get(path string) (interface{}, bool)
+}
+
+// NamespaceFunc adapts a function to the NamespaceProvider interface
+type NamespaceFunc func(path string) (interface{}, bool)
+
+// Get calls f(path)
+func (f NamespaceFunc) Get(path string) (interface{}, bool) {
+	return f(path)
+}
+
+// RegisterNamespace exposes a variable whose attributes are looked up
+// through provider when read, so large trees such as configuration or
+// translations are not copied into each render context. An attribute
+// chain like config.site.title makes a single Get call with the whole
+// path; config[key] and other dynamic accesses resolve one level at a
+// time. Context variables, globals and services of the same name take
+// precedence. A nil provider removes the namespace.
+func (e *Engine) RegisterNamespace(name string, provider NamespaceProvider) {
+	if provider == nil {
+		delete(e.environment.namespaces, name)
+		return
+	}
+	if e.environment.namespaces == nil {
+		e.environment.namespaces = make(map[string]NamespaceProvider)
+	}
+	e.environment.namespaces[name] = provider
+}
+
+// namespaceValue is the value of a namespace variable. Its attributes are
+// the top level values of the provider.
+type namespaceValue struct {
+	name     string
+	provider NamespaceProvider
+}
+
+// GetAttr returns the value under name
+func (n *namespaceValue) GetAttr(name string) (interface{}, bool) {
+	return n.provider.Get(name)
+}
+
+// String returns the name of the namespace
+func (n *namespaceValue) String() string {
+	return n.name
+}
+
+// resolveNamespace returns the value of a registered namespace variable
+func (ctx *RenderContext) resolveNamespace(name string) (interface{}, bool) {
+	if ctx.env == nil {
+		return nil, false
+	}
+	provider, ok := ctx.env.namespaces[name]
+	if !ok {
+		return nil, false
+	}
+	return &namespaceValue{name: name, provider: provider}, true
+}
+
+// namespaceAttr resolves a chain of attribute accesses on a namespace
+// variable with one Get call. handled is false unless the chain starts at
+// a namespace variable and names every attribute literally.
+func (ctx *RenderContext) namespaceAttr(n *GetAttrNode) (value interface{}, found, handled bool) {
+	if ctx.env == nil || len(ctx.env.namespaces) == 0 {
+		return nil, false, false
+	}
+
+	var parts []string
+	var node Node = n
+	for {
+		attr, ok := node.(*GetAttrNode)
+		if !ok {
+			break
+		}
+		literal, ok := attr.attribute.(*LiteralNode)
+		if !ok {
+			return nil, false, false
+		}
+		part, ok := literal.value.(string)
+		if !ok {
+			return nil, false, false
+		}
+		parts = append(parts, part)
+		node = attr.node
+	}
+
+	root, ok := node.(*VariableNode)
+	if !ok {
+		return nil, false, false
+	}
+	if _, ok := ctx.env.namespaces[root.name]; !ok {
+		return nil, false, false
+	}
+
+	// A variable of the same name hides the namespace
+	variable, err := ctx.GetVariable(root.name)
+	if err != nil {
+		return nil, false, false
+	}
+	ns, ok := variable.(*namespaceValue)
+	if !ok {
+		return nil, false, false
+	}
+
+	// The parts were collected from the outermost attribute inwards
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	value, found = ns.provider.Get(strings.Join(parts, "."))
+	return value, found, true
+}
@@ -0,0 +1,63 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegisterNamespace(t *testing.T) {
+	engine := New()
+
+	config := map[string]interface{}{
+		"site.title": "Example",
+		"site":       map[string]interface{}{"title": "Example", "lang": "en"},
+		"debug":      false,
+	}
+	var calls []string
+	engine.RegisterNamespace("config", NamespaceFunc(func(path string) (interface{}, bool) {
+		calls = append(calls, path)
+		value, ok := config[path]
+		return value, ok
+	}))
+
+	tests := []struct {
+		source   string
+		context  map[string]interface{}
+		expected string
+		calls    []string
+	}{
+		{"{{ config.site.title }}", nil, "Example", []string{"site.title"}},
+		{"{{ config.site.title is defined ? 'y' : 'n' }}{{ config.site.missing is defined ? 'y' : 'n' }}", nil, "yn", []string{"site.title", "site.missing"}},
+		{"{{ config[key] }}", map[string]interface{}{"key": "site.title"}, "Example", []string{"site.title"}},
+		{"{{ config.site[key] }}", map[string]interface{}{"key": "lang"}, "en", []string{"site"}},
+		{"{{ config.debug ? 'on' : 'off' }}", nil, "off", []string{"debug"}},
+		// A context variable hides the namespace
+		{"{{ config.site.title }}", map[string]interface{}{"config": map[string]interface{}{"site": map[string]interface{}{"title": "Local"}}}, "Local", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			calls = nil
+			template, err := engine.ParseTemplate(tt.source)
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := template.Render(tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if strings.Join(calls, ",") != strings.Join(tt.calls, ",") {
+				t.Errorf("Expected lookups %v, got %v", tt.calls, calls)
+			}
+		})
+	}
+
+	engine.RegisterNamespace("config", nil)
+	template, _ := engine.ParseTemplate("{{ config.site.title }}")
+	if result, _ := template.Render(nil); result != "" {
+		t.Errorf("Expected the removed namespace to be undefined, got %q", result)
+	}
+}
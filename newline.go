@@ -0,0 +1,135 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of
+// UTF-8 files
+const utf8BOM = "\uFEFF"
+
+// SetNewline sets the line ending template sources are normalized to when
+// they are parsed, "\n" or "\r\n", so output does not depend on how files
+// were checked out. The empty string, the default, keeps line endings as
+// written. It applies to templates parsed after the call.
+func (e *Engine) SetNewline(newline string) error {
+	switch newline {
+	case "", "\n", "\r\n":
+		e.newline = newline
+		return nil
+	}
+	return fmt.Errorf("unsupported newline %q", newline)
+}
+
+// SetKeepTrailingNewline sets whether the newline at the end of a template
+// source is rendered, true by default. Most editors end files with a
+// newline, so generated config files and emails get one more than intended;
+// false drops a single trailing newline, like Jinja's keep_trailing_newline.
+// It applies to templates parsed after the call.
+func (e *Engine) SetKeepTrailingNewline(keep bool) {
+	e.trimFinalNewline = !keep
+}
+
+// SetNormalizeOutputNewlines sets whether line endings in rendered output,
+// including those in variable values, are converted to the newline set
+// with SetNewline, or "\n" when it is unset
+func (e *Engine) SetNormalizeOutputNewlines(normalize bool) {
+	e.normalizeOutput = normalize
+}
+
+// prepareSource removes a leading byte order mark, normalizes line endings
+// and drops the trailing newline if configured before a template is parsed
+func (e *Engine) prepareSource(source string) string {
+	source = strings.TrimPrefix(source, utf8BOM)
+	if e.newline != "" {
+		source = normalizeNewlines(source, e.newline)
+	}
+	if e.trimFinalNewline {
+		if strings.HasSuffix(source, "\r\n") {
+			source = source[:len(source)-2]
+		} else {
+			source = strings.TrimSuffix(source, "\n")
+		}
+	}
+	return source
+}
+
+// normalizeNewlines converts CRLF, CR and LF line endings to newline
+func normalizeNewlines(source, newline string) string {
+	if !strings.ContainsRune(source, '\r') && (newline == "\n" || !strings.ContainsRune(source, '\n')) {
+		return source
+	}
+
+	var b strings.Builder
+	b.Grow(len(source))
+	for i := 0; i < len(source); i++ {
+		switch c := source[i]; c {
+		case '\r':
+			if i+1 < len(source) && source[i+1] == '\n' {
+				i++
+			}
+			b.WriteString(newline)
+		case '\n':
+			b.WriteString(newline)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// newlineWriter converts the line endings written through it, holding back
+// a trailing CR until it knows whether an LF follows
+type newlineWriter struct {
+	w         io.Writer
+	newline   string
+	pendingCR bool
+	buf       []byte
+}
+
+// newlineWriter wraps w to normalize output line endings
+func (e *Engine) newlineWriter(w io.Writer) *newlineWriter {
+	newline := e.newline
+	if newline == "" {
+		newline = "\n"
+	}
+	return &newlineWriter{w: w, newline: newline}
+}
+
+// Write converts line endings in p and writes the result
+func (nw *newlineWriter) Write(p []byte) (int, error) {
+	nw.buf = nw.buf[:0]
+	for _, c := range p {
+		if nw.pendingCR {
+			nw.pendingCR = false
+			nw.buf = append(nw.buf, nw.newline...)
+			if c == '\n' {
+				continue
+			}
+		}
+		switch c {
+		case '\r':
+			nw.pendingCR = true
+		case '\n':
+			nw.buf = append(nw.buf, nw.newline...)
+		default:
+			nw.buf = append(nw.buf, c)
+		}
+	}
+	if _, err := nw.w.Write(nw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes a CR held back at the end of the output
+func (nw *newlineWriter) Flush() error {
+	if !nw.pendingCR {
+		return nil
+	}
+	nw.pendingCR = false
+	_, err := io.WriteString(nw.w, nw.newline)
+	return err
+}
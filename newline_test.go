@@ -0,0 +1,154 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateSourceNewlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		newline  string
+		source   string
+		expected string
+	}{
+		{"preserve CRLF", "", "<p>\r\n{{ name }}\r\n</p>", "<p>\r\nAda\r\n</p>"},
+		{"preserve LF", "", "<p>\n{{ name }}\n</p>", "<p>\nAda\n</p>"},
+		{"strip BOM", "", "\uFEFF<p>{{ name }}</p>", "<p>Ada</p>"},
+		{"BOM only at start", "", "{{ name }}\uFEFF", "Ada\uFEFF"},
+		{"normalize CRLF to LF", "\n", "a\r\nb\r\n{{ name }}", "a\nb\nAda"},
+		{"normalize CR to LF", "\n", "a\rb", "a\nb"},
+		{"normalize LF to CRLF", "\r\n", "a\nb\r\nc", "a\r\nb\r\nc"},
+		{"normalize with BOM", "\n", "\uFEFFa\r\n{{ name }}", "a\nAda"},
+		{"values are not normalized", "\n", "{{ text }}", "x\r\ny"},
+		{"trim markers with CRLF", "", "a\r\n  {{- name -}}  \r\nb", "aAdab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.SetNewline(tt.newline); err != nil {
+				t.Fatal(err)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"name": "Ada", "text": "x\r\ny"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemplateSourceNewlinesFromLoader(t *testing.T) {
+	engine := New()
+	engine.SetNewline("\n")
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"page.twig": "\uFEFFline one\r\nline two\r\n{{ missing_function() }}",
+	}))
+
+	_, err := engine.Render("page.twig", nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Expected the error on line 3, got %v", err)
+	}
+
+	template, err := engine.Load("page.twig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.source != "line one\nline two\n{{ missing_function() }}" {
+		t.Errorf("Unexpected normalized source %q", template.source)
+	}
+}
+
+func TestSetNewlineRejectsOtherValues(t *testing.T) {
+	engine := New()
+	if err := engine.SetNewline("\r"); err == nil {
+		t.Error("Expected an error for a lone carriage return")
+	}
+}
+
+func TestKeepTrailingNewline(t *testing.T) {
+	tests := []struct {
+		name     string
+		keep     bool
+		source   string
+		expected string
+	}{
+		{"kept by default", true, "a: {{ name }}\n", "a: Ada\n"},
+		{"drop LF", false, "a: {{ name }}\n", "a: Ada"},
+		{"drop CRLF", false, "a: {{ name }}\r\n", "a: Ada"},
+		{"drop only one", false, "a\n\n", "a\n"},
+		{"nothing to drop", false, "a", "a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetKeepTrailingNewline(tt.keep)
+			engine.RegisterLoader(NewArrayLoader(map[string]string{"test": tt.source}))
+
+			result, err := engine.Render("test", map[string]interface{}{"name": "Ada"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNormalizeOutputNewlines(t *testing.T) {
+	tests := []struct {
+		name     string
+		newline  string
+		text     string
+		expected string
+	}{
+		{"CRLF values to LF", "", "x\r\ny", "<x\ny\n>"},
+		{"CR values to LF", "\n", "x\ry", "<x\ny\n>"},
+		{"values to CRLF", "\r\n", "x\ny", "<x\r\ny\r\n>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.SetNewline(tt.newline); err != nil {
+				t.Fatal(err)
+			}
+			engine.SetNormalizeOutputNewlines(true)
+			if err := engine.RegisterString("test", "<{{ text }}\n>"); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"text": tt.text})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// A CR at the very end of the output is only written on Flush
+	var b strings.Builder
+	nw := New().newlineWriter(&b)
+	nw.Write([]byte("a\r"))
+	nw.Write([]byte("\nb\r"))
+	if err := nw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "a\nb\n" {
+		t.Errorf("Expected %q, got %q", "a\nb\n", b.String())
+	}
+}
@@ -0,0 +1,117 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestFiltersOnNil(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"upper", "[{{ x|upper }}]", "[]"},
+		{"lower", "[{{ x|lower }}]", "[]"},
+		{"capitalize", "[{{ x|capitalize }}]", "[]"},
+		{"title", "[{{ x|title }}]", "[]"},
+		{"trim", "[{{ x|trim }}]", "[]"},
+		{"escape", "[{{ x|e }}]", "[]"},
+		{"striptags", "[{{ x|striptags }}]", "[]"},
+		{"nl2br", "[{{ x|nl2br }}]", "[]"},
+		{"spaceless", "[{{ x|spaceless }}]", "[]"},
+		{"url_encode", "[{{ x|url_encode }}]", "[]"},
+		{"replace with arguments", "[{{ x|replace('a', 'b') }}]", "[]"},
+		{"replace with map", "[{{ x|replace({'a': 'b'}) }}]", "[]"},
+		{"format", "[{{ x|format(1, 2) }}]", "[]"},
+		{"length", "[{{ x|length }}]", "[0]"},
+		{"count", "[{{ x|count }}]", "[0]"},
+		{"join", "[{{ x|join(', ') }}]", "[]"},
+		{"split", "[{{ x|split(',')|length }}]", "[0]"},
+		{"first", "[{{ x|first }}]", "[]"},
+		{"last", "[{{ x|last }}]", "[]"},
+		{"slice", "[{{ x|slice(1, 2)|length }}]", "[0]"},
+		{"reverse", "[{{ x|reverse|length }}]", "[0]"},
+		{"sort", "[{{ x|sort|length }}]", "[0]"},
+		{"keys", "[{{ x|keys|length }}]", "[0]"},
+		{"merge array", "[{{ x|merge([1, 2])|join(',') }}]", "[1,2]"},
+		{"merge hash", "[{{ x|merge({'a': 1})|keys|join(',') }}]", "[a]"},
+		{"abs", "[{{ x|abs }}]", "[]"},
+		{"round", "[{{ x|round(2) }}]", "[]"},
+		{"number_format", "[{{ x|number_format(2) }}]", "[]"},
+		{"json_encode", "[{{ x|json_encode }}]", "[null]"},
+		{"for over filtered nil", "{% for i in x|sort %}{{ i }}{% else %}empty{% endfor %}", "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			for _, context := range []map[string]interface{}{{"x": nil}, {}} {
+				result, err := engine.Render("test", context)
+				if err != nil {
+					t.Fatalf("Error rendering template with %v: %v", context, err)
+				}
+				if result != tt.expected {
+					t.Errorf("With %v: expected %q, got %q", context, tt.expected, result)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultAfterFilterChain(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Missing variable", "{{ missing|upper|default('d') }}", "d"},
+		{"Missing attribute", "{{ user.name|trim|upper|default('anon') }}", "anon"},
+		{"Attribute of nil", "{{ nothing.name|default('n') }}", "n"},
+		{"Empty list", "{{ items|first|default('none') }}", "none"},
+		{"Join of missing", "{{ missing|join(', ')|default('none') }}", "none"},
+		{"Split of missing", "{{ missing|split(',')|join('-')|default('none') }}", "none"},
+		{"Default then filter", "{{ missing|default('d')|upper }}", "D"},
+	}
+
+	context := map[string]interface{}{
+		"user":    map[string]interface{}{},
+		"nothing": nil,
+		"items":   []interface{}{},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReplaceWithMap(t *testing.T) {
+	engine := New()
+	engine.RegisterString("test", "{{ 'hello world'|replace({'hello': 'bye', 'o': '0', 'hell': 'X'}) }}")
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	// Longer keys win and replaced text is not scanned again
+	if result != "bye w0rld" {
+		t.Errorf("Expected %q, got %q", "bye w0rld", result)
+	}
+}
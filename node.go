@@ -114,6 +114,9 @@ const (
 	NodeModuleMethod
 	NodeApply
 	NodeSandbox
+	NodeReturn
+	NodeEmbed
+	NodeWith
 )
 
 // RootNode represents the root of a template
@@ -125,6 +128,7 @@ type RootNode struct {
 // TextNode represents a raw text node
 type TextNode struct {
 	content string
+	data    []byte // Precomputed content bytes, set by the optimizer
 	line    int
 }
 
@@ -178,7 +182,11 @@ func (n *IfNode) Render(w io.Writer, ctx *RenderContext) error {
 			if IsDebugEnabled() {
 				LogError(err, "Error evaluating 'if' condition")
 			}
-			return err
+			line := condition.Line()
+			if line == 0 {
+				line = n.line
+			}
+			return ctx.wrapError(err, line)
 		}
 
 		// Log result if debug is enabled
@@ -195,7 +203,7 @@ func (n *IfNode) Render(w io.Writer, ctx *RenderContext) error {
 
 			// Render all nodes in the body
 			for _, node := range n.bodies[i] {
-				err := node.Render(w, ctx)
+				err := ctx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -211,7 +219,7 @@ func (n *IfNode) Render(w io.Writer, ctx *RenderContext) error {
 		}
 
 		for _, node := range n.elseBranch {
-			err := node.Render(w, ctx)
+			err := ctx.render(w, node)
 			if err != nil {
 				return err
 			}
@@ -225,6 +233,7 @@ func (n *IfNode) Render(w io.Writer, ctx *RenderContext) error {
 type ForNode struct {
 	keyVar     string
 	valueVar   string
+	targets    []string // Names the value is destructured into, see setValue
 	sequence   Node
 	body       []Node
 	elseBranch []Node
@@ -265,13 +274,13 @@ func (n *ForNode) Render(w io.Writer, ctx *RenderContext) error {
 		// Get the base value first
 		baseNode, filterChain, err := ctx.DetectFilterChain(filterNode)
 		if err != nil {
-			return err
+			return ctx.wrapError(err, n.line)
 		}
 
 		// Evaluate the base value
 		baseValue, err := ctx.EvaluateExpression(baseNode)
 		if err != nil {
-			return err
+			return ctx.wrapError(err, n.line)
 		}
 
 		if IsDebugEnabled() {
@@ -286,9 +295,9 @@ func (n *ForNode) Render(w io.Writer, ctx *RenderContext) error {
 			}
 
 			// Apply the filter
-			result, err = ctx.ApplyFilter(filter.name, result, filter.args...)
+			result, err = ctx.applyChainItem(filter, result)
 			if err != nil {
-				return err
+				return ctx.wrapError(err, n.line)
 			}
 
 			if IsDebugEnabled() {
@@ -303,7 +312,7 @@ func (n *ForNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Standard evaluation for other types of sequences
 	seq, err := ctx.EvaluateExpression(n.sequence)
 	if err != nil {
-		return err
+		return ctx.wrapError(err, n.line)
 	}
 
 	// WORKAROUND: When a filter is used directly in a for loop sequence like:
@@ -363,7 +372,7 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 	if seq == nil {
 		if n.elseBranch != nil {
 			for _, node := range n.elseBranch {
-				err := node.Render(w, ctx)
+				err := ctx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -372,11 +381,21 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 		return nil
 	}
 
+	// Custom collections iterate without reflection
+	if handled, err := n.renderCollection(w, ctx, seq); handled {
+		return err
+	}
+
+	// Go function iterators stream their items, see iterators.go
+	if handled, err := n.renderIterator(w, ctx, seq); handled {
+		return err
+	}
+
 	// Get the value as a reflect.Value for iteration
 	val := reflect.ValueOf(seq)
 
-	// Create a new context for the loop variables
-	loopCtx := ctx
+	// Loop variables live in a child scope, see loop_scope.go
+	loopCtx := ctx.newLoopScope()
 
 	// Keep track of loop variables
 	loopVars := map[string]interface{}{
@@ -443,7 +462,7 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 	if !isIterable || length == 0 {
 		if n.elseBranch != nil {
 			for _, node := range n.elseBranch {
-				err := node.Render(w, ctx)
+				err := ctx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -469,22 +488,22 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 
 			// Set the value variable
 			if val.Index(i).CanInterface() {
-				loopCtx.SetVariable(n.valueVar, val.Index(i).Interface())
+				n.setValue(loopCtx, val.Index(i).Interface())
 			} else {
-				loopCtx.SetVariable(n.valueVar, nil)
+				n.setValue(loopCtx, nil)
 			}
 
 			// Set the key variable if provided
 			if n.keyVar != "" {
-				loopCtx.SetVariable(n.keyVar, i)
+				loopCtx.setLocal(n.keyVar, i)
 			}
 
 			// Set the loop variables
-			loopCtx.SetVariable("loop", loopVars["loop"])
+			loopCtx.setLocal("loop", loopVars["loop"])
 
 			// Render the body
 			for _, node := range n.body {
-				err := node.Render(w, loopCtx)
+				err := loopCtx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -504,26 +523,26 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 
 			// Set the value variable
 			if val.MapIndex(key).CanInterface() {
-				loopCtx.SetVariable(n.valueVar, val.MapIndex(key).Interface())
+				n.setValue(loopCtx, val.MapIndex(key).Interface())
 			} else {
-				loopCtx.SetVariable(n.valueVar, nil)
+				n.setValue(loopCtx, nil)
 			}
 
 			// Set the key variable if provided
 			if n.keyVar != "" {
 				if key.CanInterface() {
-					loopCtx.SetVariable(n.keyVar, key.Interface())
+					loopCtx.setLocal(n.keyVar, key.Interface())
 				} else {
-					loopCtx.SetVariable(n.keyVar, nil)
+					loopCtx.setLocal(n.keyVar, nil)
 				}
 			}
 
 			// Set the loop variables
-			loopCtx.SetVariable("loop", loopVars["loop"])
+			loopCtx.setLocal("loop", loopVars["loop"])
 
 			// Render the body
 			for _, node := range n.body {
-				err := node.Render(w, loopCtx)
+				err := loopCtx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -541,19 +560,19 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 			loopVars["loop"].(map[string]interface{})["last"] = i == length-1
 
 			// Set the value variable
-			loopCtx.SetVariable(n.valueVar, string(char))
+			n.setValue(loopCtx, string(char))
 
 			// Set the key variable if provided
 			if n.keyVar != "" {
-				loopCtx.SetVariable(n.keyVar, i)
+				loopCtx.setLocal(n.keyVar, i)
 			}
 
 			// Set the loop variables
-			loopCtx.SetVariable("loop", loopVars["loop"])
+			loopCtx.setLocal("loop", loopVars["loop"])
 
 			// Render the body
 			for _, node := range n.body {
-				err := node.Render(w, loopCtx)
+				err := loopCtx.render(w, node)
 				if err != nil {
 					return err
 				}
@@ -566,9 +585,10 @@ func (n *ForNode) renderForLoop(w io.Writer, ctx *RenderContext, seq interface{}
 
 // BlockNode represents a block definition
 type BlockNode struct {
-	name string
-	body []Node
-	line int
+	name  string
+	body  []Node
+	cache *blockCache // cache(...) annotation, nil for none
+	line  int
 }
 
 func (n *BlockNode) Type() NodeType {
@@ -615,21 +635,42 @@ func (n *BlockNode) Render(w io.Writer, ctx *RenderContext) error {
 	previousBlock := ctx.currentBlock
 	ctx.currentBlock = n
 
+	// When the content comes from another template (a child overriding this
+	// block), attribute errors to that template and record where we came from
+	previousTemplate := ctx.lastLoadedTemplate
+	previousStack := ctx.templateStack
+	if owner := ctx.blockOwners[n.name]; owner != nil && owner != ctx.lastLoadedTemplate {
+		ctx.templateStack = ctx.childTemplateStack(n.line)
+		ctx.lastLoadedTemplate = owner
+	}
+
 	// Create an isolated context for rendering this block
 	// This prevents parent() from accessing the wrong block context
 	blockCtx := ctx
 
 	// Render the appropriate content
-	for _, node := range content {
-		err := node.Render(w, blockCtx)
-		if err != nil {
-			return err
+	out, done := ctx.trackOutput(w, "block", n.name, n.line)
+	render := func(w io.Writer) error {
+		for _, node := range content {
+			if err := blockCtx.render(w, node); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
+	var err error
+	if n.cache != nil {
+		err = n.cache.renderCached(out, ctx, n.name, render)
+	} else {
+		err = render(out)
+	}
+	done()
 
-	// Restore the previous block
+	// Restore the previous block and template
 	ctx.currentBlock = previousBlock
-	return nil
+	ctx.lastLoadedTemplate = previousTemplate
+	ctx.templateStack = previousStack
+	return err
 }
 
 // ExtendsNode represents an extends directive
@@ -656,56 +697,27 @@ func (n *ExtendsNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Flag that this template extends another
 	ctx.extending = true
 
-	// Get the parent template name
-	templateExpr, err := ctx.EvaluateExpression(n.parent)
+	// Resolve the parent template and its blocks
+	parentTemplate, parentBlocks, err := n.resolveParent(ctx)
 	if err != nil {
-		return err
-	}
-
-	templateName := ctx.ToString(templateExpr)
-
-	// Load the parent template
-	if ctx.engine == nil {
-		return fmt.Errorf("no template engine available to load parent template: %s", templateName)
-	}
-
-	// Handle relative paths for templates
-	resolvedName := templateName
-	if strings.HasPrefix(templateName, "./") || strings.HasPrefix(templateName, "../") {
-		// Get the directory of the current template
-		currentTemplate := ctx.engine.currentTemplate
-		if currentTemplate != "" {
-			// Extract the directory part of the current template
-			currentDir := filepath.Dir(currentTemplate)
-			// Join the directory with the relative path
-			resolvedName = filepath.Join(currentDir, templateName)
-		}
-	}
-
-	// Load the parent template with resolved path
-	parentTemplate, err := ctx.engine.Load(resolvedName)
-	if err != nil {
-		// Only try the fallback if the template was not found AND the paths are different
-		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
-			parentTemplate, err = ctx.engine.Load(templateName)
-			if err != nil {
-				return err
-			}
-		} else {
-			// For any other error (including syntax errors), return immediately
-			return err
-		}
+		return ctx.wrapError(err, n.line)
 	}
 
 	// Blocks from child template are registered to the parent context
 
 	// Create a new context for the parent template, but with our child blocks
 	// This ensures the parent template knows it's being extended and preserves our blocks
-	parentCtx := NewRenderContext(ctx.env, ctx.context, ctx.engine)
+	parentCtx := NewRenderContext(ctx.env, ctx.variables(), ctx.engine)
 	parentCtx.extending = true // Flag that the parent is being extended
 
 	// Pass along the parent template as lastLoadedTemplate for relative path resolution
 	parentCtx.lastLoadedTemplate = parentTemplate
+	parentCtx.templateStack = ctx.childTemplateStack(n.line)
+	parentCtx.profile = ctx.profile
+	parentCtx.options = ctx.options
+	parentCtx.visits = ctx.visits
+	parentCtx.labels = ctx.labels
+	parentCtx.features = ctx.features
 
 	// Ensure the context is released even if an error occurs
 	defer parentCtx.Release()
@@ -717,17 +729,11 @@ func (n *ExtendsNode) Render(w io.Writer, ctx *RenderContext) error {
 		parentCtx.parentBlocks[name] = nodes
 	}
 
-	// Extract blocks from the parent template and store them as parent blocks
-	// for any blocks defined in the child but not yet in the parent chain
-	if rootNode, ok := parentTemplate.nodes.(*RootNode); ok {
-		for _, child := range rootNode.Children() {
-			if block, ok := child.(*BlockNode); ok {
-				// If we don't already have a parent for this block,
-				// use the parent template's block definition
-				if _, exists := parentCtx.parentBlocks[block.name]; !exists {
-					parentCtx.parentBlocks[block.name] = block.body
-				}
-			}
+	// Store the parent template's blocks as parent blocks for any
+	// blocks defined in the child but not yet in the parent chain
+	for name, body := range parentBlocks {
+		if _, exists := parentCtx.parentBlocks[name]; !exists {
+			parentCtx.parentBlocks[name] = body
 		}
 	}
 
@@ -736,9 +742,12 @@ func (n *ExtendsNode) Render(w io.Writer, ctx *RenderContext) error {
 	for name, nodes := range ctx.blocks {
 		parentCtx.blocks[name] = nodes
 	}
+	for name, owner := range ctx.blockOwners {
+		parentCtx.setBlockOwner(name, owner)
+	}
 
 	// Render the parent template with the updated context
-	return parentTemplate.nodes.Render(w, parentCtx)
+	return parentCtx.wrapError(parentTemplate.nodes.Render(w, parentCtx), 0)
 }
 
 // IncludeNode represents an include directive
@@ -766,112 +775,32 @@ func (n *IncludeNode) Release() {
 
 // Implement Node interface for IncludeNode
 func (n *IncludeNode) Render(w io.Writer, ctx *RenderContext) error {
-	// Get the template name
-	templateExpr, err := ctx.EvaluateExpression(n.template)
-	if err != nil {
+	template, err := ctx.loadIncluded(n.template, n.ignoreMissing, n.line)
+	if err != nil || template == nil {
 		return err
 	}
 
-	templateName := ctx.ToString(templateExpr)
-
-	// Load the template
-	if ctx.engine == nil {
-		return fmt.Errorf("no template engine available to load included template: %s", templateName)
-	}
-
-	// Handle relative paths for templates
-	resolvedName := templateName
-	if strings.HasPrefix(templateName, "./") || strings.HasPrefix(templateName, "../") {
-		// Get the directory of the current template
-		currentTemplate := ctx.engine.currentTemplate
-		if currentTemplate != "" {
-			// Extract the directory part of the current template
-			currentDir := filepath.Dir(currentTemplate)
-			// Join the directory with the relative path
-			resolvedName = filepath.Join(currentDir, templateName)
-		}
-	}
-
-	// Load the template with resolved path
-	template, err := ctx.engine.Load(resolvedName)
+	// Variables passed with 'with' don't leak into the including template
+	includeCtx, err := ctx.scopedContext(includeScope{
+		variables: n.variables,
+		only:      n.only,
+		sandboxed: n.sandboxed,
+		line:      n.line,
+	})
 	if err != nil {
-		// Only try the fallback if the template was not found AND the paths are different
-		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
-			template, err = ctx.engine.Load(templateName)
-			if err != nil {
-				if n.ignoreMissing && errors.Is(err, ErrTemplateNotFound) {
-					return nil
-				}
-				return err
-			}
-		} else {
-			if n.ignoreMissing && errors.Is(err, ErrTemplateNotFound) {
-				return nil
-			}
-			// For any other error (including syntax errors), return immediately
-			return err
-		}
-	}
-
-	// Create optimized context handling for includes
-
-	// Fast path: if no special handling needed and not sandboxed, render with current context
-	if !n.only && !n.sandboxed && len(n.variables) == 0 {
-		// Clone the context but with the new lastLoadedTemplate
-		includeCtx := ctx.Clone()
-		includeCtx.lastLoadedTemplate = template
-		defer includeCtx.Release()
-
-		return template.nodes.Render(w, includeCtx)
-	}
-
-	// Need a new context for 'only' mode, sandboxed mode, or with variables
-	includeCtx := ctx
-	if n.only || n.sandboxed {
-		var contextVars map[string]interface{}
-
-		if n.only {
-			// Only mode - create empty context
-			contextVars = make(map[string]interface{}, len(n.variables))
-		} else {
-			// For sandboxed mode but not 'only' mode, copy the parent context
-			contextVars = make(map[string]interface{}, len(ctx.context)+len(n.variables))
-			for k, v := range ctx.context {
-				contextVars[k] = v
-			}
-		}
-
-		// Create a new context
-		includeCtx = NewRenderContext(ctx.env, contextVars, ctx.engine)
-		// Set the template as the lastLoadedTemplate for relative path resolutionn			includeCtx.lastLoadedTemplate = template
-		defer includeCtx.Release()
-
-		// If sandboxed, enable sandbox mode
-		if n.sandboxed {
-			includeCtx.sandboxed = true
-
-			// Check if a security policy is defined
-			if ctx.env.securityPolicy == nil {
-				return fmt.Errorf("cannot use sandboxed include without a security policy")
-			}
-		}
+		return err
 	}
+	defer includeCtx.Release()
 
-	// Pre-evaluate all variables before setting them
-	if len(n.variables) > 0 {
-		for name, valueNode := range n.variables {
-			value, err := ctx.EvaluateExpression(valueNode)
-			if err != nil {
-				return err
-			}
-			includeCtx.SetVariable(name, value)
-		}
-	}
+	// Set the template as the lastLoadedTemplate for relative path resolution
+	includeCtx.lastLoadedTemplate = template
+	includeCtx.templateStack = ctx.childTemplateStack(n.line)
 
 	// Render the included template
-	err = template.nodes.Render(w, includeCtx)
-
-	return err
+	out, done := ctx.trackOutput(w, "include", template.name, n.line)
+	err = template.nodes.Render(out, includeCtx)
+	done()
+	return includeCtx.wrapError(err, 0)
 }
 
 // SetNode represents a variable assignment
@@ -899,7 +828,7 @@ func (n *SetNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Evaluate the value
 	value, err := ctx.EvaluateExpression(n.value)
 	if err != nil {
-		return err
+		return ctx.wrapError(err, n.line)
 	}
 
 	// Set the variable in the context
@@ -935,7 +864,7 @@ func (n *DoNode) Release() {
 func (n *DoNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Evaluate the expression but ignore the result
 	_, err := ctx.EvaluateExpression(n.expression)
-	return err
+	return ctx.wrapError(err, n.line)
 }
 
 // CommentNode represents a comment
@@ -952,6 +881,11 @@ func (n *CommentNode) Line() int {
 	return n.line
 }
 
+// Content returns the text of the comment, between {# and #}
+func (n *CommentNode) Content() string {
+	return n.content
+}
+
 // Release returns a CommentNode to the pool
 func (n *CommentNode) Release() {
 	ReleaseCommentNode(n)
@@ -969,6 +903,8 @@ type MacroNode struct {
 	params   []string
 	defaults map[string]Node
 	body     []Node
+	returns  bool   // Whether the body has a return tag, see ReturnNode
+	doc      string // Text of the {# @doc ... #} comment before the macro
 	line     int
 }
 
@@ -1112,9 +1048,22 @@ func renderVariableString(text string, ctx *RenderContext, w io.Writer) error {
 
 // CallMacro calls the macro with the provided arguments
 func (n *MacroNode) CallMacro(w io.Writer, ctx *RenderContext, args ...interface{}) error {
+	_, err := n.call(w, ctx, args)
+	return err
+}
+
+// call renders the macro, returning the value of a return tag if one runs
+func (n *MacroNode) call(w io.Writer, ctx *RenderContext, args []interface{}) (interface{}, error) {
 	// Create a new context for the macro
 	macroCtx := NewRenderContext(ctx.env, nil, ctx.engine)
 	macroCtx.parent = ctx
+	macroCtx.lastLoadedTemplate = ctx.lastLoadedTemplate
+	macroCtx.templateStack = ctx.templateStack
+	macroCtx.profile = ctx.profile
+	macroCtx.options = ctx.options
+	macroCtx.visits = ctx.visits
+	macroCtx.labels = ctx.labels
+	macroCtx.features = ctx.features
 
 	// Ensure context is released even in error paths
 	defer macroCtx.Release()
@@ -1125,10 +1074,12 @@ func (n *MacroNode) CallMacro(w io.Writer, ctx *RenderContext, args ...interface
 			// If an argument was provided, use it
 			macroCtx.SetVariable(param, args[i])
 		} else if defaultVal, ok := n.defaults[param]; ok {
-			// Otherwise, use the default value if available
-			value, err := ctx.EvaluateExpression(defaultVal)
+			// Otherwise, use the default value if available. Defaults are
+			// evaluated in the macro's context, in order, so they can refer
+			// to earlier parameters, as in macro input(name, id = name)
+			value, err := macroCtx.EvaluateExpression(defaultVal)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			macroCtx.SetVariable(param, value)
 		} else {
@@ -1144,18 +1095,21 @@ func (n *MacroNode) CallMacro(w io.Writer, ctx *RenderContext, args ...interface
 			// This TextNode contains variable references that need processing
 			err := renderVariableString(textNode.content, macroCtx, w)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			// Standard rendering for other node types
-			err := node.Render(w, macroCtx)
+			err := macroCtx.render(w, node)
+			if value, ok := returnedValue(err); ok {
+				return value, nil
+			}
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	return nil, nil
 }
 
 // ImportNode represents a macro import
@@ -1213,23 +1167,16 @@ func (n *ImportNode) Render(w io.Writer, ctx *RenderContext) error {
 		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
 			template, err = ctx.engine.Load(templateName)
 			if err != nil {
-				return err
+				return ctx.wrapError(err, n.line)
 			}
 		} else {
 			// For any other error (including syntax errors), return immediately
-			return err
+			return ctx.wrapError(err, n.line)
 		}
 	}
 
-	// Create a new context for the imported template
-	importCtx := NewRenderContext(ctx.env, nil, ctx.engine)
-	// Set the template as the lastLoadedTemplate for relative path resolutionn	importCtx.lastLoadedTemplate = template
-
-	// Ensure context is released even in error paths
-	defer importCtx.Release()
-
-	// Render the imported template to capture its macros
-	err = template.nodes.Render(io.Discard, importCtx)
+	// Harvest the macros defined by the imported template
+	importedMacros, err := ctx.importMacros(template, n.line)
 	if err != nil {
 		return err
 	}
@@ -1237,8 +1184,8 @@ func (n *ImportNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Create a map for the macros
 	macros := make(map[string]interface{})
 
-	// Copy macros from import context to the map
-	for name, macro := range importCtx.macros {
+	// Copy the imported macros to the map
+	for name, macro := range importedMacros {
 		macros[name] = macro
 	}
 
@@ -1304,23 +1251,16 @@ func (n *FromImportNode) Render(w io.Writer, ctx *RenderContext) error {
 		if errors.Is(err, ErrTemplateNotFound) && resolvedName != templateName {
 			template, err = ctx.engine.Load(templateName)
 			if err != nil {
-				return err
+				return ctx.wrapError(err, n.line)
 			}
 		} else {
 			// For any other error (including syntax errors), return immediately
-			return err
+			return ctx.wrapError(err, n.line)
 		}
 	}
 
-	// Create a new context for the imported template
-	importCtx := NewRenderContext(ctx.env, nil, ctx.engine)
-	// Set the template as the lastLoadedTemplate for relative path resolutionn	importCtx.lastLoadedTemplate = template
-
-	// Ensure context is released even in error paths
-	defer importCtx.Release()
-
-	// Render the imported template to capture its macros
-	err = template.nodes.Render(io.Discard, importCtx)
+	// Harvest the macros defined by the imported template
+	importedMacros, err := ctx.importMacros(template, n.line)
 	if err != nil {
 		return err
 	}
@@ -1333,8 +1273,8 @@ func (n *FromImportNode) Render(w io.Writer, ctx *RenderContext) error {
 			targetName = alias
 		}
 
-		// Get the macro from the import context
-		macro, ok := importCtx.macros[macroName]
+		// Get the macro from the imported template
+		macro, ok := importedMacros[macroName]
 		if !ok {
 			return fmt.Errorf("macro '%s' not found in template '%s'", macroName, templateName)
 		}
@@ -1428,7 +1368,7 @@ func (n *ApplyNode) Render(w io.Writer, ctx *RenderContext) error {
 
 	// Render all body nodes
 	for _, node := range n.body {
-		err := node.Render(&buf, ctx)
+		err := ctx.render(&buf, node)
 		if err != nil {
 			return err
 		}
@@ -1478,6 +1418,7 @@ func (n *RootNode) Render(w io.Writer, ctx *RenderContext) error {
 			if !hasChildBlocks || ctx.blocks[block.name] == nil {
 				// Register the block
 				ctx.blocks[block.name] = block.body
+				ctx.setBlockOwner(block.name, ctx.lastLoadedTemplate)
 			}
 		} else if ext, ok := child.(*ExtendsNode); ok {
 			// If this is an extends node, record it for later
@@ -1495,7 +1436,7 @@ func (n *RootNode) Render(w io.Writer, ctx *RenderContext) error {
 	// For a regular template (not extending another), render all nodes
 	// This includes block nodes, which will use their default content unless overridden
 	for _, child := range n.children {
-		err := child.Render(w, ctx)
+		err := ctx.render(w, child)
 		if err != nil {
 			return err
 		}
@@ -1524,6 +1465,10 @@ func (n *RootNode) Children() []Node {
 func (n *TextNode) Render(w io.Writer, ctx *RenderContext) error {
 	// Simply write the original content without modification
 	// This preserves HTML flow and whitespace exactly as in the template
+	if n.data != nil {
+		_, err := w.Write(n.data)
+		return err
+	}
 	_, err := WriteString(w, n.content)
 	return err
 }
@@ -1559,7 +1504,7 @@ func (n *PrintNode) Render(w io.Writer, ctx *RenderContext) error {
 			message := fmt.Sprintf("Error evaluating print expression at line %d", n.line)
 			LogError(err, message)
 		}
-		return err
+		return ctx.wrapError(err, n.line)
 	}
 
 	// Check if result is a callable for macros
@@ -1581,6 +1526,23 @@ func (n *PrintNode) Render(w io.Writer, ctx *RenderContext) error {
 		return err
 	}
 
+	// Byte slices, readers and values writing themselves, such as csv
+	// exports, are copied to the writer without converting to string, so
+	// large fragments are not duplicated
+	switch v := result.(type) {
+	case []byte:
+		_, err = w.Write(v)
+		return err
+	case io.WriterTo:
+		_, err = v.WriteTo(w)
+		return err
+	case io.Reader:
+		// Printing streams a reader and consumes it, so it prints once.
+		// Filters and operators rewind readers that can seek.
+		_, err = io.Copy(w, v)
+		return err
+	}
+
 	// Convert result to string
 	var str string
 
@@ -18,6 +18,7 @@ var TextNodePool = sync.Pool{
 func GetTextNode(content string, line int) *TextNode {
 	node := TextNodePool.Get().(*TextNode)
 	node.content = content
+	node.data = nil
 	node.line = line
 	return node
 }
@@ -28,6 +29,7 @@ func ReleaseTextNode(node *TextNode) {
 		return
 	}
 	node.content = ""
+	node.data = nil
 	TextNodePool.Put(node)
 }
 
@@ -164,6 +166,7 @@ func ReleaseForNode(node *ForNode) {
 	}
 	node.keyVar = ""
 	node.valueVar = ""
+	node.targets = nil
 	node.sequence = nil
 	node.body = nil
 	node.elseBranch = nil
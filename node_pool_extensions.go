@@ -30,6 +30,7 @@ func ReleaseBlockNode(node *BlockNode) {
 	}
 	node.name = ""
 	node.body = nil
+	node.cache = nil
 	BlockNodePool.Put(node)
 }
 
@@ -153,6 +154,7 @@ func GetMacroNode(name string, params []string, defaults map[string]Node, body [
 	node.params = params
 	node.defaults = defaults
 	node.body = body
+	node.returns = containsReturn(body)
 	node.line = line
 	return node
 }
@@ -166,6 +168,8 @@ func ReleaseMacroNode(node *MacroNode) {
 	node.params = nil
 	node.defaults = nil
 	node.body = nil
+	node.returns = false
+	node.doc = ""
 	MacroNodePool.Put(node)
 }
 
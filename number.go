@@ -0,0 +1,96 @@
+package twig
+
+import (
+	"strconv"
+	"strings"
+)
+
+// scanNumber returns the end of the numeric literal starting at start:
+// decimal integers and floats with an optional exponent, 0x hexadecimal and
+// 0b binary integers, all with single underscores between digits
+func scanNumber(source string, start int) int {
+	pos := start
+	if pos < len(source) && source[pos] == '-' {
+		pos++
+	}
+
+	// Hexadecimal and binary integers
+	if pos+2 < len(source) && source[pos] == '0' {
+		switch source[pos+1] {
+		case 'x', 'X':
+			if end := scanDigits(source, pos+2, isHexDigit); end > pos+2 {
+				return end
+			}
+		case 'b', 'B':
+			if end := scanDigits(source, pos+2, isBinaryDigit); end > pos+2 {
+				return end
+			}
+		}
+	}
+
+	pos = scanDigits(source, pos, isDigit)
+
+	// A fraction needs a digit after the point, so 1..5 and items.0.name
+	// keep their dots
+	if pos+1 < len(source) && source[pos] == '.' && isDigit(source[pos+1]) {
+		pos = scanDigits(source, pos+1, isDigit)
+	}
+
+	// An exponent needs digits, so 2em stays a number and a name
+	if pos < len(source) && (source[pos] == 'e' || source[pos] == 'E') {
+		exp := pos + 1
+		if exp < len(source) && (source[exp] == '+' || source[exp] == '-') {
+			exp++
+		}
+		if exp < len(source) && isDigit(source[exp]) {
+			pos = scanDigits(source, exp, isDigit)
+		}
+	}
+
+	return pos
+}
+
+// scanDigits consumes digits accepted by valid, allowing an underscore
+// between two digits
+func scanDigits(source string, pos int, valid func(byte) bool) int {
+	for pos < len(source) {
+		c := source[pos]
+		if valid(c) {
+			pos++
+			continue
+		}
+		if c == '_' && pos > 0 && valid(source[pos-1]) && pos+1 < len(source) && valid(source[pos+1]) {
+			pos++
+			continue
+		}
+		break
+	}
+	return pos
+}
+
+// isHexDigit checks if a character is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return isDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isBinaryDigit checks if a character is 0 or 1
+func isBinaryDigit(c byte) bool {
+	return c == '0' || c == '1'
+}
+
+// parseNumber converts a numeric literal to an int, or a float64 when it
+// has a fraction or an exponent
+func parseNumber(literal string) (interface{}, error) {
+	value := strings.ReplaceAll(literal, "_", "")
+
+	digits := strings.TrimPrefix(value, "-")
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXbB", rune(digits[1])) {
+		n, err := strconv.ParseInt(value, 0, 64)
+		return int(n), err
+	}
+
+	if strings.ContainsAny(value, ".eE") {
+		return strconv.ParseFloat(value, 64)
+	}
+	return strconv.Atoi(value)
+}
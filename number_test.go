@@ -0,0 +1,68 @@
+package twig
+
+import "testing"
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{name: "underscores", source: `{{ 1_000_000 }}`, expected: "1000000"},
+		{name: "underscores in floats", source: `{{ 1_000.5 }}`, expected: "1000.5"},
+		{name: "hexadecimal", source: `{{ 0xFF }}`, expected: "255"},
+		{name: "hexadecimal with underscores", source: `{{ 0xff_ff }}`, expected: "65535"},
+		{name: "binary", source: `{{ 0b1010 }}`, expected: "10"},
+		{name: "scientific", source: `{{ 1.5e3 }}`, expected: "1500"},
+		{name: "negative exponent", source: `{{ 25e-2 }}`, expected: "0.25"},
+		{name: "signed exponent", source: `{{ 2E+2 }}`, expected: "200"},
+		{name: "arithmetic", source: `{{ 0x10 + 0b10 * 1e1 }}`, expected: "36"},
+		{name: "comparison", source: `{% if 1_000 == 1e3 %}yes{% endif %}`, expected: "yes"},
+		{name: "trailing underscore is not part of the number", source: `{% set _x = 1 %}{{ 1 ~ _x }}`, expected: "11"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"items": []string{"a", "b"}})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestScanNumber(t *testing.T) {
+	tests := []struct {
+		source string
+		length int
+	}{
+		{"123", 3},
+		{"1_000", 5},
+		{"1__0", 1},
+		{"1_", 1},
+		{"1.5", 3},
+		{"1..5", 1},
+		{"1.", 1},
+		{"1e3", 3},
+		{"2em", 1},
+		{"1e+", 1},
+		{"0x1F", 4},
+		{"0xG", 1},
+		{"0b102", 4},
+		{"-42", 3},
+	}
+
+	for _, tt := range tests {
+		if got := scanNumber(tt.source, 0); got != tt.length {
+			t.Errorf("scanNumber(%q) = %d, want %d", tt.source, got, tt.length)
+		}
+	}
+}
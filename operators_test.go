@@ -261,3 +261,55 @@ func TestLogicalOperators(t *testing.T) {
 		})
 	}
 }
+
+func TestNotOperator(t *testing.T) {
+	context := map[string]interface{}{
+		"n":     2,
+		"a":     true,
+		"b":     false,
+		"items": []int{},
+		"user":  map[string]interface{}{"role": "editor"},
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"parenthesized tests", "{% if not (user.role is same_as('admin') or user.role is same_as('editor')) %}y{% else %}n{% endif %}", "n"},
+		{"parenthesized tests false", "{% if not (n is odd or n is null) %}y{% else %}n{% endif %}", "y"},
+		{"negates the whole test", "{% if not n is even %}y{% else %}n{% endif %}", "n"},
+		{"test with arguments", "{% if not n is divisible_by(3) %}y{% else %}n{% endif %}", "y"},
+		{"defined", "{% if not missing is defined %}y{% else %}n{% endif %}", "y"},
+		{"negates the whole filter", "{% if not items|length %}y{% else %}n{% endif %}", "y"},
+		{"binds tighter than and", "{% if not b and a %}y{% else %}n{% endif %}", "y"},
+		{"binds tighter than or", "{% if not a or a %}y{% else %}n{% endif %}", "y"},
+		{"double negation", "{% if not not a %}y{% else %}n{% endif %}", "y"},
+		{"after and", "{% if a and not n is odd %}y{% else %}n{% endif %}", "y"},
+		{"in expressions", "{{ not (n > 1) ? 'y' : 'n' }}", "n"},
+		{"not in", "{% if n not in [1, 3] %}y{% else %}n{% endif %}", "y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// not only follows an operand as part of not in
+	engine := New()
+	if err := engine.RegisterString("page", "{% if missing not defined %}y{% endif %}"); err == nil ||
+		!strings.Contains(err.Error(), "is not") {
+		t.Errorf("Expected an error suggesting 'is not', got %v", err)
+	}
+}
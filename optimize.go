@@ -0,0 +1,91 @@
+package twig
+
+import (
+	"strings"
+)
+
+// optimizeNodes runs whole-template optimization passes over a parsed node list
+func optimizeNodes(nodes []Node) []Node {
+	return mergeTextNodes(nodes)
+}
+
+// mergeTextNodes merges runs of adjacent text nodes into a single node with
+// precomputed bytes, so each run is written with one Write call. Bodies of
+// nested nodes are merged recursively. The slice is compacted in place.
+func mergeTextNodes(nodes []Node) []Node {
+	merged := nodes[:0]
+
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
+
+		text, ok := node.(*TextNode)
+		if !ok {
+			mergeNestedTextNodes(node)
+			merged = append(merged, node)
+			continue
+		}
+
+		// Find the end of the run of text nodes
+		end := i + 1
+		for end < len(nodes) {
+			if _, ok := nodes[end].(*TextNode); !ok {
+				break
+			}
+			end++
+		}
+
+		if end-i > 1 {
+			var b strings.Builder
+			for _, n := range nodes[i:end] {
+				b.WriteString(n.(*TextNode).content)
+			}
+			text.content = b.String()
+
+			// The merged-away nodes are only referenced from this list
+			for _, n := range nodes[i+1 : end] {
+				ReleaseTextNode(n.(*TextNode))
+			}
+		}
+
+		text.data = []byte(text.content)
+		merged = append(merged, text)
+		i = end - 1
+	}
+
+	// Clear the tail so released nodes are not kept alive by the backing array
+	for i := len(merged); i < len(nodes); i++ {
+		nodes[i] = nil
+	}
+
+	return merged
+}
+
+// mergeNestedTextNodes merges text nodes inside the bodies of a node
+func mergeNestedTextNodes(node Node) {
+	switch n := node.(type) {
+	case *RootNode:
+		n.children = mergeTextNodes(n.children)
+	case *IfNode:
+		for i := range n.bodies {
+			n.bodies[i] = mergeTextNodes(n.bodies[i])
+		}
+		n.elseBranch = mergeTextNodes(n.elseBranch)
+	case *ForNode:
+		n.body = mergeTextNodes(n.body)
+		n.elseBranch = mergeTextNodes(n.elseBranch)
+	case *BlockNode:
+		n.body = mergeTextNodes(n.body)
+	case *MacroNode:
+		n.body = mergeTextNodes(n.body)
+	case *SpacelessNode:
+		n.body = mergeTextNodes(n.body)
+	case *ApplyNode:
+		n.body = mergeTextNodes(n.body)
+	case *EmbedNode:
+		for _, block := range n.blocks {
+			mergeNestedTextNodes(block)
+		}
+	case *WithNode:
+		n.body = mergeTextNodes(n.body)
+	}
+}
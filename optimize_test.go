@@ -0,0 +1,82 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestMergeTextNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Comment between text", "Hello {# comment #}world", "Hello world"},
+		{"Several comments", "a{# 1 #}b{# 2 #}c{# 3 #}d", "abcd"},
+		{"Inside if", "{% if true %}a{# x #}b{% endif %}", "ab"},
+		{"Inside for", "{% for i in [1, 2] %}<{# x #}{{ i }}{# y #}>{% endfor %}", "<1><2>"},
+		{"Inside block", "{% block content %}x{# y #}z{% endblock %}", "xz"},
+		{"Inside macro", "{% macro m() %}a{# b #}c{% endmacro %}{{ _self.m() }}", "ac"},
+		{"Inside with", "{% with %}a{# b #}c{% endwith %}", "ac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			// Render twice to make sure the merged nodes are reusable
+			for i := 0; i < 2; i++ {
+				result, err := engine.Render("test", nil)
+				if err != nil {
+					t.Fatalf("Error rendering template: %v", err)
+				}
+				if result != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, result)
+				}
+			}
+		})
+	}
+
+	t.Run("Adjacent text nodes merged", func(t *testing.T) {
+		parser := &Parser{}
+		node, err := parser.Parse("a{# 1 #}b{# 2 #}c{% if true %}d{# 3 #}e{% endif %}f")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		children := node.(*RootNode).children
+		if len(children) != 3 {
+			t.Fatalf("Expected 3 top-level nodes, got %d", len(children))
+		}
+
+		text, ok := children[0].(*TextNode)
+		if !ok || text.content != "abc" || string(text.data) != "abc" {
+			t.Errorf("Expected merged text node 'abc', got %v", children[0])
+		}
+
+		ifNode := children[1].(*IfNode)
+		if len(ifNode.bodies[0]) != 1 {
+			t.Errorf("Expected if body to be merged into 1 node, got %d", len(ifNode.bodies[0]))
+		}
+	})
+
+	t.Run("Text merged inside embed and with", func(t *testing.T) {
+		parser := &Parser{}
+		node, err := parser.Parse("{% embed 'card' %}{% block body %}a{# 1 #}b{% endblock %}{% endembed %}{% with %}c{# 2 #}d{% endwith %}")
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		children := node.(*RootNode).children
+		embed := children[0].(*EmbedNode)
+		if body := embed.blocks[0].body; len(body) != 1 || body[0].(*TextNode).content != "ab" {
+			t.Errorf("Expected the embedded block body to be merged into 'ab', got %v", body)
+		}
+		with := children[1].(*WithNode)
+		if len(with.body) != 1 || with.body[0].(*TextNode).content != "cd" {
+			t.Errorf("Expected the with body to be merged into 'cd', got %v", with.body)
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// OutputProfile breaks down the bytes a render wrote by the includes, embeds
+// and blocks that wrote them, to find the partials that make pages heavy.
+// Sizes are inclusive: an include counts the blocks and includes inside it.
+type OutputProfile struct {
+	Total   int64         // Bytes written by the whole render
+	Entries []OutputEntry // In the order the sections started
+
+	depth int // Nesting of the sections being rendered
+}
+
+// OutputEntry is the output of one include, embed or block
+type OutputEntry struct {
+	Kind     string // "include", "embed" or "block"
+	Name     string // Template included or embedded, or block name
+	Template string // Template containing the tag
+	Line     int    // Line of the tag
+	Depth    int    // Sections enclosing this one
+	Bytes    int64
+}
+
+// String formats the profile as an indented tree with the size of each
+// section
+func (p *OutputProfile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%10d  total\n", p.Total)
+	for _, entry := range p.Entries {
+		fmt.Fprintf(&b, "%10d  %s%s %s (%s:%d)\n", entry.Bytes,
+			strings.Repeat("  ", entry.Depth+1), entry.Kind, entry.Name, entry.Template, entry.Line)
+	}
+	return b.String()
+}
+
+// SetMaxIncludeOutput limits the bytes a single include or embed may write.
+// An include writing more fails the render with ErrOutputTooLarge, so a
+// runaway partial is caught instead of sending megabytes of HTML. Zero, the
+// default, means no limit.
+func (e *Engine) SetMaxIncludeOutput(limit int64) {
+	if limit < 0 {
+		limit = 0
+	}
+	e.maxIncludeOutput = limit
+}
+
+// RenderProfile renders a template like Render and reports the bytes
+// written by each include, embed and block
+func (e *Engine) RenderProfile(name string, context map[string]interface{}) (string, *OutputProfile, error) {
+	template, err := e.loadResolved(name, context)
+	if err != nil {
+		template, err = e.loadFallback(err)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := NewStringBuffer()
+	defer buf.Release()
+
+	profile := &OutputProfile{}
+	if err := template.renderProfiled(buf, context, profile, nil); err != nil {
+		return "", profile, err
+	}
+	return buf.String(), profile, nil
+}
+
+// renderProfiled renders the template, recording its output in profile
+func (t *Template) renderProfiled(w io.Writer, context map[string]interface{}, profile *OutputProfile, options *RenderOptions) error {
+	ctx := newRootContext(t.env, context, t.engine)
+	defer ctx.Release()
+
+	ctx.lastLoadedTemplate = t
+	ctx.profile = profile
+	ctx.options = options
+	defer ctx.labelSection("template", t.name)()
+
+	counter := &outputCounter{w: w}
+	err := t.nodes.Render(counter, ctx)
+	profile.Total = counter.n
+	return err
+}
+
+// trackOutput wraps w to count the output of an include, embed or block,
+// enforcing the engine's include output limit. The returned function
+// records the count once the section is rendered. Without a profile or a
+// limit w is returned as is. The section is also labelled for pprof, see
+// SetPprofLabels.
+func (ctx *RenderContext) trackOutput(w io.Writer, kind, name string, line int) (io.Writer, func()) {
+	unlabel := ctx.labelSection(kind, name)
+
+	var limit int64
+	if kind != "block" && ctx.engine != nil {
+		limit = ctx.engine.maxIncludeOutput
+	}
+	profile := ctx.profile
+	if profile == nil && limit == 0 {
+		return w, unlabel
+	}
+
+	counter := &outputCounter{w: w, limit: limit, kind: kind, name: name}
+	if profile == nil {
+		return counter, unlabel
+	}
+
+	var template string
+	if ctx.lastLoadedTemplate != nil {
+		template = ctx.lastLoadedTemplate.name
+	}
+	index := len(profile.Entries)
+	profile.Entries = append(profile.Entries, OutputEntry{
+		Kind:     kind,
+		Name:     name,
+		Template: template,
+		Line:     line,
+		Depth:    profile.depth,
+	})
+	profile.depth++
+
+	return counter, func() {
+		profile.depth--
+		profile.Entries[index].Bytes = counter.n
+		unlabel()
+	}
+}
+
+// outputCounter counts the bytes written through it, failing writes that
+// would exceed limit when one is set
+type outputCounter struct {
+	w     io.Writer
+	n     int64
+	limit int64
+	kind  string
+	name  string
+}
+
+func (c *outputCounter) Write(p []byte) (int, error) {
+	if c.limit > 0 && c.n+int64(len(p)) > c.limit {
+		return 0, fmt.Errorf("%w: %s '%s' wrote more than %d bytes", ErrOutputTooLarge, c.kind, c.name, c.limit)
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
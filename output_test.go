@@ -0,0 +1,87 @@
+package twig
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRenderProfile(t *testing.T) {
+	engine := New()
+	sources := map[string]string{
+		"base":   `<html>{% block body %}{% endblock %}</html>`,
+		"header": `<header>{{ title }}</header>`,
+		"card":   `<div>{% block content %}{% endblock %}</div>`,
+		"page":   `{% extends "base" %}{% block body %}{% include "header" %}{% embed "card" %}{% block content %}hi{% endblock %}{% endembed %}{% endblock %}`,
+	}
+	for name, source := range sources {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	result, profile, err := engine.RenderProfile("page", map[string]interface{}{"title": "Home"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "<html><header>Home</header><div>hi</div></html>" {
+		t.Errorf("Unexpected output %q", result)
+	}
+	if profile.Total != int64(len(result)) {
+		t.Errorf("Expected total %d, got %d", len(result), profile.Total)
+	}
+
+	expected := []OutputEntry{
+		{Kind: "block", Name: "body", Template: "page", Line: 1, Depth: 0, Bytes: 34},
+		{Kind: "include", Name: "header", Template: "page", Line: 1, Depth: 1, Bytes: 21},
+		{Kind: "embed", Name: "card", Template: "page", Line: 1, Depth: 1, Bytes: 13},
+		{Kind: "block", Name: "content", Template: "page", Line: 1, Depth: 2, Bytes: 2},
+	}
+	if len(profile.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), profile.Entries)
+	}
+	for i, entry := range profile.Entries {
+		if entry != expected[i] {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, expected[i], entry)
+		}
+	}
+
+	if !strings.Contains(profile.String(), "include header (page:1)") {
+		t.Errorf("Expected the include in the profile text, got:\n%s", profile)
+	}
+}
+
+func TestMaxIncludeOutput(t *testing.T) {
+	engine := New()
+	engine.SetMaxIncludeOutput(10)
+	sources := map[string]string{
+		"small": `short`,
+		"large": `{% for i in range(1, 20) %}x{% endfor %}`,
+		"ok":    `{% include "small" %}{% include "small" %}{% include "small" %}`,
+		"fail":  `{% include "small" %}{% include "large" %}`,
+	}
+	for name, source := range sources {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing template %s: %v", name, err)
+		}
+	}
+
+	// The limit applies to each include, not to the page
+	result, err := engine.Render("ok", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "shortshortshort" {
+		t.Errorf("Unexpected output %q", result)
+	}
+
+	var buf bytes.Buffer
+	err = engine.RenderTo(&buf, "fail", nil)
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Fatalf("Expected ErrOutputTooLarge, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "large") {
+		t.Errorf("Expected the error to name the include, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PaginationMacros is a macro library rendering pagers returned by the
+// paginate function. Register it under a name of your choice:
+//
+//	engine.RegisterString("pagination.twig", twig.PaginationMacros)
+//
+// and import it in list templates:
+//
+//	{% import "pagination.twig" as pagination %}
+//	{{ pagination.links(pager, "/posts?page=") }}
+const PaginationMacros = `{% macro links(pager, url, size) %}{% if pager.pages > 1 %}<nav class="pagination"><ul>
+{% if pager.has_prev %}<li class="prev"><a href="{{ url }}{{ pager.prev }}">&laquo;</a></li>{% endif %}
+{% for page in pager.window(size|default(5)) %}{% if page == pager.current %}<li class="current"><span>{{ page }}</span></li>{% else %}<li><a href="{{ url }}{{ page }}">{{ page }}</a></li>{% endif %}{% endfor %}
+{% if pager.has_next %}<li class="next"><a href="{{ url }}{{ pager.next }}">&raquo;</a></li>{% endif %}
+</ul></nav>{% endif %}{% endmacro %}`
+
+// newPager slices items for the given page and returns the pager map used by
+// templates. Pages are 1-based and out of range pages are clamped.
+func newPager(items interface{}, page, perPage int) (map[string]interface{}, error) {
+	if perPage <= 0 {
+		return nil, fmt.Errorf("paginate: items per page must be positive, got %d", perPage)
+	}
+
+	var all []interface{}
+	if items != nil {
+		v := reflect.ValueOf(items)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, fmt.Errorf("paginate: cannot paginate %T", items)
+		}
+		all = make([]interface{}, v.Len())
+		for i := range all {
+			all[i] = v.Index(i).Interface()
+		}
+	}
+
+	total := len(all)
+	pages := (total + perPage - 1) / perPage
+	if pages < 1 {
+		pages = 1
+	}
+	if page < 1 {
+		page = 1
+	} else if page > pages {
+		page = pages
+	}
+
+	start := (page - 1) * perPage
+	end := start + perPage
+	if end > total {
+		end = total
+	}
+
+	prev, next := 0, 0
+	if page > 1 {
+		prev = page - 1
+	}
+	if page < pages {
+		next = page + 1
+	}
+
+	return map[string]interface{}{
+		"items":    all[start:end],
+		"total":    total,
+		"per_page": perPage,
+		"pages":    pages,
+		"current":  page,
+		"has_prev": page > 1,
+		"has_next": page < pages,
+		"prev":     prev,
+		"next":     next,
+		"window": func(args ...interface{}) (interface{}, error) {
+			size := 5
+			if len(args) > 0 {
+				n, err := toInt(args[0])
+				if err != nil {
+					return nil, fmt.Errorf("window: %w", err)
+				}
+				size = n
+			}
+			return pageWindow(page, pages, size), nil
+		},
+	}, nil
+}
+
+// pageWindow returns up to size page numbers centered on the current page
+func pageWindow(current, pages, size int) []int {
+	if size <= 0 {
+		return []int{}
+	}
+	if size > pages {
+		size = pages
+	}
+
+	first := current - size/2
+	if first < 1 {
+		first = 1
+	}
+	if first+size-1 > pages {
+		first = pages - size + 1
+	}
+
+	window := make([]int, size)
+	for i := range window {
+		window[i] = first + i
+	}
+	return window
+}
+
+// paginateArgs converts the page and per page arguments, defaulting to the
+// first page of 10 items
+func paginateArgs(args []interface{}) (int, int, error) {
+	page, perPage := 1, 10
+
+	if len(args) > 0 && args[0] != nil {
+		n, err := toInt(args[0])
+		if err != nil {
+			return 0, 0, fmt.Errorf("paginate: invalid page: %w", err)
+		}
+		page = n
+	}
+	if len(args) > 1 && args[1] != nil {
+		n, err := toInt(args[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("paginate: invalid items per page: %w", err)
+		}
+		perPage = n
+	}
+
+	return page, perPage, nil
+}
+
+// functionPaginate implements paginate(items, page, perPage)
+func (e *CoreExtension) functionPaginate(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("paginate function requires an items argument")
+	}
+
+	page, perPage, err := paginateArgs(args[1:])
+	if err != nil {
+		return nil, err
+	}
+	return newPager(args[0], page, perPage)
+}
+
+// filterPaginate implements items|paginate(page, perPage)
+func (e *CoreExtension) filterPaginate(value interface{}, args ...interface{}) (interface{}, error) {
+	page, perPage, err := paginateArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return newPager(value, page, perPage)
+}
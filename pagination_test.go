@@ -0,0 +1,126 @@
+package twig
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23}
+
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "function slices items",
+			source:   `{% set pager = paginate(items, 2, 5) %}{{ pager.items|join(",") }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "6,7,8,9,10",
+		},
+		{
+			name:     "filter form",
+			source:   `{% set pager = items|paginate(5, 5) %}{{ pager.items|join(",") }} {{ pager.current }}/{{ pager.pages }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "21,22,23 5/5",
+		},
+		{
+			name:     "navigation flags",
+			source:   `{% set pager = paginate(items, 1, 10) %}{{ pager.has_prev ? "y" : "n" }}{{ pager.has_next ? "y" : "n" }} {{ pager.next }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "ny 2",
+		},
+		{
+			name:     "page is clamped",
+			source:   `{% set pager = paginate(items, 99, 10) %}{{ pager.current }} {{ pager.has_next ? "y" : "n" }} {{ pager.prev }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "3 n 2",
+		},
+		{
+			name:     "empty list has one page",
+			source:   `{% set pager = paginate(items, 1, 10) %}{{ pager.pages }} {{ pager.total }} {{ pager.items|length }}`,
+			context:  map[string]interface{}{"items": []string{}},
+			expected: "1 0 0",
+		},
+		{
+			name:     "window",
+			source:   `{% set pager = paginate(items, 12, 1) %}{{ pager.window(5)|join(",") }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "10,11,12,13,14",
+		},
+		{
+			name:     "window at the end",
+			source:   `{% set pager = paginate(items, 23, 1) %}{{ pager.window(4)|join(",") }}`,
+			context:  map[string]interface{}{"items": items},
+			expected: "20,21,22,23",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestPageWindow(t *testing.T) {
+	tests := []struct {
+		current, pages, size int
+		expected             []int
+	}{
+		{1, 10, 5, []int{1, 2, 3, 4, 5}},
+		{5, 10, 5, []int{3, 4, 5, 6, 7}},
+		{10, 10, 5, []int{6, 7, 8, 9, 10}},
+		{2, 3, 5, []int{1, 2, 3}},
+		{1, 1, 0, []int{}},
+	}
+
+	for _, tt := range tests {
+		got := pageWindow(tt.current, tt.pages, tt.size)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("pageWindow(%d, %d, %d) = %v, want %v", tt.current, tt.pages, tt.size, got, tt.expected)
+		}
+	}
+}
+
+func TestPaginationMacros(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("pagination.twig", PaginationMacros); err != nil {
+		t.Fatalf("Error parsing macros: %v", err)
+	}
+	source := `{% import "pagination.twig" as pagination %}{{ pagination.links(paginate(items, 2, 2), "/posts?page=") }}`
+	if err := engine.RegisterString("list", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := engine.Render("list", map[string]interface{}{"items": []int{1, 2, 3, 4, 5}})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	for _, want := range []string{
+		`<li class="prev"><a href="/posts?page=1">`,
+		`<li class="current"><span>2</span></li>`,
+		`<li><a href="/posts?page=3">3</a></li>`,
+		`<li class="next"><a href="/posts?page=3">`,
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, result)
+		}
+	}
+}
@@ -14,6 +14,40 @@ func (p *Parser) parseBlock(parser *Parser) (Node, error) {
 	blockName := parser.tokens[parser.tokenIndex].Value
 	parser.tokenIndex++
 
+	// Optional cache(ttl, vary=[...]) annotation, see block_cache.go
+	var cache *blockCache
+	if parser.tokenIndex+1 < len(parser.tokens) &&
+		parser.tokens[parser.tokenIndex].Type == TOKEN_NAME &&
+		parser.tokens[parser.tokenIndex].Value == "cache" &&
+		parser.tokens[parser.tokenIndex+1].Type == TOKEN_PUNCTUATION &&
+		parser.tokens[parser.tokenIndex+1].Value == "(" {
+		parser.tokenIndex++
+		var err error
+		if cache, err = parser.parseBlockCache(blockName, blockLine); err != nil {
+			return nil, err
+		}
+	}
+
+	// Short form {% block title page.title %}: a block printing one
+	// expression, without an endblock
+	if cache == nil && parser.tokenIndex < len(parser.tokens) && parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END {
+		expr, err := parser.parseExpression()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing expression in block %s at line %d: %w", blockName, blockLine, err)
+		}
+
+		if parser.tokenIndex >= len(parser.tokens) || parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END {
+			return nil, fmt.Errorf("expected block end token after block expression at line %d", blockLine)
+		}
+		parser.tokenIndex++
+
+		return &BlockNode{
+			name: blockName,
+			body: []Node{NewPrintNode(expr, blockLine)},
+			line: blockLine,
+		}, nil
+	}
+
 	// Expect the block end token
 	if parser.tokenIndex >= len(parser.tokens) || parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END {
 		return nil, fmt.Errorf("expected block end token after block name at line %d", blockLine)
@@ -56,9 +90,10 @@ func (p *Parser) parseBlock(parser *Parser) (Node, error) {
 
 	// Create the block node
 	blockNode := &BlockNode{
-		name: blockName,
-		body: blockBody,
-		line: blockLine,
+		name:  blockName,
+		body:  blockBody,
+		cache: cache,
+		line:  blockLine,
 	}
 
 	return blockNode, nil
@@ -0,0 +1,112 @@
+package twig
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// DefaultParseCacheSize is the number of parsed sources kept by default
+const DefaultParseCacheSize = 512
+
+// parseCache shares parsed node trees between engines, keyed by the SHA-256
+// of the source. Node trees are not modified after parsing, so templates
+// registered from identical strings, in one engine or many, can render the
+// same tree. The least recently used entries are evicted when full.
+type parseCache struct {
+	mu      sync.Mutex
+	maxSize int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // Most recently used first
+}
+
+// parseCacheEntry is the value stored in the parse cache's list
+type parseCacheEntry struct {
+	key   [sha256.Size]byte
+	nodes Node
+}
+
+// sharedParseCache is the parse cache used by RegisterString and ParseTemplate
+var sharedParseCache = &parseCache{
+	maxSize: DefaultParseCacheSize,
+	entries: make(map[[sha256.Size]byte]*list.Element),
+	order:   list.New(),
+}
+
+// SetParseCacheSize sets how many parsed sources RegisterString and
+// ParseTemplate keep for reuse. Zero disables the cache.
+func SetParseCacheSize(size int) {
+	c := sharedParseCache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+	c.maxSize = size
+	for c.order.Len() > size {
+		c.evictOldest()
+	}
+}
+
+// get returns the cached tree for key
+func (c *parseCache) get(key [sha256.Size]byte) (Node, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*parseCacheEntry).nodes, true
+}
+
+// put stores the tree parsed from the source with key
+func (c *parseCache) put(key [sha256.Size]byte, nodes Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxSize == 0 {
+		return
+	}
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.maxSize {
+		c.evictOldest()
+	}
+	c.entries[key] = c.order.PushFront(&parseCacheEntry{key: key, nodes: nodes})
+}
+
+// evictOldest removes the least recently used entry. The caller holds c.mu.
+func (c *parseCache) evictOldest() {
+	elem := c.order.Back()
+	if elem == nil {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*parseCacheEntry).key)
+}
+
+// parseCached parses a prepared source, reusing the tree of an identical
+// source parsed before
+func (e *Engine) parseCached(source string) (Node, error) {
+	key := sha256.Sum256([]byte(source))
+	if e.retainComments {
+		// Trees with comments differ from those without
+		key[0] ^= 0xff
+	}
+	if nodes, ok := sharedParseCache.get(key); ok {
+		return nodes, nil
+	}
+
+	nodes, err := e.newParser().Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	sharedParseCache.put(key, nodes)
+	return nodes, nil
+}
@@ -0,0 +1,73 @@
+package twig
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+)
+
+func TestParseCacheSharesTrees(t *testing.T) {
+	source := `{% for n in items %}{{ n }}{% endfor %} parse cache test`
+
+	first := New()
+	second := New()
+	if err := first.RegisterString("a", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := second.RegisterString("b", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	a, _ := first.Load("a")
+	b, _ := second.Load("b")
+	if a.nodes != b.nodes {
+		t.Error("Expected identical sources to share the parsed tree")
+	}
+
+	for name, engine := range map[string]*Engine{"a": first, "b": second} {
+		result, err := engine.Render(name, map[string]interface{}{"items": []int{1, 2}})
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "12 parse cache test" {
+			t.Errorf("Unexpected result %q", result)
+		}
+	}
+}
+
+func TestParseCacheEviction(t *testing.T) {
+	defer SetParseCacheSize(DefaultParseCacheSize)
+	SetParseCacheSize(2)
+
+	engine := New()
+	for i := 0; i < 3; i++ {
+		if err := engine.RegisterString("t", fmt.Sprintf("eviction %d", i)); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+	}
+
+	if _, ok := sharedParseCache.get(sha256.Sum256([]byte("eviction 0"))); ok {
+		t.Error("Expected the oldest source to be evicted")
+	}
+	if _, ok := sharedParseCache.get(sha256.Sum256([]byte("eviction 2"))); !ok {
+		t.Error("Expected the newest source to be cached")
+	}
+
+	SetParseCacheSize(0)
+	if err := engine.RegisterString("t", "eviction 3"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, ok := sharedParseCache.get(sha256.Sum256([]byte("eviction 3"))); ok {
+		t.Error("Expected a disabled cache to store nothing")
+	}
+}
+
+func BenchmarkRegisterStringCached(b *testing.B) {
+	source := `<ul>{% for item in items %}<li>{{ item.name|upper }}</li>{% endfor %}</ul>`
+	for i := 0; i < b.N; i++ {
+		engine := New()
+		if err := engine.RegisterString("list", source); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -26,3 +26,36 @@ func (p *Parser) parseExtends(parser *Parser) (Node, error) {
 
 	return extendsNode, nil
 }
+
+// checkExtendsPlacement rejects extends tags nested in other tags. Extends
+// applies to the whole template, so one inside an if or a block cannot
+// choose a layout; the condition belongs in the tag's expression.
+func checkExtendsPlacement(nodes []Node) error {
+	for _, node := range nodes {
+		if _, ok := node.(*ExtendsNode); ok {
+			continue
+		}
+
+		finder := &extendsFinder{}
+		Walk(node, finder)
+		if finder.found != nil {
+			return fmt.Errorf("extends at line %d must be at the top level of the template, not inside another tag; "+
+				"to choose the layout at render time use {%% extends condition ? 'a.twig' : 'b.twig' %%}", finder.found.line)
+		}
+	}
+	return nil
+}
+
+// extendsFinder finds the first extends tag of a tree
+type extendsFinder struct {
+	found *ExtendsNode
+}
+
+func (f *extendsFinder) Enter(node Node) bool {
+	if extends, ok := node.(*ExtendsNode); ok && f.found == nil {
+		f.found = extends
+	}
+	return f.found == nil
+}
+
+func (f *extendsFinder) Leave(node Node) {}
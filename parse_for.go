@@ -8,24 +8,24 @@ import (
 // Examples:
 // {% for item in items %}...{% endfor %}
 // {% for key, value in items %}...{% endfor %}
+// {% for [x, y] in points %}...{% endfor %}
 // {% for item in items %}...{% else %}...{% endfor %}
+// {% for row in items in groups of 3 fill '-' %}...{% endfor %}
 func (p *Parser) parseFor(parser *Parser) (Node, error) {
 	// Get the line number of the for token
 	forLine := parser.tokens[parser.tokenIndex-2].Line
 
 	// Parse the loop variable name(s)
-	if parser.tokenIndex >= len(parser.tokens) || parser.tokens[parser.tokenIndex].Type != TOKEN_NAME {
-		return nil, fmt.Errorf("expected variable name after for at line %d", forLine)
+	valueVar, targets, err := parser.parseForTarget(forLine)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get value variable name
-	valueVar := parser.tokens[parser.tokenIndex].Value
-	parser.tokenIndex++
-
 	var keyVar string
 
 	// Check for key, value syntax
-	if parser.tokenIndex < len(parser.tokens) &&
+	if targets == nil &&
+		parser.tokenIndex < len(parser.tokens) &&
 		parser.tokens[parser.tokenIndex].Type == TOKEN_PUNCTUATION &&
 		parser.tokens[parser.tokenIndex].Value == "," {
 
@@ -35,12 +35,9 @@ func (p *Parser) parseFor(parser *Parser) (Node, error) {
 		// Now valueVar is actually the key, and we need to get the value
 		keyVar = valueVar
 
-		if parser.tokenIndex >= len(parser.tokens) || parser.tokens[parser.tokenIndex].Type != TOKEN_NAME {
-			return nil, fmt.Errorf("expected value variable name after comma at line %d", forLine)
+		if valueVar, targets, err = parser.parseForTarget(forLine); err != nil {
+			return nil, err
 		}
-
-		valueVar = parser.tokens[parser.tokenIndex].Value
-		parser.tokenIndex++
 	}
 
 	// Expect 'in' keyword
@@ -51,11 +48,29 @@ func (p *Parser) parseFor(parser *Parser) (Node, error) {
 	}
 	parser.tokenIndex++
 
-	// Parse the sequence expression
-	sequence, err := parser.parseExpression()
+	// Parse the sequence expression, which ends before "in groups of"
+	groups := parser.findGroupsOf()
+	var sequence Node
+	if groups < 0 {
+		sequence, err = parser.parseExpression()
+	} else {
+		tokens := parser.tokens
+		parser.tokens = tokens[:groups]
+		sequence, err = parser.parseExpression()
+		parser.tokens = tokens
+		if err == nil && parser.tokenIndex != groups {
+			err = fmt.Errorf("unexpected %s in for loop sequence at line %d", parser.tokens[parser.tokenIndex].Value, forLine)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
+	if groups >= 0 {
+		parser.tokenIndex = groups + 3
+		if sequence, err = parser.parseGroupsOf(sequence, forLine); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check for filter operator (|) - needed for cases where filter detection might be missed
 	if IsDebugEnabled() {
@@ -133,6 +148,7 @@ func (p *Parser) parseFor(parser *Parser) (Node, error) {
 	forNode := &ForNode{
 		keyVar:     keyVar,
 		valueVar:   valueVar,
+		targets:    targets,
 		sequence:   sequence,
 		body:       loopBody,
 		elseBranch: elseBody,
@@ -141,3 +157,51 @@ func (p *Parser) parseFor(parser *Parser) (Node, error) {
 
 	return forNode, nil
 }
+
+// findGroupsOf returns the index of "in groups of" in the rest of a for
+// tag, or -1 when the loop does not use it
+func (p *Parser) findGroupsOf() int {
+	depth := 0
+	for i := p.tokenIndex; i < len(p.tokens) && !isBlockEndToken(p.tokens[i].Type); i++ {
+		token := p.tokens[i]
+		if token.Type == TOKEN_PUNCTUATION {
+			switch token.Value {
+			case "(", "[", "{":
+				depth++
+			case ")", "]", "}":
+				depth--
+			}
+			continue
+		}
+		if depth == 0 && i+2 < len(p.tokens) &&
+			token.Type == TOKEN_NAME && token.Value == "in" &&
+			p.tokens[i+1].Type == TOKEN_NAME && p.tokens[i+1].Value == "groups" &&
+			p.tokens[i+2].Type == TOKEN_NAME && p.tokens[i+2].Value == "of" {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseGroupsOf parses the size and optional fill value after "in groups
+// of", turning the sequence into sequence|batch(size, fill)
+func (p *Parser) parseGroupsOf(sequence Node, line int) (Node, error) {
+	size, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	args := []Node{size}
+
+	if p.tokenIndex < len(p.tokens) &&
+		p.tokens[p.tokenIndex].Type == TOKEN_NAME &&
+		p.tokens[p.tokenIndex].Value == "fill" {
+		p.tokenIndex++
+		fill, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, fill)
+	}
+
+	return NewFilterNode(sequence, "batch", args, line), nil
+}
@@ -15,120 +15,100 @@ func (p *Parser) parseInclude(parser *Parser) (Node, error) {
 		return nil, err
 	}
 
-	// Check for optional parameters
-	var variables map[string]Node
-	var ignoreMissing bool
-	var onlyContext bool
-	var sandboxed bool
+	options, err := parser.parseIncludeOptions("include", includeLine)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expect the block end token
+	if parser.tokenIndex >= len(parser.tokens) ||
+		(parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END &&
+			parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END_TRIM) {
+		return nil, fmt.Errorf("expected block end token after include at line %d, found token type %d with value '%s'",
+			includeLine,
+			parser.tokens[parser.tokenIndex].Type,
+			parser.tokens[parser.tokenIndex].Value)
+	}
+	parser.tokenIndex++
+
+	// Create the include node
+	includeNode := &IncludeNode{
+		template:      templateExpr,
+		variables:     options.variables,
+		ignoreMissing: options.ignoreMissing,
+		only:          options.only,
+		sandboxed:     options.sandboxed,
+		line:          includeLine,
+	}
+
+	return includeNode, nil
+}
+
+// includeOptions are the options of an include or embed tag
+type includeOptions struct {
+	variables     map[string]Node
+	ignoreMissing bool
+	only          bool
+	sandboxed     bool
+}
+
+// parseIncludeOptions parses the with, ignore missing, only and sandboxed
+// options following the template name of an include or embed tag
+func (p *Parser) parseIncludeOptions(tag string, includeLine int) (includeOptions, error) {
+	var options includeOptions
 
 	// Look for 'with', 'ignore missing', or 'only'
-	for parser.tokenIndex < len(parser.tokens) &&
-		parser.tokens[parser.tokenIndex].Type == TOKEN_NAME {
+	for p.tokenIndex < len(p.tokens) &&
+		p.tokens[p.tokenIndex].Type == TOKEN_NAME {
 
-		keyword := parser.tokens[parser.tokenIndex].Value
-		parser.tokenIndex++
+		keyword := p.tokens[p.tokenIndex].Value
+		p.tokenIndex++
 
 		switch keyword {
 		case "with":
 			// Parse variables as a hash
-			if variables == nil {
-				variables = make(map[string]Node)
+			if options.variables == nil {
+				options.variables = make(map[string]Node)
 			}
 
 			// Check for opening brace
-			if parser.tokenIndex < len(parser.tokens) &&
-				parser.tokens[parser.tokenIndex].Type == TOKEN_PUNCTUATION &&
-				parser.tokens[parser.tokenIndex].Value == "{" {
-				parser.tokenIndex++ // Skip opening brace
-
-				// Parse key-value pairs
-				for {
-					// If we see a closing brace, we're done
-					if parser.tokenIndex < len(parser.tokens) &&
-						parser.tokens[parser.tokenIndex].Type == TOKEN_PUNCTUATION &&
-						parser.tokens[parser.tokenIndex].Value == "}" {
-						parser.tokenIndex++ // Skip closing brace
-						break
-					}
-
-					// Get the variable name - can be string literal or name token
-					var varName string
-					if parser.tokenIndex < len(parser.tokens) && parser.tokens[parser.tokenIndex].Type == TOKEN_STRING {
-						// It's a quoted string key
-						varName = parser.tokens[parser.tokenIndex].Value
-						parser.tokenIndex++
-					} else if parser.tokenIndex < len(parser.tokens) && parser.tokens[parser.tokenIndex].Type == TOKEN_NAME {
-						// It's an unquoted key
-						varName = parser.tokens[parser.tokenIndex].Value
-						parser.tokenIndex++
-					} else {
-						return nil, fmt.Errorf("expected variable name or string at line %d", includeLine)
-					}
-
-					// Expect colon or equals
-					if parser.tokenIndex >= len(parser.tokens) ||
-						((parser.tokens[parser.tokenIndex].Type != TOKEN_PUNCTUATION &&
-							parser.tokens[parser.tokenIndex].Value != ":") &&
-							(parser.tokens[parser.tokenIndex].Type != TOKEN_OPERATOR &&
-								parser.tokens[parser.tokenIndex].Value != "=")) {
-						return nil, fmt.Errorf("expected ':' or '=' after variable name at line %d", includeLine)
-					}
-					parser.tokenIndex++ // Skip : or =
-
-					// Parse the value expression
-					varExpr, err := parser.parseExpression()
-					if err != nil {
-						return nil, err
-					}
-
-					// Add to variables map
-					variables[varName] = varExpr
-
-					// If there's a comma, skip it
-					if parser.tokenIndex < len(parser.tokens) &&
-						parser.tokens[parser.tokenIndex].Type == TOKEN_PUNCTUATION &&
-						parser.tokens[parser.tokenIndex].Value == "," {
-						parser.tokenIndex++
-					}
-
-					// If we see whitespace, skip it
-					for parser.tokenIndex < len(parser.tokens) &&
-						parser.tokens[parser.tokenIndex].Type == TOKEN_TEXT &&
-						strings.TrimSpace(parser.tokens[parser.tokenIndex].Value) == "" {
-						parser.tokenIndex++
-					}
+			if p.tokenIndex < len(p.tokens) &&
+				p.tokens[p.tokenIndex].Type == TOKEN_PUNCTUATION &&
+				p.tokens[p.tokenIndex].Value == "{" {
+				if err := p.parseScopeVariables(options.variables, includeLine); err != nil {
+					return options, err
 				}
 			} else {
 				// If there's no opening brace, expect name-value pairs in the old format
-				for parser.tokenIndex < len(parser.tokens) &&
-					parser.tokens[parser.tokenIndex].Type == TOKEN_NAME {
+				for p.tokenIndex < len(p.tokens) &&
+					p.tokens[p.tokenIndex].Type == TOKEN_NAME {
 
 					// Get the variable name
-					varName := parser.tokens[parser.tokenIndex].Value
-					parser.tokenIndex++
+					varName := p.tokens[p.tokenIndex].Value
+					p.tokenIndex++
 
 					// Expect '='
-					if parser.tokenIndex >= len(parser.tokens) ||
-						parser.tokens[parser.tokenIndex].Type != TOKEN_OPERATOR ||
-						parser.tokens[parser.tokenIndex].Value != "=" {
-						return nil, fmt.Errorf("expected '=' after variable name at line %d", includeLine)
+					if p.tokenIndex >= len(p.tokens) ||
+						p.tokens[p.tokenIndex].Type != TOKEN_OPERATOR ||
+						p.tokens[p.tokenIndex].Value != "=" {
+						return options, fmt.Errorf("expected '=' after variable name at line %d", includeLine)
 					}
-					parser.tokenIndex++
+					p.tokenIndex++
 
 					// Parse the value expression
-					varExpr, err := parser.parseExpression()
+					varExpr, err := p.parseExpression()
 					if err != nil {
-						return nil, err
+						return options, err
 					}
 
 					// Add to variables map
-					variables[varName] = varExpr
+					options.variables[varName] = varExpr
 
 					// If there's a comma, skip it
-					if parser.tokenIndex < len(parser.tokens) &&
-						parser.tokens[parser.tokenIndex].Type == TOKEN_PUNCTUATION &&
-						parser.tokens[parser.tokenIndex].Value == "," {
-						parser.tokenIndex++
+					if p.tokenIndex < len(p.tokens) &&
+						p.tokens[p.tokenIndex].Type == TOKEN_PUNCTUATION &&
+						p.tokens[p.tokenIndex].Value == "," {
+						p.tokenIndex++
 					} else {
 						break
 					}
@@ -137,46 +117,92 @@ func (p *Parser) parseInclude(parser *Parser) (Node, error) {
 
 		case "ignore":
 			// Check for 'missing' keyword
-			if parser.tokenIndex >= len(parser.tokens) ||
-				parser.tokens[parser.tokenIndex].Type != TOKEN_NAME ||
-				parser.tokens[parser.tokenIndex].Value != "missing" {
-				return nil, fmt.Errorf("expected 'missing' after 'ignore' at line %d", includeLine)
+			if p.tokenIndex >= len(p.tokens) ||
+				p.tokens[p.tokenIndex].Type != TOKEN_NAME ||
+				p.tokens[p.tokenIndex].Value != "missing" {
+				return options, fmt.Errorf("expected 'missing' after 'ignore' at line %d", includeLine)
 			}
-			parser.tokenIndex++
+			p.tokenIndex++
 
-			ignoreMissing = true
+			options.ignoreMissing = true
 
 		case "only":
-			onlyContext = true
+			options.only = true
 
 		case "sandboxed":
-			sandboxed = true
+			options.sandboxed = true
 
 		default:
-			return nil, fmt.Errorf("unexpected keyword '%s' in include at line %d", keyword, includeLine)
+			return options, fmt.Errorf("unexpected keyword '%s' in %s at line %d", keyword, tag, includeLine)
 		}
 	}
 
-	// Expect the block end token
-	if parser.tokenIndex >= len(parser.tokens) ||
-		(parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END &&
-			parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END_TRIM) {
-		return nil, fmt.Errorf("expected block end token after include at line %d, found token type %d with value '%s'",
-			includeLine,
-			parser.tokens[parser.tokenIndex].Type,
-			parser.tokens[parser.tokenIndex].Value)
-	}
-	parser.tokenIndex++
+	return options, nil
+}
 
-	// Create the include node
-	includeNode := &IncludeNode{
-		template:      templateExpr,
-		variables:     variables,
-		ignoreMissing: ignoreMissing,
-		only:          onlyContext,
-		sandboxed:     sandboxed,
-		line:          includeLine,
+// parseScopeVariables parses the {key: value} hash of variables passed to an
+// include, embed or with tag. Keys are names, quoted or not, and values are
+// evaluated when the tag renders.
+func (p *Parser) parseScopeVariables(variables map[string]Node, line int) error {
+	p.tokenIndex++ // Skip opening brace
+
+	// Parse key-value pairs
+	for {
+		// If we see a closing brace, we're done
+		if p.tokenIndex < len(p.tokens) &&
+			p.tokens[p.tokenIndex].Type == TOKEN_PUNCTUATION &&
+			p.tokens[p.tokenIndex].Value == "}" {
+			p.tokenIndex++ // Skip closing brace
+			break
+		}
+
+		// Get the variable name - can be string literal or name token
+		var varName string
+		if p.tokenIndex < len(p.tokens) && p.tokens[p.tokenIndex].Type == TOKEN_STRING {
+			// It's a quoted string key
+			varName = p.tokens[p.tokenIndex].Value
+			p.tokenIndex++
+		} else if p.tokenIndex < len(p.tokens) && p.tokens[p.tokenIndex].Type == TOKEN_NAME {
+			// It's an unquoted key
+			varName = p.tokens[p.tokenIndex].Value
+			p.tokenIndex++
+		} else {
+			return fmt.Errorf("expected variable name or string at line %d", line)
+		}
+
+		// Expect colon or equals
+		if p.tokenIndex >= len(p.tokens) ||
+			((p.tokens[p.tokenIndex].Type != TOKEN_PUNCTUATION &&
+				p.tokens[p.tokenIndex].Value != ":") &&
+				(p.tokens[p.tokenIndex].Type != TOKEN_OPERATOR &&
+					p.tokens[p.tokenIndex].Value != "=")) {
+			return fmt.Errorf("expected ':' or '=' after variable name at line %d", line)
+		}
+		p.tokenIndex++ // Skip : or =
+
+		// Parse the value expression
+		varExpr, err := p.parseExpression()
+		if err != nil {
+			return err
+		}
+
+		// Add to variables map
+		variables[varName] = varExpr
+
+		// If there's a comma, skip it
+		if p.tokenIndex < len(p.tokens) &&
+			p.tokens[p.tokenIndex].Type == TOKEN_PUNCTUATION &&
+			p.tokens[p.tokenIndex].Value == "," {
+			p.tokenIndex++
+		}
+
+		// If we see whitespace, skip it
+		for p.tokenIndex < len(p.tokens) &&
+			p.tokens[p.tokenIndex].Type == TOKEN_TEXT &&
+			strings.TrimSpace(p.tokens[p.tokenIndex].Value) == "" {
+			p.tokenIndex++
+		}
 	}
 
-	return includeNode, nil
+	return nil
 }
@@ -97,7 +97,7 @@ func (p *Parser) parseVerbatim(parser *Parser) (Node, error) {
 
 				parser.tokenIndex++
 			}
-		} else if token.Type == TOKEN_COMMENT_START {
+		} else if token.Type == TOKEN_COMMENT_START || token.Type == TOKEN_COMMENT_START_TRIM {
 			// For comment tags, preserve them as literal text
 			contentBuilder.WriteString("{#")
 
@@ -108,7 +108,7 @@ func (p *Parser) parseVerbatim(parser *Parser) (Node, error) {
 			for parser.tokenIndex < len(parser.tokens) {
 				innerToken := parser.tokens[parser.tokenIndex]
 
-				if innerToken.Type == TOKEN_COMMENT_END {
+				if isCommentEnd(innerToken.Type) {
 					contentBuilder.WriteString("#}")
 					break
 				} else if innerToken.Type == TOKEN_TEXT {
@@ -2,7 +2,6 @@ package twig
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -23,10 +22,12 @@ const (
 	TOKEN_EOF
 
 	// Whitespace control token types
-	TOKEN_VAR_START_TRIM   // {{-
-	TOKEN_VAR_END_TRIM     // -}}
-	TOKEN_BLOCK_START_TRIM // {%-
-	TOKEN_BLOCK_END_TRIM   // -%}
+	TOKEN_VAR_START_TRIM     // {{-
+	TOKEN_VAR_END_TRIM       // -}}
+	TOKEN_BLOCK_START_TRIM   // {%-
+	TOKEN_BLOCK_END_TRIM     // -%}
+	TOKEN_COMMENT_START_TRIM // {#-
+	TOKEN_COMMENT_END_TRIM   // -#}
 )
 
 // Parser handles parsing Twig templates into node trees
@@ -38,6 +39,10 @@ type Parser struct {
 	cursor        int
 	line          int
 	blockHandlers map[string]blockHandlerFunc
+	interner      *stringInterner // Optional table shared with other parses
+	keepComments  bool            // Keep comments as CommentNodes, see SetRetainComments
+	pendingDoc    string          // Text of an @doc comment waiting for the macro after it
+	streamingSize int             // Sources of at least this size parse in streaming mode, 0 for never
 }
 
 type blockHandlerFunc func(*Parser) (Node, error)
@@ -59,21 +64,25 @@ func (p *Parser) Parse(source string) (Node, error) {
 	// Initialize default block handlers
 	p.initBlockHandlers()
 
+	// Huge sources are tokenized a window at a time, see SetStreamingParse
+	if p.streamingSize > 0 && len(source) >= p.streamingSize {
+		nodes, err := p.parseStreaming()
+		if err != nil {
+			return nil, err
+		}
+		return finishParse(nodes)
+	}
+
 	// Use the optimized tokenizer for maximum performance and minimal allocations
 	// This will treat everything outside twig tags as TEXT tokens
 	var err error
 
 	// Use zero allocation tokenizer for optimal performance
 	tokenizer := GetTokenizer(p.source, 0)
+	tokenizer.interner = p.interner
 
-	// Use optimized version for larger templates
-	if len(p.source) > 4096 {
-		// Use the optimized tag detection for large templates
-		p.tokens, err = tokenizer.TokenizeOptimized()
-	} else {
-		// Use regular tokenization for smaller templates
-		p.tokens, err = tokenizer.TokenizeHtmlPreserving()
-	}
+	// Single pass over the source, for templates of any size
+	p.tokens, err = tokenizer.TokenizeHtmlPreserving()
 
 	// Apply whitespace control to handle whitespace trimming directives
 	if err == nil {
@@ -101,6 +110,18 @@ func (p *Parser) Parse(source string) (Node, error) {
 	// Clean up token slice after successful parsing
 	ReleaseTokenSlice(p.tokens)
 
+	return finishParse(nodes)
+}
+
+// finishParse checks and optimizes the top-level nodes of a template
+func finishParse(nodes []Node) (Node, error) {
+	if err := checkExtendsPlacement(nodes); err != nil {
+		return nil, fmt.Errorf("parsing error: %w", err)
+	}
+
+	// Merge adjacent text nodes produced by the tokenizer
+	nodes = optimizeNodes(nodes)
+
 	return NewRootNode(nodes, 1), nil
 }
 
@@ -120,6 +141,9 @@ func (p *Parser) initBlockHandlers() {
 		"spaceless": p.parseSpaceless,
 		"verbatim":  p.parseVerbatim,
 		"apply":     p.parseApply,
+		"return":    p.parseReturn,
+		"embed":     p.parseEmbed,
+		"with":      p.parseWith,
 
 		// Special closing tags - they will be handled in their corresponding open tag parsers
 		"endif":        p.parseEndTag,
@@ -128,6 +152,8 @@ func (p *Parser) initBlockHandlers() {
 		"endblock":     p.parseEndTag,
 		"endspaceless": p.parseEndTag,
 		"endapply":     p.parseEndTag,
+		"endembed":     p.parseEndTag,
+		"endwith":      p.parseEndTag,
 
 		"else":        p.parseEndTag,
 		"elseif":      p.parseEndTag,
@@ -135,6 +161,11 @@ func (p *Parser) initBlockHandlers() {
 	}
 }
 
+// isCommentEnd reports whether a token type closes a comment
+func isCommentEnd(tokenType int) bool {
+	return tokenType == TOKEN_COMMENT_END || tokenType == TOKEN_COMMENT_END_TRIM
+}
+
 func isDigit(c byte) bool {
 	return c >= '0' && c <= '9'
 }
@@ -193,124 +224,171 @@ func (p *Parser) parseOuterTemplate() ([]Node, error) {
 	var nodes []Node
 
 	for p.tokenIndex < len(p.tokens) && p.tokens[p.tokenIndex].Type != TOKEN_EOF {
-		token := p.tokens[p.tokenIndex]
+		node, end, err := p.parseOuterNode()
+		if err != nil {
+			return nil, err
+		}
+		if end {
+			break
+		}
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+	}
 
-		switch token.Type {
-		case TOKEN_TEXT:
-			nodes = append(nodes, NewTextNode(token.Value, token.Line))
-			p.tokenIndex++
+	return nodes, nil
+}
 
-		case TOKEN_VAR_START, TOKEN_VAR_START_TRIM:
-			// Handle both normal and whitespace trimming var start tokens
-			p.tokenIndex++
-			expr, err := p.parseExpression()
-			if err != nil {
-				return nil, err
-			}
+// parseOuterNode parses the text, print tag or block at the current token.
+// It reports end at a tag closing the enclosing block, which is left for the
+// parser of that block, and returns no node for skipped comments.
+func (p *Parser) parseOuterNode() (Node, bool, error) {
+	token := p.tokens[p.tokenIndex]
 
-			nodes = append(nodes, NewPrintNode(expr, token.Line))
+	switch token.Type {
+	case TOKEN_TEXT:
+		p.tokenIndex++
+		if strings.TrimSpace(token.Value) != "" {
+			p.pendingDoc = ""
+		}
+		return NewTextNode(token.Value, token.Line), false, nil
 
-			// Check for either normal or whitespace trimming var end tokens
-			if p.tokenIndex >= len(p.tokens) || !isVarEndToken(p.tokens[p.tokenIndex].Type) {
-				return nil, fmt.Errorf("expected }} or -}} at line %d", token.Line)
-			}
-			p.tokenIndex++
+	case TOKEN_VAR_START, TOKEN_VAR_START_TRIM:
+		// Handle both normal and whitespace trimming var start tokens
+		p.tokenIndex++
+		p.pendingDoc = ""
 
-		case TOKEN_BLOCK_START, TOKEN_BLOCK_START_TRIM:
-			// Handle both normal and whitespace trimming block start tokens
-			p.tokenIndex++
+		// Errors point at the expression, which can start on a later
+		// line than the tag
+		exprLine := token.Line
+		if p.tokenIndex < len(p.tokens) && !isVarEndToken(p.tokens[p.tokenIndex].Type) {
+			exprLine = p.tokens[p.tokenIndex].Line
+		}
 
-			if p.tokenIndex >= len(p.tokens) || p.tokens[p.tokenIndex].Type != TOKEN_NAME {
-				return nil, fmt.Errorf("expected block name at line %d", token.Line)
-			}
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, false, err
+		}
 
-			blockName := p.tokens[p.tokenIndex].Value
-			p.tokenIndex++
+		// Check for either normal or whitespace trimming var end tokens
+		if p.tokenIndex >= len(p.tokens) || !isVarEndToken(p.tokens[p.tokenIndex].Type) {
+			return nil, false, fmt.Errorf("expected }} or -}} at line %d", token.Line)
+		}
+		p.tokenIndex++
+		return NewPrintNode(expr, exprLine), false, nil
 
-			// Check if this is a control ending tag (endif, endfor, endblock, etc.)
-			if blockName == "endif" || blockName == "endfor" || blockName == "endblock" ||
-				blockName == "endmacro" || blockName == "else" || blockName == "elseif" ||
-				blockName == "endspaceless" || blockName == "endapply" || blockName == "endverbatim" {
-				// We should return to the parent parser that's handling the parent block
-				// First move back two steps to the start of the block tag
-				p.tokenIndex -= 2
-				return nodes, nil
-			}
+	case TOKEN_BLOCK_START, TOKEN_BLOCK_START_TRIM:
+		// Handle both normal and whitespace trimming block start tokens
+		p.tokenIndex++
 
-			// Check if we have a handler for this block type
-			handler, ok := p.blockHandlers[blockName]
-			if !ok {
-				return nil, fmt.Errorf("unknown block type '%s' at line %d", blockName, token.Line)
-			}
+		if p.tokenIndex >= len(p.tokens) || p.tokens[p.tokenIndex].Type != TOKEN_NAME {
+			return nil, false, fmt.Errorf("expected block name at line %d", token.Line)
+		}
 
-			node, err := handler(p)
-			if err != nil {
-				return nil, err
-			}
+		blockName := p.tokens[p.tokenIndex].Value
+		p.tokenIndex++
 
-			nodes = append(nodes, node)
+		// Check if this is a control ending tag (endif, endfor, endblock, etc.)
+		if blockName == "endif" || blockName == "endfor" || blockName == "endblock" ||
+			blockName == "endmacro" || blockName == "else" || blockName == "elseif" ||
+			blockName == "endspaceless" || blockName == "endapply" || blockName == "endverbatim" ||
+			blockName == "endembed" || blockName == "endwith" {
+			// We should return to the parent parser that's handling the parent block
+			// First move back two steps to the start of the block tag
+			p.tokenIndex -= 2
+			return nil, true, nil
+		}
 
-		case TOKEN_COMMENT_START:
-			// Skip comments
-			p.tokenIndex++
-			startLine := token.Line
+		// Check if we have a handler for this block type
+		handler, ok := p.blockHandlers[blockName]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown block type '%s' at line %d", blockName, token.Line)
+		}
 
-			// Find the end of the comment
-			for p.tokenIndex < len(p.tokens) && p.tokens[p.tokenIndex].Type != TOKEN_COMMENT_END {
-				p.tokenIndex++
-			}
+		// A doc comment belongs to the tag right after it
+		doc := p.pendingDoc
+		p.pendingDoc = ""
 
-			if p.tokenIndex >= len(p.tokens) {
-				return nil, fmt.Errorf("unclosed comment starting at line %d", startLine)
-			}
+		node, err := handler(p)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if macro, ok := node.(*MacroNode); ok && doc != "" {
+			macro.doc = doc
+		}
 
+		return node, false, nil
+
+	case TOKEN_COMMENT_START, TOKEN_COMMENT_START_TRIM:
+		// Skip comments, or keep them for tooling
+		p.tokenIndex++
+		startLine := token.Line
+
+		// Find the end of the comment
+		var content string
+		for p.tokenIndex < len(p.tokens) && !isCommentEnd(p.tokens[p.tokenIndex].Type) {
+			if p.tokens[p.tokenIndex].Type == TOKEN_TEXT {
+				content += p.tokens[p.tokenIndex].Value
+			}
 			p.tokenIndex++
+		}
 
-		// Add special handling for trim token types
-		case TOKEN_VAR_END_TRIM, TOKEN_BLOCK_END_TRIM:
-			// These should have been handled with their corresponding start tokens
-			return nil, fmt.Errorf("unexpected token %v at line %d", token.Type, token.Line)
+		if p.tokenIndex >= len(p.tokens) {
+			return nil, false, fmt.Errorf("unclosed comment starting at line %d", startLine)
+		}
 
-		// Add special handling for TOKEN_NAME outside of a tag
-		case TOKEN_NAME, TOKEN_PUNCTUATION, TOKEN_OPERATOR, TOKEN_STRING, TOKEN_NUMBER:
-			// For raw names, punctuation, operators, and literals not inside tags, convert to text
-			// In many languages, the text "true" is a literal boolean, but in our parser it's just a name token
-			// outside of an expression context
+		p.tokenIndex++
+		p.pendingDoc = macroDoc(content)
 
-			// Special handling for text content words - add spaces between consecutive text tokens
-			// This fixes issues with the spaceless tag's handling of text content
-			if token.Type == TOKEN_NAME && p.tokenIndex+1 < len(p.tokens) &&
-				p.tokens[p.tokenIndex+1].Type == TOKEN_NAME &&
-				p.tokens[p.tokenIndex+1].Line == token.Line {
-				// Look ahead for consecutive name tokens and join them with spaces
-				var textContent strings.Builder
-				textContent.WriteString(token.Value)
-
-				currentLine := token.Line
-				p.tokenIndex++ // Skip the first token as we've already added it
-
-				// Collect consecutive name tokens on the same line
-				for p.tokenIndex < len(p.tokens) &&
-					p.tokens[p.tokenIndex].Type == TOKEN_NAME &&
-					p.tokens[p.tokenIndex].Line == currentLine {
-					textContent.WriteString(" ") // Add space between words
-					textContent.WriteString(p.tokens[p.tokenIndex].Value)
-					p.tokenIndex++
-				}
+		if p.keepComments {
+			return NewCommentNode(content, startLine), false, nil
+		}
 
-				nodes = append(nodes, NewTextNode(textContent.String(), token.Line))
-			} else {
-				// Regular handling for single text tokens
-				nodes = append(nodes, NewTextNode(token.Value, token.Line))
+	// Add special handling for trim token types
+	case TOKEN_VAR_END_TRIM, TOKEN_BLOCK_END_TRIM:
+		// These should have been handled with their corresponding start tokens
+		return nil, false, fmt.Errorf("unexpected token %v at line %d", token.Type, token.Line)
+
+	// Add special handling for TOKEN_NAME outside of a tag
+	case TOKEN_NAME, TOKEN_PUNCTUATION, TOKEN_OPERATOR, TOKEN_STRING, TOKEN_NUMBER:
+		// For raw names, punctuation, operators, and literals not inside tags, convert to text
+		// In many languages, the text "true" is a literal boolean, but in our parser it's just a name token
+		// outside of an expression context
+
+		// Special handling for text content words - add spaces between consecutive text tokens
+		// This fixes issues with the spaceless tag's handling of text content
+		if token.Type == TOKEN_NAME && p.tokenIndex+1 < len(p.tokens) &&
+			p.tokens[p.tokenIndex+1].Type == TOKEN_NAME &&
+			p.tokens[p.tokenIndex+1].Line == token.Line {
+			// Look ahead for consecutive name tokens and join them with spaces
+			var textContent strings.Builder
+			textContent.WriteString(token.Value)
+
+			currentLine := token.Line
+			p.tokenIndex++ // Skip the first token as we've already added it
+
+			// Collect consecutive name tokens on the same line
+			for p.tokenIndex < len(p.tokens) &&
+				p.tokens[p.tokenIndex].Type == TOKEN_NAME &&
+				p.tokens[p.tokenIndex].Line == currentLine {
+				textContent.WriteString(" ") // Add space between words
+				textContent.WriteString(p.tokens[p.tokenIndex].Value)
 				p.tokenIndex++
 			}
 
-		default:
-			return nil, fmt.Errorf("unexpected token %v at line %d", token.Type, token.Line)
+			return NewTextNode(textContent.String(), token.Line), false, nil
 		}
+
+		// Regular handling for single text tokens
+		p.tokenIndex++
+		return NewTextNode(token.Value, token.Line), false, nil
+
+	default:
+		return nil, false, fmt.Errorf("unexpected token %v at line %d", token.Type, token.Line)
 	}
 
-	return nodes, nil
+	return nil, false, nil
 }
 
 // Parse an expression
@@ -432,6 +510,27 @@ func (p *Parser) parseConditionalExpression(condition Node) (Node, error) {
 	}, nil
 }
 
+// parseNotOperand applies the filters and tests that follow the operand of
+// a not operator to the operand
+func (p *Parser) parseNotOperand(operand Node) (Node, error) {
+	var err error
+	for p.tokenIndex < len(p.tokens) {
+		token := p.tokens[p.tokenIndex]
+		switch {
+		case token.Type == TOKEN_PUNCTUATION && token.Value == "|":
+			operand, err = p.parseFilters(operand)
+		case token.Type == TOKEN_NAME && token.Value == "is":
+			operand, err = p.parseBinaryExpression(operand)
+		default:
+			return operand, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return operand, nil
+}
+
 // Parse a simple expression (literal, variable, function call, array)
 func (p *Parser) parseSimpleExpression() (Node, error) {
 	if p.tokenIndex >= len(p.tokens) {
@@ -456,6 +555,15 @@ func (p *Parser) parseSimpleExpression() (Node, error) {
 			return nil, err
 		}
 
+		// Filters and tests bind tighter than not, so not x|length and
+		// not x is empty negate the whole filter or test
+		if operator == "not" {
+			operand, err = p.parseNotOperand(operand)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		// Create a unary node
 		return NewUnaryNode(operator, operand, line), nil
 	}
@@ -469,16 +577,12 @@ func (p *Parser) parseSimpleExpression() (Node, error) {
 
 	case TOKEN_NUMBER:
 		p.tokenIndex++
-		// Attempt to convert to int or float
-		if strings.Contains(token.Value, ".") {
-			// It's a float
-			val, _ := strconv.ParseFloat(token.Value, 64)
-			return NewLiteralNode(val, token.Line), nil
-		} else {
-			// It's an int
-			val, _ := strconv.Atoi(token.Value)
-			return NewLiteralNode(val, token.Line), nil
+		// Convert to int or float
+		val, err := parseNumber(token.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at line %d", token.Value, token.Line)
 		}
+		return NewLiteralNode(val, token.Line), nil
 
 	case TOKEN_NAME:
 		p.tokenIndex++
@@ -852,6 +956,7 @@ func (p *Parser) parseFilters(node Node) (Node, error) {
 
 		// Check for filter arguments
 		var args []Node
+		var named map[string]Node
 
 		// If there are arguments in parentheses
 		if p.tokenIndex < len(p.tokens) &&
@@ -866,12 +971,34 @@ func (p *Parser) parseFilters(node Node) (Node, error) {
 					p.tokens[p.tokenIndex].Value == ")") {
 
 				for {
+					// Arguments passed by name look like name=expression
+					argName := ""
+					if p.tokenIndex+1 < len(p.tokens) &&
+						p.tokens[p.tokenIndex].Type == TOKEN_NAME &&
+						p.tokens[p.tokenIndex+1].Type == TOKEN_OPERATOR &&
+						p.tokens[p.tokenIndex+1].Value == "=" {
+						argName = p.tokens[p.tokenIndex].Value
+						p.tokenIndex += 2
+					}
+
 					// Parse each argument expression
 					argExpr, err := p.parseExpression()
 					if err != nil {
 						return nil, err
 					}
-					args = append(args, argExpr)
+					if argName != "" {
+						if named == nil {
+							named = make(map[string]Node)
+						}
+						if _, dup := named[argName]; dup {
+							return nil, fmt.Errorf("argument '%s' of filter '%s' passed twice at line %d", argName, filterName, line)
+						}
+						named[argName] = argExpr
+					} else if len(named) > 0 {
+						return nil, fmt.Errorf("positional argument after named arguments of filter '%s' at line %d", filterName, line)
+					} else {
+						args = append(args, argExpr)
+					}
 
 					// Check for comma separator
 					if p.tokenIndex < len(p.tokens) &&
@@ -904,6 +1031,7 @@ func (p *Parser) parseFilters(node Node) (Node, error) {
 			node:   node,
 			filter: filterName,
 			args:   args,
+			named:  named,
 		}
 	}
 
@@ -918,7 +1046,7 @@ const (
 	PREC_COMPARE = 3 // ==, !=, <, >, <=, >=, in, not in, matches, starts with, ends with
 	PREC_SUM     = 4 // +, -
 	PREC_PRODUCT = 5 // *, /, %
-	PREC_POWER   = 6 // ^
+	PREC_POWER   = 6 // **, ^
 	PREC_PREFIX  = 7 // not, !, +, - (unary)
 )
 
@@ -935,48 +1063,31 @@ func getOperatorPrecedence(operator string) int {
 		return PREC_SUM
 	case "*", "/", "%":
 		return PREC_PRODUCT
-	case "^":
+	case "**", "^":
 		return PREC_POWER
 	default:
 		return PREC_LOWEST
 	}
 }
 
+// twoWordTests maps the first word of the tests named by two words to the
+// second one
+var twoWordTests = map[string]string{
+	"same":      "as",
+	"divisible": "by",
+}
+
 // Parse binary expressions (a + b, a and b, a in b, etc.)
 func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 	token := p.tokens[p.tokenIndex]
 	operator := token.Value
 	line := token.Line
 
-	// Special handling for "not defined" pattern
-	// This is the common pattern used in Twig: {% if variable not defined %}
-	if operator == "not" && p.tokenIndex+1 < len(p.tokens) &&
+	// Outside of 'not in', not is a prefix operator, see parseSimpleExpression
+	if operator == "not" && !(p.tokenIndex+1 < len(p.tokens) &&
 		p.tokens[p.tokenIndex+1].Type == TOKEN_NAME &&
-		p.tokens[p.tokenIndex+1].Value == "defined" {
-
-		// Next token should be "defined"
-		p.tokenIndex += 2 // Skip both "not" and "defined"
-
-		// Create a TestNode with "defined" test
-		testNode := &TestNode{
-			ExpressionNode: ExpressionNode{
-				exprType: ExprTest,
-				line:     line,
-			},
-			node: left,
-			test: "defined",
-			args: []Node{},
-		}
-
-		// Then wrap it in a unary "not" node
-		return &UnaryNode{
-			ExpressionNode: ExpressionNode{
-				exprType: ExprUnary,
-				line:     line,
-			},
-			operator: "not",
-			node:     testNode,
-		}, nil
+		p.tokens[p.tokenIndex+1].Value == "in") {
+		return nil, fmt.Errorf("unexpected 'not' at line %d, use 'is not' to negate a test", line)
 	}
 
 	// Process multi-word operators
@@ -1021,6 +1132,14 @@ func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 			testName := p.tokens[p.tokenIndex].Value
 			p.tokenIndex++ // Skip the test name
 
+			// Two-word tests: same as, divisible by
+			if second, ok := twoWordTests[testName]; ok && p.tokenIndex < len(p.tokens) &&
+				p.tokens[p.tokenIndex].Type == TOKEN_NAME &&
+				p.tokens[p.tokenIndex].Value == second {
+				testName += " " + second
+				p.tokenIndex++
+			}
+
 			// Parse test arguments if any
 			var args []Node
 
@@ -1105,7 +1224,7 @@ func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 	}
 
 	// Create the current binary node
-	binaryNode := NewBinaryNode(operator, left, right, line)
+	binaryNode := newBinaryExpression(operator, left, right, line)
 
 	// Check for another binary operator
 	if p.tokenIndex < len(p.tokens) &&
@@ -1145,8 +1264,9 @@ func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 
 		nextPrecedence := getOperatorPrecedence(nextOperator)
 
-		// If the next operator has higher precedence, we need to parse it first
-		if nextPrecedence > precedence {
+		// If the next operator has higher precedence, we need to parse it
+		// first, as for a following ** since power is right-associative
+		if nextPrecedence > precedence || (nextPrecedence == PREC_POWER && precedence == PREC_POWER) {
 			// Replace the right side with a binary expression
 			newRight, err := p.parseBinaryExpression(right)
 			if err != nil {
@@ -1154,7 +1274,7 @@ func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 			}
 
 			// Update the binary node with the new right side
-			binaryNode = NewBinaryNode(operator, left, newRight, line)
+			binaryNode = newBinaryExpression(operator, left, newRight, line)
 		}
 	}
 
@@ -1169,6 +1289,15 @@ func (p *Parser) parseBinaryExpression(left Node) (Node, error) {
 	return binaryNode, nil
 }
 
+// newBinaryExpression creates the node for a binary operator, collapsing
+// chains of ~ into a single concatenation node
+func newBinaryExpression(operator string, left, right Node, line int) Node {
+	if operator == "~" {
+		return NewConcatNode(left, right, line)
+	}
+	return NewBinaryNode(operator, left, right, line)
+}
+
 // parseEndTag handles closing tags like endif, endfor, endblock, etc.
 // These tags should only be encountered inside their respective block parsing methods,
 // so if we reach here directly, it's an error.
@@ -0,0 +1,155 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"plugin"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// PluginTemplatesSymbol is the variable a template plugin exports: a
+// map[string][]byte of compiled templates by name, as written by
+// WritePluginSource
+const PluginTemplatesSymbol = "Templates"
+
+// PluginLoader loads compiled templates from Go plugins, so template updates
+// can ship as a .so built with go build -buildmode=plugin instead of a new
+// binary. Go cannot unload plugins, so each update must be built to a new
+// path; Open swaps the templates to those of the new plugin and drops the
+// old ones from the engines the loader is attached to.
+type PluginLoader struct {
+	mu        sync.RWMutex
+	templates map[string]*CompiledTemplate
+	version   int64 // Incremented by each Open, reported as modification time
+	engines   []*Engine
+}
+
+// NewPluginLoader creates a loader with no templates until Open is called
+func NewPluginLoader() *PluginLoader {
+	return &PluginLoader{templates: make(map[string]*CompiledTemplate)}
+}
+
+// Attach registers the loader with an engine, and has Open remove the
+// replaced templates from that engine's cache
+func (l *PluginLoader) Attach(engine *Engine) {
+	engine.RegisterLoader(l)
+
+	l.mu.Lock()
+	l.engines = append(l.engines, engine)
+	l.mu.Unlock()
+}
+
+// Open loads the plugin at path and serves its templates from now on
+func (l *PluginLoader) Open(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open template plugin: %w", err)
+	}
+
+	symbol, err := p.Lookup(PluginTemplatesSymbol)
+	if err != nil {
+		return fmt.Errorf("template plugin %s: %w", path, err)
+	}
+	artifacts, ok := symbol.(*map[string][]byte)
+	if !ok {
+		return fmt.Errorf("template plugin %s: %s is a %T, not a map[string][]byte", path, PluginTemplatesSymbol, symbol)
+	}
+
+	return l.setTemplates(*artifacts)
+}
+
+// setTemplates replaces the loader's templates with the compiled artifacts
+func (l *PluginLoader) setTemplates(artifacts map[string][]byte) error {
+	templates := make(map[string]*CompiledTemplate, len(artifacts))
+	for name, data := range artifacts {
+		compiled, err := DeserializeCompiledTemplate(data)
+		if err != nil {
+			return fmt.Errorf("failed to deserialize compiled template %s: %w", name, err)
+		}
+		templates[name] = compiled
+	}
+
+	l.mu.Lock()
+	replaced := l.templates
+	l.templates = templates
+	l.version++
+	engines := append([]*Engine(nil), l.engines...)
+	l.mu.Unlock()
+
+	for _, engine := range engines {
+		for name := range replaced {
+			engine.InvalidateTemplate(name)
+		}
+		for name := range templates {
+			engine.InvalidateTemplate(name)
+		}
+	}
+	return nil
+}
+
+// Load implements the Loader interface
+func (l *PluginLoader) Load(name string) (string, error) {
+	l.mu.RLock()
+	compiled, ok := l.templates[name]
+	l.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s not found in template plugin", ErrTemplateNotFound, name)
+	}
+	return compiled.Source, nil
+}
+
+// Exists checks if the current plugin has the template
+func (l *PluginLoader) Exists(name string) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, ok := l.templates[name]
+	return ok
+}
+
+// GetModifiedTime returns the number of plugins opened, so engines with
+// auto-reload pick up templates from a new plugin
+func (l *PluginLoader) GetModifiedTime(name string) (int64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if _, ok := l.templates[name]; !ok {
+		return 0, fmt.Errorf("%w: %s not found in template plugin", ErrTemplateNotFound, name)
+	}
+	return l.version, nil
+}
+
+// WritePluginSource writes the Go source of a template plugin holding the
+// named templates compiled, to be built with go build -buildmode=plugin and
+// loaded with PluginLoader.Open
+func WritePluginSource(w io.Writer, engine *Engine, names []string) error {
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	if _, err := fmt.Fprintf(w, "// Code generated by twig. DO NOT EDIT.\n\npackage main\n\n// %s are the compiled templates of this plugin\nvar %s = map[string][]byte{\n",
+		PluginTemplatesSymbol, PluginTemplatesSymbol); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		template, err := engine.Load(name)
+		if err != nil {
+			return err
+		}
+		compiled, err := template.Compile()
+		if err != nil {
+			return fmt.Errorf("failed to compile template %s: %w", name, err)
+		}
+		data, err := SerializeCompiledTemplate(compiled)
+		if err != nil {
+			return fmt.Errorf("failed to serialize template %s: %w", name, err)
+		}
+
+		if _, err := fmt.Fprintf(w, "\t%s: []byte(%s),\n", strconv.Quote(name), strconv.Quote(string(data))); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
@@ -0,0 +1,82 @@
+package twig
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+)
+
+func TestPluginLoader(t *testing.T) {
+	source := New()
+	if err := source.RegisterString("hello", "Hello {{ name }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	// Generate the plugin source and read the artifacts back from it
+	var buf bytes.Buffer
+	if err := WritePluginSource(&buf, source, []string{"hello"}); err != nil {
+		t.Fatalf("Error writing plugin source: %v", err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "templates.go", buf.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("Generated source does not parse: %v\n%s", err, buf.String())
+	}
+	artifacts := make(map[string][]byte)
+	ast.Inspect(file, func(node ast.Node) bool {
+		if kv, ok := node.(*ast.KeyValueExpr); ok {
+			name, _ := strconv.Unquote(kv.Key.(*ast.BasicLit).Value)
+			data, _ := strconv.Unquote(kv.Value.(*ast.CallExpr).Args[0].(*ast.BasicLit).Value)
+			artifacts[name] = []byte(data)
+		}
+		return true
+	})
+
+	loader := NewPluginLoader()
+	engine := New()
+	loader.Attach(engine)
+
+	if _, err := loader.Load("hello"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound before a plugin is opened, got %v", err)
+	}
+
+	if err := loader.setTemplates(artifacts); err != nil {
+		t.Fatalf("Error setting templates: %v", err)
+	}
+	result, err := engine.Render("hello", map[string]interface{}{"name": "plugin"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Hello plugin" {
+		t.Errorf("Expected %q, got %q", "Hello plugin", result)
+	}
+
+	// A new plugin replaces the cached templates
+	source.RegisterString("hello", "Hi {{ name }}")
+	template, _ := source.Load("hello")
+	compiled, _ := template.Compile()
+	data, _ := SerializeCompiledTemplate(compiled)
+	if err := loader.setTemplates(map[string][]byte{"hello": data}); err != nil {
+		t.Fatalf("Error setting templates: %v", err)
+	}
+	result, err = engine.Render("hello", map[string]interface{}{"name": "plugin"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Hi plugin" {
+		t.Errorf("Expected the new plugin's template, got %q", result)
+	}
+	if version, _ := loader.GetModifiedTime("hello"); version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+
+	if err := loader.setTemplates(map[string][]byte{"bad": []byte("garbage")}); err == nil {
+		t.Error("Expected an error for a corrupt artifact")
+	}
+	if err := loader.Open("testdata/missing.so"); err == nil {
+		t.Error("Expected an error opening a missing plugin")
+	}
+}
@@ -0,0 +1,55 @@
+package twig
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// SetPprofLabels sets whether renders attach pprof labels to the rendering
+// goroutine, so CPU profiles taken with runtime/pprof or net/http/pprof
+// attribute samples to templates. The twig_template label names the
+// template being rendered, switching to included and embedded templates
+// while they render, and twig_block names the block. Filter a profile with
+// go tool pprof -tagfocus=twig_template=page.twig. Labels the caller set on
+// the goroutine are replaced during the render and cleared after it.
+func (e *Engine) SetPprofLabels(enabled bool) {
+	e.pprofLabels = enabled
+}
+
+// labelState holds the pprof labels of one render, shared by its contexts
+type labelState struct {
+	current context.Context
+}
+
+// newLabelState returns the label state for a render, nil when pprof
+// labels are off
+func (e *Engine) newLabelState() *labelState {
+	if e == nil || !e.pprofLabels {
+		return nil
+	}
+	return &labelState{current: context.Background()}
+}
+
+// labelSection labels the goroutine for a section of the render: the
+// root template, an include, an embed or a block. The returned function
+// restores the labels of the enclosing section.
+func (ctx *RenderContext) labelSection(kind, name string) func() {
+	state := ctx.labels
+	if state == nil {
+		return func() {}
+	}
+
+	key := "twig_template"
+	if kind == "block" {
+		key = "twig_block"
+	}
+
+	previous := state.current
+	state.current = pprof.WithLabels(previous, pprof.Labels(key, name))
+	pprof.SetGoroutineLabels(state.current)
+
+	return func() {
+		state.current = previous
+		pprof.SetGoroutineLabels(previous)
+	}
+}
@@ -0,0 +1,50 @@
+package twig
+
+import (
+	"runtime/pprof"
+	"testing"
+)
+
+func TestPprofLabels(t *testing.T) {
+	engine := New()
+	engine.SetPprofLabels(true)
+
+	// Reports the labels of the section the filter runs in
+	engine.AddFilterV2("labels", func(value interface{}, args FilterArgs) (interface{}, error) {
+		labels := args.Context.labels
+		if labels == nil {
+			return "none", nil
+		}
+		template, _ := pprof.Label(labels.current, "twig_template")
+		block, _ := pprof.Label(labels.current, "twig_block")
+		return "[" + template + "/" + block + "]", nil
+	})
+
+	templates := map[string]string{
+		"page.twig": "{{ 1|labels }}{% block body %}{{ 1|labels }}{% include 'part.twig' %}{% endblock %}{{ 1|labels }}",
+		"part.twig": "{{ 1|labels }}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+	}
+
+	result, err := engine.Render("page.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	expected := "[page.twig/][page.twig/body][part.twig/body][page.twig/]"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	engine.SetPprofLabels(false)
+	result, err = engine.Render("part.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "none" {
+		t.Errorf("Expected no labels when disabled, got %q", result)
+	}
+}
@@ -0,0 +1,108 @@
+package twig
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// defaultAlphabet is used by random_string when no alphabet is given
+const defaultAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// maxRandomStringLength bounds random_string so templates cannot request
+// arbitrarily large allocations
+const maxRandomStringLength = 1024
+
+// readRandom fills b from the environment's random source
+func (env *Environment) readRandom(b []byte) error {
+	if env == nil {
+		_, err := io.ReadFull(cryptorand.Reader, b)
+		return err
+	}
+
+	env.randomMu.Lock()
+	defer env.randomMu.Unlock()
+
+	source := env.random
+	if source == nil {
+		source = cryptorand.Reader
+	}
+	_, err := io.ReadFull(source, b)
+	return err
+}
+
+// functionUUID implements uuid(), returning a random version 4 UUID
+func (e *CoreExtension) functionUUID(args ...interface{}) (interface{}, error) {
+	var u [16]byte
+	if err := e.env.readRandom(u[:]); err != nil {
+		return nil, fmt.Errorf("uuid: %w", err)
+	}
+
+	u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+
+	return string(buf[:]), nil
+}
+
+// functionRandomString implements random_string(length, alphabet). Characters
+// are drawn uniformly from the alphabet, which defaults to letters and digits.
+func (e *CoreExtension) functionRandomString(args ...interface{}) (interface{}, error) {
+	length := 16
+	if len(args) > 0 && args[0] != nil {
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("random_string: invalid length: %w", err)
+		}
+		length = n
+	}
+	if length < 0 || length > maxRandomStringLength {
+		return nil, fmt.Errorf("random_string: length must be between 0 and %d, got %d", maxRandomStringLength, length)
+	}
+
+	alphabet := []rune(defaultAlphabet)
+	if len(args) > 1 && args[1] != nil {
+		s := e.toString(args[1])
+		if !utf8.ValidString(s) {
+			return nil, fmt.Errorf("random_string: alphabet is not valid UTF-8")
+		}
+		alphabet = []rune(s)
+	}
+	if len(alphabet) < 2 || len(alphabet) > 256 {
+		return nil, fmt.Errorf("random_string: alphabet must have between 2 and 256 characters, got %d", len(alphabet))
+	}
+
+	// Bytes at or above limit are rejected so every character is equally likely
+	limit := 256 - 256%len(alphabet)
+
+	result := make([]rune, 0, length)
+	buf := make([]byte, length+length/4+1)
+	for len(result) < length {
+		if err := e.env.readRandom(buf); err != nil {
+			return nil, fmt.Errorf("random_string: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) >= limit {
+				continue
+			}
+			result = append(result, alphabet[int(b)%len(alphabet)])
+			if len(result) == length {
+				break
+			}
+		}
+	}
+
+	return string(result), nil
+}
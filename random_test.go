@@ -0,0 +1,135 @@
+package twig
+
+import (
+	"bytes"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestUUIDFunction(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("test", `{{ uuid() }} {{ uuid() }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	result, err := engine.Render("test", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ids := strings.Split(result, " ")
+	for _, id := range ids {
+		if !pattern.MatchString(id) {
+			t.Errorf("Expected a version 4 UUID, got %q", id)
+		}
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("Expected different UUIDs, got %q twice", ids[0])
+	}
+}
+
+func TestRandomStringFunction(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		pattern string
+	}{
+		{"default", `{{ random_string() }}`, `^[A-Za-z0-9]{16}$`},
+		{"length", `{{ random_string(40) }}`, `^[A-Za-z0-9]{40}$`},
+		{"alphabet", `{{ random_string(12, "abc") }}`, `^[abc]{12}$`},
+		{"unicode alphabet", `{{ random_string(5, "äöü") }}`, `^[äöü]{5}$`},
+		{"empty", `[{{ random_string(0) }}]`, `^\[\]$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if !regexp.MustCompile(tt.pattern).MatchString(result) {
+				t.Errorf("Expected output matching %s, got %q", tt.pattern, result)
+			}
+		})
+	}
+}
+
+func TestRandomStringErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"negative length", `{{ random_string(-1) }}`},
+		{"too long", `{{ random_string(100000) }}`},
+		{"single character alphabet", `{{ random_string(4, "a") }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			if _, err := engine.Render("test", nil); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSetRandomSource(t *testing.T) {
+	render := func(seed int64) string {
+		engine := New()
+		engine.SetRandomSource(rand.New(rand.NewSource(seed)))
+		if err := engine.RegisterString("test", `{{ uuid() }} {{ random_string(8) }}`); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+
+		result, err := engine.Render("test", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		return result
+	}
+
+	if a, b := render(42), render(42); a != b {
+		t.Errorf("Expected the same output for the same seed, got %q and %q", a, b)
+	}
+	if a, b := render(1), render(2); a == b {
+		t.Errorf("Expected different output for different seeds, got %q", a)
+	}
+}
+
+func TestRandomFunctionsSandbox(t *testing.T) {
+	engine := New()
+	policy := NewDefaultSecurityPolicy()
+	delete(policy.AllowedFunctions, "random_string")
+	engine.EnableSandbox(policy)
+
+	if err := engine.RegisterString("test", `{{ random_string(8) }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	template, err := engine.Load("test")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+
+	ctx := NewRenderContext(engine.environment, nil, engine)
+	ctx.EnableSandbox()
+	defer ctx.Release()
+
+	var buf bytes.Buffer
+	if err := template.nodes.Render(&buf, ctx); err == nil {
+		t.Error("Expected the sandbox to block random_string")
+	}
+}
@@ -0,0 +1,184 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RemoteTemplate is a template returned by a Fetcher
+type RemoteTemplate struct {
+	Source      string
+	ETag        string    // Version tag passed back to the next fetch
+	ModTime     time.Time // Last change, the fetch time when zero
+	NotModified bool      // The cached copy matching the requested etag is current
+}
+
+// Fetcher retrieves templates from a remote store such as an HTTP server,
+// S3 or a database. etag is the tag of the cached copy, empty when there is
+// none; a fetcher that supports tags may answer with NotModified set instead
+// of the source. Missing templates are reported with an error wrapping
+// ErrTemplateNotFound.
+type Fetcher interface {
+	Fetch(name, etag string) (*RemoteTemplate, error)
+}
+
+// FetcherFunc adapts a function to the Fetcher interface
+type FetcherFunc func(name, etag string) (*RemoteTemplate, error)
+
+// Fetch calls f
+func (f FetcherFunc) Fetch(name, etag string) (*RemoteTemplate, error) {
+	return f(name, etag)
+}
+
+// RemoteLoader loads templates through a Fetcher and caches them for a TTL.
+// Expired templates are revalidated with their etag, templates that do not
+// exist are remembered for the negative TTL, and a template that fails to
+// refresh keeps being served from the cache until the fetcher recovers.
+type RemoteLoader struct {
+	fetcher     Fetcher
+	ttl         time.Duration
+	negativeTTL time.Duration
+	clock       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*remoteEntry
+}
+
+// remoteEntry is the cached state of one template
+type remoteEntry struct {
+	source   string
+	etag     string
+	modified int64
+	missing  bool
+	expires  time.Time
+}
+
+// NewRemoteLoader creates a loader that caches fetched templates for ttl.
+// Missing templates are cached for the same duration unless
+// SetNegativeTTL says otherwise.
+func NewRemoteLoader(fetcher Fetcher, ttl time.Duration) *RemoteLoader {
+	return &RemoteLoader{
+		fetcher:     fetcher,
+		ttl:         ttl,
+		negativeTTL: ttl,
+		clock:       time.Now,
+		entries:     make(map[string]*remoteEntry),
+	}
+}
+
+// SetNegativeTTL sets how long a missing template is remembered before the
+// fetcher is asked again
+func (l *RemoteLoader) SetNegativeTTL(ttl time.Duration) {
+	l.mu.Lock()
+	l.negativeTTL = ttl
+	l.mu.Unlock()
+}
+
+// Invalidate expires the cached copy of a template, for example when a CMS
+// reports that it was edited, so the next load fetches it again
+func (l *RemoteLoader) Invalidate(name string) {
+	l.mu.Lock()
+	if entry, ok := l.entries[name]; ok {
+		entry.expires = time.Time{}
+	}
+	l.mu.Unlock()
+}
+
+// Load implements the Loader interface
+func (l *RemoteLoader) Load(name string) (string, error) {
+	entry, err := l.entry(name)
+	if err != nil {
+		return "", err
+	}
+	return entry.source, nil
+}
+
+// Exists checks if the fetcher has the template
+func (l *RemoteLoader) Exists(name string) bool {
+	_, err := l.entry(name)
+	return err == nil
+}
+
+// GetModifiedTime returns the last change of a template, so engines with
+// auto-reload pick up new versions once the cached copy expires
+func (l *RemoteLoader) GetModifiedTime(name string) (int64, error) {
+	entry, err := l.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return entry.modified, nil
+}
+
+// entry returns the current cache entry for name, fetching it when it has
+// expired
+func (l *RemoteLoader) entry(name string) (remoteEntry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	cached, ok := l.entries[name]
+	if ok && now.Before(cached.expires) {
+		return cached.result(name)
+	}
+
+	etag := ""
+	if ok && !cached.missing {
+		etag = cached.etag
+	}
+
+	fetched, err := l.fetcher.Fetch(name, etag)
+	switch {
+	case errors.Is(err, ErrTemplateNotFound):
+		missing := &remoteEntry{missing: true, expires: now.Add(l.negativeTTL)}
+		l.entries[name] = missing
+		return missing.result(name)
+	case err != nil:
+		if ok && !cached.missing {
+			LogWarning("Serving cached template '%s' after fetch error: %v", name, err)
+			return *cached, nil
+		}
+		return remoteEntry{}, fmt.Errorf("failed to fetch template %s: %w", name, err)
+	case fetched == nil:
+		return remoteEntry{}, fmt.Errorf("failed to fetch template %s: fetcher returned no template", name)
+	}
+
+	if fetched.NotModified {
+		if !ok || cached.missing {
+			return remoteEntry{}, fmt.Errorf("failed to fetch template %s: not modified without a cached copy", name)
+		}
+		cached.expires = now.Add(l.ttl)
+		return *cached, nil
+	}
+
+	// The modification time only moves forward when the source changes, even
+	// for edits within the same second, so engines reparse exactly once
+	modified := fetched.ModTime.Unix()
+	if fetched.ModTime.IsZero() {
+		modified = now.Unix()
+	}
+	if ok && !cached.missing {
+		if cached.source == fetched.Source {
+			modified = cached.modified
+		} else if modified <= cached.modified {
+			modified = cached.modified + 1
+		}
+	}
+	entry := &remoteEntry{
+		source:   fetched.Source,
+		etag:     fetched.ETag,
+		modified: modified,
+		expires:  now.Add(l.ttl),
+	}
+	l.entries[name] = entry
+	return *entry, nil
+}
+
+// result returns the entry, or the not found error for a missing template
+func (e *remoteEntry) result(name string) (remoteEntry, error) {
+	if e.missing {
+		return remoteEntry{}, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return *e, nil
+}
@@ -0,0 +1,140 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeStore is a template database whose rows carry a version used as etag
+type fakeStore struct {
+	templates map[string]string
+	versions  map[string]int
+	fetches   int
+	down      bool
+}
+
+func (s *fakeStore) Fetch(name, etag string) (*RemoteTemplate, error) {
+	s.fetches++
+	if s.down {
+		return nil, errors.New("database unavailable")
+	}
+	source, ok := s.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	version := fmt.Sprint(s.versions[name])
+	if etag == version {
+		return &RemoteTemplate{NotModified: true}, nil
+	}
+	return &RemoteTemplate{Source: source, ETag: version}, nil
+}
+
+func (s *fakeStore) update(name, source string) {
+	s.templates[name] = source
+	s.versions[name]++
+}
+
+func TestRemoteLoaderCaching(t *testing.T) {
+	store := &fakeStore{templates: map[string]string{}, versions: map[string]int{}}
+	store.update("page", "v1")
+
+	now := time.Unix(1000, 0)
+	loader := NewRemoteLoader(store, time.Minute)
+	loader.SetNegativeTTL(10 * time.Second)
+	loader.clock = func() time.Time { return now }
+
+	load := func(expected string) {
+		t.Helper()
+		source, err := loader.Load("page")
+		if err != nil {
+			t.Fatalf("Error loading template: %v", err)
+		}
+		if source != expected {
+			t.Errorf("Expected %q, got %q", expected, source)
+		}
+	}
+
+	load("v1")
+	load("v1")
+	if store.fetches != 1 {
+		t.Errorf("Expected a cached template within the TTL, got %d fetches", store.fetches)
+	}
+
+	// Edits become visible once the TTL expires
+	store.update("page", "v2")
+	load("v1")
+	now = now.Add(2 * time.Minute)
+	load("v2")
+
+	// An expired but unchanged template is revalidated by etag
+	now = now.Add(2 * time.Minute)
+	load("v2")
+	if store.fetches != 3 {
+		t.Errorf("Expected 3 fetches, got %d", store.fetches)
+	}
+
+	// A failing store keeps serving the cached copy
+	store.down = true
+	now = now.Add(2 * time.Minute)
+	load("v2")
+	store.down = false
+
+	// Missing templates are remembered for the negative TTL
+	fetches := store.fetches
+	for i := 0; i < 3; i++ {
+		if _, err := loader.Load("missing"); !errors.Is(err, ErrTemplateNotFound) {
+			t.Fatalf("Expected ErrTemplateNotFound, got %v", err)
+		}
+	}
+	if store.fetches != fetches+1 {
+		t.Errorf("Expected one fetch for a missing template, got %d", store.fetches-fetches)
+	}
+	store.update("missing", "found")
+	now = now.Add(11 * time.Second)
+	if !loader.Exists("missing") {
+		t.Error("Expected the template to be found after the negative TTL")
+	}
+
+	// Invalidate forces a fetch
+	store.update("page", "v3")
+	loader.Invalidate("page")
+	load("v3")
+}
+
+func TestRemoteLoaderWithEngine(t *testing.T) {
+	store := &fakeStore{templates: map[string]string{}, versions: map[string]int{}}
+	store.update("base.twig", `<body>{% block content %}{% endblock %}</body>`)
+	store.update("nav.twig", `<nav>{{ title }}</nav>`)
+	store.update("page.twig", `{% extends "base.twig" %}{% block content %}{% include "nav.twig" %}{% endblock %}`)
+
+	loader := NewRemoteLoader(store, time.Minute)
+	engine := New()
+	engine.SetAutoReload(true)
+	engine.RegisterLoader(loader)
+
+	result, err := engine.Render("page.twig", map[string]interface{}{"title": "Home"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	expected := "<body><nav>Home</nav></body>"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// An edit within the same second is still picked up after invalidation
+	store.update("nav.twig", `<nav>{{ title }}!</nav>`)
+	loader.Invalidate("nav.twig")
+	result, err = engine.Render("page.twig", map[string]interface{}{"title": "Home"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "<body><nav>Home!</nav></body>" {
+		t.Errorf("Expected the edited include, got %q", result)
+	}
+
+	if _, err := engine.Render("missing.twig", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
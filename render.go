@@ -13,6 +13,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/semihalev/twig/loader"
 )
 
 // RenderContext holds the state during template rendering
@@ -29,6 +31,19 @@ type RenderContext struct {
 	inParentCall       bool       // Flag to indicate if we're currently rendering a parent() call
 	sandboxed          bool       // Flag indicating if this context is sandboxed
 	lastLoadedTemplate *Template  // The template that created this context (for resolving relative paths)
+
+	// Error reporting: where this context sits in the include chain
+	templateStack []TemplateFrame      // Templates that included/extended/imported this one, outermost first
+	blockOwners   map[string]*Template // Template that defined each overriding block
+
+	features map[string]bool        // Feature flags already evaluated during this render, shared by its contexts
+	services map[string]interface{} // Service variables already created during this render
+	profile  *OutputProfile         // Output sizes recorded during this render, if profiling
+	options  *RenderOptions         // Overrides of the engine settings for this render
+	visits   *visitCounter          // Node visits of this render, if limited
+	labels   *labelState            // pprof labels of this render, if enabled
+
+	loopScope bool // Whether this is the child scope of a for loop
 }
 
 // contextMapPool is a pool for the maps used in RenderContext
@@ -113,6 +128,16 @@ func NewRenderContext(env *Environment, context map[string]interface{}, engine *
 	ctx.parent = nil
 	ctx.inParentCall = false
 	ctx.sandboxed = false
+	ctx.lastLoadedTemplate = nil
+	ctx.templateStack = nil
+	ctx.blockOwners = nil
+	ctx.features = nil
+	ctx.services = nil
+	ctx.profile = nil
+	ctx.options = nil
+	ctx.visits = nil
+	ctx.labels = nil
+	ctx.loopScope = false
 
 	// Copy the context values directly
 	if context != nil {
@@ -130,6 +155,15 @@ func (ctx *RenderContext) Release() {
 	ctx.env = nil
 	ctx.engine = nil
 	ctx.currentBlock = nil
+	ctx.lastLoadedTemplate = nil
+	ctx.templateStack = nil
+	ctx.blockOwners = nil
+	ctx.features = nil
+	ctx.services = nil
+	ctx.profile = nil
+	ctx.options = nil
+	ctx.visits = nil
+	ctx.labels = nil
 
 	// Save the maps so we can return them to their respective pools
 	contextMap := ctx.context
@@ -181,11 +215,15 @@ func (ctx *RenderContext) Release() {
 
 // Error types
 var (
-	ErrTemplateNotFound = errors.New("template not found")
+	ErrTemplateNotFound = loader.ErrTemplateNotFound
 	ErrUndefinedVar     = errors.New("undefined variable")
 	ErrInvalidAttribute = errors.New("invalid attribute access")
 	ErrCompilation      = errors.New("compilation error")
 	ErrRender           = errors.New("render error")
+	ErrTemplateTooLarge = errors.New("template too large")
+	ErrOutputTooLarge   = errors.New("output too large")
+
+	ErrTooManyNodeVisits = errors.New("too many node visits")
 )
 
 // GetVariable gets a variable from the context
@@ -275,6 +313,21 @@ func (ctx *RenderContext) GetVariable(name string) (interface{}, error) {
 		return ctx.parent.GetVariable(name)
 	}
 
+	// Services are created on first use
+	if value, ok := ctx.resolveService(name); ok {
+		return value, nil
+	}
+
+	// Namespaces resolve their attributes through a provider
+	if value, ok := ctx.resolveNamespace(name); ok {
+		return value, nil
+	}
+
+	// The now variable is the current time unless a template or global sets it
+	if name == "now" && ctx.env != nil {
+		return ctx.env.now(), nil
+	}
+
 	// Return nil with no error for undefined variables
 	// Twig treats undefined variables as empty strings during rendering
 	return nil, nil
@@ -288,6 +341,10 @@ func (ctx *RenderContext) GetVariableOrNil(name string) interface{} {
 
 // SetVariable sets a variable in the context
 func (ctx *RenderContext) SetVariable(name string, value interface{}) {
+	if ctx.loopScope {
+		ctx.setScoped(name, value)
+		return
+	}
 	ctx.context[name] = value
 }
 
@@ -328,12 +385,23 @@ func (ctx *RenderContext) Clone() *RenderContext {
 	newCtx.currentBlock = nil
 	newCtx.parent = ctx
 	newCtx.inParentCall = false
+	newCtx.loopScope = false
 
 	// Inherit sandbox state
 	newCtx.sandboxed = ctx.sandboxed
 
 	// Copy the lastLoadedTemplate reference (crucial for relative path resolution)
 	newCtx.lastLoadedTemplate = ctx.lastLoadedTemplate
+	newCtx.templateStack = ctx.templateStack
+	newCtx.profile = ctx.profile
+	newCtx.options = ctx.options
+	newCtx.visits = ctx.visits
+	newCtx.labels = ctx.labels
+	newCtx.features = ctx.features
+	newCtx.blockOwners = nil
+	for name, owner := range ctx.blockOwners {
+		newCtx.setBlockOwner(name, owner)
+	}
 
 	// Ensure maps are initialized (they should be from the pool already)
 	if newCtx.context == nil {
@@ -385,6 +453,60 @@ func (ctx *RenderContext) Clone() *RenderContext {
 	return newCtx
 }
 
+// templateName returns the name of the template rendered by this context
+func (ctx *RenderContext) templateName() string {
+	if ctx.lastLoadedTemplate == nil {
+		return ""
+	}
+	return ctx.lastLoadedTemplate.name
+}
+
+// childTemplateStack returns the include chain for a template entered from
+// the given line of the template rendered by this context
+func (ctx *RenderContext) childTemplateStack(line int) []TemplateFrame {
+	if ctx.lastLoadedTemplate == nil {
+		return ctx.templateStack
+	}
+
+	// Always copy so sibling contexts never share a backing array
+	stack := make([]TemplateFrame, len(ctx.templateStack), len(ctx.templateStack)+1)
+	copy(stack, ctx.templateStack)
+	return append(stack, TemplateFrame{Template: ctx.lastLoadedTemplate.name, Line: line})
+}
+
+// setBlockOwner records the template that defined the content of a block
+func (ctx *RenderContext) setBlockOwner(name string, owner *Template) {
+	if owner == nil {
+		return
+	}
+	if ctx.blockOwners == nil {
+		ctx.blockOwners = make(map[string]*Template)
+	}
+	ctx.blockOwners[name] = owner
+}
+
+// wrapError attaches the current template, line and include chain to an error
+// that does not carry location information yet
+func (ctx *RenderContext) wrapError(err error, line int) error {
+	if err == nil {
+		return nil
+	}
+
+	var enhanced *EnhancedError
+	if errors.As(err, &enhanced) {
+		return err
+	}
+
+	source := ""
+	if ctx.lastLoadedTemplate != nil {
+		source = ctx.lastLoadedTemplate.source
+	}
+
+	wrapped := NewError(err, ctx.templateName(), line, 0, source).(*EnhancedError)
+	wrapped.Stack = ctx.templateStack
+	return wrapped
+}
+
 // GetMacro gets a macro from the context
 func (ctx *RenderContext) GetMacro(name string) (interface{}, bool) {
 	// Check local macros first
@@ -463,18 +585,30 @@ func (ctx *RenderContext) CallFunction(name string, args []interface{}) (interfa
 		return ctx.callMaxFunction(args)
 	case "min":
 		return ctx.callMinFunction(args)
+	case "feature":
+		return ctx.callFeatureFunction(args)
+	case "csp_nonce":
+		return ctx.callCSPNonceFunction(args)
+	case "attribute":
+		return ctx.callAttributeFunction(args)
+	case "include_raw":
+		return ctx.callIncludeRaw(args)
 	}
 
 	// Check if it's a macro
 	if macro, ok := ctx.GetMacro(name); ok {
-		// Return a callable function
-		return func(w io.Writer) error {
-			macroNode, ok := macro.(*MacroNode)
-			if !ok {
-				return fmt.Errorf("'%s' is not a macro", name)
-			}
-			return macroNode.CallMacro(w, ctx, args...)
-		}, nil
+		macroNode, ok := macro.(*MacroNode)
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not a macro", name)
+		}
+		return ctx.macroValue(macroNode, args)
+	}
+
+	// Check if it's a func passed in the context
+	if value, err := ctx.GetVariable(name); err == nil {
+		if fn := reflect.ValueOf(value); fn.Kind() == reflect.Func && !fn.IsNil() {
+			return callFunc(fn, name, args)
+		}
 	}
 
 	return nil, fmt.Errorf("function '%s' not found", name)
@@ -530,6 +664,9 @@ func (ctx *RenderContext) callLengthFunction(args []interface{}) (interface{}, e
 	}
 
 	val := args[0]
+	if n, ok := collectionLen(val); ok {
+		return n, nil
+	}
 	v := reflect.ValueOf(val)
 
 	switch v.Kind() {
@@ -657,6 +794,9 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 	if node == nil {
 		return nil, nil
 	}
+	if err := ctx.visit(node); err != nil {
+		return nil, err
+	}
 
 	// Check sandbox security if enabled
 	if ctx.sandboxed && ctx.env.securityPolicy != nil {
@@ -686,6 +826,11 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 		return ctx.GetVariable(n.name)
 
 	case *GetAttrNode:
+		// config.site.title on a namespace is a single lookup
+		if value, _, ok := ctx.namespaceAttr(n); ok {
+			return value, nil
+		}
+
 		obj, err := ctx.EvaluateExpression(n.node)
 		if err != nil {
 			return nil, err
@@ -704,11 +849,15 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 		// Check if obj is a map containing macros (from import)
 		if moduleMap, ok := obj.(map[string]interface{}); ok {
 			if macro, ok := moduleMap[attrStr]; ok {
-				return macro, nil
+				return ctx.autoInvoke(macro, attrStr)
 			}
 		}
 
-		return ctx.getAttribute(obj, attrStr)
+		value, err := ctx.getAttribute(obj, attrStr)
+		if err != nil {
+			return nil, err
+		}
+		return ctx.autoInvoke(value, attrStr)
 
 	case *GetItemNode:
 		// Evaluate the container (array, slice, map)
@@ -731,14 +880,15 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		operator := ctx.resolveOperator(n.operator)
 
 		// Implement short-circuit evaluation for logical operators
-		if n.operator == "and" || n.operator == "&&" {
+		if operator == "and" || operator == "&&" {
 			// For "and" operator, if left side is false, return false without evaluating right side
 			if !ctx.toBool(left) {
 				return false, nil
 			}
-		} else if n.operator == "or" || n.operator == "||" {
+		} else if operator == "or" || operator == "||" {
 			// For "or" operator, if left side is true, return true without evaluating right side
 			if ctx.toBool(left) {
 				return true, nil
@@ -751,7 +901,10 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 			return nil, err
 		}
 
-		return ctx.evaluateBinaryOp(n.operator, left, right)
+		return ctx.evaluateBinaryOp(operator, left, right)
+
+	case *ConcatNode:
+		return ctx.evaluateConcat(n)
 
 	case *ConditionalNode:
 		// Evaluate the condition
@@ -855,16 +1008,26 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 						LogVerbose("Found macro '%s' in module map", n.name)
 					}
 
-					// If the macro is a MacroNode, return a callable to render it
+					// If the macro is a MacroNode, call it or return a callable to render it
 					if macroNode, ok := macroObj.(*MacroNode); ok {
-						// Return a callable that can be rendered later
-						return func(w io.Writer) error {
-							return macroNode.CallMacro(w, ctx, args...)
-						}, nil
+						return ctx.macroValue(macroNode, args)
+					}
+
+					// Function values stored in maps, such as pager.window(5)
+					switch fn := macroObj.(type) {
+					case FunctionFunc:
+						return fn(args...)
+					case func(...interface{}) (interface{}, error):
+						return fn(args...)
 					}
 				}
 			}
 
+			// Funcs in context values and methods, such as user.permissions('admin')
+			if fn, ok := callableValue(moduleObj, n.name); ok {
+				return callFunc(fn, n.name, args)
+			}
+
 			// Fallback - try calling it like a regular function
 			if IsDebugEnabled() && debugger.level >= DebugVerbose {
 				LogVerbose("Fallback - calling '%s' as a regular function", n.name)
@@ -891,14 +1054,11 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 				args[i] = val
 			}
 
-			// Return a callable that can be rendered later
-			return func(w io.Writer) error {
-				macroNode, ok := macro.(*MacroNode)
-				if !ok {
-					return fmt.Errorf("'%s' is not a macro", n.name)
-				}
-				return macroNode.CallMacro(w, ctx, args...)
-			}, nil
+			macroNode, ok := macro.(*MacroNode)
+			if !ok {
+				return nil, fmt.Errorf("'%s' is not a macro", n.name)
+			}
+			return ctx.macroValue(macroNode, args)
 		}
 
 		// Otherwise, it's a regular function call
@@ -941,79 +1101,15 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 		return result, nil
 
 	case *TestNode:
-		// Handle special "not defined" test (from parseBinaryExpression)
-		if n.test == "not defined" {
-			// Check if it's a variable reference
-			if varNode, ok := n.node.(*VariableNode); ok {
-				// Check directly in context
-				if ctx.context != nil {
-					_, exists := ctx.context[varNode.name]
-					if exists {
-						// If it exists, "not defined" is false
-						return false, nil
-					}
-				}
-
-				// Try full variable lookup
-				val, err := ctx.GetVariable(varNode.name)
-				// Return true if not defined (err != nil or val is nil)
-				return err != nil || val == nil, nil
-			}
-
-			// For non-variable nodes, assume defined
-			return false, nil
-		}
-
-		// Special handling for "is defined" test with attribute access
-		if n.test == "defined" {
-			// Check if this is a GetAttrNode
-			if getAttrNode, ok := n.node.(*GetAttrNode); ok {
-				// Evaluate the object
-				obj, err := ctx.EvaluateExpression(getAttrNode.node)
-				if err != nil {
-					return false, nil // If can't evaluate the object, it's not defined
-				}
-
-				// If obj is nil, attribute not defined
-				if obj == nil {
-					return false, nil
-				}
-
-				// Evaluate the attribute name
-				attrNameNode, err := ctx.EvaluateExpression(getAttrNode.attribute)
-				if err != nil {
-					return false, nil
-				}
-
-				attrName, ok := attrNameNode.(string)
-				if !ok {
-					return false, nil
-				}
-
-				// For maps, directly check if the key exists
-				if objMap, ok := obj.(map[string]interface{}); ok {
-					_, exists := objMap[attrName]
-					return exists, nil
-				}
-
-				// For other types, try to get the attribute but catch the error
-				_, err = ctx.getAttribute(obj, attrName)
-				return err == nil, nil
-			}
-
-			// Check for simple variable references
-			if varNode, ok := n.node.(*VariableNode); ok {
-				// Check directly in context
-				if ctx.context != nil {
-					_, exists := ctx.context[varNode.name]
-					if exists {
-						return true, nil
-					}
-				}
-
-				// Try full variable lookup
-				val, err := ctx.GetVariable(varNode.name)
-				return err == nil && val != nil, nil
+		// Defined tests probe variables, attributes and items rather than
+		// evaluating them, see isDefined
+		if n.test == "defined" || n.test == "not defined" {
+			if defined, handled := ctx.isDefined(n.node); handled {
+				return defined == (n.test == "defined"), nil
+			}
+			if n.test == "not defined" {
+				// For other nodes, assume defined
+				return false, nil
 			}
 		}
 
@@ -1038,12 +1134,17 @@ func (ctx *RenderContext) EvaluateExpression(node Node) (interface{}, error) {
 
 		// Look for the test in the environment
 		if ctx.env != nil {
-			if test, ok := ctx.env.tests[n.test]; ok {
+			if test, ok := ctx.env.tests[ctx.resolveTest(n.test)]; ok {
 				// Call the test with the value and any arguments
 				return test(value, args...)
 			}
 		}
 
+		// Feature flags need the render context, so they are not a registered test
+		if n.test == "enabled" {
+			return ctx.featureEnabled(value)
+		}
+
 		return false, fmt.Errorf("test '%s' not found", n.test)
 
 	case *UnaryNode:
@@ -1094,26 +1195,150 @@ type attributeCacheEntry struct {
 	accessCount int       // How many times this entry has been accessed
 }
 
-// attributeCache caches attribute lookups by type and attribute name
+// Attribute cache defaults
+const (
+	defaultAttributeCacheSize = 1000 // Limit the cache to prevent unbounded growth
+	defaultAttributeEviction  = 0.1  // Evict 10% of entries when the cache is full
+
+	// maxAttributeCacheKeyLen is the longest attribute name cached. Longer
+	// names, usually from dynamic attribute access, are resolved each time.
+	maxAttributeCacheKeyLen = 128
+)
+
+// attributeCacheStore caches attribute lookups by type and attribute name.
 // Uses a simplified LRU strategy for eviction - when cache fills up,
-// we remove 10% of the least recently used entries to make room
-var attributeCache = struct {
+// we remove 10% of the least recently used entries to make room.
+// Each engine has its own store; the zero value is ready to use.
+type attributeCacheStore struct {
 	sync.RWMutex
 	m           map[attributeCacheKey]attributeCacheEntry
-	maxSize     int     // Maximum number of entries to cache
+	maxSize     int     // Maximum number of entries to cache, default when 0
 	currSize    int     // Current number of entries
-	evictionPct float64 // Percentage of cache to evict when full (0.0-1.0)
-}{
-	m:           make(map[attributeCacheKey]attributeCacheEntry),
-	maxSize:     1000, // Limit cache to 1000 entries to prevent unbounded growth
-	evictionPct: 0.1,  // Evict 10% of entries when cache is full
+	evictionPct float64 // Percentage of cache to evict when full (0.0-1.0), default when 0
+}
+
+// attributeCache is used by render contexts without an engine
+var attributeCache attributeCacheStore
+
+// attributeCache returns the attribute cache of the context's engine
+func (ctx *RenderContext) attributeCache() *attributeCacheStore {
+	if ctx.engine != nil {
+		return &ctx.engine.attributes
+	}
+	return &attributeCache
+}
+
+// lookup returns how attr is read from values of objType, resolving and
+// caching it on first use
+func (c *attributeCacheStore) lookup(objType reflect.Type, attr string) attributeCacheEntry {
+	if len(attr) > maxAttributeCacheKeyLen {
+		return resolveAttribute(objType, attr)
+	}
+
+	key := attributeCacheKey{
+		typ:  objType,
+		attr: attr,
+	}
+
+	// Get a read lock to check the cache first
+	c.RLock()
+	entry, found := c.m[key]
+	c.RUnlock()
+
+	c.Lock()
+	defer c.Unlock()
+
+	if found {
+		// Update the entry's access statistics, checking again after
+		// acquiring the write lock
+		if cachedEntry, stillExists := c.m[key]; stillExists {
+			cachedEntry.lastAccess = time.Now()
+			cachedEntry.accessCount++
+			c.m[key] = cachedEntry
+			entry = cachedEntry
+		}
+		return entry
+	}
+
+	// Double-check if another goroutine added it while we were waiting
+	if entry, found = c.m[key]; found {
+		return entry
+	}
+
+	// Check if cache has reached maximum size
+	maxSize := c.maxSize
+	if maxSize == 0 {
+		maxSize = defaultAttributeCacheSize
+	}
+	if c.currSize >= maxSize {
+		// Cache is full, use our LRU eviction strategy
+		c.evictLRUEntries(maxSize)
+	}
+
+	entry = resolveAttribute(objType, attr)
+	if c.m == nil {
+		c.m = make(map[attributeCacheKey]attributeCacheEntry)
+	}
+	c.m[key] = entry
+	c.currSize++
+	return entry
+}
+
+// clear removes all cached lookups
+func (c *attributeCacheStore) clear() {
+	c.Lock()
+	defer c.Unlock()
+	c.m = nil
+	c.currSize = 0
+}
+
+// resolveAttribute finds the field or method that provides attr
+func resolveAttribute(objType reflect.Type, attr string) attributeCacheEntry {
+	// Create a new entry with current timestamp
+	entry := attributeCacheEntry{
+		fieldIndex:  -1,
+		methodIndex: -1,
+		lastAccess:  time.Now(),
+		accessCount: 1,
+	}
+
+	// Look for a field
+	if objType.Kind() == reflect.Struct {
+		field, found := objType.FieldByName(attr)
+		if found {
+			entry.fieldIndex = field.Index[0] // Assuming single-level field access
+		}
+	}
+
+	// Look for a method on the value
+	method, found := objType.MethodByName(attr)
+	if found && method.Type.NumIn() == 1 { // The receiver is the first argument
+		entry.isMethod = true
+		entry.methodIndex = method.Index
+	} else {
+		// Look for a method on the pointer to the value
+		ptrType := reflect.PtrTo(objType)
+		method, found := ptrType.MethodByName(attr)
+		if found && method.Type.NumIn() == 1 {
+			entry.isMethod = true
+			entry.ptrMethod = true
+			entry.methodIndex = method.Index
+		}
+	}
+
+	return entry
 }
 
 // evictLRUEntries removes the least recently used entries from the cache
-// This function assumes that the caller holds the attributeCache lock
-func evictLRUEntries() {
+// This function assumes that the caller holds the cache lock
+func (c *attributeCacheStore) evictLRUEntries(maxSize int) {
+	evictionPct := c.evictionPct
+	if evictionPct == 0 {
+		evictionPct = defaultAttributeEviction
+	}
+
 	// Calculate how many entries to evict
-	numToEvict := int(float64(attributeCache.maxSize) * attributeCache.evictionPct)
+	numToEvict := int(float64(maxSize) * evictionPct)
 	if numToEvict < 1 {
 		numToEvict = 1 // Always evict at least one entry
 	}
@@ -1124,8 +1349,8 @@ func evictLRUEntries() {
 		entry attributeCacheEntry
 	}
 
-	entries := make([]cacheItem, 0, attributeCache.currSize)
-	for k, v := range attributeCache.m {
+	entries := make([]cacheItem, 0, c.currSize)
+	for k, v := range c.m {
 		entries = append(entries, cacheItem{k, v})
 	}
 
@@ -1141,13 +1366,15 @@ func evictLRUEntries() {
 
 	// Remove the oldest entries
 	for i := 0; i < numToEvict && i < len(entries); i++ {
-		delete(attributeCache.m, entries[i].key)
-		attributeCache.currSize--
+		delete(c.m, entries[i].key)
+		c.currSize--
 	}
 }
 
 // getItem gets an item from a container (array, slice, map) by index or key
-func (ctx *RenderContext) getItem(container, index interface{}) (interface{}, error) {
+func (ctx *RenderContext) getItem(container, index interface{}) (value interface{}, err error) {
+	defer recoverItem(&err, index, container)
+
 	if container == nil {
 		return nil, nil
 	}
@@ -1159,11 +1386,11 @@ func (ctx *RenderContext) getItem(container, index interface{}) (interface{}, er
 	// Handle different container types
 	switch c := container.(type) {
 	case []interface{}:
-		// Check bounds
-		if intIndex < 0 || intIndex >= len(c) {
+		i, ok := itemIndex(intIndex, len(c))
+		if !ok {
 			return nil, fmt.Errorf("array index out of bounds: %d", intIndex)
 		}
-		return c[intIndex], nil
+		return c[i], nil
 
 	case map[string]interface{}:
 		// Try string key
@@ -1181,17 +1408,56 @@ func (ctx *RenderContext) getItem(container, index interface{}) (interface{}, er
 
 		return nil, nil // Nil for missing keys
 
+	case []string:
+		i, ok := itemIndex(intIndex, len(c))
+		if !ok {
+			return nil, fmt.Errorf("array index out of bounds: %d", intIndex)
+		}
+		return c[i], nil
+
+	case []int:
+		i, ok := itemIndex(intIndex, len(c))
+		if !ok {
+			return nil, fmt.Errorf("array index out of bounds: %d", intIndex)
+		}
+		return c[i], nil
+
+	case map[string]string:
+		if value, exists := c[ctx.ToString(index)]; exists {
+			return value, nil
+		}
+		return nil, nil
+
+	case map[string]int:
+		if value, exists := c[ctx.ToString(index)]; exists {
+			return value, nil
+		}
+		return nil, nil
+
 	default:
+		if value, ok, err := ctx.collectionItem(container, index); ok {
+			return value, err
+		}
+
 		// Use reflection for other types
 		v := reflect.ValueOf(container)
 
 		switch v.Kind() {
 		case reflect.Slice, reflect.Array:
-			// Check bounds
-			if intIndex < 0 || intIndex >= v.Len() {
+			i, ok := itemIndex(intIndex, v.Len())
+			if !ok {
 				return nil, fmt.Errorf("array index out of bounds: %d", intIndex)
 			}
-			return v.Index(intIndex).Interface(), nil
+			return v.Index(i).Interface(), nil
+
+		case reflect.String:
+			// Strings are indexed by character, like the slice filter
+			runes := []rune(v.String())
+			i, ok := itemIndex(intIndex, len(runes))
+			if !ok {
+				return nil, fmt.Errorf("string index out of bounds: %d", intIndex)
+			}
+			return string(runes[i]), nil
 
 		case reflect.Map:
 			// Try to find the key
@@ -1223,21 +1489,61 @@ func (ctx *RenderContext) getItem(container, index interface{}) (interface{}, er
 	return nil, nil // Default nil for non-indexable types
 }
 
+// itemIndex resolves an index into a sequence of the given length, where
+// negative indexes count from the end as in the slice filter, so items[-1]
+// is the last item. ok is false when the index is out of range.
+func itemIndex(index, length int) (int, bool) {
+	if index < 0 {
+		index += length
+	}
+	return index, index >= 0 && index < length
+}
+
 // getAttribute gets an attribute from an object
-func (ctx *RenderContext) getAttribute(obj interface{}, attr string) (interface{}, error) {
+func (ctx *RenderContext) getAttribute(obj interface{}, attr string) (value interface{}, err error) {
+	defer recoverAttribute(&err, attr, obj)
+
 	if obj == nil {
 		// Instead of returning an error for nil objects, return nil value
 		return nil, nil
 	}
 
-	// Fast path for maps
-	if objMap, ok := obj.(map[string]interface{}); ok {
-		if value, exists := objMap[attr]; exists {
+	// Fast paths for common map types. For non-existent keys in maps,
+	// return nil instead of an error.
+	switch m := obj.(type) {
+	case map[string]interface{}:
+		if value, exists := m[attr]; exists {
+			return value, nil
+		}
+		return nil, nil
+	case map[string]string:
+		if value, exists := m[attr]; exists {
 			return value, nil
 		}
-
-		// For non-existent keys in maps, return nil instead of an error
 		return nil, nil
+	case map[string]int:
+		if value, exists := m[attr]; exists {
+			return value, nil
+		}
+		return nil, nil
+	case map[string]int64:
+		if value, exists := m[attr]; exists {
+			return value, nil
+		}
+		return nil, nil
+	case map[string]float64:
+		if value, exists := m[attr]; exists {
+			return value, nil
+		}
+		return nil, nil
+	case map[string]bool:
+		if value, exists := m[attr]; exists {
+			return value, nil
+		}
+		return nil, nil
+	case AttrGetter:
+		value, _ := m.GetAttr(attr)
+		return value, nil
 	}
 
 	// Get the reflect.Value and type for the object
@@ -1250,90 +1556,21 @@ func (ctx *RenderContext) getAttribute(obj interface{}, attr string) (interface{
 		objValue = objValue.Elem()
 	}
 
-	// Only use caching for struct types
-	if objValue.Kind() != reflect.Struct {
-		// Instead of returning an error for non-struct types, return nil
+	switch objValue.Kind() {
+	case reflect.Struct:
+	case reflect.Map:
+		// Other maps with string keys are indexed by the attribute name.
+		// Keys win over methods of named map types, as with structs fields.
+		if value, ok := mapAttribute(objValue, attr); ok {
+			return value, nil
+		}
+	default:
+		// Instead of returning an error for other types, return nil
 		return nil, nil
 	}
 
 	objType := objValue.Type()
-
-	// Create a cache key
-	key := attributeCacheKey{
-		typ:  objType,
-		attr: attr,
-	}
-
-	// Get a read lock to check the cache first
-	attributeCache.RLock()
-	entry, found := attributeCache.m[key]
-	if found {
-		// Found in cache, update access stats later with a write lock
-		attributeCache.RUnlock()
-
-		// Update the entry's access statistics with a write lock
-		attributeCache.Lock()
-		// Need to check again after acquiring write lock
-		if cachedEntry, stillExists := attributeCache.m[key]; stillExists {
-			// Update access time and count
-			cachedEntry.lastAccess = time.Now()
-			cachedEntry.accessCount++
-			attributeCache.m[key] = cachedEntry
-			entry = cachedEntry
-		}
-		attributeCache.Unlock()
-	} else {
-		// Not found in cache - release read lock and get write lock for update
-		attributeCache.RUnlock()
-		attributeCache.Lock()
-
-		// Double-check if another goroutine added it while we were waiting
-		entry, found = attributeCache.m[key]
-		if !found {
-			// Still not found, need to populate the cache
-
-			// Check if cache has reached maximum size
-			if attributeCache.currSize >= attributeCache.maxSize {
-				// Cache is full, use our LRU eviction strategy
-				evictLRUEntries()
-			}
-
-			// Create a new entry with current timestamp
-			entry = attributeCacheEntry{
-				fieldIndex:  -1,
-				methodIndex: -1,
-				lastAccess:  time.Now(),
-				accessCount: 1,
-			}
-
-			// Look for a field
-			field, found := objType.FieldByName(attr)
-			if found {
-				entry.fieldIndex = field.Index[0] // Assuming single-level field access
-			}
-
-			// Look for a method on the value
-			method, found := objType.MethodByName(attr)
-			if found && method.Type.NumIn() == 1 { // The receiver is the first argument
-				entry.isMethod = true
-				entry.methodIndex = method.Index
-			} else {
-				// Look for a method on the pointer to the value
-				ptrType := reflect.PtrTo(objType)
-				method, found := ptrType.MethodByName(attr)
-				if found && method.Type.NumIn() == 1 {
-					entry.isMethod = true
-					entry.ptrMethod = true
-					entry.methodIndex = method.Index
-				}
-			}
-
-			// Store in cache
-			attributeCache.m[key] = entry
-			attributeCache.currSize++
-		}
-		attributeCache.Unlock()
-	}
+	entry := ctx.attributeCache().lookup(objType, attr)
 
 	// Use the cached lookup information to get the attribute
 
@@ -1378,27 +1615,52 @@ func (ctx *RenderContext) getAttribute(obj interface{}, attr string) (interface{
 	return nil, nil
 }
 
+// mapAttribute looks up attr in a map whose keys are strings or named
+// string types
+func mapAttribute(m reflect.Value, attr string) (interface{}, bool) {
+	keyType := m.Type().Key()
+	if keyType.Kind() != reflect.String {
+		return nil, false
+	}
+
+	value := m.MapIndex(reflect.ValueOf(attr).Convert(keyType))
+	if !value.IsValid() {
+		return nil, false
+	}
+	return value.Interface(), true
+}
+
+// evaluateConcat evaluates a concatenation chain into a single pooled buffer
+func (ctx *RenderContext) evaluateConcat(n *ConcatNode) (interface{}, error) {
+	buf := GetBuffer()
+	defer buf.Release()
+
+	for _, part := range n.parts {
+		value, err := ctx.EvaluateExpression(part)
+		if err != nil {
+			return nil, err
+		}
+
+		if str, ok := value.(string); ok {
+			buf.WriteString(str)
+		} else {
+			buf.WriteString(ctx.ToString(value))
+		}
+	}
+
+	return buf.String(), nil
+}
+
 // evaluateBinaryOp evaluates a binary operation
 func (ctx *RenderContext) evaluateBinaryOp(operator string, left, right interface{}) (interface{}, error) {
-	// Check for the special case: 'not defined' test
-	if operator == "not" && (right == "defined" || right == string("defined")) {
-		// Special case for variable node check
-		if varNode, ok := left.(*VariableNode); ok {
-			// Get the variable name from the node
-			varName := varNode.name
-			// Check if the variable exists in the context
-			_, err := ctx.GetVariable(varName)
-			// Variable not defined = return true, otherwise false
-			return err != nil, nil
-		}
-		// Default to false for other cases
-		return false, nil
+	// Registered comparators decide comparisons between custom types
+	if result, ok := ctx.compareOperation(operator, left, right); ok {
+		return result, nil
 	}
 
-	// For standard 'not' operator
-	if operator == "not" {
-		// Handle regular boolean negation
-		return !ctx.toBool(right), nil
+	// Arithmetic involving decimal types is done exactly instead of via float64
+	if hasDecimalOperand(operator, left, right) {
+		return applyDecimalOperation(operator, left, right)
 	}
 
 	switch operator {
@@ -1433,6 +1695,9 @@ func (ctx *RenderContext) evaluateBinaryOp(operator string, left, right interfac
 				return lNum * rNum, nil
 			}
 		}
+		if result, ok, err := ctx.repeatOperation(left, right); ok {
+			return result, err
+		}
 
 	case "/":
 		if lNum, lok := ctx.toNumber(left); lok {
@@ -1455,7 +1720,7 @@ func (ctx *RenderContext) evaluateBinaryOp(operator string, left, right interfac
 			}
 		}
 
-	case "^":
+	case "**", "^":
 		// Exponentiation operator
 		if lNum, lok := ctx.toNumber(left); lok {
 			if rNum, rok := ctx.toNumber(right); rok {
@@ -1606,36 +1871,6 @@ func (ctx *RenderContext) contains(container, item interface{}) (bool, error) {
 		// Use string conversion only once
 		return strings.Contains(c, ctx.ToString(item)), nil
 	case []interface{}:
-		// For small slices, linear search is fine
-		// For larger slices (>50 items), consider a map-based approach
-		if len(c) > 50 {
-			// Create a temporary map for O(1) lookups
-			// Only worth doing for sufficiently large slices
-			tempMap := make(map[interface{}]struct{}, len(c))
-			for _, v := range c {
-				tempMap[v] = struct{}{}
-			}
-
-			// For numeric items, try direct lookup first
-			if _, ok := tempMap[item]; ok {
-				return true, nil
-			}
-
-			// For string-comparable items, try string version
-			if _, ok := tempMap[ctx.ToString(item)]; ok {
-				return true, nil
-			}
-
-			// Fall back to deep equality comparison
-			for k := range tempMap {
-				if ctx.equals(k, item) {
-					return true, nil
-				}
-			}
-			return false, nil
-		}
-
-		// For small slices, linear search
 		for _, v := range c {
 			if ctx.equals(v, item) {
 				return true, nil
@@ -1655,34 +1890,6 @@ func (ctx *RenderContext) contains(container, item interface{}) (bool, error) {
 	case reflect.String:
 		return strings.Contains(rv.String(), ctx.ToString(item)), nil
 	case reflect.Array, reflect.Slice:
-		// Optimize for large slices/arrays
-		if rv.Len() > 50 {
-			// Same map-based optimization as above
-			tempMap := make(map[interface{}]struct{}, rv.Len())
-			for i := 0; i < rv.Len(); i++ {
-				tempMap[rv.Index(i).Interface()] = struct{}{}
-			}
-
-			// Try direct lookup
-			if _, ok := tempMap[item]; ok {
-				return true, nil
-			}
-
-			// Try string-based lookup
-			if _, ok := tempMap[ctx.ToString(item)]; ok {
-				return true, nil
-			}
-
-			// Fall back to equality comparison
-			for k := range tempMap {
-				if ctx.equals(k, item) {
-					return true, nil
-				}
-			}
-			return false, nil
-		}
-
-		// For small collections, linear search
 		for i := 0; i < rv.Len(); i++ {
 			if ctx.equals(rv.Index(i).Interface(), item) {
 				return true, nil
@@ -1709,6 +1916,11 @@ func (ctx *RenderContext) equals(a, b interface{}) bool {
 		return false
 	}
 
+	// Times are equal when they denote the same instant, whatever the location
+	if c, ok := compareTimes(a, b); ok {
+		return c == 0
+	}
+
 	// Try numeric comparison
 	if aNum, aok := ctx.toNumber(a); aok {
 		if bNum, bok := ctx.toNumber(b); bok {
@@ -1716,6 +1928,11 @@ func (ctx *RenderContext) equals(a, b interface{}) bool {
 		}
 	}
 
+	// Maps, lists and structs are compared by content, see equality.go
+	if equal, ok := ctx.structuralEquals(a, b); ok {
+		return equal
+	}
+
 	// Try string comparison
 	return ctx.ToString(a) == ctx.ToString(b)
 }
@@ -1762,6 +1979,9 @@ func (ctx *RenderContext) toNumber(val interface{}) (float64, bool) {
 			return 1, true
 		}
 		return 0, true
+	case time.Time:
+		// Unix nanoseconds keep times ordered when used as numbers
+		return float64(v.UnixNano()), true
 	}
 
 	// Try reflection for custom types
@@ -1847,8 +2067,29 @@ func (ctx *RenderContext) ToString(val interface{}) string {
 		return strconv.FormatBool(v)
 	case []byte:
 		return string(v)
+	}
+
+	// Give registered stringers a chance to format domain types
+	if ctx != nil && ctx.env != nil {
+		for _, stringer := range ctx.env.stringers {
+			if str, ok := stringer(val); ok {
+				return str
+			}
+		}
+	}
+
+	var env *Environment
+	if ctx != nil {
+		env = ctx.env
+	}
+
+	switch v := val.(type) {
+	case error:
+		return env.formatError(v)
 	case fmt.Stringer:
-		return v.String()
+		return env.stringerString(v)
+	case io.Reader:
+		return readerString(v)
 	}
 
 	return fmt.Sprintf("%v", val)
@@ -5,19 +5,35 @@ import (
 	"strings"
 )
 
+// UndefinedFilterMode controls what happens when a template applies a filter
+// that is not registered in the environment
+type UndefinedFilterMode int
+
+const (
+	// UndefinedFilterError aborts rendering with an error (default)
+	UndefinedFilterError UndefinedFilterMode = iota
+	// UndefinedFilterPassThrough logs a warning and returns the value unchanged
+	UndefinedFilterPassThrough
+	// UndefinedFilterResolve asks the configured resolver for the filter and
+	// falls back to an error if it cannot provide one
+	UndefinedFilterResolve
+)
+
+// UndefinedFilterResolver looks up a filter that is not registered in the environment
+type UndefinedFilterResolver func(name string) (FilterFunc, bool)
+
 // ApplyFilter applies a filter to a value
 func (ctx *RenderContext) ApplyFilter(name string, value interface{}, args ...interface{}) (interface{}, error) {
-	// Look for the filter in the environment
-	if ctx.env != nil {
-		if filter, ok := ctx.env.filters[name]; ok {
-			result, err := filter(value, args...)
-			if err != nil {
-				return nil, err
-			}
+	args = ctx.filterDefaults(name, args)
 
-			// We've moved the script-specific string handling to PrintNode.Render
-			return result, nil
+	// Look for the filter in the environment
+	if result, ok, err := ctx.applyFilterArgs(name, value, FilterArgs{Positional: args}); ok {
+		if err != nil {
+			return nil, err
 		}
+
+		// We've moved the script-specific string handling to PrintNode.Render
+		return result, nil
 	}
 
 	// Handle built-in filters for macro compatibility
@@ -53,6 +69,30 @@ func (ctx *RenderContext) ApplyFilter(name string, value interface{}, args ...in
 		}
 
 		return b.String(), nil
+
+	case "map_macro":
+		return ctx.mapMacro(value, args)
+	}
+
+	return ctx.applyUndefinedFilter(name, value, args...)
+}
+
+// applyUndefinedFilter handles a filter that is not registered according to
+// the environment's undefined filter mode
+func (ctx *RenderContext) applyUndefinedFilter(name string, value interface{}, args ...interface{}) (interface{}, error) {
+	if ctx.env != nil {
+		switch ctx.env.undefinedFilterMode {
+		case UndefinedFilterPassThrough:
+			LogWarning("Unknown filter '%s' - passing value through unchanged", name)
+			return value, nil
+
+		case UndefinedFilterResolve:
+			if ctx.env.undefinedFilterResolver != nil {
+				if filter, ok := ctx.env.undefinedFilterResolver(name); ok && filter != nil {
+					return filter(value, args...)
+				}
+			}
+		}
 	}
 
 	return nil, fmt.Errorf("filter '%s' not found", name)
@@ -60,8 +100,10 @@ func (ctx *RenderContext) ApplyFilter(name string, value interface{}, args ...in
 
 // FilterChainItem represents a single filter in a chain
 type FilterChainItem struct {
-	name string
-	args []interface{}
+	name  string
+	args  []interface{}
+	named map[string]interface{}
+	line  int
 }
 
 // DetectFilterChain analyzes a filter node and extracts all filters in the chain
@@ -98,10 +140,24 @@ func (ctx *RenderContext) DetectFilterChain(node Node) (Node, []FilterChainItem,
 			args[j] = val
 		}
 
+		var named map[string]interface{}
+		if len(filterNode.named) > 0 {
+			named = make(map[string]interface{}, len(filterNode.named))
+			for name, arg := range filterNode.named {
+				val, err := ctx.EvaluateExpression(arg)
+				if err != nil {
+					return nil, nil, err
+				}
+				named[name] = val
+			}
+		}
+
 		// Add to the chain in the correct position
 		chain[i] = FilterChainItem{
-			name: filterNode.filter,
-			args: args,
+			name:  filterNode.filter,
+			args:  args,
+			named: named,
+			line:  filterNode.Line(),
 		}
 
 		// Continue with the next node
@@ -120,7 +176,10 @@ func (ctx *RenderContext) ApplyFilterChain(baseValue interface{}, chain []Filter
 
 	// Apply each filter in the chain
 	for _, filter := range chain {
-		result, err = ctx.ApplyFilter(filter.name, result, filter.args...)
+		if ctx.strictFilterArgs() {
+			ctx.checkFilterArgs(filter.name, filter.args, filter.line)
+		}
+		result, err = ctx.applyChainItem(filter, result)
 		if err != nil {
 			return nil, err
 		}
@@ -129,6 +188,21 @@ func (ctx *RenderContext) ApplyFilterChain(baseValue interface{}, chain []Filter
 	return result, nil
 }
 
+// applyChainItem applies one filter of a chain, passing its named arguments
+// to filters registered with AddFilterV2
+func (ctx *RenderContext) applyChainItem(filter FilterChainItem, value interface{}) (interface{}, error) {
+	if len(filter.named) == 0 {
+		return ctx.ApplyFilter(filter.name, value, filter.args...)
+	}
+
+	args := FilterArgs{Positional: ctx.filterDefaults(filter.name, filter.args), Named: filter.named}
+	result, ok, err := ctx.applyFilterArgs(filter.name, value, args)
+	if !ok {
+		return ctx.applyUndefinedFilter(filter.name, value, args.Positional...)
+	}
+	return result, err
+}
+
 // Override for the original FilterNode evaluation in render.go
 func (ctx *RenderContext) evaluateFilterNode(n *FilterNode) (interface{}, error) {
 	// Detect the complete filter chain
@@ -0,0 +1,110 @@
+package twig
+
+import "time"
+
+// RenderOptions override engine settings for a single render, so one
+// engine can render the same template for several locales at once. Zero
+// fields keep the engine's settings. Pass them to Render or RenderTo.
+type RenderOptions struct {
+	// Locale is returned by RenderContext.Locale, for filters and functions
+	// that translate or localize their output
+	Locale string
+
+	// Timezone is used by the date filter when no timezone is passed
+	Timezone *time.Location
+
+	// EscapeStrategy is the strategy of the escape filter when none is
+	// passed, html or xml
+	EscapeStrategy string
+
+	// StrictFilterArgs overrides SetStrictFilterArgs when not nil
+	StrictFilterArgs *bool
+
+	diagnostics *diagnostics // Errors recorded by RenderCollect
+}
+
+// mergeRenderOptions combines the options passed to a render, later
+// non-zero fields winning. It returns nil when no options were passed.
+func mergeRenderOptions(opts []RenderOptions) *RenderOptions {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	merged := opts[0]
+	for _, o := range opts[1:] {
+		if o.Locale != "" {
+			merged.Locale = o.Locale
+		}
+		if o.Timezone != nil {
+			merged.Timezone = o.Timezone
+		}
+		if o.EscapeStrategy != "" {
+			merged.EscapeStrategy = o.EscapeStrategy
+		}
+		if o.StrictFilterArgs != nil {
+			merged.StrictFilterArgs = o.StrictFilterArgs
+		}
+		if o.diagnostics != nil {
+			merged.diagnostics = o.diagnostics
+		}
+	}
+	return &merged
+}
+
+// renderOptions returns the options of the render as a slice for passing
+// them on to RenderTo
+func (ctx *RenderContext) renderOptions() []RenderOptions {
+	if ctx.options == nil {
+		return nil
+	}
+	return []RenderOptions{*ctx.options}
+}
+
+// Locale returns the locale of the current render, set with
+// RenderOptions, or "" when none was given
+func (ctx *RenderContext) Locale() string {
+	if ctx.options == nil {
+		return ""
+	}
+	return ctx.options.Locale
+}
+
+// Timezone returns the timezone of the current render, set with
+// RenderOptions, or nil when none was given
+func (ctx *RenderContext) Timezone() *time.Location {
+	if ctx.options == nil {
+		return nil
+	}
+	return ctx.options.Timezone
+}
+
+// strictFilterArgs reports whether filter arguments are checked in this render
+func (ctx *RenderContext) strictFilterArgs() bool {
+	if ctx.options != nil && ctx.options.StrictFilterArgs != nil {
+		return *ctx.options.StrictFilterArgs
+	}
+	return ctx.env != nil && ctx.env.strictFilterArgs
+}
+
+// filterDefaults adds the render's defaults to the arguments of filters
+// that take them: the escaping strategy of escape and the timezone of date
+func (ctx *RenderContext) filterDefaults(name string, args []interface{}) []interface{} {
+	if ctx.options == nil {
+		return args
+	}
+
+	switch name {
+	case "escape", "e":
+		if len(args) == 0 && ctx.options.EscapeStrategy != "" {
+			return []interface{}{ctx.options.EscapeStrategy}
+		}
+	case "date":
+		if len(args) < 2 && ctx.options.Timezone != nil {
+			withZone := make([]interface{}, 2)
+			copy(withZone, args)
+			withZone[1] = ctx.options.Timezone
+			return withZone
+		}
+	}
+	return args
+}
@@ -0,0 +1,114 @@
+package twig
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRenderOptions(t *testing.T) {
+	engine := New()
+	engine.AddFilterV2("greet", func(value interface{}, args FilterArgs) (interface{}, error) {
+		if args.Context != nil && args.Context.Locale() == "fr" {
+			return "Bonjour " + toString(value), nil
+		}
+		return "Hello " + toString(value), nil
+	})
+
+	source := "{{ name|greet }} {{ when|date('H:i') }}{% include 'part' %}"
+	if err := engine.RegisterString("page", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := engine.RegisterString("part", " {{ name|greet }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("Timezone data not available: %v", err)
+	}
+	context := map[string]interface{}{
+		"name": "Ann",
+		"when": time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		options  RenderOptions
+		expected string
+	}{
+		{RenderOptions{}, "Hello Ann 12:00 Hello Ann"},
+		{RenderOptions{Locale: "fr", Timezone: paris}, "Bonjour Ann 13:00 Bonjour Ann"},
+		{RenderOptions{Locale: "en", Timezone: time.FixedZone("X", -2*3600)}, "Hello Ann 10:00 Hello Ann"},
+	}
+
+	template, err := engine.Load("page")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+
+	// The renders share the template and run at the same time
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for _, tt := range tests {
+			wg.Add(1)
+			go func(options RenderOptions, expected string) {
+				defer wg.Done()
+				var buf bytes.Buffer
+				if err := template.RenderTo(&buf, context, options); err != nil {
+					t.Errorf("Error rendering template: %v", err)
+					return
+				}
+				if buf.String() != expected {
+					t.Errorf("Expected %q, got %q", expected, buf.String())
+				}
+			}(tt.options, tt.expected)
+		}
+	}
+	wg.Wait()
+
+	result, err := engine.Render("page", context, tests[1].options)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != tests[1].expected {
+		t.Errorf("Expected %q, got %q", tests[1].expected, result)
+	}
+}
+
+func TestRenderOptionsEscapeAndStrictness(t *testing.T) {
+	engine := New()
+
+	var warnings []FilterArgWarning
+	engine.SetStrictFilterArgs(true, func(warning FilterArgWarning) {
+		warnings = append(warnings, warning)
+	})
+
+	template, err := engine.ParseTemplate("{{ \"<a href='x'>\"|e }}{{ 'x'|trim(0) }}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	off := false
+	result, err := template.Render(nil, RenderOptions{EscapeStrategy: "xml", StrictFilterArgs: &off})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "&lt;a href=&apos;x&apos;&gt;x" {
+		t.Errorf("Expected XML escaping, got %q", result)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Expected strict filter arguments to be off, got %v", warnings)
+	}
+
+	result, err = template.Render(nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "&lt;a href=&#39;x&#39;&gt;x" {
+		t.Errorf("Expected HTML escaping, got %q", result)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("Expected one strict filter argument warning, got %v", warnings)
+	}
+}
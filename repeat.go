@@ -0,0 +1,105 @@
+package twig
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// maxRepeatLength caps the characters or items produced by repetition, so
+// '-' * count with a huge count fails instead of exhausting memory
+const maxRepeatLength = 1 << 24
+
+// SetListRepetition sets whether the * operator repeats lists, so that
+// [0] * 3 is [0, 0, 0] as in Python. It is off by default, like in Twig,
+// where multiplying a list is an error. String repetition is always on.
+func (e *Engine) SetListRepetition(enabled bool) {
+	e.environment.listRepetition = enabled
+}
+
+// repeatOperation implements string * count and count * string, and list
+// repetition when enabled. It reports false for other operands, which are
+// multiplied as numbers.
+func (ctx *RenderContext) repeatOperation(left, right interface{}) (interface{}, bool, error) {
+	value, count := left, right
+	if _, ok := ctx.toNumber(left); ok {
+		value, count = right, left
+	}
+	if _, ok := ctx.toNumber(count); !ok {
+		return nil, false, nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+	case reflect.Slice, reflect.Array:
+		if ctx.env == nil || !ctx.env.listRepetition {
+			return nil, false, nil
+		}
+	default:
+		return nil, false, nil
+	}
+
+	n, err := repeatCount(count)
+	if err != nil {
+		return nil, true, err
+	}
+	result, err := repeatValue(value, n)
+	return result, true, err
+}
+
+// filterRepeat implements value|repeat(count) for strings and lists
+func (e *CoreExtension) filterRepeat(value interface{}, args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("repeat filter requires a count")
+	}
+	n, err := repeatCount(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return "", nil
+	}
+	return repeatValue(value, n)
+}
+
+// repeatCount converts a repetition count, which must be a whole number
+// that is not negative
+func repeatCount(count interface{}) (int, error) {
+	f, err := toFloat64(count)
+	if err != nil || f != math.Trunc(f) || f < 0 {
+		return 0, fmt.Errorf("repeat count must be a non-negative integer, got %v", count)
+	}
+	if f > maxRepeatLength {
+		return 0, fmt.Errorf("repeat count %v exceeds the limit of %d", count, maxRepeatLength)
+	}
+	return int(f), nil
+}
+
+// repeatValue repeats a string or the items of a list n times
+func repeatValue(value interface{}, n int) (interface{}, error) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		if n > 0 && len(s) > maxRepeatLength/n {
+			return nil, fmt.Errorf("repeated string exceeds the limit of %d bytes", maxRepeatLength)
+		}
+		return strings.Repeat(s, n), nil
+
+	case reflect.Slice, reflect.Array:
+		length := v.Len()
+		if n > 0 && length > maxRepeatLength/n {
+			return nil, fmt.Errorf("repeated list exceeds the limit of %d items", maxRepeatLength)
+		}
+		result := make([]interface{}, 0, length*n)
+		for i := 0; i < n; i++ {
+			for j := 0; j < length; j++ {
+				result = append(result, v.Index(j).Interface())
+			}
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("repeat expects a string or a list, got %T", value)
+}
@@ -0,0 +1,64 @@
+package twig
+
+import (
+	"testing"
+)
+
+func TestStringRepetition(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"string times count", "{{ '-' * 5 }}", "-----"},
+		{"count times string", "{{ 3 * 'ab' }}", "ababab"},
+		{"variable count", "{{ '=' * width }}", "===="},
+		{"zero", "[{{ 'x' * 0 }}]", "[]"},
+		{"numeric strings still multiply", "{{ '3' * 2 }}", "6"},
+		{"filter", "{{ '=-'|repeat(3) }}", "=-=-=-"},
+		{"filter on a list", "{{ [1, 2]|repeat(2)|join(',') }}", "1,2,1,2"},
+		{"filter on null", "[{{ none|repeat(2) }}]", "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", map[string]interface{}{"width": 4, "none": nil})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	for _, source := range []string{"{{ '-' * -1 }}", "{{ '-' * 1.5 }}", "{{ '-'|repeat(-2) }}", "{{ 'ab' * 100000000 }}", "{{ [0] * 3 }}"} {
+		if err := engine.RegisterString("page", source); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+		if _, err := engine.Render("page", nil); err == nil {
+			t.Errorf("Expected an error for %q", source)
+		}
+	}
+}
+
+func TestListRepetition(t *testing.T) {
+	engine := New()
+	engine.SetListRepetition(true)
+
+	if err := engine.RegisterString("page", "{{ ([0] * 3)|join(',') }}|{{ (2 * items)|join(',') }}|{{ ('-' * 2) }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", map[string]interface{}{"items": []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if expected := "0,0,0|a,b,a,b|--"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
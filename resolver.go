@@ -0,0 +1,48 @@
+package twig
+
+import "errors"
+
+// TemplateNameResolver returns the names to try, in order, for a template
+// requested by a render call or an include, given the variables of the
+// render. It lets applications serve variants such as page.mobile.twig or
+// page.amp.twig without changing call sites. The requested name is tried
+// last when the resolver does not list it.
+type TemplateNameResolver func(requested string, context map[string]interface{}) []string
+
+// SetTemplateNameResolver sets the resolver consulted before loading
+// templates for Render, RenderTo and include. Nil removes it.
+func (e *Engine) SetTemplateNameResolver(resolver TemplateNameResolver) {
+	e.nameResolver = resolver
+}
+
+// loadResolved loads the first candidate of the name resolver that exists
+func (e *Engine) loadResolved(name string, context map[string]interface{}) (*Template, error) {
+	if e.nameResolver == nil {
+		return e.Load(name)
+	}
+
+	candidates := e.nameResolver(name, context)
+	requested := false
+	for _, candidate := range candidates {
+		if candidate == name {
+			requested = true
+		}
+	}
+	if !requested {
+		candidates = append(candidates, name)
+	}
+
+	var err error
+	for _, candidate := range candidates {
+		var template *Template
+		template, err = e.Load(candidate)
+		if err == nil {
+			return template, nil
+		}
+		// A variant that exists but fails to parse is reported, not skipped
+		if !errors.Is(err, ErrTemplateNotFound) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,64 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateNameResolver(t *testing.T) {
+	// Mobile requests prefer a .mobile.twig variant of each template
+	resolver := func(requested string, context map[string]interface{}) []string {
+		if context["device"] != "mobile" {
+			return nil
+		}
+		return []string{strings.TrimSuffix(requested, ".twig") + ".mobile.twig"}
+	}
+
+	templates := map[string]string{
+		"page.twig":        `<page>{% include "nav.twig" %}{% include "footer.twig" %}</page>`,
+		"page.mobile.twig": `<mpage>{% include "nav.twig" %}{% include "footer.twig" %}</mpage>`,
+		"nav.twig":         `<nav>`,
+		"nav.mobile.twig":  `<mnav>`,
+		"footer.twig":      `<footer>`,
+	}
+
+	tests := []struct {
+		name     string
+		device   string
+		expected string
+	}{
+		{name: "desktop", device: "desktop", expected: "<page><nav><footer></page>"},
+		{name: "mobile variants with fallback", device: "mobile", expected: "<mpage><mnav><footer></mpage>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.RegisterLoader(NewArrayLoader(templates))
+			engine.SetTemplateNameResolver(resolver)
+
+			result, err := engine.Render("page.twig", map[string]interface{}{"device": tt.device})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemplateNameResolverReportsBrokenVariant(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"page.twig":     "ok",
+		"page.amp.twig": "{% if %}",
+	}))
+	engine.SetTemplateNameResolver(func(requested string, context map[string]interface{}) []string {
+		return []string{"page.amp.twig"}
+	})
+
+	if _, err := engine.Render("page.twig", nil); err == nil {
+		t.Error("Expected the parse error of the variant")
+	}
+}
@@ -0,0 +1,119 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ReturnNode represents a {% return expr %} tag. A macro containing one is
+// a function: calling it in an expression evaluates to the returned value,
+// as in {% set discounted = helpers.discount(price, 0.2) %}, and its output
+// is discarded. A function that ends without returning evaluates to nil.
+type ReturnNode struct {
+	value Node
+	line  int
+}
+
+func (n *ReturnNode) Type() NodeType {
+	return NodeReturn
+}
+
+func (n *ReturnNode) Line() int {
+	return n.line
+}
+
+// Render evaluates the value and stops the enclosing macro
+func (n *ReturnNode) Render(w io.Writer, ctx *RenderContext) error {
+	var value interface{}
+	if n.value != nil {
+		var err error
+		value, err = ctx.EvaluateExpression(n.value)
+		if err != nil {
+			return ctx.wrapError(err, n.line)
+		}
+	}
+	return &macroReturn{value: value}
+}
+
+// macroReturn carries a returned value up through the nodes of a macro body
+type macroReturn struct {
+	value interface{}
+}
+
+func (r *macroReturn) Error() string {
+	return "return tag used outside of a macro"
+}
+
+// returnedValue unwraps the value of a return tag from a render error
+func returnedValue(err error) (interface{}, bool) {
+	var ret *macroReturn
+	if errors.As(err, &ret) {
+		return ret.value, true
+	}
+	return nil, false
+}
+
+// parseReturn parses {% return %} and {% return expr %}
+func (p *Parser) parseReturn(parser *Parser) (Node, error) {
+	returnLine := parser.tokens[parser.tokenIndex-2].Line
+
+	var value Node
+	if parser.tokenIndex < len(parser.tokens) &&
+		parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END &&
+		parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END_TRIM {
+		var err error
+		value, err = parser.parseExpression()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing expression in return tag at line %d: %w", returnLine, err)
+		}
+	}
+
+	if parser.tokenIndex >= len(parser.tokens) ||
+		(parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END &&
+			parser.tokens[parser.tokenIndex].Type != TOKEN_BLOCK_END_TRIM) {
+		return nil, fmt.Errorf("expecting end of return tag at line %d", returnLine)
+	}
+	parser.tokenIndex++
+
+	return &ReturnNode{value: value, line: returnLine}, nil
+}
+
+// returnFinder looks for return tags in a macro body, ignoring nested macros
+type returnFinder struct {
+	found bool
+}
+
+func (f *returnFinder) Enter(node Node) bool {
+	switch node.(type) {
+	case *ReturnNode:
+		f.found = true
+	case *MacroNode:
+		return false
+	}
+	return !f.found
+}
+
+func (f *returnFinder) Leave(node Node) {}
+
+// containsReturn reports whether a macro body has a return tag
+func containsReturn(body []Node) bool {
+	finder := &returnFinder{}
+	for _, node := range body {
+		Walk(node, finder)
+	}
+	return finder.found
+}
+
+// macroValue calls a macro from an expression. Functions, macros with a
+// return tag, run immediately and yield their value; other macros yield a
+// callable that renders their output when printed.
+func (ctx *RenderContext) macroValue(macro *MacroNode, args []interface{}) (interface{}, error) {
+	if !macro.returns {
+		return func(w io.Writer) error {
+			return macro.CallMacro(w, ctx, args...)
+		}, nil
+	}
+
+	return macro.call(io.Discard, ctx, args)
+}
@@ -0,0 +1,55 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReturnTag(t *testing.T) {
+	helpers := `{% macro discount(price, rate) %}ignored output{% return price - price * rate %}{% endmacro %}` +
+		`{% macro grade(score) %}{% for limit in [90, 80] %}{% if score >= limit %}{% return limit %}{% endif %}{% endfor %}{% return 0 %}{% endmacro %}` +
+		`{% macro nothing() %}{% if false %}{% return 1 %}{% endif %}{% endmacro %}` +
+		`{% macro greet(name) %}Hello {{ name }}{% endmacro %}`
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"set from imported function", `{% import "helpers" as h %}{% set d = h.discount(100, 0.2) %}{{ d }}`, "80"},
+		{"used in expressions", `{% import "helpers" as h %}{{ h.discount(50, 0.5) * 2 }}`, "50"},
+		{"return from a loop", `{% import "helpers" as h %}{{ h.grade(85) }} {{ h.grade(95) }} {{ h.grade(10) }}`, "80 90 0"},
+		{"no return reached", `{% import "helpers" as h %}[{{ h.nothing() }}]`, "[]"},
+		{"macros still print", `{% import "helpers" as h %}{{ h.greet('Bob') }}`, "Hello Bob"},
+		{"local function", `{% macro twice(n) %}{% return n * 2 %}{% endmacro %}{{ twice(21) }}`, "42"},
+		{"from import", `{% from "helpers" import discount %}{{ discount(10, 0.5) }}`, "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("helpers", helpers); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	engine := New()
+	if err := engine.RegisterString("test", `{% return 1 %}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("test", nil); err == nil || !strings.Contains(err.Error(), "outside of a macro") {
+		t.Errorf("Expected an error for return outside a macro, got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RouteResolver generates the path of a named route, allowing router
+// integrations to back the path and url functions
+type RouteResolver interface {
+	Resolve(name string, params map[string]interface{}) (string, error)
+}
+
+// RouteResolverFunc adapts a function to the RouteResolver interface
+type RouteResolverFunc func(name string, params map[string]interface{}) (string, error)
+
+// Resolve calls f(name, params)
+func (f RouteResolverFunc) Resolve(name string, params map[string]interface{}) (string, error) {
+	return f(name, params)
+}
+
+// routeArgs extracts the route name and parameters from function arguments
+func routeArgs(function string, args []interface{}) (string, map[string]interface{}, error) {
+	if len(args) == 0 {
+		return "", nil, fmt.Errorf("%s function requires a route name", function)
+	}
+
+	name := toString(args[0])
+	params := map[string]interface{}{}
+	if len(args) > 1 && args[1] != nil {
+		p, ok := args[1].(map[string]interface{})
+		if !ok {
+			return "", nil, fmt.Errorf("%s function expects route parameters as a hash, got %T", function, args[1])
+		}
+		params = p
+	}
+
+	return name, params, nil
+}
+
+// resolveRoute generates the path of a route with the engine's route resolver
+func (e *CoreExtension) resolveRoute(function string, args []interface{}) (string, error) {
+	name, params, err := routeArgs(function, args)
+	if err != nil {
+		return "", err
+	}
+
+	if e.env == nil || e.env.routeResolver == nil {
+		return "", errors.New("no route resolver configured, use SetRouteResolver")
+	}
+
+	return e.env.routeResolver.Resolve(name, params)
+}
+
+// functionPath returns the relative path of a named route
+func (e *CoreExtension) functionPath(args ...interface{}) (interface{}, error) {
+	return e.resolveRoute("path", args)
+}
+
+// functionURL returns the absolute URL of a named route using the base URL
+func (e *CoreExtension) functionURL(args ...interface{}) (interface{}, error) {
+	routePath, err := e.resolveRoute("url", args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolvers may already return absolute URLs
+	if strings.Contains(routePath, "://") || e.env.baseURL == "" {
+		return routePath, nil
+	}
+
+	return joinAssetURL(e.env.baseURL, routePath), nil
+}
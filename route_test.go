@@ -0,0 +1,105 @@
+package twig
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testRoutes is a minimal router used to back the path and url functions
+func testRoutes(name string, params map[string]interface{}) (string, error) {
+	routes := map[string]string{
+		"home":      "/",
+		"user_show": "/users/{id}",
+		"search":    "/search",
+	}
+
+	pattern, ok := routes[name]
+	if !ok {
+		return "", fmt.Errorf("unknown route %q", name)
+	}
+
+	// Fill placeholders, passing the remaining parameters as a query string
+	query := url.Values{}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := fmt.Sprint(params[key])
+		placeholder := "{" + key + "}"
+		if strings.Contains(pattern, placeholder) {
+			pattern = strings.ReplaceAll(pattern, placeholder, url.PathEscape(value))
+		} else {
+			query.Set(key, value)
+		}
+	}
+	if len(query) > 0 {
+		pattern += "?" + query.Encode()
+	}
+	return pattern, nil
+}
+
+func TestRouteFunctions(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Path without parameters", "{{ path('home') }}", "/"},
+		{"Path with parameters", "{{ path('user_show', {'id': 42}) }}", "/users/42"},
+		{"Extra parameters become query", "{{ path('search', {'q': 'go twig'}) }}", "/search?q=go+twig"},
+		{"Parameters from context", "{{ path('user_show', {'id': user.id}) }}", "/users/7"},
+		{"Absolute URL", "{{ url('user_show', {'id': 42}) }}", "https://example.com/users/42"},
+		{"Absolute URL for root", "{{ url('home') }}", "https://example.com/"},
+		{"In attribute", `<a href="{{ path('home') }}">home</a>`, `<a href="/">home</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetRouteResolver(RouteResolverFunc(testRoutes))
+			engine.SetBaseURL("https://example.com")
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"user": map[string]interface{}{"id": 7}})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	errorTests := []struct {
+		name     string
+		source   string
+		resolver RouteResolver
+		message  string
+	}{
+		{"Unknown route", "{{ path('missing') }}", RouteResolverFunc(testRoutes), "unknown route"},
+		{"Parameters not a hash", "{{ path('home', 'x') }}", RouteResolverFunc(testRoutes), "as a hash"},
+		{"No resolver", "{{ url('home') }}", nil, "no route resolver"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if tt.resolver != nil {
+				engine.SetRouteResolver(tt.resolver)
+			}
+			engine.RegisterString("test", tt.source)
+
+			_, err := engine.Render("test", nil)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package runtime defines the contract between templates and Go code at
+// render time: the signatures of filters, functions, tests, operators and
+// stringers, the interfaces custom collections implement, and comparators.
+// It does not depend on the engine, so extensions and the types they expose
+// to templates can import it alone; the twig package keeps aliases of these
+// names for a deprecation period.
+package runtime
+
+// FilterFunc is a function that can be used as a filter
+type FilterFunc func(value interface{}, args ...interface{}) (interface{}, error)
+
+// FunctionFunc is a function that can be used in templates
+type FunctionFunc func(args ...interface{}) (interface{}, error)
+
+// TestFunc is a function that can be used for testing conditions
+type TestFunc func(value interface{}, args ...interface{}) (bool, error)
+
+// OperatorFunc is a function that implements a custom operator
+type OperatorFunc func(left, right interface{}) (interface{}, error)
+
+// StringerFunc converts a value to its template string form, reporting
+// false when it does not handle the value's type
+type StringerFunc func(value interface{}) (string, bool)
+
+// Lengther is implemented by collections that know their size. The length
+// filter, length/count functions and for loops call Len instead of using
+// reflection.
+type Lengther interface {
+	Len() int
+}
+
+// Indexer is implemented by sequences. For loops iterate from 0 to Len()-1
+// and items[i] calls At, so the backing storage stays unexported.
+type Indexer interface {
+	Lengther
+	At(i int) interface{}
+}
+
+// AttrGetter is implemented by objects that resolve their own attributes.
+// Both obj.name and obj['name'] call GetAttr; false means the attribute is
+// missing and evaluates to nil, as with map keys.
+type AttrGetter interface {
+	GetAttr(name string) (interface{}, bool)
+}
+
+// Keyer is implemented by mappings that list their keys. For loops visit the
+// keys in the order returned, reading each value with GetAttr.
+type Keyer interface {
+	AttrGetter
+	Keys() []string
+}
+
+// Comparator orders values of custom types such as version strings, money
+// or time.Time. Compare returns a negative number, zero or a positive number
+// when a is less than, equal to or greater than b, and false when it does not
+// handle the pair, in which case the default comparison is used.
+type Comparator interface {
+	Compare(a, b interface{}) (int, bool)
+}
+
+// ComparatorFunc adapts a function to the Comparator interface
+type ComparatorFunc func(a, b interface{}) (int, bool)
+
+// Compare calls f(a, b)
+func (f ComparatorFunc) Compare(a, b interface{}) (int, bool) {
+	return f(a, b)
+}
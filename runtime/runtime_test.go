@@ -0,0 +1,42 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/semihalev/twig"
+	"github.com/semihalev/twig/runtime"
+)
+
+// settings is a mapping implemented against the runtime package only
+type settings map[string]string
+
+func (s settings) GetAttr(name string) (interface{}, bool) {
+	value, ok := s[name]
+	return value, ok
+}
+
+func (s settings) Keys() []string {
+	return []string{"theme", "lang"}
+}
+
+var _ runtime.Keyer = settings(nil)
+
+func TestRuntimeTypes(t *testing.T) {
+	engine := twig.New()
+	var shout runtime.FilterFunc = func(value interface{}, args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(value.(string)) + "!", nil
+	}
+	engine.AddFilter("shout", shout)
+
+	if err := engine.RegisterString("page", "{% for k, v in s %}{{ k }}={{ v|shout }} {% endfor %}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("page", map[string]interface{}{"s": settings{"theme": "dark", "lang": "en"}})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "theme=DARK! lang=EN! " {
+		t.Errorf("Expected %q, got %q", "theme=DARK! lang=EN! ", result)
+	}
+}
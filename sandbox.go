@@ -28,38 +28,44 @@ func NewDefaultSecurityPolicy() *DefaultSecurityPolicy {
 	return &DefaultSecurityPolicy{
 		AllowedFunctions: map[string]bool{
 			// Basic functions
-			"range":  true,
-			"cycle":  true,
-			"date":   true,
-			"min":    true,
-			"max":    true,
-			"random": true,
-			"length": true,
-			"merge":  true,
+			"range":         true,
+			"cycle":         true,
+			"date":          true,
+			"min":           true,
+			"max":           true,
+			"random":        true,
+			"uuid":          true,
+			"random_string": true,
+			"length":        true,
+			"merge":         true,
 		},
 		AllowedFilters: map[string]bool{
 			// Basic filters
-			"escape":     true,
-			"e":          true,
-			"raw":        true,
-			"length":     true,
-			"count":      true,
-			"lower":      true,
-			"upper":      true,
-			"title":      true,
-			"capitalize": true,
-			"trim":       true,
-			"nl2br":      true,
-			"join":       true,
-			"split":      true,
-			"default":    true,
-			"date":       true,
-			"abs":        true,
-			"first":      true,
-			"last":       true,
-			"reverse":    true,
-			"sort":       true,
-			"slice":      true,
+			"escape":       true,
+			"e":            true,
+			"raw":          true,
+			"length":       true,
+			"count":        true,
+			"lower":        true,
+			"upper":        true,
+			"title":        true,
+			"capitalize":   true,
+			"trim":         true,
+			"nl2br":        true,
+			"join":         true,
+			"split":        true,
+			"default":      true,
+			"date":         true,
+			"abs":          true,
+			"first":        true,
+			"last":         true,
+			"reverse":      true,
+			"sort":         true,
+			"natural_sort": true,
+			"sort_by_keys": true,
+			"slice":        true,
+			"batch":        true,
+			"trans":        true,
 		},
 		AllowedTags: map[string]bool{
 			// Basic control tags
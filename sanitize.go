@@ -0,0 +1,308 @@
+package twig
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+// HTMLSanitizer cleans untrusted HTML for the sanitize_html filter.
+// A bluemonday *Policy satisfies this interface and can be passed to
+// Engine.SetHTMLSanitizer directly.
+type HTMLSanitizer interface {
+	Sanitize(s string) string
+}
+
+// SanitizePolicy is the built-in whitelist HTML sanitizer. Tags that are not
+// allowed are removed while their text is kept, except for tags such as
+// script and style whose content is removed as well.
+type SanitizePolicy struct {
+	AllowedTags      map[string][]string // Tag name to allowed attribute names
+	AllowedProtocols []string            // URL schemes allowed in href and src
+}
+
+// DefaultSanitizePolicy returns a policy suitable for user-generated rich text
+func DefaultSanitizePolicy() *SanitizePolicy {
+	return &SanitizePolicy{
+		AllowedTags: map[string][]string{
+			"a":          {"href", "title"},
+			"abbr":       {"title"},
+			"b":          nil,
+			"blockquote": nil,
+			"br":         nil,
+			"code":       nil,
+			"del":        nil,
+			"em":         nil,
+			"h1":         nil,
+			"h2":         nil,
+			"h3":         nil,
+			"h4":         nil,
+			"h5":         nil,
+			"h6":         nil,
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "title", "width", "height"},
+			"li":         nil,
+			"ol":         nil,
+			"p":          nil,
+			"pre":        nil,
+			"s":          nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"u":          nil,
+			"ul":         nil,
+		},
+		AllowedProtocols: []string{"http", "https", "mailto"},
+	}
+}
+
+// voidElements are tags without content or closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements are tags whose content is dropped along with the tag
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true,
+	"embed": true, "noscript": true, "template": true, "textarea": true,
+	"title": true, "xmp": true, "noembed": true, "noframes": true,
+}
+
+// urlAttributes are attributes whose values are checked against the protocols
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true,
+}
+
+// htmlAttribute is a parsed attribute of a tag
+type htmlAttribute struct {
+	name  string
+	value string
+}
+
+// Sanitize removes everything from s that the policy does not allow
+func (p *SanitizePolicy) Sanitize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+
+	var open []string // Allowed tags that are currently open
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c != '<' {
+			if c == '>' {
+				b.WriteString("&gt;")
+			} else {
+				b.WriteByte(c)
+			}
+			i++
+			continue
+		}
+
+		// Comments, doctypes and processing instructions are removed
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+		if i+1 < len(s) && (s[i+1] == '!' || s[i+1] == '?') {
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+
+		name, attrs, closing, next, ok := parseHTMLTag(s, i)
+		if !ok {
+			// Not a tag, so the bracket is text
+			b.WriteString("&lt;")
+			i++
+			continue
+		}
+		i = next
+
+		if !closing && rawTextElements[name] {
+			// Skip everything up to the matching closing tag
+			end := strings.Index(strings.ToLower(s[i:]), "</"+name)
+			if end < 0 {
+				break
+			}
+			i += end
+			if gt := strings.IndexByte(s[i:], '>'); gt >= 0 {
+				i += gt + 1
+			} else {
+				i = len(s)
+			}
+			continue
+		}
+
+		allowedAttrs, allowed := p.AllowedTags[name]
+		if !allowed {
+			continue
+		}
+
+		if closing {
+			// Close the tag only if it is open, closing any tags nested in it
+			for j := len(open) - 1; j >= 0; j-- {
+				if open[j] == name {
+					for k := len(open) - 1; k >= j; k-- {
+						b.WriteString("</" + open[k] + ">")
+					}
+					open = open[:j]
+					break
+				}
+			}
+			continue
+		}
+
+		b.WriteString("<" + name)
+		for _, attr := range attrs {
+			if !slices.Contains(allowedAttrs, attr.name) {
+				continue
+			}
+			if urlAttributes[attr.name] && !p.allowedURL(attr.value) {
+				continue
+			}
+			b.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+		}
+		b.WriteString(">")
+
+		if !voidElements[name] {
+			open = append(open, name)
+		}
+	}
+
+	// Close tags left open by the input
+	for j := len(open) - 1; j >= 0; j-- {
+		b.WriteString("</" + open[j] + ">")
+	}
+
+	return b.String()
+}
+
+// allowedURL reports whether a URL is relative or uses an allowed protocol
+func (p *SanitizePolicy) allowedURL(value string) bool {
+	// Browsers ignore whitespace and control characters inside the scheme
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+
+	colon := strings.IndexByte(cleaned, ':')
+	if colon < 0 || strings.ContainsAny(cleaned[:colon], "/?#") {
+		return true
+	}
+
+	scheme := strings.ToLower(cleaned[:colon])
+	return slices.Contains(p.AllowedProtocols, scheme)
+}
+
+// parseHTMLTag parses the tag starting at s[start], which must be '<'
+func parseHTMLTag(s string, start int) (name string, attrs []htmlAttribute, closing bool, next int, ok bool) {
+	i := start + 1
+	if i < len(s) && s[i] == '/' {
+		closing = true
+		i++
+	}
+
+	nameStart := i
+	for i < len(s) && isTagNameChar(s[i]) {
+		i++
+	}
+	if i == nameStart || !isASCIILetter(s[nameStart]) {
+		return "", nil, false, 0, false
+	}
+	name = strings.ToLower(s[nameStart:i])
+
+	for i < len(s) {
+		// Skip whitespace and stray slashes between attributes
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return "", nil, false, 0, false
+		}
+		if s[i] == '>' {
+			return name, attrs, closing, i + 1, true
+		}
+
+		// Attribute name
+		attrStart := i
+		for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		attr := htmlAttribute{name: strings.ToLower(s[attrStart:i])}
+
+		for i < len(s) && isHTMLSpace(s[i]) {
+			i++
+		}
+
+		// Optional value
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && isHTMLSpace(s[i]) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return "", nil, false, 0, false
+				}
+				attr.value = html.UnescapeString(s[i+1 : i+1+end])
+				i += end + 2
+			} else {
+				valueStart := i
+				for i < len(s) && !isHTMLSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				attr.value = html.UnescapeString(s[valueStart:i])
+			}
+		}
+
+		attrs = append(attrs, attr)
+	}
+
+	return "", nil, false, 0, false
+}
+
+// isTagNameChar reports whether c can appear in a tag name
+func isTagNameChar(c byte) bool {
+	return isASCIILetter(c) || (c >= '0' && c <= '9') || c == '-'
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isHTMLSpace reports whether c is HTML whitespace
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// filterSanitizeHTML removes tags, attributes and URLs not allowed by the
+// engine's sanitizer, using DefaultSanitizePolicy when none is set
+func (e *CoreExtension) filterSanitizeHTML(value interface{}, args ...interface{}) (interface{}, error) {
+	str := e.toString(value)
+	if str == "" {
+		return "", nil
+	}
+
+	if e.env != nil && e.env.htmlSanitizer != nil {
+		return e.env.htmlSanitizer.Sanitize(str), nil
+	}
+	return defaultSanitizer.Sanitize(str), nil
+}
+
+// defaultSanitizer is used by sanitize_html when no sanitizer is configured
+var defaultSanitizer = DefaultSanitizePolicy()
@@ -0,0 +1,109 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTMLFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Allowed markup kept", "<p>Hello <strong>world</strong></p>", "<p>Hello <strong>world</strong></p>"},
+		{"Script removed with content", "a<script>alert(1)</script>b", "ab"},
+		{"Style removed with content", "<style>body{}</style>text", "text"},
+		{"Unknown tag stripped, text kept", "<div><span>text</span></div>", "text"},
+		{"Event handlers removed", `<p onclick="evil()">x</p>`, "<p>x</p>"},
+		{"Allowed attributes kept", `<a href="https://example.com" title="t" target="_blank">x</a>`, `<a href="https://example.com" title="t">x</a>`},
+		{"Relative URL kept", `<a href="/path?q=1#top">x</a>`, `<a href="/path?q=1#top">x</a>`},
+		{"JavaScript URL removed", `<a href="javascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"Obfuscated JavaScript URL removed", `<a href=" jav&#x09;ascript:alert(1)">x</a>`, "<a>x</a>"},
+		{"Uppercase scheme", `<a href="JAVASCRIPT:alert(1)">x</a>`, "<a>x</a>"},
+		{"Data image removed", `<img src="data:image/png;base64,AAAA" alt="a">`, `<img alt="a">`},
+		{"Mailto allowed", `<a href="mailto:me@example.com">m</a>`, `<a href="mailto:me@example.com">m</a>`},
+		{"Attribute values escaped", `<img alt='"><script>x</script>'>`, `<img alt="&#34;&gt;&lt;script&gt;x&lt;/script&gt;">`},
+		{"Unquoted attributes", `<a href=https://example.com>x</a>`, `<a href="https://example.com">x</a>`},
+		{"Comments removed", "a<!-- <script>x</script> -->b", "ab"},
+		{"Unclosed tags closed", "<p><em>text", "<p><em>text</em></p>"},
+		{"Stray closing tags dropped", "text</p></div>", "text"},
+		{"Misnested tags", "<b><i>x</b>y</i>", "<b><i>x</i></b>y"},
+		{"Bare angle brackets escaped", "1 < 2 > 0", "1 &lt; 2 &gt; 0"},
+		{"Uppercase tags", "<P>x</P><SCRIPT>y</SCRIPT>", "<p>x</p>"},
+		{"Self-closing void element", "a<br/>b<br />c", "a<br>b<br>c"},
+		{"Entities preserved", "&lt;b&gt; &amp; more", "&lt;b&gt; &amp; more"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", "{{ html|sanitize_html }}"); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{"html": tt.input})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+// upperSanitizer stands in for an external sanitizer such as bluemonday
+type upperSanitizer struct{}
+
+func (upperSanitizer) Sanitize(s string) string {
+	return strings.ToUpper(s)
+}
+
+func TestSetHTMLSanitizer(t *testing.T) {
+	context := map[string]interface{}{"html": `<p class="c">x</p><a href="ftp://host">f</a>`}
+
+	t.Run("Custom policy", func(t *testing.T) {
+		engine := New()
+		policy := DefaultSanitizePolicy()
+		policy.AllowedTags["p"] = []string{"class"}
+		policy.AllowedProtocols = append(policy.AllowedProtocols, "ftp")
+		engine.SetHTMLSanitizer(policy)
+		engine.RegisterString("test", "{{ html|sanitize_html }}")
+
+		result, err := engine.Render("test", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if expected := `<p class="c">x</p><a href="ftp://host">f</a>`; result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+
+	t.Run("External sanitizer", func(t *testing.T) {
+		engine := New()
+		engine.SetHTMLSanitizer(upperSanitizer{})
+		engine.RegisterString("test", "{{ 'abc'|sanitize_html }}")
+
+		result, err := engine.Render("test", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "ABC" {
+			t.Errorf("Expected %q, got %q", "ABC", result)
+		}
+	})
+
+	t.Run("Default policy does not leak changes", func(t *testing.T) {
+		engine := New()
+		engine.RegisterString("test", "{{ html|sanitize_html }}")
+
+		result, err := engine.Render("test", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if expected := `<p>x</p><a>f</a>`; result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	})
+}
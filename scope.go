@@ -0,0 +1,242 @@
+package twig
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// NewScope creates a child engine sharing this engine's parsed templates and
+// extensions, for example one per tenant. A scope has its own loaders,
+// globals, filters, functions and settings such as the sandbox policy;
+// globals and registrations of the engine are copied when the scope is
+// created. Templates are looked up in the scope's loaders first and then in
+// the engine, whose parsed templates are reused without parsing again.
+func (e *Engine) NewScope() *Engine {
+	scope := &Engine{
+		templates:        make(map[string]*Template),
+		autoReload:       e.autoReload,
+		strictVars:       e.strictVars,
+		debug:            e.debug,
+		errorTemplate:    e.errorTemplate,
+		fallback:         e.fallback,
+		errorHook:        e.errorHook,
+		newline:          e.newline,
+		trimFinalNewline: e.trimFinalNewline,
+		normalizeOutput:  e.normalizeOutput,
+		maxTemplateSize:  e.maxTemplateSize,
+		streamingParse:   e.streamingParse,
+		retainComments:   e.retainComments,
+		maxIncludeOutput: e.maxIncludeOutput,
+		maxNodeVisits:    e.maxNodeVisits,
+		pprofLabels:      e.pprofLabels,
+		markDiagnostics:  e.markDiagnostics,
+		themes:           e.themes,
+		nameResolver:     e.nameResolver,
+		middleware:       append([]namedMiddleware(nil), e.middleware...),
+		defaultContext:   e.defaultContext, // Replaced, never modified, by SetDefaultContext
+		parent:           e,
+	}
+	scope.environment = e.environment.scope()
+
+	// Scopes sharing the engine's fragment cache must not serve each other's
+	// blocks, which may render with other globals or filters
+	scope.environment.fragmentPrefix = e.environment.fragmentPrefix + "scope" + strconv.FormatUint(e.scopes.Add(1), 10) + ":"
+
+	// The core extension reads settings from its environment, so the scope
+	// gets its own copy. Filters the engine overrode are kept.
+	core := &CoreExtension{}
+	core.Initialize(scope)
+	for name, filter := range scope.environment.filtersV2 {
+		if sameFunc(e.environment.filtersV2[name], filter) {
+			continue
+		}
+		// Initialize replaced a filter the engine overrode
+		if override, ok := e.environment.filtersV2[name]; ok {
+			scope.environment.filtersV2[name] = override
+		} else {
+			delete(scope.environment.filtersV2, name)
+		}
+		scope.environment.filters[name] = e.environment.filters[name]
+	}
+	for name, filter := range core.GetFilters() {
+		if sameFunc(scope.environment.filters[name], filter) {
+			scope.environment.filters[name] = filter
+		}
+	}
+	for name, function := range core.GetFunctions() {
+		if sameFunc(scope.environment.functions[name], function) {
+			scope.environment.functions[name] = function
+		}
+	}
+	for name, test := range core.GetTests() {
+		if sameFunc(scope.environment.tests[name], test) {
+			scope.environment.tests[name] = test
+		}
+	}
+	for i, extension := range scope.environment.extensions {
+		if _, ok := extension.(*CoreExtension); ok {
+			scope.environment.extensions[i] = core
+		}
+	}
+
+	return scope
+}
+
+// WithFilters returns a scope of the engine in which the given filters
+// replace the engine's, for example to try a new implementation of a filter
+// on a fraction of renders. Templates, globals and the other registrations
+// are shared as with NewScope.
+func (e *Engine) WithFilters(filters map[string]FilterFunc) *Engine {
+	scope := e.NewScope()
+	for name, filter := range filters {
+		scope.AddFilter(name, filter)
+	}
+	return scope
+}
+
+// scope returns a copy of the environment for a scoped engine. Maps and
+// slices are copied so registrations on the scope do not leak back.
+func (env *Environment) scope() *Environment {
+	scoped := &Environment{
+		globals:                 make(map[string]interface{}, len(env.globals)),
+		filters:                 make(map[string]FilterFunc, len(env.filters)),
+		filtersV2:               make(map[string]FilterFuncV2, len(env.filtersV2)),
+		functions:               make(map[string]FunctionFunc, len(env.functions)),
+		tests:                   make(map[string]TestFunc, len(env.tests)),
+		operators:               make(map[string]OperatorFunc, len(env.operators)),
+		extensions:              append([]Extension(nil), env.extensions...),
+		cache:                   env.cache,
+		autoescape:              env.autoescape,
+		debug:                   env.debug,
+		sandbox:                 env.sandbox,
+		securityPolicy:          env.securityPolicy,
+		undefinedFilterMode:     env.undefinedFilterMode,
+		undefinedFilterResolver: env.undefinedFilterResolver,
+		stringers:               append([]StringerFunc(nil), env.stringers...),
+		comparators:             append([]Comparator(nil), env.comparators...),
+		clock:                   env.clock,
+		htmlSanitizer:           env.htmlSanitizer,
+		assetResolver:           env.assetResolver,
+		translator:              env.translator,
+		routeResolver:           env.routeResolver,
+		baseURL:                 env.baseURL,
+		fileResolver:            env.fileResolver,
+		featureProvider:         env.featureProvider,
+		appConfig:               env.appConfig,
+		nonceProvider:           env.nonceProvider,
+		cspPolicy:               env.cspPolicy,
+		loopScopeMode:           env.loopScopeMode,
+		fragmentCache:           env.fragmentCache,
+		strictFilterArgs:        env.strictFilterArgs,
+		filterArgReporter:       env.filterArgReporter,
+		listRepetition:          env.listRepetition,
+		unitFormatter:           env.unitFormatter,
+		escapeFormatVerbs:       env.escapeFormatVerbs,
+		operatorAliases:         make(map[string]syntaxAlias, len(env.operatorAliases)),
+		testAliases:             make(map[string]syntaxAlias, len(env.testAliases)),
+		hasDeprecations:         env.hasDeprecations,
+		deprecationHandler:      env.deprecationHandler,
+		collators:               make(map[string]Collator, len(env.collators)),
+		errorFormatter:          env.errorFormatter,
+		namespaces:              make(map[string]NamespaceProvider, len(env.namespaces)),
+		integrityProvider:       env.integrityProvider,
+		services:                make(map[string]ServiceFunc, len(env.services)),
+		constants:               make(map[string]interface{}, len(env.constants)),
+		enums:                   make(map[string]*enumType, len(env.enums)),
+	}
+
+	env.randomMu.Lock()
+	scoped.random = env.random
+	env.randomMu.Unlock()
+
+	for name, value := range env.globals {
+		scoped.globals[name] = value
+	}
+	for name, service := range env.services {
+		scoped.services[name] = service
+	}
+	for name, value := range env.constants {
+		scoped.constants[name] = value
+	}
+	for name, enum := range env.enums {
+		scoped.enums[name] = enum
+	}
+	for locale, collator := range env.collators {
+		scoped.collators[locale] = collator
+	}
+	for name, provider := range env.namespaces {
+		scoped.namespaces[name] = provider
+	}
+	for name, filter := range env.filtersV2 {
+		scoped.filtersV2[name] = filter
+	}
+	for name, filter := range env.filters {
+		scoped.filters[name] = filter
+	}
+	for name, function := range env.functions {
+		scoped.functions[name] = function
+	}
+	for name, test := range env.tests {
+		scoped.tests[name] = test
+	}
+	for name, operator := range env.operators {
+		scoped.operators[name] = operator
+	}
+	for alias, operator := range env.operatorAliases {
+		scoped.operatorAliases[alias] = operator
+	}
+	for alias, test := range env.testAliases {
+		scoped.testAliases[alias] = test
+	}
+
+	return scoped
+}
+
+// loadFromParent returns the parent engine's template bound to this scope.
+// The parsed nodes are shared; the copy only differs in the environment and
+// engine it renders with, and is replaced when the parent reloads.
+func (e *Engine) loadFromParent(name string) (*Template, error) {
+	origin, err := e.parent.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	tmpl, ok := e.templates[name]
+	e.mu.RUnlock()
+	if ok && tmpl.origin == origin {
+		return tmpl, nil
+	}
+
+	template := &Template{
+		name:         origin.name,
+		source:       origin.source,
+		nodes:        origin.nodes,
+		env:          e.environment,
+		engine:       e,
+		loader:       origin.loader,
+		lastModified: origin.lastModified,
+		origin:       origin,
+
+		parsedAt:      origin.parsedAt,
+		parseDuration: origin.parseDuration,
+	}
+
+	if e.environment.cache {
+		e.mu.Lock()
+		e.templates[name] = template
+		e.mu.Unlock()
+	}
+
+	return template, nil
+}
+
+// sameFunc reports whether two functions share their code, which for method
+// values means they are the same method of possibly different receivers
+func sameFunc(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() != reflect.Func || vb.Kind() != reflect.Func || va.IsNil() || vb.IsNil() {
+		return false
+	}
+	return va.Pointer() == vb.Pointer()
+}
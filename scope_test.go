@@ -0,0 +1,205 @@
+package twig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestEngineScope(t *testing.T) {
+	engine := New()
+	engine.AddGlobal("site", "shared")
+	engine.AddFilter("shout", func(value interface{}, args ...interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(value)) + "!", nil
+	})
+	engine.RegisterString("page.twig", `{{ site }}|{% include "footer.twig" %}|{{ "hi"|shout }}`)
+	engine.RegisterString("footer.twig", `default footer`)
+
+	acme := engine.NewScope()
+	acme.AddGlobal("site", "acme")
+	acme.RegisterLoader(NewArrayLoader(map[string]string{"footer.twig": "acme footer"}))
+
+	other := engine.NewScope()
+
+	tests := []struct {
+		name     string
+		engine   *Engine
+		expected string
+	}{
+		{"engine", engine, "shared|default footer|HI!"},
+		{"scope with overrides", acme, "acme|acme footer|HI!"},
+		{"scope without overrides", other, "shared|default footer|HI!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.engine.Render("page.twig", nil)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("shares parsed templates", func(t *testing.T) {
+		shared, err := engine.Load("page.twig")
+		if err != nil {
+			t.Fatal(err)
+		}
+		scoped, err := acme.Load("page.twig")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scoped.nodes != shared.nodes {
+			t.Error("Expected the scope to reuse the parsed nodes")
+		}
+		if again, _ := acme.Load("page.twig"); again != scoped {
+			t.Error("Expected the scoped template to be cached")
+		}
+	})
+
+	t.Run("follows parent reloads", func(t *testing.T) {
+		scope := engine.NewScope()
+		engine.RegisterString("notice.twig", "v1")
+		if result, _ := scope.Render("notice.twig", nil); result != "v1" {
+			t.Fatalf("Expected v1, got %q", result)
+		}
+		engine.RegisterString("notice.twig", "v2")
+		if result, _ := scope.Render("notice.twig", nil); result != "v2" {
+			t.Errorf("Expected v2, got %q", result)
+		}
+	})
+
+	t.Run("registrations do not leak", func(t *testing.T) {
+		acme.AddFilter("tenant_only", func(value interface{}, args ...interface{}) (interface{}, error) {
+			return value, nil
+		})
+		if _, ok := engine.environment.filters["tenant_only"]; ok {
+			t.Error("Expected the scope filter to stay in the scope")
+		}
+		if engine.environment.globals["site"] != "shared" {
+			t.Error("Expected the engine global to be unchanged")
+		}
+	})
+}
+
+func TestEngineScopeSettings(t *testing.T) {
+	engine := New()
+	engine.RegisterString("today.twig", `{{ now|date('Y-m-d') }}|{{ 'now'|date('Y') }}`)
+
+	scope := engine.NewScope()
+	scope.SetClock(func() time.Time { return time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC) })
+
+	result, err := scope.Render("today.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "2020-05-01|2020" {
+		t.Errorf("Expected the scope clock to be used, got %q", result)
+	}
+
+	result, err = engine.Render("today.twig", nil)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if strings.HasPrefix(result, "2020-05-01") {
+		t.Errorf("Expected the engine clock to be unchanged, got %q", result)
+	}
+}
+
+func TestEngineWithFilters(t *testing.T) {
+	engine := New()
+	engine.AddFilter("money", func(value interface{}, args ...interface{}) (interface{}, error) {
+		return fmt.Sprintf("$%v", value), nil
+	})
+	engine.AddGlobal("currency", "USD")
+	if err := engine.RegisterString("price.twig", `{{ price|money }} {{ currency }} {{ name|upper }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	variant := engine.WithFilters(map[string]FilterFunc{
+		"money": func(value interface{}, args ...interface{}) (interface{}, error) {
+			return fmt.Sprintf("%v.00 $", value), nil
+		},
+	})
+
+	context := map[string]interface{}{"price": 5, "name": "pen"}
+	result, err := variant.Render("price.twig", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "5.00 $ USD PEN" {
+		t.Errorf("Expected the overridden filter, got %q", result)
+	}
+
+	result, err = engine.Render("price.twig", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "$5 USD PEN" {
+		t.Errorf("Expected the engine filter to be unchanged, got %q", result)
+	}
+}
+
+func TestEngineScopeKeepsFilterOverrides(t *testing.T) {
+	engine := New()
+	engine.AddFilter("sort", func(value interface{}, args ...interface{}) (interface{}, error) {
+		return "custom", nil
+	})
+	if err := engine.RegisterString("list.twig", `{{ items|sort }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	context := map[string]interface{}{"items": []int{2, 1}}
+	for _, scope := range []*Engine{engine.NewScope(), engine.WithFilters(nil)} {
+		result, err := scope.Render("list.twig", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "custom" {
+			t.Errorf("Expected the engine's sort filter in the scope, got %q", result)
+		}
+	}
+}
+
+func TestEnvironmentScopeCopiesMapsAndSlices(t *testing.T) {
+	env := New().environment
+
+	// Give every map and slice of the environment storage to alias
+	rv := reflect.ValueOf(env).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		field := rv.Field(i)
+		field = reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem()
+		switch field.Kind() {
+		case reflect.Map:
+			if field.IsNil() {
+				field.Set(reflect.MakeMap(field.Type()))
+			}
+		case reflect.Slice:
+			if field.Len() == 0 {
+				field.Set(reflect.MakeSlice(field.Type(), 1, 1))
+			}
+		}
+	}
+
+	scoped := reflect.ValueOf(env.scope()).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		parent, child := rv.Field(i), scoped.Field(i)
+		switch parent.Kind() {
+		case reflect.Map:
+			if child.IsNil() || child.Pointer() == parent.Pointer() {
+				t.Errorf("Expected the scope to copy the %s map", name)
+			}
+		case reflect.Slice:
+			if child.Len() > 0 && child.Pointer() == parent.Pointer() {
+				t.Errorf("Expected the scope to copy the %s slice", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package twig
+
+// ServiceFunc creates the value of a service variable. It is called the
+// first time a render reads the variable, with the context reading it.
+type ServiceFunc func(ctx *RenderContext) interface{}
+
+// RegisterService exposes a variable whose value is created on first use in
+// each render, for objects such as a shopping cart that are costly to build
+// and read by few templates. Context variables and globals of the same name
+// take precedence.
+func (e *Engine) RegisterService(name string, service ServiceFunc) {
+	if e.environment.services == nil {
+		e.environment.services = make(map[string]ServiceFunc)
+	}
+	e.environment.services[name] = service
+}
+
+// resolveService returns the value of a registered service, creating it on
+// the first access of the render
+func (ctx *RenderContext) resolveService(name string) (interface{}, bool) {
+	if ctx.env == nil {
+		return nil, false
+	}
+	service, ok := ctx.env.services[name]
+	if !ok {
+		return nil, false
+	}
+
+	base := ctx.scopeBase()
+	if value, ok := base.services[name]; ok {
+		return value, true
+	}
+
+	value := service(ctx)
+	if base.services == nil {
+		base.services = make(map[string]interface{})
+	}
+	base.services[name] = value
+	return value, true
+}
@@ -0,0 +1,103 @@
+package twig
+
+import "testing"
+
+func TestRegisterService(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  map[string]string
+		context  map[string]interface{}
+		expected string
+		calls    int
+	}{
+		{
+			name:     "created on first use",
+			sources:  map[string]string{"test": `{{ cart.count }} {{ cart.count }}`},
+			expected: "3 3",
+			calls:    1,
+		},
+		{
+			name:     "not created when unused",
+			sources:  map[string]string{"test": `hello`},
+			expected: "hello",
+			calls:    0,
+		},
+		{
+			name: "shared with includes",
+			sources: map[string]string{
+				"header": `{{ cart.count }}`,
+				"test":   `{{ cart.count }}-{% include "header" %}`,
+			},
+			expected: "3-3",
+			calls:    1,
+		},
+		{
+			name:     "shared with loop bodies",
+			sources:  map[string]string{"test": `{% for i in [1, 2] %}{{ cart.count }}{% endfor %}`},
+			expected: "33",
+			calls:    1,
+		},
+		{
+			name:     "context takes precedence",
+			sources:  map[string]string{"test": `{{ cart.count }}`},
+			context:  map[string]interface{}{"cart": map[string]interface{}{"count": 7}},
+			expected: "7",
+			calls:    0,
+		},
+		{
+			name:     "defined test",
+			sources:  map[string]string{"test": `{{ cart is defined ? 'yes' : 'no' }}`},
+			expected: "yes",
+			calls:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			calls := 0
+			engine.RegisterService("cart", func(ctx *RenderContext) interface{} {
+				calls++
+				return map[string]interface{}{"count": 3}
+			})
+			for name, source := range tt.sources {
+				if err := engine.RegisterString(name, source); err != nil {
+					t.Fatalf("Error parsing template %s: %v", name, err)
+				}
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+			if calls != tt.calls {
+				t.Errorf("Expected %d service calls, got %d", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestRegisterServicePerRender(t *testing.T) {
+	engine := New()
+	calls := 0
+	engine.RegisterService("counter", func(ctx *RenderContext) interface{} {
+		calls++
+		return calls
+	})
+	if err := engine.RegisterString("test", `{{ counter }}{{ counter }}`); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	for _, expected := range []string{"11", "22"} {
+		result, err := engine.Render("test", nil)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+	}
+}
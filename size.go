@@ -0,0 +1,161 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SetMaxTemplateSize limits the size in bytes of template sources the engine
+// parses. Larger templates fail to load with ErrTemplateTooLarge. Zero, the
+// default, means no limit.
+func (e *Engine) SetMaxTemplateSize(size int) {
+	if size < 0 {
+		size = 0
+	}
+	e.maxTemplateSize = size
+}
+
+// checkTemplateSize returns ErrTemplateTooLarge if source exceeds the limit
+func (e *Engine) checkTemplateSize(name string, size int) error {
+	if e.maxTemplateSize > 0 && size > e.maxTemplateSize {
+		return fmt.Errorf("%w: '%s' is %d bytes, limit is %d", ErrTemplateTooLarge, name, size, e.maxTemplateSize)
+	}
+	return nil
+}
+
+// RegisterReader registers a template read from r. With a size limit set,
+// reading stops as soon as the limit is exceeded, so oversized generated
+// templates are rejected without being held in memory.
+func (e *Engine) RegisterReader(name string, r io.Reader) error {
+	if e.maxTemplateSize > 0 {
+		r = io.LimitReader(r, int64(e.maxTemplateSize)+1)
+	}
+
+	var b strings.Builder
+	n, err := io.Copy(&b, r)
+	if err != nil {
+		return err
+	}
+	if err := e.checkTemplateSize(name, int(n)); err != nil {
+		return err
+	}
+	return e.RegisterString(name, b.String())
+}
+
+// TemplateStats describes the memory held by a cached template
+type TemplateStats struct {
+	Name        string
+	SourceBytes int // Size of the template source
+	Nodes       int // Nodes in the parsed tree
+	Memory      int // Estimated bytes used by the source and the parsed tree
+}
+
+// EngineStats describes the templates cached by an engine
+type EngineStats struct {
+	Templates   []TemplateStats // Sorted by name
+	SourceBytes int
+	Memory      int
+}
+
+// Stats reports the templates in the engine's cache and an estimate of the
+// memory each one uses
+func (e *Engine) Stats() EngineStats {
+	e.mu.RLock()
+	templates := make([]*Template, 0, len(e.templates))
+	for _, template := range e.templates {
+		templates = append(templates, template)
+	}
+	e.mu.RUnlock()
+
+	stats := EngineStats{Templates: make([]TemplateStats, 0, len(templates))}
+	for _, template := range templates {
+		ts := template.Stats()
+		stats.Templates = append(stats.Templates, ts)
+		stats.SourceBytes += ts.SourceBytes
+		stats.Memory += ts.Memory
+	}
+	sort.Slice(stats.Templates, func(i, j int) bool {
+		return stats.Templates[i].Name < stats.Templates[j].Name
+	})
+	return stats
+}
+
+// Stats reports the size of the template and an estimate of its memory use
+func (t *Template) Stats() TemplateStats {
+	m := &memoryCounter{}
+	if t.nodes != nil {
+		Walk(t.nodes, m)
+	}
+	return TemplateStats{
+		Name:        t.name,
+		SourceBytes: len(t.source),
+		Nodes:       m.nodes,
+		Memory:      len(t.source) + m.bytes,
+	}
+}
+
+// memoryCounter estimates the size of a tree from the nodes' struct sizes
+// and the strings and byte slices they hold directly. Strings sliced from
+// the source are counted twice, so the estimate errs on the high side.
+type memoryCounter struct {
+	nodes int
+	bytes int
+}
+
+// Enter adds the size of a node
+func (m *memoryCounter) Enter(node Node) bool {
+	m.nodes++
+
+	v := reflect.ValueOf(node)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	m.bytes += int(v.Type().Size())
+	if v.Kind() != reflect.Struct {
+		return true
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			m.bytes += field.Len()
+		case reflect.Slice:
+			m.bytes += field.Cap() * int(field.Type().Elem().Size())
+		}
+	}
+	return true
+}
+
+// Leave is a no-op
+func (m *memoryCounter) Leave(node Node) {}
+
+// Source returns the template source as parsed, after line endings were
+// normalized
+func (t *Template) Source() string {
+	return t.source
+}
+
+// Size returns the size of the template source in bytes
+func (t *Template) Size() int {
+	return len(t.source)
+}
+
+// ParsedAt returns when the template source was parsed, or when a compiled
+// template was decoded
+func (t *Template) ParsedAt() time.Time {
+	return t.parsedAt
+}
+
+// ParseDuration returns how long parsing the template took. Sources parsed
+// before by the engine are reused, so their duration is near zero.
+func (t *Template) ParseDuration() time.Duration {
+	return t.parseDuration
+}
@@ -0,0 +1,142 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxTemplateSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		source  string
+		wantErr bool
+	}{
+		{name: "no limit", limit: 0, source: strings.Repeat("x", 1000)},
+		{name: "under limit", limit: 10, source: "{{ a }}"},
+		{name: "at limit", limit: 7, source: "{{ a }}"},
+		{name: "over limit", limit: 6, source: "{{ a }}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetMaxTemplateSize(tt.limit)
+
+			err := engine.RegisterString("test", tt.source)
+			if tt.wantErr {
+				if !errors.Is(err, ErrTemplateTooLarge) {
+					t.Errorf("Expected ErrTemplateTooLarge, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+		})
+	}
+}
+
+func TestMaxTemplateSizeLoader(t *testing.T) {
+	engine := New()
+	engine.SetMaxTemplateSize(16)
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"small.twig": "hello",
+		"large.twig": strings.Repeat("large ", 10),
+	}))
+
+	if _, err := engine.Render("small.twig", nil); err != nil {
+		t.Errorf("Error rendering small template: %v", err)
+	}
+	if _, err := engine.Render("large.twig", nil); !errors.Is(err, ErrTemplateTooLarge) {
+		t.Errorf("Expected ErrTemplateTooLarge, got %v", err)
+	}
+}
+
+func TestRegisterReader(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterReader("test", strings.NewReader("Hello {{ name }}")); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	result, err := engine.Render("test", map[string]interface{}{"name": "World"})
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if result != "Hello World" {
+		t.Errorf("Expected %q, got %q", "Hello World", result)
+	}
+
+	engine.SetMaxTemplateSize(4)
+	if err := engine.RegisterReader("large", strings.NewReader("Hello {{ name }}")); !errors.Is(err, ErrTemplateTooLarge) {
+		t.Errorf("Expected ErrTemplateTooLarge, got %v", err)
+	}
+}
+
+func TestEngineStats(t *testing.T) {
+	engine := New()
+	if err := engine.RegisterString("b", "{% for i in items %}{{ i }}{% endfor %}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if err := engine.RegisterString("a", "text"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	stats := engine.Stats()
+	if len(stats.Templates) != 2 || stats.Templates[0].Name != "a" || stats.Templates[1].Name != "b" {
+		t.Fatalf("Expected stats for a and b, got %+v", stats.Templates)
+	}
+
+	b := stats.Templates[1]
+	if b.SourceBytes != 39 {
+		t.Errorf("Expected 39 source bytes, got %d", b.SourceBytes)
+	}
+	if b.Nodes < 4 {
+		t.Errorf("Expected the for loop and its children to be counted, got %d nodes", b.Nodes)
+	}
+	if b.Memory <= b.SourceBytes {
+		t.Errorf("Expected memory to include the parsed tree, got %d", b.Memory)
+	}
+	if stats.SourceBytes != 43 || stats.Memory != stats.Templates[0].Memory+b.Memory {
+		t.Errorf("Expected totals to sum the templates, got %+v", stats)
+	}
+}
+
+func TestTemplateProvenance(t *testing.T) {
+	engine := New()
+	before := time.Now()
+	source := "Hello {{ name }}\r\n"
+	if err := engine.SetNewline("\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.RegisterString("hello.twig", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	template, err := engine.Load("hello.twig")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+	if template.Source() != "Hello {{ name }}\n" {
+		t.Errorf("Expected the normalized source, got %q", template.Source())
+	}
+	if template.Size() != len(template.Source()) {
+		t.Errorf("Expected size %d, got %d", len(template.Source()), template.Size())
+	}
+	if template.ParsedAt().Before(before) || template.ParsedAt().After(time.Now()) {
+		t.Errorf("Unexpected parse time %v", template.ParsedAt())
+	}
+	if template.ParseDuration() < 0 {
+		t.Errorf("Unexpected parse duration %v", template.ParseDuration())
+	}
+
+	// A scope shares the parse of the engine's template
+	scoped, err := engine.NewScope().Load("hello.twig")
+	if err != nil {
+		t.Fatalf("Error loading template: %v", err)
+	}
+	if !scoped.ParsedAt().Equal(template.ParsedAt()) {
+		t.Errorf("Expected the scope to report the original parse time")
+	}
+}
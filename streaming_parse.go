@@ -0,0 +1,112 @@
+package twig
+
+import "fmt"
+
+// streamWindowTags is the number of tags the streaming parser tokenizes
+// ahead of the statement it parses
+const streamWindowTags = 256
+
+// SetStreamingParse parses sources of at least minSize bytes in streaming
+// mode: the source is tokenized a window of tags at a time and the tokens of
+// each top-level statement are dropped once it is parsed, so multi-megabyte
+// generated templates never hold the tokens of the whole source. Zero, the
+// default, tokenizes every source up front.
+func (e *Engine) SetStreamingParse(minSize int) {
+	if minSize < 0 {
+		minSize = 0
+	}
+	e.streamingParse = minSize
+}
+
+// parseStreaming parses the top-level statements of the source a window of
+// tokens at a time. A statement that fails to parse is retried with a window
+// twice as large until the rest of the source is tokenized, so statements
+// spanning windows, such as a block around the whole page, parse as usual.
+func (p *Parser) parseStreaming() ([]Node, error) {
+	tokenizer := GetTokenizer(p.source, streamWindowTags*8)
+	tokenizer.interner = p.interner
+	defer ReleaseTokenizer(tokenizer)
+	tokenizer.position = 0
+	tokenizer.line = 1
+	tokenizer.textStart = 0
+	tokenizer.lastType = TOKEN_EOF
+
+	var nodes []Node
+	tags := streamWindowTags
+	for {
+		more, err := tokenizer.tokenizeWindow(tags)
+		if err != nil {
+			return nil, fmt.Errorf("tokenization error: %w", err)
+		}
+		p.tokens = tokenizer.tokenBuffer
+		p.tokenIndex = 0
+
+		parsed := false
+		for p.tokenIndex < len(p.tokens) && p.tokens[p.tokenIndex].Type != TOKEN_EOF {
+			start, doc := p.tokenIndex, p.pendingDoc
+			node, end, err := p.parseOuterNode()
+			if err != nil {
+				if !more {
+					return nil, fmt.Errorf("parsing error: %w", err)
+				}
+				// The statement may go on past the window
+				p.tokenIndex, p.pendingDoc = start, doc
+				break
+			}
+			if end {
+				return nodes, nil
+			}
+			if node != nil {
+				nodes = append(nodes, node)
+			}
+			parsed = true
+		}
+		if !more {
+			return nodes, nil
+		}
+
+		if parsed {
+			tags = streamWindowTags
+		} else {
+			tags *= 2
+		}
+		// Keep the tokens of the statement not parsed yet
+		tokenizer.tokenBuffer = append(tokenizer.tokenBuffer[:0], tokenizer.tokenBuffer[p.tokenIndex:]...)
+	}
+}
+
+// tokenizeWindow adds the tokens of up to tags more tags to the buffer,
+// followed by an EOF token, and reports whether the source goes on.
+// Whitespace control is applied to the new tokens, including the text after
+// a trimming tag that ended the previous window.
+func (t *ZeroAllocTokenizer) tokenizeWindow(tags int) (bool, error) {
+	if n := len(t.tokenBuffer); n > 0 && t.tokenBuffer[n-1].Type == TOKEN_EOF {
+		t.tokenBuffer = t.tokenBuffer[:n-1]
+	}
+	start := len(t.tokenBuffer)
+
+	more := true
+	for i := 0; i < tags && more; i++ {
+		var err error
+		if more, err = t.tokenizeTag(); err != nil {
+			return false, err
+		}
+	}
+
+	t.result = t.tokenBuffer[start:]
+	if len(t.result) > 0 {
+		if t.result[0].Type == TOKEN_TEXT && isTrimEnd(t.lastType) {
+			t.result[0].Value = trimLeadingWhitespace(t.result[0].Value)
+		}
+		t.ApplyWhitespaceControl()
+		t.lastType = t.result[len(t.result)-1].Type
+	}
+
+	t.AddToken(TOKEN_EOF, "", t.line)
+	return more, nil
+}
+
+// isTrimEnd reports whether a token closes a tag with a trimming dash
+func isTrimEnd(tokenType int) bool {
+	return tokenType == TOKEN_VAR_END_TRIM || tokenType == TOKEN_BLOCK_END_TRIM || tokenType == TOKEN_COMMENT_END_TRIM
+}
@@ -0,0 +1,58 @@
+package twig
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestStreamingParse(t *testing.T) {
+	// Enough tags for several windows, with statements spanning them
+	var page strings.Builder
+	page.WriteString("{# @doc Greets #}{% macro hi(name) %}hi {{ name }}{% endmacro %}")
+	page.WriteString("{% block rows %}<ul>\n")
+	for i := 0; i < 3*streamWindowTags; i++ {
+		fmt.Fprintf(&page, "  {%%- if %d is odd -%%}\n <li>{{ %d }}</li>{%% else %%}<li>{{ _self.hi('%d') }}</li>{%% endif %%}\n", i, i, i)
+	}
+	page.WriteString("</ul>{% endblock %}\n")
+	for i := 0; i < 2*streamWindowTags; i++ {
+		fmt.Fprintf(&page, "{{ 'x%d' }} {#- comment -#}  \\{{ literal }}\n", i)
+	}
+
+	sources := map[string]string{
+		"page":  page.String(),
+		"small": "{{ 1 + 1 }}",
+	}
+
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			whole := New()
+			streaming := New()
+			streaming.SetStreamingParse(1)
+
+			// The comment keeps the parse cache from sharing the tree
+			var results []string
+			for i, engine := range []*Engine{whole, streaming} {
+				if err := engine.RegisterString(name, source+strings.Repeat("{# streaming #}", i)); err != nil {
+					t.Fatalf("Error parsing template: %v", err)
+				}
+				result, err := engine.Render(name, nil)
+				if err != nil {
+					t.Fatalf("Error rendering template: %v", err)
+				}
+				results = append(results, result)
+			}
+			if results[0] != results[1] {
+				t.Errorf("Expected streaming parse to match, got %d bytes instead of %d", len(results[1]), len(results[0]))
+			}
+		})
+	}
+
+	// Errors are reported once the rest of the source is tokenized
+	engine := New()
+	engine.SetStreamingParse(1)
+	err := engine.RegisterString("broken", strings.Repeat("{{ 1 }}", 2*streamWindowTags)+"{% if true %}open")
+	if err == nil || !strings.Contains(err.Error(), "parsing error") {
+		t.Errorf("Expected a parsing error, got %v", err)
+	}
+}
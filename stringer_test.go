@@ -0,0 +1,78 @@
+package twig
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testMoney struct {
+	cents    int64
+	currency string
+}
+
+type testID [4]byte
+
+func (id testID) String() string {
+	return "stringer"
+}
+
+func TestRegisterStringer(t *testing.T) {
+	engine := New()
+	engine.RegisterStringer(func(v interface{}) (string, bool) {
+		if m, ok := v.(testMoney); ok {
+			return fmt.Sprintf("%d.%02d %s", m.cents/100, m.cents%100, m.currency), true
+		}
+		return "", false
+	})
+	engine.RegisterStringer(func(v interface{}) (string, bool) {
+		if id, ok := v.(testID); ok {
+			return fmt.Sprintf("%x", id[:]), true
+		}
+		return "", false
+	})
+
+	context := map[string]interface{}{
+		"price": testMoney{cents: 1999, currency: "EUR"},
+		"id":    testID{0xde, 0xad, 0xbe, 0xef},
+		"n":     42,
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Print", "{{ price }}", "19.99 EUR"},
+		{"Concatenation", "{{ 'Total: ' ~ price }}", "Total: 19.99 EUR"},
+		{"Before fmt.Stringer", "{{ id }}", "deadbeef"},
+		{"Builtin types unaffected", "{{ n }}", "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Unregistered engine uses fmt.Stringer", func(t *testing.T) {
+		other := New()
+		other.RegisterString("test", "{{ id }}")
+		result, err := other.Render("test", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "stringer" {
+			t.Errorf("Expected %q, got %q", "stringer", result)
+		}
+	})
+}
@@ -0,0 +1,192 @@
+package twig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TaintWarning reports a print that sends values from the render context
+// through the raw filter, so they reach the page unescaped
+type TaintWarning struct {
+	Template  string
+	Line      int
+	Variables []string // Context variables the raw value comes from
+}
+
+// String formats the warning for the debug log
+func (w TaintWarning) String() string {
+	return fmt.Sprintf("%s:%d: raw output of context data from %s", w.Template, w.Line, strings.Join(w.Variables, ", "))
+}
+
+// TaintWarnings reports the prints of the template that output context
+// variables with |raw. A variable is tainted when it comes from the render
+// context, or is set or looped over from a tainted expression; literals,
+// globals and values passed through escape are trusted. The check is
+// static and conservative, meant to audit templates for XSS before
+// enabling escaping. In debug mode the warnings are logged the first time
+// the template renders.
+func (t *Template) TaintWarnings() []TaintWarning {
+	a := &taintAnalysis{
+		assignments: make(map[string][]Node),
+		tainted:     make(map[string]bool),
+	}
+	if t.env != nil {
+		a.globals = t.env.globals
+	}
+	Walk(t.nodes, taintCollector{a})
+	a.propagate()
+
+	var warnings []TaintWarning
+	Walk(t.nodes, taintChecker{a: a, template: t.name, warnings: &warnings})
+	return warnings
+}
+
+// logTaintWarnings logs the template's taint warnings once
+func (t *Template) logTaintWarnings() {
+	t.taintOnce.Do(func() {
+		for _, warning := range t.TaintWarnings() {
+			LogWarning("%s", warning)
+		}
+	})
+}
+
+// taintAnalysis tracks which variables of a template may hold context data
+type taintAnalysis struct {
+	assignments map[string][]Node // Values assigned to each variable; nil entries are untrusted
+	tainted     map[string]bool
+	globals     map[string]interface{}
+}
+
+// propagate marks the variables assigned from tainted expressions until
+// no more change
+func (a *taintAnalysis) propagate() {
+	for changed := true; changed; {
+		changed = false
+		for name, values := range a.assignments {
+			if a.tainted[name] {
+				continue
+			}
+			for _, value := range values {
+				if value == nil || len(a.sources(value)) > 0 {
+					a.tainted[name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// isTainted reports whether a variable may hold context data
+func (a *taintAnalysis) isTainted(name string) bool {
+	if builtinVariables[name] {
+		return false
+	}
+	if _, ok := a.assignments[name]; ok {
+		return a.tainted[name]
+	}
+	_, global := a.globals[name]
+	return !global
+}
+
+// sources returns the tainted variables an expression reads, sorted;
+// escaped expressions have none
+func (a *taintAnalysis) sources(expr Node) []string {
+	found := make(map[string]bool)
+	var visit func(Node)
+	visit = func(node Node) {
+		switch n := node.(type) {
+		case nil:
+			return
+		case *FilterNode:
+			if n.filter == "escape" || n.filter == "e" {
+				return
+			}
+		case *VariableNode:
+			if a.isTainted(n.name) {
+				found[n.name] = true
+			}
+			return
+		case *FunctionNode:
+			// Function results are trusted, but not what they are given
+			for _, arg := range n.args {
+				visit(arg)
+			}
+			return
+		}
+		for _, child := range childNodes(node) {
+			visit(child)
+		}
+	}
+	visit(expr)
+
+	names := make([]string, 0, len(found))
+	for name := range found {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// taintCollector records the assignments of a template
+type taintCollector struct {
+	a *taintAnalysis
+}
+
+func (c taintCollector) Enter(node Node) bool {
+	assign := func(name string, value Node) {
+		if name != "" {
+			c.a.assignments[name] = append(c.a.assignments[name], value)
+		}
+	}
+
+	switch n := node.(type) {
+	case *SetNode:
+		assign(n.name, n.value)
+	case *ForNode:
+		assign(n.keyVar, n.sequence)
+		assign(n.valueVar, n.sequence)
+		for _, target := range n.targets {
+			assign(target, n.sequence)
+		}
+	case *MacroNode:
+		// Arguments come from the caller
+		for _, param := range n.params {
+			assign(param, nil)
+		}
+	}
+	return true
+}
+
+func (c taintCollector) Leave(node Node) {}
+
+// taintChecker finds the prints of tainted values through raw
+type taintChecker struct {
+	a        *taintAnalysis
+	template string
+	warnings *[]TaintWarning
+}
+
+func (c taintChecker) Enter(node Node) bool {
+	out, ok := node.(*PrintNode)
+	if !ok {
+		return true
+	}
+
+	// Only a raw at the end of the filter chain decides the output
+	filter, ok := out.expression.(*FilterNode)
+	if !ok || filter.filter != "raw" {
+		return false
+	}
+	if sources := c.a.sources(filter.node); len(sources) > 0 {
+		*c.warnings = append(*c.warnings, TaintWarning{
+			Template:  c.template,
+			Line:      out.line,
+			Variables: sources,
+		})
+	}
+	return false
+}
+
+func (c taintChecker) Leave(node Node) {}
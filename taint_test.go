@@ -0,0 +1,49 @@
+package twig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaintWarnings(t *testing.T) {
+	engine := New()
+	engine.AddGlobal("site_footer", "<footer></footer>")
+
+	tests := []struct {
+		name     string
+		source   string
+		expected []string
+	}{
+		{"context variable", "{{ bio|raw }}", []string{"page:1: raw output of context data from bio"}},
+		{"attribute", "\n{{ user.bio|upper|raw }}", []string{"page:2: raw output of context data from user"}},
+		{"escaped", "{{ bio|e|raw }}", nil},
+		{"not raw", "{{ bio }}{{ bio|raw|upper }}", nil},
+		{"literal", "{{ '<b>hi</b>'|raw }}", nil},
+		{"global", "{{ site_footer|raw }}", nil},
+		{"set from literal", "{% set html = '<hr>' %}{{ html|raw }}", nil},
+		{"set from context", "{% set html = '<p>' ~ bio %}{{ html|raw }}", []string{"page:1: raw output of context data from html"}},
+		{"loop variable", "{% for c in comments %}{{ c.body|raw }}{% endfor %}", []string{"page:1: raw output of context data from c"}},
+		{"function argument", "{{ range(1, count)|join|raw }}", []string{"page:1: raw output of context data from count"}},
+		{"macro argument", "{% macro show(text) %}{{ text|raw }}{% endmacro %}", []string{"page:1: raw output of context data from text"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			template, err := engine.Load("page")
+			if err != nil {
+				t.Fatalf("Error loading template: %v", err)
+			}
+
+			var got []string
+			for _, warning := range template.TaintWarnings() {
+				got = append(got, warning.String())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package twig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// WriterError reports the failure of one writer passed to RenderToMulti
+type WriterError struct {
+	Index int // Position of the writer in the RenderToMulti call
+	Err   error
+}
+
+// Error returns the error message
+func (e *WriterError) Error() string {
+	return fmt.Sprintf("writer %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying write error
+func (e *WriterError) Unwrap() error {
+	return e.Err
+}
+
+// RenderToMulti renders a template once and streams the output to every
+// writer, for example a response, a cache entry and a log sampler. A writer
+// that fails is dropped and the render continues for the others; its error
+// is returned as a *WriterError joined with those of other failed writers.
+// A render error is returned as is.
+func (e *Engine) RenderToMulti(name string, context map[string]interface{}, writers ...io.Writer) error {
+	tee := &teeWriter{writers: writers, errs: make([]error, len(writers))}
+	if err := e.RenderTo(tee, name, context); err != nil {
+		return err
+	}
+
+	var errs []error
+	for i, err := range tee.errs {
+		if err != nil {
+			errs = append(errs, &WriterError{Index: i, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// teeWriter writes to several writers, skipping the ones that failed
+type teeWriter struct {
+	writers []io.Writer
+	errs    []error
+}
+
+// Write writes p to every writer that has not failed. It never fails, so a
+// broken writer does not abort the render.
+func (t *teeWriter) Write(p []byte) (int, error) {
+	for i, w := range t.writers {
+		if t.errs[i] != nil {
+			continue
+		}
+		if n, err := w.Write(p); err != nil {
+			t.errs[i] = err
+		} else if n < len(p) {
+			t.errs[i] = io.ErrShortWrite
+		}
+	}
+	return len(p), nil
+}
+
+// WriteString writes s to every writer that has not failed
+func (t *teeWriter) WriteString(s string) (int, error) {
+	for i, w := range t.writers {
+		if t.errs[i] != nil {
+			continue
+		}
+		if n, err := WriteString(w, s); err != nil {
+			t.errs[i] = err
+		} else if n < len(s) {
+			t.errs[i] = io.ErrShortWrite
+		}
+	}
+	return len(s), nil
+}
@@ -0,0 +1,61 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// failingWriter accepts limit bytes and then fails
+type failingWriter struct {
+	limit   int
+	written strings.Builder
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written.Len()+len(p) > w.limit {
+		return 0, errors.New("connection reset")
+	}
+	return w.written.Write(p)
+}
+
+func TestRenderToMulti(t *testing.T) {
+	engine := New()
+	source := `{% for i in [1, 2, 3] %}<li>{{ i }}</li>{% endfor %}`
+	if err := engine.RegisterString("list", source); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	expected := "<li>1</li><li>2</li><li>3</li>"
+
+	var response, cache strings.Builder
+	if err := engine.RenderToMulti("list", nil, &response, &cache); err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	if response.String() != expected || cache.String() != expected {
+		t.Errorf("Expected both writers to get %q, got %q and %q", expected, response.String(), cache.String())
+	}
+
+	// A failing writer does not stop the others
+	broken := &failingWriter{limit: 5}
+	var log strings.Builder
+	err := engine.RenderToMulti("list", nil, broken, &log)
+	if log.String() != expected {
+		t.Errorf("Expected the healthy writer to get %q, got %q", expected, log.String())
+	}
+
+	var writerErr *WriterError
+	if !errors.As(err, &writerErr) || writerErr.Index != 0 {
+		t.Fatalf("Expected a WriterError for writer 0, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
+
+func TestRenderToMultiRenderError(t *testing.T) {
+	engine := New()
+	var out strings.Builder
+	if err := engine.RenderToMulti("missing", nil, &out); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package twig
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestTemplateStackInErrors tests that render errors carry the include chain
+func TestTemplateStackInErrors(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"page.twig":         "{% extends 'layout.twig' %}\n{% block content %}\nPage\n{{ title|no_such_filter }}\n{% endblock %}",
+		"layout.twig":       "<html>\n{% include 'partials/nav.twig' %}\n{% block content %}{% endblock %}\n</html>",
+		"partials/nav.twig": "<nav>\n{{ user.name }}\n{{ items|no_such_filter }}\n</nav>",
+		"simple.twig":       "{% include 'partials/nav.twig' with {'items': []} %}",
+	}))
+
+	tests := []struct {
+		name     string
+		template string
+		expected []TemplateFrame
+	}{
+		{
+			name:     "error in included template",
+			template: "simple.twig",
+			expected: []TemplateFrame{
+				{Template: "simple.twig", Line: 1},
+				{Template: "partials/nav.twig", Line: 3},
+			},
+		},
+		{
+			name:     "error in include of extended template",
+			template: "page.twig",
+			expected: []TemplateFrame{
+				{Template: "page.twig", Line: 1},
+				{Template: "layout.twig", Line: 2},
+				{Template: "partials/nav.twig", Line: 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := engine.Render(test.template, nil)
+			if err == nil {
+				t.Fatalf("Expected render error")
+			}
+
+			var enhanced *EnhancedError
+			if !errors.As(err, &enhanced) {
+				t.Fatalf("Expected *EnhancedError, got %T: %v", err, err)
+			}
+
+			if chain := enhanced.TemplateChain(); !reflect.DeepEqual(chain, test.expected) {
+				t.Errorf("Expected chain %v, got %v", test.expected, chain)
+			}
+			if !strings.Contains(err.Error(), "Template stack: ") {
+				t.Errorf("Expected error message to include the template stack, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestTemplateStackForOverriddenBlock tests that errors in child blocks are attributed to the child
+func TestTemplateStackForOverriddenBlock(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"page.twig":   "{% extends 'layout.twig' %}\n{% block content %}\nPage\n{{ title|no_such_filter }}\n{% endblock %}",
+		"layout.twig": "<html>\n<body>\n{% block content %}{% endblock %}\n</html>",
+	}))
+
+	_, err := engine.Render("page.twig", nil)
+
+	var enhanced *EnhancedError
+	if !errors.As(err, &enhanced) {
+		t.Fatalf("Expected *EnhancedError, got %T: %v", err, err)
+	}
+
+	expected := []TemplateFrame{
+		{Template: "page.twig", Line: 1},
+		{Template: "layout.twig", Line: 3},
+		{Template: "page.twig", Line: 4},
+	}
+	if chain := enhanced.TemplateChain(); !reflect.DeepEqual(chain, expected) {
+		t.Errorf("Expected chain %v, got %v", expected, chain)
+	}
+}
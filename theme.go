@@ -0,0 +1,43 @@
+package twig
+
+import "strings"
+
+// ThemeDirectory is the directory that holds one subdirectory per theme
+const ThemeDirectory = "themes"
+
+// SetThemeChain sets the themes templates are looked up in, most specific
+// first. With SetThemeChain("dark", "default"), loading card.twig tries
+// themes/dark/card.twig, then themes/default/card.twig, then card.twig.
+// Includes, extends and imports resolve through the same chain, and names
+// that already start with the theme directory are loaded as given. Changing
+// the chain clears the template cache.
+func (e *Engine) SetThemeChain(themes ...string) {
+	e.mu.Lock()
+	e.themes = append([]string(nil), themes...)
+	e.templates = make(map[string]*Template)
+	e.mu.Unlock()
+
+	e.macros.clear()
+	e.inheritance.clear()
+}
+
+// themeCandidates returns the names tried by the loaders for name
+func (e *Engine) themeCandidates(name string) []string {
+	if len(e.themes) == 0 || strings.HasPrefix(name, ThemeDirectory+"/") {
+		return []string{name}
+	}
+
+	candidates := make([]string, 0, len(e.themes)+1)
+	for _, theme := range e.themes {
+		candidates = append(candidates, ThemeDirectory+"/"+theme+"/"+name)
+	}
+	return append(candidates, name)
+}
+
+// loaderName returns the name the template was loaded under
+func (t *Template) loaderName() string {
+	if t.path != "" {
+		return t.path
+	}
+	return t.name
+}
@@ -0,0 +1,86 @@
+package twig
+
+import "testing"
+
+func TestThemeChain(t *testing.T) {
+	templates := map[string]string{
+		"themes/dark/card.twig":     `<div class="dark">{% include "title.twig" %}</div>`,
+		"themes/default/card.twig":  `<div>{% include "title.twig" %}</div>`,
+		"themes/default/title.twig": `<h1>{{ title }}</h1>`,
+		"themes/dark/page.twig":     `{% extends "layout.twig" %}{% block body %}{% include "card.twig" %}{% endblock %}`,
+		"layout.twig":               `<body>{% block body %}{% endblock %}</body>`,
+		"plain.twig":                `plain`,
+	}
+
+	tests := []struct {
+		name     string
+		themes   []string
+		template string
+		expected string
+	}{
+		{
+			name:     "most specific theme wins",
+			themes:   []string{"dark", "default"},
+			template: "card.twig",
+			expected: `<div class="dark"><h1>Hi</h1></div>`,
+		},
+		{
+			name:     "falls back along the chain",
+			themes:   []string{"default"},
+			template: "card.twig",
+			expected: `<div><h1>Hi</h1></div>`,
+		},
+		{
+			name:     "extends and include resolve through the chain",
+			themes:   []string{"dark", "default"},
+			template: "page.twig",
+			expected: `<body><div class="dark"><h1>Hi</h1></div></body>`,
+		},
+		{
+			name:     "unthemed templates load by name",
+			themes:   []string{"dark", "default"},
+			template: "plain.twig",
+			expected: `plain`,
+		},
+		{
+			name:     "explicit theme path",
+			themes:   []string{"dark", "default"},
+			template: "themes/default/title.twig",
+			expected: `<h1>Hi</h1>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.RegisterLoader(NewArrayLoader(templates))
+			engine.SetThemeChain(tt.themes...)
+
+			result, err := engine.Render(tt.template, map[string]interface{}{"title": "Hi"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSetThemeChainClearsCache(t *testing.T) {
+	engine := New()
+	engine.RegisterLoader(NewArrayLoader(map[string]string{
+		"themes/dark/card.twig":  "dark",
+		"themes/light/card.twig": "light",
+	}))
+
+	engine.SetThemeChain("dark")
+	if result, _ := engine.Render("card.twig", nil); result != "dark" {
+		t.Errorf("Expected dark, got %q", result)
+	}
+
+	engine.SetThemeChain("light")
+	if result, _ := engine.Render("card.twig", nil); result != "light" {
+		t.Errorf("Expected light after switching themes, got %q", result)
+	}
+}
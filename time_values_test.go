@@ -0,0 +1,65 @@
+package twig
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeValues(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	published := now.Add(-48 * time.Hour)
+	scheduled := now.Add(time.Hour)
+	sameInstant := now.In(time.FixedZone("CET", 3600))
+
+	context := map[string]interface{}{
+		"now":         now,
+		"published":   published,
+		"scheduled":   &scheduled,
+		"sameInstant": sameInstant,
+		"text":        "2024-03-15",
+		"nothing":     (*time.Time)(nil),
+		"laterWest":   now.Add(time.Hour).In(time.FixedZone("EST", -5*3600)),
+		"earlier":     now.AddDate(0, 0, -14),
+	}
+
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Greater than", "{% if scheduled > now %}future{% endif %}", "future"},
+		{"Less than", "{% if published < now %}past{% endif %}", "past"},
+		{"Less or equal", "{% if now <= sameInstant %}yes{% endif %}", "yes"},
+		{"Greater or equal", "{% if published >= now %}yes{% else %}no{% endif %}", "no"},
+		{"Equal across locations", "{% if now == sameInstant %}same{% endif %}", "same"},
+		{"Not equal", "{% if now != published %}different{% endif %}", "different"},
+		{"Pointer and value", "{% if scheduled != now %}different{% endif %}", "different"},
+		{"Is date", "{% if now is date %}yes{% endif %}", "yes"},
+		{"Pointer is date", "{% if scheduled is date %}yes{% endif %}", "yes"},
+		{"String is not date", "{% if text is date %}yes{% else %}no{% endif %}", "no"},
+		{"Nil pointer is not date", "{% if nothing is date %}yes{% else %}no{% endif %}", "no"},
+		{"Is not date", "{% if text is not date %}yes{% endif %}", "yes"},
+		{"Sort by time", "{{ [now, published]|sort|first|date('Y-m-d') }}", "2024-03-13"},
+		{"Sort across locations", "{% for t in [laterWest, now]|sort %}{{ t|date('H') }} {% endfor %}", "12 08 "},
+		{"Max time", "{{ max(published, now, earlier)|date('Y-m-d') }}", "2024-03-15"},
+		{"Min time", "{{ min(published, now, earlier)|date('Y-m-d') }}", "2024-03-01"},
+		{"In list", "{% if sameInstant in [published, now] %}found{% endif %}", "found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error registering template: %v", err)
+			}
+
+			result, err := engine.Render("test", context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
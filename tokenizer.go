@@ -16,7 +16,7 @@ func processWhitespaceControl(tokens []Token) []Token {
 		token := tokens[i]
 
 		// Handle opening tags that trim whitespace before them
-		if token.Type == TOKEN_VAR_START_TRIM || token.Type == TOKEN_BLOCK_START_TRIM {
+		if token.Type == TOKEN_VAR_START_TRIM || token.Type == TOKEN_BLOCK_START_TRIM || token.Type == TOKEN_COMMENT_START_TRIM {
 			// If there's a text token before this, trim its trailing whitespace
 			if i > 0 && tokens[i-1].Type == TOKEN_TEXT {
 				tokens[i-1].Value = trimTrailingWhitespace(tokens[i-1].Value)
@@ -24,7 +24,7 @@ func processWhitespaceControl(tokens []Token) []Token {
 		}
 
 		// Handle closing tags that trim whitespace after them
-		if token.Type == TOKEN_VAR_END_TRIM || token.Type == TOKEN_BLOCK_END_TRIM {
+		if token.Type == TOKEN_VAR_END_TRIM || token.Type == TOKEN_BLOCK_END_TRIM || token.Type == TOKEN_COMMENT_END_TRIM {
 			// If there's a text token after this, trim its leading whitespace
 			if i+1 < len(tokens) && tokens[i+1].Type == TOKEN_TEXT {
 				tokens[i+1].Value = trimLeadingWhitespace(tokens[i+1].Value)
@@ -0,0 +1,250 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizeHtmlPreserving(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []Token
+	}{
+		{
+			name:   "text and variable",
+			source: "<p>{{ name }}</p>",
+			expected: []Token{
+				{TOKEN_TEXT, "<p>", 1},
+				{TOKEN_VAR_START, "", 1},
+				{TOKEN_NAME, "name", 1},
+				{TOKEN_VAR_END, "", 1},
+				{TOKEN_TEXT, "</p>", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+		{
+			name:   "trim markers",
+			source: "{%- if a -%}{{- b -}}",
+			expected: []Token{
+				{TOKEN_BLOCK_START_TRIM, "", 1},
+				{TOKEN_NAME, "if", 1},
+				{TOKEN_NAME, "a", 1},
+				{TOKEN_BLOCK_END_TRIM, "", 1},
+				{TOKEN_VAR_START_TRIM, "", 1},
+				{TOKEN_NAME, "b", 1},
+				{TOKEN_VAR_END_TRIM, "", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+		{
+			name:   "trailing trim only",
+			source: "{% if a -%}",
+			expected: []Token{
+				{TOKEN_BLOCK_START, "", 1},
+				{TOKEN_NAME, "if", 1},
+				{TOKEN_NAME, "a", 1},
+				{TOKEN_BLOCK_END_TRIM, "", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+		{
+			name:   "escaped tag",
+			source: `a\{{ b }}`,
+			expected: []Token{
+				{TOKEN_TEXT, "a", 1},
+				{TOKEN_TEXT, "{{", 1},
+				{TOKEN_TEXT, " b }}", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+		{
+			name:   "braces in text",
+			source: "x { y {z} }",
+			expected: []Token{
+				{TOKEN_TEXT, "x { y {z} }", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+		{
+			name:   "lines inside multi-line tags",
+			source: "a\n{{\n  first\n  ~ second\n}}\nb",
+			expected: []Token{
+				{TOKEN_TEXT, "a\n", 1},
+				{TOKEN_VAR_START, "", 2},
+				{TOKEN_NAME, "first", 3},
+				{TOKEN_OPERATOR, "~", 4},
+				{TOKEN_NAME, "second", 4},
+				{TOKEN_VAR_END, "", 5},
+				{TOKEN_TEXT, "\nb", 5},
+				{TOKEN_EOF, "", 6},
+			},
+		},
+		{
+			name:   "comment",
+			source: "{# note\n #}x",
+			expected: []Token{
+				{TOKEN_COMMENT_START, "", 1},
+				{TOKEN_TEXT, " note\n ", 1},
+				{TOKEN_COMMENT_END, "", 2},
+				{TOKEN_TEXT, "x", 2},
+				{TOKEN_EOF, "", 2},
+			},
+		},
+		{
+			name:   "comment with whitespace control",
+			source: "{#- note -#}",
+			expected: []Token{
+				{TOKEN_COMMENT_START_TRIM, "", 1},
+				{TOKEN_TEXT, " note ", 1},
+				{TOKEN_COMMENT_END_TRIM, "", 1},
+				{TOKEN_EOF, "", 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := GetTokenizer(tt.source, 0)
+			defer ReleaseTokenizer(tokenizer)
+
+			tokens, err := tokenizer.TokenizeHtmlPreserving()
+			if err != nil {
+				t.Fatalf("Error tokenizing: %v", err)
+			}
+
+			if len(tokens) != len(tt.expected) {
+				t.Fatalf("Expected %d tokens, got %d: %v", len(tt.expected), len(tokens), tokens)
+			}
+			for i, token := range tokens {
+				if token != tt.expected[i] {
+					t.Errorf("Token %d: expected %v, got %v", i, tt.expected[i], token)
+				}
+			}
+		})
+	}
+}
+
+func TestTokenizeUnclosedTags(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"a\n{{ b", "unclosed variable tag at line 2"},
+		{"{% if a", "unclosed block tag at line 1"},
+		{"{# note", "unclosed comment at line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			tokenizer := GetTokenizer(tt.source, 0)
+			defer ReleaseTokenizer(tokenizer)
+
+			_, err := tokenizer.TokenizeHtmlPreserving()
+			if err == nil || err.Error() != tt.expected {
+				t.Errorf("Expected error %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestMultilineBlockTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"if across lines", "{% if user.name ==\n 'x' %}yes{% endif %}", "yes"},
+		{"tabs", "{%\tif\ta\t%}A{% endif %}", "A"},
+		{"include across lines", "{% include\n  'part.twig'\n  with {'n': 1}\n  only %}", "[1]"},
+		{"for across lines", "{% for k,\n v in {'a': 1} %}{{ k }}{{ v }}{% endfor %}", "a1"},
+		{"set across lines", "{% set\n x =\n 5 %}{{ x }}", "5"},
+		{"from across lines", "{% from 'macros.twig' import\n hi as hello %}{{ hello() }}", "HI"},
+		{"import across lines", "{% import 'macros.twig' as\n m %}{{ m.hi() }}", "HI"},
+		{"extends across lines", "{% extends\n 'part.twig' %}", "[2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.RegisterString("part.twig", "[{{ n }}]")
+			engine.RegisterString("macros.twig", "{% macro hi() %}HI{% endmacro %}")
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", map[string]interface{}{
+				"user": map[string]interface{}{"name": "x"},
+				"a":    true,
+				"n":    2,
+			})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestBlockTagTokenLines(t *testing.T) {
+	tokenizer := GetTokenizer("{% if a\n  and b %}", 0)
+	defer ReleaseTokenizer(tokenizer)
+
+	tokens, err := tokenizer.TokenizeHtmlPreserving()
+	if err != nil {
+		t.Fatalf("Error tokenizing: %v", err)
+	}
+
+	lines := map[string]int{}
+	for _, token := range tokens {
+		if token.Type == TOKEN_NAME {
+			lines[token.Value] = token.Line
+		}
+	}
+	if lines["if"] != 1 || lines["a"] != 1 || lines["and"] != 2 || lines["b"] != 2 {
+		t.Errorf("Unexpected token lines: %v", lines)
+	}
+}
+
+// filterLines records the line of each filter in a parse tree
+type filterLines map[string]int
+
+func (f filterLines) Enter(node Node) bool {
+	if n, ok := node.(*FilterNode); ok {
+		f[n.filter] = n.Line()
+	}
+	return true
+}
+
+func (f filterLines) Leave(node Node) {}
+
+func TestMultilineTagNodeLines(t *testing.T) {
+	parser := &Parser{}
+	nodes, err := parser.Parse("line one\n{{\n  value\n  |upper\n}}")
+	if err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+
+	lines := filterLines{}
+	Walk(nodes, lines)
+	if lines["upper"] != 4 {
+		t.Errorf("Expected the filter on line 4, got %v", lines)
+	}
+}
+
+// Many tags of one kind used to make every iteration rescan the rest of the
+// source for the other tag kinds
+func BenchmarkTokenizeManyTags(b *testing.B) {
+	source := strings.Repeat("<li>{{ item }}</li>\n", 5000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tokenizer := GetTokenizer(source, 0)
+		if _, err := tokenizer.TokenizeHtmlPreserving(); err != nil {
+			b.Fatal(err)
+		}
+		ReleaseTokenizer(tokenizer)
+	}
+}
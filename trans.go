@@ -0,0 +1,59 @@
+package twig
+
+import "fmt"
+
+// Translator looks up the translation of a message for the trans filter.
+// An empty locale stands for the translator's current one. It reports false
+// when the message has no translation.
+type Translator interface {
+	Translate(id, domain, locale string) (string, bool)
+}
+
+// TranslatorFunc adapts a function to the Translator interface
+type TranslatorFunc func(id, domain, locale string) (string, bool)
+
+// Translate calls f(id, domain, locale)
+func (f TranslatorFunc) Translate(id, domain, locale string) (string, bool) {
+	return f(id, domain, locale)
+}
+
+// SetTranslator sets the translator used by the trans filter. Without one,
+// or for messages it has no translation for, trans only replaces the
+// parameters in the message, so templates render in their source language.
+func (e *Engine) SetTranslator(translator Translator) {
+	e.environment.translator = translator
+}
+
+// filterTrans implements message|trans(parameters, domain, locale), as in
+// 'Hello %name%'|trans({'%name%': user.name}, 'admin'). The messages it is
+// applied to are those collected by ExtractMessages.
+func (e *CoreExtension) filterTrans(value interface{}, args ...interface{}) (interface{}, error) {
+	id := e.toString(value)
+
+	var parameters map[string]interface{}
+	if len(args) > 0 && args[0] != nil {
+		p, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("trans parameters must be a mapping, got %T", args[0])
+		}
+		parameters = p
+	}
+	domain := DefaultMessageDomain
+	if len(args) > 1 && args[1] != nil {
+		if d := e.toString(args[1]); d != "" {
+			domain = d
+		}
+	}
+	locale := ""
+	if len(args) > 2 && args[2] != nil {
+		locale = e.toString(args[2])
+	}
+
+	message := id
+	if e.env != nil && e.env.translator != nil {
+		if translated, ok := e.env.translator.Translate(id, domain, locale); ok {
+			message = translated
+		}
+	}
+	return replacePairs(message, parameters), nil
+}
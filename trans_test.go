@@ -0,0 +1,44 @@
+package twig
+
+import "testing"
+
+func TestTransFilter(t *testing.T) {
+	source := "{{ 'Hello %name%'|trans({'%name%': name}) }}|{{ 'Save'|trans({}, 'admin') }}|{{ 'Save'|trans({}, 'admin', 'de') }}"
+	french := map[string]string{
+		"messages:Hello %name%": "Bonjour %name%",
+		"admin:Save":            "Enregistrer",
+	}
+
+	tests := []struct {
+		name       string
+		translator Translator
+		expected   string
+	}{
+		{"without translator", nil, "Hello Ann|Save|Save"},
+		{"with translator", TranslatorFunc(func(id, domain, locale string) (string, bool) {
+			if locale == "de" {
+				return "Speichern", true
+			}
+			message, ok := french[domain+":"+id]
+			return message, ok
+		}), "Bonjour Ann|Enregistrer|Speichern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			engine.SetTranslator(tt.translator)
+			if err := engine.RegisterString("page", source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("page", map[string]interface{}{"name": "Ann"})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
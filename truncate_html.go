@@ -0,0 +1,87 @@
+package twig
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// filterTruncateHTML implements html|truncate_html(length, ellipsis = '…'),
+// which shortens HTML to length characters of text and closes the tags that
+// are open at the cut, so the result stays well formed. Tags and comments
+// do not count towards the length and an entity such as &amp; counts as one
+// character. HTML that is short enough is returned unchanged.
+func (e *CoreExtension) filterTruncateHTML(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return "", nil
+	}
+	s := e.toString(value)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("truncate_html filter requires a length")
+	}
+	limit, err := toInt(args[0])
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("truncate_html filter expects a non-negative length")
+	}
+	ellipsis := "…"
+	if len(args) > 1 && args[1] != nil {
+		ellipsis = e.toString(args[1])
+	}
+
+	var b strings.Builder
+	var open []string // Tags open at the current position
+	count := 0
+
+	for i := 0; i < len(s); {
+		if s[i] == '<' {
+			if strings.HasPrefix(s[i:], "<!--") {
+				end := strings.Index(s[i+4:], "-->")
+				if end < 0 {
+					end = len(s) - i - 7
+				}
+				b.WriteString(s[i : i+4+end+3])
+				i += 4 + end + 3
+				continue
+			}
+			if name, _, closing, next, ok := parseHTMLTag(s, i); ok {
+				b.WriteString(s[i:next])
+				switch {
+				case closing:
+					for j := len(open) - 1; j >= 0; j-- {
+						if open[j] == name {
+							open = open[:j]
+							break
+						}
+					}
+				case !voidElements[name] && !strings.HasSuffix(s[i:next], "/>"):
+					open = append(open, name)
+				}
+				i = next
+				continue
+			}
+		}
+
+		// One character of text, or a whole entity
+		size := 1
+		if s[i] == '&' {
+			if end := strings.IndexByte(s[i:], ';'); end > 1 && end <= 10 && !strings.ContainsAny(s[i+1:i+end], " <&") {
+				size = end + 1
+			}
+		} else {
+			_, size = utf8.DecodeRuneInString(s[i:])
+		}
+
+		if count == limit {
+			b.WriteString(ellipsis)
+			for j := len(open) - 1; j >= 0; j-- {
+				b.WriteString("</" + open[j] + ">")
+			}
+			return b.String(), nil
+		}
+		b.WriteString(s[i : i+size])
+		count++
+		i += size
+	}
+
+	return s, nil
+}
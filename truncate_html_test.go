@@ -0,0 +1,43 @@
+package twig
+
+import "testing"
+
+func TestTruncateHTMLFilter(t *testing.T) {
+	engine := New()
+
+	tests := []struct {
+		source   string
+		value    string
+		expected string
+	}{
+		{"{{ value|truncate_html(5)|raw }}", "<p>Hello <b>world</b></p>", "<p>Hello…</p>"},
+		{"{{ value|truncate_html(8)|raw }}", "<p>Hello <b>world</b></p>", "<p>Hello <b>wo…</b></p>"},
+		{"{{ value|truncate_html(20)|raw }}", "<p>Hello <b>world</b></p>", "<p>Hello <b>world</b></p>"},
+		{"{{ value|truncate_html(3, '...')|raw }}", "<div>a<br>b<img src=\"x\"/>c d</div>", "<div>a<br>b<img src=\"x\"/>c...</div>"},
+		{"{{ value|truncate_html(2)|raw }}", "&amp;&lt;&gt;", "&amp;&lt;…"},
+		{"{{ value|truncate_html(2)|raw }}", "<!-- <b> -->héllo", "<!-- <b> -->hé…"},
+		{"{{ value|truncate_html(0)|raw }}", "<em>x</em>", "<em>…</em>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if err := engine.RegisterString("page", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+			result, err := engine.Render("page", map[string]interface{}{"value": tt.value})
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	if err := engine.RegisterString("bad", "{{ 'abc'|truncate_html }}"); err != nil {
+		t.Fatalf("Error parsing template: %v", err)
+	}
+	if _, err := engine.Render("bad", nil); err == nil {
+		t.Error("Expected an error without a length")
+	}
+}
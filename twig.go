@@ -7,19 +7,46 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Engine represents the Twig template engine
 type Engine struct {
-	templates       map[string]*Template
-	mu              sync.RWMutex
-	autoReload      bool
-	strictVars      bool
-	loaders         []Loader
-	environment     *Environment
-	debug           bool
-	currentTemplate string // Tracks the name of the template currently being rendered
+	templates        map[string]*Template
+	mu               sync.RWMutex
+	autoReload       bool
+	strictVars       bool
+	loaders          []Loader
+	environment      *Environment
+	debug            bool
+	currentTemplate  string // Tracks the name of the template currently being rendered
+	errorTemplate    string // Template used to render errors in debug mode
+	fallback         string // Template rendered in place of missing templates
+	errorHook        func(err error)
+	parent           *Engine        // Engine whose templates a scope shares, see NewScope
+	scopes           atomic.Uint64  // Scopes created, numbering their cached blocks
+	interner         stringInterner // Identifiers shared by parsed templates
+	newline          string         // Line ending sources are normalized to, empty to preserve
+	trimFinalNewline bool           // Drop one trailing newline from sources, see SetKeepTrailingNewline
+	normalizeOutput  bool           // Normalize line endings of rendered output, see SetNormalizeOutputNewlines
+	maxTemplateSize  int            // Largest source in bytes the engine parses, 0 for no limit
+	streamingParse   int            // Smallest source in bytes parsed in streaming mode, 0 for never
+	retainComments   bool           // Keep comments in parsed templates, see SetRetainComments
+	maxIncludeOutput int64          // Bytes a single include or embed may write, 0 for no limit
+	maxNodeVisits    int            // Nodes a single render may visit, 0 for no limit
+	pprofLabels      bool           // Label renders for CPU profiles, see SetPprofLabels
+	markDiagnostics  bool           // RenderCollect marks failed statements, see SetDiagnosticComments
+	themes           []string       // Theme directories tried before the plain name, see SetThemeChain
+	nameResolver     TemplateNameResolver
+	middleware       []namedMiddleware      // Writer middleware applied to renders, see UseWriter
+	defaultContext   map[string]interface{} // Values merged under every render's context
+
+	// Caches reused across renders
+	macros      macroCache          // Macros harvested from imported templates
+	inheritance inheritanceCache    // Resolved parents of templates using extends
+	attributes  attributeCacheStore // Struct field and method lookups by type
+	raw         rawSourceCache      // Files output by include_raw
 
 	// Test helper - override Parse function
 	Parse func(source string) (*Template, error)
@@ -31,15 +58,26 @@ type Template struct {
 	source       string
 	nodes        Node
 	env          *Environment
-	engine       *Engine // Reference back to the engine for loading parent templates
-	loader       Loader  // The loader that loaded this template
-	lastModified int64   // Last modified timestamp for this template
+	engine       *Engine   // Reference back to the engine for loading parent templates
+	loader       Loader    // The loader that loaded this template
+	lastModified int64     // Last modified timestamp for this template
+	origin       *Template // Parent engine template this one shares nodes with
+	path         string    // Name the loader found the template under, see SetThemeChain
+
+	parsedAt      time.Time     // When the source was parsed
+	parseDuration time.Duration // Time taken to parse the source
+
+	taintOnce       sync.Once // Logs the taint warnings once, see TaintWarnings
+	deprecationOnce sync.Once // Reports the deprecations once, see Deprecations
 }
 
 // Environment holds configuration and context for template rendering
 type Environment struct {
 	globals        map[string]interface{}
+	services       map[string]ServiceFunc       // Lazily created variables, see RegisterService
+	namespaces     map[string]NamespaceProvider // Variables resolved by path, see RegisterNamespace
 	filters        map[string]FilterFunc
+	filtersV2      map[string]FilterFuncV2 // Filters taking FilterArgs, see AddFilterV2
 	functions      map[string]FunctionFunc
 	tests          map[string]TestFunc
 	operators      map[string]OperatorFunc
@@ -49,6 +87,51 @@ type Environment struct {
 	debug          bool
 	sandbox        bool
 	securityPolicy SecurityPolicy // Security policy for sandbox mode
+
+	undefinedFilterMode     UndefinedFilterMode     // How unknown filters are handled
+	undefinedFilterResolver UndefinedFilterResolver // Fallback lookup for unknown filters
+	stringers               []StringerFunc          // Custom conversions used when printing values
+	comparators             []Comparator            // Custom ordering for sort, min, max and comparisons
+	clock                   func() time.Time        // Source of the current time, time.Now when nil
+	htmlSanitizer           HTMLSanitizer           // Policy used by the sanitize_html filter
+	assetResolver           AssetResolver           // Resolves paths for the asset function
+	translator              Translator              // Looks up messages for the trans filter
+	routeResolver           RouteResolver           // Generates paths for the path and url functions
+	baseURL                 string                  // Scheme and host prepended by the url function
+	random                  io.Reader               // Source for uuid and random_string, crypto/rand when nil
+	randomMu                sync.Mutex              // Serializes reads from random
+	fileResolver            FileResolver            // Opens files for the image_size function
+	imageSizes              sync.Map                // Cached image_size results by path
+	featureProvider         FeatureProvider         // Answers feature() and the enabled test
+	appConfig               AppConfig               // Settings for the app variable of RenderHTTP
+	nonceProvider           NonceProvider           // Creates CSP nonces, random bytes when nil
+	cspPolicy               string                  // Content-Security-Policy sent by RenderHTTP
+	integrityProvider       IntegrityProvider       // Answers sri_hash, hashing files when nil
+	integrityHashes         sync.Map                // Cached sri_hash results by path
+	constants               map[string]interface{}  // Values for the constant function and test
+	enums                   map[string]*enumType    // Enum types for the enum function
+	loopScopeMode           LoopScopeMode           // How set inside for loops is scoped
+	fragmentCache           FragmentCache           // Store for blocks annotated with cache(...)
+	fragmentPrefix          string                  // Keeps the cached blocks of scopes apart, see NewScope
+	strictFilterArgs        bool                    // Report filter arguments of unexpected types
+	filterArgReporter       FilterArgReporter       // Receives those reports, logged when nil
+	listRepetition          bool                    // Let * repeat lists, see SetListRepetition
+	unitFormatter           UnitFormatter           // Formats filesize and duration output, see SetUnitFormatter
+	escapeFormatVerbs       bool                    // Write unknown format verbs as is, see SetEscapeUnknownFormatVerbs
+	operatorAliases         map[string]syntaxAlias  // Operators standing for others, see AliasOperator
+	testAliases             map[string]syntaxAlias  // Tests standing for others, see AliasTest
+	hasDeprecations         bool                    // Some alias is deprecated, see DeprecateOperator
+	deprecationHandler      DeprecationHandler      // Receives deprecation reports, logged when nil
+	collators               map[string]Collator     // Collators by locale, see SetCollator
+	errorFormatter          ErrorFormatter          // Prints error values, see SetErrorFormatter
+}
+
+// now returns the current time according to the environment's clock
+func (env *Environment) now() time.Time {
+	if env != nil && env.clock != nil {
+		return env.clock()
+	}
+	return time.Now()
 }
 
 // New creates a new Twig engine instance
@@ -91,6 +174,22 @@ func (e *Engine) SetStrictVars(strictVars bool) {
 	e.strictVars = strictVars
 }
 
+// SetUndefinedFilterMode sets how unknown filters are handled during rendering
+func (e *Engine) SetUndefinedFilterMode(mode UndefinedFilterMode) {
+	e.environment.undefinedFilterMode = mode
+}
+
+// SetUndefinedFilterResolver sets the fallback used to look up unknown filters
+// and switches the engine to UndefinedFilterResolve mode
+func (e *Engine) SetUndefinedFilterResolver(resolver UndefinedFilterResolver) {
+	e.environment.undefinedFilterResolver = resolver
+	if resolver != nil {
+		e.environment.undefinedFilterMode = UndefinedFilterResolve
+	} else {
+		e.environment.undefinedFilterMode = UndefinedFilterError
+	}
+}
+
 // EnableSandbox enables sandbox mode with the given security policy
 func (e *Engine) EnableSandbox(policy SecurityPolicy) {
 	e.environment.sandbox = true
@@ -118,6 +217,10 @@ func (e *Engine) SetDebug(enabled bool) {
 // SetCache enables or disables template caching
 func (e *Engine) SetCache(enabled bool) {
 	e.environment.cache = enabled
+	if !enabled {
+		e.macros.clear()
+		e.inheritance.clear()
+	}
 }
 
 // SetDevelopmentMode enables settings appropriate for development
@@ -127,10 +230,23 @@ func (e *Engine) SetDevelopmentMode(enabled bool) {
 	e.debug = enabled
 	e.autoReload = enabled
 	e.environment.cache = !enabled
+	if enabled {
+		e.macros.clear()
+		e.inheritance.clear()
+	}
 }
 
-// Render renders a template with the given context
-func (e *Engine) Render(name string, context map[string]interface{}) (string, error) {
+// Render renders a template with the given context and optional
+// per-render overrides of the engine settings
+func (e *Engine) Render(name string, context map[string]interface{}, opts ...RenderOptions) (string, error) {
+	// Middleware work on writers, so the output goes through RenderTo
+	if e.hasWriterMiddleware(context) {
+		buf := NewStringBuffer()
+		defer buf.Release()
+		err := e.RenderTo(buf, name, context, opts...)
+		return buf.String(), err
+	}
+
 	LogInfo("Rendering template: %s", name)
 
 	// Store current template name and previous template name
@@ -141,7 +257,10 @@ func (e *Engine) Render(name string, context map[string]interface{}) (string, er
 		e.currentTemplate = prevTemplate
 	}()
 
-	template, err := e.Load(name)
+	template, err := e.loadResolved(name, context)
+	if err != nil {
+		template, err = e.loadFallback(err)
+	}
 	if err != nil {
 		LogError(err, fmt.Sprintf("Failed to load template: %s", name))
 		return "", err
@@ -150,7 +269,8 @@ func (e *Engine) Render(name string, context map[string]interface{}) (string, er
 	// If debug is enabled, use more detailed error reporting
 	if e.environment.debug {
 		var buf StringBuffer
-		ctx := NewRenderContext(e.environment, context, e)
+		ctx := newRootContext(e.environment, context, e)
+		ctx.options = mergeRenderOptions(opts)
 		defer ctx.Release()
 
 		// Use debug rendering with enhanced error reporting
@@ -166,6 +286,15 @@ func (e *Engine) Render(name string, context map[string]interface{}) (string, er
 					enhancedErr.Template = name
 				}
 			}
+
+			// Render a styled error page if one is configured
+			if e.errorTemplate != "" {
+				page := NewStringBuffer()
+				defer page.Release()
+				if pageErr := e.renderErrorPage(page, err); pageErr == nil {
+					return page.String(), err
+				}
+			}
 			return "", err
 		}
 
@@ -173,11 +302,26 @@ func (e *Engine) Render(name string, context map[string]interface{}) (string, er
 	}
 
 	// Normal rendering path without debug overhead
-	return template.Render(context)
+	return template.Render(context, opts...)
 }
 
-// RenderTo renders a template to a writer
-func (e *Engine) RenderTo(w io.Writer, name string, context map[string]interface{}) error {
+// RenderTo renders a template to a writer with optional per-render
+// overrides of the engine settings, see RenderOptions
+func (e *Engine) RenderTo(w io.Writer, name string, context map[string]interface{}, opts ...RenderOptions) error {
+	chain := e.wrapWriter(w, name, context)
+	if chain == nil {
+		return e.renderTo(w, name, context, opts)
+	}
+
+	err := e.renderTo(chain, name, context, opts)
+	if closeErr := chain.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// renderTo renders a template to a writer without writer middleware
+func (e *Engine) renderTo(w io.Writer, name string, context map[string]interface{}, opts []RenderOptions) error {
 	LogInfo("Rendering template to writer: %s", name)
 
 	// Store current template name and previous template name
@@ -188,7 +332,10 @@ func (e *Engine) RenderTo(w io.Writer, name string, context map[string]interface
 		e.currentTemplate = prevTemplate
 	}()
 
-	template, err := e.Load(name)
+	template, err := e.loadResolved(name, context)
+	if err != nil {
+		template, err = e.loadFallback(err)
+	}
 	if err != nil {
 		LogError(err, fmt.Sprintf("Failed to load template: %s", name))
 		return err
@@ -196,11 +343,21 @@ func (e *Engine) RenderTo(w io.Writer, name string, context map[string]interface
 
 	// If debug is enabled, use more detailed error reporting
 	if e.environment.debug {
-		ctx := NewRenderContext(e.environment, context, e)
+		ctx := newRootContext(e.environment, context, e)
+		ctx.options = mergeRenderOptions(opts)
 		defer ctx.Release()
 
+		// Buffer the output when an error page may replace it
+		out := w
+		var buf *StringBuffer
+		if e.errorTemplate != "" {
+			buf = NewStringBuffer()
+			defer buf.Release()
+			out = buf
+		}
+
 		// Use debug rendering with enhanced error reporting
-		err = DebugRender(w, template, ctx)
+		err = DebugRender(out, template, ctx)
 		if err != nil {
 			LogError(err, fmt.Sprintf("Error rendering template: %s", name))
 			// Enhance error with template information
@@ -212,18 +369,35 @@ func (e *Engine) RenderTo(w io.Writer, name string, context map[string]interface
 					enhancedErr.Template = name
 				}
 			}
+
+			// Render a styled error page if one is configured
+			if buf != nil {
+				_ = e.renderErrorPage(w, err)
+			}
 			return err
 		}
 
-		return nil
+		if buf != nil {
+			_, err = WriteString(w, buf.String())
+		}
+		return err
 	}
 
 	// Normal rendering path without debug overhead
-	return template.RenderTo(w, context)
+	return template.RenderTo(w, context, opts...)
 }
 
 // Load loads a template by name
 func (e *Engine) Load(name string) (*Template, error) {
+	template, err := e.load(name)
+	if err == nil && e.environment.hasDeprecations {
+		template.reportDeprecations()
+	}
+	return template, err
+}
+
+// load loads a template by name without reporting its deprecations
+func (e *Engine) load(name string) (*Template, error) {
 	// Only check the cache if caching is enabled
 	if e.environment.cache {
 		// Use a quick check under read lock first to avoid contention
@@ -231,6 +405,11 @@ func (e *Engine) Load(name string) (*Template, error) {
 		tmpl, ok := e.templates[name]
 		e.mu.RUnlock()
 
+		// Templates shared from a parent engine follow the parent's cache
+		if ok && tmpl.origin != nil {
+			return e.loadFromParent(name)
+		}
+
 		// If template exists in cache
 		if ok {
 			// If auto-reload is disabled, return the cached template immediately
@@ -245,7 +424,7 @@ func (e *Engine) Load(name string) (*Template, error) {
 				// Check if the loader supports timestamp checking
 				if tsLoader, ok := tmpl.loader.(TimestampAwareLoader); ok {
 					// Get the current modification time
-					currentModTime, err := tsLoader.GetModifiedTime(name)
+					currentModTime, err := tsLoader.GetModifiedTime(tmpl.loaderName())
 					if err != nil || currentModTime > tmpl.lastModified {
 						needsReload = true
 					}
@@ -266,44 +445,61 @@ func (e *Engine) Load(name string) (*Template, error) {
 	var loaderErrors []error
 	var template *Template
 
-	for _, loader := range e.loaders {
-		source, err := loader.Load(name)
-		if err != nil {
-			// Collect loader errors for better diagnostics
-			loaderErrors = append(loaderErrors, fmt.Errorf("loader %T: %w", loader, err))
-			continue
-		}
+	for _, candidate := range e.themeCandidates(name) {
+		for _, loader := range e.loaders {
+			source, err := loader.Load(candidate)
+			if err != nil {
+				// Collect loader errors for better diagnostics
+				loaderErrors = append(loaderErrors, fmt.Errorf("loader %T: %w", loader, err))
+				continue
+			}
 
-		// If this loader supports modification times, get the time
-		if tsLoader, ok := loader.(TimestampAwareLoader); ok {
-			lastModified, _ = tsLoader.GetModifiedTime(name)
-		}
+			// If this loader supports modification times, get the time
+			if tsLoader, ok := loader.(TimestampAwareLoader); ok {
+				lastModified, _ = tsLoader.GetModifiedTime(candidate)
+			}
 
-		sourceLoader = loader
-		LogInfo("Template '%s' loaded from %T", name, loader)
+			sourceLoader = loader
+			LogInfo("Template '%s' loaded from %T", candidate, loader)
 
-		parser := &Parser{}
-		nodes, err := parser.Parse(source)
-		if err != nil {
-			// Include more context in parsing errors
-			return nil, NewError(err, name, 0, 0, source)
-		}
+			if err := e.checkTemplateSize(name, len(source)); err != nil {
+				return nil, err
+			}
 
-		template = &Template{
-			name:         name,
-			source:       source,
-			nodes:        nodes,
-			env:          e.environment,
-			engine:       e, // Add reference to the engine
-			loader:       sourceLoader,
-			lastModified: lastModified,
-		}
+			source = e.prepareSource(source)
+			parser := e.newParser()
+			parseStart := time.Now()
+			nodes, err := parser.Parse(source)
+			if err != nil {
+				// Include more context in parsing errors
+				return nil, NewError(err, candidate, 0, 0, source)
+			}
+
+			template = &Template{
+				name:          name,
+				path:          candidate,
+				source:        source,
+				nodes:         nodes,
+				env:           e.environment,
+				engine:        e, // Add reference to the engine
+				loader:        sourceLoader,
+				lastModified:  lastModified,
+				parsedAt:      parseStart,
+				parseDuration: time.Since(parseStart),
+			}
 
-		// Successfully loaded template
-		break
+			// Successfully loaded template
+			break
+		}
+		if template != nil {
+			break
+		}
 	}
 
 	// If we failed to load the template from any loader
+	if template == nil && e.parent != nil {
+		return e.loadFromParent(name)
+	}
 	if template == nil {
 		// If we have collected errors from loaders, include them in the error message
 		if len(loaderErrors) > 0 {
@@ -334,8 +530,13 @@ func (e *Engine) Load(name string) (*Template, error) {
 
 // RegisterString registers a template from a string source
 func (e *Engine) RegisterString(name string, source string) error {
-	parser := &Parser{}
-	nodes, err := parser.Parse(source)
+	if err := e.checkTemplateSize(name, len(source)); err != nil {
+		return err
+	}
+
+	source = e.prepareSource(source)
+	parseStart := time.Now()
+	nodes, err := e.parseCached(source)
 	if err != nil {
 		return err
 	}
@@ -345,13 +546,15 @@ func (e *Engine) RegisterString(name string, source string) error {
 	now := time.Now().Unix()
 
 	template := &Template{
-		name:         name,
-		source:       source,
-		nodes:        nodes,
-		env:          e.environment,
-		engine:       e,
-		lastModified: now,
-		loader:       nil, // String templates don't have a loader
+		name:          name,
+		source:        source,
+		nodes:         nodes,
+		env:           e.environment,
+		engine:        e,
+		lastModified:  now,
+		loader:        nil, // String templates don't have a loader
+		parsedAt:      parseStart,
+		parseDuration: time.Since(parseStart),
 	}
 
 	// Only cache if caching is enabled
@@ -402,6 +605,39 @@ func (e *Engine) GetCachedTemplateNames() []string {
 	return names
 }
 
+// InvalidateTemplate removes a template from the cache, so the next render
+// loads it from the loaders again. Parents and macros cached for the old
+// version are dropped along with it.
+func (e *Engine) InvalidateTemplate(name string) {
+	e.mu.Lock()
+	delete(e.templates, name)
+	e.mu.Unlock()
+}
+
+// ClearCaches empties every cache of the engine: templates parsed from its
+// loaders, macros and parents cached for imports and extends, attribute
+// lookups, and the image_size and sri_hash results. Templates registered
+// with RegisterString, RegisterTemplate or ImportBundle have no loader to
+// parse them again from, so they are kept. Long-running processes call it
+// after reloading plugins whose types or assets changed. Other engines,
+// including scopes of this one, keep their caches.
+func (e *Engine) ClearCaches() {
+	e.mu.Lock()
+	for name, template := range e.templates {
+		if template.loader != nil || template.origin != nil {
+			delete(e.templates, name)
+		}
+	}
+	e.mu.Unlock()
+
+	e.macros.clear()
+	e.inheritance.clear()
+	e.attributes.clear()
+	e.raw.clear()
+	e.environment.imageSizes.Clear()
+	e.environment.integrityHashes.Clear()
+}
+
 // RegisterTemplate directly registers a pre-built template
 func (e *Engine) RegisterTemplate(name string, template *Template) {
 	// Set the lastModified timestamp if it's not already set
@@ -461,6 +697,7 @@ func (e *Engine) LoadFromCompiledData(data []byte) error {
 // AddFilter registers a custom filter function
 func (e *Engine) AddFilter(name string, filter FilterFunc) {
 	e.environment.filters[name] = filter
+	delete(e.environment.filtersV2, name)
 }
 
 // AddFunction registers a custom function
@@ -473,6 +710,79 @@ func (e *Engine) AddTest(name string, test TestFunc) {
 	e.environment.tests[name] = test
 }
 
+// RegisterStringer registers a function used to convert values to strings
+// before falling back to fmt.Stringer and fmt.Sprintf. Stringers are
+// consulted in registration order.
+func (e *Engine) RegisterStringer(stringer StringerFunc) {
+	e.environment.stringers = append(e.environment.stringers, stringer)
+}
+
+// RegisterComparator registers a comparator consulted by sort, min, max and
+// the comparison operators before the built-in ordering. Comparators are
+// consulted in registration order.
+func (e *Engine) RegisterComparator(comparator Comparator) {
+	e.environment.comparators = append(e.environment.comparators, comparator)
+}
+
+// SetClock sets the function used for the current time by the date filter,
+// the date function and the now variable. Passing nil restores time.Now.
+func (e *Engine) SetClock(clock func() time.Time) {
+	e.environment.clock = clock
+}
+
+// SetHTMLSanitizer sets the sanitizer used by the sanitize_html filter, such
+// as a customized SanitizePolicy or a bluemonday policy. Passing nil restores
+// DefaultSanitizePolicy.
+func (e *Engine) SetHTMLSanitizer(sanitizer HTMLSanitizer) {
+	e.environment.htmlSanitizer = sanitizer
+}
+
+// SetAssetResolver sets the resolver used by the asset function. Without a
+// resolver asset returns its path unchanged.
+func (e *Engine) SetAssetResolver(resolver AssetResolver) {
+	e.environment.assetResolver = resolver
+}
+
+// SetRouteResolver sets the resolver used by the path and url functions
+func (e *Engine) SetRouteResolver(resolver RouteResolver) {
+	e.environment.routeResolver = resolver
+}
+
+// SetBaseURL sets the scheme and host, e.g. "https://example.com", that the
+// url function prepends to route paths
+func (e *Engine) SetBaseURL(baseURL string) {
+	e.environment.baseURL = baseURL
+}
+
+// SetRandomSource sets the source of random bytes for the uuid and
+// random_string functions. Pass a seeded math/rand *Rand for reproducible
+// output in tests; nil restores crypto/rand.
+func (e *Engine) SetRandomSource(source io.Reader) {
+	e.environment.randomMu.Lock()
+	e.environment.random = source
+	e.environment.randomMu.Unlock()
+}
+
+// SetFileResolver sets the resolver used by image_size and sri_hash to open
+// files and clears their cached results
+func (e *Engine) SetFileResolver(resolver FileResolver) {
+	e.environment.fileResolver = resolver
+	e.environment.imageSizes.Clear()
+	e.environment.integrityHashes.Clear()
+}
+
+// SetFeatureProvider sets the provider for the feature function and the
+// enabled test. Without a provider every flag is disabled.
+func (e *Engine) SetFeatureProvider(provider FeatureProvider) {
+	e.environment.featureProvider = provider
+}
+
+// SetAppConfig sets the environment name and locales used to build the app
+// variable injected by RenderHTTP
+func (e *Engine) SetAppConfig(config AppConfig) {
+	e.environment.appConfig = config
+}
+
 // AddGlobal adds a global variable to the template environment
 func (e *Engine) AddGlobal(name string, value interface{}) {
 	e.environment.globals[name] = value
@@ -485,6 +795,7 @@ func (e *Engine) AddExtension(extension Extension) {
 	// Register all filters from the extension
 	for name, filter := range extension.GetFilters() {
 		e.environment.filters[name] = filter
+		delete(e.environment.filtersV2, name)
 	}
 
 	// Register all functions from the extension
@@ -562,6 +873,7 @@ func (e *Engine) NewTemplate(name string, source string, nodes Node) *Template {
 		engine:       e,
 		lastModified: time.Now().Unix(),
 		loader:       nil,
+		parsedAt:     time.Now(),
 	}
 }
 
@@ -572,44 +884,81 @@ func (e *Engine) ParseTemplate(source string) (*Template, error) {
 		return e.Parse(source)
 	}
 
-	parser := &Parser{}
-	nodes, err := parser.Parse(source)
+	if err := e.checkTemplateSize("", len(source)); err != nil {
+		return nil, err
+	}
+
+	source = e.prepareSource(source)
+	parseStart := time.Now()
+	nodes, err := e.parseCached(source)
 	if err != nil {
 		return nil, err
 	}
 
 	template := &Template{
-		source:       source,
-		nodes:        nodes,
-		env:          e.environment,
-		engine:       e,
-		lastModified: time.Now().Unix(),
-		loader:       nil,
+		source:        source,
+		nodes:         nodes,
+		env:           e.environment,
+		engine:        e,
+		lastModified:  time.Now().Unix(),
+		loader:        nil,
+		parsedAt:      parseStart,
+		parseDuration: time.Since(parseStart),
 	}
 
 	return template, nil
 }
 
-// Render renders a template with the given context
-func (t *Template) Render(context map[string]interface{}) (string, error) {
+// Render renders a template with the given context and optional
+// per-render overrides of the engine settings
+func (t *Template) Render(context map[string]interface{}, opts ...RenderOptions) (string, error) {
 	// Get a string buffer from the pool
 	buf := NewStringBuffer()
 	defer buf.Release()
 
-	err := t.RenderTo(buf, context)
+	err := t.RenderTo(buf, context, opts...)
 	if err != nil {
 		return "", err
 	}
 	return buf.String(), nil
 }
 
-// RenderTo renders a template to a writer
-func (t *Template) RenderTo(w io.Writer, context map[string]interface{}) error {
+// RenderTo renders a template to a writer with optional per-render
+// overrides of the engine settings
+func (t *Template) RenderTo(w io.Writer, context map[string]interface{}, opts ...RenderOptions) error {
+	options := mergeRenderOptions(opts)
+	if t.engine != nil && t.engine.normalizeOutput {
+		nw := t.engine.newlineWriter(w)
+		if err := t.renderTo(nw, context, options); err != nil {
+			return err
+		}
+		return nw.Flush()
+	}
+	return t.renderTo(w, context, options)
+}
+
+// renderTo renders a template to a writer as is
+func (t *Template) renderTo(w io.Writer, context map[string]interface{}, options *RenderOptions) error {
+	if t.env != nil && t.env.hasDeprecations {
+		t.reportDeprecations()
+	}
+
+	// In debug mode, log how much output each include and block wrote
+	if t.env != nil && t.env.debug {
+		t.logTaintWarnings()
+		profile := &OutputProfile{}
+		err := t.renderProfiled(w, context, profile, options)
+		LogInfo("Output of template '%s' in bytes:\n%s", t.name, profile)
+		return err
+	}
+
 	// Get a render context from the pool
-	ctx := NewRenderContext(t.env, context, t.engine)
+	ctx := newRootContext(t.env, context, t.engine)
 
 	// Set the template as the lastLoadedTemplate for relative path resolution
 	ctx.lastLoadedTemplate = t
+	ctx.options = options
+	defer ctx.labelSection("template", t.name)()
 
 	// Debug logging only when enabled
 	LogDebug("Rendering template '%s'", t.name)
@@ -617,18 +966,8 @@ func (t *Template) RenderTo(w io.Writer, context map[string]interface{}) error {
 	// Ensure the context is returned to the pool
 	defer ctx.Release()
 
-	// Check if it's a RootNode that supports release
-	if rootNode, ok := t.nodes.(*RootNode); ok {
-		err := rootNode.Render(w, ctx)
-		// Don't release during rendering in case of extends nodes
-		// Only release when we're sure rendering is complete
-		if !ctx.extending {
-			defer rootNode.Release()
-		}
-		return err
-	}
-
-	// For other node types
+	// The node tree belongs to the template and is reused by later renders,
+	// so it must not be returned to the node pool here
 	return t.nodes.Render(w, ctx)
 }
 
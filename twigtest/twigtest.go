@@ -0,0 +1,104 @@
+// Package twigtest provides helpers for testing Twig templates.
+//
+// Golden files are rewritten with the current output when the
+// TWIG_UPDATE_GOLDEN environment variable is set to a non-empty value.
+package twigtest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/semihalev/twig"
+)
+
+// UpdateGoldenEnv is the environment variable that makes RenderGolden
+// write the rendered output to the golden file instead of comparing
+const UpdateGoldenEnv = "TWIG_UPDATE_GOLDEN"
+
+var (
+	whitespaceRun     = regexp.MustCompile(`\s+`)
+	whitespaceBetween = regexp.MustCompile(`>\s+<`)
+)
+
+// NormalizeHTML collapses whitespace runs into a single space, removes
+// whitespace between tags and trims the result, so markup can be compared
+// regardless of indentation and line breaks
+func NormalizeHTML(s string) string {
+	s = whitespaceRun.ReplaceAllString(s, " ")
+	s = whitespaceBetween.ReplaceAllString(s, "><")
+	return strings.TrimSpace(s)
+}
+
+// EqualHTML reports whether two HTML strings are equal after normalization
+func EqualHTML(a, b string) bool {
+	return NormalizeHTML(a) == NormalizeHTML(b)
+}
+
+// Render renders source with a fresh engine and fails the test on error
+func Render(t testing.TB, source string, context map[string]interface{}) string {
+	t.Helper()
+
+	engine := twig.New()
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+
+	result, err := engine.Render("test", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	return result
+}
+
+// AssertRenders checks that source renders exactly to expected
+func AssertRenders(t testing.TB, source string, context map[string]interface{}, expected string) {
+	t.Helper()
+
+	if result := Render(t, source, context); result != expected {
+		t.Errorf("Template %q\nexpected: %q\n     got: %q", source, expected, result)
+	}
+}
+
+// AssertRendersHTML checks that source renders to markup equal to expected,
+// ignoring differences in whitespace
+func AssertRendersHTML(t testing.TB, source string, context map[string]interface{}, expected string) {
+	t.Helper()
+
+	if result := Render(t, source, context); !EqualHTML(result, expected) {
+		t.Errorf("Template %q\nexpected: %q\n     got: %q", source, NormalizeHTML(expected), NormalizeHTML(result))
+	}
+}
+
+// RenderGolden renders the named template and compares the output with the
+// contents of goldenPath, ignoring differences in whitespace between tags
+func RenderGolden(t testing.TB, engine *twig.Engine, name string, context map[string]interface{}, goldenPath string) {
+	t.Helper()
+
+	result, err := engine.Render(name, context)
+	if err != nil {
+		t.Fatalf("Error rendering template %q: %v", name, err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("Error creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(result), 0o644); err != nil {
+			t.Fatalf("Error writing golden file: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("Error reading golden file (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+
+	if !EqualHTML(result, string(golden)) {
+		t.Errorf("Template %q does not match %s\nexpected: %q\n     got: %q",
+			name, goldenPath, NormalizeHTML(string(golden)), NormalizeHTML(result))
+	}
+}
@@ -0,0 +1,57 @@
+package twigtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semihalev/twig"
+)
+
+func TestNormalizeHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Whitespace between tags", "<ul>\n  <li>a</li>\n</ul>", "<ul><li>a</li></ul>"},
+		{"Collapse runs", "<p>hello   \n world</p>", "<p>hello world</p>"},
+		{"Trim", "  <br>  ", "<br>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := NormalizeHTML(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAssertRenders(t *testing.T) {
+	AssertRenders(t, "Hello {{ name }}!", map[string]interface{}{"name": "World"}, "Hello World!")
+	AssertRendersHTML(t, "<ul>{% for i in items %}\n  <li>{{ i }}</li>{% endfor %}\n</ul>",
+		map[string]interface{}{"items": []string{"a", "b"}}, "<ul><li>a</li><li>b</li></ul>")
+}
+
+func TestRenderGolden(t *testing.T) {
+	engine := twig.New()
+	engine.RegisterString("page", "<ul>{% for fruit in fruits %}<li>{{ fruit }}</li>{% endfor %}</ul>")
+	context := map[string]interface{}{"fruits": []string{"Apple", "Banana"}}
+
+	RenderGolden(t, engine, "page", context, filepath.Join("testdata", "page.golden"))
+
+	t.Run("Update", func(t *testing.T) {
+		golden := filepath.Join(t.TempDir(), "out", "page.golden")
+		t.Setenv(UpdateGoldenEnv, "1")
+		RenderGolden(t, engine, "page", context, golden)
+
+		data, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("Expected golden file to be written: %v", err)
+		}
+		if string(data) != "<ul><li>Apple</li><li>Banana</li></ul>" {
+			t.Errorf("Unexpected golden content %q", data)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package twig
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUndefinedFilterModes tests the configurable handling of unknown filters
+func TestUndefinedFilterModes(t *testing.T) {
+	source := "{{ name|php_only_filter|upper }}"
+	context := map[string]interface{}{"name": "twig"}
+
+	t.Run("error by default", func(t *testing.T) {
+		engine := New()
+		if err := engine.RegisterString("test", source); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+
+		_, err := engine.Render("test", context)
+		if err == nil || !strings.Contains(err.Error(), "php_only_filter") {
+			t.Fatalf("Expected unknown filter error, got %v", err)
+		}
+	})
+
+	t.Run("pass through", func(t *testing.T) {
+		engine := New()
+		engine.SetUndefinedFilterMode(UndefinedFilterPassThrough)
+		if err := engine.RegisterString("test", source); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+
+		result, err := engine.Render("test", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "TWIG" {
+			t.Errorf("Expected %q, got %q", "TWIG", result)
+		}
+	})
+
+	t.Run("resolver", func(t *testing.T) {
+		engine := New()
+		engine.SetUndefinedFilterResolver(func(name string) (FilterFunc, bool) {
+			if name != "php_only_filter" {
+				return nil, false
+			}
+			return func(value interface{}, args ...interface{}) (interface{}, error) {
+				return toString(value) + "!", nil
+			}, true
+		})
+		if err := engine.RegisterString("test", source); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+		if err := engine.RegisterString("other", "{{ name|missing }}"); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+
+		result, err := engine.Render("test", context)
+		if err != nil {
+			t.Fatalf("Error rendering template: %v", err)
+		}
+		if result != "TWIG!" {
+			t.Errorf("Expected %q, got %q", "TWIG!", result)
+		}
+
+		// Filters the resolver does not know about still fail
+		if _, err := engine.Render("other", context); err == nil {
+			t.Errorf("Expected error for filter unknown to the resolver")
+		}
+	})
+
+	t.Run("named arguments", func(t *testing.T) {
+		named := "{{ name|php_only_filter(1, suffix='?')|upper }}"
+
+		engine := New()
+		if err := engine.RegisterString("test", named); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+		if _, err := engine.Render("test", context); err == nil || !strings.Contains(err.Error(), "php_only_filter") {
+			t.Fatalf("Expected unknown filter error, got %v", err)
+		}
+
+		engine.SetUndefinedFilterMode(UndefinedFilterPassThrough)
+		if result, err := engine.Render("test", context); err != nil || result != "TWIG" {
+			t.Errorf("Expected %q, got %q (%v)", "TWIG", result, err)
+		}
+
+		engine.SetUndefinedFilterResolver(func(name string) (FilterFunc, bool) {
+			return func(value interface{}, args ...interface{}) (interface{}, error) {
+				return toString(value) + toString(args[0]), nil
+			}, true
+		})
+		if result, err := engine.Render("test", context); err != nil || result != "TWIG1" {
+			t.Errorf("Expected %q, got %q (%v)", "TWIG1", result, err)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package twig
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// hottestVisits is how many locations a VisitLimitError reports
+const hottestVisits = 5
+
+// SetMaxNodeVisits limits the statements and expressions a single render
+// may visit, counting includes, macro calls and loop iterations. A render
+// going over fails with a *VisitLimitError, which stops runaway recursion
+// or loops that no depth limit catches. Zero, the default, means no limit.
+func (e *Engine) SetMaxNodeVisits(limit int) {
+	if limit < 0 {
+		limit = 0
+	}
+	e.maxNodeVisits = limit
+}
+
+// NodeVisits is the number of visits to the nodes on one template line
+type NodeVisits struct {
+	Template string
+	Line     int
+	Visits   int
+}
+
+// String formats the location as "name line N (V visits)"
+func (v NodeVisits) String() string {
+	return fmt.Sprintf("%s (%d visits)", TemplateFrame{Template: v.Template, Line: v.Line}, v.Visits)
+}
+
+// VisitLimitError reports a render stopped by SetMaxNodeVisits, with the
+// template lines visited most, which usually point at the loop or the
+// recursion that ran away
+type VisitLimitError struct {
+	Limit   int
+	Hottest []NodeVisits // Most visited lines, most visits first
+}
+
+func (e *VisitLimitError) Error() string {
+	hottest := make([]string, len(e.Hottest))
+	for i, v := range e.Hottest {
+		hottest[i] = v.String()
+	}
+	return fmt.Sprintf("%v: more than %d nodes visited, most visited: %s",
+		ErrTooManyNodeVisits, e.Limit, strings.Join(hottest, ", "))
+}
+
+// Unwrap returns ErrTooManyNodeVisits
+func (e *VisitLimitError) Unwrap() error {
+	return ErrTooManyNodeVisits
+}
+
+// visitKey identifies a template line
+type visitKey struct {
+	template string
+	line     int
+}
+
+// visitCounter counts the node visits of one render, shared by all of its
+// contexts
+type visitCounter struct {
+	limit  int
+	total  int
+	counts map[visitKey]int
+}
+
+// newVisitCounter returns the counter for a render, nil without a limit
+func (e *Engine) newVisitCounter() *visitCounter {
+	if e == nil || e.maxNodeVisits <= 0 {
+		return nil
+	}
+	return &visitCounter{limit: e.maxNodeVisits, counts: make(map[visitKey]int)}
+}
+
+// visit counts a visit to node, failing once the render is over its limit
+func (ctx *RenderContext) visit(node Node) error {
+	v := ctx.visits
+	if v == nil {
+		return nil
+	}
+
+	v.counts[visitKey{template: ctx.templateName(), line: node.Line()}]++
+	v.total++
+	if v.total > v.limit {
+		return &VisitLimitError{Limit: v.limit, Hottest: v.hottest()}
+	}
+	return nil
+}
+
+// render renders a statement of a body, counting the visit. Under
+// RenderCollect the statement's error is recorded instead of returned.
+func (ctx *RenderContext) render(w io.Writer, node Node) error {
+	if err := ctx.visit(node); err != nil {
+		return err
+	}
+	err := node.Render(w, ctx)
+	if err != nil && ctx.options != nil && ctx.options.diagnostics != nil {
+		return ctx.options.diagnostics.recordError(w, ctx, node, err)
+	}
+	return err
+}
+
+// hottest returns the most visited lines
+func (v *visitCounter) hottest() []NodeVisits {
+	visits := make([]NodeVisits, 0, len(v.counts))
+	for key, count := range v.counts {
+		visits = append(visits, NodeVisits{Template: key.template, Line: key.line, Visits: count})
+	}
+	sort.Slice(visits, func(i, j int) bool {
+		if visits[i].Visits != visits[j].Visits {
+			return visits[i].Visits > visits[j].Visits
+		}
+		if visits[i].Template != visits[j].Template {
+			return visits[i].Template < visits[j].Template
+		}
+		return visits[i].Line < visits[j].Line
+	})
+	if len(visits) > hottestVisits {
+		visits = visits[:hottestVisits]
+	}
+	return visits
+}
@@ -0,0 +1,53 @@
+package twig
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMaxNodeVisits(t *testing.T) {
+	engine := New()
+	engine.SetMaxNodeVisits(1000)
+
+	templates := map[string]string{
+		"recursive.twig": "{% macro down(n) %}{{ n }}{{ _self.down(n + 1) }}{% endmacro %}\n{{ _self.down(0) }}",
+		"loop.twig":      "{% for i in range(1, 100000) %}\n{{ i }}{% endfor %}",
+		"small.twig":     "{% for i in range(1, 10) %}{{ i }}{% endfor %}",
+	}
+	for name, source := range templates {
+		if err := engine.RegisterString(name, source); err != nil {
+			t.Fatalf("Error parsing %s: %v", name, err)
+		}
+	}
+
+	_, err := engine.Render("loop.twig", nil)
+	var limitErr *VisitLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected a VisitLimitError, got %v", err)
+	}
+	if !errors.Is(err, ErrTooManyNodeVisits) {
+		t.Errorf("Expected the error to wrap ErrTooManyNodeVisits, got %v", err)
+	}
+	if len(limitErr.Hottest) == 0 || limitErr.Hottest[0].Template != "loop.twig" || limitErr.Hottest[0].Line != 2 {
+		t.Errorf("Expected line 2 of loop.twig to be the hottest, got %v", limitErr.Hottest)
+	}
+	if !strings.Contains(err.Error(), "loop.twig line 2") {
+		t.Errorf("Expected the message to name the hottest line, got %q", err.Error())
+	}
+
+	if _, err := engine.Render("recursive.twig", nil); !errors.Is(err, ErrTooManyNodeVisits) {
+		t.Errorf("Expected the recursive macro to hit the limit, got %v", err)
+	}
+
+	// Each render has its own budget
+	for i := 0; i < 3; i++ {
+		result, err := engine.Render("small.twig", nil)
+		if err != nil {
+			t.Fatalf("Error rendering small.twig: %v", err)
+		}
+		if result != "12345678910" {
+			t.Errorf("Expected %q, got %q", "12345678910", result)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package twig
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeVisitor is called for every node of a parse tree by Walk. Enter is
+// called before a node's children and can return false to skip them; Leave
+// is called after them.
+type NodeVisitor interface {
+	Enter(node Node) bool
+	Leave(node Node)
+}
+
+// Walk traverses the tree rooted at node depth first. Statements and
+// expressions are both visited; nodes from custom tags are visited but
+// their children are not.
+func Walk(node Node, visitor NodeVisitor) {
+	if node == nil {
+		return
+	}
+	if visitor.Enter(node) {
+		for _, child := range childNodes(node) {
+			Walk(child, visitor)
+		}
+	}
+	visitor.Leave(node)
+}
+
+// childNodes returns the direct children of a node in source order
+func childNodes(node Node) []Node {
+	var children []Node
+	add := func(nodes ...Node) {
+		for _, n := range nodes {
+			if n != nil {
+				children = append(children, n)
+			}
+		}
+	}
+
+	switch n := node.(type) {
+	case *RootNode:
+		add(n.children...)
+	case *PrintNode:
+		add(n.expression)
+	case *IfNode:
+		for i, condition := range n.conditions {
+			add(condition)
+			if i < len(n.bodies) {
+				add(n.bodies[i]...)
+			}
+		}
+		add(n.elseBranch...)
+	case *ForNode:
+		add(n.sequence)
+		add(n.body...)
+		add(n.elseBranch...)
+	case *BlockNode:
+		if n.cache != nil {
+			add(n.cache.ttl, n.cache.vary)
+		}
+		add(n.body...)
+	case *ExtendsNode:
+		add(n.parent)
+	case *IncludeNode:
+		add(n.template)
+		for _, name := range sortedNodeKeys(n.variables) {
+			add(n.variables[name])
+		}
+	case *EmbedNode:
+		add(n.template)
+		for _, name := range sortedNodeKeys(n.variables) {
+			add(n.variables[name])
+		}
+		for _, block := range n.blocks {
+			add(block)
+		}
+	case *WithNode:
+		for _, name := range sortedNodeKeys(n.variables) {
+			add(n.variables[name])
+		}
+		add(n.values)
+		add(n.body...)
+	case *SetNode:
+		add(n.value)
+	case *DoNode:
+		add(n.expression)
+	case *ReturnNode:
+		add(n.value)
+	case *MacroNode:
+		for _, name := range sortedNodeKeys(n.defaults) {
+			add(n.defaults[name])
+		}
+		add(n.body...)
+	case *ImportNode:
+		add(n.template)
+	case *FromImportNode:
+		add(n.template)
+	case *ApplyNode:
+		add(n.args...)
+		add(n.body...)
+	case *SpacelessNode:
+		add(n.body...)
+	case *UnaryNode:
+		add(n.node)
+	case *BinaryNode:
+		add(n.left, n.right)
+	case *FunctionNode:
+		add(n.moduleExpr)
+		add(n.args...)
+	case *FilterNode:
+		add(n.node)
+		add(n.args...)
+		for _, name := range sortedNodeKeys(n.named) {
+			add(n.named[name])
+		}
+	case *TestNode:
+		add(n.node)
+		add(n.args...)
+	case *GetAttrNode:
+		add(n.node, n.attribute)
+	case *GetItemNode:
+		add(n.node, n.item)
+	case *ArrayNode:
+		add(n.items...)
+	case *HashNode:
+		for _, key := range sortedHashKeys(n) {
+			add(key, n.items[key])
+		}
+	case *ConditionalNode:
+		add(n.condition, n.trueExpr, n.falseExpr)
+	case *ConcatNode:
+		add(n.parts...)
+	}
+
+	return children
+}
+
+// sortedNodeKeys returns the keys of a node map in a stable order
+func sortedNodeKeys(m map[string]Node) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedHashKeys returns the keys of a hash literal ordered by line and
+// literal value, since the parser stores them in a map
+func sortedHashKeys(n *HashNode) []Node {
+	keys := make([]Node, 0, len(n.items))
+	for key := range n.items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Line() != keys[j].Line() {
+			return keys[i].Line() < keys[j].Line()
+		}
+		return hashKeyLabel(keys[i]) < hashKeyLabel(keys[j])
+	})
+	return keys
+}
+
+// hashKeyLabel returns a sortable label for a hash key
+func hashKeyLabel(key Node) string {
+	switch k := key.(type) {
+	case *LiteralNode:
+		return fmt.Sprint(k.value)
+	case *VariableNode:
+		return k.name
+	}
+	return ""
+}
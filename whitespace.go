@@ -39,7 +39,7 @@ func (n *SpacelessNode) Render(w io.Writer, ctx *RenderContext) error {
 
 	// Render all body nodes
 	for _, node := range n.body {
-		err := node.Render(&buf, ctx)
+		err := ctx.render(&buf, node)
 		if err != nil {
 			return err
 		}
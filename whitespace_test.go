@@ -50,6 +50,24 @@ func TestWhitespaceControl(t *testing.T) {
 			context:  nil,
 			expected: "Line1contentLine2",
 		},
+		{
+			name:     "Whitespace control with comments",
+			source:   "Before {#- note -#} After",
+			context:  nil,
+			expected: "BeforeAfter",
+		},
+		{
+			name:     "Comment with left dash only",
+			source:   "Before\n  {#- note #}\nAfter",
+			context:  nil,
+			expected: "Before\nAfter",
+		},
+		{
+			name:     "Dashes inside a comment",
+			source:   "a {# -- #} b",
+			context:  nil,
+			expected: "a  b",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,92 @@
+package twig
+
+import "io"
+
+// WithNode represents {% with {...} only %} ... {% endwith %}. The body
+// renders in its own scope: the values given are added to it, only hides
+// the outer variables, and variables set inside do not leak out.
+type WithNode struct {
+	variables map[string]Node // Values of a {key: value} hash
+	values    Node            // Any other expression giving a hash, nil for none
+	only      bool
+	body      []Node
+	line      int
+}
+
+func (n *WithNode) Type() NodeType {
+	return NodeWith
+}
+
+func (n *WithNode) Line() int {
+	return n.line
+}
+
+// Render renders the body in a scoped context
+func (n *WithNode) Render(w io.Writer, ctx *RenderContext) error {
+	withCtx, err := ctx.scopedContext(includeScope{
+		variables: n.variables,
+		values:    n.values,
+		only:      n.only,
+		line:      n.line,
+	})
+	if err != nil {
+		return err
+	}
+	defer withCtx.Release()
+
+	for _, node := range n.body {
+		if err := withCtx.render(w, node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseWith parses {% with %}, {% with {a: 1} %}, {% with vars only %} and
+// the like
+func (p *Parser) parseWith(parser *Parser) (Node, error) {
+	withLine := parser.tokens[parser.tokenIndex-2].Line
+	node := &WithNode{line: withLine}
+
+	next := func() *Token {
+		if parser.tokenIndex < len(parser.tokens) {
+			return &parser.tokens[parser.tokenIndex]
+		}
+		return nil
+	}
+
+	if tok := next(); tok != nil && tok.Type == TOKEN_PUNCTUATION && tok.Value == "{" {
+		// A hash binds its keys like the with option of include and embed
+		node.variables = make(map[string]Node)
+		if err := parser.parseScopeVariables(node.variables, withLine); err != nil {
+			return nil, err
+		}
+	} else if tok != nil && tok.Type != TOKEN_BLOCK_END && tok.Type != TOKEN_BLOCK_END_TRIM &&
+		!(tok.Type == TOKEN_NAME && tok.Value == "only") {
+		values, err := parser.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		node.values = values
+	}
+
+	if tok := next(); tok != nil && tok.Type == TOKEN_NAME && tok.Value == "only" {
+		node.only = true
+		parser.tokenIndex++
+	}
+
+	if err := parser.expectBlockEnd("with", withLine); err != nil {
+		return nil, err
+	}
+
+	body, err := parser.parseOuterTemplate()
+	if err != nil {
+		return nil, err
+	}
+	node.body = body
+
+	if err := parser.expectEndTag("endwith", withLine); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
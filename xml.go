@@ -0,0 +1,57 @@
+package twig
+
+import "strings"
+
+// escapeXML escapes s for use in XML text and attribute values. Unlike HTML
+// escaping, apostrophes use the XML entity &apos;, and characters that are
+// not allowed in XML 1.0 documents (most control characters and invalid
+// UTF-8) are replaced with U+FFFD so the output is always well-formed.
+func escapeXML(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + len(s)/8)
+
+	for _, c := range s {
+		switch c {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\'':
+			b.WriteString("&apos;")
+		default:
+			if !isXMLChar(c) {
+				c = '\uFFFD'
+			}
+			b.WriteRune(c)
+		}
+	}
+
+	return b.String()
+}
+
+// isXMLChar reports whether r matches the Char production of XML 1.0
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+// escapeStrategy returns the escaper for a strategy named in e('name').
+// Strategies without their own escaper, such as js, css, url and html_attr,
+// fall back to HTML escaping.
+func escapeStrategy(args []interface{}) func(string) string {
+	if len(args) > 0 && args[0] != nil && toString(args[0]) == "xml" {
+		return escapeXML
+	}
+	return escapeHTML
+}
+
+// filterXMLEncode escapes a value for XML documents such as sitemaps and feeds
+func (e *CoreExtension) filterXMLEncode(value interface{}, args ...interface{}) (interface{}, error) {
+	return escapeXML(e.toString(value)), nil
+}
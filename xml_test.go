@@ -0,0 +1,78 @@
+package twig
+
+import "testing"
+
+func TestXMLEscaping(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		context  map[string]interface{}
+		expected string
+	}{
+		{
+			name:     "xml_encode escapes markup and apostrophes",
+			source:   `{{ title|xml_encode }}`,
+			context:  map[string]interface{}{"title": `Tom & Jerry's "<Show>"`},
+			expected: "Tom &amp; Jerry&apos;s &quot;&lt;Show&gt;&quot;",
+		},
+		{
+			name:     "escape with xml strategy",
+			source:   `{{ title|escape('xml') }}`,
+			context:  map[string]interface{}{"title": "it's"},
+			expected: "it&apos;s",
+		},
+		{
+			name:     "e with html strategy",
+			source:   `{{ title|e('html') }}`,
+			context:  map[string]interface{}{"title": "it's"},
+			expected: "it&#39;s",
+		},
+		{
+			name:     "invalid characters are replaced",
+			source:   `{{ title|xml_encode }}`,
+			context:  map[string]interface{}{"title": "a\x00b\x1bc\tline\n\xff"},
+			expected: "a�b�c\tline\n�",
+		},
+		{
+			name:     "sitemap entry",
+			source:   `<url><loc>{{ loc|e('xml') }}</loc></url>`,
+			context:  map[string]interface{}{"loc": "https://example.com/?a=1&b=2"},
+			expected: "<url><loc>https://example.com/?a=1&amp;b=2</loc></url>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := New()
+			if err := engine.RegisterString("test", tt.source); err != nil {
+				t.Fatalf("Error parsing template: %v", err)
+			}
+
+			result, err := engine.Render("test", tt.context)
+			if err != nil {
+				t.Fatalf("Error rendering template: %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestEscapeOtherStrategies(t *testing.T) {
+	engine := New()
+	for _, strategy := range []string{"js", "html_attr", "url", "css"} {
+		if err := engine.RegisterString("test", `{{ "<a'b>"|e('`+strategy+`') }}`); err != nil {
+			t.Fatalf("Error parsing template: %v", err)
+		}
+
+		result, err := engine.Render("test", nil)
+		if err != nil {
+			t.Fatalf("Error rendering %s strategy: %v", strategy, err)
+		}
+		if result != "&lt;a&#39;b&gt;" {
+			t.Errorf("Expected %s strategy to escape as HTML, got %q", strategy, result)
+		}
+	}
+}
@@ -0,0 +1,711 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unmarshal parses a YAML document into maps (map[string]interface{}),
+// lists ([]interface{}) and scalars (string, int, float64, bool and nil).
+func Unmarshal(text string) (interface{}, error) {
+	d, err := newDecoder(text)
+	if err != nil {
+		return nil, err
+	}
+
+	d.skipEmpty()
+	if d.eof() {
+		return nil, nil
+	}
+
+	value, err := d.node(d.lines[d.pos].indent)
+	if err != nil {
+		return nil, err
+	}
+
+	d.skipEmpty()
+	if !d.eof() {
+		return nil, d.errorf("unexpected content")
+	}
+	return value, nil
+}
+
+// sourceLine is a line of the document split into indentation and text
+type sourceLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// decoder parses block structure line by line
+type decoder struct {
+	lines []sourceLine
+	pos   int
+}
+
+// newDecoder splits a document into lines, dropping the document markers
+func newDecoder(text string) (*decoder, error) {
+	d := &decoder{}
+
+	raw := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	started := false
+	for i, line := range raw {
+		trimmed := strings.TrimRight(line, " \t")
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- #") {
+			if started {
+				return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+			}
+			started = true
+			continue
+		}
+		if trimmed == "..." {
+			break
+		}
+
+		text := strings.TrimLeft(line, " ")
+		if strings.HasPrefix(text, "\t") && strings.TrimSpace(text) != "" {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		if strings.TrimSpace(text) == "" {
+			text = ""
+		} else {
+			started = true
+		}
+		d.lines = append(d.lines, sourceLine{num: i + 1, indent: len(line) - len(text), text: strings.TrimRight(text, " \t")})
+	}
+
+	return d, nil
+}
+
+// eof reports whether all lines have been consumed
+func (d *decoder) eof() bool {
+	return d.pos >= len(d.lines)
+}
+
+// skipEmpty moves past blank and comment lines
+func (d *decoder) skipEmpty() {
+	for !d.eof() && (d.lines[d.pos].text == "" || strings.HasPrefix(d.lines[d.pos].text, "#")) {
+		d.pos++
+	}
+}
+
+// errorf reports an error at the current line
+func (d *decoder) errorf(format string, args ...interface{}) error {
+	num := 0
+	if !d.eof() {
+		num = d.lines[d.pos].num
+	} else if len(d.lines) > 0 {
+		num = d.lines[len(d.lines)-1].num
+	}
+	return fmt.Errorf("line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// node parses the collection or scalar starting at the current line
+func (d *decoder) node(indent int) (interface{}, error) {
+	text := stripComment(d.lines[d.pos].text)
+
+	if isSequenceEntry(text) {
+		return d.sequence(indent)
+	}
+	if _, _, ok, err := splitMappingEntry(text); err != nil {
+		return nil, d.errorf("%v", err)
+	} else if ok {
+		return d.mapping(indent)
+	}
+
+	d.pos++
+	return d.inlineValue(text, indent)
+}
+
+// sequence parses block sequence entries at indent
+func (d *decoder) sequence(indent int) (interface{}, error) {
+	result := []interface{}{}
+
+	for {
+		d.skipEmpty()
+		if d.eof() || d.lines[d.pos].indent < indent {
+			break
+		}
+
+		line := d.lines[d.pos]
+		text := stripComment(line.text)
+		if line.indent > indent {
+			return nil, d.errorf("unexpected indentation")
+		}
+		if !isSequenceEntry(text) {
+			break
+		}
+
+		rest := strings.TrimLeft(text[1:], " ")
+		offset := len(text) - len(rest)
+
+		// A nested collection starting on the dash line is parsed as if it
+		// started on its own line at the column after the dash
+		_, _, isMapping, _ := splitMappingEntry(rest)
+		if rest != "" && (isSequenceEntry(rest) || isMapping) {
+			d.lines[d.pos] = sourceLine{num: line.num, indent: indent + offset, text: line.text[offset:]}
+			value, err := d.node(indent + offset)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		d.pos++
+		value, err := d.inlineValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// mapping parses block mapping entries at indent
+func (d *decoder) mapping(indent int) (interface{}, error) {
+	result := map[string]interface{}{}
+
+	for {
+		d.skipEmpty()
+		if d.eof() || d.lines[d.pos].indent < indent {
+			break
+		}
+
+		line := d.lines[d.pos]
+		if line.indent > indent {
+			return nil, d.errorf("unexpected indentation")
+		}
+
+		text := stripComment(line.text)
+		key, rest, ok, err := splitMappingEntry(text)
+		if err != nil {
+			return nil, d.errorf("%v", err)
+		}
+		if !ok {
+			if isSequenceEntry(text) {
+				break
+			}
+			return nil, d.errorf("expected a mapping key")
+		}
+		if _, exists := result[key]; exists {
+			return nil, d.errorf("duplicate key %q", key)
+		}
+		d.pos++
+
+		// Sequences may start at the same indentation as their key
+		if rest == "" {
+			d.skipEmpty()
+			if !d.eof() && d.lines[d.pos].indent == indent && isSequenceEntry(stripComment(d.lines[d.pos].text)) {
+				value, err := d.sequence(indent)
+				if err != nil {
+					return nil, err
+				}
+				result[key] = value
+				continue
+			}
+		}
+
+		value, err := d.inlineValue(rest, indent)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// inlineValue parses the value following a key or dash on the line already
+// consumed, reading nested or block scalar lines when it is empty or | or >
+func (d *decoder) inlineValue(text string, parentIndent int) (interface{}, error) {
+	switch {
+	case text == "":
+		d.skipEmpty()
+		if !d.eof() && d.lines[d.pos].indent > parentIndent {
+			return d.node(d.lines[d.pos].indent)
+		}
+		return nil, nil
+	case text[0] == '|' || text[0] == '>':
+		return d.blockScalar(text, parentIndent)
+	case text[0] == '[' || text[0] == '{':
+		p := &flowParser{text: text}
+		value, err := p.parse()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", d.lines[d.pos-1].num, err)
+		}
+		return value, nil
+	}
+
+	value, err := parseScalar(text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", d.lines[d.pos-1].num, err)
+	}
+	return value, nil
+}
+
+// blockScalar reads a literal (|) or folded (>) block scalar
+func (d *decoder) blockScalar(header string, parentIndent int) (interface{}, error) {
+	style, chomp, explicit := header[0], byte(0), 0
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			explicit = int(c - '0')
+		default:
+			return nil, fmt.Errorf("line %d: invalid block scalar header %q", d.lines[d.pos-1].num, header)
+		}
+	}
+
+	contentIndent := -1
+	if explicit > 0 {
+		contentIndent = parentIndent + explicit
+	}
+
+	var lines []string
+	for !d.eof() {
+		line := d.lines[d.pos]
+		if line.text == "" {
+			lines = append(lines, "")
+			d.pos++
+			continue
+		}
+		if contentIndent < 0 {
+			if line.indent <= parentIndent {
+				break
+			}
+			contentIndent = line.indent
+		}
+		if line.indent < contentIndent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", line.indent-contentIndent)+line.text)
+		d.pos++
+	}
+
+	// Separate trailing blank lines, which belong to the chomping
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	trailing := len(lines) - end
+	lines = lines[:end]
+
+	var content string
+	if style == '|' {
+		content = strings.Join(lines, "\n")
+	} else {
+		content = foldLines(lines)
+	}
+
+	switch {
+	case content == "" && chomp != '+':
+		return "", nil
+	case chomp == '-':
+		return content, nil
+	case chomp == '+':
+		return content + "\n" + strings.Repeat("\n", trailing), nil
+	}
+	return content + "\n", nil
+}
+
+// foldLines joins the lines of a folded block scalar, keeping line breaks
+// around blank and more indented lines
+func foldLines(lines []string) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			prev := lines[i-1]
+			switch {
+			case line == "":
+				b.WriteString("\n")
+				continue
+			case prev == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(prev, " "):
+				if prev != "" {
+					b.WriteString("\n")
+				}
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// isSequenceEntry reports whether text starts a block sequence entry
+func isSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitMappingEntry splits "key: value" into its key and value text
+func splitMappingEntry(text string) (key, rest string, ok bool, err error) {
+	if text == "" || text[0] == '[' || text[0] == '{' || isSequenceEntry(text) {
+		return "", "", false, nil
+	}
+
+	if text[0] == '"' || text[0] == '\'' {
+		end := quotedEnd(text)
+		if end < 0 {
+			return "", "", false, nil
+		}
+		after := strings.TrimLeft(text[end:], " ")
+		if after != ":" && !strings.HasPrefix(after, ": ") {
+			return "", "", false, nil
+		}
+		value, err := parseScalar(text[:end])
+		if err != nil {
+			return "", "", false, err
+		}
+		return fmt.Sprint(value), strings.TrimSpace(after[1:]), true, nil
+	}
+
+	idx := strings.Index(text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(text, ":") {
+			return "", "", false, nil
+		}
+		idx = len(text) - 1
+	}
+	return strings.TrimSpace(text[:idx]), strings.TrimSpace(text[idx+1:]), true, nil
+}
+
+// quotedEnd returns the index after the quoted scalar starting text, or -1
+func quotedEnd(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// stripComment removes a trailing comment, ignoring # inside quoted scalars
+func stripComment(text string) string {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", text[i-1]) >= 0) {
+			end := quotedEnd(text[i:])
+			if end < 0 {
+				break
+			}
+			i += end - 1
+			continue
+		}
+		if c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t') {
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+	return text
+}
+
+// parseScalar resolves a plain or quoted scalar to its value
+func parseScalar(text string) (interface{}, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	switch text[0] {
+	case '"':
+		if quotedEnd(text) != len(text) {
+			return nil, fmt.Errorf("invalid double-quoted string %s", text)
+		}
+		return unescapeDouble(text[1 : len(text)-1])
+	case '\'':
+		if quotedEnd(text) != len(text) {
+			return nil, fmt.Errorf("invalid single-quoted string %s", text)
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %s", text)
+	}
+
+	switch text {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1), nil
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1), nil
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), nil
+	}
+
+	if isNumeric(text) {
+		unsigned := strings.TrimLeft(text, "+-")
+		switch {
+		case strings.HasPrefix(unsigned, "0x"):
+			if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+				return int(n), nil
+			}
+		case strings.HasPrefix(unsigned, "0o"):
+			if n, err := strconv.ParseInt(strings.Replace(text, "0o", "", 1), 8, 64); err == nil {
+				return int(n), nil
+			}
+		case strings.Trim(unsigned, "0123456789") == "":
+			// Leading zeros are decimal in YAML 1.2, so 0755 is 755
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return int(n), nil
+			}
+		}
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+	}
+
+	return text, nil
+}
+
+// isNumeric reports whether text looks like a YAML number
+func isNumeric(text string) bool {
+	s := strings.TrimLeft(text, "+-")
+	if s == "" {
+		return false
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o") {
+		return len(s) > 2
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && c != '.' && c != 'e' && c != 'E' && c != '+' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeDouble resolves the escapes of a double-quoted scalar
+func unescapeDouble(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+
+		switch c := s[i]; c {
+		case '0':
+			b.WriteByte(0)
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 't', '\t':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'v':
+			b.WriteByte('\v')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case 'e':
+			b.WriteByte(0x1b)
+		case ' ', '"', '/', '\\':
+			b.WriteByte(c)
+		case 'N':
+			b.WriteString("\u0085")
+		case '_':
+			b.WriteString(" ")
+		case 'L':
+			b.WriteString("\u2028")
+		case 'P':
+			b.WriteString("\u2029")
+		case 'x', 'u', 'U':
+			size := 2
+			if c == 'u' {
+				size = 4
+			} else if c == 'U' {
+				size = 8
+			}
+			if i+1+size > len(s) {
+				return "", fmt.Errorf("invalid escape \\%c", c)
+			}
+			n, err := strconv.ParseUint(s[i+1:i+1+size], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\%c%s", c, s[i+1:i+1+size])
+			}
+			if c == 'x' {
+				b.WriteByte(byte(n))
+			} else {
+				if !utf8.ValidRune(rune(n)) {
+					return "", fmt.Errorf("invalid code point \\%c%s", c, s[i+1:i+1+size])
+				}
+				b.WriteRune(rune(n))
+			}
+			i += size
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+// flowParser parses single-line flow collections such as [a, b] and {a: 1}
+type flowParser struct {
+	text string
+	pos  int
+}
+
+// parse reads one flow value and rejects trailing content
+func (p *flowParser) parse() (interface{}, error) {
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.text) {
+		return nil, fmt.Errorf("unexpected %q after flow collection", p.text[p.pos:])
+	}
+	return value, nil
+}
+
+// skipSpaces moves past spaces
+func (p *flowParser) skipSpaces() {
+	for p.pos < len(p.text) && p.text[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// value reads a flow collection or scalar
+func (p *flowParser) value() (interface{}, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.text) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+
+	switch p.text[p.pos] {
+	case '[':
+		return p.sequence()
+	case '{':
+		return p.mapping()
+	}
+
+	text, err := p.scalarText(",]}")
+	if err != nil {
+		return nil, err
+	}
+	return parseScalar(text)
+}
+
+// scalarText reads a quoted scalar or a plain scalar ending at a stop byte
+func (p *flowParser) scalarText(stops string) (string, error) {
+	start := p.pos
+	if c := p.text[p.pos]; c == '"' || c == '\'' {
+		end := quotedEnd(p.text[p.pos:])
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted string")
+		}
+		p.pos += end
+		return p.text[start:p.pos], nil
+	}
+
+	for p.pos < len(p.text) && strings.IndexByte(stops, p.text[p.pos]) < 0 {
+		if p.text[p.pos] == ':' && strings.IndexByte(stops, ':') < 0 && p.pos+1 < len(p.text) && p.text[p.pos+1] == ' ' {
+			return "", fmt.Errorf("unexpected ': ' in flow sequence")
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.text[start:p.pos]), nil
+}
+
+// sequence reads [a, b, ...]
+func (p *flowParser) sequence() (interface{}, error) {
+	p.pos++ // [
+	result := []interface{}{}
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.text) {
+			return nil, fmt.Errorf("unterminated flow sequence")
+		}
+		if p.text[p.pos] == ']' {
+			p.pos++
+			return result, nil
+		}
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+
+		p.skipSpaces()
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+		} else if p.pos < len(p.text) && p.text[p.pos] != ']' {
+			return nil, fmt.Errorf("expected , or ] in flow sequence")
+		}
+	}
+}
+
+// mapping reads {a: 1, ...}
+func (p *flowParser) mapping() (interface{}, error) {
+	p.pos++ // {
+	result := map[string]interface{}{}
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.text) {
+			return nil, fmt.Errorf("unterminated flow mapping")
+		}
+		if p.text[p.pos] == '}' {
+			p.pos++
+			return result, nil
+		}
+
+		keyText, err := p.scalarText(":,}")
+		if err != nil {
+			return nil, err
+		}
+		key, err := parseScalar(keyText)
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpaces()
+		var value interface{}
+		if p.pos < len(p.text) && p.text[p.pos] == ':' {
+			p.pos++
+			p.skipSpaces()
+			if p.pos < len(p.text) && p.text[p.pos] != ',' && p.text[p.pos] != '}' {
+				if value, err = p.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		result[fmt.Sprint(key)] = value
+
+		p.skipSpaces()
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+		} else if p.pos < len(p.text) && p.text[p.pos] != '}' {
+			return nil, fmt.Errorf("expected , or } in flow mapping")
+		}
+	}
+}
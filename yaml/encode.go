@@ -0,0 +1,318 @@
+package yaml
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Marshal encodes a value as block-style YAML using indent spaces per level.
+// Map keys are sorted so output is stable; structs are encoded by their
+// exported fields, named by a yaml tag when present.
+func Marshal(value interface{}, indent int) (string, error) {
+	if indent < 2 {
+		return "", fmt.Errorf("indent must be at least 2, got %d", indent)
+	}
+
+	e := &encoder{indent: indent}
+	v := deref(reflect.ValueOf(value))
+	if isBlock(v) {
+		if err := e.block(v, 0, ""); err != nil {
+			return "", err
+		}
+	} else {
+		s, err := e.scalar(v)
+		if err != nil {
+			return "", err
+		}
+		e.b.WriteString(s + "\n")
+	}
+
+	return e.b.String(), nil
+}
+
+// encoder writes YAML into a buffer
+type encoder struct {
+	b      strings.Builder
+	indent int
+}
+
+// deref unwraps interfaces and pointers
+func deref(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isBlock reports whether v is written as a block collection rather than
+// on a single line. Empty collections are written inline as {} and [].
+func isBlock(v reflect.Value) bool {
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() > 0 && !isBytes(v)
+	case reflect.Struct:
+		return v.Type() != reflect.TypeOf(time.Time{}) && len(structFields(v)) > 0
+	}
+	return false
+}
+
+// isBytes reports whether v is a byte slice, which is encoded as a string
+func isBytes(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8
+}
+
+// field is a named value of a mapping
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// fields returns the entries of a map or struct in output order
+func fields(v reflect.Value) []field {
+	if v.Kind() == reflect.Struct {
+		return structFields(v)
+	}
+
+	entries := make([]field, 0, v.Len())
+	for _, key := range v.MapKeys() {
+		entries = append(entries, field{key: fmt.Sprint(key.Interface()), value: v.MapIndex(key)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries
+}
+
+// structFields returns the exported fields of a struct, honoring yaml tags
+// with the omitempty option and "-" to skip a field
+func structFields(v reflect.Value) []field {
+	var entries []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := sf.Name
+		tag := sf.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		tagName, options, _ := strings.Cut(tag, ",")
+		if tagName != "" {
+			name = tagName
+		}
+
+		value := v.Field(i)
+		if options == "omitempty" && value.IsZero() {
+			continue
+		}
+		entries = append(entries, field{key: name, value: value})
+	}
+	return entries
+}
+
+// block writes a non-empty collection at the given nesting level. The first
+// line starts with prefix instead of indentation when the collection follows
+// a sequence dash.
+func (e *encoder) block(v reflect.Value, level int, prefix string) error {
+	pad := strings.Repeat(" ", level*e.indent)
+	first := true
+	linePad := func() string {
+		if first && prefix != "" {
+			first = false
+			return prefix
+		}
+		first = false
+		return pad
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			item := deref(v.Index(i))
+			start := linePad() + "-"
+			if isBlock(item) {
+				dash := start + strings.Repeat(" ", e.indent-1)
+				if err := e.block(item, level+1, dash); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.value(start, item, level+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, f := range fields(v) {
+		item := deref(f.value)
+		start := linePad() + quoteString(f.key) + ":"
+		if isBlock(item) {
+			e.b.WriteString(start + "\n")
+			if err := e.block(item, level+1, ""); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.value(start, item, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value writes a scalar after start, using a literal block for multi-line
+// strings
+func (e *encoder) value(start string, v reflect.Value, level int) error {
+	if v.IsValid() && v.Kind() == reflect.String {
+		if lines, chomp, ok := literalLines(v.String()); ok {
+			pad := strings.Repeat(" ", level*e.indent)
+			e.b.WriteString(start + " |" + chomp + "\n")
+			for _, line := range lines {
+				if line == "" {
+					e.b.WriteString("\n")
+				} else {
+					e.b.WriteString(pad + line + "\n")
+				}
+			}
+			return nil
+		}
+	}
+
+	s, err := e.scalar(v)
+	if err != nil {
+		return err
+	}
+	e.b.WriteString(start + " " + s + "\n")
+	return nil
+}
+
+// scalar formats a single-line value
+func (e *encoder) scalar(v reflect.Value) (string, error) {
+	if !v.IsValid() {
+		return "null", nil
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(v.Float()), nil
+	case reflect.String:
+		return quoteString(v.String()), nil
+	case reflect.Map, reflect.Struct:
+		return "{}", nil
+	case reflect.Slice, reflect.Array:
+		if isBytes(v) {
+			return quoteString(string(v.Bytes())), nil
+		}
+		return "[]", nil
+	}
+
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return quoteString(s.String()), nil
+	}
+	return "", fmt.Errorf("cannot encode %s as YAML", v.Type())
+}
+
+// formatFloat formats floats using the YAML spellings of special values
+func formatFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// literalLines splits a multi-line string for a literal block scalar,
+// returning the chomping indicator that preserves its trailing newline
+func literalLines(s string) ([]string, string, bool) {
+	if !strings.Contains(s, "\n") || strings.HasSuffix(s, "\n\n") {
+		return nil, "", false
+	}
+	// Leading spaces would need an indentation indicator
+	if strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\n") {
+		return nil, "", false
+	}
+	for _, r := range s {
+		if r != '\n' && r != '\t' && !unicode.IsPrint(r) {
+			return nil, "", false
+		}
+	}
+
+	chomp := "-"
+	if strings.HasSuffix(s, "\n") {
+		chomp = ""
+		s = s[:len(s)-1]
+	}
+	return strings.Split(s, "\n"), chomp, true
+}
+
+// quoteString returns s as a plain scalar when it cannot be mistaken for
+// another type or structure, and double-quoted otherwise
+func quoteString(s string) string {
+	if isPlainSafe(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// isPlainSafe reports whether s can be written without quotes
+func isPlainSafe(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return false
+	}
+	if strings.ContainsRune(",[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return false
+	}
+	// Dashes, question marks and colons are indicators only before a space
+	if strings.ContainsRune("-?:", rune(s[0])) && (len(s) == 1 || s[1] == ' ') {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+
+	// Strings that would be read back as another type
+	switch strings.ToLower(s) {
+	case "null", "~", "true", "false", "yes", "no", "on", "off", "y", "n",
+		".inf", "-.inf", "+.inf", ".nan":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+	if _, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return false
+	}
+	return true
+}
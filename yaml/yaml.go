@@ -0,0 +1,75 @@
+// Package yaml adds yaml_encode and yaml_parse filters to Twig templates, for
+// generating configuration files and Kubernetes manifests.
+//
+// The package has no dependencies outside the standard library. The encoder
+// writes block-style YAML; the parser reads the block and flow subset used by
+// configuration files (mappings, sequences, scalars, block scalars and
+// comments) and rejects anchors, aliases and tags.
+package yaml
+
+import (
+	"fmt"
+
+	"github.com/semihalev/twig"
+)
+
+// DefaultIndent is the number of spaces per nesting level used by yaml_encode
+const DefaultIndent = 2
+
+// Register adds the yaml_encode and yaml_parse filters to an engine
+func Register(engine *twig.Engine) {
+	engine.AddFilter("yaml_encode", filterEncode)
+	engine.AddFilter("yaml_parse", filterParse)
+}
+
+// filterEncode implements value|yaml_encode(indent)
+func filterEncode(value interface{}, args ...interface{}) (interface{}, error) {
+	indent := DefaultIndent
+	if len(args) > 0 && args[0] != nil {
+		n, ok := toInt(args[0])
+		if !ok || n < 2 || n > 9 {
+			return nil, fmt.Errorf("yaml_encode: indent must be between 2 and 9, got %v", args[0])
+		}
+		indent = n
+	}
+
+	return Marshal(value, indent)
+}
+
+// filterParse implements text|yaml_parse
+func filterParse(value interface{}, args ...interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	result, err := Unmarshal(text)
+	if err != nil {
+		return nil, fmt.Errorf("yaml_parse: %w", err)
+	}
+	return result, nil
+}
+
+// toInt converts numeric filter arguments
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		if n == float64(int(n)) {
+			return int(n), true
+		}
+	}
+	return 0, false
+}
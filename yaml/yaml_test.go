@@ -0,0 +1,293 @@
+package yaml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/semihalev/twig"
+)
+
+func newEngine(t *testing.T) *twig.Engine {
+	t.Helper()
+
+	engine := twig.New()
+	Register(engine)
+	return engine
+}
+
+func render(t *testing.T, engine *twig.Engine, source string, context map[string]interface{}) string {
+	t.Helper()
+
+	if err := engine.RegisterString("test", source); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+	result, err := engine.Render("test", context)
+	if err != nil {
+		t.Fatalf("Error rendering template: %v", err)
+	}
+	return result
+}
+
+func TestMarshal(t *testing.T) {
+	type container struct {
+		Name  string   `yaml:"name"`
+		Image string   `yaml:"image"`
+		Args  []string `yaml:"args,omitempty"`
+		Debug bool     `yaml:"-"`
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		indent   int
+		expected string
+	}{
+		{
+			name:     "scalar",
+			value:    "hello",
+			indent:   2,
+			expected: "hello\n",
+		},
+		{
+			name: "nested mapping with sorted keys",
+			value: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "web", "labels": map[string]string{"app": "web"}},
+			},
+			indent:   2,
+			expected: "kind: Deployment\nmetadata:\n  labels:\n    app: web\n  name: web\n",
+		},
+		{
+			name: "sequence of mappings",
+			value: map[string]interface{}{
+				"containers": []interface{}{
+					container{Name: "web", Image: "nginx:1.25", Debug: true},
+					container{Name: "sidecar", Image: "envoy", Args: []string{"--v", "2"}},
+				},
+			},
+			indent: 2,
+			expected: "containers:\n" +
+				"  - name: web\n" +
+				"    image: nginx:1.25\n" +
+				"  - name: sidecar\n" +
+				"    image: envoy\n" +
+				"    args:\n" +
+				"      - --v\n" +
+				"      - \"2\"\n",
+		},
+		{
+			name:     "indent of four",
+			value:    map[string]interface{}{"a": []int{1, 2}, "b": map[string]int{"c": 3}},
+			indent:   4,
+			expected: "a:\n    - 1\n    - 2\nb:\n    c: 3\n",
+		},
+		{
+			name: "strings that need quotes",
+			value: map[string]interface{}{
+				"bool":    "true",
+				"comment": "a #b",
+				"colon":   "a: b",
+				"empty":   "",
+				"number":  "8080",
+				"space":   " padded",
+				"quote":   `say "hi"`,
+			},
+			indent: 2,
+			expected: "bool: \"true\"\ncolon: \"a: b\"\ncomment: \"a #b\"\nempty: \"\"\n" +
+				"number: \"8080\"\nquote: say \"hi\"\nspace: \" padded\"\n",
+		},
+		{
+			name:     "multi-line strings use literal blocks",
+			value:    map[string]interface{}{"script": "echo a\necho b\n", "note": "one\ntwo"},
+			indent:   2,
+			expected: "note: |-\n  one\n  two\nscript: |\n  echo a\n  echo b\n",
+		},
+		{
+			name:     "empty collections and null",
+			value:    map[string]interface{}{"list": []string{}, "map": map[string]int{}, "none": nil},
+			indent:   2,
+			expected: "list: []\nmap: {}\nnone: null\n",
+		},
+		{
+			name:     "nested sequences",
+			value:    []interface{}{[]interface{}{1, 2}, 3.5},
+			indent:   2,
+			expected: "- - 1\n  - 2\n- 3.5\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Marshal(tt.value, tt.indent)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected interface{}
+	}{
+		{
+			name:     "empty document",
+			source:   "# only a comment\n",
+			expected: nil,
+		},
+		{
+			name: "mapping with scalars",
+			source: "---\nname: web # trailing comment\nreplicas: 3\nratio: 0.5\nenabled: true\n" +
+				"mode: \"0755\"\nport: '8080'\nnothing: ~\nurl: http://example.com/#top\n",
+			expected: map[string]interface{}{
+				"name": "web", "replicas": 3, "ratio": 0.5, "enabled": true,
+				"mode": "0755", "port": "8080", "nothing": nil, "url": "http://example.com/#top",
+			},
+		},
+		{
+			name:   "nested collections",
+			source: "spec:\n  containers:\n  - name: web\n    ports:\n      - 80\n      - 443\n  - name: sidecar\n",
+			expected: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "web", "ports": []interface{}{80, 443}},
+						map[string]interface{}{"name": "sidecar"},
+					},
+				},
+			},
+		},
+		{
+			name:   "flow collections",
+			source: "args: [--port, \"8080\", 1]\nlabels: {app: web, tier: 'front'}\nempty: []\n",
+			expected: map[string]interface{}{
+				"args":   []interface{}{"--port", "8080", 1},
+				"labels": map[string]interface{}{"app": "web", "tier": "front"},
+				"empty":  []interface{}{},
+			},
+		},
+		{
+			name:   "block scalars",
+			source: "literal: |\n  line one\n    indented\n\n  # not a comment\nfolded: >-\n  folded\n  text\n\n  new paragraph\nend: 1\n",
+			expected: map[string]interface{}{
+				"literal": "line one\n  indented\n\n# not a comment\n",
+				"folded":  "folded text\nnew paragraph",
+				"end":     1,
+			},
+		},
+		{
+			name:     "escapes in double quotes",
+			source:   `text: "tab\there\nnew \u00e9 \"q\""`,
+			expected: map[string]interface{}{"text": "tab\there\nnew é \"q\""},
+		},
+		{
+			name:     "nested sequences",
+			source:   "- - 1\n  - 2\n- 3\n",
+			expected: []interface{}{[]interface{}{1, 2}, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Unmarshal(tt.source)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{"bad indentation", "a: 1\n   b: 2\n", "line 2"},
+		{"duplicate key", "a: 1\na: 2\n", "duplicate key"},
+		{"alias", "a: &x 1\nb: *x\n", "not supported"},
+		{"multiple documents", "a: 1\n---\nb: 2\n", "multiple documents"},
+		{"unterminated flow", "a: [1, 2\n", "unterminated"},
+		{"tab indentation", "a:\n\tb: 1\n", "tabs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(tt.source)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			if !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	value := map[string]interface{}{
+		"apiVersion": "v1",
+		"data": map[string]interface{}{
+			"config.json": "{\"debug\": true}\n",
+			"empty":       "",
+			"port":        "8080",
+		},
+		"items":  []interface{}{1, 2.5, false, nil, "- dash", map[string]interface{}{"a": []interface{}{"x"}}},
+		"nested": []interface{}{[]interface{}{"a", "b"}, []interface{}{}},
+	}
+
+	for _, indent := range []int{2, 4} {
+		encoded, err := Marshal(value, indent)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		decoded, err := Unmarshal(encoded)
+		if err != nil {
+			t.Fatalf("Error parsing encoded document: %v\n%s", err, encoded)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("Round trip with indent %d changed the value:\n%s\n%#v", indent, encoded, decoded)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	engine := newEngine(t)
+
+	config := map[string]interface{}{
+		"name":  "web",
+		"ports": []int{80, 443},
+	}
+
+	result := render(t, engine, `{{ config|yaml_encode }}`, map[string]interface{}{"config": config})
+	if expected := "name: web\nports:\n  - 80\n  - 443\n"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	result = render(t, engine, `{{ config|yaml_encode(4) }}`, map[string]interface{}{"config": config})
+	if expected := "name: web\nports:\n    - 80\n    - 443\n"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	source := `{% set doc = text|yaml_parse %}{{ doc.name }}:{% for port in doc.ports %} {{ port }}{% endfor %}`
+	result = render(t, engine, source, map[string]interface{}{"text": "name: web\nports: [80, 443]\n"})
+	if expected := "web: 80 443"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	if err := engine.RegisterString("bad", `{{ config|yaml_encode(1) }}`); err != nil {
+		t.Fatalf("Error registering template: %v", err)
+	}
+	if _, err := engine.Render("bad", map[string]interface{}{"config": config}); err == nil {
+		t.Error("Expected an error for an invalid indent")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"unicode"
 	"unsafe"
 )
 
@@ -78,12 +79,15 @@ type TagLocation struct {
 // ZeroAllocTokenizer is an allocation-free tokenizer
 // It uses a pre-allocated token buffer for all token operations
 type ZeroAllocTokenizer struct {
-	tokenBuffer []Token  // Pre-allocated buffer of tokens
-	source      string   // Source string being tokenized
-	position    int      // Current position in source
-	line        int      // Current line
-	result      []Token  // Slice of actually used tokens
-	tempStrings []string // String constants that we can reuse
+	tokenBuffer []Token         // Pre-allocated buffer of tokens
+	source      string          // Source string being tokenized
+	position    int             // Current position in source
+	line        int             // Current line
+	result      []Token         // Slice of actually used tokens
+	textStart   int             // Start of the text not yet added as a token
+	lastType    int             // Type of the last token of the previous window
+	tempStrings []string        // String constants that we can reuse
+	interner    *stringInterner // Engine-wide identifier table, if any
 }
 
 // This array contains commonly used strings in tokenization to avoid allocations
@@ -137,6 +141,7 @@ func GetTokenizer(source string, capacityHint int) *ZeroAllocTokenizer {
 	tokenizer.source = source
 	tokenizer.position = 0
 	tokenizer.line = 1
+	tokenizer.interner = nil
 
 	// Ensure token buffer has enough capacity
 	neededCapacity := capacityHint
@@ -176,6 +181,7 @@ func ReleaseTokenizer(tokenizer *ZeroAllocTokenizer) {
 		pooled.used = false
 		tokenizer.source = ""
 		tokenizer.result = nil
+		tokenizer.interner = nil
 
 		// Return to pool
 		tokenizerPool.Put(pooled)
@@ -197,6 +203,11 @@ func (t *ZeroAllocTokenizer) AddToken(tokenType int, value string, line int) {
 // GetStringConstant checks if a string exists in our constants and returns
 // the canonical version to avoid allocation
 func (t *ZeroAllocTokenizer) GetStringConstant(s string) string {
+	// Prefer the engine's table, which is shared by all its templates
+	if t.interner != nil {
+		return t.interner.intern(s)
+	}
+
 	// First check common strings
 	for _, constant := range t.tempStrings {
 		if constant == s {
@@ -270,7 +281,8 @@ func (t *ZeroAllocTokenizer) TokenizeExpression(expr string) []Token {
 					(c == '<' && nextChar == '=') ||
 					(c == '&' && nextChar == '&') ||
 					(c == '|' && nextChar == '|') ||
-					(c == '?' && nextChar == '?') {
+					(c == '?' && nextChar == '?') ||
+					(c == '*' && nextChar == '*') {
 
 					op = twoCharOp
 					t.position++
@@ -335,26 +347,7 @@ func (t *ZeroAllocTokenizer) TokenizeExpression(expr string) []Token {
 		// Handle numbers
 		if (c >= '0' && c <= '9') || (c == '-' && t.position+1 < len(t.source) && t.source[t.position+1] >= '0' && t.source[t.position+1] <= '9') {
 			start := t.position
-
-			// Skip the negative sign if present
-			if c == '-' {
-				t.position++
-			}
-
-			// Consume digits
-			for t.position < len(t.source) && t.source[t.position] >= '0' && t.source[t.position] <= '9' {
-				t.position++
-			}
-
-			// Handle decimal point
-			if t.position < len(t.source) && t.source[t.position] == '.' {
-				t.position++
-
-				// Consume fractional digits
-				for t.position < len(t.source) && t.source[t.position] >= '0' && t.source[t.position] <= '9' {
-					t.position++
-				}
-			}
+			t.position = scanNumber(t.source, start)
 
 			// Add the number token
 			t.AddToken(TOKEN_NUMBER, t.source[start:t.position], t.line)
@@ -376,454 +369,233 @@ func (t *ZeroAllocTokenizer) TokenizeExpression(expr string) []Token {
 	return tokens
 }
 
-// TokenizeHtmlPreserving performs full tokenization of a template with HTML preservation
+// TokenizeHtmlPreserving performs full tokenization of a template with HTML preservation.
+// The source is scanned once: text is skipped to the next '{' and each tag
+// is searched for its end from where it opens, so pathological inputs stay
+// linear. Tokens inside a tag carry the line they appear on.
 func (t *ZeroAllocTokenizer) TokenizeHtmlPreserving() ([]Token, error) {
 	// Reset position and line
 	t.position = 0
 	t.line = 1
+	t.textStart = 0
 
 	// Clear token buffer
 	t.tokenBuffer = t.tokenBuffer[:0]
 
-	tagPatterns := [5]string{"{{-", "{{", "{%-", "{%", "{#"}
-	tagTypes := [5]int{TOKEN_VAR_START_TRIM, TOKEN_VAR_START, TOKEN_BLOCK_START_TRIM, TOKEN_BLOCK_START, TOKEN_COMMENT_START}
-	tagLengths := [5]int{3, 2, 3, 2, 2}
-
-	for t.position < len(t.source) {
-		// Find the next tag
-		nextTagPos := -1
-		tagType := -1
-		tagLength := 0
-
-		// Check for all possible tag patterns
-		// This loop avoids allocations by manually checking prefixes
-		remainingSource := t.source[t.position:]
-		for i := 0; i < 5; i++ {
-			pattern := tagPatterns[i]
-			if len(remainingSource) >= len(pattern) &&
-				remainingSource[:len(pattern)] == pattern {
-				// Tag found at current position
-				nextTagPos = t.position
-				tagType = tagTypes[i]
-				tagLength = tagLengths[i]
-				break
-			}
-
-			// If not found at current position, find it in the remainder
-			patternPos := strings.Index(remainingSource, pattern)
-			if patternPos != -1 {
-				pos := t.position + patternPos
-				if nextTagPos == -1 || pos < nextTagPos {
-					nextTagPos = pos
-					tagType = tagTypes[i]
-					tagLength = tagLengths[i]
-				}
-			}
+	for {
+		more, err := t.tokenizeTag()
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
 		}
+	}
 
-		// Check if the tag is escaped
-		if nextTagPos != -1 && nextTagPos > 0 && t.source[nextTagPos-1] == '\\' {
-			// Add text up to the backslash
-			if nextTagPos-1 > t.position {
-				preText := t.source[t.position : nextTagPos-1]
-				t.AddToken(TOKEN_TEXT, preText, t.line)
-				t.line += countNewlines(preText)
-			}
+	// Add EOF token
+	t.AddToken(TOKEN_EOF, "", t.line)
 
-			// Add the tag as literal text (without the backslash)
-			// Find which pattern was matched
-			for i := 0; i < 5; i++ {
-				if tagType == tagTypes[i] {
-					t.AddToken(TOKEN_TEXT, tagPatterns[i], t.line)
-					break
-				}
-			}
+	// Save the token buffer to result
+	t.result = t.tokenBuffer
+	return t.result, nil
+}
 
-			// Move past this tag
-			t.position = nextTagPos + tagLength
-			continue
-		}
+// tokenizeTag adds the text up to the next tag and the tokens of that tag.
+// At the end of the source it adds the remaining text and reports false.
+func (t *ZeroAllocTokenizer) tokenizeTag() (bool, error) {
+	source := t.source
 
-		// No more tags found - add the rest as TEXT
-		if nextTagPos == -1 {
-			if t.position < len(t.source) {
-				remainingText := t.source[t.position:]
-				t.AddToken(TOKEN_TEXT, remainingText, t.line)
-				t.line += countNewlines(remainingText)
-			}
+	for t.position < len(source) {
+		// Skip to the next possible tag opening
+		offset := strings.IndexByte(source[t.position:], '{')
+		if offset == -1 {
 			break
 		}
+		pos := t.position + offset
 
-		// Add text before the tag
-		if nextTagPos > t.position {
-			textContent := t.source[t.position:nextTagPos]
-			t.AddToken(TOKEN_TEXT, textContent, t.line)
-			t.line += countNewlines(textContent)
+		tagType, tagLength := tagOpening(source, pos)
+		if tagType == -1 {
+			t.position = pos + 1
+			continue
 		}
 
-		// Add the tag start token
-		t.AddToken(tagType, "", t.line)
-
-		// Move past opening tag
-		t.position = nextTagPos + tagLength
-
-		// Find matching end tag
-		var endTag string
-		var endTagType int
-		var endTagLength int
-
-		if tagType == TOKEN_VAR_START || tagType == TOKEN_VAR_START_TRIM {
-			// Look for "}}" or "-}}"
-			endPos1 := strings.Index(t.source[t.position:], "}}")
-			endPos2 := strings.Index(t.source[t.position:], "-}}")
-
-			if endPos1 != -1 && (endPos2 == -1 || endPos1 < endPos2) {
-				endTag = "}}"
-				endTagType = TOKEN_VAR_END
-				endTagLength = 2
-			} else if endPos2 != -1 {
-				endTag = "-}}"
-				endTagType = TOKEN_VAR_END_TRIM
-				endTagLength = 3
-			} else {
-				return nil, fmt.Errorf("unclosed variable tag at line %d", t.line)
-			}
-		} else if tagType == TOKEN_BLOCK_START || tagType == TOKEN_BLOCK_START_TRIM {
-			// Look for "%}" or "-%}"
-			endPos1 := strings.Index(t.source[t.position:], "%}")
-			endPos2 := strings.Index(t.source[t.position:], "-%}")
-
-			if endPos1 != -1 && (endPos2 == -1 || endPos1 < endPos2) {
-				endTag = "%}"
-				endTagType = TOKEN_BLOCK_END
-				endTagLength = 2
-			} else if endPos2 != -1 {
-				endTag = "-%}"
-				endTagType = TOKEN_BLOCK_END_TRIM
-				endTagLength = 3
-			} else {
-				return nil, fmt.Errorf("unclosed block tag at line %d", t.line)
-			}
-		} else if tagType == TOKEN_COMMENT_START {
-			// Look for "#}"
-			endPos := strings.Index(t.source[t.position:], "#}")
-			if endPos == -1 {
-				return nil, fmt.Errorf("unclosed comment at line %d", t.line)
-			}
-			endTag = "#}"
-			endTagType = TOKEN_COMMENT_END
-			endTagLength = 2
-		}
+		// Check if the tag is escaped
+		if pos > 0 && source[pos-1] == '\\' {
+			// Add text up to the backslash, then the tag as literal text
+			t.addText(source[t.textStart : pos-1])
+			t.addText(source[pos : pos+tagLength])
 
-		// Find position of the end tag
-		endPos := strings.Index(t.source[t.position:], endTag)
-		if endPos == -1 {
-			return nil, fmt.Errorf("unclosed tag at line %d", t.line)
+			t.position = pos + tagLength
+			t.textStart = t.position
+			return true, nil
 		}
 
-		// Get content between tags
-		tagContent := t.source[t.position : t.position+endPos]
-		t.line += countNewlines(tagContent)
-
-		// Process tag content based on type
-		if tagType == TOKEN_COMMENT_START {
-			// Store comments as TEXT tokens
-			if len(tagContent) > 0 {
-				t.AddToken(TOKEN_TEXT, tagContent, t.line)
-			}
-		} else {
-			// For variable and block tags, tokenize the content
-			tagContent = strings.TrimSpace(tagContent)
+		// Add text before the tag
+		t.addText(source[t.textStart:pos])
 
-			if tagType == TOKEN_BLOCK_START || tagType == TOKEN_BLOCK_START_TRIM {
-				// Process block tags with specialized tokenization
-				t.processBlockTag(tagContent)
-			} else {
-				// Process variable tags with optimized tokenization
-				if len(tagContent) > 0 {
-					if !strings.ContainsAny(tagContent, ".|[](){}\"',+-*/=!<>%&^~") {
-						// Simple variable name
-						identifier := t.GetStringConstant(tagContent)
-						t.AddToken(TOKEN_NAME, identifier, t.line)
-					} else {
-						// Complex expression
-						t.TokenizeExpression(tagContent)
-					}
-				}
-			}
+		if err := t.scanTag(pos, tagType, tagLength); err != nil {
+			return false, err
 		}
-
-		// Add the end tag token
-		t.AddToken(endTagType, "", t.line)
-
-		// Move past the end tag
-		t.position = t.position + endPos + endTagLength
+		t.textStart = t.position
+		return true, nil
 	}
 
-	// Add EOF token
-	t.AddToken(TOKEN_EOF, "", t.line)
-
-	// Save the token buffer to result
-	t.result = t.tokenBuffer
-	return t.result, nil
+	// Add the rest as TEXT
+	t.addText(source[t.textStart:])
+	t.position = len(source)
+	t.textStart = len(source)
+	return false, nil
 }
 
-// processBlockTag handles specialized block tag tokenization
-func (t *ZeroAllocTokenizer) processBlockTag(content string) {
-	// Extract the tag name
-	spacePos := strings.IndexByte(content, ' ')
-	var blockName string
-	var blockContent string
-
-	if spacePos == -1 {
-		// No space found, the whole content is the tag name
-		blockName = content
-		blockContent = ""
-	} else {
-		blockName = content[:spacePos]
-		blockContent = strings.TrimSpace(content[spacePos+1:])
+// tagOpening returns the start token type and opening length of the tag at
+// pos, or -1 if no tag starts there
+func tagOpening(source string, pos int) (int, int) {
+	if pos+1 >= len(source) || source[pos] != '{' {
+		return -1, 0
 	}
 
-	// Use canonical string for block name
-	blockName = t.GetStringConstant(blockName)
-	t.AddToken(TOKEN_NAME, blockName, t.line)
+	trim := pos+2 < len(source) && source[pos+2] == '-'
+	switch source[pos+1] {
+	case '{':
+		if trim {
+			return TOKEN_VAR_START_TRIM, 3
+		}
+		return TOKEN_VAR_START, 2
+	case '%':
+		if trim {
+			return TOKEN_BLOCK_START_TRIM, 3
+		}
+		return TOKEN_BLOCK_START, 2
+	case '#':
+		if trim {
+			return TOKEN_COMMENT_START_TRIM, 3
+		}
+		return TOKEN_COMMENT_START, 2
+	}
+	return -1, 0
+}
 
-	// If there's no content, we're done
-	if blockContent == "" {
+// addText adds a TEXT token and advances the line count past it
+func (t *ZeroAllocTokenizer) addText(text string) {
+	if text == "" {
 		return
 	}
+	t.AddToken(TOKEN_TEXT, text, t.line)
+	t.line += countNewlines(text)
+}
 
-	// Process based on block type
-	switch blockName {
-	case "if", "elseif":
-		// For conditional blocks, tokenize expression
-		t.TokenizeExpression(blockContent)
-
-	case "for":
-		// Process for loop with iterator(s) and collection
-		inPos := strings.Index(strings.ToLower(blockContent), " in ")
-		if inPos != -1 {
-			iterators := strings.TrimSpace(blockContent[:inPos])
-			collection := strings.TrimSpace(blockContent[inPos+4:])
-
-			// Handle key, value iterator syntax
-			if strings.Contains(iterators, ",") {
-				iterParts := strings.SplitN(iterators, ",", 2)
-				if len(iterParts) == 2 {
-					// Process iterator variables
-					keyVar := t.GetStringConstant(strings.TrimSpace(iterParts[0]))
-					valueVar := t.GetStringConstant(strings.TrimSpace(iterParts[1]))
-
-					t.AddToken(TOKEN_NAME, keyVar, t.line)
-					t.AddToken(TOKEN_PUNCTUATION, ",", t.line)
-					t.AddToken(TOKEN_NAME, valueVar, t.line)
-				}
-			} else {
-				// Single iterator
-				iterator := t.GetStringConstant(iterators)
-				t.AddToken(TOKEN_NAME, iterator, t.line)
-			}
+// scanTag tokenizes the tag opening at pos and moves past its end
+func (t *ZeroAllocTokenizer) scanTag(pos, tagType, tagLength int) error {
+	startLine := t.line
 
-			// Add 'in' keyword
-			t.AddToken(TOKEN_NAME, "in", t.line)
+	// Add the tag start token
+	t.AddToken(tagType, "", t.line)
 
-			// Process collection expression
-			t.TokenizeExpression(collection)
-		} else {
-			// Fallback for malformed for loops
-			t.AddToken(TOKEN_NAME, blockContent, t.line)
-		}
+	// Find the matching end tag
+	var closer string
+	var endType, trimEndType int
+	switch tagType {
+	case TOKEN_VAR_START, TOKEN_VAR_START_TRIM:
+		closer, endType, trimEndType = "}}", TOKEN_VAR_END, TOKEN_VAR_END_TRIM
+	case TOKEN_BLOCK_START, TOKEN_BLOCK_START_TRIM:
+		closer, endType, trimEndType = "%}", TOKEN_BLOCK_END, TOKEN_BLOCK_END_TRIM
+	default:
+		closer, endType, trimEndType = "#}", TOKEN_COMMENT_END, TOKEN_COMMENT_END_TRIM
+	}
 
-	case "set":
-		// Handle variable assignment
-		assignPos := strings.Index(blockContent, "=")
-		if assignPos != -1 {
-			varName := strings.TrimSpace(blockContent[:assignPos])
-			value := strings.TrimSpace(blockContent[assignPos+1:])
-
-			// Add the variable name token
-			varName = t.GetStringConstant(varName)
-			t.AddToken(TOKEN_NAME, varName, t.line)
-
-			// Add the assignment operator
-			t.AddToken(TOKEN_OPERATOR, "=", t.line)
-
-			// Tokenize the value expression
-			t.TokenizeExpression(value)
-		} else {
-			// Simple set without assignment
-			blockContent = t.GetStringConstant(blockContent)
-			t.AddToken(TOKEN_NAME, blockContent, t.line)
+	contentStart := pos + tagLength
+	end := strings.Index(t.source[contentStart:], closer)
+	if end == -1 {
+		switch tagType {
+		case TOKEN_VAR_START, TOKEN_VAR_START_TRIM:
+			return fmt.Errorf("unclosed variable tag at line %d", t.line)
+		case TOKEN_BLOCK_START, TOKEN_BLOCK_START_TRIM:
+			return fmt.Errorf("unclosed block tag at line %d", t.line)
 		}
+		return fmt.Errorf("unclosed comment at line %d", t.line)
+	}
+	end += contentStart
+
+	// A dash before the closer trims whitespace after the tag
+	endLength := len(closer)
+	if end > contentStart && t.source[end-1] == '-' {
+		end--
+		endType = trimEndType
+		endLength++
+	}
 
-	case "do":
-		// Handle variable assignment similar to set tag
-		assignPos := strings.Index(blockContent, "=")
-		if assignPos != -1 {
-			varName := strings.TrimSpace(blockContent[:assignPos])
-			value := strings.TrimSpace(blockContent[assignPos+1:])
-
-			// Check if varName is valid (should be a variable name)
-			// In Twig, variable names must start with a letter or underscore
-			if len(varName) > 0 && (isCharAlpha(varName[0]) || varName[0] == '_') {
-				// Add the variable name token
-				varName = t.GetStringConstant(varName)
-				t.AddToken(TOKEN_NAME, varName, t.line)
-
-				// Add the assignment operator
-				t.AddToken(TOKEN_OPERATOR, "=", t.line)
-
-				// Tokenize the value expression
-				if len(value) > 0 {
-					t.TokenizeExpression(value)
-				} else {
-					// Empty value after =, which is invalid
-					// Add an error token to trigger proper parser error
-					t.AddToken(TOKEN_EOF, "ERROR_MISSING_VALUE", t.line)
-				}
-			} else {
-				// Invalid variable name (like a number or operator)
-				// Just tokenize as expressions to produce an error in the parser
-				t.TokenizeExpression(varName)
-				t.AddToken(TOKEN_OPERATOR, "=", t.line)
-				t.TokenizeExpression(value)
-			}
-		} else {
-			// No assignment, just an expression to evaluate
-			t.TokenizeExpression(blockContent)
-		}
+	// Get content between tags
+	tagContent := t.source[contentStart:end]
 
-	case "include":
-		// Handle include with template path and optional context
-		withPos := strings.Index(strings.ToLower(blockContent), " with ")
-		if withPos != -1 {
-			templatePath := strings.TrimSpace(blockContent[:withPos])
-			contextExpr := strings.TrimSpace(blockContent[withPos+6:])
-
-			// Process template path
-			t.tokenizeTemplatePath(templatePath)
-
-			// Add 'with' keyword
-			t.AddToken(TOKEN_NAME, "with", t.line)
-
-			// Process context expression as object
-			if strings.HasPrefix(contextExpr, "{") && strings.HasSuffix(contextExpr, "}") {
-				// Context is an object literal
-				t.AddToken(TOKEN_PUNCTUATION, "{", t.line)
-				objectContent := contextExpr[1 : len(contextExpr)-1]
-				t.tokenizeObjectContents(objectContent)
-				t.AddToken(TOKEN_PUNCTUATION, "}", t.line)
-			} else {
-				// Context is a variable or expression
-				t.TokenizeExpression(contextExpr)
-			}
-		} else {
-			// Just a template path
-			t.tokenizeTemplatePath(blockContent)
+	if tagType == TOKEN_COMMENT_START || tagType == TOKEN_COMMENT_START_TRIM {
+		// Store comments as TEXT tokens
+		if len(tagContent) > 0 {
+			t.AddToken(TOKEN_TEXT, tagContent, t.line)
 		}
-
-	case "extends":
-		// Handle extends tag (similar to include template path)
-		t.tokenizeTemplatePath(blockContent)
-
-	case "from":
-		// Handle from tag which has a special format:
-		// {% from "template.twig" import macro1, macro2 as alias %}
-		importPos := strings.Index(strings.ToLower(blockContent), " import ")
-		if importPos != -1 {
-			// Extract template path and macros list
-			templatePath := strings.TrimSpace(blockContent[:importPos])
-			macrosStr := strings.TrimSpace(blockContent[importPos+8:]) // 8 = len(" import ")
-
-			// Process template path
-			t.tokenizeTemplatePath(templatePath)
-
-			// Add 'import' keyword
-			t.AddToken(TOKEN_NAME, "import", t.line)
-
-			// Process macro imports
-			macros := strings.Split(macrosStr, ",")
-			for i, macro := range macros {
-				macro = strings.TrimSpace(macro)
-
-				// Check for "as" alias
-				asPos := strings.Index(strings.ToLower(macro), " as ")
-				if asPos != -1 {
-					// Extract macro name and alias
-					macroName := strings.TrimSpace(macro[:asPos])
-					alias := strings.TrimSpace(macro[asPos+4:])
-
-					// Add macro name
-					macroName = t.GetStringConstant(macroName)
-					t.AddToken(TOKEN_NAME, macroName, t.line)
-
-					// Add 'as' keyword
-					t.AddToken(TOKEN_NAME, "as", t.line)
-
-					// Add alias
-					alias = t.GetStringConstant(alias)
-					t.AddToken(TOKEN_NAME, alias, t.line)
-				} else {
-					// Just the macro name
-					macro = t.GetStringConstant(macro)
-					t.AddToken(TOKEN_NAME, macro, t.line)
-				}
-
-				// Add comma if not the last macro
-				if i < len(macros)-1 {
-					t.AddToken(TOKEN_PUNCTUATION, ",", t.line)
-				}
+	} else {
+		// Tokens start on the line of the first non-space character
+		trimmed := strings.TrimSpace(tagContent)
+		leading := len(tagContent) - len(strings.TrimLeftFunc(tagContent, unicode.IsSpace))
+		t.line = startLine + countNewlines(tagContent[:leading])
+
+		if tagType == TOKEN_BLOCK_START || tagType == TOKEN_BLOCK_START_TRIM {
+			// Process block tags with specialized tokenization
+			if len(trimmed) > 0 {
+				t.processBlockTag(trimmed)
+			}
+		} else if len(trimmed) > 0 {
+			// Process variable tags with optimized tokenization
+			if isIdentifier(trimmed) {
+				// Simple variable name
+				identifier := t.GetStringConstant(trimmed)
+				t.AddToken(TOKEN_NAME, identifier, t.line)
+			} else {
+				// Complex expression
+				t.TokenizeExpression(trimmed)
 			}
-		} else {
-			// Malformed from tag, just tokenize as expression
-			t.TokenizeExpression(blockContent)
 		}
+	}
 
-	case "import":
-		// Handle import tag which allows importing entire templates
-		// {% import "template.twig" as alias %}
-		asPos := strings.Index(strings.ToLower(blockContent), " as ")
-		if asPos != -1 {
-			// Extract template path and alias
-			templatePath := strings.TrimSpace(blockContent[:asPos])
-			alias := strings.TrimSpace(blockContent[asPos+4:])
-
-			// Process template path
-			t.tokenizeTemplatePath(templatePath)
-
-			// Add 'as' keyword
-			t.AddToken(TOKEN_NAME, "as", t.line)
-
-			// Add alias
-			alias = t.GetStringConstant(alias)
-			t.AddToken(TOKEN_NAME, alias, t.line)
-		} else {
-			// Simple import without alias
-			t.TokenizeExpression(blockContent)
-		}
+	// The end tag is on the line where the content finishes
+	t.line = startLine + countNewlines(tagContent)
+	t.AddToken(endType, "", t.line)
 
-	default:
-		// Other block types - tokenize as expression
-		t.TokenizeExpression(blockContent)
-	}
+	// Move past the end tag
+	t.position = end + endLength
+	return nil
 }
 
-// Helper methods for specialized tag tokenization
+// processBlockTag tokenizes the content of a block tag: the tag name
+// followed by its arguments, which are tokenized as expressions whatever the
+// tag so whitespace and newlines are handled the same everywhere
+func (t *ZeroAllocTokenizer) processBlockTag(content string) {
+	// The tag name is the leading identifier
+	nameEnd := 0
+	for nameEnd < len(content) && (isCharAlpha(content[nameEnd]) || content[nameEnd] == '_' ||
+		(nameEnd > 0 && content[nameEnd] >= '0' && content[nameEnd] <= '9')) {
+		nameEnd++
+	}
+	if nameEnd == 0 {
+		// Not a tag name, let the parser report the expression
+		t.TokenizeExpression(content)
+		return
+	}
 
-// tokenizeTemplatePath handles template paths in extends/include tags
-func (t *ZeroAllocTokenizer) tokenizeTemplatePath(path string) {
-	path = strings.TrimSpace(path)
+	// Use canonical string for block name
+	blockName := t.GetStringConstant(content[:nameEnd])
+	t.AddToken(TOKEN_NAME, blockName, t.line)
 
-	// If it's a quoted string
-	if (strings.HasPrefix(path, "\"") && strings.HasSuffix(path, "\"")) ||
-		(strings.HasPrefix(path, "'") && strings.HasSuffix(path, "'")) {
-		// Extract content without quotes
-		content := path[1 : len(path)-1]
-		t.AddToken(TOKEN_STRING, content, t.line)
-	} else {
-		// Otherwise tokenize as expression
-		t.TokenizeExpression(path)
+	// The arguments keep their line offsets from the tag name
+	rest := content[nameEnd:]
+	blockContent := strings.TrimLeftFunc(rest, unicode.IsSpace)
+	if blockContent == "" {
+		return
 	}
+
+	savedLine := t.line
+	t.line += countNewlines(rest[:len(rest)-len(blockContent)])
+	t.TokenizeExpression(blockContent)
+	t.line = savedLine
 }
 
 // isCharAlpha checks if a byte is an alphabetic character
@@ -831,100 +603,6 @@ func isCharAlpha(c byte) bool {
 	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
 }
 
-// tokenizeObjectContents handles object literal contents
-func (t *ZeroAllocTokenizer) tokenizeObjectContents(content string) {
-	// Track state for nested structures
-	inString := false
-	stringDelim := byte(0)
-	inObject := 0
-	inArray := 0
-
-	start := 0
-	colonPos := -1
-
-	for i := 0; i <= len(content); i++ {
-		// At end of string or at a comma at the top level
-		atEnd := i == len(content)
-		isComma := !atEnd && content[i] == ','
-
-		// Process key-value pair when we find a comma or reach the end
-		if (isComma || atEnd) && inObject == 0 && inArray == 0 && !inString {
-			if colonPos != -1 {
-				// We have a key-value pair
-				keyStr := strings.TrimSpace(content[start:colonPos])
-				valueStr := strings.TrimSpace(content[colonPos+1 : i])
-
-				// Process key
-				if (len(keyStr) >= 2 && keyStr[0] == '"' && keyStr[len(keyStr)-1] == '"') ||
-					(len(keyStr) >= 2 && keyStr[0] == '\'' && keyStr[len(keyStr)-1] == '\'') {
-					// Quoted key
-					t.AddToken(TOKEN_STRING, keyStr[1:len(keyStr)-1], t.line)
-				} else {
-					// Unquoted key
-					keyStr = t.GetStringConstant(keyStr)
-					t.AddToken(TOKEN_NAME, keyStr, t.line)
-				}
-
-				// Add colon
-				t.AddToken(TOKEN_PUNCTUATION, ":", t.line)
-
-				// Process value
-				t.TokenizeExpression(valueStr)
-
-				// Add comma if needed
-				if isComma && i < len(content)-1 {
-					t.AddToken(TOKEN_PUNCTUATION, ",", t.line)
-				}
-
-				// Reset for next pair
-				start = i + 1
-				colonPos = -1
-			}
-			continue
-		}
-
-		// Skip end of string case
-		if atEnd {
-			continue
-		}
-
-		// Current character
-		c := content[i]
-
-		// Handle string literals
-		if (c == '"' || c == '\'') && (i == 0 || content[i-1] != '\\') {
-			if inString && c == stringDelim {
-				inString = false
-			} else if !inString {
-				inString = true
-				stringDelim = c
-			}
-			continue
-		}
-
-		// Skip processing inside strings
-		if inString {
-			continue
-		}
-
-		// Handle object and array nesting
-		if c == '{' {
-			inObject++
-		} else if c == '}' {
-			inObject--
-		} else if c == '[' {
-			inArray++
-		} else if c == ']' {
-			inArray--
-		}
-
-		// Track colon position for key-value separator
-		if c == ':' && inObject == 0 && inArray == 0 && colonPos == -1 {
-			colonPos = i
-		}
-	}
-}
-
 // ApplyWhitespaceControl applies whitespace control to the tokenized result
 func (t *ZeroAllocTokenizer) ApplyWhitespaceControl() {
 	tokens := t.result
@@ -933,7 +611,7 @@ func (t *ZeroAllocTokenizer) ApplyWhitespaceControl() {
 		token := tokens[i]
 
 		// Handle opening tags that trim whitespace before them
-		if token.Type == TOKEN_VAR_START_TRIM || token.Type == TOKEN_BLOCK_START_TRIM {
+		if token.Type == TOKEN_VAR_START_TRIM || token.Type == TOKEN_BLOCK_START_TRIM || token.Type == TOKEN_COMMENT_START_TRIM {
 			// If there's a text token before this, trim its trailing whitespace
 			if i > 0 && tokens[i-1].Type == TOKEN_TEXT {
 				tokens[i-1].Value = trimTrailingWhitespace(tokens[i-1].Value)
@@ -941,7 +619,7 @@ func (t *ZeroAllocTokenizer) ApplyWhitespaceControl() {
 		}
 
 		// Handle closing tags that trim whitespace after them
-		if token.Type == TOKEN_VAR_END_TRIM || token.Type == TOKEN_BLOCK_END_TRIM {
+		if token.Type == TOKEN_VAR_END_TRIM || token.Type == TOKEN_BLOCK_END_TRIM || token.Type == TOKEN_COMMENT_END_TRIM {
 			// If there's a text token after this, trim its leading whitespace
 			if i+1 < len(tokens) && tokens[i+1].Type == TOKEN_TEXT {
 				tokens[i+1].Value = trimLeadingWhitespace(tokens[i+1].Value)
@@ -1106,192 +784,21 @@ func FindTagEnd(source string, startPos int, tagType TagType) int {
 	return -1
 }
 
-// TokenizeOptimized uses the enhanced tag detection for faster tokenization
-// This is a hybrid approach that combines direct tag detection with
-// full HTML-preserving tokenization for maximum performance
+// TokenizeOptimized tokenizes the template like TokenizeHtmlPreserving,
+// which scans the source in a single pass for templates of any size
 func (t *ZeroAllocTokenizer) TokenizeOptimized() ([]Token, error) {
-	// Reset position and line
-	t.position = 0
-	t.line = 1
-
-	// Clear token buffer
-	t.tokenBuffer = t.tokenBuffer[:0]
-
-	// Process the template content
-	pos := 0
-
-	for pos < len(t.source) {
-		// Find the next tag using optimized detection
-		tagLoc := FindNextTag(t.source, pos)
-
-		// Check if no more tags found
-		if tagLoc.Position == -1 {
-			// Add remaining text as TOKEN_TEXT
-			if pos < len(t.source) {
-				remainingText := t.source[pos:]
-				t.AddToken(TOKEN_TEXT, remainingText, t.line)
-				t.line += countNewlines(remainingText)
-			}
-			break
-		}
-
-		// Check if the tag is escaped with a backslash
-		if tagLoc.Position > 0 && t.source[tagLoc.Position-1] == '\\' {
-			// Add text up to the backslash
-			if tagLoc.Position-1 > pos {
-				preText := t.source[pos : tagLoc.Position-1]
-				t.AddToken(TOKEN_TEXT, preText, t.line)
-				t.line += countNewlines(preText)
-			}
-
-			// Add the tag as literal text (without the backslash)
-			var tagText string
-			switch tagLoc.Type {
-			case TAG_VAR:
-				tagText = "{{"
-			case TAG_VAR_TRIM:
-				tagText = "{{-"
-			case TAG_BLOCK:
-				tagText = "{%"
-			case TAG_BLOCK_TRIM:
-				tagText = "{%-"
-			case TAG_COMMENT:
-				tagText = "{#"
-			}
-
-			t.AddToken(TOKEN_TEXT, tagText, t.line)
-
-			// Move past this tag
-			pos = tagLoc.Position + tagLoc.Length
-			continue
-		}
-
-		// Add text before the tag
-		if tagLoc.Position > pos {
-			textContent := t.source[pos:tagLoc.Position]
-			t.AddToken(TOKEN_TEXT, textContent, t.line)
-			t.line += countNewlines(textContent)
-		}
-
-		// Add the tag start token
-		var startTokenType int
-		switch tagLoc.Type {
-		case TAG_VAR:
-			startTokenType = TOKEN_VAR_START
-		case TAG_VAR_TRIM:
-			startTokenType = TOKEN_VAR_START_TRIM
-		case TAG_BLOCK:
-			startTokenType = TOKEN_BLOCK_START
-		case TAG_BLOCK_TRIM:
-			startTokenType = TOKEN_BLOCK_START_TRIM
-		case TAG_COMMENT:
-			startTokenType = TOKEN_COMMENT_START
-		}
-
-		t.AddToken(startTokenType, "", t.line)
-
-		// Move past the tag's opening characters
-		tagContentStart := tagLoc.Position + tagLoc.Length
-
-		// Find the end of the tag
-		tagEndPos := FindTagEnd(t.source, tagContentStart, tagLoc.Type)
-		if tagEndPos == -1 {
-			var unclosedType string
-			switch tagLoc.Type {
-			case TAG_VAR, TAG_VAR_TRIM:
-				unclosedType = "variable"
-			case TAG_BLOCK, TAG_BLOCK_TRIM:
-				unclosedType = "block"
-			case TAG_COMMENT:
-				unclosedType = "comment"
-			}
-			return nil, fmt.Errorf("unclosed %s tag at line %d", unclosedType, t.line)
-		}
-
-		// Get tag content
-		tagContent := t.source[tagContentStart:tagEndPos]
-		t.line += countNewlines(tagContent)
-
-		// Determine the end token type and length
-		var endTokenType int
-		var endLength int
-
-		switch tagLoc.Type {
-		case TAG_VAR:
-			endTokenType = TOKEN_VAR_END
-			endLength = 2 // }}
-		case TAG_VAR_TRIM:
-			// Check if it ends with -}}
-			if tagEndPos > 0 && t.source[tagEndPos-1] == '-' {
-				endTokenType = TOKEN_VAR_END_TRIM
-				endLength = 3 // -}}
-				// Adjust tag content to remove the trailing dash
-				tagContent = tagContent[:len(tagContent)-1]
-			} else {
-				endTokenType = TOKEN_VAR_END
-				endLength = 2 // }}
-			}
-		case TAG_BLOCK:
-			endTokenType = TOKEN_BLOCK_END
-			endLength = 2 // %}
-		case TAG_BLOCK_TRIM:
-			// Check if it ends with -%}
-			if tagEndPos > 0 && t.source[tagEndPos-1] == '-' {
-				endTokenType = TOKEN_BLOCK_END_TRIM
-				endLength = 3 // -%}
-				// Adjust tag content to remove the trailing dash
-				tagContent = tagContent[:len(tagContent)-1]
-			} else {
-				endTokenType = TOKEN_BLOCK_END
-				endLength = 2 // %}
-			}
-		case TAG_COMMENT:
-			endTokenType = TOKEN_COMMENT_END
-			endLength = 2 // #}
-		}
+	return t.TokenizeHtmlPreserving()
+}
 
-		// Process tag content based on tag type
-		if tagLoc.Type == TAG_COMMENT {
-			// Store comments as TEXT tokens
-			if len(tagContent) > 0 {
-				t.AddToken(TOKEN_TEXT, tagContent, t.line)
-			}
-		} else {
-			// For variable and block tags, tokenize the content
-			tagContent = strings.TrimSpace(tagContent)
-
-			if tagLoc.Type == TAG_BLOCK || tagLoc.Type == TAG_BLOCK_TRIM {
-				// Process block tags using specialized tokenization
-				if len(tagContent) > 0 {
-					t.processBlockTag(tagContent)
-				}
-			} else {
-				// Process variable tags using optimized tokenization
-				if len(tagContent) > 0 {
-					// Check if it's a simple variable or a complex expression
-					if !strings.ContainsAny(tagContent, ".|[](){}\"',+-*/=!<>%&^~") {
-						// Simple variable name - use string interning for efficiency
-						identifier := Intern(tagContent)
-						t.AddToken(TOKEN_NAME, identifier, t.line)
-					} else {
-						// Complex expression - tokenize fully
-						t.TokenizeExpression(tagContent)
-					}
-				}
-			}
+// isIdentifier reports whether s is a single name, such as a variable
+func isIdentifier(s string) bool {
+	if len(s) == 0 || !isAlpha(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
 		}
-
-		// Add end token
-		t.AddToken(endTokenType, "", t.line)
-
-		// Move past the end tag
-		pos = tagEndPos + endLength
 	}
-
-	// Add EOF token
-	t.AddToken(TOKEN_EOF, "", t.line)
-
-	// Save and return result
-	t.result = t.tokenBuffer
-	return t.result, nil
+	return true
 }